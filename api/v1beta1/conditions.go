@@ -0,0 +1,183 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionDegraded indicates that the operator is running, but the data it is producing is suspect.
+	ConditionDegraded = "Degraded"
+
+	// ReasonEmptyReports indicates that one or more generated reports contained no rows.
+	ReasonEmptyReports = "EmptyReports"
+
+	// ReasonMissingLabels indicates that expected pod labels were not found in the collected metrics.
+	ReasonMissingLabels = "MissingLabels"
+
+	// ReasonInconsistentReports indicates that pods in the pod report reference namespaces or nodes missing from the
+	// namespace or node report of the same window.
+	ReasonInconsistentReports = "InconsistentReports"
+
+	// ReasonReportsValid indicates that the generated reports passed validation.
+	ReasonReportsValid = "ReportsValid"
+
+	// ReasonMaxSizeClamped indicates that the packaging max size was larger than the upload limit and the limit is used.
+	ReasonMaxSizeClamped = "MaxSizeClamped"
+
+	// ReasonStatusTimestampRepaired indicates that a status timestamp in the future or too far in the past was repaired.
+	ReasonStatusTimestampRepaired = "StatusTimestampRepaired"
+
+	// ConditionClockSkew indicates that the local clock differs from the clock of the API by more than the threshold.
+	ConditionClockSkew = "ClockSkew"
+
+	// ReasonClockSkewed indicates that the manifest date is corrected for the skew of the local clock.
+	ReasonClockSkewed = "ClockSkewed"
+
+	// ReasonClockSynchronized indicates that the local clock is within the threshold of the clock of the API.
+	ReasonClockSynchronized = "ClockSynchronized"
+
+	// ConditionMonitoringAccess indicates whether the operator is authorized to query the cluster monitoring stack.
+	ConditionMonitoringAccess = "MonitoringAccess"
+
+	// ReasonMonitoringAccessAllowed indicates that the operator's service account may query the cluster monitoring stack.
+	ReasonMonitoringAccessAllowed = "MonitoringAccessAllowed"
+
+	// ReasonMissingMonitoringBinding indicates that the operator's service account is not bound to cluster-monitoring-view.
+	ReasonMissingMonitoringBinding = "MissingClusterMonitoringViewBinding"
+
+	// ReasonAccessReviewFailed indicates that the operator's access to the cluster monitoring stack could not be reviewed.
+	ReasonAccessReviewFailed = "AccessReviewFailed"
+
+	// ConditionPayloadsQuarantined indicates whether payloads rejected by ingress are waiting for review in the
+	// quarantine directory.
+	ConditionPayloadsQuarantined = "PayloadsQuarantined"
+
+	// ReasonRejectedPayloads indicates that payloads were quarantined after repeated 4xx rejections.
+	ReasonRejectedPayloads = "RejectedPayloads"
+
+	// ReasonNoQuarantinedPayloads indicates that the quarantine directory is empty.
+	ReasonNoQuarantinedPayloads = "NoQuarantinedPayloads"
+
+	// ConditionReadyToUpload indicates whether the pre-flight check reached the ingress and Sources endpoints with
+	// the configured credentials.
+	ConditionReadyToUpload = "ReadyToUpload"
+
+	// ReasonPreflightSucceeded indicates that the ingress and Sources endpoints accepted the pre-flight requests.
+	ReasonPreflightSucceeded = "PreflightSucceeded"
+
+	// ReasonPreflightFailed indicates that the ingress or Sources endpoint could not be reached, or rejected the
+	// credentials.
+	ReasonPreflightFailed = "PreflightFailed"
+
+	// ReasonUploadUnauthorized indicates that ingress rejected the credentials of an upload with 401 or 403.
+	ReasonUploadUnauthorized = "UploadUnauthorized"
+
+	// ReasonUploadDisabled indicates that upload is disabled, so the pre-flight check is not run.
+	ReasonUploadDisabled = "UploadDisabled"
+
+	// ConditionCertValidationDisabled indicates whether the certificate of the ingress or Sources endpoint is not
+	// validated, so that credentials and payloads are sent to a server that is not authenticated.
+	ConditionCertValidationDisabled = "CertValidationDisabled"
+
+	// ReasonInsecureEndpoints indicates that cert_validation turns off the validation of an endpoint's certificate.
+	ReasonInsecureEndpoints = "InsecureEndpoints"
+
+	// ReasonCertificatesValidated indicates that the certificates of every endpoint are validated.
+	ReasonCertificatesValidated = "CertificatesValidated"
+)
+
+// Condition is a field of KokuMetricsConfigStatus to represent an observation of the operator's state.
+type Condition struct {
+	// Type is the type of the condition.
+	Type string `json:"type"`
+
+	// Status is the status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+
+	// LastTransitionTime is the last time the condition transitioned from one status to another.
+	// +nullable
+	LastTransitionTime metav1.Time `json:"last_transition_time,omitempty"`
+
+	// Reason is a one-word CamelCase reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human-readable message indicating details about the transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// SetCondition adds or updates the condition in the list of conditions. The LastTransitionTime
+// is only updated when the status of the condition changes.
+func SetCondition(conditions *[]Condition, newCondition Condition) {
+	if conditions == nil {
+		return
+	}
+	existing := FindCondition(*conditions, newCondition.Type)
+	if existing == nil {
+		if newCondition.LastTransitionTime.IsZero() {
+			newCondition.LastTransitionTime = metav1.Now()
+		}
+		*conditions = append(*conditions, newCondition)
+		return
+	}
+	if existing.Status != newCondition.Status {
+		existing.Status = newCondition.Status
+		existing.LastTransitionTime = newCondition.LastTransitionTime
+		if existing.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		}
+	}
+	existing.Reason = newCondition.Reason
+	existing.Message = newCondition.Message
+}
+
+// RemoveCondition removes the condition with the given type from the list of conditions.
+func RemoveCondition(conditions *[]Condition, conditionType string) {
+	if conditions == nil {
+		return
+	}
+	kept := (*conditions)[:0]
+	for _, c := range *conditions {
+		if c.Type != conditionType {
+			kept = append(kept, c)
+		}
+	}
+	*conditions = kept
+}
+
+// FindCondition returns the condition with the given type, or nil if it is not present.
+func FindCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue returns true if the condition with the given type is present and has a status of True.
+func IsConditionTrue(conditions []Condition, conditionType string) bool {
+	c := FindCondition(conditions, conditionType)
+	return c != nil && c.Status == corev1.ConditionTrue
+}
@@ -32,11 +32,56 @@ const (
 	// DefaultSourcesPath The default ingress path.
 	DefaultSourcesPath string = "/api/sources/v1.0/"
 
+	// DefaultReportStatusPath The default path of the cost management report status API.
+	DefaultReportStatusPath string = "/api/cost-management/v1/ingress/reports/"
+
+	// DefaultSSOTokenURL The default SSO token endpoint.
+	DefaultSSOTokenURL string = "https://sso.redhat.com/auth/realms/redhat-external/protocol/openid-connect/token"
+
+	// DefaultAPIEnvironment The default API environment.
+	DefaultAPIEnvironment APIEnvironment = ProductionEnvironment
+
 	// DefaultPrometheusSvcAddress The default address to thanos-querier.
 	DefaultPrometheusSvcAddress string = "https://thanos-querier.openshift-monitoring.svc:9091"
 
+	// DefaultUserWorkloadSvcAddress The default address to the user workload monitoring prometheus.
+	DefaultUserWorkloadSvcAddress string = "https://prometheus-user-workload.openshift-user-workload-monitoring.svc:9091"
+
+	// DefaultBillingTimezone The default time zone that reports are aligned to
+	DefaultBillingTimezone string = "UTC"
+
+	// DefaultPeriodStartDay The default day of the month that billing periods start on
+	DefaultPeriodStartDay int64 = 1
+
+	// DefaultReportGranularity The default level that usage is reported at
+	DefaultReportGranularity ReportGranularity = PodGranularity
+
+	// DefaultUploadInterval The default number of seconds between two payload uploads
+	DefaultUploadInterval int64 = 5
+
+	// DefaultMaxBackfillUploads The default number of backfill payloads uploaded each upload cycle
+	DefaultMaxBackfillUploads int64 = 24
+
+	// DefaultQuarantineAfterRejections The default number of 4xx rejections after which a payload is quarantined
+	DefaultQuarantineAfterRejections int64 = 3
+
+	// DefaultFailoverThreshold The default number of unreachable upload cycles before uploads fail over to the next API URL
+	DefaultFailoverThreshold int64 = 3
+
+	// DefaultClockSkewThreshold The default number of seconds that the local clock may differ from the API clock
+	DefaultClockSkewThreshold int64 = 300
+
+	// DefaultPayloadAuditTTLHours The default number of hours a KokuMetricsPayload is kept
+	DefaultPayloadAuditTTLHours int64 = 168
+
+	// DefaultProcessingStatusPollCycle The default number of minutes between each poll of the report status API
+	DefaultProcessingStatusPollCycle int64 = 60
+
+	// DefaultCronJobActiveDeadlineSeconds The default number of seconds a Job of the cronjob execution mode may run
+	DefaultCronJobActiveDeadlineSeconds int64 = 1800
+
 	// DefaultValidateCert The default cert validation setting
-	DefaultValidateCert bool = CertIgnore
+	DefaultValidateCert bool = CertCheck
 
 	//DefaultUploadToggle The default upload toggle
 	DefaultUploadToggle bool = UploadOn
@@ -49,4 +94,61 @@ const (
 
 	//DefaultMaxSize The default max size for report files
 	DefaultMaxSize int64 = PackagingMaxSize
+
+	// DefaultUploadMaxSize The default largest report size in megabytes accepted by the upload endpoint
+	DefaultUploadMaxSize int64 = PackagingMaxSize
+
+	// DefaultPayloadCadence The default granularity of the payloads
+	DefaultPayloadCadence PayloadCadence = HourlyCadence
+
+	// DefaultCSVDelimiter The default field delimiter of the reports exported to a destination
+	DefaultCSVDelimiter CSVDelimiter = CommaDelimiter
+
+	// DefaultCSVQuoting The default quoting of the fields of the reports exported to a destination
+	DefaultCSVQuoting CSVQuoting = MinimalQuoting
+
+	// DefaultTokenHeader The default header for static token authentication
+	DefaultTokenHeader string = "Authorization"
+
+	// DefaultPayloadFormat The default upload payload format
+	DefaultPayloadFormat PayloadFormat = MultipartPayload
+
+	// DefaultOperatorProfile The default operator profile
+	DefaultOperatorProfile OperatorProfile = DefaultProfile
+
+	// DefaultResourceSize The default resource size, unless the operator profile is lightweight
+	DefaultResourceSize ResourceSize = MediumResources
+
+	// DefaultExecutionMode The default execution mode
+	DefaultExecutionMode ExecutionMode = ControllerExecution
+
+	// DefaultCronJobSchedule The default cron schedule of the Jobs of the cronjob execution mode
+	DefaultCronJobSchedule string = "5 * * * *"
+
+	// DefaultBlackoutDurationMinutes The default length of a recurring blackout window
+	DefaultBlackoutDurationMinutes int64 = 60
+
+	// DefaultUpgradeDeferralEnabled The default for deferring collection while the cluster is upgrading
+	DefaultUpgradeDeferralEnabled bool = true
+
+	// DefaultUpgradeDeferUpload The default for deferring uploads while the cluster is upgrading
+	DefaultUpgradeDeferUpload bool = true
+
+	// DefaultUpgradeSettleMinutes The default time that collection is still deferred after an upgrade completes
+	DefaultUpgradeSettleMinutes int64 = 15
+
+	// DefaultUpgradeMaxHours The default longest time that collection is deferred for an upgrade
+	DefaultUpgradeMaxHours int64 = 12
+
+	// DefaultAdaptiveStepMaxDurationSeconds The default number of seconds a collection may take before the query step is coarsened
+	DefaultAdaptiveStepMaxDurationSeconds int64 = 300
+
+	// DefaultAdaptiveStepMaxMemoryMB The default memory, in megabytes, a collection may use before the query step is coarsened
+	DefaultAdaptiveStepMaxMemoryMB int64 = 400
+
+	// DefaultAdaptiveStepRecoveryCollections The default number of consecutive collections within the thresholds after which a coarsened query step is made finer
+	DefaultAdaptiveStepRecoveryCollections int64 = 24
+
+	// DefaultMaxLabelValueLength The default length, in bytes, that label and annotation values are truncated to
+	DefaultMaxLabelValueLength int64 = 1024
 )
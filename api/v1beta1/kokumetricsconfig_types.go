@@ -52,13 +52,29 @@ const (
 
 	//PackagingMaxSize sets the default max file size to be 100 MB
 	PackagingMaxSize int64 = 100
+
+	// ConnectionTestAnnotation is the annotation used to trigger a connection test. A test is run
+	// each time the value of the annotation changes.
+	ConnectionTestAnnotation = "koku-metrics-cfg.openshift.io/connection-test"
+
+	// DebugBundleAnnotation is the annotation used to trigger generation of a debug bundle. A bundle is
+	// generated each time the value of the annotation changes.
+	DebugBundleAnnotation = "koku-metrics-cfg.openshift.io/debug-bundle"
+
+	// RecollectAnnotation is the annotation used to request that reports are regenerated for a past date range.
+	// The value is the range as `<start>/<end>`, and the range is re-collected each time the value changes.
+	RecollectAnnotation = "koku-metrics-cfg.openshift.io/recollect"
+
+	// ReplayAnnotation is the annotation used to request that the payloads in the retry directory of the report
+	// volume are uploaded again. The payloads are uploaded each time the value of the annotation changes.
+	ReplayAnnotation = "koku-metrics-cfg.openshift.io/replay"
 )
 
 // AuthenticationType describes how the upload will be handled.
 // Only one of the following authentication types may be specified.
 // If none of the following types are specified, the default one
 // is Token.
-// +kubebuilder:validation:Enum=token;basic
+// +kubebuilder:validation:Enum=token;basic;static;service-account
 type AuthenticationType string
 
 const (
@@ -67,6 +83,369 @@ const (
 
 	// Token allows upload of data using token authentication.
 	Token AuthenticationType = "token"
+
+	// Static allows upload of data using a static token sent in a request header. It is intended for
+	// on-prem relays and export gateways that do not use cloud.redhat.com authentication.
+	Static AuthenticationType = "static"
+
+	// ServiceAccount allows upload of data using a token issued by SSO for the client ID and secret of a
+	// console service account.
+	ServiceAccount AuthenticationType = "service-account"
+)
+
+// PayloadFormat describes how payloads are sent in an upload request.
+// Only one of the following formats may be specified.
+// If none of the following formats are specified, the default one
+// is multipart.
+// +kubebuilder:validation:Enum=multipart;passthrough
+type PayloadFormat string
+
+const (
+	// MultipartPayload sends the payload as a multipart form file, as expected by the ingress service.
+	MultipartPayload PayloadFormat = "multipart"
+
+	// PassthroughPayload sends the tar.gz payload as the raw request body.
+	PassthroughPayload PayloadFormat = "passthrough"
+)
+
+// PayloadCadence describes the granularity of the packaged payloads.
+// Only one of the following cadences may be specified.
+// If none of the following cadences are specified, the default one
+// is hourly.
+// +kubebuilder:validation:Enum=hourly;daily;both
+type PayloadCadence string
+
+const (
+	// HourlyCadence packages the reports with one row for each hour.
+	HourlyCadence PayloadCadence = "hourly"
+
+	// DailyCadence packages the reports with one row for each day, rolled up from the hourly rows.
+	DailyCadence PayloadCadence = "daily"
+
+	// HourlyAndDailyCadence packages the hourly reports, and a separate payload of the reports rolled up for each complete day.
+	HourlyAndDailyCadence PayloadCadence = "both"
+)
+
+// ExecutionMode describes how the operator collects, packages, and uploads the reports.
+// Only one of the following modes may be specified.
+// If none of the following modes are specified, the default one
+// is controller.
+// +kubebuilder:validation:Enum=controller;cronjob
+type ExecutionMode string
+
+const (
+	// ControllerExecution collects, packages, and uploads the reports in the reconcile loop of the operator, and
+	// stores them on a PVC.
+	ControllerExecution ExecutionMode = "controller"
+
+	// CronJobExecution collects, packages, and uploads the reports in short-lived Jobs scheduled by a CronJob that
+	// the operator manages. The Jobs store the reports on a small PVC of their own, so that payloads that a Job did
+	// not upload are uploaded by the next Job.
+	CronJobExecution ExecutionMode = "cronjob"
+)
+
+// CronJobSpec defines the schedule of the Jobs of the cronjob execution mode.
+type CronJobSpec struct {
+
+	// Schedule is a field of KokuMetricsConfig to represent the cron schedule of the Jobs. The default is
+	// `5 * * * *`, which runs a Job 5 minutes after each hour.
+	// +kubebuilder:default=`5 * * * *`
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// ActiveDeadlineSeconds is a field of KokuMetricsConfig to represent the number of seconds a Job may run
+	// before it is stopped. The default is 1800.
+	// +kubebuilder:validation:Minimum=60
+	// +kubebuilder:default=1800
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"active_deadline_seconds,omitempty"`
+}
+
+// SchedulingSpec defines how the pod of the operator is scheduled and stopped in the KokuMetricsConfigSpec.
+type SchedulingSpec struct {
+
+	// PriorityClassName is a field of KokuMetricsConfig to represent the priority class of the operator pod. The
+	// operator sets it in its own Deployment, which restarts the pod.
+	// +optional
+	PriorityClassName string `json:"priority_class_name,omitempty"`
+
+	// TerminationGracePeriodSeconds is a field of KokuMetricsConfig to represent the number of seconds the operator
+	// pod is given to finish the reconcile in progress when it is stopped, for example by a node drain. The operator
+	// sets it in its own Deployment, which restarts the pod, and waits for the reconcile in progress before it exits.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"termination_grace_period_seconds,omitempty"`
+}
+
+// ResourcesSpec defines the resource hints of the operator in the KokuMetricsConfigSpec. The size selects a bundle of
+// hints, and each hint that is set overrides the hint of the size.
+type ResourcesSpec struct {
+
+	// Size is a field of KokuMetricsConfig to represent the bundle of resource hints the operator is sized for.
+	// Valid values are:
+	// - "small": One query and one compression at a time, with small buffers. Intended for arm64 edge devices.
+	// - "medium": Two queries and two compressions at a time. The default, unless the profile is lightweight.
+	// - "large": Four queries and four compressions at a time, with larger buffers. Intended for clusters with hundreds of nodes.
+	// +optional
+	Size ResourceSize `json:"size,omitempty"`
+
+	// MaxParallelQueries is a field of KokuMetricsConfig to represent the number of prometheus queries that are run at a time.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=16
+	// +optional
+	MaxParallelQueries *int64 `json:"max_parallel_queries,omitempty"`
+
+	// CompressionWorkers is a field of KokuMetricsConfig to represent the number of tar.gz files of a payload that
+	// are compressed at a time.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=8
+	// +optional
+	CompressionWorkers *int64 `json:"compression_workers,omitempty"`
+
+	// LogBufferLines is a field of KokuMetricsConfig to represent the number of recent log lines kept in memory
+	// for the must-gather.
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=20000
+	// +optional
+	LogBufferLines *int64 `json:"log_buffer_lines,omitempty"`
+
+	// CopyBufferKiB is a field of KokuMetricsConfig to represent the size, in KiB, of the buffer that reports are
+	// copied into the tar.gz files with.
+	// +kubebuilder:validation:Minimum=4
+	// +kubebuilder:validation:Maximum=1024
+	// +optional
+	CopyBufferKiB *int64 `json:"copy_buffer_kib,omitempty"`
+}
+
+// TLSVersion describes the minimum TLS version of a connection.
+// Only one of the following versions may be specified.
+// If none of the following versions are specified, the default of the Go TLS client is used.
+// +kubebuilder:validation:Enum=VersionTLS10;VersionTLS11;VersionTLS12;VersionTLS13
+type TLSVersion string
+
+const (
+	// TLSVersion10 allows TLS 1.0 and later.
+	TLSVersion10 TLSVersion = "VersionTLS10"
+
+	// TLSVersion11 allows TLS 1.1 and later.
+	TLSVersion11 TLSVersion = "VersionTLS11"
+
+	// TLSVersion12 allows TLS 1.2 and later.
+	TLSVersion12 TLSVersion = "VersionTLS12"
+
+	// TLSVersion13 only allows TLS 1.3.
+	TLSVersion13 TLSVersion = "VersionTLS13"
+)
+
+// TLSSpec defines the TLS settings of the connections of the operator.
+type TLSSpec struct {
+
+	// MinVersion is a field of KokuMetricsConfig to represent the minimum TLS version that is negotiated.
+	// +optional
+	MinVersion TLSVersion `json:"min_version,omitempty"`
+
+	// CipherSuites is a field of KokuMetricsConfig to represent the cipher suites that are offered for TLS 1.2 and
+	// earlier, by their IANA names, for example `TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256`. The cipher suites of TLS 1.3
+	// are not configurable. When not set, the default cipher suites of the Go TLS client are offered.
+	// +optional
+	CipherSuites []string `json:"cipher_suites,omitempty"`
+}
+
+// CertValidationSpec defines whether the certificates of each cloud.redhat.com endpoint are validated.
+type CertValidationSpec struct {
+
+	// Ingress is a field of KokuMetricsConfig to represent if the certificate of the Ingress endpoint, or of the relay
+	// that payloads are uploaded to, is validated. The certificate is validated unless this is set to false.
+	// +optional
+	Ingress *bool `json:"ingress,omitempty"`
+
+	// Sources is a field of KokuMetricsConfig to represent if the certificate of the Sources API is validated. The
+	// certificate is validated unless this is set to false.
+	// +optional
+	Sources *bool `json:"sources,omitempty"`
+}
+
+// CertValidationStatus defines whether the certificates of each cloud.redhat.com endpoint are validated.
+type CertValidationStatus struct {
+
+	// Ingress is a field of KokuMetricsConfigStatus to represent if the certificate of the Ingress endpoint is validated.
+	Ingress bool `json:"ingress"`
+
+	// Sources is a field of KokuMetricsConfigStatus to represent if the certificate of the Sources API is validated.
+	Sources bool `json:"sources"`
+
+	// SSO is a field of KokuMetricsConfigStatus to represent if the certificate of the SSO token endpoint is validated.
+	// The SSO endpoint is sent the client secret of service accounts, so its certificate is always validated.
+	SSO bool `json:"sso"`
+}
+
+// CSVDelimiter describes the field delimiter of the reports exported to a destination.
+// Only one of the following delimiters may be specified.
+// If none of the following delimiters are specified, the default one
+// is comma.
+// +kubebuilder:validation:Enum=comma;tab
+type CSVDelimiter string
+
+const (
+	// CommaDelimiter separates the fields of the reports with commas, as expected by the Ingress API.
+	CommaDelimiter CSVDelimiter = "comma"
+
+	// TabDelimiter separates the fields of the reports with tabs.
+	TabDelimiter CSVDelimiter = "tab"
+)
+
+// CSVQuoting describes when the fields of the reports exported to a destination are quoted.
+// Only one of the following quoting modes may be specified.
+// If none of the following quoting modes are specified, the default one
+// is minimal.
+// +kubebuilder:validation:Enum=minimal;all
+type CSVQuoting string
+
+const (
+	// MinimalQuoting only quotes fields that contain the delimiter, a quote, or a line break.
+	MinimalQuoting CSVQuoting = "minimal"
+
+	// AllQuoting quotes every field.
+	AllQuoting CSVQuoting = "all"
+)
+
+// OperatorProfile describes the resource footprint of the operator.
+// Only one of the following profiles may be specified.
+// If none of the following profiles are specified, the default one
+// is default.
+// +kubebuilder:validation:Enum=default;lightweight
+type OperatorProfile string
+
+const (
+	// DefaultProfile collects the full query set and reconciles every 5 minutes.
+	DefaultProfile OperatorProfile = "default"
+
+	// LightweightProfile reduces the footprint of the operator for edge clusters such as
+	// Single-node OpenShift and MicroShift. Label queries are skipped, reconciles are less
+	// frequent, and smaller in-memory buffers are used.
+	LightweightProfile OperatorProfile = "lightweight"
+)
+
+// ResourceSize describes the resource hints the operator is sized for.
+// Only one of the following sizes may be specified.
+// If none of the following sizes are specified, the default one
+// is small for the lightweight profile and medium otherwise.
+// +kubebuilder:validation:Enum=small;medium;large
+type ResourceSize string
+
+const (
+	// SmallResources runs one query and one compression at a time with small buffers, for arm64 edge devices
+	// and other constrained nodes.
+	SmallResources ResourceSize = "small"
+
+	// MediumResources runs a few queries and compressions at a time.
+	MediumResources ResourceSize = "medium"
+
+	// LargeResources runs more queries and compressions at a time with larger buffers, for clusters with
+	// hundreds of nodes.
+	LargeResources ResourceSize = "large"
+)
+
+// ClusterType describes who manages the cluster.
+type ClusterType string
+
+const (
+	// SelfManagedCluster is a cluster managed by the customer.
+	SelfManagedCluster ClusterType = "self-managed"
+
+	// ROSACluster is a Red Hat OpenShift Service on AWS cluster.
+	ROSACluster ClusterType = "rosa"
+
+	// OSDCluster is an OpenShift Dedicated cluster.
+	OSDCluster ClusterType = "osd"
+)
+
+// UploadWaitSource describes where the upload wait of the operator comes from.
+type UploadWaitSource string
+
+const (
+	// SpecUploadWait is an upload wait set in the spec.
+	SpecUploadWait UploadWaitSource = "spec"
+
+	// ClusterIDUploadWait is an upload wait derived from the cluster ID, which is the same for every reconcile of the
+	// cluster.
+	ClusterIDUploadWait UploadWaitSource = "cluster_id"
+
+	// RandomUploadWait is a random upload wait, which is used until the cluster ID is known.
+	RandomUploadWait UploadWaitSource = "random"
+)
+
+// APIEnvironment describes the cloud.redhat.com environment that the operator interacts with.
+// Only one of the following environments may be specified.
+// If none of the following environments are specified, the default one
+// is production.
+// +kubebuilder:validation:Enum=production;stage;fedramp
+type APIEnvironment string
+
+const (
+	// ProductionEnvironment is the cloud.redhat.com production environment.
+	ProductionEnvironment APIEnvironment = "production"
+
+	// StageEnvironment is the cloud.redhat.com stage environment.
+	StageEnvironment APIEnvironment = "stage"
+
+	// FedRAMPEnvironment is the FedRAMP environment for government customers.
+	FedRAMPEnvironment APIEnvironment = "fedramp"
+)
+
+// APIEndpoints are the endpoints of an API environment. They must be consistent with each other.
+type APIEndpoints struct {
+	APIURL      string
+	IngressPath string
+	SourcesPath string
+	SSOTokenURL string
+}
+
+// APIEnvironments are the endpoints of each API environment.
+var APIEnvironments = map[APIEnvironment]APIEndpoints{
+	ProductionEnvironment: {
+		APIURL:      DefaultAPIURL,
+		IngressPath: DefaultIngressPath,
+		SourcesPath: DefaultSourcesPath,
+		SSOTokenURL: DefaultSSOTokenURL,
+	},
+	StageEnvironment: {
+		APIURL:      "https://cloud.stage.redhat.com",
+		IngressPath: DefaultIngressPath,
+		SourcesPath: DefaultSourcesPath,
+		SSOTokenURL: "https://sso.stage.redhat.com/auth/realms/redhat-external/protocol/openid-connect/token",
+	},
+	FedRAMPEnvironment: {
+		APIURL:      "https://console.openshiftusgov.com",
+		IngressPath: DefaultIngressPath,
+		SourcesPath: DefaultSourcesPath,
+		SSOTokenURL: "https://sso.openshiftusgov.com/realms/redhat-external/protocol/openid-connect/token",
+	},
+}
+
+// CostModelDistribution describes how the cost of a cluster is distributed to projects.
+// Only one of the following distributions may be specified.
+// +kubebuilder:validation:Enum=cpu;memory
+type CostModelDistribution string
+
+const (
+	// CPUDistribution distributes cost by CPU usage.
+	CPUDistribution CostModelDistribution = "cpu"
+
+	// MemoryDistribution distributes cost by memory usage.
+	MemoryDistribution CostModelDistribution = "memory"
+)
+
+// CostModelCostType describes whether a rate is an infrastructure or supplementary cost.
+// +kubebuilder:validation:Enum=Infrastructure;Supplementary
+type CostModelCostType string
+
+const (
+	// InfrastructureCost rates represent the cost of the underlying infrastructure.
+	InfrastructureCost CostModelCostType = "Infrastructure"
+
+	// SupplementaryCost rates represent additional costs, such as support or licensing.
+	SupplementaryCost CostModelCostType = "Supplementary"
 )
 
 // EmbeddedObjectMetadata contains a subset of the fields included in k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta
@@ -117,21 +496,35 @@ type AuthenticationSpec struct {
 	// Valid values are:
 	// - "basic" : Enables authentication using user and password from authentication secret.
 	// - "token" (default): Uses cluster token for authentication.
+	// - "static" : Sends the token from the authentication secret in the token_header. Intended for on-prem relays.
+	// - "service-account" : Uses a token issued by SSO for the client_id and client_secret from the authentication secret.
 	// +kubebuilder:default="token"
 	AuthType AuthenticationType `json:"type"`
 
+	// Methods is a field of KokuMetricsConfig to represent the ordered list of authentication types that are tried until
+	// the credentials of one are found, for example during a migration from basic to service account authentication.
+	// When set, the type is ignored. The static type cannot be part of the list.
+	// +optional
+	Methods []AuthenticationType `json:"methods,omitempty"`
+
 	// AuthenticationSecretName is a field of KokuMetricsConfig to represent the secret with the user and password used for uploads.
 	// +optional
 	AuthenticationSecretName string `json:"secret_name,omitempty"`
+
+	// TokenHeader is a field of KokuMetricsConfig to represent the request header that the static token is sent in.
+	// The value of the `token` key in the authentication secret is sent verbatim.
+	// The default is `Authorization`.
+	// +optional
+	TokenHeader string `json:"token_header,omitempty"`
 }
 
 // PackagingSpec defines the desired state of the Packaging object in the KokuMetricsConfigSpec.
 type PackagingSpec struct {
 
 	// MaxSize is a field of KokuMetricsConfig to represent the max file size in megabytes that will be compressed for upload to Ingress.
+	// Values larger than the upload limit in `upload.max_size_MB` are clamped to the limit.
 	// The default is 100.
 	// +kubebuilder:validation:Minimum=1
-	// +kubebuilder:validation:Maximum=100
 	// +kubebuilder:default=100
 	MaxSize int64 `json:"max_size_MB"`
 
@@ -140,6 +533,112 @@ type PackagingSpec struct {
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:default=30
 	MaxReports int64 `json:"max_reports_to_store"`
+
+	// PackagingCycle is a field of KokuMetricsConfig to represent the number of minutes between each packaging of the reports.
+	// Packaging runs on its own schedule, and each upload uploads whatever has been packaged.
+	// The default is the upload cycle.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	PackagingCycle *int64 `json:"packaging_cycle,omitempty"`
+
+	// DeltaReports is a field of KokuMetricsConfig to represent if the node and namespace reports are left out of a payload
+	// when they have not changed since they were last sent. The manifest references the payload that includes them.
+	// The default is false.
+	// +optional
+	DeltaReports *bool `json:"delta_reports,omitempty"`
+
+	// SigningKeySecretName is a field of KokuMetricsConfig to represent the secret with the Ed25519 private key that the reports of each payload are signed with.
+	// The secret must be in the operator namespace, and contain the PEM encoded PKCS #8 key in the `private_key` key.
+	// The signature and the fingerprint of the public key are written to the manifest. Payloads are not signed by default.
+	// +optional
+	SigningKeySecretName string `json:"signing_key_secret_name,omitempty"`
+
+	// PayloadCadence is a field of KokuMetricsConfig to represent the granularity of the payloads: `hourly` rows,
+	// `daily` rows rolled up from the hourly rows, or `both` in separate payloads.
+	// The default is hourly.
+	// +optional
+	PayloadCadence PayloadCadence `json:"payload_cadence,omitempty"`
+}
+
+// DestinationSpec defines an additional destination that payloads are exported to.
+type DestinationSpec struct {
+
+	// Name is a field of KokuMetricsConfig to represent the name of the destination in logs and status.
+	Name string `json:"name"`
+
+	// Type is a field of KokuMetricsConfig to represent the kind of destination.
+	// Built-in types are:
+	// - "filesystem": Copies payloads to a directory on the operator's volumes.
+	// - "webhook": Sends payloads as the raw body of a POST request.
+	// - "s3": Writes payloads to an S3 compatible bucket.
+	// - "kafka": Publishes the hourly pod and storage usage records of payloads as JSON messages to a topic.
+	Type string `json:"type"`
+
+	// Path is a field of KokuMetricsConfig to represent the directory for filesystem destinations, or the object key
+	// prefix for s3 destinations.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// URL is a field of KokuMetricsConfig to represent the endpoint for webhook and s3 destinations.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Bucket is a field of KokuMetricsConfig to represent the bucket for s3 destinations.
+	// +optional
+	Bucket string `json:"bucket,omitempty"`
+
+	// Region is a field of KokuMetricsConfig to represent the region for s3 destinations.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Brokers is a field of KokuMetricsConfig to represent the bootstrap brokers, as host:port, for kafka destinations.
+	// +optional
+	Brokers []string `json:"brokers,omitempty"`
+
+	// Topic is a field of KokuMetricsConfig to represent the topic for kafka destinations.
+	// +optional
+	Topic string `json:"topic,omitempty"`
+
+	// SecretName is a field of KokuMetricsConfig to represent the secret with the credentials for the destination.
+	// Webhook destinations use the `token` key. S3 destinations use the `access_key_id` and `secret_access_key` keys.
+	// Kafka destinations use the optional `username` and `password` keys for SASL/PLAIN authentication, and the
+	// optional `ca.crt`, `tls.crt` and `tls.key` keys to connect with TLS.
+	// +optional
+	SecretName string `json:"secret_name,omitempty"`
+
+	// CSVDelimiter is a field of KokuMetricsConfig to represent the field delimiter of the reports in the payloads
+	// exported to the destination: `comma` or `tab`. The payloads uploaded to ingress always use commas.
+	// The default is comma.
+	// +optional
+	CSVDelimiter CSVDelimiter `json:"csv_delimiter,omitempty"`
+
+	// CSVQuoting is a field of KokuMetricsConfig to represent when the fields of the reports in the payloads exported
+	// to the destination are quoted: `minimal` only quotes fields that need it, and `all` quotes every field.
+	// The default is minimal.
+	// +optional
+	CSVQuoting CSVQuoting `json:"csv_quoting,omitempty"`
+}
+
+// UploadHeader defines an additional HTTP header of the requests to cloud.redhat.com in the UploadSpec.
+type UploadHeader struct {
+
+	// Name is a field of KokuMetricsConfig to represent the name of the header. The headers that the operator sets,
+	// such as Authorization, Content-Type and User-Agent, cannot be overridden.
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9-]+$`
+	Name string `json:"name"`
+
+	// Value is a field of KokuMetricsConfig to represent the value of the header.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// SecretName is a field of KokuMetricsConfig to represent the secret that the value of a sensitive header is read
+	// from, instead of value. The value of a secret-backed header is masked in the logs.
+	// +optional
+	SecretName string `json:"secret_name,omitempty"`
+
+	// SecretKey is a field of KokuMetricsConfig to represent the key of the value in the secret.
+	// +optional
+	SecretKey string `json:"secret_key,omitempty"`
 }
 
 // UploadSpec defines the desired state of Authentication object in the KokuMetricsConfigSpec.
@@ -151,7 +650,9 @@ type UploadSpec struct {
 	// +kubebuilder:default=`/api/ingress/v1/upload`
 	IngressAPIPath string `json:"ingress_path"`
 
-	// UploadWait is a field of KokuMetricsConfig to represent the time to wait before sending an upload.
+	// UploadWait is a field of KokuMetricsConfig to represent the number of seconds to wait before sending an upload.
+	// Set to 0 to upload as soon as the upload cycle is due. If unset, a random wait of up to 34 seconds is used so
+	// that clusters do not all upload at the same time.
 	// +optional
 	// +kubebuilder:validation:Minimum=0
 	UploadWait *int64 `json:"upload_wait,omitempty"`
@@ -162,6 +663,33 @@ type UploadSpec struct {
 	// +kubebuilder:default=360
 	UploadCycle *int64 `json:"upload_cycle"`
 
+	// MaxSize is a field of KokuMetricsConfig to represent the largest report size in megabytes accepted by the upload
+	// endpoint. The packaging max size is clamped to this limit. The default is 100, the limit of the Ingress API.
+	// Relays that accept larger payloads can raise the limit.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxSize *int64 `json:"max_size_MB,omitempty"`
+
+	// UploadInterval is a field of KokuMetricsConfig to represent the minimum number of seconds between two payload uploads.
+	// The default is 5 seconds.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	UploadInterval *int64 `json:"upload_interval,omitempty"`
+
+	// MaxBackfillUploads is a field of KokuMetricsConfig to represent the maximum number of backfill payloads uploaded
+	// each upload cycle. Backfill payloads are re-collected payloads and payloads that have waited for more than a day.
+	// Recent payloads are always uploaded first. The default is 24.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxBackfillUploads *int64 `json:"max_backfill_uploads,omitempty"`
+
+	// QuarantineAfterRejections is a field of KokuMetricsConfig to represent the number of times ingress may reject a
+	// payload with a 4xx status before the payload is moved to the quarantine directory of the report volume, where it
+	// waits for review instead of being retried. The default is 3. Set it to 0 to retry rejected payloads forever.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	QuarantineAfterRejections *int64 `json:"quarantine_after_rejections,omitempty"`
+
 	// UploadToggle is a field of KokuMetricsConfig to represent if the operator is installed in a restricted-network.
 	// If `false`, the operator will not upload to cloud.redhat.com or check/create sources.
 	// The default is true.
@@ -169,8 +697,66 @@ type UploadSpec struct {
 	UploadToggle *bool `json:"upload_toggle"`
 
 	// ValidateCert is a field of KokuMetricsConfig to represent if the Ingress endpoint must be certificate validated.
+	// Certificates are validated against the system CAs whatever its value. To skip the validation of an endpoint, use
+	// cert_validation.
 	// +kubebuilder:default=true
 	ValidateCert *bool `json:"validate_cert"`
+
+	// PayloadFormat is a field of KokuMetricsConfig to represent how payloads are sent to the upload endpoint.
+	// Valid values are:
+	// - "multipart" (default): Sends the payload as a multipart form file, as expected by the Ingress API.
+	// - "passthrough": Sends the tar.gz payload as the raw request body. Intended for on-prem relays.
+	// +kubebuilder:default="multipart"
+	// +optional
+	PayloadFormat PayloadFormat `json:"payload_format,omitempty"`
+
+	// IPFamily is a field of KokuMetricsConfig to represent the address family that is tried first when connecting to
+	// the upload endpoint. The other family is tried if the preferred family does not connect within 300ms.
+	// Valid values are:
+	// - "ipv4": Prefers IPv4 addresses.
+	// - "ipv6": Prefers IPv6 addresses. Intended for IPv6-only and IPv6-primary dual-stack clusters.
+	// When not set, the order returned by the resolver is used.
+	// +kubebuilder:validation:Enum=ipv4;ipv6
+	// +optional
+	IPFamily string `json:"ip_family,omitempty"`
+
+	// DNSServer is a field of KokuMetricsConfig to represent the address, as host:port, of a DNS server that resolves
+	// the upload endpoint instead of the cluster resolver.
+	// +optional
+	DNSServer string `json:"dns_server,omitempty"`
+
+	// TLS is a field of KokuMetricsConfig to represent the TLS settings of the connections to cloud.redhat.com, for
+	// uploads and for the sources API.
+	// +optional
+	TLS TLSSpec `json:"tls,omitempty"`
+
+	// CertValidation is a field of KokuMetricsConfig to represent if the certificates of the Ingress endpoint and of
+	// the Sources API are validated, for relays that are reached without a trusted certificate. The certificate of the
+	// SSO token endpoint is always validated.
+	// +optional
+	CertValidation CertValidationSpec `json:"cert_validation,omitempty"`
+
+	// ExtraHeaders is a field of KokuMetricsConfig to represent additional HTTP headers, such as the organization or
+	// routing headers required by a relay gateway, that are added to the requests to cloud.redhat.com.
+	// +optional
+	ExtraHeaders []UploadHeader `json:"extra_headers,omitempty"`
+
+	// FallbackAPIURLs is a field of KokuMetricsConfig to represent the API URLs, for example of a relay, that payloads
+	// are uploaded to when the api_url is unreachable. The endpoints are tried in order.
+	// +optional
+	FallbackAPIURLs []string `json:"fallback_api_urls,omitempty"`
+
+	// FailoverThreshold is a field of KokuMetricsConfig to represent the number of consecutive upload cycles that the
+	// active API URL must be unreachable before uploads fail over to the next endpoint. The default is 3.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	FailoverThreshold *int64 `json:"failover_threshold,omitempty"`
+
+	// Destinations is a field of KokuMetricsConfig to represent additional destinations payloads are exported to.
+	// Payloads are exported on the upload_cycle, and are removed once every destination has accepted them.
+	// Destinations are used even if upload_toggle is `false`.
+	// +optional
+	Destinations []DestinationSpec `json:"destinations,omitempty"`
 }
 
 // PrometheusSpec defines the desired state of PrometheusConfig object in the KokuMetricsConfigSpec.
@@ -187,6 +773,114 @@ type PrometheusSpec struct {
 	// The default is false.
 	// +kubebuilder:default=false
 	SkipTLSVerification *bool `json:"skip_tls_verification"`
+
+	// ShardAddresses is a field of KokuMetricsConfig to represent the addresses of the Prometheus shards of clusters that
+	// shard Prometheus. The service_address must then be a querier that federates the shards, such as Thanos Query, since
+	// the queries join and aggregate series across shards. The reports are queried from the service_address, and a window
+	// is only collected when every shard answers the connection test.
+	// +optional
+	ShardAddresses []string `json:"shard_addresses,omitempty"`
+
+	// FallbackAddress is a field of KokuMetricsConfig to represent the address of a Prometheus, such as
+	// `https://prometheus-k8s.openshift-monitoring.svc:9091`, that is queried when the service_address cannot be
+	// queried, for example while the monitoring stack is upgraded. The service_address is tried again on each reconcile.
+	// Sharded Prometheus does not fall back.
+	// +optional
+	FallbackAddress string `json:"fallback_address,omitempty"`
+
+	// TLS is a field of KokuMetricsConfig to represent the TLS settings of the connections to Prometheus.
+	// +optional
+	TLS TLSSpec `json:"tls,omitempty"`
+
+	// ExtraSelectors is a field of KokuMetricsConfig to represent the label matchers, such as `cluster="name"`, that
+	// are added to every query. Set them when the service_address is a multi-cluster Thanos that requires the
+	// queries to be scoped to a single cluster.
+	// +optional
+	ExtraSelectors []string `json:"extra_selectors,omitempty"`
+
+	// ManageMonitoringBinding is a field of KokuMetricsConfig to represent if the operator creates and repairs the
+	// ClusterRoleBinding that grants its service account the cluster-monitoring-view role. The operator must be
+	// permitted to manage ClusterRoleBindings and to bind the cluster-monitoring-view role.
+	// The default is false.
+	// +optional
+	ManageMonitoringBinding *bool `json:"manage_monitoring_binding,omitempty"`
+
+	// AdaptiveStep is a field of KokuMetricsConfig to represent the thresholds that coarsen the query step of the
+	// following windows when a collection uses too much memory or takes too long.
+	// +optional
+	AdaptiveStep AdaptiveStepSpec `json:"adaptive_step,omitempty"`
+
+	// PinnedQueryHash is a field of KokuMetricsConfig to represent the hash of the reviewed query allow-list. When it
+	// is set, no query is run while the hash of the queries the operator would run differs from it, so that a change
+	// to the collected data is reviewed before it takes effect.
+	// +optional
+	PinnedQueryHash string `json:"pinned_query_hash,omitempty"`
+
+	// QueryOverrides is a field of KokuMetricsConfig to represent the PromQL that replaces the default query of a
+	// report column, keyed by the column, such as `pod_usage_cpu_core_seconds`, or by the name of the query. Use it to
+	// patch a single query on clusters with nonstandard recording rules. The replacement must return the same labels
+	// as the default query.
+	// +optional
+	QueryOverrides map[string]string `json:"query_overrides,omitempty"`
+
+	// Snapshot is a field of KokuMetricsConfig to represent a temporary Prometheus, such as one restored from a
+	// snapshot or a backup, that a past range is re-collected from. Use it after a monitoring outage that is longer
+	// than the retention of the service_address.
+	// +optional
+	Snapshot SnapshotSpec `json:"snapshot,omitempty"`
+}
+
+// SnapshotSpec defines a temporary Prometheus and the range that is re-collected from it in the PrometheusSpec. The
+// range is re-collected once each time the address or the range changes, and the regular collection continues to
+// query the service_address.
+type SnapshotSpec struct {
+
+	// ServiceAddress is a field of KokuMetricsConfig to represent the address of the snapshot Prometheus. It is queried
+	// with the token and CA of the service_address.
+	// +optional
+	ServiceAddress string `json:"service_address,omitempty"`
+
+	// SkipTLSVerification is a field of KokuMetricsConfig to represent if the certificate of the snapshot Prometheus
+	// is not validated. The default is false.
+	// +optional
+	SkipTLSVerification *bool `json:"skip_tls_verification,omitempty"`
+
+	// Range is a field of KokuMetricsConfig to represent the range that is re-collected from the snapshot Prometheus,
+	// as `<start>/<end>`, like the value of the recollect annotation. The reports of the range are overwritten.
+	// +optional
+	Range string `json:"range,omitempty"`
+}
+
+// AdaptiveStepSpec defines the thresholds of the adaptive query step in the PrometheusSpec.
+type AdaptiveStepSpec struct {
+
+	// Enabled is a field of KokuMetricsConfig to represent if the query step is coarsened, from 1m to 2m to 5m, when
+	// a collection exceeds a threshold. Disabling it resets the query step to 1m.
+	// The default is false.
+	// +kubebuilder:default=false
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// MaxDurationSeconds is a field of KokuMetricsConfig to represent the number of seconds a collection may take
+	// before the query step is coarsened. The default is 300.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=300
+	// +optional
+	MaxDurationSeconds *int64 `json:"max_duration_seconds,omitempty"`
+
+	// MaxMemoryMB is a field of KokuMetricsConfig to represent the memory, in megabytes, the operator may use during a
+	// collection before the query step is coarsened. The default is 400.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=400
+	// +optional
+	MaxMemoryMB *int64 `json:"max_memory_mb,omitempty"`
+
+	// RecoveryCollections is a field of KokuMetricsConfig to represent the number of consecutive collections within
+	// the thresholds after which a coarsened query step is made finer again, one step at a time. The default is 24.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=24
+	// +optional
+	RecoveryCollections *int64 `json:"recovery_collections,omitempty"`
 }
 
 // CloudDotRedHatSourceSpec defines the desired state of CloudDotRedHatSource object in the KokuMetricsConfigSpec.
@@ -199,6 +893,7 @@ type CloudDotRedHatSourceSpec struct {
 	SourcesAPIPath string `json:"sources_path"`
 
 	// SourceName is a field of KokuMetricsConfigSpec to represent the source name on cloud.redhat.com.
+	// If not set, the display name of the cluster in OpenShift Cluster Manager is used.
 	// +optional
 	SourceName string `json:"name,omitempty"`
 
@@ -213,12 +908,310 @@ type CloudDotRedHatSourceSpec struct {
 	CheckCycle *int64 `json:"check_cycle"`
 }
 
-// KokuMetricsConfigSpec defines the desired state of KokuMetricsConfig.
-type KokuMetricsConfigSpec struct {
-	// +kubebuilder:validation:preserveUnknownFields=false
+// HubSpec defines the desired state of hub aggregation in the KokuMetricsConfigSpec.
+type HubSpec struct {
 
-	// ClusterID is a field of KokuMetricsConfig to represent the cluster UUID. Normally this value should not be
-	// specified. Only set this value if the clusterID cannot be obtained from the ClusterVersion.
+	// Enabled is a field of KokuMetricsConfig to represent if the operator accepts payloads uploaded by spoke clusters.
+	// Received payloads are forwarded to cloud.redhat.com with the operator's own uploads, so only the hub needs egress.
+	// The default is false.
+	// +kubebuilder:default=false
+	Enabled *bool `json:"enabled"`
+
+	// SecretName is a field of KokuMetricsConfig to represent the secret with the user and password spoke clusters
+	// use to authenticate to the hub.
+	// +optional
+	SecretName string `json:"secret_name,omitempty"`
+}
+
+// CostModelRate defines a rate in the CostModelSpec.
+type CostModelRate struct {
+
+	// Metric is a field of KokuMetricsConfig to represent the metric the rate is applied to.
+	// +kubebuilder:validation:Enum=cpu_core_usage_per_hour;cpu_core_request_per_hour;memory_gb_usage_per_hour;memory_gb_request_per_hour;storage_gb_usage_per_month;storage_gb_request_per_month;node_cost_per_month;cluster_cost_per_month
+	Metric string `json:"metric"`
+
+	// Value is a field of KokuMetricsConfig to represent the rate, as a decimal, in the currency of the cost model.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	Value string `json:"value"`
+
+	// CostType is a field of KokuMetricsConfig to represent if the rate is an infrastructure or supplementary cost.
+	// The default is `Supplementary`.
+	// +kubebuilder:default=Supplementary
+	// +optional
+	CostType CostModelCostType `json:"cost_type,omitempty"`
+}
+
+// CostModelSpec defines the cost model hints in the KokuMetricsConfigSpec. The hints are written to the manifest of
+// each payload so that a cost model can be created and associated with the source by cost management.
+type CostModelSpec struct {
+
+	// Name is a field of KokuMetricsConfig to represent the name of the cost model. Clusters with the same name share
+	// a cost model.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Currency is a field of KokuMetricsConfig to represent the ISO 4217 currency code of the rates.
+	// +kubebuilder:validation:Pattern=`^[A-Z]{3}$`
+	// +optional
+	Currency string `json:"currency,omitempty"`
+
+	// Rates is a field of KokuMetricsConfig to represent the default rates of the cost model.
+	// +optional
+	Rates []CostModelRate `json:"rates,omitempty"`
+
+	// Distribution is a field of KokuMetricsConfig to represent how the cost of the cluster is distributed to projects.
+	// Valid values are:
+	// - "cpu": Distributes cost by CPU usage.
+	// - "memory": Distributes cost by memory usage.
+	// +optional
+	Distribution CostModelDistribution `json:"distribution,omitempty"`
+
+	// Markup is a field of KokuMetricsConfig to represent the percentage, as a decimal, added to or subtracted from the
+	// infrastructure cost of the cluster.
+	// +kubebuilder:validation:Pattern=`^-?[0-9]+(\.[0-9]+)?$`
+	// +optional
+	Markup string `json:"markup,omitempty"`
+}
+
+// ReportFiltersSpec defines how the collected data is filtered and partitioned in the KokuMetricsConfigSpec.
+type ReportFiltersSpec struct {
+
+	// TenantLabel is a field of KokuMetricsConfig to represent the namespace label used to partition reports by
+	// tenant. The pod, storage, and namespace rows of namespaces with the label are written to a separate report set
+	// for each label value, and each report set is packaged into its own payloads. Namespaces without the label are
+	// reported with the cluster.
+	// +optional
+	TenantLabel string `json:"tenant_label,omitempty"`
+
+	// AnnotationPrefixes is a field of KokuMetricsConfig to represent the prefixes of the node, pod, and namespace
+	// annotations that are collected. Matching annotations are written to the annotations column of each report.
+	// Annotations are only available when kube-state-metrics is configured to expose them.
+	// +optional
+	AnnotationPrefixes []string `json:"annotation_prefixes,omitempty"`
+
+	// ExpectedPodLabels is a field of KokuMetricsConfig to represent the pod labels that chargeback depends on, such
+	// as `app` or `cost-center`. When none of the pods have one of the labels in `kube_pod_labels`, for example because
+	// it is not in the kube-state-metrics label allowlist, the Degraded condition is set with the missing labels.
+	// +optional
+	ExpectedPodLabels []string `json:"expected_pod_labels,omitempty"`
+
+	// MaxLabelValueLength is a field of KokuMetricsConfig to represent the length, in bytes, that label and
+	// annotation values are truncated to before they are written to the reports.
+	// The default is 1024.
+	// +kubebuilder:validation:Minimum=16
+	// +kubebuilder:validation:Maximum=65536
+	// +optional
+	MaxLabelValueLength *int64 `json:"max_label_value_length,omitempty"`
+}
+
+// ReportGranularity describes the level that usage is reported at.
+// Only one of the following granularities may be specified.
+// If none of the following granularities are specified, the default one
+// is pod.
+// +kubebuilder:validation:Enum=pod;namespace
+type ReportGranularity string
+
+const (
+	// PodGranularity reports usage for each pod and persistent volume claim.
+	PodGranularity ReportGranularity = "pod"
+
+	// NamespaceGranularity reports usage aggregated to each namespace, without pod or workload names.
+	NamespaceGranularity ReportGranularity = "namespace"
+)
+
+// ReportingSpec defines how report windows and billing periods are aligned in the KokuMetricsConfigSpec.
+type ReportingSpec struct {
+
+	// BillingTimezone is a field of KokuMetricsConfig to represent the IANA time zone, such as `America/New_York`,
+	// that hourly report windows, billing periods, and manifest dates are aligned to. The default is `UTC`.
+	// +kubebuilder:default=UTC
+	// +optional
+	BillingTimezone string `json:"billing_timezone,omitempty"`
+
+	// PeriodStartDay is a field of KokuMetricsConfig to represent the day of the month that billing periods start on.
+	// The default is 1.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=28
+	// +kubebuilder:default=1
+	// +optional
+	PeriodStartDay *int64 `json:"period_start_day,omitempty"`
+
+	// Granularity is a field of KokuMetricsConfig to represent the level that usage is reported at. With `namespace`,
+	// the pod, storage, and custom metric rows are aggregated to each namespace before the reports are written, and
+	// pod, persistent volume claim, and persistent volume names and labels are left out. The default is `pod`.
+	// +kubebuilder:default=pod
+	// +optional
+	Granularity ReportGranularity `json:"granularity,omitempty"`
+
+	// DataResidency is a field of KokuMetricsConfig to represent the region that the data of the cluster must be
+	// processed in.
+	// +optional
+	DataResidency DataResidencySpec `json:"data_residency,omitempty"`
+
+	// BlackoutWindows is a field of KokuMetricsConfig to represent the periods, such as the maintenance of the
+	// monitoring stack, during which reports are not collected or uploaded. The hours that are missed are back-filled
+	// once the window ends.
+	// +optional
+	BlackoutWindows []BlackoutWindow `json:"blackout_windows,omitempty"`
+
+	// UpgradeDeferral is a field of KokuMetricsConfig to represent if reports are collected and uploaded while the
+	// cluster is upgrading.
+	// +optional
+	UpgradeDeferral UpgradeDeferralSpec `json:"upgrade_deferral,omitempty"`
+}
+
+// UpgradeDeferralSpec defines how collection is deferred while the cluster is upgrading in the ReportingSpec. While
+// the ClusterVersion is progressing, the Prometheus pods restart, so the hours collected during an upgrade may be
+// missing data. The hours that are deferred are back-filled once the upgrade completes.
+type UpgradeDeferralSpec struct {
+
+	// Enabled is a field of KokuMetricsConfig to represent if collection is deferred while the cluster is upgrading.
+	// The default is true.
+	// +kubebuilder:default=true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// DeferUpload is a field of KokuMetricsConfig to represent if payloads are also not uploaded while collection is
+	// deferred. The default is true.
+	// +kubebuilder:default=true
+	// +optional
+	DeferUpload *bool `json:"defer_upload,omitempty"`
+
+	// SettleMinutes is a field of KokuMetricsConfig to represent how long collection is still deferred after the
+	// upgrade completes, so that Prometheus is ready before the missed hours are back-filled. The default is 15.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=240
+	// +kubebuilder:default=15
+	// +optional
+	SettleMinutes *int64 `json:"settle_minutes,omitempty"`
+
+	// MaxHours is a field of KokuMetricsConfig to represent the longest that collection is deferred for an upgrade,
+	// including the settle time. After it, collection resumes even if the upgrade is still in progress or the
+	// ClusterVersion cannot be read, so that the deferred hours are back-filled well before Prometheus drops them.
+	// The default is 12.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=72
+	// +kubebuilder:default=12
+	// +optional
+	MaxHours *int64 `json:"max_hours,omitempty"`
+}
+
+// BlackoutWindow defines a period during which reports are not collected or uploaded in the ReportingSpec. A window
+// is either recurring, with a schedule and a duration, or one-time, with a start and an end.
+type BlackoutWindow struct {
+
+	// Name is a field of KokuMetricsConfig to represent the name of the window in the status and events.
+	Name string `json:"name"`
+
+	// Schedule is a field of KokuMetricsConfig to represent the cron schedule, in UTC, of the start of a recurring
+	// window, such as `0 2 * * 6` for 02:00 every Saturday.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// DurationMinutes is a field of KokuMetricsConfig to represent the length of a recurring window. The default is 60.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10080
+	// +kubebuilder:default=60
+	// +optional
+	DurationMinutes *int64 `json:"duration_minutes,omitempty"`
+
+	// Start is a field of KokuMetricsConfig to represent the start of a one-time window.
+	// +optional
+	Start *metav1.Time `json:"start,omitempty"`
+
+	// End is a field of KokuMetricsConfig to represent the end of a one-time window.
+	// +optional
+	End *metav1.Time `json:"end,omitempty"`
+}
+
+// DataResidencySpec defines the region that the data of the cluster is tagged with and routed to in the ReportingSpec.
+type DataResidencySpec struct {
+
+	// Region is a field of KokuMetricsConfig to represent the data residency region, such as `eu`, that is written to
+	// the manifest of each payload.
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9-]*$`
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// IngressAPIURL is a field of KokuMetricsConfig to represent the API URL of the region-specific processing
+	// endpoints that payloads are uploaded to instead of the api_url. When it is set, the fallback_api_urls are not
+	// used, so that payloads are only uploaded to the region.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	IngressAPIURL string `json:"ingress_api_url,omitempty"`
+}
+
+// CustomMetricAggregation describes how the samples of a custom metric are combined over the hour.
+// +kubebuilder:validation:Enum=sum;max
+type CustomMetricAggregation string
+
+const (
+	// CustomMetricSum sums the samples over the hour.
+	CustomMetricSum CustomMetricAggregation = "sum"
+
+	// CustomMetricMax reports the largest sample over the hour.
+	CustomMetricMax CustomMetricAggregation = "max"
+)
+
+// CustomMetricQuery defines a query for a custom metric in the CustomMetricsSpec.
+type CustomMetricQuery struct {
+
+	// Name is a field of KokuMetricsConfig to represent the name of the custom metric in the custom usage report.
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9_]*$`
+	Name string `json:"name"`
+
+	// Query is a field of KokuMetricsConfig to represent the PromQL query for the custom metric. Series without a
+	// `namespace` label are ignored, and series are reported per `namespace` and `pod` label.
+	Query string `json:"query"`
+
+	// Aggregation is a field of KokuMetricsConfig to represent how the samples are combined over the hour.
+	// The default is `sum`.
+	// +kubebuilder:default=sum
+	// +optional
+	Aggregation CustomMetricAggregation `json:"aggregation,omitempty"`
+}
+
+// CustomMetricsSpec defines the custom metrics queried from the user workload monitoring prometheus in the KokuMetricsConfigSpec.
+type CustomMetricsSpec struct {
+
+	// SvcAddress is a field of KokuMetricsConfig to represent the address of the user workload monitoring prometheus.
+	// The default is `https://prometheus-user-workload.openshift-user-workload-monitoring.svc:9091`.
+	// +kubebuilder:default=`https://prometheus-user-workload.openshift-user-workload-monitoring.svc:9091`
+	SvcAddress string `json:"service_address,omitempty"`
+
+	// SkipTLSVerification is a field of KokuMetricsConfig to represent if the user workload monitoring prometheus
+	// endpoint must be certificate validated. The default is false.
+	// +kubebuilder:default=false
+	// +optional
+	SkipTLSVerification *bool `json:"skip_tls_verification,omitempty"`
+
+	// Queries is a field of KokuMetricsConfig to represent the custom metrics that are written to the custom usage report.
+	Queries []CustomMetricQuery `json:"queries"`
+}
+
+// CostEstimationSpec defines the local cost estimation in the KokuMetricsConfigSpec.
+type CostEstimationSpec struct {
+
+	// RateCardConfigMap is a field of KokuMetricsConfig to represent the ConfigMap containing the rate card used to
+	// estimate cost. Each key is a rate metric and each value is the rate as a decimal. The supported keys are
+	// `cpu_core_usage_per_hour`, `cpu_core_request_per_hour`, `memory_gb_usage_per_hour`, `memory_gb_request_per_hour`
+	// and `currency`.
+	RateCardConfigMap string `json:"rate_card_config_map"`
+
+	// ExposeMetrics is a field of KokuMetricsConfig to represent if the estimated cost of each namespace over the last
+	// 24 hours is exposed on the metrics endpoint.
+	// The default is false.
+	// +kubebuilder:default=false
+	// +optional
+	ExposeMetrics *bool `json:"expose_metrics,omitempty"`
+}
+
+// KokuMetricsConfigSpec defines the desired state of KokuMetricsConfig.
+type KokuMetricsConfigSpec struct {
+	// +kubebuilder:validation:preserveUnknownFields=false
+
+	// ClusterID is a field of KokuMetricsConfig to represent the cluster UUID. Normally this value should not be
+	// specified. Only set this value if the clusterID cannot be obtained from the ClusterVersion.
 	// +optional
 	ClusterID string `json:"clusterID,omitempty"`
 
@@ -228,6 +1221,16 @@ type KokuMetricsConfigSpec struct {
 	// +kubebuilder:default=`https://cloud.redhat.com`
 	APIURL string `json:"api_url,omitempty"`
 
+	// APIEnvironment is a field of KokuMetricsConfig to represent the environment the operator interacts with.
+	// The environment sets the API URL, ingress path, sources path, and SSO token URL together. An API URL, ingress path,
+	// or sources path set to a value other than its default overrides the value of the environment.
+	// Valid values are:
+	// - "production" (default): cloud.redhat.com.
+	// - "stage": the cloud.redhat.com stage environment.
+	// - "fedramp": the FedRAMP environment for government customers.
+	// +optional
+	APIEnvironment APIEnvironment `json:"api_environment,omitempty"`
+
 	// Authentication is a field of KokuMetricsConfig to represent the authentication object.
 	Authentication AuthenticationSpec `json:"authentication"`
 
@@ -245,17 +1248,116 @@ type KokuMetricsConfigSpec struct {
 
 	// VolumeClaimTemplate is a field of KokuMetricsConfig to represent a PVC template.
 	VolumeClaimTemplate *EmbeddedPersistentVolumeClaim `json:"volume_claim_template,omitempty"`
+
+	// Profile is a field of KokuMetricsConfig to represent the resource footprint of the operator.
+	// Valid values are:
+	// - "default" (default): Collects the full query set and reconciles every 5 minutes.
+	// - "lightweight": Skips label queries, reconciles every 15 minutes, and uses smaller buffers. Intended for edge clusters.
+	// +kubebuilder:default="default"
+	// +optional
+	Profile OperatorProfile `json:"profile,omitempty"`
+
+	// Hub is a field of KokuMetricsConfig to represent the configuration of hub aggregation for spoke clusters.
+	// +optional
+	Hub HubSpec `json:"hub,omitempty"`
+
+	// UseEmptyDir is a field of KokuMetricsConfig to represent if reports should be stored on the operator's EmptyDir
+	// volume instead of a PVC. Reports that have not been uploaded are lost when the operator pod restarts.
+	// This field is only honored by the lightweight profile.
+	// +optional
+	UseEmptyDir *bool `json:"use_empty_dir,omitempty"`
+
+	// DryRun is a field of KokuMetricsConfig to represent if the operator only evaluates report generation. Reports are
+	// generated into a scratch directory, validated, summarized in the status, and deleted. Nothing is packaged or
+	// uploaded. The default is false.
+	// +optional
+	DryRun *bool `json:"dry_run,omitempty"`
+
+	// ExecutionMode is a field of KokuMetricsConfig to represent how reports are collected, packaged, and uploaded.
+	// Valid values are:
+	// - "controller" (default): in the reconcile loop of the operator, with the reports stored on a PVC.
+	// - "cronjob": in short-lived Jobs scheduled by a CronJob that the operator manages, with the reports stored on a
+	//   small PVC of the Jobs.
+	// +kubebuilder:default="controller"
+	// +optional
+	ExecutionMode ExecutionMode `json:"execution_mode,omitempty"`
+
+	// CronJob is a field of KokuMetricsConfig to represent the schedule of the Jobs of the cronjob execution mode.
+	// +optional
+	CronJob CronJobSpec `json:"cronjob,omitempty"`
+
+	// Scheduling is a field of KokuMetricsConfig to represent the priority class and termination grace period of the operator pod.
+	// +optional
+	Scheduling SchedulingSpec `json:"scheduling,omitempty"`
+
+	// Resources is a field of KokuMetricsConfig to represent the resource hints of the operator: how many queries and
+	// compressions run at a time, and the size of its buffers.
+	// +optional
+	Resources ResourcesSpec `json:"resources,omitempty"`
+
+	// PayloadAudit is a field of KokuMetricsConfig to represent the configuration of the KokuMetricsPayload records of
+	// uploaded payloads.
+	// +optional
+	PayloadAudit PayloadAuditSpec `json:"payload_audit,omitempty"`
+
+	// ProcessingStatus is a field of KokuMetricsConfig to represent the configuration of the poller of the cost
+	// management report status API, which records whether the uploaded payloads were processed.
+	// +optional
+	ProcessingStatus ProcessingStatusSpec `json:"processing_status,omitempty"`
+
+	// CostModel is a field of KokuMetricsConfig to represent the cost model hints written to each payload.
+	// +optional
+	CostModel *CostModelSpec `json:"cost_model,omitempty"`
+
+	// CostEstimation is a field of KokuMetricsConfig to represent the configuration of local cost estimation. When set,
+	// the collected usage is multiplied by the rate card and an estimated cost report is written to the PVC.
+	// +optional
+	CostEstimation *CostEstimationSpec `json:"cost_estimation,omitempty"`
+
+	// ReportFilters is a field of KokuMetricsConfig to represent how the collected data is filtered and partitioned.
+	// +optional
+	ReportFilters ReportFiltersSpec `json:"report_filters,omitempty"`
+
+	// CustomMetrics is a field of KokuMetricsConfig to represent the custom metrics queried from the user workload
+	// monitoring prometheus. When set, a supplementary custom usage report is generated each hour.
+	// +optional
+	CustomMetrics *CustomMetricsSpec `json:"custom_metrics,omitempty"`
+
+	// Reporting is a field of KokuMetricsConfig to represent how report windows and billing periods are aligned.
+	// +optional
+	Reporting ReportingSpec `json:"reporting,omitempty"`
 }
 
 // AuthenticationStatus defines the desired state of Authentication object in the KokuMetricsConfigStatus.
 type AuthenticationStatus struct {
 
 	// AuthType is a field of KokuMetricsConfig to represent the authentication type to be used basic or token.
+	// When a list of methods is set, it is the first method whose credentials were found.
 	AuthType AuthenticationType `json:"type,omitempty"`
 
+	// Methods is a field of KokuMetricsConfigStatus to represent the ordered list of authentication types that are tried.
+	Methods []AuthenticationType `json:"methods,omitempty"`
+
+	// FallbackErrors is a field of KokuMetricsConfigStatus to represent why the methods before the authentication type in use failed.
+	FallbackErrors []string `json:"fallback_errors,omitempty"`
+
+	// RejectedMethods is a field of KokuMetricsConfigStatus to represent the methods whose credentials were rejected with
+	// a 401 or 403 by an upload or by the credential validation. They are skipped until every method has been rejected.
+	RejectedMethods []AuthenticationType `json:"rejected_methods,omitempty"`
+
+	// SucceededMethod is a field of KokuMetricsConfigStatus to represent the authentication type whose credentials were
+	// last accepted by an upload or by the credential validation.
+	SucceededMethod AuthenticationType `json:"succeeded_method,omitempty"`
+
 	// AuthenticationSecretName is a field of KokuMetricsConfig to represent the secret with the user and password used for uploads.
 	AuthenticationSecretName string `json:"secret_name,omitempty"`
 
+	// TokenHeader is a field of KokuMetricsConfig to represent the request header that the static token is sent in.
+	TokenHeader string `json:"token_header,omitempty"`
+
+	// SSOTokenURL is a field of KokuMetricsConfig to represent the SSO endpoint of the API environment that issues tokens.
+	SSOTokenURL string `json:"sso_token_url,omitempty"`
+
 	// AuthenticationCredentialsFound is a field of KokuMetricsConfig to represent if used for uploads were found.
 	AuthenticationCredentialsFound *bool `json:"credentials_found,omitempty"`
 
@@ -281,18 +1383,65 @@ type PackagingStatus struct {
 	MaxReports *int64 `json:"max_reports_to_store,omitempty"`
 
 	// MaxSize is a field of KokuMetricsConfig to represent the max file size in megabytes that will be compressed for upload to Ingress.
+	// This is the effective size after the spec value is clamped to the upload limit.
 	MaxSize *int64 `json:"max_size_MB,omitempty"`
 
+	// MaxSizeLimit is a field of KokuMetricsConfigStatus to represent the largest report size in megabytes accepted by the upload endpoint.
+	MaxSizeLimit *int64 `json:"max_size_limit_MB,omitempty"`
+
+	// MaxSizeWarning is a field of KokuMetricsConfigStatus to represent why the max size in the spec was not used.
+	// +optional
+	MaxSizeWarning string `json:"max_size_warning,omitempty"`
+
 	// PackagedFiles is a field of KokuMetricsConfig to represent the list of file packages in storage.
 	PackagedFiles []string `json:"packaged_files,omitempty"`
 
+	// PackagingCycle is a field of KokuMetricsConfig to represent the number of minutes between each packaging of the reports.
+	PackagingCycle *int64 `json:"packaging_cycle,omitempty"`
+
+	// DeltaReports is a field of KokuMetricsConfig to represent if unchanged node and namespace reports are left out of payloads.
+	DeltaReports bool `json:"delta_reports,omitempty"`
+
+	// SigningKeyFingerprint is a field of KokuMetricsConfig to represent the SHA-256 fingerprint of the public key that verifies the payload signatures.
+	// +optional
+	SigningKeyFingerprint string `json:"signing_key_fingerprint,omitempty"`
+
+	// PayloadCadence is a field of KokuMetricsConfig to represent the granularity of the payloads.
+	// +optional
+	PayloadCadence PayloadCadence `json:"payload_cadence,omitempty"`
+
+	// CSVDelimiter is a field of KokuMetricsConfig to represent the field delimiter of the packaged reports.
 	// PackagingError is a field of KokuMetricsConfig to represent the error encountered packaging the reports.
 	PackagingError string `json:"error,omitempty"`
 
+	// PayloadHistory is a field of KokuMetricsConfig to represent the sizes of the most recently packaged payloads, oldest first.
+	// +optional
+	PayloadHistory []PayloadSize `json:"payload_history,omitempty"`
+
 	// ReportCount is a field of KokuMetricsConfig to represent the number of reports in storage.
 	ReportCount *int64 `json:"number_reports_stored,omitempty"`
 }
 
+// PayloadSize defines the size of a packaged payload in the PackagingStatus.
+type PayloadSize struct {
+
+	// File is a field of KokuMetricsConfig to represent the name of the payload tar.gz file.
+	File string `json:"file"`
+
+	// PackagedTime is a field of KokuMetricsConfig to represent the time the payload was packaged.
+	PackagedTime metav1.Time `json:"packaged_time"`
+
+	// RawBytes is a field of KokuMetricsConfig to represent the size in bytes of the reports in the payload before compression.
+	RawBytes int64 `json:"raw_bytes"`
+
+	// CompressedBytes is a field of KokuMetricsConfig to represent the size in bytes of the compressed payload.
+	CompressedBytes int64 `json:"compressed_bytes"`
+
+	// ReportBytes is a field of KokuMetricsConfig to represent the size in bytes of each type of report in the payload before compression.
+	// +optional
+	ReportBytes map[string]int64 `json:"report_bytes,omitempty"`
+}
+
 // UploadStatus defines the observed state of Upload object in the KokuMetricsConfigStatus.
 type UploadStatus struct {
 
@@ -304,13 +1453,38 @@ type UploadStatus struct {
 	// The default is true
 	UploadToggle *bool `json:"upload,omitempty"`
 
-	// UploadWait is a field of KokuMetricsConfig to represent the time to wait before sending an upload.
+	// UploadWait is a field of KokuMetricsConfig to represent the number of seconds to wait before sending an upload.
 	UploadWait *int64 `json:"upload_wait,omitempty"`
 
+	// UploadWaitSource is a field of KokuMetricsConfigStatus to represent where the upload wait comes from: spec,
+	// cluster_id, or random.
+	// +optional
+	UploadWaitSource UploadWaitSource `json:"upload_wait_source,omitempty"`
+
 	// UploadCycle is a field of KokuMetricsConfig to represent the number of minutes between each upload schedule.
 	// The default is 360 min (6 hours).
 	UploadCycle *int64 `json:"upload_cycle,omitempty"`
 
+	// UploadInterval is a field of KokuMetricsConfig to represent the minimum number of seconds between two payload uploads.
+	UploadInterval *int64 `json:"upload_interval,omitempty"`
+
+	// MaxBackfillUploads is a field of KokuMetricsConfig to represent the maximum number of backfill payloads uploaded
+	// each upload cycle.
+	MaxBackfillUploads *int64 `json:"max_backfill_uploads,omitempty"`
+
+	// QuarantineAfterRejections is a field of KokuMetricsConfig to represent the number of 4xx rejections after which
+	// a payload is quarantined. 0 means payloads are never quarantined.
+	QuarantineAfterRejections *int64 `json:"quarantine_after_rejections,omitempty"`
+
+	// Queue is a field of KokuMetricsConfigStatus to represent the state of the upload queue.
+	// +optional
+	Queue UploadQueueStatus `json:"queue,omitempty"`
+
+	// Quarantine is a field of KokuMetricsConfigStatus to represent the payloads that were quarantined after repeated
+	// 4xx rejections.
+	// +optional
+	Quarantine QuarantineStatus `json:"quarantine,omitempty"`
+
 	// UploadError is a field of KokuMetricsConfigStatus to represent the error encountered uploading reports.
 	// +optional
 	UploadError string `json:"error,omitempty"`
@@ -318,12 +1492,136 @@ type UploadStatus struct {
 	// LastUploadStatus is a field of KokuMetricsConfig that shows the http status of the last upload.
 	LastUploadStatus string `json:"last_upload_status,omitempty"`
 
+	// LastRejectionResponse is a field of KokuMetricsConfigStatus to represent the truncated response body of the last
+	// upload that was rejected with a 4xx status. It is cleared by the next successful upload.
+	// +optional
+	LastRejectionResponse string `json:"last_rejection_response,omitempty"`
+
 	// LastSuccessfulUploadTime is a field of KokuMetricsConfig that shows the time of the last successful upload.
 	// +nullable
 	LastSuccessfulUploadTime metav1.Time `json:"last_successful_upload_time,omitempty"`
 
 	// ValidateCert is a field of KokuMetricsConfig to represent if the Ingress endpoint must be certificate validated.
 	ValidateCert *bool `json:"validate_cert,omitempty"`
+
+	// PayloadFormat is a field of KokuMetricsConfig to represent how payloads are sent to the upload endpoint.
+	PayloadFormat PayloadFormat `json:"payload_format,omitempty"`
+
+	// IPFamily is a field of KokuMetricsConfig to represent the address family that is tried first when connecting to
+	// the upload endpoint.
+	IPFamily string `json:"ip_family,omitempty"`
+
+	// DNSServer is a field of KokuMetricsConfig to represent the DNS server that resolves the upload endpoint.
+	DNSServer string `json:"dns_server,omitempty"`
+
+	// TLS is a field of KokuMetricsConfig to represent the TLS settings of the connections to cloud.redhat.com.
+	// +optional
+	TLS TLSSpec `json:"tls,omitempty"`
+
+	// CertValidation is a field of KokuMetricsConfigStatus to represent if the certificates of each cloud.redhat.com
+	// endpoint are validated.
+	// +optional
+	CertValidation CertValidationStatus `json:"cert_validation,omitempty"`
+
+	// TLSError is a field of KokuMetricsConfigStatus to represent the error in the TLS settings. The previous TLS
+	// settings are used until the error is corrected.
+	// +optional
+	TLSError string `json:"tls_error,omitempty"`
+
+	// ExtraHeaders is a field of KokuMetricsConfigStatus to represent the names of the additional headers that are
+	// added to the requests to cloud.redhat.com.
+	// +optional
+	ExtraHeaders []string `json:"extra_headers,omitempty"`
+
+	// ExtraHeadersError is a field of KokuMetricsConfigStatus to represent the error in the additional headers.
+	// Requests are sent without the headers that have an error.
+	// +optional
+	ExtraHeadersError string `json:"extra_headers_error,omitempty"`
+
+	// FailoverThreshold is a field of KokuMetricsConfig to represent the number of consecutive upload cycles that the
+	// active API URL must be unreachable before uploads fail over to the next endpoint.
+	FailoverThreshold *int64 `json:"failover_threshold,omitempty"`
+
+	// ActiveAPIURL is a field of KokuMetricsConfigStatus to represent the API URL that payloads are uploaded to.
+	// +optional
+	ActiveAPIURL string `json:"active_api_url,omitempty"`
+
+	// EndpointFailures is a field of KokuMetricsConfigStatus to represent the number of consecutive upload cycles that
+	// the active API URL has been unreachable.
+	// +optional
+	EndpointFailures int64 `json:"endpoint_failures,omitempty"`
+
+	// Destinations is a field of KokuMetricsConfig to represent the state of the additional export destinations.
+	// +optional
+	Destinations []DestinationStatus `json:"destinations,omitempty"`
+}
+
+// UploadQueueStatus defines the observed state of the upload queue in the KokuMetricsConfigStatus.
+type UploadQueueStatus struct {
+
+	// CriticalPayloads is a field of KokuMetricsConfigStatus to represent the number of recent payloads waiting to be uploaded.
+	CriticalPayloads int64 `json:"critical_payloads,omitempty"`
+
+	// BackfillPayloads is a field of KokuMetricsConfigStatus to represent the number of backfill payloads waiting to be uploaded.
+	BackfillPayloads int64 `json:"backfill_payloads,omitempty"`
+
+	// InProgress is a field of KokuMetricsConfigStatus to represent if payloads are being uploaded.
+	InProgress bool `json:"in_progress,omitempty"`
+
+	// LastBatchStartTime is a field of KokuMetricsConfigStatus to represent the last time the queue started uploading payloads.
+	// +nullable
+	LastBatchStartTime metav1.Time `json:"last_batch_start_time,omitempty"`
+
+	// LastBatchEndTime is a field of KokuMetricsConfigStatus to represent the last time the queue finished uploading payloads.
+	// +nullable
+	LastBatchEndTime metav1.Time `json:"last_batch_end_time,omitempty"`
+
+	// PostProcessError is a field of KokuMetricsConfigStatus to represent the error of the last payload that the post-processing hook
+	// of the operator failed for.
+	// +optional
+	PostProcessError string `json:"post_process_error,omitempty"`
+}
+
+// QuarantineStatus defines the payloads in the quarantine directory in the KokuMetricsConfigStatus.
+type QuarantineStatus struct {
+
+	// Payloads is a field of KokuMetricsConfigStatus to represent the number of payloads in the quarantine directory.
+	// +optional
+	Payloads int64 `json:"payloads,omitempty"`
+
+	// Files is a field of KokuMetricsConfigStatus to represent the names of the payloads in the quarantine directory.
+	// +optional
+	Files []string `json:"files,omitempty"`
+
+	// Quarantined is a field of KokuMetricsConfigStatus to represent the number of payloads that have been quarantined.
+	// +optional
+	Quarantined int64 `json:"quarantined,omitempty"`
+
+	// LastQuarantineTime is a field of KokuMetricsConfigStatus to represent the last time a payload was quarantined.
+	// +nullable
+	LastQuarantineTime metav1.Time `json:"last_quarantine_time,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered reading the quarantine directory.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// DestinationStatus defines the observed state of an export destination in the KokuMetricsConfigStatus.
+type DestinationStatus struct {
+
+	// Name is a field of KokuMetricsConfigStatus to represent the name of the destination.
+	Name string `json:"name"`
+
+	// Type is a field of KokuMetricsConfigStatus to represent the kind of destination.
+	Type string `json:"type"`
+
+	// LastExportTime is a field of KokuMetricsConfigStatus to represent the last time a payload was exported to the destination.
+	// +nullable
+	LastExportTime metav1.Time `json:"last_export_time,omitempty"`
+
+	// ExportError is a field of KokuMetricsConfigStatus to represent the error encountered exporting to the destination.
+	// +optional
+	ExportError string `json:"error,omitempty"`
 }
 
 // CloudDotRedHatSourceStatus defines the observed state of CloudDotRedHatSource object in the KokuMetricsConfigStatus.
@@ -337,6 +1635,11 @@ type CloudDotRedHatSourceStatus struct {
 	// +optional
 	SourceName string `json:"name,omitempty"`
 
+	// ClusterDisplayName is a field of KokuMetricsConfigStatus to represent the display name of the cluster in
+	// OpenShift Cluster Manager. It is the source name when `source.name` is not set.
+	// +optional
+	ClusterDisplayName string `json:"cluster_display_name,omitempty"`
+
 	// SourceDefined is a field of KokuMetricsConfigStatus to represent if the source exists as defined on cloud.redhat.com.
 	// +optional
 	SourceDefined *bool `json:"source_defined,omitempty"`
@@ -387,22 +1690,331 @@ type PrometheusStatus struct {
 
 	// SkipTLSVerification is a field of KokuMetricsConfigStatus to represent if the thanos-querier endpoint must be certificate validated.
 	SkipTLSVerification *bool `json:"skip_tls_verification,omitempty"`
-}
 
-// ReportsStatus defines the status for generating reports.
-type ReportsStatus struct {
+	// ServingAddress is a field of KokuMetricsConfigStatus to represent the address of the Prometheus that is queried:
+	// the service address, or the fallback address when the service address cannot be queried.
+	// +optional
+	ServingAddress string `json:"serving_address,omitempty"`
 
-	// ReportMonth is a field of KokuMetricsConfigStatus to represent the month for which reports are being generated.
-	ReportMonth string `json:"report_month,omitempty"`
+	// TLS is a field of KokuMetricsConfig to represent the TLS settings of the connections to Prometheus.
+	// +optional
+	TLS TLSSpec `json:"tls,omitempty"`
 
-	// LastHourQueried is a field of KokuMetricsConfigStatus to represent the time range for which metrics were last queried.
-	LastHourQueried string `json:"last_hour_queried,omitempty"`
+	// ExtraSelectors is a field of KokuMetricsConfigStatus to represent the label matchers that are added to every query.
+	// +optional
+	ExtraSelectors []string `json:"extra_selectors,omitempty"`
 
-	// DataCollected is a field of KokuMetricsConfigStatus to represent whether or not data was collected for the last query.
-	DataCollected bool `json:"data_collected,omitempty"`
+	// QueryOverrides is a field of KokuMetricsConfigStatus to represent the PromQL of each default query that is overridden, by query name.
+	// +optional
+	QueryOverrides map[string]string `json:"query_overrides,omitempty"`
 
-	// DataCollectionMessage is a field of KokuMetricsConfigStatus to represent a message associated with the data_collected status.
+	// QueryOverridesError is a field of KokuMetricsConfigStatus to represent the query overrides that are not applied.
+	// +optional
+	QueryOverridesError string `json:"query_overrides_error,omitempty"`
+
+	// MonitoringBinding is a field of KokuMetricsConfigStatus to represent the state of the cluster-monitoring-view binding.
+	MonitoringBinding MonitoringBindingStatus `json:"monitoring_binding,omitempty"`
+
+	// Endpoints is a field of KokuMetricsConfigStatus to represent the health of each endpoint of a sharded Prometheus.
+	// +optional
+	Endpoints []PrometheusEndpointStatus `json:"endpoints,omitempty"`
+
+	// AdaptiveStep is a field of KokuMetricsConfigStatus to represent the query step and the measurements of the last collection.
+	// +optional
+	AdaptiveStep AdaptiveStepStatus `json:"adaptive_step,omitempty"`
+
+	// QueryAllowList is a field of KokuMetricsConfigStatus to represent the queries the operator may run.
+	// +optional
+	QueryAllowList QueryAllowListStatus `json:"query_allow_list,omitempty"`
+
+	// QueryCost is a field of KokuMetricsConfigStatus to represent the load that the queries of the last window queried put on Prometheus.
+	// +optional
+	QueryCost QueryCostStatus `json:"query_cost,omitempty"`
+}
+
+// QueryCostStatus defines the cost of the queries of the last window queried.
+type QueryCostStatus struct {
+
+	// Queries is a field of KokuMetricsConfigStatus to represent the number of queries sent to Prometheus.
+	// +optional
+	Queries int64 `json:"queries,omitempty"`
+
+	// CachedQueries is a field of KokuMetricsConfigStatus to represent the number of queries answered from an earlier result of the window.
+	// +optional
+	CachedQueries int64 `json:"cached_queries,omitempty"`
+
+	// DurationMilliseconds is a field of KokuMetricsConfigStatus to represent the total duration of the queries sent to Prometheus.
+	// +optional
+	DurationMilliseconds int64 `json:"duration_milliseconds,omitempty"`
+
+	// Samples is a field of KokuMetricsConfigStatus to represent the total number of samples returned by the queries.
+	// +optional
+	Samples int64 `json:"samples,omitempty"`
+
+	// Warnings is a field of KokuMetricsConfigStatus to represent the number of warnings returned with the queries.
+	// +optional
+	Warnings int64 `json:"warnings,omitempty"`
+
+	// SlowestQueries is a field of KokuMetricsConfigStatus to represent the queries that took the longest.
+	// +optional
+	SlowestQueries []QueryCost `json:"slowest_queries,omitempty"`
+}
+
+// QueryCost defines the cost of a single query.
+type QueryCost struct {
+
+	// Name is a field of KokuMetricsConfigStatus to represent the name of the query.
+	Name string `json:"name"`
+
+	// DurationMilliseconds is a field of KokuMetricsConfigStatus to represent how long the query took.
+	DurationMilliseconds int64 `json:"duration_milliseconds"`
+
+	// Series is a field of KokuMetricsConfigStatus to represent the number of series returned by the query.
+	Series int64 `json:"series"`
+
+	// Samples is a field of KokuMetricsConfigStatus to represent the number of samples returned by the query.
+	Samples int64 `json:"samples"`
+
+	// Warnings is a field of KokuMetricsConfigStatus to represent the warnings returned with the query.
+	// +optional
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// QueryAllowListStatus defines the state of the query allow-list.
+type QueryAllowListStatus struct {
+
+	// ConfigMap is a field of KokuMetricsConfigStatus to represent the name of the ConfigMap the queries are published to.
+	// +optional
+	ConfigMap string `json:"config_map,omitempty"`
+
+	// Hash is a field of KokuMetricsConfigStatus to represent the sha256 hash of the queries the operator would run.
+	// +optional
+	Hash string `json:"hash,omitempty"`
+
+	// Queries is a field of KokuMetricsConfigStatus to represent the number of queries the operator would run.
+	// +optional
+	Queries int64 `json:"queries,omitempty"`
+
+	// PinnedHash is a field of KokuMetricsConfigStatus to represent the reviewed hash that the queries must match.
+	// +optional
+	PinnedHash string `json:"pinned_hash,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent why the queries are not run, or could not be published.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// AdaptiveStepStatus defines the state of the adaptive query step.
+type AdaptiveStepStatus struct {
+
+	// Enabled is a field of KokuMetricsConfigStatus to represent if the query step is coarsened when a collection exceeds a threshold.
+	Enabled bool `json:"enabled"`
+
+	// MaxDurationSeconds is a field of KokuMetricsConfigStatus to represent the duration threshold of a collection.
+	MaxDurationSeconds *int64 `json:"max_duration_seconds,omitempty"`
+
+	// MaxMemoryMB is a field of KokuMetricsConfigStatus to represent the memory threshold of a collection.
+	MaxMemoryMB *int64 `json:"max_memory_mb,omitempty"`
+
+	// RecoveryCollections is a field of KokuMetricsConfigStatus to represent the number of consecutive collections
+	// within the thresholds after which a coarsened query step is made finer.
+	RecoveryCollections *int64 `json:"recovery_collections,omitempty"`
+
+	// CollectionsWithinThresholds is a field of KokuMetricsConfigStatus to represent the number of consecutive
+	// collections within the thresholds since the query step last changed.
+	// +optional
+	CollectionsWithinThresholds int64 `json:"collections_within_thresholds,omitempty"`
+
+	// StepSeconds is a field of KokuMetricsConfigStatus to represent the query step, in seconds, of the following windows.
+	// +optional
+	StepSeconds int64 `json:"step_seconds,omitempty"`
+
+	// LastDurationSeconds is a field of KokuMetricsConfigStatus to represent the duration of the last collection.
+	// +optional
+	LastDurationSeconds int64 `json:"last_duration_seconds,omitempty"`
+
+	// LastMemoryMB is a field of KokuMetricsConfigStatus to represent the memory used by the operator at the end of the last collection.
+	// +optional
+	LastMemoryMB int64 `json:"last_memory_mb,omitempty"`
+
+	// Reason is a field of KokuMetricsConfigStatus to represent why the query step was last changed.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// LastChangeTime is a field of KokuMetricsConfigStatus to represent the last time the query step was changed.
+	// +nullable
+	// +optional
+	LastChangeTime metav1.Time `json:"last_change_time,omitempty"`
+}
+
+// PrometheusEndpointStatus defines the health of one endpoint of a sharded Prometheus.
+type PrometheusEndpointStatus struct {
+
+	// Address is a field of KokuMetricsConfigStatus to represent the address of the endpoint.
+	Address string `json:"address"`
+
+	// Connected is a field of KokuMetricsConfigStatus to represent if the last query to the endpoint succeeded.
+	Connected bool `json:"connected"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error of the last query to the endpoint.
+	Error string `json:"error,omitempty"`
+
+	// LastQuerySuccessTime is a field of KokuMetricsConfigStatus to represent the last time the endpoint was queried successfully.
+	// +nullable
+	LastQuerySuccessTime metav1.Time `json:"last_query_success_time,omitempty"`
+}
+
+// MonitoringBindingStatus defines the status for the ClusterRoleBinding that grants the cluster-monitoring-view role.
+type MonitoringBindingStatus struct {
+
+	// Managed is a field of KokuMetricsConfigStatus to represent if the operator creates and repairs the binding.
+	Managed bool `json:"managed,omitempty"`
+
+	// Name is a field of KokuMetricsConfigStatus to represent the name of the managed ClusterRoleBinding.
+	Name string `json:"name,omitempty"`
+
+	// Drift is a field of KokuMetricsConfigStatus to represent how the binding differed from the expected binding
+	// when it was last repaired.
+	Drift string `json:"drift,omitempty"`
+
+	// LastRepairTime is a field of KokuMetricsConfigStatus to represent the last time the binding was created or repaired.
+	// +nullable
+	LastRepairTime metav1.Time `json:"last_repair_time,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent errors checking or repairing the binding.
+	Error string `json:"error,omitempty"`
+}
+
+// ReportingStatus defines the status for report window and billing period alignment.
+type ReportingStatus struct {
+
+	// BillingTimezone is a field of KokuMetricsConfigStatus to represent the time zone that reports are aligned to.
+	BillingTimezone string `json:"billing_timezone,omitempty"`
+
+	// PeriodStartDay is a field of KokuMetricsConfigStatus to represent the day of the month that billing periods start on.
+	PeriodStartDay int64 `json:"period_start_day,omitempty"`
+
+	// Granularity is a field of KokuMetricsConfigStatus to represent the level that usage is reported at.
+	Granularity ReportGranularity `json:"granularity,omitempty"`
+
+	// DataResidency is a field of KokuMetricsConfigStatus to represent the data residency region written to the manifest.
+	// +optional
+	DataResidency string `json:"data_residency,omitempty"`
+
+	// IngressAPIURL is a field of KokuMetricsConfigStatus to represent the region-specific API URL that payloads are uploaded to.
+	// +optional
+	IngressAPIURL string `json:"ingress_api_url,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent errors loading the billing time zone.
+	Error string `json:"error,omitempty"`
+}
+
+// CustomMetricsStatus defines the status for querying custom metrics.
+type CustomMetricsStatus struct {
+
+	// Connected is a field of KokuMetricsConfigStatus to represent if the user workload monitoring prometheus can be queried.
+	Connected bool `json:"connected,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent errors connecting to the user workload monitoring
+	// prometheus or querying custom metrics.
+	Error string `json:"error,omitempty"`
+
+	// LastQuerySuccessTime is a field of KokuMetricsConfigStatus to represent the last time custom metrics were successfully queried.
+	// +nullable
+	LastQuerySuccessTime metav1.Time `json:"last_query_success_time,omitempty"`
+}
+
+// ReportsStatus defines the status for generating reports.
+type ReportsStatus struct {
+
+	// ReportMonth is a field of KokuMetricsConfigStatus to represent the month for which reports are being generated.
+	ReportMonth string `json:"report_month,omitempty"`
+
+	// LastHourQueried is a field of KokuMetricsConfigStatus to represent the time range for which metrics were last queried.
+	LastHourQueried string `json:"last_hour_queried,omitempty"`
+
+	// DataCollected is a field of KokuMetricsConfigStatus to represent whether or not data was collected for the last query.
+	DataCollected bool `json:"data_collected,omitempty"`
+
+	// DataCollectionMessage is a field of KokuMetricsConfigStatus to represent a message associated with the data_collected status.
 	DataCollectionMessage string `json:"data_collection_message,omitempty"`
+
+	// EmptyReports is a field of KokuMetricsConfigStatus to represent the reports that contained no rows during the last query.
+	// +optional
+	EmptyReports []string `json:"empty_reports,omitempty"`
+
+	// Tenants is a field of KokuMetricsConfigStatus to represent the tenants that reports were partitioned into during the last query.
+	// +optional
+	Tenants []string `json:"tenants,omitempty"`
+
+	// ReportStats is a field of KokuMetricsConfigStatus to represent the rows, bytes, and query duration of each report during the last query.
+	// +optional
+	ReportStats []ReportStat `json:"report_stats,omitempty"`
+
+	// MissingLabels is a field of KokuMetricsConfigStatus to represent the expected pod labels that no pod had during the last query.
+	// +optional
+	MissingLabels []string `json:"missing_labels,omitempty"`
+
+	// SanitizedValues is a field of KokuMetricsConfigStatus to represent the label and annotation values that were changed before they were written during the last query.
+	// +optional
+	SanitizedValues SanitizedValuesStatus `json:"sanitized_values,omitempty"`
+
+	// Inconsistencies is a field of KokuMetricsConfigStatus to represent the pods of the pod report that referenced a namespace or node missing from the namespace or node report during the last query.
+	// +optional
+	Inconsistencies ConsistencyStatus `json:"inconsistencies,omitempty"`
+}
+
+// ConsistencyStatus defines the rows of the pod report that reference a namespace or node that is not in the namespace
+// or node report of the same window.
+type ConsistencyStatus struct {
+
+	// PodsWithoutNamespace is a field of KokuMetricsConfigStatus to represent the number of pods whose namespace is not in the namespace report.
+	// +optional
+	PodsWithoutNamespace int64 `json:"pods_without_namespace,omitempty"`
+
+	// MissingNamespaces is a field of KokuMetricsConfigStatus to represent the first 10 namespaces, in order, that pods reference but are not in the namespace report.
+	// +optional
+	MissingNamespaces []string `json:"missing_namespaces,omitempty"`
+
+	// PodsWithoutNode is a field of KokuMetricsConfigStatus to represent the number of pods whose node is not in the node report.
+	// +optional
+	PodsWithoutNode int64 `json:"pods_without_node,omitempty"`
+
+	// MissingNodes is a field of KokuMetricsConfigStatus to represent the first 10 nodes, in order, that pods reference but are not in the node report.
+	// +optional
+	MissingNodes []string `json:"missing_nodes,omitempty"`
+}
+
+// SanitizedValuesStatus defines the number of label and annotation values that were changed, by change.
+type SanitizedValuesStatus struct {
+
+	// Truncated is a field of KokuMetricsConfigStatus to represent the number of values that were longer than the maximum label value length.
+	// +optional
+	Truncated int64 `json:"truncated,omitempty"`
+
+	// ControlCharacters is a field of KokuMetricsConfigStatus to represent the number of values that control characters, such as newlines, or invalid UTF-8 were stripped from.
+	// +optional
+	ControlCharacters int64 `json:"control_characters,omitempty"`
+
+	// Escaped is a field of KokuMetricsConfigStatus to represent the number of values that contained the label separator `|`, which is escaped as `%7C`.
+	// +optional
+	Escaped int64 `json:"escaped,omitempty"`
+}
+
+// ReportStat defines the statistics of a single report for the last query.
+type ReportStat struct {
+
+	// Report is a field of KokuMetricsConfigStatus to represent the name of the report.
+	Report string `json:"report"`
+
+	// Rows is a field of KokuMetricsConfigStatus to represent the number of rows of the report for the hour queried.
+	Rows int64 `json:"rows"`
+
+	// Bytes is a field of KokuMetricsConfigStatus to represent the number of bytes written to the report files for the hour queried.
+	Bytes int64 `json:"bytes"`
+
+	// QueryDurationMilliseconds is a field of KokuMetricsConfigStatus to represent how long the queries of the report took.
+	QueryDurationMilliseconds int64 `json:"query_duration_milliseconds"`
 }
 
 // StorageStatus defines the status for storage.
@@ -413,6 +2025,651 @@ type StorageStatus struct {
 
 	// VolumeMounted is a bool to indicate if storage volume was mounted.
 	VolumeMounted bool `json:"volume_mounted,omitempty"`
+
+	// MigratedLayouts is the list of legacy directory layouts that files were migrated from when the operator started.
+	MigratedLayouts []string `json:"migrated_layouts,omitempty"`
+
+	// MigratedFiles is the number of files that were moved out of legacy directory layouts.
+	MigratedFiles int64 `json:"migrated_files,omitempty"`
+
+	// LastMigrationTime is the time of the last migration of the data on the volume.
+	// +nullable
+	LastMigrationTime metav1.Time `json:"last_migration_time,omitempty"`
+
+	// MigrationError is the error of the last migration of the data on the volume.
+	MigrationError string `json:"migration_error,omitempty"`
+
+	// DataVersion is the version of the format of the data on the volume.
+	DataVersion int64 `json:"data_version,omitempty"`
+
+	// AppliedMigrations is the list of data migrations that ran when the operator last started.
+	AppliedMigrations []string `json:"applied_migrations,omitempty"`
+}
+
+// HubStatus defines the observed state of hub aggregation in the KokuMetricsConfigStatus.
+type HubStatus struct {
+
+	// Enabled is a field of KokuMetricsConfigStatus to represent if the operator is accepting payloads from spoke clusters.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// SecretName is a field of KokuMetricsConfigStatus to represent the secret used to authenticate spoke clusters.
+	SecretName string `json:"secret_name,omitempty"`
+
+	// ReceivedPayloads is a field of KokuMetricsConfigStatus to represent the number of payloads received from spoke clusters
+	// since the operator started.
+	ReceivedPayloads int64 `json:"received_payloads,omitempty"`
+
+	// RejectedPayloads is a field of KokuMetricsConfigStatus to represent the number of uploads from spoke clusters that were
+	// rejected since the operator started.
+	RejectedPayloads int64 `json:"rejected_payloads,omitempty"`
+
+	// LastReceivedTime is a field of KokuMetricsConfigStatus to represent the last time a payload was received from a spoke cluster.
+	// +nullable
+	LastReceivedTime metav1.Time `json:"last_received_time,omitempty"`
+
+	// LastSpokeClusterID is a field of KokuMetricsConfigStatus to represent the cluster ID of the last payload received.
+	LastSpokeClusterID string `json:"last_spoke_cluster_id,omitempty"`
+
+	// HubError is a field of KokuMetricsConfigStatus to represent the error encountered configuring hub aggregation.
+	// +optional
+	HubError string `json:"error,omitempty"`
+}
+
+// ConnectionCheckResult describes the outcome of a single connection check.
+// +kubebuilder:validation:Enum=Succeeded;Failed;Skipped
+type ConnectionCheckResult string
+
+const (
+	// ConnectionCheckSucceeded means the endpoint was reachable and accepted the request.
+	ConnectionCheckSucceeded ConnectionCheckResult = "Succeeded"
+
+	// ConnectionCheckFailed means the endpoint was unreachable or rejected the request.
+	ConnectionCheckFailed ConnectionCheckResult = "Failed"
+
+	// ConnectionCheckSkipped means the check does not apply to the current configuration.
+	ConnectionCheckSkipped ConnectionCheckResult = "Skipped"
+)
+
+// ConnectionCheck defines the result of a connectivity check against a single endpoint.
+type ConnectionCheck struct {
+
+	// Result is a field of ConnectionCheck to represent the outcome of the check.
+	// +optional
+	Result ConnectionCheckResult `json:"result,omitempty"`
+
+	// Message is a field of ConnectionCheck to represent the details of the outcome of the check.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ConnectionTestStatus defines the result of the connection test triggered by the connection-test annotation.
+type ConnectionTestStatus struct {
+
+	// Trigger is a field of KokuMetricsConfigStatus to represent the value of the annotation that triggered the last connection test.
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+
+	// LastRunTime is a field of KokuMetricsConfigStatus to represent the time the last connection test was run.
+	// +nullable
+	LastRunTime metav1.Time `json:"last_run_time,omitempty"`
+
+	// Prometheus is a field of KokuMetricsConfigStatus to represent the result of the prometheus test query.
+	// +optional
+	Prometheus ConnectionCheck `json:"prometheus,omitempty"`
+
+	// Ingress is a field of KokuMetricsConfigStatus to represent the result of the request to the ingress endpoint.
+	// +optional
+	Ingress ConnectionCheck `json:"ingress,omitempty"`
+
+	// Sources is a field of KokuMetricsConfigStatus to represent the result of the request to the Sources API.
+	// +optional
+	Sources ConnectionCheck `json:"sources,omitempty"`
+}
+
+// PreflightStatus defines the result of the pre-flight check of the upload endpoints in the KokuMetricsConfigStatus.
+type PreflightStatus struct {
+
+	// LastRunTime is a field of KokuMetricsConfigStatus to represent the time the last pre-flight check was run.
+	// +nullable
+	LastRunTime metav1.Time `json:"last_run_time,omitempty"`
+
+	// IngressAPIURL is a field of KokuMetricsConfigStatus to represent the ingress URL that was checked.
+	// +optional
+	IngressAPIURL string `json:"ingress_api_url,omitempty"`
+
+	// AuthType is a field of KokuMetricsConfigStatus to represent the authentication type, or the list of
+	// authentication methods, that was checked.
+	// +optional
+	AuthType string `json:"auth_type,omitempty"`
+
+	// CredentialsVersion is a field of KokuMetricsConfigStatus to represent the resource version of the secret that
+	// held the credentials that were checked.
+	// +optional
+	CredentialsVersion string `json:"credentials_version,omitempty"`
+
+	// Ingress is a field of KokuMetricsConfigStatus to represent the result of the request to the ingress endpoint.
+	// +optional
+	Ingress ConnectionCheck `json:"ingress,omitempty"`
+
+	// Sources is a field of KokuMetricsConfigStatus to represent the result of the request to the Sources API.
+	// +optional
+	Sources ConnectionCheck `json:"sources,omitempty"`
+}
+
+// PayloadAuditSpec defines the desired state of the KokuMetricsPayload records in the KokuMetricsConfigSpec.
+type PayloadAuditSpec struct {
+
+	// Enabled is a field of KokuMetricsConfig to represent if a KokuMetricsPayload is created for each payload that is
+	// uploaded to ingress. The default is true.
+	// +kubebuilder:default=true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// TTLHours is a field of KokuMetricsConfig to represent the number of hours a KokuMetricsPayload is kept after it
+	// is created. The default is 168.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=168
+	// +optional
+	TTLHours *int64 `json:"ttl_hours,omitempty"`
+}
+
+// PayloadAuditStatus defines the observed state of the KokuMetricsPayload records in the KokuMetricsConfigStatus.
+type PayloadAuditStatus struct {
+
+	// Enabled is a field of KokuMetricsConfigStatus to represent if a KokuMetricsPayload is created for each uploaded payload.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// TTLHours is a field of KokuMetricsConfigStatus to represent the number of hours a KokuMetricsPayload is kept.
+	TTLHours *int64 `json:"ttl_hours,omitempty"`
+
+	// Records is a field of KokuMetricsConfigStatus to represent the number of KokuMetricsPayload records.
+	Records int64 `json:"records,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered recording or pruning the
+	// KokuMetricsPayload records.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// ProcessingStatusSpec defines the desired state of the processing status poller in the KokuMetricsConfigSpec.
+type ProcessingStatusSpec struct {
+
+	// Enabled is a field of KokuMetricsConfig to represent if the operator polls the cost management report status API
+	// for the processing status of the uploaded payloads. The default is false.
+	// +kubebuilder:default=false
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// FOR DEVELOPMENT ONLY.
+	// StatusAPIPath is a field of KokuMetricsConfig to represent the path of the cost management report status API.
+	// The default is `/api/cost-management/v1/ingress/reports/`.
+	// +kubebuilder:default=`/api/cost-management/v1/ingress/reports/`
+	// +optional
+	StatusAPIPath string `json:"status_path,omitempty"`
+
+	// PollCycle is a field of KokuMetricsConfig to represent the number of minutes between each poll of the report
+	// status API. The default is 60.
+	// +kubebuilder:validation:Minimum=15
+	// +kubebuilder:default=60
+	// +optional
+	PollCycle *int64 `json:"poll_cycle,omitempty"`
+}
+
+// ProcessingStatusStatus defines the observed processing status of the uploaded payloads in the KokuMetricsConfigStatus.
+type ProcessingStatusStatus struct {
+
+	// Enabled is a field of KokuMetricsConfigStatus to represent if the report status API is polled.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// StatusAPIPath is a field of KokuMetricsConfigStatus to represent the path of the report status API.
+	// +optional
+	StatusAPIPath string `json:"status_path,omitempty"`
+
+	// PollCycle is a field of KokuMetricsConfigStatus to represent the number of minutes between each poll of the report status API.
+	PollCycle *int64 `json:"poll_cycle,omitempty"`
+
+	// LastPollTime is a field of KokuMetricsConfigStatus to represent the last time the report status API was polled.
+	// +nullable
+	LastPollTime metav1.Time `json:"last_poll_time,omitempty"`
+
+	// DataCurrentThrough is a field of KokuMetricsConfigStatus to represent the end of the latest window of the
+	// payloads that cost management has processed. Cost data is current through this time.
+	// +nullable
+	DataCurrentThrough metav1.Time `json:"data_current_through,omitempty"`
+
+	// Processed is a field of KokuMetricsConfigStatus to represent the number of recent payloads that were processed.
+	Processed int64 `json:"processed,omitempty"`
+
+	// Pending is a field of KokuMetricsConfigStatus to represent the number of recent payloads that were received but not processed yet.
+	Pending int64 `json:"pending,omitempty"`
+
+	// Failed is a field of KokuMetricsConfigStatus to represent the number of recent payloads that failed processing.
+	Failed int64 `json:"failed,omitempty"`
+
+	// FailedPayloads is a field of KokuMetricsConfigStatus to represent the IDs of the recent payloads that failed processing.
+	// +optional
+	FailedPayloads []string `json:"failed_payloads,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error of the last poll of the report status API.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// CronJobStatus defines the observed state of the CronJob of the cronjob execution mode.
+type CronJobStatus struct {
+
+	// Name is a field of KokuMetricsConfigStatus to represent the name of the CronJob.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Schedule is a field of KokuMetricsConfigStatus to represent the cron schedule of the Jobs.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// ActiveDeadlineSeconds is a field of KokuMetricsConfigStatus to represent the number of seconds a Job may run.
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"active_deadline_seconds,omitempty"`
+
+	// LastScheduleTime is a field of KokuMetricsConfigStatus to represent the last time a Job was scheduled.
+	// +nullable
+	LastScheduleTime metav1.Time `json:"last_schedule_time,omitempty"`
+
+	// LastRunTime is a field of KokuMetricsConfigStatus to represent the last time a Job ran.
+	// +nullable
+	LastRunTime metav1.Time `json:"last_run_time,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered managing the CronJob.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// SchedulingStatus defines the priority class and termination grace period of the operator pod.
+type SchedulingStatus struct {
+
+	// PriorityClassName is a field of KokuMetricsConfigStatus to represent the priority class set in the operator Deployment.
+	// +optional
+	PriorityClassName string `json:"priority_class_name,omitempty"`
+
+	// TerminationGracePeriodSeconds is a field of KokuMetricsConfigStatus to represent the termination grace period
+	// set in the operator Deployment.
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"termination_grace_period_seconds,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered setting the priority class or
+	// the termination grace period.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// ResourcesStatus defines the resource hints the operator is running with.
+type ResourcesStatus struct {
+
+	// Size is a field of KokuMetricsConfigStatus to represent the bundle of resource hints the operator is sized for.
+	// +optional
+	Size ResourceSize `json:"size,omitempty"`
+
+	// MaxParallelQueries is a field of KokuMetricsConfigStatus to represent the number of prometheus queries that are run at a time.
+	// +optional
+	MaxParallelQueries int64 `json:"max_parallel_queries,omitempty"`
+
+	// CompressionWorkers is a field of KokuMetricsConfigStatus to represent the number of tar.gz files that are compressed at a time.
+	// +optional
+	CompressionWorkers int64 `json:"compression_workers,omitempty"`
+
+	// LogBufferLines is a field of KokuMetricsConfigStatus to represent the number of recent log lines kept in memory.
+	// +optional
+	LogBufferLines int64 `json:"log_buffer_lines,omitempty"`
+
+	// CopyBufferKiB is a field of KokuMetricsConfigStatus to represent the size, in KiB, of the buffer reports are copied with.
+	// +optional
+	CopyBufferKiB int64 `json:"copy_buffer_kib,omitempty"`
+}
+
+// DryRunStatus defines the result of the last dry run of report generation.
+type DryRunStatus struct {
+
+	// Enabled is a field of KokuMetricsConfigStatus to represent if the operator is running in dry-run mode.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// LastRunTime is a field of KokuMetricsConfigStatus to represent the time the last dry run was run.
+	// +nullable
+	LastRunTime metav1.Time `json:"last_run_time,omitempty"`
+
+	// IntervalStart is a field of KokuMetricsConfigStatus to represent the start of the hour collected by the last dry run.
+	// +nullable
+	IntervalStart metav1.Time `json:"interval_start,omitempty"`
+
+	// Valid is a field of KokuMetricsConfigStatus to represent if every report of the last dry run passed validation.
+	Valid bool `json:"valid,omitempty"`
+
+	// Reports is a field of KokuMetricsConfigStatus to represent the validation of each report file of the last dry run.
+	// +optional
+	Reports []DryRunReport `json:"reports,omitempty"`
+
+	// EmptyReports is a field of KokuMetricsConfigStatus to represent the reports of the last dry run that had no rows.
+	// +optional
+	EmptyReports []string `json:"empty_reports,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered running the last dry run.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// DryRunReport defines the validation of a report file generated by a dry run.
+type DryRunReport struct {
+
+	// File is a field of KokuMetricsConfigStatus to represent the name of the report file.
+	File string `json:"file"`
+
+	// Report is a field of KokuMetricsConfigStatus to represent the report that the columns of the file match.
+	// +optional
+	Report string `json:"report,omitempty"`
+
+	// Rows is a field of KokuMetricsConfigStatus to represent the number of data rows of the file.
+	Rows int64 `json:"rows"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent why the file failed validation.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// DebugBundleStatus defines the status of the debug bundle triggered by the debug-bundle annotation.
+type DebugBundleStatus struct {
+
+	// Trigger is a field of KokuMetricsConfigStatus to represent the value of the annotation that triggered the last debug bundle.
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+
+	// LastGeneratedTime is a field of KokuMetricsConfigStatus to represent the time the last debug bundle was generated.
+	// +nullable
+	LastGeneratedTime metav1.Time `json:"last_generated_time,omitempty"`
+
+	// Path is a field of KokuMetricsConfigStatus to represent the location of the last debug bundle on the report volume.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered generating the debug bundle.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// ReplayFileStatus defines the result of uploading one payload of a replay.
+type ReplayFileStatus struct {
+
+	// Name is a field of KokuMetricsConfigStatus to represent the file name of the payload.
+	Name string `json:"name"`
+
+	// PayloadID is a field of KokuMetricsConfigStatus to represent the uuid from the payload manifest.
+	// +optional
+	PayloadID string `json:"payload_id,omitempty"`
+
+	// Uploaded is a field of KokuMetricsConfigStatus to represent whether every destination accepted the payload.
+	// Uploaded payloads are removed from the retry directory. Payloads waiting in the upload queue are not uploaded and have no error.
+	Uploaded bool `json:"uploaded"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered uploading the payload.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// ReplayStatus defines the status of the replay requested by the replay annotation.
+type ReplayStatus struct {
+
+	// Trigger is a field of KokuMetricsConfigStatus to represent the value of the annotation that requested the last replay.
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+
+	// LastRunTime is a field of KokuMetricsConfigStatus to represent the time the last replay was run.
+	// +nullable
+	LastRunTime metav1.Time `json:"last_run_time,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error that prevented the replay.
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// Files is a field of KokuMetricsConfigStatus to represent the result of each payload in the last replay.
+	// At most 50 payloads are listed.
+	// +optional
+	Files []ReplayFileStatus `json:"files,omitempty"`
+}
+
+// RecollectionStatus defines the status of the re-collection requested by the recollect annotation.
+type RecollectionStatus struct {
+
+	// Trigger is a field of KokuMetricsConfigStatus to represent the value of the annotation that requested the re-collection.
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+
+	// Start is a field of KokuMetricsConfigStatus to represent the start of the range being re-collected.
+	// +nullable
+	Start metav1.Time `json:"start,omitempty"`
+
+	// End is a field of KokuMetricsConfigStatus to represent the end of the range being re-collected.
+	// +nullable
+	End metav1.Time `json:"end,omitempty"`
+
+	// NextHour is a field of KokuMetricsConfigStatus to represent the next hour to be re-collected.
+	// +nullable
+	NextHour metav1.Time `json:"next_hour,omitempty"`
+
+	// HoursCollected is a field of KokuMetricsConfigStatus to represent the number of hours that were re-collected.
+	// +optional
+	HoursCollected int64 `json:"hours_collected,omitempty"`
+
+	// HoursWithoutData is a field of KokuMetricsConfigStatus to represent the number of hours that prometheus had no data for.
+	// +optional
+	HoursWithoutData int64 `json:"hours_without_data,omitempty"`
+
+	// Complete is a field of KokuMetricsConfigStatus to represent if the re-collection has finished.
+	// +optional
+	Complete bool `json:"complete,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered during the re-collection.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// SnapshotStatus defines the status of the re-collection from a snapshot Prometheus in the KokuMetricsConfigStatus.
+type SnapshotStatus struct {
+
+	// ServiceAddress is a field of KokuMetricsConfigStatus to represent the address of the snapshot Prometheus that
+	// the range is, or was last, re-collected from.
+	// +optional
+	ServiceAddress string `json:"service_address,omitempty"`
+
+	// Recollection is a field of KokuMetricsConfigStatus to represent the progress of the re-collection. Its trigger is
+	// the range of the snapshot spec.
+	// +optional
+	Recollection RecollectionStatus `json:"recollection,omitempty"`
+}
+
+// PauseStatus defines the status of the last pause of the operator in the KokuMetricsConfigStatus.
+type PauseStatus struct {
+
+	// Start is a field of KokuMetricsConfigStatus to represent the start of the first hour that was not collected
+	// because the operator was not running.
+	// +nullable
+	Start metav1.Time `json:"start,omitempty"`
+
+	// End is a field of KokuMetricsConfigStatus to represent the start of the hour collected when the operator resumed.
+	// +nullable
+	End metav1.Time `json:"end,omitempty"`
+
+	// MissedHours is a field of KokuMetricsConfigStatus to represent the number of hours that were not collected
+	// during the last pause.
+	// +optional
+	MissedHours int64 `json:"missed_hours,omitempty"`
+
+	// Pauses is a field of KokuMetricsConfigStatus to represent the number of pauses that have been detected.
+	// +optional
+	Pauses int64 `json:"pauses,omitempty"`
+
+	// Backfill is a field of KokuMetricsConfigStatus to represent the re-collection of the missed hours.
+	// +optional
+	Backfill RecollectionStatus `json:"backfill,omitempty"`
+}
+
+// UpgradeStatus defines the status of the collection deferred during cluster upgrades in the KokuMetricsConfigStatus.
+type UpgradeStatus struct {
+
+	// Active is a field of KokuMetricsConfigStatus to represent if collection is deferred because the cluster is upgrading.
+	// +optional
+	Active bool `json:"active,omitempty"`
+
+	// UploadDeferred is a field of KokuMetricsConfigStatus to represent if payloads are not uploaded while collection
+	// is deferred.
+	// +optional
+	UploadDeferred bool `json:"upload_deferred,omitempty"`
+
+	// Version is a field of KokuMetricsConfigStatus to represent the version of the upgrade in progress, or of the last upgrade.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Start is a field of KokuMetricsConfigStatus to represent the start of the upgrade in progress, or of the last upgrade.
+	// +nullable
+	Start metav1.Time `json:"start,omitempty"`
+
+	// End is a field of KokuMetricsConfigStatus to represent the completion of the last upgrade.
+	// +nullable
+	End metav1.Time `json:"end,omitempty"`
+
+	// Expired is a field of KokuMetricsConfigStatus to represent if the deferral of the upgrade in progress, or of the
+	// last upgrade, ended because it reached the maximum deferral instead of because the upgrade completed.
+	// +optional
+	Expired bool `json:"expired,omitempty"`
+
+	// Upgrades is a field of KokuMetricsConfigStatus to represent the number of upgrades during which collection was deferred.
+	// +optional
+	Upgrades int64 `json:"upgrades,omitempty"`
+
+	// Backfill is a field of KokuMetricsConfigStatus to represent the re-collection of the hours deferred during the last upgrade.
+	// +optional
+	Backfill RecollectionStatus `json:"backfill,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered when reading the ClusterVersion.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// BlackoutStatus defines the status of the blackout windows in the KokuMetricsConfigStatus.
+type BlackoutStatus struct {
+
+	// Active is a field of KokuMetricsConfigStatus to represent if reports are not collected or uploaded because a
+	// blackout window is in progress.
+	// +optional
+	Active bool `json:"active,omitempty"`
+
+	// Window is a field of KokuMetricsConfigStatus to represent the name of the window in progress, or of the last window.
+	// +optional
+	Window string `json:"window,omitempty"`
+
+	// Start is a field of KokuMetricsConfigStatus to represent the start of the window in progress, or of the last window.
+	// +nullable
+	Start metav1.Time `json:"start,omitempty"`
+
+	// End is a field of KokuMetricsConfigStatus to represent the end of the window in progress, or of the last window.
+	// +nullable
+	End metav1.Time `json:"end,omitempty"`
+
+	// Backfill is a field of KokuMetricsConfigStatus to represent the re-collection of the hours missed during the last window.
+	// +optional
+	Backfill RecollectionStatus `json:"backfill,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the windows that are ignored because they are invalid.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// MissedWindowStage is the stage of the pipeline at which an hour was missed
+type MissedWindowStage string
+
+const (
+	// CollectionStage the hour was not collected from Prometheus
+	CollectionStage MissedWindowStage = "collection"
+	// UploadStage the reports of the hour were not uploaded
+	UploadStage MissedWindowStage = "upload"
+)
+
+// MissedWindow defines an hour that was not collected or not uploaded.
+type MissedWindow struct {
+
+	// Start is a field of KokuMetricsConfigStatus to represent the start of the hour.
+	Start metav1.Time `json:"start"`
+
+	// Stage is a field of KokuMetricsConfigStatus to represent if the hour was not collected or not uploaded.
+	Stage MissedWindowStage `json:"stage"`
+
+	// Destination is a field of KokuMetricsConfigStatus to represent the destination that the hour was not uploaded to.
+	// +optional
+	Destination string `json:"destination,omitempty"`
+
+	// Reason is a field of KokuMetricsConfigStatus to represent why the hour was missed.
+	Reason string `json:"reason"`
+
+	// LastAttemptTime is a field of KokuMetricsConfigStatus to represent the last time the hour was missed.
+	// +nullable
+	LastAttemptTime metav1.Time `json:"last_attempt_time,omitempty"`
+}
+
+// MissedWindowsStatus defines the hours that were not collected or not uploaded in the KokuMetricsConfigStatus.
+type MissedWindowsStatus struct {
+
+	// Windows is a field of KokuMetricsConfigStatus to represent the most recent missed hours, oldest first. An hour is
+	// removed once it is collected or uploaded.
+	// +optional
+	Windows []MissedWindow `json:"windows,omitempty"`
+
+	// Overflow is a field of KokuMetricsConfigStatus to represent the number of missed hours that were removed from
+	// the list because it was full.
+	// +optional
+	Overflow int64 `json:"overflow,omitempty"`
+}
+
+// CostEstimationStatus defines the observed state of local cost estimation in the KokuMetricsConfigStatus.
+type CostEstimationStatus struct {
+
+	// RateCardConfigMap is a field of KokuMetricsConfig to represent the ConfigMap containing the rate card.
+	// +optional
+	RateCardConfigMap string `json:"rate_card_config_map,omitempty"`
+
+	// Currency is a field of KokuMetricsConfig to represent the currency of the estimates.
+	// +optional
+	Currency string `json:"currency,omitempty"`
+
+	// LastEstimateTime is a field of KokuMetricsConfig to represent the last time cost was estimated.
+	// +nullable
+	// +optional
+	LastEstimateTime metav1.Time `json:"last_estimate_time,omitempty"`
+
+	// LastEstimateFile is a field of KokuMetricsConfig to represent the report the last estimate was written to.
+	// +optional
+	LastEstimateFile string `json:"last_estimate_file,omitempty"`
+
+	// EstimationError is a field of KokuMetricsConfig to represent the error encountered estimating cost.
+	// +optional
+	EstimationError string `json:"error,omitempty"`
+}
+
+// FleetDefaultsStatus defines the state of the KokuMetricsDefault layered under the spec in the KokuMetricsConfigStatus.
+type FleetDefaultsStatus struct {
+
+	// Name is a field of KokuMetricsConfigStatus to represent the name of the KokuMetricsDefault that was applied.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// ResourceVersion is a field of KokuMetricsConfigStatus to represent the resource version of the
+	// KokuMetricsDefault that was applied.
+	// +optional
+	ResourceVersion string `json:"resource_version,omitempty"`
+
+	// Fields is a field of KokuMetricsConfigStatus to represent the spec fields that were taken from the
+	// KokuMetricsDefault, because they are not set in the KokuMetricsConfig.
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+
+	// Error is a field of KokuMetricsConfigStatus to represent the error encountered when reading the KokuMetricsDefault.
+	// +optional
+	Error string `json:"error,omitempty"`
 }
 
 // KokuMetricsConfigStatus defines the observed state of KokuMetricsConfig.
@@ -423,10 +2680,20 @@ type KokuMetricsConfigStatus struct {
 	// ClusterID is a field of KokuMetricsConfig to represent the cluster UUID.
 	ClusterID string `json:"clusterID,omitempty"`
 
+	// ClusterType is a field of KokuMetricsConfig to represent if the cluster is self-managed or is a managed service
+	// cluster (rosa or osd). The managed service registers its clusters, so sources are not created on managed clusters
+	// unless create_source is set.
+	// +optional
+	ClusterType ClusterType `json:"cluster_type,omitempty"`
+
 	// APIURL is a field of KokuMetricsConfig to represent the url of the API endpoint for service interaction.
 	// +optional
 	APIURL string `json:"api_url,omitempty"`
 
+	// APIEnvironment is a field of KokuMetricsConfig to represent the environment the operator interacts with.
+	// +optional
+	APIEnvironment APIEnvironment `json:"api_environment,omitempty"`
+
 	// Authentication is a field of KokuMetricsConfig to represent the authentication status.
 	Authentication AuthenticationStatus `json:"authentication,omitempty"`
 
@@ -452,8 +2719,118 @@ type KokuMetricsConfigStatus struct {
 	// Storage is a field
 	Storage StorageStatus `json:"storage,omitempty"`
 
+	// Profile is a field of KokuMetricsConfig to represent the resource footprint the operator is running with.
+	// +optional
+	Profile OperatorProfile `json:"profile,omitempty"`
+
+	// Resources is a field of KokuMetricsConfigStatus to represent the resource hints the operator is running with.
+	// +optional
+	Resources ResourcesStatus `json:"resources,omitempty"`
+
+	// Hub is a field of KokuMetricsConfig to represent the observed state of hub aggregation.
+	// +optional
+	Hub HubStatus `json:"hub,omitempty"`
+
+	// CostModel is a field of KokuMetricsConfig to represent the cost model hints written to each payload.
+	// +optional
+	CostModel *CostModelSpec `json:"cost_model,omitempty"`
+
+	// CostEstimation is a field of KokuMetricsConfig to represent the observed state of local cost estimation.
+	// +optional
+	CostEstimation CostEstimationStatus `json:"cost_estimation,omitempty"`
+
+	// CustomMetrics is a field of KokuMetricsConfig to represent the status of custom metrics collection.
+	// +optional
+	CustomMetrics CustomMetricsStatus `json:"custom_metrics,omitempty"`
+
+	// Reporting is a field of KokuMetricsConfig to represent the report window and billing period alignment.
+	// +optional
+	Reporting ReportingStatus `json:"reporting,omitempty"`
+
 	// PersistentVolumeClaim is a field of KokuMetricsConfig to represent a PVC.
 	PersistentVolumeClaim *EmbeddedPersistentVolumeClaim `json:"persistent_volume_claim,omitempty"`
+
+	// ConnectionTest is a field of KokuMetricsConfig to represent the result of the last connection test.
+	// +optional
+	ConnectionTest ConnectionTestStatus `json:"connection_test,omitempty"`
+
+	// Preflight is a field of KokuMetricsConfig to represent the result of the last pre-flight check of the upload
+	// endpoints, which is run before the first collection.
+	// +optional
+	Preflight PreflightStatus `json:"preflight,omitempty"`
+
+	// DryRun is a field of KokuMetricsConfig to represent the result of the last dry run of report generation.
+	// +optional
+	DryRun DryRunStatus `json:"dry_run,omitempty"`
+
+	// ExecutionMode is a field of KokuMetricsConfig to represent how reports are collected, packaged, and uploaded.
+	// +optional
+	ExecutionMode ExecutionMode `json:"execution_mode,omitempty"`
+
+	// CronJob is a field of KokuMetricsConfig to represent the state of the CronJob of the cronjob execution mode.
+	// +optional
+	CronJob CronJobStatus `json:"cronjob,omitempty"`
+
+	// Scheduling is a field of KokuMetricsConfig to represent the priority class and termination grace period of the operator pod.
+	// +optional
+	Scheduling SchedulingStatus `json:"scheduling,omitempty"`
+
+	// PayloadAudit is a field of KokuMetricsConfig to represent the state of the KokuMetricsPayload records.
+	// +optional
+	PayloadAudit PayloadAuditStatus `json:"payload_audit,omitempty"`
+
+	// ProcessingStatus is a field of KokuMetricsConfig to represent the processing status of the uploaded payloads in cost management.
+	// +optional
+	ProcessingStatus ProcessingStatusStatus `json:"processing_status,omitempty"`
+
+	// Recollection is a field of KokuMetricsConfig to represent the status of the re-collection requested by the recollect annotation.
+	// +optional
+	Recollection RecollectionStatus `json:"recollection,omitempty"`
+
+	// Snapshot is a field of KokuMetricsConfig to represent the status of the re-collection from the snapshot Prometheus.
+	// +optional
+	Snapshot SnapshotStatus `json:"snapshot,omitempty"`
+
+	// Pause is a field of KokuMetricsConfig to represent the last time the operator was not running, for example
+	// because its deployment was scaled to zero, and the back-fill of the hours it missed.
+	// +optional
+	Pause PauseStatus `json:"pause,omitempty"`
+
+	// Blackout is a field of KokuMetricsConfig to represent the blackout window in progress, or the last one, and the
+	// back-fill of the hours it missed.
+	// +optional
+	Blackout BlackoutStatus `json:"blackout,omitempty"`
+
+	// Upgrade is a field of KokuMetricsConfig to represent the cluster upgrade in progress, or the last one, and the
+	// back-fill of the hours deferred during it.
+	// +optional
+	Upgrade UpgradeStatus `json:"upgrade,omitempty"`
+
+	// MissedWindows is a field of KokuMetricsConfig to represent the hours that were not collected or not uploaded.
+	// +optional
+	MissedWindows MissedWindowsStatus `json:"missed_windows,omitempty"`
+
+	// FleetDefaults is a field of KokuMetricsConfig to represent the cluster-scoped KokuMetricsDefault that the spec
+	// is layered over.
+	// +optional
+	FleetDefaults FleetDefaultsStatus `json:"fleet_defaults,omitempty"`
+
+	// DebugBundle is a field of KokuMetricsConfig to represent the status of the last debug bundle.
+	// +optional
+	DebugBundle DebugBundleStatus `json:"debug_bundle,omitempty"`
+
+	// Replay is a field of KokuMetricsConfig to represent the status of the last replay requested by the replay annotation.
+	// +optional
+	Replay ReplayStatus `json:"replay,omitempty"`
+
+	// ClockSkewSeconds is a field of KokuMetricsConfigStatus to represent how many seconds the local clock is ahead of
+	// the clock of the API, measured from the Date header of verified ingress responses. A negative value means the local clock is behind.
+	// +optional
+	ClockSkewSeconds int64 `json:"clock_skew_seconds,omitempty"`
+
+	// Conditions is a field of KokuMetricsConfig to represent the latest observations of the operator's state.
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,55 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FleetDefaultsName is the name of the KokuMetricsDefault that the KokuMetricsConfigs of the cluster are layered over
+const FleetDefaultsName = "cluster"
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// KokuMetricsDefault is the Schema for the kokumetricsdefaults API. The spec of the KokuMetricsDefault named cluster
+// provides the defaults of every KokuMetricsConfig in the cluster, so that fleet management can create the same
+// KokuMetricsDefault on every cluster. A field set in a KokuMetricsConfig overrides the same field of the
+// KokuMetricsDefault.
+type KokuMetricsDefault struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Spec KokuMetricsConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KokuMetricsDefaultList contains a list of KokuMetricsDefault
+type KokuMetricsDefaultList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KokuMetricsDefault `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KokuMetricsDefault{}, &KokuMetricsDefaultList{})
+}
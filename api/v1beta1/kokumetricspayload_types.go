@@ -0,0 +1,134 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PayloadConfigLabel is the label of a KokuMetricsPayload with the name of the KokuMetricsConfig that uploaded it
+	PayloadConfigLabel = "koku-metrics-cfg.openshift.io/config"
+
+	// PayloadIdempotencyKeyLabel is the label of a KokuMetricsPayload with the idempotency key of the payload, which
+	// links the payloads packaged for the same window from the same reports
+	PayloadIdempotencyKeyLabel = "koku-metrics-cfg.openshift.io/idempotency-key"
+)
+
+// KokuMetricsPayloadSpec defines the payload recorded by a KokuMetricsPayload.
+type KokuMetricsPayloadSpec struct {
+
+	// PayloadID is a field of KokuMetricsPayload to represent the UUID of the payload manifest.
+	PayloadID string `json:"payload_id"`
+
+	// ClusterID is a field of KokuMetricsPayload to represent the cluster the payload was collected from.
+	// +optional
+	ClusterID string `json:"cluster_id,omitempty"`
+
+	// File is a field of KokuMetricsPayload to represent the name of the payload file.
+	File string `json:"file"`
+
+	// Start is a field of KokuMetricsPayload to represent the start of the window of the reports in the payload.
+	// +nullable
+	Start metav1.Time `json:"start,omitempty"`
+
+	// End is a field of KokuMetricsPayload to represent the end of the window of the reports in the payload.
+	// +nullable
+	End metav1.Time `json:"end,omitempty"`
+
+	// Files is a field of KokuMetricsPayload to represent the report files in the payload.
+	// +optional
+	Files []string `json:"files,omitempty"`
+
+	// SizeBytes is a field of KokuMetricsPayload to represent the size of the payload file.
+	SizeBytes int64 `json:"size_bytes"`
+
+	// IdempotencyKey is a field of KokuMetricsPayload to represent the key that was sent in the Idempotency-Key
+	// header of the uploads. It is derived from the cluster, the window and the content of the reports.
+	// +optional
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// KokuMetricsPayloadStatus defines the upload and processing status of a payload.
+type KokuMetricsPayloadStatus struct {
+
+	// Uploaded is a field of KokuMetricsPayloadStatus to represent if ingress accepted the payload.
+	Uploaded bool `json:"uploaded"`
+
+	// UploadStatus is a field of KokuMetricsPayloadStatus to represent the http status of the last upload attempt.
+	// +optional
+	UploadStatus string `json:"upload_status,omitempty"`
+
+	// UploadError is a field of KokuMetricsPayloadStatus to represent the error of the last upload attempt.
+	// +optional
+	UploadError string `json:"upload_error,omitempty"`
+
+	// Attempts is a field of KokuMetricsPayloadStatus to represent the number of times the payload was sent to ingress.
+	Attempts int64 `json:"attempts"`
+
+	// LastUploadTime is a field of KokuMetricsPayloadStatus to represent the time of the last upload attempt.
+	// +nullable
+	LastUploadTime metav1.Time `json:"last_upload_time,omitempty"`
+
+	// RequestID is a field of KokuMetricsPayloadStatus to represent the ID that ingress assigned to the last upload attempt.
+	// +optional
+	RequestID string `json:"request_id,omitempty"`
+
+	// RequestIDs is a field of KokuMetricsPayloadStatus to represent the IDs that ingress assigned to the most recent
+	// upload attempts, oldest first.
+	// +optional
+	RequestIDs []string `json:"request_ids,omitempty"`
+
+	// Endpoint is a field of KokuMetricsPayloadStatus to represent the ingress URL the payload was sent to.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// ProcessingStatus is a field of KokuMetricsPayloadStatus to represent the status of the payload in cost
+	// management after it was accepted. It is empty until the processing status is known.
+	// +optional
+	ProcessingStatus string `json:"processing_status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced
+
+// KokuMetricsPayload is the Schema for the kokumetricspayloads API. The operator creates a KokuMetricsPayload for
+// each payload it uploads, and deletes it when the TTL of the KokuMetricsConfig payload_audit expires.
+type KokuMetricsPayload struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KokuMetricsPayloadSpec   `json:"spec"`
+	Status KokuMetricsPayloadStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KokuMetricsPayloadList contains a list of KokuMetricsPayload
+type KokuMetricsPayloadList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KokuMetricsPayload `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KokuMetricsPayload{}, &KokuMetricsPayloadList{})
+}
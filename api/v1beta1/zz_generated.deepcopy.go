@@ -27,9 +27,95 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIEndpoints) DeepCopyInto(out *APIEndpoints) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIEndpoints.
+func (in *APIEndpoints) DeepCopy() *APIEndpoints {
+	if in == nil {
+		return nil
+	}
+	out := new(APIEndpoints)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdaptiveStepSpec) DeepCopyInto(out *AdaptiveStepSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MaxDurationSeconds != nil {
+		in, out := &in.MaxDurationSeconds, &out.MaxDurationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxMemoryMB != nil {
+		in, out := &in.MaxMemoryMB, &out.MaxMemoryMB
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RecoveryCollections != nil {
+		in, out := &in.RecoveryCollections, &out.RecoveryCollections
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdaptiveStepSpec.
+func (in *AdaptiveStepSpec) DeepCopy() *AdaptiveStepSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AdaptiveStepSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdaptiveStepStatus) DeepCopyInto(out *AdaptiveStepStatus) {
+	*out = *in
+	if in.MaxDurationSeconds != nil {
+		in, out := &in.MaxDurationSeconds, &out.MaxDurationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxMemoryMB != nil {
+		in, out := &in.MaxMemoryMB, &out.MaxMemoryMB
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RecoveryCollections != nil {
+		in, out := &in.RecoveryCollections, &out.RecoveryCollections
+		*out = new(int64)
+		**out = **in
+	}
+	in.LastChangeTime.DeepCopyInto(&out.LastChangeTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdaptiveStepStatus.
+func (in *AdaptiveStepStatus) DeepCopy() *AdaptiveStepStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AdaptiveStepStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthenticationSpec) DeepCopyInto(out *AuthenticationSpec) {
 	*out = *in
+	if in.Methods != nil {
+		in, out := &in.Methods, &out.Methods
+		*out = make([]AuthenticationType, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationSpec.
@@ -45,6 +131,21 @@ func (in *AuthenticationSpec) DeepCopy() *AuthenticationSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthenticationStatus) DeepCopyInto(out *AuthenticationStatus) {
 	*out = *in
+	if in.Methods != nil {
+		in, out := &in.Methods, &out.Methods
+		*out = make([]AuthenticationType, len(*in))
+		copy(*out, *in)
+	}
+	if in.FallbackErrors != nil {
+		in, out := &in.FallbackErrors, &out.FallbackErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RejectedMethods != nil {
+		in, out := &in.RejectedMethods, &out.RejectedMethods
+		*out = make([]AuthenticationType, len(*in))
+		copy(*out, *in)
+	}
 	if in.AuthenticationCredentialsFound != nil {
 		in, out := &in.AuthenticationCredentialsFound, &out.AuthenticationCredentialsFound
 		*out = new(bool)
@@ -61,349 +162,1873 @@ func (in *AuthenticationStatus) DeepCopyInto(out *AuthenticationStatus) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationStatus.
-func (in *AuthenticationStatus) DeepCopy() *AuthenticationStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationStatus.
+func (in *AuthenticationStatus) DeepCopy() *AuthenticationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthenticationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlackoutStatus) DeepCopyInto(out *BlackoutStatus) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+	in.Backfill.DeepCopyInto(&out.Backfill)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlackoutStatus.
+func (in *BlackoutStatus) DeepCopy() *BlackoutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BlackoutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlackoutWindow) DeepCopyInto(out *BlackoutWindow) {
+	*out = *in
+	if in.DurationMinutes != nil {
+		in, out := &in.DurationMinutes, &out.DurationMinutes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Start != nil {
+		in, out := &in.Start, &out.Start
+		*out = (*in).DeepCopy()
+	}
+	if in.End != nil {
+		in, out := &in.End, &out.End
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlackoutWindow.
+func (in *BlackoutWindow) DeepCopy() *BlackoutWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(BlackoutWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertValidationSpec) DeepCopyInto(out *CertValidationSpec) {
+	*out = *in
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertValidationSpec.
+func (in *CertValidationSpec) DeepCopy() *CertValidationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CertValidationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertValidationStatus) DeepCopyInto(out *CertValidationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertValidationStatus.
+func (in *CertValidationStatus) DeepCopy() *CertValidationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertValidationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudDotRedHatSourceSpec) DeepCopyInto(out *CloudDotRedHatSourceSpec) {
+	*out = *in
+	if in.CreateSource != nil {
+		in, out := &in.CreateSource, &out.CreateSource
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CheckCycle != nil {
+		in, out := &in.CheckCycle, &out.CheckCycle
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudDotRedHatSourceSpec.
+func (in *CloudDotRedHatSourceSpec) DeepCopy() *CloudDotRedHatSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudDotRedHatSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudDotRedHatSourceStatus) DeepCopyInto(out *CloudDotRedHatSourceStatus) {
+	*out = *in
+	if in.SourceDefined != nil {
+		in, out := &in.SourceDefined, &out.SourceDefined
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CreateSource != nil {
+		in, out := &in.CreateSource, &out.CreateSource
+		*out = new(bool)
+		**out = **in
+	}
+	in.LastSourceCheckTime.DeepCopyInto(&out.LastSourceCheckTime)
+	if in.CheckCycle != nil {
+		in, out := &in.CheckCycle, &out.CheckCycle
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudDotRedHatSourceStatus.
+func (in *CloudDotRedHatSourceStatus) DeepCopy() *CloudDotRedHatSourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudDotRedHatSourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
+	if in == nil {
+		return nil
+	}
+	out := new(Condition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionCheck) DeepCopyInto(out *ConnectionCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionCheck.
+func (in *ConnectionCheck) DeepCopy() *ConnectionCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionTestStatus) DeepCopyInto(out *ConnectionTestStatus) {
+	*out = *in
+	in.LastRunTime.DeepCopyInto(&out.LastRunTime)
+	out.Prometheus = in.Prometheus
+	out.Ingress = in.Ingress
+	out.Sources = in.Sources
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionTestStatus.
+func (in *ConnectionTestStatus) DeepCopy() *ConnectionTestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionTestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsistencyStatus) DeepCopyInto(out *ConsistencyStatus) {
+	*out = *in
+	if in.MissingNamespaces != nil {
+		in, out := &in.MissingNamespaces, &out.MissingNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MissingNodes != nil {
+		in, out := &in.MissingNodes, &out.MissingNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsistencyStatus.
+func (in *ConsistencyStatus) DeepCopy() *ConsistencyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsistencyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostEstimationSpec) DeepCopyInto(out *CostEstimationSpec) {
+	*out = *in
+	if in.ExposeMetrics != nil {
+		in, out := &in.ExposeMetrics, &out.ExposeMetrics
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostEstimationSpec.
+func (in *CostEstimationSpec) DeepCopy() *CostEstimationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CostEstimationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostEstimationStatus) DeepCopyInto(out *CostEstimationStatus) {
+	*out = *in
+	in.LastEstimateTime.DeepCopyInto(&out.LastEstimateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostEstimationStatus.
+func (in *CostEstimationStatus) DeepCopy() *CostEstimationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CostEstimationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostModelRate) DeepCopyInto(out *CostModelRate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostModelRate.
+func (in *CostModelRate) DeepCopy() *CostModelRate {
+	if in == nil {
+		return nil
+	}
+	out := new(CostModelRate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostModelSpec) DeepCopyInto(out *CostModelSpec) {
+	*out = *in
+	if in.Rates != nil {
+		in, out := &in.Rates, &out.Rates
+		*out = make([]CostModelRate, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostModelSpec.
+func (in *CostModelSpec) DeepCopy() *CostModelSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CostModelSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronJobSpec) DeepCopyInto(out *CronJobSpec) {
+	*out = *in
+	if in.ActiveDeadlineSeconds != nil {
+		in, out := &in.ActiveDeadlineSeconds, &out.ActiveDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronJobSpec.
+func (in *CronJobSpec) DeepCopy() *CronJobSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CronJobSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronJobStatus) DeepCopyInto(out *CronJobStatus) {
+	*out = *in
+	if in.ActiveDeadlineSeconds != nil {
+		in, out := &in.ActiveDeadlineSeconds, &out.ActiveDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	in.LastScheduleTime.DeepCopyInto(&out.LastScheduleTime)
+	in.LastRunTime.DeepCopyInto(&out.LastRunTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronJobStatus.
+func (in *CronJobStatus) DeepCopy() *CronJobStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CronJobStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomMetricQuery) DeepCopyInto(out *CustomMetricQuery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomMetricQuery.
+func (in *CustomMetricQuery) DeepCopy() *CustomMetricQuery {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomMetricQuery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomMetricsSpec) DeepCopyInto(out *CustomMetricsSpec) {
+	*out = *in
+	if in.SkipTLSVerification != nil {
+		in, out := &in.SkipTLSVerification, &out.SkipTLSVerification
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Queries != nil {
+		in, out := &in.Queries, &out.Queries
+		*out = make([]CustomMetricQuery, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomMetricsSpec.
+func (in *CustomMetricsSpec) DeepCopy() *CustomMetricsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomMetricsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomMetricsStatus) DeepCopyInto(out *CustomMetricsStatus) {
+	*out = *in
+	in.LastQuerySuccessTime.DeepCopyInto(&out.LastQuerySuccessTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomMetricsStatus.
+func (in *CustomMetricsStatus) DeepCopy() *CustomMetricsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomMetricsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataResidencySpec) DeepCopyInto(out *DataResidencySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataResidencySpec.
+func (in *DataResidencySpec) DeepCopy() *DataResidencySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DataResidencySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DebugBundleStatus) DeepCopyInto(out *DebugBundleStatus) {
+	*out = *in
+	in.LastGeneratedTime.DeepCopyInto(&out.LastGeneratedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DebugBundleStatus.
+func (in *DebugBundleStatus) DeepCopy() *DebugBundleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DebugBundleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DestinationSpec) DeepCopyInto(out *DestinationSpec) {
+	*out = *in
+	if in.Brokers != nil {
+		in, out := &in.Brokers, &out.Brokers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DestinationSpec.
+func (in *DestinationSpec) DeepCopy() *DestinationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DestinationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DestinationStatus) DeepCopyInto(out *DestinationStatus) {
+	*out = *in
+	in.LastExportTime.DeepCopyInto(&out.LastExportTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DestinationStatus.
+func (in *DestinationStatus) DeepCopy() *DestinationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DestinationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunReport) DeepCopyInto(out *DryRunReport) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunReport.
+func (in *DryRunReport) DeepCopy() *DryRunReport {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunStatus) DeepCopyInto(out *DryRunStatus) {
+	*out = *in
+	in.LastRunTime.DeepCopyInto(&out.LastRunTime)
+	in.IntervalStart.DeepCopyInto(&out.IntervalStart)
+	if in.Reports != nil {
+		in, out := &in.Reports, &out.Reports
+		*out = make([]DryRunReport, len(*in))
+		copy(*out, *in)
+	}
+	if in.EmptyReports != nil {
+		in, out := &in.EmptyReports, &out.EmptyReports
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunStatus.
+func (in *DryRunStatus) DeepCopy() *DryRunStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmbeddedObjectMetadata) DeepCopyInto(out *EmbeddedObjectMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmbeddedObjectMetadata.
+func (in *EmbeddedObjectMetadata) DeepCopy() *EmbeddedObjectMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(EmbeddedObjectMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmbeddedPersistentVolumeClaim) DeepCopyInto(out *EmbeddedPersistentVolumeClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.EmbeddedObjectMetadata.DeepCopyInto(&out.EmbeddedObjectMetadata)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmbeddedPersistentVolumeClaim.
+func (in *EmbeddedPersistentVolumeClaim) DeepCopy() *EmbeddedPersistentVolumeClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(EmbeddedPersistentVolumeClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FleetDefaultsStatus) DeepCopyInto(out *FleetDefaultsStatus) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FleetDefaultsStatus.
+func (in *FleetDefaultsStatus) DeepCopy() *FleetDefaultsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FleetDefaultsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubSpec) DeepCopyInto(out *HubSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubSpec.
+func (in *HubSpec) DeepCopy() *HubSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HubSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubStatus) DeepCopyInto(out *HubStatus) {
+	*out = *in
+	in.LastReceivedTime.DeepCopyInto(&out.LastReceivedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubStatus.
+func (in *HubStatus) DeepCopy() *HubStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HubStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsConfig) DeepCopyInto(out *KokuMetricsConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsConfig.
+func (in *KokuMetricsConfig) DeepCopy() *KokuMetricsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KokuMetricsConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsConfigList) DeepCopyInto(out *KokuMetricsConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KokuMetricsConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsConfigList.
+func (in *KokuMetricsConfigList) DeepCopy() *KokuMetricsConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KokuMetricsConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsConfigSpec) DeepCopyInto(out *KokuMetricsConfigSpec) {
+	*out = *in
+	in.Authentication.DeepCopyInto(&out.Authentication)
+	in.Packaging.DeepCopyInto(&out.Packaging)
+	in.Upload.DeepCopyInto(&out.Upload)
+	in.PrometheusConfig.DeepCopyInto(&out.PrometheusConfig)
+	in.Source.DeepCopyInto(&out.Source)
+	if in.VolumeClaimTemplate != nil {
+		in, out := &in.VolumeClaimTemplate, &out.VolumeClaimTemplate
+		*out = new(EmbeddedPersistentVolumeClaim)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Hub.DeepCopyInto(&out.Hub)
+	if in.UseEmptyDir != nil {
+		in, out := &in.UseEmptyDir, &out.UseEmptyDir
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
+	in.CronJob.DeepCopyInto(&out.CronJob)
+	in.Scheduling.DeepCopyInto(&out.Scheduling)
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.PayloadAudit.DeepCopyInto(&out.PayloadAudit)
+	in.ProcessingStatus.DeepCopyInto(&out.ProcessingStatus)
+	if in.CostModel != nil {
+		in, out := &in.CostModel, &out.CostModel
+		*out = new(CostModelSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CostEstimation != nil {
+		in, out := &in.CostEstimation, &out.CostEstimation
+		*out = new(CostEstimationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.ReportFilters.DeepCopyInto(&out.ReportFilters)
+	if in.CustomMetrics != nil {
+		in, out := &in.CustomMetrics, &out.CustomMetrics
+		*out = new(CustomMetricsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Reporting.DeepCopyInto(&out.Reporting)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsConfigSpec.
+func (in *KokuMetricsConfigSpec) DeepCopy() *KokuMetricsConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsConfigStatus) DeepCopyInto(out *KokuMetricsConfigStatus) {
+	*out = *in
+	in.Authentication.DeepCopyInto(&out.Authentication)
+	in.Packaging.DeepCopyInto(&out.Packaging)
+	in.Upload.DeepCopyInto(&out.Upload)
+	in.Prometheus.DeepCopyInto(&out.Prometheus)
+	in.Reports.DeepCopyInto(&out.Reports)
+	in.Source.DeepCopyInto(&out.Source)
+	in.Storage.DeepCopyInto(&out.Storage)
+	out.Resources = in.Resources
+	in.Hub.DeepCopyInto(&out.Hub)
+	if in.CostModel != nil {
+		in, out := &in.CostModel, &out.CostModel
+		*out = new(CostModelSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.CostEstimation.DeepCopyInto(&out.CostEstimation)
+	in.CustomMetrics.DeepCopyInto(&out.CustomMetrics)
+	out.Reporting = in.Reporting
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(EmbeddedPersistentVolumeClaim)
+		(*in).DeepCopyInto(*out)
+	}
+	in.ConnectionTest.DeepCopyInto(&out.ConnectionTest)
+	in.Preflight.DeepCopyInto(&out.Preflight)
+	in.DryRun.DeepCopyInto(&out.DryRun)
+	in.CronJob.DeepCopyInto(&out.CronJob)
+	in.Scheduling.DeepCopyInto(&out.Scheduling)
+	in.PayloadAudit.DeepCopyInto(&out.PayloadAudit)
+	in.ProcessingStatus.DeepCopyInto(&out.ProcessingStatus)
+	in.Recollection.DeepCopyInto(&out.Recollection)
+	in.Snapshot.DeepCopyInto(&out.Snapshot)
+	in.Pause.DeepCopyInto(&out.Pause)
+	in.Blackout.DeepCopyInto(&out.Blackout)
+	in.Upgrade.DeepCopyInto(&out.Upgrade)
+	in.MissedWindows.DeepCopyInto(&out.MissedWindows)
+	in.FleetDefaults.DeepCopyInto(&out.FleetDefaults)
+	in.DebugBundle.DeepCopyInto(&out.DebugBundle)
+	in.Replay.DeepCopyInto(&out.Replay)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsConfigStatus.
+func (in *KokuMetricsConfigStatus) DeepCopy() *KokuMetricsConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsDefault) DeepCopyInto(out *KokuMetricsDefault) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsDefault.
+func (in *KokuMetricsDefault) DeepCopy() *KokuMetricsDefault {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsDefault)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KokuMetricsDefault) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsDefaultList) DeepCopyInto(out *KokuMetricsDefaultList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KokuMetricsDefault, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsDefaultList.
+func (in *KokuMetricsDefaultList) DeepCopy() *KokuMetricsDefaultList {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsDefaultList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KokuMetricsDefaultList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsPayload) DeepCopyInto(out *KokuMetricsPayload) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsPayload.
+func (in *KokuMetricsPayload) DeepCopy() *KokuMetricsPayload {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsPayload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KokuMetricsPayload) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsPayloadList) DeepCopyInto(out *KokuMetricsPayloadList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KokuMetricsPayload, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsPayloadList.
+func (in *KokuMetricsPayloadList) DeepCopy() *KokuMetricsPayloadList {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsPayloadList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KokuMetricsPayloadList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsPayloadSpec) DeepCopyInto(out *KokuMetricsPayloadSpec) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsPayloadSpec.
+func (in *KokuMetricsPayloadSpec) DeepCopy() *KokuMetricsPayloadSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsPayloadSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KokuMetricsPayloadStatus) DeepCopyInto(out *KokuMetricsPayloadStatus) {
+	*out = *in
+	in.LastUploadTime.DeepCopyInto(&out.LastUploadTime)
+	if in.RequestIDs != nil {
+		in, out := &in.RequestIDs, &out.RequestIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsPayloadStatus.
+func (in *KokuMetricsPayloadStatus) DeepCopy() *KokuMetricsPayloadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KokuMetricsPayloadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MissedWindow) DeepCopyInto(out *MissedWindow) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.LastAttemptTime.DeepCopyInto(&out.LastAttemptTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MissedWindow.
+func (in *MissedWindow) DeepCopy() *MissedWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MissedWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MissedWindowsStatus) DeepCopyInto(out *MissedWindowsStatus) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]MissedWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MissedWindowsStatus.
+func (in *MissedWindowsStatus) DeepCopy() *MissedWindowsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MissedWindowsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringBindingStatus) DeepCopyInto(out *MonitoringBindingStatus) {
+	*out = *in
+	in.LastRepairTime.DeepCopyInto(&out.LastRepairTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringBindingStatus.
+func (in *MonitoringBindingStatus) DeepCopy() *MonitoringBindingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringBindingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackagingSpec) DeepCopyInto(out *PackagingSpec) {
+	*out = *in
+	if in.PackagingCycle != nil {
+		in, out := &in.PackagingCycle, &out.PackagingCycle
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DeltaReports != nil {
+		in, out := &in.DeltaReports, &out.DeltaReports
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackagingSpec.
+func (in *PackagingSpec) DeepCopy() *PackagingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PackagingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackagingStatus) DeepCopyInto(out *PackagingStatus) {
+	*out = *in
+	in.LastSuccessfulPackagingTime.DeepCopyInto(&out.LastSuccessfulPackagingTime)
+	if in.MaxReports != nil {
+		in, out := &in.MaxReports, &out.MaxReports
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxSizeLimit != nil {
+		in, out := &in.MaxSizeLimit, &out.MaxSizeLimit
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PackagedFiles != nil {
+		in, out := &in.PackagedFiles, &out.PackagedFiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PackagingCycle != nil {
+		in, out := &in.PackagingCycle, &out.PackagingCycle
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PayloadHistory != nil {
+		in, out := &in.PayloadHistory, &out.PayloadHistory
+		*out = make([]PayloadSize, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReportCount != nil {
+		in, out := &in.ReportCount, &out.ReportCount
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackagingStatus.
+func (in *PackagingStatus) DeepCopy() *PackagingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PackagingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PauseStatus) DeepCopyInto(out *PauseStatus) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+	in.Backfill.DeepCopyInto(&out.Backfill)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PauseStatus.
+func (in *PauseStatus) DeepCopy() *PauseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PauseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PayloadAuditSpec) DeepCopyInto(out *PayloadAuditSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TTLHours != nil {
+		in, out := &in.TTLHours, &out.TTLHours
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PayloadAuditSpec.
+func (in *PayloadAuditSpec) DeepCopy() *PayloadAuditSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PayloadAuditSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PayloadAuditStatus) DeepCopyInto(out *PayloadAuditStatus) {
+	*out = *in
+	if in.TTLHours != nil {
+		in, out := &in.TTLHours, &out.TTLHours
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PayloadAuditStatus.
+func (in *PayloadAuditStatus) DeepCopy() *PayloadAuditStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PayloadAuditStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PayloadSize) DeepCopyInto(out *PayloadSize) {
+	*out = *in
+	in.PackagedTime.DeepCopyInto(&out.PackagedTime)
+	if in.ReportBytes != nil {
+		in, out := &in.ReportBytes, &out.ReportBytes
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PayloadSize.
+func (in *PayloadSize) DeepCopy() *PayloadSize {
+	if in == nil {
+		return nil
+	}
+	out := new(PayloadSize)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightStatus) DeepCopyInto(out *PreflightStatus) {
+	*out = *in
+	in.LastRunTime.DeepCopyInto(&out.LastRunTime)
+	out.Ingress = in.Ingress
+	out.Sources = in.Sources
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightStatus.
+func (in *PreflightStatus) DeepCopy() *PreflightStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProcessingStatusSpec) DeepCopyInto(out *ProcessingStatusSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PollCycle != nil {
+		in, out := &in.PollCycle, &out.PollCycle
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProcessingStatusSpec.
+func (in *ProcessingStatusSpec) DeepCopy() *ProcessingStatusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProcessingStatusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProcessingStatusStatus) DeepCopyInto(out *ProcessingStatusStatus) {
+	*out = *in
+	if in.PollCycle != nil {
+		in, out := &in.PollCycle, &out.PollCycle
+		*out = new(int64)
+		**out = **in
+	}
+	in.LastPollTime.DeepCopyInto(&out.LastPollTime)
+	in.DataCurrentThrough.DeepCopyInto(&out.DataCurrentThrough)
+	if in.FailedPayloads != nil {
+		in, out := &in.FailedPayloads, &out.FailedPayloads
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProcessingStatusStatus.
+func (in *ProcessingStatusStatus) DeepCopy() *ProcessingStatusStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProcessingStatusStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusEndpointStatus) DeepCopyInto(out *PrometheusEndpointStatus) {
+	*out = *in
+	in.LastQuerySuccessTime.DeepCopyInto(&out.LastQuerySuccessTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusEndpointStatus.
+func (in *PrometheusEndpointStatus) DeepCopy() *PrometheusEndpointStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusEndpointStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSpec) DeepCopyInto(out *PrometheusSpec) {
+	*out = *in
+	if in.SkipTLSVerification != nil {
+		in, out := &in.SkipTLSVerification, &out.SkipTLSVerification
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ShardAddresses != nil {
+		in, out := &in.ShardAddresses, &out.ShardAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.TLS.DeepCopyInto(&out.TLS)
+	if in.ExtraSelectors != nil {
+		in, out := &in.ExtraSelectors, &out.ExtraSelectors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ManageMonitoringBinding != nil {
+		in, out := &in.ManageMonitoringBinding, &out.ManageMonitoringBinding
+		*out = new(bool)
+		**out = **in
+	}
+	in.AdaptiveStep.DeepCopyInto(&out.AdaptiveStep)
+	if in.QueryOverrides != nil {
+		in, out := &in.QueryOverrides, &out.QueryOverrides
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Snapshot.DeepCopyInto(&out.Snapshot)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSpec.
+func (in *PrometheusSpec) DeepCopy() *PrometheusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusStatus) DeepCopyInto(out *PrometheusStatus) {
+	*out = *in
+	in.LastQueryStartTime.DeepCopyInto(&out.LastQueryStartTime)
+	in.LastQuerySuccessTime.DeepCopyInto(&out.LastQuerySuccessTime)
+	if in.SkipTLSVerification != nil {
+		in, out := &in.SkipTLSVerification, &out.SkipTLSVerification
+		*out = new(bool)
+		**out = **in
+	}
+	in.TLS.DeepCopyInto(&out.TLS)
+	if in.ExtraSelectors != nil {
+		in, out := &in.ExtraSelectors, &out.ExtraSelectors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.QueryOverrides != nil {
+		in, out := &in.QueryOverrides, &out.QueryOverrides
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.MonitoringBinding.DeepCopyInto(&out.MonitoringBinding)
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]PrometheusEndpointStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.AdaptiveStep.DeepCopyInto(&out.AdaptiveStep)
+	out.QueryAllowList = in.QueryAllowList
+	in.QueryCost.DeepCopyInto(&out.QueryCost)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusStatus.
+func (in *PrometheusStatus) DeepCopy() *PrometheusStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuarantineStatus) DeepCopyInto(out *QuarantineStatus) {
+	*out = *in
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LastQuarantineTime.DeepCopyInto(&out.LastQuarantineTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuarantineStatus.
+func (in *QuarantineStatus) DeepCopy() *QuarantineStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(QuarantineStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueryAllowListStatus) DeepCopyInto(out *QueryAllowListStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueryAllowListStatus.
+func (in *QueryAllowListStatus) DeepCopy() *QueryAllowListStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(QueryAllowListStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueryCost) DeepCopyInto(out *QueryCost) {
+	*out = *in
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueryCost.
+func (in *QueryCost) DeepCopy() *QueryCost {
+	if in == nil {
+		return nil
+	}
+	out := new(QueryCost)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueryCostStatus) DeepCopyInto(out *QueryCostStatus) {
+	*out = *in
+	if in.SlowestQueries != nil {
+		in, out := &in.SlowestQueries, &out.SlowestQueries
+		*out = make([]QueryCost, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueryCostStatus.
+func (in *QueryCostStatus) DeepCopy() *QueryCostStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(QueryCostStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecollectionStatus) DeepCopyInto(out *RecollectionStatus) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+	in.NextHour.DeepCopyInto(&out.NextHour)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecollectionStatus.
+func (in *RecollectionStatus) DeepCopy() *RecollectionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RecollectionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplayFileStatus) DeepCopyInto(out *ReplayFileStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplayFileStatus.
+func (in *ReplayFileStatus) DeepCopy() *ReplayFileStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplayFileStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplayStatus) DeepCopyInto(out *ReplayStatus) {
+	*out = *in
+	in.LastRunTime.DeepCopyInto(&out.LastRunTime)
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]ReplayFileStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplayStatus.
+func (in *ReplayStatus) DeepCopy() *ReplayStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplayStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportFiltersSpec) DeepCopyInto(out *ReportFiltersSpec) {
+	*out = *in
+	if in.AnnotationPrefixes != nil {
+		in, out := &in.AnnotationPrefixes, &out.AnnotationPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExpectedPodLabels != nil {
+		in, out := &in.ExpectedPodLabels, &out.ExpectedPodLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxLabelValueLength != nil {
+		in, out := &in.MaxLabelValueLength, &out.MaxLabelValueLength
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportFiltersSpec.
+func (in *ReportFiltersSpec) DeepCopy() *ReportFiltersSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportFiltersSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportStat) DeepCopyInto(out *ReportStat) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportStat.
+func (in *ReportStat) DeepCopy() *ReportStat {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportStat)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportingSpec) DeepCopyInto(out *ReportingSpec) {
+	*out = *in
+	if in.PeriodStartDay != nil {
+		in, out := &in.PeriodStartDay, &out.PeriodStartDay
+		*out = new(int64)
+		**out = **in
+	}
+	out.DataResidency = in.DataResidency
+	if in.BlackoutWindows != nil {
+		in, out := &in.BlackoutWindows, &out.BlackoutWindows
+		*out = make([]BlackoutWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.UpgradeDeferral.DeepCopyInto(&out.UpgradeDeferral)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportingSpec.
+func (in *ReportingSpec) DeepCopy() *ReportingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportingStatus) DeepCopyInto(out *ReportingStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportingStatus.
+func (in *ReportingStatus) DeepCopy() *ReportingStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(AuthenticationStatus)
+	out := new(ReportingStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CloudDotRedHatSourceSpec) DeepCopyInto(out *CloudDotRedHatSourceSpec) {
+func (in *ReportsStatus) DeepCopyInto(out *ReportsStatus) {
 	*out = *in
-	if in.CreateSource != nil {
-		in, out := &in.CreateSource, &out.CreateSource
-		*out = new(bool)
-		**out = **in
+	if in.EmptyReports != nil {
+		in, out := &in.EmptyReports, &out.EmptyReports
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	if in.CheckCycle != nil {
-		in, out := &in.CheckCycle, &out.CheckCycle
-		*out = new(int64)
-		**out = **in
+	if in.Tenants != nil {
+		in, out := &in.Tenants, &out.Tenants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReportStats != nil {
+		in, out := &in.ReportStats, &out.ReportStats
+		*out = make([]ReportStat, len(*in))
+		copy(*out, *in)
+	}
+	if in.MissingLabels != nil {
+		in, out := &in.MissingLabels, &out.MissingLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
+	out.SanitizedValues = in.SanitizedValues
+	in.Inconsistencies.DeepCopyInto(&out.Inconsistencies)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudDotRedHatSourceSpec.
-func (in *CloudDotRedHatSourceSpec) DeepCopy() *CloudDotRedHatSourceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportsStatus.
+func (in *ReportsStatus) DeepCopy() *ReportsStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(CloudDotRedHatSourceSpec)
+	out := new(ReportsStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CloudDotRedHatSourceStatus) DeepCopyInto(out *CloudDotRedHatSourceStatus) {
+func (in *ResourcesSpec) DeepCopyInto(out *ResourcesSpec) {
 	*out = *in
-	if in.SourceDefined != nil {
-		in, out := &in.SourceDefined, &out.SourceDefined
-		*out = new(bool)
+	if in.MaxParallelQueries != nil {
+		in, out := &in.MaxParallelQueries, &out.MaxParallelQueries
+		*out = new(int64)
 		**out = **in
 	}
-	if in.CreateSource != nil {
-		in, out := &in.CreateSource, &out.CreateSource
-		*out = new(bool)
+	if in.CompressionWorkers != nil {
+		in, out := &in.CompressionWorkers, &out.CompressionWorkers
+		*out = new(int64)
 		**out = **in
 	}
-	in.LastSourceCheckTime.DeepCopyInto(&out.LastSourceCheckTime)
-	if in.CheckCycle != nil {
-		in, out := &in.CheckCycle, &out.CheckCycle
+	if in.LogBufferLines != nil {
+		in, out := &in.LogBufferLines, &out.LogBufferLines
+		*out = new(int64)
+		**out = **in
+	}
+	if in.CopyBufferKiB != nil {
+		in, out := &in.CopyBufferKiB, &out.CopyBufferKiB
 		*out = new(int64)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudDotRedHatSourceStatus.
-func (in *CloudDotRedHatSourceStatus) DeepCopy() *CloudDotRedHatSourceStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourcesSpec.
+func (in *ResourcesSpec) DeepCopy() *ResourcesSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(CloudDotRedHatSourceStatus)
+	out := new(ResourcesSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *EmbeddedObjectMetadata) DeepCopyInto(out *EmbeddedObjectMetadata) {
+func (in *ResourcesStatus) DeepCopyInto(out *ResourcesStatus) {
 	*out = *in
-	if in.Labels != nil {
-		in, out := &in.Labels, &out.Labels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.Annotations != nil {
-		in, out := &in.Annotations, &out.Annotations
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmbeddedObjectMetadata.
-func (in *EmbeddedObjectMetadata) DeepCopy() *EmbeddedObjectMetadata {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourcesStatus.
+func (in *ResourcesStatus) DeepCopy() *ResourcesStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(EmbeddedObjectMetadata)
+	out := new(ResourcesStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *EmbeddedPersistentVolumeClaim) DeepCopyInto(out *EmbeddedPersistentVolumeClaim) {
+func (in *SanitizedValuesStatus) DeepCopyInto(out *SanitizedValuesStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.EmbeddedObjectMetadata.DeepCopyInto(&out.EmbeddedObjectMetadata)
-	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmbeddedPersistentVolumeClaim.
-func (in *EmbeddedPersistentVolumeClaim) DeepCopy() *EmbeddedPersistentVolumeClaim {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SanitizedValuesStatus.
+func (in *SanitizedValuesStatus) DeepCopy() *SanitizedValuesStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(EmbeddedPersistentVolumeClaim)
+	out := new(SanitizedValuesStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KokuMetricsConfig) DeepCopyInto(out *KokuMetricsConfig) {
+func (in *SchedulingSpec) DeepCopyInto(out *SchedulingSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsConfig.
-func (in *KokuMetricsConfig) DeepCopy() *KokuMetricsConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulingSpec.
+func (in *SchedulingSpec) DeepCopy() *SchedulingSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(KokuMetricsConfig)
+	out := new(SchedulingSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *KokuMetricsConfig) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KokuMetricsConfigList) DeepCopyInto(out *KokuMetricsConfigList) {
+func (in *SchedulingStatus) DeepCopyInto(out *SchedulingStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]KokuMetricsConfig, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsConfigList.
-func (in *KokuMetricsConfigList) DeepCopy() *KokuMetricsConfigList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulingStatus.
+func (in *SchedulingStatus) DeepCopy() *SchedulingStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(KokuMetricsConfigList)
+	out := new(SchedulingStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *KokuMetricsConfigList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KokuMetricsConfigSpec) DeepCopyInto(out *KokuMetricsConfigSpec) {
+func (in *SnapshotSpec) DeepCopyInto(out *SnapshotSpec) {
 	*out = *in
-	out.Authentication = in.Authentication
-	out.Packaging = in.Packaging
-	in.Upload.DeepCopyInto(&out.Upload)
-	in.PrometheusConfig.DeepCopyInto(&out.PrometheusConfig)
-	in.Source.DeepCopyInto(&out.Source)
-	if in.VolumeClaimTemplate != nil {
-		in, out := &in.VolumeClaimTemplate, &out.VolumeClaimTemplate
-		*out = new(EmbeddedPersistentVolumeClaim)
-		(*in).DeepCopyInto(*out)
+	if in.SkipTLSVerification != nil {
+		in, out := &in.SkipTLSVerification, &out.SkipTLSVerification
+		*out = new(bool)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsConfigSpec.
-func (in *KokuMetricsConfigSpec) DeepCopy() *KokuMetricsConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotSpec.
+func (in *SnapshotSpec) DeepCopy() *SnapshotSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(KokuMetricsConfigSpec)
+	out := new(SnapshotSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KokuMetricsConfigStatus) DeepCopyInto(out *KokuMetricsConfigStatus) {
+func (in *SnapshotStatus) DeepCopyInto(out *SnapshotStatus) {
 	*out = *in
-	in.Authentication.DeepCopyInto(&out.Authentication)
-	in.Packaging.DeepCopyInto(&out.Packaging)
-	in.Upload.DeepCopyInto(&out.Upload)
-	in.Prometheus.DeepCopyInto(&out.Prometheus)
-	out.Reports = in.Reports
-	in.Source.DeepCopyInto(&out.Source)
-	out.Storage = in.Storage
-	if in.PersistentVolumeClaim != nil {
-		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
-		*out = new(EmbeddedPersistentVolumeClaim)
-		(*in).DeepCopyInto(*out)
-	}
+	in.Recollection.DeepCopyInto(&out.Recollection)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KokuMetricsConfigStatus.
-func (in *KokuMetricsConfigStatus) DeepCopy() *KokuMetricsConfigStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotStatus.
+func (in *SnapshotStatus) DeepCopy() *SnapshotStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(KokuMetricsConfigStatus)
+	out := new(SnapshotStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackagingSpec) DeepCopyInto(out *PackagingSpec) {
+func (in *StorageStatus) DeepCopyInto(out *StorageStatus) {
 	*out = *in
+	if in.MigratedLayouts != nil {
+		in, out := &in.MigratedLayouts, &out.MigratedLayouts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LastMigrationTime.DeepCopyInto(&out.LastMigrationTime)
+	if in.AppliedMigrations != nil {
+		in, out := &in.AppliedMigrations, &out.AppliedMigrations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackagingSpec.
-func (in *PackagingSpec) DeepCopy() *PackagingSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageStatus.
+func (in *StorageStatus) DeepCopy() *StorageStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PackagingSpec)
+	out := new(StorageStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackagingStatus) DeepCopyInto(out *PackagingStatus) {
+func (in *TLSSpec) DeepCopyInto(out *TLSSpec) {
 	*out = *in
-	in.LastSuccessfulPackagingTime.DeepCopyInto(&out.LastSuccessfulPackagingTime)
-	if in.MaxReports != nil {
-		in, out := &in.MaxReports, &out.MaxReports
-		*out = new(int64)
-		**out = **in
-	}
-	if in.MaxSize != nil {
-		in, out := &in.MaxSize, &out.MaxSize
-		*out = new(int64)
-		**out = **in
-	}
-	if in.PackagedFiles != nil {
-		in, out := &in.PackagedFiles, &out.PackagedFiles
+	if in.CipherSuites != nil {
+		in, out := &in.CipherSuites, &out.CipherSuites
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.ReportCount != nil {
-		in, out := &in.ReportCount, &out.ReportCount
-		*out = new(int64)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackagingStatus.
-func (in *PackagingStatus) DeepCopy() *PackagingStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSpec.
+func (in *TLSSpec) DeepCopy() *TLSSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PackagingStatus)
+	out := new(TLSSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PrometheusSpec) DeepCopyInto(out *PrometheusSpec) {
+func (in *UpgradeDeferralSpec) DeepCopyInto(out *UpgradeDeferralSpec) {
 	*out = *in
-	if in.SkipTLSVerification != nil {
-		in, out := &in.SkipTLSVerification, &out.SkipTLSVerification
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
 		*out = new(bool)
 		**out = **in
 	}
+	if in.DeferUpload != nil {
+		in, out := &in.DeferUpload, &out.DeferUpload
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SettleMinutes != nil {
+		in, out := &in.SettleMinutes, &out.SettleMinutes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxHours != nil {
+		in, out := &in.MaxHours, &out.MaxHours
+		*out = new(int64)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSpec.
-func (in *PrometheusSpec) DeepCopy() *PrometheusSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeDeferralSpec.
+func (in *UpgradeDeferralSpec) DeepCopy() *UpgradeDeferralSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PrometheusSpec)
+	out := new(UpgradeDeferralSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PrometheusStatus) DeepCopyInto(out *PrometheusStatus) {
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
 	*out = *in
-	in.LastQueryStartTime.DeepCopyInto(&out.LastQueryStartTime)
-	in.LastQuerySuccessTime.DeepCopyInto(&out.LastQuerySuccessTime)
-	if in.SkipTLSVerification != nil {
-		in, out := &in.SkipTLSVerification, &out.SkipTLSVerification
-		*out = new(bool)
-		**out = **in
-	}
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+	in.Backfill.DeepCopyInto(&out.Backfill)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusStatus.
-func (in *PrometheusStatus) DeepCopy() *PrometheusStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PrometheusStatus)
+	out := new(UpgradeStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReportsStatus) DeepCopyInto(out *ReportsStatus) {
+func (in *UploadHeader) DeepCopyInto(out *UploadHeader) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportsStatus.
-func (in *ReportsStatus) DeepCopy() *ReportsStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UploadHeader.
+func (in *UploadHeader) DeepCopy() *UploadHeader {
 	if in == nil {
 		return nil
 	}
-	out := new(ReportsStatus)
+	out := new(UploadHeader)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *StorageStatus) DeepCopyInto(out *StorageStatus) {
+func (in *UploadQueueStatus) DeepCopyInto(out *UploadQueueStatus) {
 	*out = *in
+	in.LastBatchStartTime.DeepCopyInto(&out.LastBatchStartTime)
+	in.LastBatchEndTime.DeepCopyInto(&out.LastBatchEndTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageStatus.
-func (in *StorageStatus) DeepCopy() *StorageStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UploadQueueStatus.
+func (in *UploadQueueStatus) DeepCopy() *UploadQueueStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(StorageStatus)
+	out := new(UploadQueueStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -421,6 +2046,26 @@ func (in *UploadSpec) DeepCopyInto(out *UploadSpec) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		*out = new(int64)
+		**out = **in
+	}
+	if in.UploadInterval != nil {
+		in, out := &in.UploadInterval, &out.UploadInterval
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxBackfillUploads != nil {
+		in, out := &in.MaxBackfillUploads, &out.MaxBackfillUploads
+		*out = new(int64)
+		**out = **in
+	}
+	if in.QuarantineAfterRejections != nil {
+		in, out := &in.QuarantineAfterRejections, &out.QuarantineAfterRejections
+		*out = new(int64)
+		**out = **in
+	}
 	if in.UploadToggle != nil {
 		in, out := &in.UploadToggle, &out.UploadToggle
 		*out = new(bool)
@@ -431,6 +2076,30 @@ func (in *UploadSpec) DeepCopyInto(out *UploadSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	in.TLS.DeepCopyInto(&out.TLS)
+	in.CertValidation.DeepCopyInto(&out.CertValidation)
+	if in.ExtraHeaders != nil {
+		in, out := &in.ExtraHeaders, &out.ExtraHeaders
+		*out = make([]UploadHeader, len(*in))
+		copy(*out, *in)
+	}
+	if in.FallbackAPIURLs != nil {
+		in, out := &in.FallbackAPIURLs, &out.FallbackAPIURLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailoverThreshold != nil {
+		in, out := &in.FailoverThreshold, &out.FailoverThreshold
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]DestinationSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UploadSpec.
@@ -461,12 +2130,48 @@ func (in *UploadStatus) DeepCopyInto(out *UploadStatus) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.UploadInterval != nil {
+		in, out := &in.UploadInterval, &out.UploadInterval
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxBackfillUploads != nil {
+		in, out := &in.MaxBackfillUploads, &out.MaxBackfillUploads
+		*out = new(int64)
+		**out = **in
+	}
+	if in.QuarantineAfterRejections != nil {
+		in, out := &in.QuarantineAfterRejections, &out.QuarantineAfterRejections
+		*out = new(int64)
+		**out = **in
+	}
+	in.Queue.DeepCopyInto(&out.Queue)
+	in.Quarantine.DeepCopyInto(&out.Quarantine)
 	in.LastSuccessfulUploadTime.DeepCopyInto(&out.LastSuccessfulUploadTime)
 	if in.ValidateCert != nil {
 		in, out := &in.ValidateCert, &out.ValidateCert
 		*out = new(bool)
 		**out = **in
 	}
+	in.TLS.DeepCopyInto(&out.TLS)
+	out.CertValidation = in.CertValidation
+	if in.ExtraHeaders != nil {
+		in, out := &in.ExtraHeaders, &out.ExtraHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailoverThreshold != nil {
+		in, out := &in.FailoverThreshold, &out.FailoverThreshold
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]DestinationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UploadStatus.
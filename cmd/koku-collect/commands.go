@@ -0,0 +1,197 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	promconfig "github.com/prometheus/common/config"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/controllers"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+)
+
+// collect writes the reports of the hours to the reports directory
+func collect(s *session) error {
+	hours, err := hoursToCollect(s.opts.start, s.opts.hours, time.Now(), controllers.BillingLocation(s.kmCfg))
+	if err != nil {
+		return fmt.Errorf("collect: %v", err)
+	}
+	conn, err := s.prometheusConnection()
+	if err != nil {
+		return fmt.Errorf("collect: %v", err)
+	}
+
+	c := &collector.PromCollector{PromConn: conn, Log: s.log}
+	controllers.ConfigureCollector(c, s.kmCfg)
+	if len(c.CustomMetrics) > 0 {
+		// custom metrics are queried from the user workload monitoring prometheus, which is only reached in-cluster
+		s.log.Info("custom metrics are not collected")
+		c.CustomMetrics = nil
+	}
+	w := &collector.DirWriter{Path: s.dirCfg.Reports.Path, Log: s.log}
+	for _, hour := range hours {
+		timeSeries := controllers.HourTimeSeries(s.kmCfg, hour)
+		c.TimeSeries = &timeSeries
+		result, err := c.Generate(w)
+		if err != nil {
+			return fmt.Errorf("collect: %s: %v", hour.Format(time.RFC3339), err)
+		}
+		if !result.DataCollected {
+			fmt.Printf("%s: no data collected\n", hour.Format(time.RFC3339))
+			continue
+		}
+		fmt.Printf("%s: wrote %s\n", hour.Format(time.RFC3339), strings.Join(result.Files, ", "))
+		if result.Anomaly != "" {
+			fmt.Printf("%s: %s\n", hour.Format(time.RFC3339), result.Anomaly)
+		}
+	}
+	return nil
+}
+
+// prometheusConnection returns the connection to the fixtures, or to the prometheus of the flags
+func (s *session) prometheusConnection() (collector.PrometheusConnection, error) {
+	if s.opts.fixtures != "" {
+		fixtures, err := collector.LoadFixtures(s.opts.fixtures)
+		if err != nil {
+			return nil, fmt.Errorf("prometheusConnection: %v", err)
+		}
+		return fixtures.Connection(), nil
+	}
+	if s.opts.prometheusURL == "" {
+		return nil, fmt.Errorf("prometheusConnection: --prometheus-url is required unless --fixtures is set")
+	}
+	token := s.opts.prometheusToken
+	if token == "" {
+		if cfg, err := config.GetConfig(); err == nil {
+			token = cfg.BearerToken
+		}
+	}
+	return collector.NewPrometheusConnection(&collector.PrometheusConfig{
+		Address:     s.opts.prometheusURL,
+		BearerToken: promconfig.Secret(token),
+		CAFile:      s.opts.prometheusCA,
+		SkipTLS:     s.opts.skipTLS,
+	})
+}
+
+// packageReports packages the reports of the reports directory into payloads in the upload directory
+func packageReports(s *session) error {
+	if err := s.clusterID(); err != nil {
+		return fmt.Errorf("package: %v", err)
+	}
+	packager := &packaging.FilePackager{KMCfg: s.kmCfg, DirCfg: s.dirCfg, Log: s.log}
+	if err := packager.PackageReports(); err != nil {
+		return fmt.Errorf("package: %v", err)
+	}
+	files, err := s.dirCfg.Upload.GetFiles()
+	if err != nil {
+		return fmt.Errorf("package: %v", err)
+	}
+	for _, file := range files {
+		fmt.Printf("packaged %s\n", filepath.Join(s.dirCfg.Upload.Path, file))
+	}
+	return nil
+}
+
+// upload uploads the payloads of the upload directory to ingress. Uploaded payloads are removed unless --keep is set.
+func upload(s *session) error {
+	authConfig, err := s.uploadAuthConfig()
+	if err != nil {
+		return fmt.Errorf("upload: %v", err)
+	}
+	ingress := &exporter.Ingress{
+		AuthConfig: authConfig,
+		URL:        controllers.IngressURL(s.kmCfg),
+		Format:     s.kmCfg.Status.Upload.PayloadFormat,
+	}
+	files, err := s.dirCfg.Upload.GetFiles()
+	if err != nil {
+		return fmt.Errorf("upload: %v", err)
+	}
+	if len(files) <= 0 {
+		fmt.Println("no files to upload")
+		return nil
+	}
+	for _, file := range files {
+		path := filepath.Join(s.dirCfg.Upload.Path, file)
+		summary, err := packaging.ReadPayloadSummary(path)
+		if err != nil {
+			return fmt.Errorf("upload: %v", err)
+		}
+		item := exporter.Payload{Path: path, Name: file, PayloadID: summary.PayloadID}
+		if identity, err := packaging.ReadPayloadIdentity(path); err == nil {
+			item.IdempotencyKey = packaging.IdempotencyKey(identity)
+		}
+		if err := ingress.Export(item); err != nil {
+			return fmt.Errorf("upload: %s: %v", file, err)
+		}
+		fmt.Printf("uploaded %s: %s, request ID %s\n", file, ingress.LastStatus, ingress.LastRequestID)
+		if !s.opts.keep {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("upload: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// uploadAuthConfig returns the upload configuration with the token of the flag, or the credentials of the
+// KokuMetricsConfig
+func (s *session) uploadAuthConfig() (*crhchttp.AuthConfig, error) {
+	if err := s.clusterID(); err != nil {
+		return nil, err
+	}
+	if s.opts.uploadToken != "" {
+		return &crhchttp.AuthConfig{
+			Log:               s.log,
+			CertPolicy:        controllers.CertPolicy(s.kmCfg),
+			Authentication:    kokumetricscfgv1beta1.Token,
+			BearerTokenString: s.opts.uploadToken,
+			OperatorCommit:    s.kmCfg.Status.OperatorCommit,
+			ClusterID:         s.kmCfg.Status.ClusterID,
+		}, nil
+	}
+	r, err := s.kube()
+	if err != nil {
+		return nil, err
+	}
+	return controllers.UploadAuthConfig(r, s.kmCfg, types.NamespacedName{Namespace: s.kmCfg.Namespace, Name: s.kmCfg.Name})
+}
+
+// runAll collects, packages, and uploads
+func runAll(s *session) error {
+	for _, command := range []func(s *session) error{collect, packageReports, upload} {
+		if err := command(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/controllers"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestCollectAndPackageFixtures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "koku-collect")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	hour := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	opts := options{
+		dir:       filepath.Join(dir, "reports"),
+		namespace: "koku-metrics-operator",
+		clusterID: "cluster-id",
+		fixtures:  filepath.Join(dir, "fixtures"),
+		start:     hour.Format(time.RFC3339),
+		hours:     2,
+	}
+	s, err := newSession(opts, testutils.TestLogger{})
+	if err != nil {
+		t.Fatalf("newSession got unexpected error: %v", err)
+	}
+	fixtures := collector.SyntheticFixtures(2, 10, controllers.HourTimeSeries(s.kmCfg, hour))
+	if err := fixtures.Save(opts.fixtures); err != nil {
+		t.Fatalf("failed to save fixtures: %v", err)
+	}
+
+	if err := collect(s); err != nil {
+		t.Fatalf("collect got unexpected error: %v", err)
+	}
+	reports, err := s.dirCfg.Reports.GetFiles()
+	if err != nil || len(reports) == 0 {
+		t.Fatalf("collect got reports %v (%v) want reports", reports, err)
+	}
+
+	if err := packageReports(s); err != nil {
+		t.Fatalf("packageReports got unexpected error: %v", err)
+	}
+	payloads, err := s.dirCfg.Upload.GetFiles()
+	if err != nil || len(payloads) == 0 {
+		t.Fatalf("packageReports got payloads %v (%v) want payloads", payloads, err)
+	}
+	for _, payload := range payloads {
+		summary, err := packaging.ReadPayloadSummary(filepath.Join(s.dirCfg.Upload.Path, payload))
+		if err != nil {
+			t.Fatalf("%s got unexpected error: %v", payload, err)
+		}
+		if summary.PayloadID == "" || len(summary.Files) == 0 {
+			t.Errorf("%s got summary %+v want a payload ID and report files", payload, summary)
+		}
+	}
+}
+
+func TestUploadRequiresClusterID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "koku-collect")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	opts := options{dir: dir, namespace: "koku-metrics-operator", uploadToken: "token"}
+	s, err := newSession(opts, testutils.TestLogger{})
+	if err != nil {
+		t.Fatalf("newSession got unexpected error: %v", err)
+	}
+	// point the kubeconfig at a file that does not exist, so that the cluster ID cannot be read from a cluster
+	os.Setenv("KUBECONFIG", filepath.Join(dir, "missing"))
+	defer os.Unsetenv("KUBECONFIG")
+	if _, err := s.uploadAuthConfig(); err == nil {
+		t.Errorf("uploadAuthConfig got no error want an error without a cluster ID")
+	}
+
+	s.opts.clusterID = "cluster-id"
+	authConfig, err := s.uploadAuthConfig()
+	if err != nil {
+		t.Fatalf("uploadAuthConfig got unexpected error: %v", err)
+	}
+	if authConfig.BearerTokenString != "token" || authConfig.ClusterID != "cluster-id" {
+		t.Errorf("uploadAuthConfig got %+v want the token and cluster ID of the flags", authConfig)
+	}
+}
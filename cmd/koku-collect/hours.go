@@ -0,0 +1,55 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// hoursToCollect returns the starts of the hours to collect: the hours from start, or the previous full hour when
+// start is empty. The hours are UTC hours so that no hour is skipped or repeated when daylight saving time changes; loc
+// only sets the location of the returned times.
+func hoursToCollect(start string, hours int, now time.Time, loc *time.Location) ([]time.Time, error) {
+	if hours < 1 {
+		return nil, fmt.Errorf("hoursToCollect: hours must be at least 1, got %d", hours)
+	}
+	first := now.UTC().Truncate(time.Hour).Add(-time.Duration(hours) * time.Hour).In(loc)
+	if start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return nil, fmt.Errorf("hoursToCollect: invalid start: %v", err)
+		}
+		if t.Minute() != 0 || t.Second() != 0 || t.Nanosecond() != 0 {
+			return nil, fmt.Errorf("hoursToCollect: start %s is not the start of an hour", start)
+		}
+		first = t.In(loc)
+	}
+
+	result := make([]time.Time, 0, hours)
+	for i := 0; i < hours; i++ {
+		hour := first.Add(time.Duration(i) * time.Hour)
+		if hour.Add(time.Hour).After(now) {
+			return nil, fmt.Errorf("hoursToCollect: the hour %s has not ended", hour.Format(time.RFC3339))
+		}
+		result = append(result, hour)
+	}
+	return result, nil
+}
@@ -0,0 +1,132 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHoursToCollect(t *testing.T) {
+	now := time.Date(2021, 3, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		start string
+		hours int
+		want  []time.Time
+		err   bool
+	}{
+		{
+			name:  "previous full hour",
+			hours: 1,
+			want:  []time.Time{time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:  "hours before the current hour",
+			hours: 2,
+			want:  []time.Time{time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC), time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:  "hours from start",
+			start: "2021-02-28T23:00:00Z",
+			hours: 2,
+			want:  []time.Time{time.Date(2021, 2, 28, 23, 0, 0, 0, time.UTC), time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:  "start with offset",
+			start: "2021-03-01T04:00:00-05:00",
+			hours: 1,
+			want:  []time.Time{time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:  "start within an hour",
+			start: "2021-03-01T04:30:00Z",
+			hours: 1,
+			err:   true,
+		},
+		{
+			name:  "invalid start",
+			start: "yesterday",
+			hours: 1,
+			err:   true,
+		},
+		{
+			name:  "hour has not ended",
+			start: "2021-03-01T09:00:00Z",
+			hours: 2,
+			err:   true,
+		},
+		{
+			name:  "no hours",
+			hours: 0,
+			err:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hoursToCollect(tt.start, tt.hours, now, time.UTC)
+			if tt.err {
+				if err == nil {
+					t.Errorf("%s got no error want error", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s got unexpected error: %v", tt.name, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%s got %v want %v", tt.name, got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("%s got %v want %v", tt.name, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestHoursToCollectDaylightSavingTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	// 2021-11-07 05:00 and 06:00 UTC are both 01:00 in New York.
+	now := time.Date(2021, 11, 7, 7, 10, 0, 0, time.UTC)
+	got, err := hoursToCollect("", 3, now, loc)
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	want := []time.Time{
+		time.Date(2021, 11, 7, 4, 0, 0, 0, time.UTC),
+		time.Date(2021, 11, 7, 5, 0, 0, 0, time.UTC),
+		time.Date(2021, 11, 7, 6, 0, 0, 0, time.UTC),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("got %v want %v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// koku-collect runs the collection, packaging, and upload of the operator a single time, outside of the operator.
+// It is used to debug the reports of a cluster, to collect reports by hand where the operator cannot upload them,
+// and to validate the payloads in CI against recorded prometheus fixtures.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+var (
+	scheme = runtime.NewScheme()
+	log    = ctrl.Log.WithName("koku-collect")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(configv1.AddToScheme(scheme))
+	utilruntime.Must(kokumetricscfgv1beta1.AddToScheme(scheme))
+}
+
+const usage = `koku-collect runs the collection, packaging, and upload of the koku-metrics-operator once.
+
+Usage:
+  koku-collect <command> [flags]
+
+Commands:
+  collect   query prometheus and write the reports of the hours to the reports directory
+  package   package the reports into payloads in the upload directory
+  upload    upload the payloads of the upload directory to ingress
+  run       collect, package, and upload
+
+Flags:
+`
+
+// commands are the functions of the commands, by name
+var commands = map[string]func(s *session) error{
+	"collect": collect,
+	"package": packageReports,
+	"upload":  upload,
+	"run":     runAll,
+}
+
+func main() {
+	opts := options{}
+	opts.bindFlags(flag.CommandLine)
+	zapOpts := zap.Options{Development: true}
+	zapOpts.BindFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	if len(os.Args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(flag.CommandLine.Output(), "unknown command %q\n\n", os.Args[1])
+		flag.Usage()
+		os.Exit(2)
+	}
+	// the command is given before the flags
+	_ = flag.CommandLine.Parse(os.Args[2:])
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&zapOpts)))
+
+	s, err := newSession(opts, log)
+	if err == nil {
+		err = command(s)
+	}
+	if err != nil {
+		log.Error(err, os.Args[1]+" failed")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/clusterversion"
+	"github.com/project-koku/koku-metrics-operator/controllers"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// options are the flags of the commands
+type options struct {
+	dir       string
+	namespace string
+	config    string
+	clusterID string
+
+	prometheusURL   string
+	prometheusToken string
+	prometheusCA    string
+	skipTLS         bool
+	fixtures        string
+	start           string
+	hours           int
+
+	uploadToken string
+	keep        bool
+}
+
+func (o *options) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.dir, "dir", "koku-collect", "The directory that the reports, staging, and upload directories are created in.")
+	fs.StringVar(&o.namespace, "namespace", "koku-metrics-operator", "The namespace of the KokuMetricsConfig and its secrets.")
+	fs.StringVar(&o.config, "config", "", "The name of a KokuMetricsConfig to read the settings and credentials from. When empty, the defaults are used.")
+	fs.StringVar(&o.clusterID, "cluster-id", "", "The cluster ID of the reports. When empty, it is read from the ClusterVersion of the cluster.")
+	fs.StringVar(&o.prometheusURL, "prometheus-url", "", "The URL of prometheus or thanos-querier, for example a route to it.")
+	fs.StringVar(&o.prometheusToken, "prometheus-token", "", "The bearer token sent to prometheus. When empty, the token of the kubeconfig is used.")
+	fs.StringVar(&o.prometheusCA, "prometheus-ca-file", "", "The CA file used to verify the certificate of prometheus.")
+	fs.BoolVar(&o.skipTLS, "insecure-skip-tls-verify", false, "Do not verify the certificate of prometheus.")
+	fs.StringVar(&o.fixtures, "fixtures", "", "A directory of recorded prometheus responses that the reports are collected from instead of prometheus.")
+	fs.StringVar(&o.start, "start", "", "The first hour to collect, in RFC3339. When empty, the hours before the current hour are collected.")
+	fs.IntVar(&o.hours, "hours", 1, "The number of hours to collect.")
+	fs.StringVar(&o.uploadToken, "upload-token", "", "The bearer token used to upload. When empty, the credentials of the KokuMetricsConfig are used.")
+	fs.BoolVar(&o.keep, "keep", false, "Keep the payloads in the upload directory after they are uploaded.")
+}
+
+// session is the state shared by the commands of a single run
+type session struct {
+	opts   options
+	log    logr.Logger
+	kmCfg  *kokumetricscfgv1beta1.KokuMetricsConfig
+	dirCfg *dirconfig.DirectoryConfig
+
+	// r reflects the spec. The clients of the cluster are only set by kube, when a command needs them.
+	r          *controllers.KokuMetricsConfigReconciler
+	kubeClient bool
+}
+
+// newSession creates the working directories, and reads the settings of the run from the KokuMetricsConfig, or
+// the defaults
+func newSession(opts options, log logr.Logger) (*session, error) {
+	s := &session{
+		opts:   opts,
+		log:    log,
+		dirCfg: new(dirconfig.DirectoryConfig),
+		r:      &controllers.KokuMetricsConfigReconciler{Log: log, Namespace: opts.namespace},
+	}
+	if err := s.dirCfg.GetDirectoryConfigAt(opts.dir); err != nil {
+		return nil, fmt.Errorf("newSession: %v", err)
+	}
+
+	kmCfg := defaultConfig(opts.namespace)
+	if opts.config != "" {
+		r, err := s.kube()
+		if err != nil {
+			return nil, fmt.Errorf("newSession: %v", err)
+		}
+		if err := r.Get(context.Background(), types.NamespacedName{Namespace: opts.namespace, Name: opts.config}, kmCfg); err != nil {
+			return nil, fmt.Errorf("newSession: failed to get KokuMetricsConfig: %v", err)
+		}
+	}
+	controllers.ReflectSpec(s.r, kmCfg)
+	kmCfg.Status.OperatorCommit = controllers.GitCommit
+	if commit, ok := os.LookupEnv("GIT_COMMIT"); ok && kmCfg.Status.OperatorCommit == "" {
+		kmCfg.Status.OperatorCommit = commit
+	}
+	s.kmCfg = kmCfg
+	return s, nil
+}
+
+// defaultConfig returns a KokuMetricsConfig with the defaults of the operator, used when no KokuMetricsConfig is given
+func defaultConfig(namespace string) *kokumetricscfgv1beta1.KokuMetricsConfig {
+	validateCert := kokumetricscfgv1beta1.DefaultValidateCert
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Name = "koku-collect"
+	kmCfg.Namespace = namespace
+	kmCfg.Spec.Authentication.AuthType = kokumetricscfgv1beta1.DefaultAuthenticationType
+	kmCfg.Spec.Upload.ValidateCert = &validateCert
+	return kmCfg
+}
+
+// kube returns the reconciler with the clients of the cluster of the kubeconfig
+func (s *session) kube() (*controllers.KokuMetricsConfigReconciler, error) {
+	if s.kubeClient {
+		return s.r, nil
+	}
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("kube: failed to read kubeconfig: %v", err)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("kube: failed to create client: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("kube: failed to create clientset: %v", err)
+	}
+	s.r.Client = c
+	s.r.Clientset = clientset
+	s.r.Scheme = scheme
+	// secrets are read by name from the api server, as there is no cache
+	s.r.SecretReader = c
+	s.kubeClient = true
+	return s.r, nil
+}
+
+// clusterID sets the cluster ID of the reports from the flag, the KokuMetricsConfig, or the ClusterVersion
+func (s *session) clusterID() error {
+	if s.opts.clusterID != "" {
+		s.kmCfg.Status.ClusterID = s.opts.clusterID
+	}
+	if s.kmCfg.Status.ClusterID != "" {
+		return nil
+	}
+	r, err := s.kube()
+	if err != nil {
+		return fmt.Errorf("clusterID: a cluster ID is required: %v", err)
+	}
+	cv, err := clusterversion.NewCVClient(r.Client).GetClusterVersion()
+	if err != nil {
+		return fmt.Errorf("clusterID: failed to get ClusterVersion: %v", err)
+	}
+	if cv.Spec.ClusterID == "" {
+		return fmt.Errorf("clusterID: the ClusterVersion has no cluster ID")
+	}
+	s.kmCfg.Status.ClusterID = string(cv.Spec.ClusterID)
+	return nil
+}
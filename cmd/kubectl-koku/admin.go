@@ -0,0 +1,137 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+
+	"github.com/project-koku/koku-metrics-operator/controllers"
+)
+
+// collect requests a collection from the admin API of the operator
+func collect(p *plugin) error {
+	return p.adminAction(http.MethodPost, "collect")
+}
+
+// upload requests an upload from the admin API of the operator
+func upload(p *plugin) error {
+	return p.adminAction(http.MethodPost, "upload")
+}
+
+// state prints the state of the operator from its admin API
+func state(p *plugin) error {
+	return p.adminAction(http.MethodGet, "state")
+}
+
+// adminPath returns the path of the admin endpoint for the KokuMetricsConfig
+func adminPath(endpoint, namespace, name string) string {
+	query := url.Values{}
+	query.Set("namespace", namespace)
+	if name != "" {
+		query.Set("name", name)
+	}
+	return controllers.AdminPathPrefix + endpoint + "?" + query.Encode()
+}
+
+// adminAction sends the request to the admin API of the operator pod, and prints the indented response
+func (p *plugin) adminAction(method, endpoint string) error {
+	pod, err := p.operatorPod()
+	if err != nil {
+		return fmt.Errorf("%s: %v", endpoint, err)
+	}
+	body, status, err := p.admin(pod, method, adminPath(endpoint, p.opts.namespace, p.opts.config))
+	if err != nil {
+		return fmt.Errorf("%s: %v", endpoint, err)
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", "  "); err != nil {
+		indented.Reset()
+		indented.Write(body)
+	}
+	if status >= 300 {
+		return fmt.Errorf("%s: the admin API returned %d: %s", endpoint, status, bytes.TrimSpace(indented.Bytes()))
+	}
+	fmt.Fprint(p.out, indented.String())
+	return nil
+}
+
+// adminRequest port-forwards a local port to the admin API of the pod, which only listens on the loopback address
+// of the pod, and sends the request through it
+func adminRequest(cfg *rest.Config, pod *corev1.Pod, port int, method, path string) ([]byte, int, error) {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, 0, fmt.Errorf("adminRequest: failed to create clientset: %v", err)
+	}
+	transport, upgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return nil, 0, fmt.Errorf("adminRequest: failed to create round tripper: %v", err)
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	stop, ready := make(chan struct{}), make(chan struct{})
+	defer close(stop)
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)},
+		stop, ready, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		return nil, 0, fmt.Errorf("adminRequest: failed to create port-forward: %v", err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- forwarder.ForwardPorts() }()
+	select {
+	case <-ready:
+	case err := <-errCh:
+		return nil, 0, fmt.Errorf("adminRequest: port-forward failed: %v", err)
+	}
+	ports, err := forwarder.GetPorts()
+	if err != nil || len(ports) == 0 {
+		return nil, 0, fmt.Errorf("adminRequest: failed to get the forwarded port: %v", err)
+	}
+
+	httpReq, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", ports[0].Local, path), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("adminRequest: could not create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, 0, fmt.Errorf("adminRequest: could not send the request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("adminRequest: failed to read response: %v", err)
+	}
+	return body, resp.StatusCode, nil
+}
@@ -0,0 +1,101 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// kubectl-koku is a kubectl plugin for the day-2 operation of the koku-metrics-operator. It summarizes the health of
+// the KokuMetricsConfig, lists the files of the report volume, and triggers collections and uploads through the
+// admin API of the operator. It is installed by copying the binary to a directory of the PATH, and run as
+// `kubectl koku <command>`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kokumetricscfgv1beta1.AddToScheme(scheme))
+}
+
+const usage = `kubectl koku inspects and operates the koku-metrics-operator.
+
+Usage:
+  kubectl koku <command> [flags]
+
+Commands:
+  status     summarize the health of the KokuMetricsConfig
+  payloads   list the reports and payloads on the report volume of the operator
+  diff       compare the reports of two payloads, such as: diff upload/<old>.tar.gz upload/<new>.tar.gz
+  collect    collect the last hour and package the reports now
+  upload     upload the packaged payloads now
+  state      print the state of the operator from its admin API
+
+Flags:
+`
+
+// commands are the functions of the commands, by name
+var commands = map[string]func(p *plugin) error{
+	"status":   status,
+	"payloads": payloads,
+	"diff":     diff,
+	"collect":  collect,
+	"upload":   upload,
+	"state":    state,
+}
+
+func main() {
+	opts := options{}
+	opts.bindFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	if len(os.Args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(flag.CommandLine.Output(), "unknown command %q\n\n", os.Args[1])
+		flag.Usage()
+		os.Exit(2)
+	}
+	// the command is given before the flags
+	_ = flag.CommandLine.Parse(os.Args[2:])
+
+	p, err := newPlugin(opts, os.Stdout)
+	if err == nil {
+		p.args = flag.Args()
+		err = command(p)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+)
+
+// listFilesCommand prints the files of the report volume. The operator image has no shell, so the manager lists them.
+var listFilesCommand = []string{"/manager", "--list-files"}
+
+// diffPayloadsCommand compares two payloads of the report volume, which are appended to the command
+var diffPayloadsCommand = []string{"/manager", "--diff-payloads"}
+
+// diff compares the reports of two payloads on the report volume of the operator pod, given as arguments as they are
+// listed by the payloads command, such as upload/<file>.tar.gz
+func diff(p *plugin) error {
+	if len(p.args) != 2 {
+		return fmt.Errorf("diff: the old and the new payload are required, such as upload/<old>.tar.gz upload/<new>.tar.gz")
+	}
+	pod, err := p.operatorPod()
+	if err != nil {
+		return fmt.Errorf("diff: %v", err)
+	}
+	command := append(append([]string{}, diffPayloadsCommand...), p.args...)
+	out, err := p.exec(pod, command)
+	if err != nil {
+		return fmt.Errorf("diff: failed to compare the payloads in pod %s: %v", pod.Name, err)
+	}
+	payloadDiff := packaging.PayloadDiff{}
+	if err := json.Unmarshal(out, &payloadDiff); err != nil {
+		return fmt.Errorf("diff: failed to parse the comparison of the payloads: %v", err)
+	}
+	writePayloadDiff(p.out, payloadDiff)
+	return nil
+}
+
+// writePayloadDiff writes the rows added and removed in each report as a table, followed by the change of the
+// totals of the numeric columns, and the dimensions that are only in one of the payloads
+func writePayloadDiff(out io.Writer, payloadDiff packaging.PayloadDiff) {
+	for _, side := range []struct {
+		name    string
+		payload packaging.PayloadDiffSide
+	}{{"old", payloadDiff.Old}, {"new", payloadDiff.New}} {
+		fmt.Fprintf(out, "%s: %s (payload %s, %s to %s)\n", side.name, side.payload.File, side.payload.PayloadID,
+			side.payload.Start.UTC().Format(time.RFC3339), side.payload.End.UTC().Format(time.RFC3339))
+	}
+	if len(payloadDiff.Reports) == 0 {
+		fmt.Fprintln(out, "\nno report rows in either payload")
+		return
+	}
+
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "REPORT\tOLD ROWS\tNEW ROWS\tADDED\tREMOVED\n")
+	for _, report := range payloadDiff.Reports {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", report.Report, report.OldRows, report.NewRows, report.Added, report.Removed)
+	}
+	w.Flush()
+
+	for _, report := range payloadDiff.Reports {
+		fmt.Fprintf(out, "\n%s:\n", report.Report)
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for _, total := range report.Totals {
+			fmt.Fprintf(w, "  %s\t%g\t->\t%g\t%s\n", total.Column, total.Old, total.New, percentChange(total.Old, total.New))
+		}
+		w.Flush()
+		for _, dimensions := range report.RemovedDimensions {
+			fmt.Fprintf(out, "  removed: %s\n", dimensions)
+		}
+		for _, dimensions := range report.AddedDimensions {
+			fmt.Fprintf(out, "  added: %s\n", dimensions)
+		}
+	}
+}
+
+// percentChange formats the change from one total to another as a percentage of the first
+func percentChange(from, to float64) string {
+	switch {
+	case from == to:
+		return "(unchanged)"
+	case from == 0:
+		return "(new)"
+	}
+	return fmt.Sprintf("(%+.1f%%)", (to-from)/from*100)
+}
+
+// payloads lists the reports and payloads on the report volume of the operator pod
+func payloads(p *plugin) error {
+	pod, err := p.operatorPod()
+	if err != nil {
+		return fmt.Errorf("payloads: %v", err)
+	}
+	out, err := p.exec(pod, listFilesCommand)
+	if err != nil {
+		return fmt.Errorf("payloads: failed to list the files of pod %s: %v", pod.Name, err)
+	}
+	files := []dirconfig.InventoryFile{}
+	if err := json.Unmarshal(out, &files); err != nil {
+		return fmt.Errorf("payloads: failed to parse the files of pod %s: %v", pod.Name, err)
+	}
+	writePayloads(p.out, files)
+	return nil
+}
+
+// writePayloads writes the files as a table, followed by the number and size of the files of each directory
+func writePayloads(out io.Writer, files []dirconfig.InventoryFile) {
+	if len(files) == 0 {
+		fmt.Fprintln(out, "no reports or payloads on the report volume")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "DIRECTORY\tFILE\tSIZE\tMODIFIED\n")
+	type total struct {
+		files int
+		size  int64
+	}
+	totals := map[string]*total{}
+	var order []string
+	for _, f := range files {
+		name := f.Path
+		if f.Partial {
+			name += " (partial)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Directory, name, humanSize(f.Size), f.ModTime.UTC().Format(time.RFC3339))
+		if totals[f.Directory] == nil {
+			totals[f.Directory] = &total{}
+			order = append(order, f.Directory)
+		}
+		totals[f.Directory].files++
+		totals[f.Directory].size += f.Size
+	}
+	fmt.Fprintln(w)
+	for _, dir := range order {
+		fmt.Fprintf(w, "%s:\t%d files\t%s\n", dir, totals[dir].files, humanSize(totals[dir].size))
+	}
+	w.Flush()
+}
+
+// humanSize formats the size in bytes with a binary unit
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// execInPod runs the command in the manager container of the pod, and returns its standard output
+func execInPod(cfg *rest.Config, pod *corev1.Pod, command []string) ([]byte, error) {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("execInPod: failed to create clientset: %v", err)
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: operatorContainer,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, clientgoscheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(cfg, "POST", req.URL())
+	if err != nil {
+		return nil, fmt.Errorf("execInPod: failed to create executor: %v", err)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := executor.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		return nil, fmt.Errorf("execInPod: %v: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,136 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const (
+	// operatorPodLabel selects the pods of the operator Deployment
+	operatorPodLabel = "control-plane"
+	operatorPodValue = "controller-manager"
+	// operatorContainer is the container of the operator pod that runs the manager
+	operatorContainer = "manager"
+)
+
+// options are the flags of the commands
+type options struct {
+	namespace string
+	config    string
+	adminPort int
+}
+
+func (o *options) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.namespace, "namespace", "koku-metrics-operator", "The namespace of the operator and its KokuMetricsConfig.")
+	fs.StringVar(&o.config, "config", "", "The name of the KokuMetricsConfig. It can be left out when the namespace has a single KokuMetricsConfig.")
+	fs.IntVar(&o.adminPort, "admin-port", 8083, "The port of the admin API in the operator pod.")
+}
+
+// plugin is the state shared by the commands of a single run
+type plugin struct {
+	opts options
+	// args are the arguments of the command that follow its flags
+	args   []string
+	out    io.Writer
+	client client.Client
+	// exec runs the command in the manager container of the pod, and returns its output
+	exec func(pod *corev1.Pod, command []string) ([]byte, error)
+	// admin sends a request to the admin API of the pod, and returns the body and the http status of the response
+	admin func(pod *corev1.Pod, method, path string) ([]byte, int, error)
+}
+
+// newPlugin creates the clients of the cluster of the kubeconfig
+func newPlugin(opts options, out io.Writer) (*plugin, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("newPlugin: failed to read kubeconfig: %v", err)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("newPlugin: failed to create client: %v", err)
+	}
+	return &plugin{
+		opts:   opts,
+		out:    out,
+		client: c,
+		exec: func(pod *corev1.Pod, command []string) ([]byte, error) {
+			return execInPod(cfg, pod, command)
+		},
+		admin: func(pod *corev1.Pod, method, path string) ([]byte, int, error) {
+			return adminRequest(cfg, pod, opts.adminPort, method, path)
+		},
+	}, nil
+}
+
+// kokuMetricsConfig returns the KokuMetricsConfig of the config flag, or the only KokuMetricsConfig of the namespace
+func (p *plugin) kokuMetricsConfig() (*kokumetricscfgv1beta1.KokuMetricsConfig, error) {
+	ctx := context.Background()
+	if p.opts.config != "" {
+		kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+		if err := p.client.Get(ctx, types.NamespacedName{Namespace: p.opts.namespace, Name: p.opts.config}, kmCfg); err != nil {
+			return nil, fmt.Errorf("failed to get KokuMetricsConfig: %v", err)
+		}
+		return kmCfg, nil
+	}
+	list := &kokumetricscfgv1beta1.KokuMetricsConfigList{}
+	if err := p.client.List(ctx, list, client.InNamespace(p.opts.namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list KokuMetricsConfigs: %v", err)
+	}
+	switch len(list.Items) {
+	case 0:
+		return nil, fmt.Errorf("no KokuMetricsConfig found in namespace %q", p.opts.namespace)
+	case 1:
+		return &list.Items[0], nil
+	}
+	return nil, fmt.Errorf("%d KokuMetricsConfigs found in namespace %q: set --config", len(list.Items), p.opts.namespace)
+}
+
+// operatorPod returns the running pod of the operator Deployment. The pods of the Jobs of the cronjob execution mode
+// have the same labels, and are told apart by their owner.
+func (p *plugin) operatorPod() (*corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := p.client.List(context.Background(), pods, client.InNamespace(p.opts.namespace),
+		client.MatchingLabels{operatorPodLabel: operatorPodValue}); err != nil {
+		return nil, fmt.Errorf("failed to list operator pods: %v", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, owner := range pod.OwnerReferences {
+			if owner.Kind == "ReplicaSet" {
+				return pod, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no running operator pod found in namespace %q", p.opts.namespace)
+}
@@ -0,0 +1,283 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+)
+
+const testNamespace = "koku-metrics-operator"
+
+func testConfig(name string) *kokumetricscfgv1beta1.KokuMetricsConfig {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name}}
+	kmCfg.Status.ClusterID = "cluster-id"
+	kmCfg.Status.Reports.DataCollected = true
+	return kmCfg
+}
+
+func testPod(name string, phase corev1.PodPhase, ownerKind string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       testNamespace,
+			Name:            name,
+			Labels:          map[string]string{operatorPodLabel: operatorPodValue},
+			OwnerReferences: []metav1.OwnerReference{{Kind: ownerKind, Name: "owner"}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func testPlugin(config string, objs ...runtime.Object) (*plugin, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &plugin{
+		opts:   options{namespace: testNamespace, config: config, adminPort: 8083},
+		out:    out,
+		client: fake.NewFakeClientWithScheme(scheme, objs...),
+	}, out
+}
+
+func TestKokuMetricsConfig(t *testing.T) {
+	configTests := []struct {
+		name    string
+		config  string
+		objs    []runtime.Object
+		want    string
+		wantErr bool
+	}{
+		{name: "only config of the namespace", objs: []runtime.Object{testConfig("cfg")}, want: "cfg"},
+		{name: "named config", config: "other", objs: []runtime.Object{testConfig("cfg"), testConfig("other")}, want: "other"},
+		{name: "several configs", objs: []runtime.Object{testConfig("cfg"), testConfig("other")}, wantErr: true},
+		{name: "no config", wantErr: true},
+		{name: "missing named config", config: "missing", objs: []runtime.Object{testConfig("cfg")}, wantErr: true},
+	}
+	for _, tt := range configTests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := testPlugin(tt.config, tt.objs...)
+			got, err := p.kokuMetricsConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			if err == nil && got.Name != tt.want {
+				t.Errorf("%s got %s want %s", tt.name, got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperatorPod(t *testing.T) {
+	p, _ := testPlugin("",
+		testPod("job-pod", corev1.PodRunning, "Job"),
+		testPod("pending-pod", corev1.PodPending, "ReplicaSet"),
+		testPod("operator-pod", corev1.PodRunning, "ReplicaSet"),
+	)
+	got, err := p.operatorPod()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != "operator-pod" {
+		t.Errorf("got pod %s want operator-pod", got.Name)
+	}
+
+	p, _ = testPlugin("", testPod("job-pod", corev1.PodRunning, "Job"))
+	if _, err := p.operatorPod(); err == nil {
+		t.Errorf("expected an error without a running operator pod")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	healthy := testConfig("cfg")
+	unhealthy := testConfig("cfg")
+	unhealthy.Status.Reports.DataCollected = false
+	unhealthy.Status.Reports.DataCollectionMessage = "prometheus is unreachable"
+	unhealthy.Status.Upload.Quarantine.Payloads = 2
+	unhealthy.Status.Conditions = []kokumetricscfgv1beta1.Condition{
+		{Type: kokumetricscfgv1beta1.ConditionDegraded, Status: corev1.ConditionTrue, Reason: "Anomaly", Message: "rows dropped"},
+	}
+	statusTests := []struct {
+		name  string
+		kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig
+		want  []string
+	}{
+		{name: "healthy", kmCfg: healthy, want: []string{"koku-metrics-operator/cfg", "cluster-id", "Healthy"}},
+		{name: "unhealthy", kmCfg: unhealthy, want: []string{
+			"Unhealthy",
+			"degraded: rows dropped",
+			"collection: prometheus is unreachable",
+			"upload: 2 payloads quarantined",
+			"Anomaly",
+		}},
+	}
+	for _, tt := range statusTests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, out := testPlugin("", tt.kmCfg)
+			if err := status(p); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("%s got status\n%s\nwant it to contain %q", tt.name, out.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestPayloads(t *testing.T) {
+	modified := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	files := []dirconfig.InventoryFile{
+		{Directory: "staging", Path: "a.csv.part", Size: 10, ModTime: modified, Partial: true},
+		{Directory: "upload", Path: "payload-1.tar.gz", Size: 2048, ModTime: modified},
+		{Directory: "upload", Path: "payload-2.tar.gz", Size: 1024, ModTime: modified},
+	}
+	data, err := json.Marshal(files)
+	if err != nil {
+		t.Fatalf("failed to marshal files: %v", err)
+	}
+	p, out := testPlugin("", testPod("operator-pod", corev1.PodRunning, "ReplicaSet"))
+	var gotCommand []string
+	p.exec = func(pod *corev1.Pod, command []string) ([]byte, error) {
+		gotCommand = command
+		return data, nil
+	}
+
+	if err := payloads(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(gotCommand, listFilesCommand) {
+		t.Errorf("got command %v want %v", gotCommand, listFilesCommand)
+	}
+	for _, want := range []string{"a.csv.part (partial)", "payload-1.tar.gz", "2.0KiB", "upload:", "2 files", "3.0KiB"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got payloads\n%s\nwant it to contain %q", out.String(), want)
+		}
+	}
+
+	p.exec = func(pod *corev1.Pod, command []string) ([]byte, error) {
+		return nil, errors.New("exec failed")
+	}
+	if err := payloads(p); err == nil {
+		t.Errorf("expected an error when the exec fails")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	start := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	payloadDiff := packaging.PayloadDiff{
+		Old: packaging.PayloadDiffSide{File: "upload/old.tar.gz", PayloadID: "uid-1", Start: start, End: start.Add(24 * time.Hour)},
+		New: packaging.PayloadDiffSide{File: "upload/new.tar.gz", PayloadID: "uid-2", Start: start.Add(24 * time.Hour), End: start.Add(48 * time.Hour)},
+		Reports: []packaging.ReportDiff{{
+			Report:            "pod",
+			OldRows:           240,
+			NewRows:           144,
+			Removed:           96,
+			RemovedDimensions: []string{"node=node-1, namespace=ns1, pod=pod-a"},
+			Totals:            []packaging.ColumnTotal{{Column: "pod_usage_cpu_core_seconds", Old: 1000, New: 600}},
+		}},
+	}
+	data, err := json.Marshal(payloadDiff)
+	if err != nil {
+		t.Fatalf("failed to marshal diff: %v", err)
+	}
+	p, out := testPlugin("", testPod("operator-pod", corev1.PodRunning, "ReplicaSet"))
+	var gotCommand []string
+	p.exec = func(pod *corev1.Pod, command []string) ([]byte, error) {
+		gotCommand = command
+		return data, nil
+	}
+
+	if err := diff(p); err == nil {
+		t.Errorf("expected an error without the payloads to compare")
+	}
+	p.args = []string{"upload/old.tar.gz", "upload/new.tar.gz"}
+	if err := diff(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantCommand := append(append([]string{}, diffPayloadsCommand...), p.args...)
+	if !reflect.DeepEqual(gotCommand, wantCommand) {
+		t.Errorf("got command %v want %v", gotCommand, wantCommand)
+	}
+	for _, want := range []string{"old: upload/old.tar.gz (payload uid-1", "REPORT", "240", "96",
+		"pod_usage_cpu_core_seconds", "(-40.0%)", "removed: node=node-1, namespace=ns1, pod=pod-a"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got diff\n%s\nwant it to contain %q", out.String(), want)
+		}
+	}
+}
+
+func TestAdminAction(t *testing.T) {
+	actionTests := []struct {
+		name       string
+		command    func(p *plugin) error
+		config     string
+		status     int
+		wantMethod string
+		wantPath   string
+		wantErr    bool
+	}{
+		{name: "collect", command: collect, status: http.StatusAccepted, wantMethod: "POST", wantPath: "/admin/v1/collect?namespace=koku-metrics-operator"},
+		{name: "upload for the config", command: upload, config: "cfg", status: http.StatusAccepted, wantMethod: "POST", wantPath: "/admin/v1/upload?name=cfg&namespace=koku-metrics-operator"},
+		{name: "state", command: state, status: http.StatusOK, wantMethod: "GET", wantPath: "/admin/v1/state?namespace=koku-metrics-operator"},
+		{name: "error response", command: collect, status: http.StatusNotFound, wantMethod: "POST", wantPath: "/admin/v1/collect?namespace=koku-metrics-operator", wantErr: true},
+	}
+	for _, tt := range actionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, out := testPlugin(tt.config, testPod("operator-pod", corev1.PodRunning, "ReplicaSet"))
+			var gotMethod, gotPath string
+			p.admin = func(pod *corev1.Pod, method, path string) ([]byte, int, error) {
+				gotMethod, gotPath = method, path
+				return []byte(`{"queued":true}`), tt.status, nil
+			}
+			err := tt.command(p)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			if gotMethod != tt.wantMethod || gotPath != tt.wantPath {
+				t.Errorf("%s got %s %s want %s %s", tt.name, gotMethod, gotPath, tt.wantMethod, tt.wantPath)
+			}
+			if !tt.wantErr && !strings.Contains(out.String(), `"queued": true`) {
+				t.Errorf("%s got output %q want the indented response", tt.name, out.String())
+			}
+		})
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	for size, want := range map[int64]string{0: "0B", 1023: "1023B", 1024: "1.0KiB", 1536: "1.5KiB", 5 << 20: "5.0MiB"} {
+		if got := humanSize(size); got != want {
+			t.Errorf("humanSize(%d) got %s want %s", size, got, want)
+		}
+	}
+}
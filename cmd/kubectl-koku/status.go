@@ -0,0 +1,138 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// status prints a summary of the health of the KokuMetricsConfig
+func status(p *plugin) error {
+	kmCfg, err := p.kokuMetricsConfig()
+	if err != nil {
+		return fmt.Errorf("status: %v", err)
+	}
+	writeStatus(p.out, kmCfg)
+	return nil
+}
+
+// problems returns the errors of the pipeline of the KokuMetricsConfig, from collection to processing
+func problems(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) []string {
+	var found []string
+	add := func(stage, msg string) {
+		if msg != "" {
+			found = append(found, fmt.Sprintf("%s: %s", stage, msg))
+		}
+	}
+	status := kmCfg.Status
+	for _, condition := range status.Conditions {
+		if condition.Type == kokumetricscfgv1beta1.ConditionDegraded && condition.Status == corev1.ConditionTrue {
+			add("degraded", condition.Message)
+		}
+	}
+	add("prometheus", status.Prometheus.ConnectionError)
+	if !status.Reports.DataCollected {
+		add("collection", status.Reports.DataCollectionMessage)
+	}
+	add("packaging", status.Packaging.PackagingError)
+	add("authentication", status.Authentication.AuthErrorMessage)
+	add("upload", status.Upload.UploadError)
+	if status.Upload.Quarantine.Payloads > 0 {
+		add("upload", fmt.Sprintf("%d payloads quarantined", status.Upload.Quarantine.Payloads))
+	}
+	if status.ProcessingStatus.Failed > 0 {
+		add("processing", fmt.Sprintf("%d payloads failed processing", status.ProcessingStatus.Failed))
+	}
+	return found
+}
+
+// since formats the time and how long ago it was
+func since(t metav1.Time, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s (%s ago)", t.UTC().Format(time.RFC3339), now.Sub(t.Time).Truncate(time.Minute))
+}
+
+// writeStatus writes the summary of the KokuMetricsConfig
+func writeStatus(out io.Writer, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	now := time.Now()
+	status := kmCfg.Status
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	health := "Healthy"
+	found := problems(kmCfg)
+	if len(found) > 0 {
+		health = "Unhealthy"
+	}
+	fmt.Fprintf(w, "KokuMetricsConfig:\t%s/%s\n", kmCfg.Namespace, kmCfg.Name)
+	fmt.Fprintf(w, "Cluster ID:\t%s\n", status.ClusterID)
+	fmt.Fprintf(w, "Health:\t%s\n", health)
+	for _, problem := range found {
+		fmt.Fprintf(w, "\t- %s\n", problem)
+	}
+
+	fmt.Fprintf(w, "\nCollection\n")
+	fmt.Fprintf(w, "  Prometheus connected:\t%t\n", status.Prometheus.PrometheusConnected)
+	fmt.Fprintf(w, "  Last collection:\t%s\n", since(status.Prometheus.LastQuerySuccessTime, now))
+	fmt.Fprintf(w, "  Last hour collected:\t%s\n", status.Reports.LastHourQueried)
+
+	fmt.Fprintf(w, "\nPackaging\n")
+	fmt.Fprintf(w, "  Last packaging:\t%s\n", since(status.Packaging.LastSuccessfulPackagingTime, now))
+	fmt.Fprintf(w, "  Packaged files:\t%d\n", len(status.Packaging.PackagedFiles))
+
+	fmt.Fprintf(w, "\nUpload\n")
+	upload := status.Upload.UploadToggle != nil && *status.Upload.UploadToggle
+	fmt.Fprintf(w, "  Enabled:\t%t\n", upload)
+	if upload {
+		fmt.Fprintf(w, "  Authentication:\t%s\n", status.Authentication.AuthType)
+		fmt.Fprintf(w, "  Last upload status:\t%s\n", status.Upload.LastUploadStatus)
+		fmt.Fprintf(w, "  Last successful upload:\t%s\n", since(status.Upload.LastSuccessfulUploadTime, now))
+		fmt.Fprintf(w, "  Queue:\t%d critical, %d backfill, in progress %t\n",
+			status.Upload.Queue.CriticalPayloads, status.Upload.Queue.BackfillPayloads, status.Upload.Queue.InProgress)
+	}
+	if status.ProcessingStatus.Enabled {
+		fmt.Fprintf(w, "\nProcessing\n")
+		fmt.Fprintf(w, "  Data current through:\t%s\n", since(status.ProcessingStatus.DataCurrentThrough, now))
+		fmt.Fprintf(w, "  Payloads:\t%d processed, %d pending, %d failed\n",
+			status.ProcessingStatus.Processed, status.ProcessingStatus.Pending, status.ProcessingStatus.Failed)
+		if len(status.ProcessingStatus.FailedPayloads) > 0 {
+			fmt.Fprintf(w, "  Failed payloads:\t%s\n", strings.Join(status.ProcessingStatus.FailedPayloads, ", "))
+		}
+	}
+
+	if len(status.Conditions) > 0 {
+		fmt.Fprintf(w, "\nConditions\n")
+		fmt.Fprintf(w, "  TYPE\tSTATUS\tREASON\tMESSAGE\n")
+		for _, condition := range status.Conditions {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+		}
+	}
+	w.Flush()
+}
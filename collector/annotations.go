@@ -0,0 +1,35 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"regexp"
+	"strings"
+)
+
+// annotationRegex returns the expression matching the annotations with any of the prefixes. kube-state-metrics
+// exposes annotations as labels with the `annotation_` prefix, and replaces invalid characters the same way as labels.
+func annotationRegex(prefixes []string) string {
+	var escaped []string
+	for _, prefix := range prefixes {
+		escaped = append(escaped, regexp.QuoteMeta(invalidLabelChars.ReplaceAllString(prefix, "_")))
+	}
+	return "^annotation_(" + strings.Join(escaped, "|") + ")"
+}
@@ -0,0 +1,69 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestAnnotationRegex(t *testing.T) {
+	metric := model.Metric{
+		"annotation_example_com_cost_center":       "cc-1234",
+		"annotation_example_com_owner":             "team-a",
+		"annotation_openshift_io_sa_scc_uid_range": "1000/10000",
+		"label_example_com_cost_center":            "cc-5678",
+		"namespace":                                "default",
+	}
+	tests := []struct {
+		name     string
+		prefixes []string
+		want     string
+	}{
+		{
+			name:     "single prefix",
+			prefixes: []string{"example.com/cost-center"},
+			want:     "annotation_example_com_cost_center:cc-1234",
+		},
+		{
+			name:     "domain prefix",
+			prefixes: []string{"example.com/"},
+			want:     "annotation_example_com_cost_center:cc-1234|annotation_example_com_owner:team-a",
+		},
+		{
+			name:     "multiple prefixes",
+			prefixes: []string{"openshift.io/", "example.com/owner"},
+			want:     "annotation_example_com_owner:team-a|annotation_openshift_io_sa_scc_uid_range:1000/10000",
+		},
+		{
+			name:     "no matches",
+			prefixes: []string{"other.io/"},
+			want:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findFields(metric, annotationRegex(tt.prefixes), newLabelSanitizer(0)); got != tt.want {
+				t.Errorf("findFields() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// the kinds of autoscalers in the autoscaler report
+const (
+	horizontalPodAutoscalerKind = "HorizontalPodAutoscaler"
+	verticalPodAutoscalerKind   = "VerticalPodAutoscaler"
+)
+
+// autoscalerReport reports the replica bounds and metric targets of each HorizontalPodAutoscaler, and the container
+// recommendations of each VerticalPodAutoscaler, at the end of the hour of the report, so that resource optimization
+// recommendations can account for the autoscaling that is configured in the cluster. The report has no rows on
+// clusters without autoscalers.
+type autoscalerReport struct{}
+
+func init() {
+	RegisterReportGenerator(autoscalerReport{})
+}
+
+func (autoscalerReport) Name() string { return "autoscaler" }
+
+// NamesWorkloads is true, since the rows name the autoscalers and the workloads they scale
+func (autoscalerReport) NamesWorkloads() bool { return true }
+
+// the HPA queries with a replica count, and the metric name that they are reported with
+var hpaReplicaQueries = []struct{ name, metric string }{
+	{name: "hpa-min-replicas", metric: "min_replicas"},
+	{name: "hpa-max-replicas", metric: "max_replicas"},
+	{name: "hpa-current-replicas", metric: "current_replicas"},
+	{name: "hpa-desired-replicas", metric: "desired_replicas"},
+}
+
+// the VPA recommendation queries, and the suffix of the metric names that they are reported with
+var vpaRecommendationQueries = []struct{ name, suffix string }{
+	{name: "vpa-target", suffix: "target"},
+	{name: "vpa-lower-bound", suffix: "lower_bound"},
+	{name: "vpa-upper-bound", suffix: "upper_bound"},
+}
+
+func (autoscalerReport) Queries() []GeneratorQuery {
+	return []GeneratorQuery{
+		{Name: "hpa-info", Query: "kube_horizontalpodautoscaler_info"},
+		{Name: "hpa-min-replicas", Query: "kube_horizontalpodautoscaler_spec_min_replicas"},
+		{Name: "hpa-max-replicas", Query: "kube_horizontalpodautoscaler_spec_max_replicas"},
+		{Name: "hpa-current-replicas", Query: "kube_horizontalpodautoscaler_status_current_replicas"},
+		{Name: "hpa-desired-replicas", Query: "kube_horizontalpodautoscaler_status_desired_replicas"},
+		{Name: "hpa-target-metric", Query: "kube_horizontalpodautoscaler_spec_target_metric"},
+		{Name: "vpa-target", Query: "kube_verticalpodautoscaler_status_recommendation_containerrecommendations_target"},
+		{Name: "vpa-lower-bound", Query: "kube_verticalpodautoscaler_status_recommendation_containerrecommendations_lowerbound"},
+		{Name: "vpa-upper-bound", Query: "kube_verticalpodautoscaler_status_recommendation_containerrecommendations_upperbound"},
+	}
+}
+
+func (autoscalerReport) Columns() []string {
+	return []string{
+		"namespace",
+		"autoscaler_kind",
+		"autoscaler_name",
+		"target_kind",
+		"target_name",
+		"container",
+		"metric_name",
+		"metric_value",
+	}
+}
+
+// lastValue returns the value of the last sample of the stream, which is the value at the end of the hour
+func lastValue(stream *model.SampleStream) (float64, bool) {
+	if len(stream.Values) == 0 {
+		return 0, false
+	}
+	return float64(stream.Values[len(stream.Values)-1].Value), true
+}
+
+// Rows returns a row for each replica bound and metric target of each HPA, and for each resource recommendation of
+// each container of each VPA. The workload that an HPA scales is found in kube_horizontalpodautoscaler_info.
+func (autoscalerReport) Rows(results map[string]model.Matrix) ([][]string, error) {
+	targets := map[string][2]string{}
+	for _, stream := range results["hpa-info"] {
+		key := workloadKey(string(stream.Metric["namespace"]), string(stream.Metric["horizontalpodautoscaler"]))
+		targets[key] = [2]string{string(stream.Metric["scaletargetref_kind"]), string(stream.Metric["scaletargetref_name"])}
+	}
+
+	var rows [][]string
+	hpaRow := func(stream *model.SampleStream, metric string) {
+		value, ok := lastValue(stream)
+		if !ok {
+			return
+		}
+		namespace, name := string(stream.Metric["namespace"]), string(stream.Metric["horizontalpodautoscaler"])
+		target := targets[workloadKey(namespace, name)]
+		rows = append(rows, []string{namespace, horizontalPodAutoscalerKind, name, target[0], target[1], "", metric, floatToString(value)})
+	}
+	for _, q := range hpaReplicaQueries {
+		for _, stream := range results[q.name] {
+			hpaRow(stream, q.metric)
+		}
+	}
+	for _, stream := range results["hpa-target-metric"] {
+		// the target of a metric is named after the metric and the type of target, such as cpu_utilization
+		metric := strings.Join([]string{"target", string(stream.Metric["metric_name"]), string(stream.Metric["metric_target_type"])}, "_")
+		hpaRow(stream, strings.ToLower(metric))
+	}
+
+	for _, q := range vpaRecommendationQueries {
+		for _, stream := range results[q.name] {
+			value, ok := lastValue(stream)
+			if !ok {
+				continue
+			}
+			rows = append(rows, []string{
+				string(stream.Metric["namespace"]),
+				verticalPodAutoscalerKind,
+				string(stream.Metric["verticalpodautoscaler"]),
+				string(stream.Metric["target_kind"]),
+				string(stream.Metric["target_name"]),
+				string(stream.Metric["container"]),
+				string(stream.Metric["resource"]) + "_" + q.suffix,
+				floatToString(value),
+			})
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return strings.Join(rows[i], ",") < strings.Join(rows[j], ",") })
+	return rows, nil
+}
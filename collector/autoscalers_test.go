@@ -0,0 +1,80 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestAutoscalerRows(t *testing.T) {
+	stream := func(metric model.Metric, values ...float64) *model.SampleStream {
+		s := &model.SampleStream{Metric: metric}
+		for i, v := range values {
+			s.Values = append(s.Values, model.SamplePair{Timestamp: model.Time(i * 60000), Value: model.SampleValue(v)})
+		}
+		return s
+	}
+	hpa := model.Metric{"namespace": "shop", "horizontalpodautoscaler": "web"}
+	withLabels := func(m model.Metric, labels model.Metric) model.Metric {
+		merged := m.Clone()
+		for k, v := range labels {
+			merged[k] = v
+		}
+		return merged
+	}
+	vpa := model.Metric{"namespace": "shop", "verticalpodautoscaler": "api", "target_kind": "Deployment", "target_name": "api", "container": "server"}
+	results := map[string]model.Matrix{
+		"hpa-info":             {stream(withLabels(hpa, model.Metric{"scaletargetref_kind": "Deployment", "scaletargetref_name": "web"}), 1)},
+		"hpa-min-replicas":     {stream(hpa, 2)},
+		"hpa-max-replicas":     {stream(hpa, 10)},
+		"hpa-current-replicas": {stream(hpa, 3, 4)},
+		"hpa-desired-replicas": {stream(hpa, 4, 5)},
+		"hpa-target-metric":    {stream(withLabels(hpa, model.Metric{"metric_name": "cpu", "metric_target_type": "utilization"}), 80)},
+		"vpa-target":           {stream(withLabels(vpa, model.Metric{"resource": "cpu"}), 0.25, 0.5), stream(withLabels(vpa, model.Metric{"resource": "memory"}), 262144000)},
+		"vpa-lower-bound":      {stream(withLabels(vpa, model.Metric{"resource": "cpu"}), 0.1)},
+		"vpa-upper-bound":      {stream(withLabels(vpa, model.Metric{"resource": "cpu"})), stream(withLabels(vpa, model.Metric{"resource": "memory"}), 524288000)},
+	}
+	got, err := autoscalerReport{}.Rows(results)
+	if err != nil {
+		t.Fatalf("Rows got unexpected error: %v", err)
+	}
+	want := [][]string{
+		{"shop", horizontalPodAutoscalerKind, "web", "Deployment", "web", "", "current_replicas", "4.000000"},
+		{"shop", horizontalPodAutoscalerKind, "web", "Deployment", "web", "", "desired_replicas", "5.000000"},
+		{"shop", horizontalPodAutoscalerKind, "web", "Deployment", "web", "", "max_replicas", "10.000000"},
+		{"shop", horizontalPodAutoscalerKind, "web", "Deployment", "web", "", "min_replicas", "2.000000"},
+		{"shop", horizontalPodAutoscalerKind, "web", "Deployment", "web", "", "target_cpu_utilization", "80.000000"},
+		{"shop", verticalPodAutoscalerKind, "api", "Deployment", "api", "server", "cpu_lower_bound", "0.100000"},
+		{"shop", verticalPodAutoscalerKind, "api", "Deployment", "api", "server", "cpu_target", "0.500000"},
+		{"shop", verticalPodAutoscalerKind, "api", "Deployment", "api", "server", "memory_target", "262144000.000000"},
+		{"shop", verticalPodAutoscalerKind, "api", "Deployment", "api", "server", "memory_upper_bound", "524288000.000000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rows got %v want %v", got, want)
+	}
+
+	got, err = autoscalerReport{}.Rows(map[string]model.Matrix{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Rows of no autoscalers got %v, %v want no rows", got, err)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// the changes of the node capacity change report
+const (
+	nodeAdded   = "added"
+	nodeRemoved = "removed"
+	nodeResized = "resized"
+)
+
+// capacityChangeReport reports the nodes that are added, removed, or resized within the hour of the report, so that
+// node cost can be prorated on clusters that autoscale. It shares the node capacity queries of the node report, which
+// are answered from the query cache, so it adds no queries to prometheus.
+type capacityChangeReport struct{}
+
+func init() {
+	RegisterReportGenerator(capacityChangeReport{})
+}
+
+func (capacityChangeReport) Name() string { return "node-capacity-change" }
+
+func (capacityChangeReport) Queries() []GeneratorQuery {
+	return []GeneratorQuery{
+		{Name: "node-capacity-cpu-cores", Shares: "node-capacity-cpu-cores"},
+		{Name: "node-capacity-memory-bytes", Shares: "node-capacity-memory-bytes"},
+	}
+}
+
+func (capacityChangeReport) Columns() []string {
+	return []string{
+		"node",
+		"resource_id",
+		"change",
+		"change_time",
+		"node_capacity_cpu_cores",
+		"node_capacity_memory_bytes",
+		"previous_node_capacity_cpu_cores",
+		"previous_node_capacity_memory_bytes",
+	}
+}
+
+// nodeCapacity is the capacity of a node at a sample of the hour
+type nodeCapacity struct {
+	cpu, memory       float64
+	hasCPU, hasMemory bool
+}
+
+// Rows returns a row for each change of the node capacity. The hour of the report is the range of the samples of
+// all nodes: a node whose first sample is after the first sample of the hour was added, a node whose last sample is
+// before the last sample of the hour was removed, and a node with a gap of more than a step between its samples was
+// removed and added again. A node whose CPU or memory capacity changes between samples was resized.
+func (capacityChangeReport) Rows(results map[string]model.Matrix) ([][]string, error) {
+	timelines := map[string]map[model.Time]*nodeCapacity{}
+	resourceIDs := map[string]string{}
+	var first, last model.Time
+	var times []model.Time
+	for _, name := range []string{"node-capacity-cpu-cores", "node-capacity-memory-bytes"} {
+		for _, stream := range results[name] {
+			node := string(stream.Metric["node"])
+			if timelines[node] == nil {
+				timelines[node] = map[model.Time]*nodeCapacity{}
+			}
+			if id := getResourceID(string(stream.Metric["provider_id"])); id != "" {
+				resourceIDs[node] = id
+			}
+			for _, sample := range stream.Values {
+				c, ok := timelines[node][sample.Timestamp]
+				if !ok {
+					c = &nodeCapacity{}
+					timelines[node][sample.Timestamp] = c
+				}
+				if name == "node-capacity-cpu-cores" {
+					c.cpu, c.hasCPU = float64(sample.Value), true
+				} else {
+					c.memory, c.hasMemory = float64(sample.Value), true
+				}
+				if len(times) == 0 || sample.Timestamp.Before(first) {
+					first = sample.Timestamp
+				}
+				if len(times) == 0 || sample.Timestamp.After(last) {
+					last = sample.Timestamp
+				}
+				times = append(times, sample.Timestamp)
+			}
+		}
+	}
+	step := sampleStep(times)
+
+	nodes := make([]string, 0, len(timelines))
+	for node := range timelines {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	var rows [][]string
+	for _, node := range nodes {
+		row := func(change string, ts model.Time, current, previous *nodeCapacity) []string {
+			return append([]string{node, resourceIDs[node], change, ts.Time().UTC().String()},
+				append(capacityValues(current), capacityValues(previous)...)...)
+		}
+		timestamps := make([]model.Time, 0, len(timelines[node]))
+		for ts := range timelines[node] {
+			timestamps = append(timestamps, ts)
+		}
+		sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+
+		var prev *nodeCapacity
+		var prevTS model.Time
+		for i, ts := range timestamps {
+			c := timelines[node][ts]
+			if prev != nil {
+				// a capacity that is missing from a sample is unchanged
+				if !c.hasCPU {
+					c.cpu, c.hasCPU = prev.cpu, prev.hasCPU
+				}
+				if !c.hasMemory {
+					c.memory, c.hasMemory = prev.memory, prev.hasMemory
+				}
+			}
+			switch {
+			case i == 0 && ts.After(first):
+				rows = append(rows, row(nodeAdded, ts, c, nil))
+			case i > 0 && ts.Sub(prevTS) > step:
+				rows = append(rows, row(nodeRemoved, prevTS, nil, prev))
+				rows = append(rows, row(nodeAdded, ts, c, nil))
+			case i > 0 && (c.cpu != prev.cpu || c.memory != prev.memory):
+				rows = append(rows, row(nodeResized, ts, c, prev))
+			}
+			prev, prevTS = c, ts
+		}
+		if prev != nil && prevTS.Before(last) {
+			rows = append(rows, row(nodeRemoved, prevTS, nil, prev))
+		}
+	}
+	return rows, nil
+}
+
+// sampleStep returns the smallest interval between the sample times
+func sampleStep(times []model.Time) time.Duration {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	var step time.Duration
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d > 0 && (step == 0 || d < step) {
+			step = d
+		}
+	}
+	return step
+}
+
+// capacityValues returns the CPU and memory capacity columns of the capacity, which are empty for no capacity
+func capacityValues(c *nodeCapacity) []string {
+	values := []string{"", ""}
+	if c == nil {
+		return values
+	}
+	if c.hasCPU {
+		values[0] = floatToString(c.cpu)
+	}
+	if c.hasMemory {
+		values[1] = floatToString(c.memory)
+	}
+	return values
+}
@@ -0,0 +1,160 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// capacitySamples returns a sample of the value at each minute of the hour starting at 2020-11-06 18:00:00 UTC
+func capacitySamples(values map[int]float64) []model.SamplePair {
+	start := model.TimeFromUnix(1604685600)
+	var samples []model.SamplePair
+	for minute := 0; minute < 60; minute++ {
+		if v, ok := values[minute]; ok {
+			samples = append(samples, model.SamplePair{Timestamp: start.Add(time.Duration(minute) * time.Minute), Value: model.SampleValue(v)})
+		}
+	}
+	return samples
+}
+
+// minutes returns the value for each minute from start up to end
+func minutes(start, end int, value float64) map[int]float64 {
+	values := map[int]float64{}
+	for m := start; m < end; m++ {
+		values[m] = value
+	}
+	return values
+}
+
+func mergeMinutes(a, b map[int]float64) map[int]float64 {
+	for k, v := range b {
+		a[k] = v
+	}
+	return a
+}
+
+func TestCapacityChangeRows(t *testing.T) {
+	metric := func(node string) model.Metric {
+		return model.Metric{"node": model.LabelValue(node), "provider_id": model.LabelValue("aws:///us-east-2a/i-" + node)}
+	}
+	results := map[string]model.Matrix{
+		"node-capacity-cpu-cores": {
+			{Metric: metric("steady"), Values: capacitySamples(minutes(0, 60, 4))},
+			{Metric: metric("added"), Values: capacitySamples(minutes(20, 60, 8))},
+			{Metric: metric("removed"), Values: capacitySamples(minutes(0, 45, 4))},
+			{Metric: metric("replaced"), Values: capacitySamples(mergeMinutes(minutes(0, 10, 4), minutes(30, 60, 4)))},
+			{Metric: metric("resized"), Values: capacitySamples(mergeMinutes(minutes(0, 30, 4), minutes(30, 60, 8)))},
+		},
+		"node-capacity-memory-bytes": {
+			{Metric: metric("steady"), Values: capacitySamples(minutes(0, 60, 1024))},
+			{Metric: metric("added"), Values: capacitySamples(minutes(20, 60, 2048))},
+			{Metric: metric("removed"), Values: capacitySamples(minutes(0, 45, 1024))},
+			{Metric: metric("replaced"), Values: capacitySamples(mergeMinutes(minutes(0, 10, 1024), minutes(30, 60, 1024)))},
+			{Metric: metric("resized"), Values: capacitySamples(minutes(0, 60, 1024))},
+		},
+	}
+	got, err := capacityChangeReport{}.Rows(results)
+	if err != nil {
+		t.Fatalf("Rows got unexpected error: %v", err)
+	}
+	want := [][]string{
+		{"added", "i-added", nodeAdded, "2020-11-06 18:20:00 +0000 UTC", "8.000000", "2048.000000", "", ""},
+		{"removed", "i-removed", nodeRemoved, "2020-11-06 18:44:00 +0000 UTC", "", "", "4.000000", "1024.000000"},
+		{"replaced", "i-replaced", nodeRemoved, "2020-11-06 18:09:00 +0000 UTC", "", "", "4.000000", "1024.000000"},
+		{"replaced", "i-replaced", nodeAdded, "2020-11-06 18:30:00 +0000 UTC", "4.000000", "1024.000000", "", ""},
+		{"resized", "i-resized", nodeResized, "2020-11-06 18:30:00 +0000 UTC", "8.000000", "1024.000000", "4.000000", "1024.000000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Rows got %v want %v", got, want)
+	}
+	for _, row := range got {
+		if len(row) != len(capacityChangeReport{}.Columns()) {
+			t.Errorf("Rows got row %v with %d values want %d", row, len(row), len(capacityChangeReport{}.Columns()))
+		}
+	}
+
+	got, err = capacityChangeReport{}.Rows(map[string]model.Matrix{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Rows of no results got %v, %v want no rows", got, err)
+	}
+}
+
+func TestCapacityChangeQueries(t *testing.T) {
+	override := "max(kube_node_status_capacity_cpu_cores) by (node, provider_id)"
+	c := &PromCollector{QueryOverrides: map[string]string{"node-capacity-cpu-cores": override}}
+	want := map[string]string{"node-capacity-cpu-cores": override}
+	for _, q := range *nodeQueries {
+		if q.Name == "node-capacity-memory-bytes" {
+			want[q.Name] = q.QueryString
+		}
+	}
+	for _, q := range (capacityChangeReport{}).Queries() {
+		if got := c.generatorQueryString(q); got == "" || got != want[q.Name] {
+			t.Errorf("query %s got %q want the query of the node report %q", q.Name, got, want[q.Name])
+		}
+	}
+}
+
+func TestCapacityChangeSharesNodeQueries(t *testing.T) {
+	conn := &countingPrometheusConnection{
+		mockPrometheusConnection: mockPrometheusConnection{
+			singleResult: &mockPromResult{value: model.Matrix{
+				{
+					Metric: model.Metric{"node": "node-1", "provider_id": "aws:///us-east-1a/i-1"},
+					Values: []model.SamplePair{{Timestamp: 1604339340, Value: 4}},
+				},
+			}},
+			t: t,
+		},
+		calls: map[string]int{},
+	}
+	window := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &PromCollector{
+		PromConn:   conn,
+		TimeSeries: &promv1.Range{Start: window, End: window.Add(59 * time.Minute), Step: time.Minute},
+		Log:        testLogger,
+	}
+	if err := c.getQueryResults(nodeQueries, &mappedResults{}); err != nil {
+		t.Fatalf("getQueryResults got unexpected error: %v", err)
+	}
+	if _, err := c.generateReport(capacityChangeReport{}); err != nil {
+		t.Fatalf("generateReport got unexpected error: %v", err)
+	}
+	for _, q := range (capacityChangeReport{}).Queries() {
+		if got := conn.calls[c.generatorQueryString(q)]; got != 1 {
+			t.Errorf("query %s sent %d times want once", q.Name, got)
+		}
+	}
+	cached := 0
+	for _, stat := range c.QueryStats {
+		if stat.Cached {
+			cached++
+		}
+	}
+	if cached != 2 {
+		t.Errorf("got %d cached query stats want the 2 shared node capacity queries", cached)
+	}
+}
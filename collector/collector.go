@@ -21,16 +21,19 @@ package collector
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
 	"github.com/project-koku/koku-metrics-operator/dirconfig"
-	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var (
@@ -40,6 +43,10 @@ var (
 	namespaceFilePrefix = "cm-openshift-namespace-usage-"
 
 	statusTimeFormat = "2006-01-02 15:04:05"
+
+	// reports that should never be empty when node data exists. The storage report is
+	// legitimately empty on clusters without persistent volume claims.
+	requiredReports = []string{"namespace", "pod"}
 )
 
 type mappedCSVStruct map[string]csvStruct
@@ -93,8 +100,18 @@ func getResourceID(input string) string {
 	return splitString[len(splitString)-1]
 }
 
-func (r *mappedResults) iterateMatrix(matrix model.Matrix, q query) {
+// minutesPerSample returns the number of minutes each sample of a query step covers. The reported values are
+// calibrated for one-minute samples, so the values of a coarser step are scaled by it.
+func minutesPerSample(step time.Duration) float64 {
+	if step <= time.Minute {
+		return 1
+	}
+	return float64(step) / float64(time.Minute)
+}
+
+func (r *mappedResults) iterateMatrix(matrix model.Matrix, q query, step time.Duration, sanitizer *labelSanitizer) {
 	results := *r
+	scale := minutesPerSample(step)
 	for _, stream := range matrix {
 		obj := string(stream.Metric[q.RowKey])
 		if results[obj] == nil {
@@ -107,41 +124,154 @@ func (r *mappedResults) iterateMatrix(matrix model.Matrix, q query) {
 		}
 		if q.MetricKeyRegex != nil {
 			for key, regexField := range q.MetricKeyRegex {
-				results[obj][key] = findFields(stream.Metric, regexField)
+				results[obj][key] = findFields(stream.Metric, regexField, sanitizer)
 			}
 		}
 		if q.QueryValue != nil {
 			saveStruct := q.QueryValue
 			value := getValue(saveStruct, stream.Values)
+			if saveStruct.Method == "sum" {
+				value *= scale
+			}
 			results[obj][saveStruct.ValName] = floatToString(value)
 			if saveStruct.TransformedName != "" {
-				results[obj][saveStruct.TransformedName] = floatToString(value * float64(len(stream.Values)*saveStruct.Factor))
+				results[obj][saveStruct.TransformedName] = floatToString(value * float64(len(stream.Values)*saveStruct.Factor) * scale)
 			}
 		}
 	}
 }
 
-// GenerateReports is responsible for querying prometheus and writing to report files
+// ReportResult is the outcome of a report generation
+type ReportResult struct {
+	// ReportMonth is the month of the billing period of the window, formatted as MM
+	ReportMonth string
+	// LastHourQueried is the window that was queried
+	LastHourQueried string
+	// DataCollected is false when prometheus has no node data for the window, and no reports were written
+	DataCollected bool
+	// Files are the report files written, as returned by the report writer
+	Files []string
+	// Tenants are the tenants the reports were partitioned into
+	Tenants []string
+	// MissingLabels are the expected pod labels that no pod has
+	MissingLabels []string
+	// EmptyReports are the required reports without rows
+	EmptyReports []string
+	// Anomaly describes reports that may be missing data
+	Anomaly string
+	// ReportStats are the rows, bytes written, and query duration of each report
+	ReportStats []kokumetricscfgv1beta1.ReportStat
+	// SanitizedValues are the numbers of label and annotation values that were changed before they were written
+	SanitizedValues kokumetricscfgv1beta1.SanitizedValuesStatus
+	// Inconsistencies are the pods that reference a namespace or node missing from the namespace or node report
+	Inconsistencies kokumetricscfgv1beta1.ConsistencyStatus
+	// CustomMetricsQueried is true when custom metrics are configured, and CustomMetricsError is the error querying them
+	CustomMetricsQueried bool
+	CustomMetricsError   error
+}
+
+// GenerateReports is responsible for querying prometheus and writing to report files. The outcome is recorded in
+// the status of the KokuMetricsConfig.
 func GenerateReports(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, c *PromCollector) error {
+	result, err := c.Generate(&DirWriter{Path: dirCfg.Reports.Path, Log: c.Log})
+	if result == nil {
+		return err
+	}
+	kmCfg.Status.Prometheus.QueryCost = queryCost(c.QueryStats)
+	updateReportStatus(kmCfg, result)
+	if err != nil {
+		return err
+	}
+	if !result.DataCollected {
+		kmCfg.Status.Reports.DataCollected = false
+		kmCfg.Status.Reports.DataCollectionMessage = "No data to report for the hour queried."
+		kmCfg.Status.Reports.ReportStats = result.ReportStats
+		kmCfg.Status.Reports.SanitizedValues = result.SanitizedValues
+		return nil
+	}
+	kmCfg.Status.Reports.MissingLabels = result.MissingLabels
+	kmCfg.Status.Reports.Tenants = result.Tenants
+	kmCfg.Status.Reports.DataCollected = true
+	kmCfg.Status.Reports.DataCollectionMessage = ""
+	kmCfg.Status.Reports.ReportStats = result.ReportStats
+	kmCfg.Status.Reports.SanitizedValues = result.SanitizedValues
+	kmCfg.Status.Reports.EmptyReports = result.EmptyReports
+	kmCfg.Status.Reports.Inconsistencies = result.Inconsistencies
+	if result.Anomaly != "" {
+		kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+			Type:    kokumetricscfgv1beta1.ConditionDegraded,
+			Status:  corev1.ConditionTrue,
+			Reason:  kokumetricscfgv1beta1.ReasonEmptyReports,
+			Message: result.Anomaly,
+		})
+	} else if !consistent(result.Inconsistencies) {
+		kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+			Type:    kokumetricscfgv1beta1.ConditionDegraded,
+			Status:  corev1.ConditionTrue,
+			Reason:  kokumetricscfgv1beta1.ReasonInconsistentReports,
+			Message: inconsistencyMessage(result.Inconsistencies),
+		})
+	} else if len(result.MissingLabels) > 0 {
+		message := fmt.Sprintf("no pod has the expected labels: %s. "+
+			"This usually indicates that the labels are not in the kube-state-metrics label allowlist.", strings.Join(result.MissingLabels, ", "))
+		kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+			Type:    kokumetricscfgv1beta1.ConditionDegraded,
+			Status:  corev1.ConditionTrue,
+			Reason:  kokumetricscfgv1beta1.ReasonMissingLabels,
+			Message: message,
+		})
+	} else {
+		kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+			Type:    kokumetricscfgv1beta1.ConditionDegraded,
+			Status:  corev1.ConditionFalse,
+			Reason:  kokumetricscfgv1beta1.ReasonReportsValid,
+			Message: "",
+		})
+	}
+	return nil
+}
+
+// Generate queries prometheus over the time series of the collector and writes the reports with w. It does not
+// depend on a KokuMetricsConfig, so that tools other than the operator can generate the same reports: only PromConn,
+// TimeSeries, and Log must be set. The result is returned along with an error when the reports could not be
+// written, except when the window was already collected.
+func (c *PromCollector) Generate(w ReportWriter) (*ReportResult, error) {
 	log := c.Log.WithValues("kokumetricsconfig", "GenerateReports")
 
+	if c.Index != nil && !c.Overwrite {
+		if _, ok := c.Index.Get(c.TimeSeries.Start); ok {
+			return nil, ErrWindowCollected
+		}
+	}
+
 	// yearMonth is used in filenames
-	yearMonth := c.TimeSeries.Start.Format("200601") // this corresponds to YYYYMM format
-	updateReportStatus(kmCfg, c.TimeSeries)
+	periodStart, _ := BillingPeriod(c.TimeSeries.Start, c.PeriodStartDay)
+	yearMonth := periodStart.Format("200601") // this corresponds to YYYYMM format
+	result := &ReportResult{
+		ReportMonth:     periodStart.Format("01"),
+		LastHourQueried: c.TimeSeries.Start.Format(statusTimeFormat) + " - " + c.TimeSeries.End.Format(statusTimeFormat),
+	}
+	c.QueryStats = nil
+	c.NamespaceUsage = nil
+	c.cache = queryCache{}
+	c.sanitizer = newLabelSanitizer(c.MaxLabelValueLength)
 
 	// ################################################################################################################
 	log.Info("querying for node metrics")
+	durations := map[string]time.Duration{}
 	nodeResults := mappedResults{}
+	start := time.Now()
 	if err := c.getQueryResults(nodeQueries, &nodeResults); err != nil {
-		return err
+		return result, err
 	}
+	durations["node"] = time.Since(start)
 
+	reportRows.WithLabelValues("node").Set(float64(len(nodeResults)))
 	if len(nodeResults) <= 0 {
 		log.Info("no data to report")
-		kmCfg.Status.Reports.DataCollected = false
-		kmCfg.Status.Reports.DataCollectionMessage = "No data to report for the hour queried."
+		result.ReportStats = reportStats(map[string]int{"node": 0}, nil, durations)
 		// there is no data for the hour queried. Return nothing
-		return nil
+		return result, nil
 	}
 	for node, val := range nodeResults {
 		resourceID := getResourceID(val["provider_id"].(string))
@@ -150,159 +280,309 @@ func GenerateReports(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dir
 
 	nodeRows := make(mappedCSVStruct)
 	for node, val := range nodeResults {
-		usage := newNodeRow(c.TimeSeries)
+		usage := newNodeRow(c.dates())
 		if err := getStruct(val, &usage, nodeRows, node); err != nil {
-			return err
+			return result, err
 		}
 	}
-	emptyNodeRow := newNodeRow(c.TimeSeries)
-	nodeReport := report{
-		file: &file{
-			name: nodeFilePrefix + yearMonth + ".csv",
-			path: dirCfg.Reports.Path,
-		},
-		data: &data{
-			queryData: nodeRows,
-			headers:   emptyNodeRow.csvHeader(),
-			prefix:    emptyNodeRow.dateTimes.string(),
-		},
-	}
-	c.Log.WithValues("kokumetricsconfig", "writeResults").Info("writing node results to file", "filename", nodeReport.file.getName())
-	if err := nodeReport.writeReport(); err != nil {
-		return fmt.Errorf("failed to write node report: %v", err)
-	}
-
 	//################################################################################################################
 
 	log.Info("querying for pod metrics")
 	podResults := mappedResults{}
+	start = time.Now()
 	if err := c.getQueryResults(podQueries, &podResults); err != nil {
-		return err
+		return result, err
+	}
+	workloads, err := c.podWorkloads()
+	if err != nil {
+		return result, err
+	}
+	durations["pod"] = time.Since(start)
+	for pod, val := range podResults {
+		namespace, _ := val["namespace"].(string)
+		if w, ok := workloads[workloadKey(namespace, pod)]; ok {
+			val["workload_kind"] = w.kind
+			val["workload_name"] = w.name
+		}
 	}
 
 	podRows := make(mappedCSVStruct)
 	for pod, val := range podResults {
-		usage := newPodRow(c.TimeSeries)
+		usage := newPodRow(c.dates())
 		if err := getStruct(val, &usage, podRows, pod); err != nil {
-			return err
+			return result, err
 		}
 		if node, ok := val["node"]; ok {
 			// Add the Node usage to the pod.
 			if row, ok := nodeRows[node.(string)]; ok {
 				usage.nodeRow = *row.(*nodeRow)
 			} else {
-				usage.nodeRow = newNodeRow(c.TimeSeries)
+				usage.nodeRow = newNodeRow(c.dates())
 			}
 		}
 	}
-	emptyPodRow := newPodRow(c.TimeSeries)
-	podReport := report{
-		file: &file{
-			name: podFilePrefix + yearMonth + ".csv",
-			path: dirCfg.Reports.Path,
-		},
-		data: &data{
-			queryData: podRows,
-			headers:   emptyPodRow.csvHeader(),
-			prefix:    emptyPodRow.dateTimes.string(),
-		},
-	}
-	c.Log.WithValues("kokumetricsconfig", "writeResults").Info("writing pod results to file", "filename", podReport.file.getName())
-	if err := podReport.writeReport(); err != nil {
-		return fmt.Errorf("failed to write pod report: %v", err)
-	}
+	rowCounts := map[string]int{"node": len(nodeRows), "pod": len(podRows)}
+	result.MissingLabels = c.missingPodLabels(podRows)
+	c.NamespaceUsage = summarizeNamespaceUsage(podRows, c.TimeSeries.Start)
 
 	//################################################################################################################
 
 	log.Info("querying for storage metrics")
 	volResults := mappedResults{}
+	start = time.Now()
 	if err := c.getQueryResults(volQueries, &volResults); err != nil {
-		return err
+		return result, err
 	}
+	durations["storage"] = time.Since(start)
 
 	volRows := make(mappedCSVStruct)
 	for pvc, val := range volResults {
-		usage := newStorageRow(c.TimeSeries)
+		usage := newStorageRow(c.dates())
 		if err := getStruct(val, &usage, volRows, pvc); err != nil {
-			return err
+			return result, err
 		}
 	}
-	emptyVolRow := newStorageRow(c.TimeSeries)
-	volReport := report{
-		file: &file{
-			name: volFilePrefix + yearMonth + ".csv",
-			path: dirCfg.Reports.Path,
-		},
-		data: &data{
-			queryData: volRows,
-			headers:   emptyVolRow.csvHeader(),
-			prefix:    emptyVolRow.dateTimes.string(),
-		},
-	}
-	c.Log.WithValues("kokumetricsconfig", "writeResults").Info("writing volume results to file", "filename", volReport.file.getName())
-	if err := volReport.writeReport(); err != nil {
-		return fmt.Errorf("failed to write volume report: %v", err)
-	}
+	rowCounts["storage"] = len(volRows)
 
 	//################################################################################################################
 
 	log.Info("querying for namespaces")
 	namespaceResults := mappedResults{}
+	start = time.Now()
 	if err := c.getQueryResults(namespaceQueries, &namespaceResults); err != nil {
-		return err
+		return result, err
 	}
+	durations["namespace"] = time.Since(start)
 
 	namespaceRows := make(mappedCSVStruct)
 	for namespace, val := range namespaceResults {
-		usage := newNamespaceRow(c.TimeSeries)
+		usage := newNamespaceRow(c.dates())
 		if err := getStruct(val, &usage, namespaceRows, namespace); err != nil {
-			return err
+			return result, err
 		}
 	}
-	emptyNameRow := newNamespaceRow(c.TimeSeries)
-	namespaceReport := report{
-		file: &file{
-			name: namespaceFilePrefix + yearMonth + ".csv",
-			path: dirCfg.Reports.Path,
-		},
-		data: &data{
-			queryData: namespaceRows,
-			headers:   emptyNameRow.csvHeader(),
-			prefix:    emptyNameRow.dateTimes.string(),
-		},
+	rowCounts["namespace"] = len(namespaceRows)
+	result.Inconsistencies = checkConsistency(podResults, nodeRows, namespaceRows)
+	inconsistentPods.WithLabelValues("namespace").Set(float64(result.Inconsistencies.PodsWithoutNamespace))
+	inconsistentPods.WithLabelValues("node").Set(float64(result.Inconsistencies.PodsWithoutNode))
+
+	//################################################################################################################
+
+	start = time.Now()
+	customRows := c.queryCustomMetrics(result)
+	if customRows != nil {
+		rowCounts["custom"] = len(customRows)
+		durations["custom"] = time.Since(start)
 	}
-	c.Log.WithValues("kokumetricsconfig", "writeResults").Info("writing namespace results to file", "filename", namespaceReport.file.getName())
-	if err := namespaceReport.writeReport(); err != nil {
-		return fmt.Errorf("failed to write namespace report: %v", err)
+
+	//################################################################################################################
+
+	generated := c.generateReports()
+	for _, r := range generated {
+		rowCounts[r.generator.Name()] = len(r.rows)
+		durations[r.generator.Name()] = r.duration
 	}
 
 	//################################################################################################################
 
-	kmCfg.Status.Reports.DataCollected = true
-	kmCfg.Status.Reports.DataCollectionMessage = ""
+	all := reportSet{node: nodeRows, pod: podRows, storage: volRows, namespace: namespaceRows, custom: customRows, generated: generated}
+	if c.NamespaceGranularity {
+		all = aggregateNamespaces(all)
+	}
+	sets := partitionReports(c.TenantLabel, all)
+	result.Tenants = tenantNames(sets)
+	var reportFiles []ReportFile
+	for tenant, set := range sets {
+		dir := ""
+		if tenant != "" {
+			dir = filepath.Join(dirconfig.TenantDir, tenant)
+		}
+		reportFiles = append(reportFiles, c.reportSetFiles(set, dir, yearMonth)...)
+		reportFiles = append(reportFiles, c.generatedReportFiles(set.generated, dir, yearMonth)...)
+	}
+	written := map[string]int64{}
+	for _, f := range reportFiles {
+		location, n, err := w.WriteReport(f)
+		if err != nil {
+			return result, err
+		}
+		written[statReport(f.Report)] += n
+		result.Files = append(result.Files, location)
+	}
 
-	return nil
+	if c.Index != nil {
+		files := append([]string{}, result.Files...)
+		sort.Strings(files)
+		c.Index.Record(WindowEntry{
+			Start:       c.TimeSeries.Start,
+			End:         c.TimeSeries.End,
+			Files:       files,
+			Checksum:    rowsChecksum(nodeRows, podRows, volRows, namespaceRows, customRows),
+			CollectedAt: time.Now(),
+			Endpoint:    c.ServingAddress,
+		})
+		if err := c.Index.Save(); err != nil {
+			return result, fmt.Errorf("failed to save window index: %v", err)
+		}
+	}
+
+	//################################################################################################################
+
+	result.DataCollected = true
+	result.ReportStats = reportStats(rowCounts, written, durations)
+	result.SanitizedValues = c.sanitizer.counts
+	if c.sanitizer.counts != (kokumetricscfgv1beta1.SanitizedValuesStatus{}) {
+		log.Info("label values were sanitized", "truncated", c.sanitizer.counts.Truncated,
+			"controlCharacters", c.sanitizer.counts.ControlCharacters, "escaped", c.sanitizer.counts.Escaped)
+	}
+
+	for report, count := range rowCounts {
+		reportRows.WithLabelValues(report).Set(float64(count))
+	}
+	result.EmptyReports, result.Anomaly = validateReportRows(rowCounts)
+	for _, report := range result.EmptyReports {
+		emptyReportsTotal.WithLabelValues(report).Inc()
+	}
+	if result.Anomaly != "" {
+		log.Info("reports may be missing data", "anomaly", result.Anomaly)
+	} else if !consistent(result.Inconsistencies) {
+		log.Info("reports are inconsistent", "inconsistencies", inconsistencyMessage(result.Inconsistencies))
+	} else if len(result.MissingLabels) > 0 {
+		log.Info("reports are missing expected labels", "labels", result.MissingLabels)
+	}
+
+	return result, nil
+}
+
+// reportSetFiles returns the report file of each report of the set, in the dir of the reports directory
+func (c *PromCollector) reportSetFiles(set reportSet, dir, yearMonth string) []ReportFile {
+	type reportFile struct {
+		name   string
+		prefix string
+		rows   mappedCSVStruct
+		empty  csvStruct
+	}
+	reports := []reportFile{
+		{name: nodeReport, prefix: nodeFilePrefix, rows: set.node, empty: newNodeRow(c.dates())},
+		{name: podReport, prefix: podFilePrefix, rows: set.pod, empty: newPodRow(c.dates())},
+		{name: volumeReport, prefix: volFilePrefix, rows: set.storage, empty: newStorageRow(c.dates())},
+		{name: namespaceReport, prefix: namespaceFilePrefix, rows: set.namespace, empty: newNamespaceRow(c.dates())},
+	}
+	if set.custom != nil {
+		reports = append(reports, reportFile{name: customReport, prefix: customFilePrefix, rows: set.custom, empty: newCustomMetricRow(c.dates())})
+	}
+	dates := c.dates()
+	files := make([]ReportFile, 0, len(reports))
+	for _, r := range reports {
+		files = append(files, ReportFile{
+			Report:   r.name,
+			Name:     filepath.Join(dir, r.prefix+yearMonth+".csv"),
+			Headers:  r.empty.csvHeader(),
+			Interval: dates.string(),
+			Rows:     sortedRows(r.rows),
+		})
+	}
+	return files
+}
+
+// statReport returns the name of a report in the report stats, which matches the name used for its row count
+func statReport(report string) string {
+	if report == volumeReport {
+		return "storage"
+	}
+	return report
+}
+
+// reportStats returns the rows, bytes written, and query duration of each report, sorted by report name
+func reportStats(rowCounts map[string]int, written map[string]int64, durations map[string]time.Duration) []kokumetricscfgv1beta1.ReportStat {
+	stats := make([]kokumetricscfgv1beta1.ReportStat, 0, len(rowCounts))
+	for report, count := range rowCounts {
+		stats = append(stats, kokumetricscfgv1beta1.ReportStat{
+			Report:                    report,
+			Rows:                      int64(count),
+			Bytes:                     written[report],
+			QueryDurationMilliseconds: durations[report].Milliseconds(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Report < stats[j].Report })
+	return stats
+}
+
+// missingPodLabels returns the expected pod labels that none of the pod rows have. Labels are not checked in the
+// lightweight profile, which does not collect them, or when there are no pod rows.
+func (c *PromCollector) missingPodLabels(podRows mappedCSVStruct) []string {
+	if len(c.ExpectedPodLabels) == 0 || c.Lightweight || len(podRows) == 0 {
+		return nil
+	}
+	found := map[string]bool{}
+	for _, row := range podRows {
+		pod, ok := row.(*podRow)
+		if !ok || pod.PodLabels == "" {
+			continue
+		}
+		for _, label := range strings.Split(pod.PodLabels, "|") {
+			found[strings.SplitN(label, ":", 2)[0]] = true
+		}
+	}
+	var missing []string
+	for _, label := range c.ExpectedPodLabels {
+		if !found[ksmLabelKey(label)] {
+			missing = append(missing, label)
+		}
+	}
+	return missing
+}
+
+// validateReportRows returns the required reports that contain no rows, along with a message describing
+// any row counts that indicate missing data. An empty message means the row counts look sane.
+func validateReportRows(rowCounts map[string]int) ([]string, string) {
+	var emptyReports []string
+	for _, report := range requiredReports {
+		if rowCounts[report] <= 0 {
+			emptyReports = append(emptyReports, report)
+		}
+	}
+	if len(emptyReports) > 0 {
+		return emptyReports, fmt.Sprintf("the following reports contain no rows: %s. "+
+			"This usually indicates that kube-state-metrics is not being scraped by prometheus.", strings.Join(emptyReports, ", "))
+	}
+	// every node runs at least one pod, so fewer pods than nodes means pod metrics are missing
+	if rowCounts["pod"] < rowCounts["node"] {
+		return nil, fmt.Sprintf("the pod report contains %d rows, but there are %d nodes. "+
+			"This usually indicates that pod metrics are missing from prometheus.", rowCounts["pod"], rowCounts["node"])
+	}
+	return nil, ""
 }
 
-func findFields(input model.Metric, str string) string {
+func findFields(input model.Metric, str string, sanitizer *labelSanitizer) string {
 	result := []string{}
 	for name, val := range input {
 		name := string(name)
 		match, _ := regexp.MatchString(str, name)
 		if match {
-			result = append(result, name+":"+string(val))
+			result = append(result, name+":"+sanitizer.sanitize(string(val)))
 		}
 	}
 	switch length := len(result); {
 	case length > 0:
 		sort.Strings(result)
-		return strings.Join(result, "|")
+		return strings.Join(result, labelSeparator)
 	default:
 		return ""
 	}
 }
 
-func updateReportStatus(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, ts *promv1.Range) {
-	kmCfg.Status.Reports.ReportMonth = ts.Start.Format("01")
-	kmCfg.Status.Reports.LastHourQueried = ts.Start.Format(statusTimeFormat) + " - " + ts.End.Format(statusTimeFormat)
+// updateReportStatus records the window queried and the outcome of the custom metrics queries in the status
+func updateReportStatus(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, result *ReportResult) {
+	kmCfg.Status.Reports.ReportMonth = result.ReportMonth
+	kmCfg.Status.Reports.LastHourQueried = result.LastHourQueried
+	if !result.CustomMetricsQueried {
+		return
+	}
+	if result.CustomMetricsError != nil {
+		kmCfg.Status.CustomMetrics.Error = fmt.Sprintf("%v", result.CustomMetricsError)
+		return
+	}
+	kmCfg.Status.CustomMetrics.Error = ""
+	kmCfg.Status.CustomMetrics.LastQuerySuccessTime = metav1.Now()
 }
@@ -60,6 +60,17 @@ func Load(path string, v interface{}, t *testing.T) {
 	}
 }
 
+// addGeneratorResults adds no results for each query of the registered report generators that has no result
+func addGeneratorResults(mapResults mappedMockPromResult) {
+	for _, g := range ReportGenerators() {
+		for _, q := range g.Queries() {
+			if _, ok := mapResults[q.Query]; !ok {
+				mapResults[q.Query] = &mockPromResult{value: model.Matrix{}}
+			}
+		}
+	}
+}
+
 var (
 	fakeKMCfg  = &kokumetricscfgv1beta1.KokuMetricsConfig{}
 	fakeDirCfg = &dirconfig.DirectoryConfig{
@@ -125,7 +136,7 @@ func compareFiles(expected, generated *os.File) error {
 
 func TestGenerateReports(t *testing.T) {
 	mapResults := make(mappedMockPromResult)
-	queryList := []*querys{nodeQueries, namespaceQueries, podQueries, volQueries}
+	queryList := []*querys{nodeQueries, namespaceQueries, podQueries, workloadQueries, volQueries}
 	for _, q := range queryList {
 		for _, query := range *q {
 			res := &model.Matrix{}
@@ -133,6 +144,7 @@ func TestGenerateReports(t *testing.T) {
 			mapResults[query.QueryString] = &mockPromResult{value: *res}
 		}
 	}
+	addGeneratorResults(mapResults)
 
 	fakeCollector := &PromCollector{
 		PromConn: mockPrometheusConnection{
@@ -146,6 +158,23 @@ func TestGenerateReports(t *testing.T) {
 		t.Errorf("Failed to generate reports: %v", err)
 	}
 
+	var statNames []string
+	for _, stat := range fakeKMCfg.Status.Reports.ReportStats {
+		statNames = append(statNames, stat.Report)
+		if stat.Rows > 0 && stat.Bytes <= 0 {
+			t.Errorf("%s report stat got %d bytes for %d rows want more than 0 bytes", stat.Report, stat.Bytes, stat.Rows)
+		}
+	}
+	if want := []string{"autoscaler", "namespace", "node", "node-capacity-change", "pod", "storage"}; !reflect.DeepEqual(statNames, want) {
+		t.Errorf("report stats got %v want %v", statNames, want)
+	}
+	if len(fakeKMCfg.Status.Reports.MissingLabels) != 0 {
+		t.Errorf("missing labels got %v want none", fakeKMCfg.Status.Reports.MissingLabels)
+	}
+	if inconsistencies := fakeKMCfg.Status.Reports.Inconsistencies; !consistent(inconsistencies) {
+		t.Errorf("inconsistencies got %+v want none", inconsistencies)
+	}
+
 	// ####### everything below compares the generated reports to the expected reports #######
 	expectedMap := getFiles("expected_reports", t)
 	generatedMap := getFiles("test_reports", t)
@@ -170,6 +199,65 @@ func TestGenerateReports(t *testing.T) {
 	}
 }
 
+func TestGenerateReportsAnnotations(t *testing.T) {
+	mapResults := make(mappedMockPromResult)
+	queryList := []*querys{nodeQueries, namespaceQueries, podQueries, workloadQueries, volQueries}
+	for _, q := range queryList {
+		for _, query := range *q {
+			res := &model.Matrix{}
+			Load(filepath.Join("test_files", "test_data", query.Name), res, t)
+			mapResults[query.QueryString] = &mockPromResult{value: *res}
+		}
+	}
+	addGeneratorResults(mapResults)
+
+	fakeCollector := &PromCollector{
+		PromConn: mockPrometheusConnection{
+			mappedResults: &mapResults,
+			t:             t,
+		},
+		TimeSeries:         &fakeTimeRange,
+		Log:                testLogger,
+		AnnotationPrefixes: []string{"example.com/"},
+	}
+	if err := GenerateReports(fakeKMCfg, fakeDirCfg, fakeCollector); err != nil {
+		t.Errorf("Failed to generate reports: %v", err)
+	}
+
+	want := map[string]string{
+		"cm-openshift-node-usage-202011.csv":      "ip-10-0-189-61.us-east-2.compute.internal,",
+		"cm-openshift-pod-usage-202011.csv":       ",openshift-etcd-operator,etcd-operator-576bc857f8-6k7x2,",
+		"cm-openshift-namespace-usage-202011.csv": ",openshift-cluster-version,",
+	}
+	annotations := map[string]string{
+		"cm-openshift-node-usage-202011.csv":      ",annotation_example_com_cost_center:cc-1234",
+		"cm-openshift-pod-usage-202011.csv":       ",annotation_example_com_cost_center:cc-5678,Deployment,etcd-operator,Burstable,system-cluster-critical",
+		"cm-openshift-namespace-usage-202011.csv": ",annotation_example_com_cost_center:cc-9012",
+	}
+	for name, row := range want {
+		data, err := ioutil.ReadFile(filepath.Join(fakeDirCfg.Reports.Path, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		found := false
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.Contains(line, row) {
+				found = true
+				if !strings.HasSuffix(line, annotations[name]) {
+					t.Errorf("%s row %q does not end with %q", name, line, annotations[name])
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s does not contain a row with %q", name, row)
+		}
+	}
+
+	if err := fakeDirCfg.Reports.RemoveContents(); err != nil {
+		t.Fatal("failed to cleanup reports directory")
+	}
+}
+
 func TestGenerateReportsQueryErrors(t *testing.T) {
 	mapResults := make(mappedMockPromResult)
 	fakeCollector := &PromCollector{
@@ -181,7 +269,7 @@ func TestGenerateReportsQueryErrors(t *testing.T) {
 		Log:        testLogger,
 	}
 
-	queryList := []*querys{nodeQueries, podQueries, volQueries}
+	queryList := []*querys{nodeQueries, podQueries, workloadQueries, volQueries}
 	for _, q := range queryList {
 		for _, query := range *q {
 			res := &model.Matrix{}
@@ -189,6 +277,7 @@ func TestGenerateReportsQueryErrors(t *testing.T) {
 			mapResults[query.QueryString] = &mockPromResult{value: *res}
 		}
 	}
+	addGeneratorResults(mapResults)
 	namespaceError := "namespace error"
 	for _, q := range *namespaceQueries {
 		mapResults[q.QueryString] = &mockPromResult{err: errors.New(namespaceError)}
@@ -260,6 +349,224 @@ func TestGenerateReportsNoNodeData(t *testing.T) {
 	}
 }
 
+func TestGenerateReportsEmptyPodData(t *testing.T) {
+	mapResults := make(mappedMockPromResult)
+	for _, query := range *nodeQueries {
+		res := &model.Matrix{}
+		Load(filepath.Join("test_files", "test_data", query.Name), res, t)
+		mapResults[query.QueryString] = &mockPromResult{value: *res}
+	}
+	queryList := []*querys{namespaceQueries, podQueries, workloadQueries, volQueries}
+	for _, q := range queryList {
+		for _, query := range *q {
+			mapResults[query.QueryString] = &mockPromResult{value: model.Matrix{}}
+		}
+	}
+	addGeneratorResults(mapResults)
+
+	fakeCollector := &PromCollector{
+		PromConn: mockPrometheusConnection{
+			mappedResults: &mapResults,
+			t:             t,
+		},
+		TimeSeries: &fakeTimeRange,
+		Log:        testLogger,
+	}
+	kmCfg := fakeKMCfg.DeepCopy()
+	if err := GenerateReports(kmCfg, fakeDirCfg, fakeCollector); err != nil {
+		t.Errorf("Failed to generate reports: %v", err)
+	}
+	wanted := []string{"namespace", "pod"}
+	if !reflect.DeepEqual(kmCfg.Status.Reports.EmptyReports, wanted) {
+		t.Errorf("EmptyReports not updated correctly: got %v want %v", kmCfg.Status.Reports.EmptyReports, wanted)
+	}
+	if !kokumetricscfgv1beta1.IsConditionTrue(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionDegraded) {
+		t.Errorf("expected Degraded condition to be true, got %+v", kmCfg.Status.Conditions)
+	}
+	if err := fakeDirCfg.Reports.RemoveContents(); err != nil {
+		t.Fatal("failed to cleanup reports directory")
+	}
+}
+
+func TestReportStats(t *testing.T) {
+	rowCounts := map[string]int{"pod": 10, "node": 2, "storage": 0}
+	written := map[string]int64{"pod": 1000, "node": 200}
+	durations := map[string]time.Duration{"pod": 1500 * time.Millisecond, "node": 20 * time.Millisecond}
+	got := reportStats(rowCounts, written, durations)
+	want := []kokumetricscfgv1beta1.ReportStat{
+		{Report: "node", Rows: 2, Bytes: 200, QueryDurationMilliseconds: 20},
+		{Report: "pod", Rows: 10, Bytes: 1000, QueryDurationMilliseconds: 1500},
+		{Report: "storage", Rows: 0, Bytes: 0, QueryDurationMilliseconds: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reportStats got %+v want %+v", got, want)
+	}
+	if statReport(volumeReport) != "storage" || statReport(podReport) != "pod" {
+		t.Errorf("statReport got %s and %s want storage and pod", statReport(volumeReport), statReport(podReport))
+	}
+}
+
+func TestMissingPodLabels(t *testing.T) {
+	podRows := mappedCSVStruct{
+		"pod-a": &podRow{Pod: "pod-a", PodLabels: "label_app:web|label_cost_center:42"},
+		"pod-b": &podRow{Pod: "pod-b", PodLabels: ""},
+	}
+	missingLabelsTests := []struct {
+		name        string
+		expected    []string
+		lightweight bool
+		rows        mappedCSVStruct
+		want        []string
+	}{
+		{name: "no expected labels", expected: nil, rows: podRows, want: nil},
+		{name: "all labels found", expected: []string{"app", "cost-center"}, rows: podRows, want: nil},
+		{name: "missing labels", expected: []string{"app", "team", "example.com/owner"}, rows: podRows, want: []string{"team", "example.com/owner"}},
+		{name: "label value is not a key", expected: []string{"web"}, rows: podRows, want: []string{"web"}},
+		{name: "lightweight profile", expected: []string{"team"}, lightweight: true, rows: podRows, want: nil},
+		{name: "no pod rows", expected: []string{"team"}, rows: mappedCSVStruct{}, want: nil},
+	}
+	for _, tt := range missingLabelsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &PromCollector{ExpectedPodLabels: tt.expected, Lightweight: tt.lightweight}
+			if got := c.missingPodLabels(tt.rows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s got %v want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportRows(t *testing.T) {
+	validateReportRowsTests := []struct {
+		name       string
+		rowCounts  map[string]int
+		wantEmpty  []string
+		wantReport bool
+	}{
+		{
+			name:       "all reports contain rows",
+			rowCounts:  map[string]int{"node": 3, "pod": 50, "storage": 0, "namespace": 10},
+			wantEmpty:  nil,
+			wantReport: false,
+		},
+		{
+			name:       "pod report is empty",
+			rowCounts:  map[string]int{"node": 3, "pod": 0, "storage": 0, "namespace": 10},
+			wantEmpty:  []string{"pod"},
+			wantReport: true,
+		},
+		{
+			name:       "pod and namespace reports are empty",
+			rowCounts:  map[string]int{"node": 3, "pod": 0, "storage": 2, "namespace": 0},
+			wantEmpty:  []string{"namespace", "pod"},
+			wantReport: true,
+		},
+		{
+			name:       "fewer pods than nodes",
+			rowCounts:  map[string]int{"node": 3, "pod": 2, "storage": 0, "namespace": 10},
+			wantEmpty:  nil,
+			wantReport: true,
+		},
+	}
+	for _, tt := range validateReportRowsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEmpty, gotMessage := validateReportRows(tt.rowCounts)
+			if !reflect.DeepEqual(gotEmpty, tt.wantEmpty) {
+				t.Errorf("%s got %v want %v", tt.name, gotEmpty, tt.wantEmpty)
+			}
+			if (gotMessage != "") != tt.wantReport {
+				t.Errorf("%s got message %q, expected anomaly: %t", tt.name, gotMessage, tt.wantReport)
+			}
+		})
+	}
+}
+
+func TestBillingPeriod(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data is not available: %v", err)
+	}
+	tests := []struct {
+		name      string
+		t         time.Time
+		startDay  int
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "calendar month",
+			t:         time.Date(2020, 11, 6, 18, 0, 0, 0, time.UTC),
+			startDay:  1,
+			wantStart: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "unset start day",
+			t:         time.Date(2020, 11, 6, 18, 0, 0, 0, time.UTC),
+			startDay:  0,
+			wantStart: time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "before the start day",
+			t:         time.Date(2021, 1, 6, 18, 0, 0, 0, time.UTC),
+			startDay:  15,
+			wantStart: time.Date(2020, 12, 15, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2021, 1, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "on the start day",
+			t:         time.Date(2020, 11, 15, 0, 0, 0, 0, time.UTC),
+			startDay:  15,
+			wantStart: time.Date(2020, 11, 15, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2020, 12, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "billing time zone",
+			t:         time.Date(2020, 11, 30, 22, 0, 0, 0, newYork),
+			startDay:  1,
+			wantStart: time.Date(2020, 11, 1, 0, 0, 0, 0, newYork),
+			wantEnd:   time.Date(2020, 12, 1, 0, 0, 0, 0, newYork),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStart, gotEnd := BillingPeriod(tt.t, tt.startDay)
+			if !gotStart.Equal(tt.wantStart) || !gotEnd.Equal(tt.wantEnd) {
+				t.Errorf("BillingPeriod() = %v - %v, want %v - %v", gotStart, gotEnd, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestSummarizeNamespaceUsage(t *testing.T) {
+	start := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	podRows := mappedCSVStruct{
+		"pod-a": &podRow{Namespace: "ns1", PodUsageCPUCoreSeconds: "3600", PodRequestCPUCoreSeconds: "7200",
+			PodUsageMemoryByteSeconds: floatToString(3600 * bytesPerGiB), PodRequestMemoryByteSeconds: floatToString(7200 * bytesPerGiB)},
+		"pod-b": &podRow{Namespace: "ns1", PodUsageCPUCoreSeconds: "1800", PodRequestCPUCoreSeconds: "",
+			PodUsageMemoryByteSeconds: floatToString(1800 * bytesPerGiB), PodRequestMemoryByteSeconds: "not-a-number"},
+		"pod-c": &podRow{Namespace: "ns2", PodUsageCPUCoreSeconds: "360"},
+	}
+	want := map[string]NamespaceUsage{
+		"ns1": {CPUUsageCoreHours: 1.5, CPURequestCoreHours: 2, MemoryUsageGiBHours: 1.5, MemoryRequestGiBHours: 2},
+		"ns2": {CPUUsageCoreHours: 0.1},
+	}
+	got := summarizeNamespaceUsage(podRows, start)
+	if !got.Start.Equal(start) {
+		t.Errorf("got start %v want %v", got.Start, start)
+	}
+	if len(got.Namespaces) != len(want) {
+		t.Fatalf("got %d namespaces want %d", len(got.Namespaces), len(want))
+	}
+	for namespace, w := range want {
+		g := got.Namespaces[namespace]
+		if !nearlyEqual(g.CPUUsageCoreHours, w.CPUUsageCoreHours) || !nearlyEqual(g.CPURequestCoreHours, w.CPURequestCoreHours) ||
+			!nearlyEqual(g.MemoryUsageGiBHours, w.MemoryUsageGiBHours) || !nearlyEqual(g.MemoryRequestGiBHours, w.MemoryRequestGiBHours) {
+			t.Errorf("namespace %s got %+v want %+v", namespace, g, w)
+		}
+	}
+}
+
 func TestGetResourceID(t *testing.T) {
 	getResourceIDTests := []struct {
 		name  string
@@ -387,7 +694,7 @@ func TestFindFields(t *testing.T) {
 	}
 	for _, tt := range findFieldsTests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := findFields(tt.input, tt.str)
+			got := findFields(tt.input, tt.str, newLabelSanitizer(0))
 			if got != tt.want {
 				t.Errorf("%s got %s want %s", tt.name, got, tt.want)
 			}
@@ -528,7 +835,7 @@ func TestIterateMatrix(t *testing.T) {
 	}
 	for _, tt := range iterateMatrixTests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.results.iterateMatrix(tt.matrix, tt.query)
+			tt.results.iterateMatrix(tt.matrix, tt.query, time.Minute, newLabelSanitizer(0))
 			eq := reflect.DeepEqual(tt.results, tt.want)
 			if !eq {
 				t.Errorf("%s got:\n\t%s\n  want:\n\t%s", tt.name, tt.results, tt.want)
@@ -536,3 +843,36 @@ func TestIterateMatrix(t *testing.T) {
 		})
 	}
 }
+
+func TestIterateMatrixStep(t *testing.T) {
+	series := func(step time.Duration, value model.SampleValue) model.Matrix {
+		var values []model.SamplePair
+		for ts := time.Duration(0); ts < time.Hour; ts += step {
+			values = append(values, model.SamplePair{Timestamp: model.Time(ts / time.Millisecond), Value: value})
+		}
+		return model.Matrix{{Metric: model.Metric{"pod": "pod-a", "node": "node-a"}, Values: values}}
+	}
+	queries := []query{
+		{
+			Name:       "pod-usage-cpu-cores",
+			QueryValue: &saveQueryValue{ValName: "usage", Method: "sum", Factor: sumFactor, TransformedName: "usage-seconds"},
+			RowKey:     "pod",
+		},
+		{
+			Name:       "node-capacity-cpu-cores",
+			QueryValue: &saveQueryValue{ValName: "capacity", Method: "max", Factor: maxFactor, TransformedName: "capacity-seconds"},
+			RowKey:     "node",
+		},
+	}
+	for _, q := range queries {
+		want := mappedResults{}
+		want.iterateMatrix(series(time.Minute, 2), q, time.Minute, newLabelSanitizer(0))
+		for _, step := range []time.Duration{2 * time.Minute, 5 * time.Minute} {
+			got := mappedResults{}
+			got.iterateMatrix(series(step, 2), q, step, newLabelSanitizer(0))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s got %v at a %s step want %v", q.Name, got, step, want)
+			}
+		}
+	}
+}
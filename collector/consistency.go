@@ -0,0 +1,92 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// maxInconsistentNames is the number of missing namespaces and nodes that are listed in the status
+const maxInconsistentNames = 10
+
+// checkConsistency checks that every pod references a namespace of the namespace report and a node of the node report.
+// The reports of a window are queried separately, so a pod that references a namespace or node that is missing from
+// the same window means that a query failed part way or that prometheus returned partial results. Pods without a
+// namespace or node are not checked.
+func checkConsistency(podResults mappedResults, nodeRows, namespaceRows mappedCSVStruct) kokumetricscfgv1beta1.ConsistencyStatus {
+	status := kokumetricscfgv1beta1.ConsistencyStatus{}
+	namespaces, nodes := map[string]bool{}, map[string]bool{}
+	for _, val := range podResults {
+		if namespace, _ := val["namespace"].(string); namespace != "" {
+			if _, ok := namespaceRows[namespace]; !ok {
+				status.PodsWithoutNamespace++
+				namespaces[namespace] = true
+			}
+		}
+		if node, _ := val["node"].(string); node != "" {
+			if _, ok := nodeRows[node]; !ok {
+				status.PodsWithoutNode++
+				nodes[node] = true
+			}
+		}
+	}
+	status.MissingNamespaces = firstNames(namespaces)
+	status.MissingNodes = firstNames(nodes)
+	return status
+}
+
+// firstNames returns the first maxInconsistentNames names, in order
+func firstNames(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > maxInconsistentNames {
+		names = names[:maxInconsistentNames]
+	}
+	return names
+}
+
+// consistent returns true when no pod references a missing namespace or node
+func consistent(status kokumetricscfgv1beta1.ConsistencyStatus) bool {
+	return status.PodsWithoutNamespace == 0 && status.PodsWithoutNode == 0
+}
+
+// inconsistencyMessage describes the pods that reference a missing namespace or node
+func inconsistencyMessage(status kokumetricscfgv1beta1.ConsistencyStatus) string {
+	var problems []string
+	if status.PodsWithoutNamespace > 0 {
+		problems = append(problems, fmt.Sprintf("%d pods reference namespaces that are not in the namespace report: %s",
+			status.PodsWithoutNamespace, strings.Join(status.MissingNamespaces, ", ")))
+	}
+	if status.PodsWithoutNode > 0 {
+		problems = append(problems, fmt.Sprintf("%d pods reference nodes that are not in the node report: %s",
+			status.PodsWithoutNode, strings.Join(status.MissingNodes, ", ")))
+	}
+	return strings.Join(problems, "; ") + ". This usually indicates that a query failed part way or that prometheus returned partial results."
+}
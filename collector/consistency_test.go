@@ -0,0 +1,84 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	nodeRows := mappedCSVStruct{"node-a": &nodeRow{Node: "node-a"}}
+	namespaceRows := mappedCSVStruct{"web": &namespaceRow{Namespace: "web"}}
+	tests := []struct {
+		name       string
+		podResults mappedResults
+		want       kokumetricscfgv1beta1.ConsistencyStatus
+	}{
+		{
+			name: "consistent",
+			podResults: mappedResults{
+				"pod-1": mappedValues{"namespace": "web", "node": "node-a"},
+				"pod-2": mappedValues{"namespace": "web"},
+			},
+			want: kokumetricscfgv1beta1.ConsistencyStatus{},
+		},
+		{
+			name: "missing namespace and node",
+			podResults: mappedResults{
+				"pod-1": mappedValues{"namespace": "web", "node": "node-b"},
+				"pod-2": mappedValues{"namespace": "db", "node": "node-a"},
+				"pod-3": mappedValues{"namespace": "db", "node": "node-c"},
+			},
+			want: kokumetricscfgv1beta1.ConsistencyStatus{
+				PodsWithoutNamespace: 2,
+				MissingNamespaces:    []string{"db"},
+				PodsWithoutNode:      2,
+				MissingNodes:         []string{"node-b", "node-c"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := checkConsistency(tt.podResults, nodeRows, namespaceRows)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s got %+v want %+v", tt.name, got, tt.want)
+		}
+		if consistent(got) != (tt.name == "consistent") {
+			t.Errorf("%s got consistent %t", tt.name, consistent(got))
+		}
+	}
+}
+
+func TestInconsistencyMessage(t *testing.T) {
+	names := map[string]bool{}
+	for _, name := range strings.Split("a b c d e f g h i j k l", " ") {
+		names[name] = true
+	}
+	if got := firstNames(names); len(got) != maxInconsistentNames || got[0] != "a" {
+		t.Errorf("firstNames got %v want the first %d names", got, maxInconsistentNames)
+	}
+	message := inconsistencyMessage(kokumetricscfgv1beta1.ConsistencyStatus{PodsWithoutNode: 3, MissingNodes: []string{"node-b"}})
+	if !strings.HasPrefix(message, "3 pods reference nodes that are not in the node report: node-b.") || strings.Contains(message, "namespace report") {
+		t.Errorf("inconsistencyMessage got %q want only the missing nodes", message)
+	}
+}
@@ -0,0 +1,215 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/logging"
+)
+
+var customFilePrefix = "cm-openshift-custom-usage-"
+
+func newCustomMetricRow(d *dateTimes) customMetricRow {
+	return customMetricRow{dateTimes: d}
+}
+
+type customMetricRow struct {
+	*dateTimes
+	Namespace   string
+	Pod         string
+	MetricName  string
+	MetricValue string
+}
+
+func (customMetricRow) csvHeader() []string { return reportColumns(customReport) }
+
+func (row customMetricRow) csvRow() []string {
+	return []string{
+		row.ReportPeriodStart,
+		row.ReportPeriodEnd,
+		row.IntervalStart,
+		row.IntervalEnd,
+		row.Namespace,
+		row.Pod,
+		row.MetricName,
+		row.MetricValue,
+	}
+}
+
+func (row customMetricRow) string() string { return strings.Join(row.csvRow(), ",") }
+
+func customMetricsStatusHelper(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, err error) {
+	if err != nil {
+		kmCfg.Status.CustomMetrics.Connected = false
+		kmCfg.Status.CustomMetrics.Error = fmt.Sprintf("%v", err)
+	} else {
+		kmCfg.Status.CustomMetrics.Connected = true
+		kmCfg.Status.CustomMetrics.Error = ""
+	}
+}
+
+// GetUWMConn returns the connection to the user workload monitoring prometheus. The connection is cleared when
+// custom metrics are not configured.
+func (c *PromCollector) GetUWMConn(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) error {
+	log := c.Log.WithValues("kokumetricsconfig", "GetUWMConn")
+
+	spec := kmCfg.Spec.CustomMetrics
+	if spec == nil || len(spec.Queries) <= 0 {
+		c.UWMConn = nil
+		c.CustomMetrics = nil
+		c.uwmSpec = nil
+		kmCfg.Status.CustomMetrics = kokumetricscfgv1beta1.CustomMetricsStatus{}
+		return nil
+	}
+	c.CustomMetrics = spec.Queries
+
+	updated := c.uwmSpec == nil || !reflect.DeepEqual(*c.uwmSpec, *spec) ||
+		c.uwmTLS == nil || !reflect.DeepEqual(*c.uwmTLS, kmCfg.Spec.PrometheusConfig.TLS)
+	if c.StaticConn != nil {
+		c.UWMConn = c.StaticConn
+	} else if updated || c.UWMConn == nil || !kmCfg.Status.CustomMetrics.Connected {
+		log.Info("getting user workload monitoring prometheus connection")
+		address := spec.SvcAddress
+		if address == "" {
+			address = kokumetricscfgv1beta1.DefaultUserWorkloadSvcAddress
+		}
+		skipTLS := spec.SkipTLSVerification != nil && *spec.SkipTLSVerification
+		c.UWMConn = nil
+		// the user workload monitoring prometheus is reached with the TLS settings of the prometheus connection
+		cfg, err := getPrometheusConfig(&kokumetricscfgv1beta1.PrometheusSpec{
+			SvcAddress:          address,
+			SkipTLSVerification: &skipTLS,
+			TLS:                 kmCfg.Spec.PrometheusConfig.TLS,
+		}, c.InCluster)
+		if err != nil {
+			customMetricsStatusHelper(kmCfg, err)
+			return fmt.Errorf("cannot get user workload monitoring prometheus configuration: %v", err)
+		}
+		cfg.Log = c.Log
+		conn, err := getPrometheusConnFromCfg(cfg)
+		if err != nil {
+			customMetricsStatusHelper(kmCfg, err)
+			return err
+		}
+		c.UWMConn = conn
+		c.uwmSpec = spec.DeepCopy()
+		c.uwmTLS = kmCfg.Spec.PrometheusConfig.TLS.DeepCopy()
+	}
+
+	log.Info("testing the ability to query the user workload monitoring prometheus")
+	err := testPrometheusConnection(c.UWMConn)
+	customMetricsStatusHelper(kmCfg, err)
+	if err != nil {
+		c.UWMConn = nil
+		return fmt.Errorf("user workload monitoring prometheus test query failed: %v", err)
+	}
+	return nil
+}
+
+// getCustomMetricRows queries the user workload monitoring prometheus for each custom metric. Series with the same
+// namespace and pod are summed.
+func (c *PromCollector) getCustomMetricRows() (mappedCSVStruct, error) {
+	log := c.Log.WithValues("kokumetricsconfig", "getCustomMetricRows")
+	rows := mappedCSVStruct{}
+	values := map[string]float64{}
+	for _, metric := range c.CustomMetrics {
+		name := "custom-" + metric.Name
+		if err := c.checkAllowed(UserWorkloadTarget, metric.Query); err != nil {
+			c.recordQueryStat(QueryStat{Name: name, Error: err.Error()})
+			return nil, fmt.Errorf("query: %s: %v", metric.Query, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		start := time.Now()
+		queryResult, warnings, err := c.UWMConn.QueryRange(ctx, metric.Query, *c.TimeSeries)
+		cancel()
+		stat := QueryStat{Name: name, DurationSeconds: time.Since(start).Seconds(), sent: true}
+		if err != nil {
+			stat.Error = err.Error()
+			c.recordQueryStat(stat)
+			return nil, fmt.Errorf("query: %s: error querying user workload monitoring prometheus: %v", metric.Query, err)
+		}
+		if len(warnings) > 0 {
+			log.Info("query warnings", logging.QueryName, name, "Warnings", warnings)
+		}
+		matrix, ok := queryResult.(model.Matrix)
+		if !ok {
+			stat.Error = fmt.Sprintf("unexpected result type %v", queryResult.Type())
+			c.recordQueryStat(stat)
+			return nil, fmt.Errorf("expected a matrix in response to query, got a %v", queryResult.Type())
+		}
+		stat.setResult(matrix, warnings)
+		c.recordQueryStat(stat)
+
+		method := string(metric.Aggregation)
+		if method == "" {
+			method = string(kokumetricscfgv1beta1.CustomMetricSum)
+		}
+		for _, stream := range matrix {
+			namespace := string(stream.Metric["namespace"])
+			if namespace == "" {
+				continue
+			}
+			pod := string(stream.Metric["pod"])
+			key := strings.Join([]string{metric.Name, namespace, pod}, "/")
+			if _, ok := rows[key]; !ok {
+				row := newCustomMetricRow(c.dates())
+				row.Namespace = namespace
+				row.Pod = pod
+				row.MetricName = metric.Name
+				rows[key] = &row
+			}
+			value := getValue(&saveQueryValue{Method: method}, stream.Values)
+			if method == string(kokumetricscfgv1beta1.CustomMetricSum) {
+				value *= minutesPerSample(c.TimeSeries.Step)
+			}
+			values[key] += value
+		}
+	}
+	for key, value := range values {
+		rows[key].(*customMetricRow).MetricValue = floatToString(value)
+	}
+	return rows, nil
+}
+
+// queryCustomMetrics queries the custom metrics when they are configured. Errors are recorded in the result and
+// do not prevent the other reports from being written.
+func (c *PromCollector) queryCustomMetrics(result *ReportResult) mappedCSVStruct {
+	if c.UWMConn == nil || len(c.CustomMetrics) <= 0 {
+		return nil
+	}
+	log := c.Log.WithValues("kokumetricsconfig", "queryCustomMetrics")
+	log.Info("querying for custom metrics")
+	result.CustomMetricsQueried = true
+	rows, err := c.getCustomMetricRows()
+	if err != nil {
+		log.Error(err, "failed to query custom metrics")
+		result.CustomMetricsError = err
+		return nil
+	}
+	return rows
+}
@@ -0,0 +1,123 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/common/model"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestGetCustomMetricRows(t *testing.T) {
+	samples := []model.SamplePair{{Timestamp: 1604685600000, Value: 2}, {Timestamp: 1604685660000, Value: 3}}
+	requests := model.Matrix{
+		{Metric: model.Metric{"namespace": "shop", "pod": "web-1", "code": "200"}, Values: samples},
+		{Metric: model.Metric{"namespace": "shop", "pod": "web-1", "code": "500"}, Values: samples},
+		{Metric: model.Metric{"namespace": "shop", "pod": "web-2"}, Values: samples},
+		{Metric: model.Metric{"job": "no-namespace"}, Values: samples},
+	}
+	sessions := model.Matrix{
+		{Metric: model.Metric{"namespace": "shop"}, Values: samples},
+	}
+	tests := []struct {
+		name      string
+		metrics   []kokumetricscfgv1beta1.CustomMetricQuery
+		results   mappedMockPromResult
+		want      map[string]string
+		wantError bool
+	}{
+		{
+			name: "sum and max aggregation",
+			metrics: []kokumetricscfgv1beta1.CustomMetricQuery{
+				{Name: "requests", Query: "requests_query"},
+				{Name: "sessions", Query: "sessions_query", Aggregation: kokumetricscfgv1beta1.CustomMetricMax},
+			},
+			results: mappedMockPromResult{
+				"requests_query": &mockPromResult{value: requests},
+				"sessions_query": &mockPromResult{value: sessions},
+			},
+			want: map[string]string{
+				"requests/shop/web-1": "10.000000",
+				"requests/shop/web-2": "5.000000",
+				"sessions/shop/":      "3.000000",
+			},
+		},
+		{
+			name:    "query error",
+			metrics: []kokumetricscfgv1beta1.CustomMetricQuery{{Name: "requests", Query: "requests_query"}},
+			results: mappedMockPromResult{
+				"requests_query": &mockPromResult{err: errors.New("query failed")},
+			},
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			col := PromCollector{
+				UWMConn:       mockPrometheusConnection{mappedResults: &tt.results, t: t},
+				CustomMetrics: tt.metrics,
+				TimeSeries:    &fakeTimeRange,
+				Log:           testLogger,
+			}
+			got, err := col.getCustomMetricRows()
+			if err != nil && !tt.wantError {
+				t.Fatalf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Fatalf("%s expected error but got nil", tt.name)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("%s got %d rows, want %d", tt.name, len(got), len(tt.want))
+			}
+			for key, value := range tt.want {
+				row, ok := got[key]
+				if !ok {
+					t.Errorf("%s missing row %s", tt.name, key)
+					continue
+				}
+				if v := row.(*customMetricRow).MetricValue; v != value {
+					t.Errorf("%s row %s value = %s, want %s", tt.name, key, v, value)
+				}
+			}
+		})
+	}
+}
+
+func TestGetUWMConnNotConfigured(t *testing.T) {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.CustomMetrics.Error = "previous error"
+	col := PromCollector{
+		UWMConn:       mockPrometheusConnection{},
+		CustomMetrics: []kokumetricscfgv1beta1.CustomMetricQuery{{Name: "requests", Query: "requests_query"}},
+		Log:           testLogger,
+	}
+	if err := col.GetUWMConn(kmCfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if col.UWMConn != nil || col.CustomMetrics != nil {
+		t.Errorf("expected the custom metrics connection to be cleared")
+	}
+	if kmCfg.Status.CustomMetrics.Error != "" {
+		t.Errorf("expected the custom metrics status to be cleared")
+	}
+}
@@ -0,0 +1,58 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"fmt"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// newPrometheusConnection creates the connection to a single prometheus endpoint
+var newPrometheusConnection = func(cfg *PrometheusConfig) (PrometheusConnection, error) {
+	return getPrometheusConnFromCfg(cfg)
+}
+
+// canFallback returns true if the queries can be sent to the fallback address when the service address cannot be
+// queried. Sharded prometheus, fixtures, and static connections do not fall back.
+func (c *PromCollector) canFallback(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	return kmCfg.Spec.PrometheusConfig.FallbackAddress != "" && len(kmCfg.Spec.PrometheusConfig.ShardAddresses) == 0 &&
+		c.StaticConn == nil && c.Fixtures == nil && c.PromCfg != nil
+}
+
+// connectFallback connects to the fallback address with the token and CA of the service address, and sends the
+// queries to it if the test query succeeds. The service address is tried again on the next reconcile.
+func (c *PromCollector) connectFallback(address string) error {
+	cfg := *c.PromCfg
+	cfg.Address = address
+	conn, err := newPrometheusConnection(&cfg)
+	if err != nil {
+		return fmt.Errorf("fallback %s: %v", address, err)
+	}
+	if err := testPrometheusConnection(conn); err != nil {
+		return fmt.Errorf("fallback %s: prometheus test query failed: %v", address, err)
+	}
+	c.PromConn = conn
+	c.ServingAddress = address
+	c.onFallback = true
+	c.cache = queryCache{}
+	promFallbacks.Inc()
+	return nil
+}
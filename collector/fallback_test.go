@@ -0,0 +1,105 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestGetPromConnFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prom-fallback")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{tokenKey, certKey} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	tmpBase := serviceaccountPath
+	serviceaccountPath = dir
+	defer func() { serviceaccountPath = tmpBase }()
+	tmpNew := newPrometheusConnection
+	defer func() { newPrometheusConnection = tmpNew }()
+
+	const primary, fallback = "https://thanos-querier", "https://prometheus-k8s"
+	fallbackTests := []struct {
+		name        string
+		fallback    string
+		down        map[string]bool
+		wantServing string
+		wantErr     []string
+	}{
+		{name: "service address is up", fallback: fallback, wantServing: primary},
+		{name: "service address is down", fallback: fallback, down: map[string]bool{primary: true}, wantServing: fallback},
+		{name: "both addresses are down", fallback: fallback, down: map[string]bool{primary: true, fallback: true}, wantServing: primary, wantErr: []string{primary, "fallback " + fallback}},
+		{name: "no fallback address", down: map[string]bool{primary: true}, wantServing: primary, wantErr: []string{primary}},
+	}
+	for _, tt := range fallbackTests {
+		t.Run(tt.name, func(t *testing.T) {
+			newPrometheusConnection = func(cfg *PrometheusConfig) (PrometheusConnection, error) {
+				var err error
+				if tt.down[cfg.Address] {
+					err = errors.New(cfg.Address + " is unavailable")
+				}
+				return &mockPrometheusConnection{singleResult: &mockPromResult{err: err}}, nil
+			}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.PrometheusConfig.SvcAddress = primary
+			kmCfg.Spec.PrometheusConfig.FallbackAddress = tt.fallback
+			kmCfg.Spec.PrometheusConfig.SkipTLSVerification = &trueDef
+			promSpec = nil
+			col := &PromCollector{Log: testLogger, InCluster: true}
+
+			err := col.GetPromConn(kmCfg)
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("%s got error %v want error %v", tt.name, err, tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("%s got error %v want it to contain %s", tt.name, err, want)
+				}
+			}
+			if got := kmCfg.Status.Prometheus.ServingAddress; got != tt.wantServing {
+				t.Errorf("%s got serving address %s want %s", tt.name, got, tt.wantServing)
+			}
+			if tt.wantServing != fallback {
+				return
+			}
+
+			// the service address is tried again on the next reconcile
+			tt.down = nil
+			if err := col.GetPromConn(kmCfg); err != nil {
+				t.Fatalf("%s got unexpected error: %v", tt.name, err)
+			}
+			if got := kmCfg.Status.Prometheus.ServingAddress; got != primary {
+				t.Errorf("%s got serving address %s after recovery want %s", tt.name, got, primary)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// FixturesEnvVar is the environment variable with the directory of recorded prometheus responses that reports are
+// collected from instead of prometheus. The fixture mode is used to benchmark and load test report generation.
+const FixturesEnvVar = "PROMETHEUS_FIXTURES"
+
+// RecordFixturesEnvVar is the environment variable with the directory that the prometheus responses of each
+// collected window are recorded to, so that they can be replayed with the fixture mode
+const RecordFixturesEnvVar = "PROMETHEUS_RECORD_FIXTURES"
+
+// Fixtures are recorded prometheus responses keyed by query name. Fixtures are stored as a directory with a file
+// for each query, named after the query, that holds the matrix returned by prometheus.
+type Fixtures map[string]model.Matrix
+
+// reportQueries returns the queries of the node, pod, storage, and namespace reports, and of the pod workloads
+func reportQueries() []query {
+	var queries []query
+	for _, q := range []*querys{nodeQueries, podQueries, volQueries, namespaceQueries, workloadQueries} {
+		queries = append(queries, *q...)
+	}
+	return queries
+}
+
+// LoadFixtures reads the fixtures in dir. Queries without a fixture return no results when replayed.
+func LoadFixtures(dir string) (Fixtures, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("LoadFixtures: %v", err)
+	}
+	fixtures := Fixtures{}
+	for _, q := range reportQueries() {
+		data, err := ioutil.ReadFile(filepath.Join(dir, q.Name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("LoadFixtures: failed to read %s: %v", q.Name, err)
+		}
+		matrix := model.Matrix{}
+		if err := json.Unmarshal(data, &matrix); err != nil {
+			return nil, fmt.Errorf("LoadFixtures: failed to parse %s: %v", q.Name, err)
+		}
+		fixtures[q.Name] = matrix
+	}
+	return fixtures, nil
+}
+
+// Save writes the fixtures to dir
+func (f Fixtures) Save(dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("Save: failed to create fixtures directory: %v", err)
+	}
+	for name, matrix := range f {
+		data, err := json.MarshalIndent(matrix, "", "\t")
+		if err != nil {
+			return fmt.Errorf("Save: failed to marshal %s: %v", name, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return fmt.Errorf("Save: failed to write %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// fixtureConnection answers prometheus queries from fixtures
+type fixtureConnection struct {
+	results map[string]model.Matrix
+}
+
+func newFixtureConnection(fixtures Fixtures) fixtureConnection {
+	conn := fixtureConnection{results: map[string]model.Matrix{}}
+	for _, q := range reportQueries() {
+		if matrix, ok := fixtures[q.Name]; ok {
+			conn.results[q.QueryString] = matrix
+		}
+	}
+	return conn
+}
+
+// Connection returns a prometheus connection that answers the report queries from the fixtures
+func (f Fixtures) Connection() PrometheusConnection {
+	return newFixtureConnection(f)
+}
+
+func (f fixtureConnection) QueryRange(ctx context.Context, query string, r promv1.Range) (model.Value, promv1.Warnings, error) {
+	if matrix, ok := f.results[query]; ok {
+		return matrix, nil, nil
+	}
+	return model.Matrix{}, nil, nil
+}
+
+func (f fixtureConnection) Query(ctx context.Context, query string, ts time.Time) (model.Value, promv1.Warnings, error) {
+	return model.Vector{&model.Sample{Metric: model.Metric{model.MetricNameLabel: "up"}, Value: 1, Timestamp: model.TimeFromUnixNano(ts.UnixNano())}}, nil, nil
+}
+
+// RecordFixtures writes the prometheus responses of the last generated window to dir
+func (c *PromCollector) RecordFixtures(dir string) error {
+	fixtures := Fixtures{}
+	for _, q := range reportQueries() {
+		if matrix, ok := c.cache.get(addSelectors(c.queryString(q), c.ExtraSelectors), *c.TimeSeries); ok {
+			fixtures[q.Name] = matrix
+		}
+	}
+	if err := fixtures.Save(dir); err != nil {
+		return fmt.Errorf("RecordFixtures: %v", err)
+	}
+	return nil
+}
+
+// SyntheticFixtures generates the prometheus responses of a cluster with the number of nodes and pods over the
+// range. There is a namespace for every 20 pods and a persistent volume for every 10 pods.
+func SyntheticFixtures(nodes, pods int, r promv1.Range) Fixtures {
+	if nodes < 1 {
+		nodes = 1
+	}
+	namespaces := pods/20 + 1
+	volumes := pods / 10
+
+	var timestamps []model.Time
+	for ts := r.Start; !ts.After(r.End); ts = ts.Add(r.Step) {
+		timestamps = append(timestamps, model.TimeFromUnixNano(ts.UnixNano()))
+	}
+	// the labels of each kind of row, by the label that the rows are keyed on
+	entities := map[model.LabelName][]model.Metric{}
+	for i := 0; i < nodes; i++ {
+		entities["node"] = append(entities["node"], model.Metric{
+			"node":                              model.LabelValue(fmt.Sprintf("node-%d", i)),
+			"provider_id":                       model.LabelValue(fmt.Sprintf("aws:///us-east-1a/i-%08d", i)),
+			"label_node_role_kubernetes_io":     "worker",
+			"annotation_example_com_rack":       model.LabelValue(fmt.Sprintf("rack-%d", i%8)),
+			"label_topology_kubernetes_io_zone": "us-east-1a",
+		})
+	}
+	for i := 0; i < pods; i++ {
+		entities["pod"] = append(entities["pod"], model.Metric{
+			"pod":                          model.LabelValue(fmt.Sprintf("pod-%d", i)),
+			"namespace":                    model.LabelValue(fmt.Sprintf("namespace-%d", i%namespaces)),
+			"node":                         model.LabelValue(fmt.Sprintf("node-%d", i%nodes)),
+			"label_app":                    model.LabelValue(fmt.Sprintf("app-%d", i%50)),
+			"annotation_example_com_owner": model.LabelValue(fmt.Sprintf("team-%d", i%10)),
+		})
+	}
+	for i := 0; i < namespaces; i++ {
+		entities["namespace"] = append(entities["namespace"], model.Metric{
+			"namespace":                    model.LabelValue(fmt.Sprintf("namespace-%d", i)),
+			"label_team":                   model.LabelValue(fmt.Sprintf("team-%d", i%10)),
+			"annotation_example_com_owner": model.LabelValue(fmt.Sprintf("team-%d", i%10)),
+		})
+	}
+	for i := 0; i < volumes; i++ {
+		volume := model.LabelValue(fmt.Sprintf("pv-%d", i))
+		entities["volumename"] = append(entities["volumename"], model.Metric{
+			"volumename":            volume,
+			"persistentvolumeclaim": model.LabelValue(fmt.Sprintf("pvc-%d", i)),
+			"namespace":             model.LabelValue(fmt.Sprintf("namespace-%d", (i*10)%namespaces)),
+			"pod":                   model.LabelValue(fmt.Sprintf("pod-%d", i*10)),
+			"label_app":             model.LabelValue(fmt.Sprintf("app-%d", i%50)),
+		})
+		entities["persistentvolume"] = append(entities["persistentvolume"], model.Metric{
+			"persistentvolume": volume,
+			"storageclass":     "gp2",
+			"label_type":       "ebs",
+		})
+	}
+
+	fixtures := Fixtures{}
+	for _, q := range reportQueries() {
+		matrix := model.Matrix{}
+		for i, metric := range entities[q.RowKey] {
+			values := make([]model.SamplePair, len(timestamps))
+			for j, ts := range timestamps {
+				values[j] = model.SamplePair{Timestamp: ts, Value: model.SampleValue(i%10 + 1)}
+			}
+			matrix = append(matrix, &model.SampleStream{Metric: metric, Values: values})
+		}
+		fixtures[q.Name] = matrix
+	}
+	return fixtures
+}
@@ -0,0 +1,173 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+func TestFixtureReplay(t *testing.T) {
+	fixtures, err := LoadFixtures(filepath.Join("test_files", "test_data"))
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	if len(fixtures) != len(reportQueries()) {
+		t.Errorf("got %d fixtures, want one for each of the %d queries", len(fixtures), len(reportQueries()))
+	}
+
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	c := &PromCollector{Fixtures: fixtures, TimeSeries: &fakeTimeRange, Log: testLogger}
+	if err := c.GetPromConn(kmCfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !kmCfg.Status.Prometheus.PrometheusConfigured || !kmCfg.Status.Prometheus.PrometheusConnected {
+		t.Errorf("expected prometheus to be reported as configured and connected")
+	}
+
+	dirCfg := &dirconfig.DirectoryConfig{
+		Parent:  dirconfig.Directory{Path: "."},
+		Reports: dirconfig.Directory{Path: filepath.Join("test_files", "fixture_reports")},
+	}
+	defer os.RemoveAll(dirCfg.Reports.Path)
+	if err := GenerateReports(kmCfg, dirCfg, c); err != nil {
+		t.Fatalf("failed to generate reports: %v", err)
+	}
+
+	expectedMap := getFiles("expected_reports", t)
+	generatedMap := getFiles("fixture_reports", t)
+	if len(expectedMap) != len(generatedMap) {
+		t.Errorf("incorrect number of reports generated")
+	}
+	for expected, expectedinfo := range expectedMap {
+		generatedinfo, ok := generatedMap[expected]
+		if !ok {
+			t.Errorf("%s report file was not generated", expected)
+			continue
+		}
+		if err := compareFiles(expectedinfo, generatedinfo); err != nil {
+			t.Errorf("%s files do not compare: error: %v", expected, err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := c.RecordFixtures(dir); err != nil {
+		t.Fatalf("failed to record fixtures: %v", err)
+	}
+	recorded, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatalf("failed to load recorded fixtures: %v", err)
+	}
+	// the annotation queries are only run when annotation prefixes are configured
+	if len(recorded) != len(fixtures)-3 {
+		t.Errorf("recorded %d fixtures, want %d", len(recorded), len(fixtures)-3)
+	}
+	for name, matrix := range recorded {
+		if len(fixtures[name]) != len(matrix) {
+			t.Errorf("%s: recorded %d series, want %d", name, len(matrix), len(fixtures[name]))
+		}
+	}
+}
+
+func TestLoadFixturesMissingDir(t *testing.T) {
+	if _, err := LoadFixtures(filepath.Join("test_files", "nonexistent")); err == nil {
+		t.Errorf("expected an error for a missing fixtures directory")
+	}
+}
+
+func TestSyntheticFixtures(t *testing.T) {
+	fixtures := SyntheticFixtures(5, 100, fakeTimeRange)
+	c := &PromCollector{PromConn: newFixtureConnection(fixtures), TimeSeries: &fakeTimeRange, Log: testLogger}
+	dir, err := ioutil.TempDir("", "synthetic")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dirCfg := &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: dir}, Reports: dirconfig.Directory{Path: dir}}
+	if err := GenerateReports(&kokumetricscfgv1beta1.KokuMetricsConfig{}, dirCfg, c); err != nil {
+		t.Fatalf("failed to generate reports: %v", err)
+	}
+
+	want := map[string]int{
+		nodeFilePrefix:      5,
+		podFilePrefix:       100,
+		volFilePrefix:       10,
+		namespaceFilePrefix: 6,
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read reports: %v", err)
+	}
+	for _, file := range files {
+		for prefix, rows := range want {
+			if !strings.HasPrefix(file.Name(), prefix) {
+				continue
+			}
+			data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", file.Name(), err)
+			}
+			// the header is not a row
+			if got := strings.Count(string(data), "\n") - 1; got != rows {
+				t.Errorf("%s: got %d rows, want %d", file.Name(), got, rows)
+			}
+			delete(want, prefix)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("reports were not generated: %v", want)
+	}
+}
+
+// BenchmarkGenerateReports generates the reports of a cluster with 500 nodes and 10,000 pods. Run it with
+// `make bench` to compare the time and memory of report generation between changes.
+func BenchmarkGenerateReports(b *testing.B) {
+	fixtures := SyntheticFixtures(500, 10000, fakeTimeRange)
+	dir, err := ioutil.TempDir("", "benchmark")
+	if err != nil {
+		b.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dirCfg := &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: dir}, Reports: dirconfig.Directory{Path: dir}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		if err := dirCfg.Reports.RemoveContents(); err != nil {
+			b.Fatalf("failed to clear reports: %v", err)
+		}
+		c := &PromCollector{PromConn: newFixtureConnection(fixtures), TimeSeries: &fakeTimeRange, Log: testLogger}
+		b.StartTimer()
+		if err := GenerateReports(&kokumetricscfgv1beta1.KokuMetricsConfig{}, dirCfg, c); err != nil {
+			b.Fatalf("failed to generate reports: %v", err)
+		}
+	}
+}
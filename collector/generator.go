@@ -0,0 +1,243 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// GeneratorQuery is a prometheus query of a ReportGenerator
+type GeneratorQuery struct {
+	// Name identifies the query in logs and query stats
+	Name string
+	// Query is the PromQL query, which is queried over the hour of the report
+	Query string
+	// Shares is the name of a query of the node, pod, storage, or namespace reports that is used instead of Query.
+	// The result of the report query, with its query override, is shared, so it is only sent to prometheus once.
+	Shares string
+}
+
+// ReportGenerator generates a report that is written alongside the node, pod, storage, and namespace reports. New
+// report types, such as GPU, virtual machine, or network usage, are added by registering a ReportGenerator from an
+// init function, so that each report type keeps its queries, columns, and rows, and their tests, in its own file.
+type ReportGenerator interface {
+	// Name identifies the report. The report is written to cm-openshift-<name>-usage-<YYYYMM>.csv.
+	Name() string
+	// Queries returns the queries of the report
+	Queries() []GeneratorQuery
+	// Columns returns the columns of the report. The report period and interval columns are added before them.
+	Columns() []string
+	// Rows returns the rows of the report, with a value for each column, from the result of each query by name
+	Rows(results map[string]model.Matrix) ([][]string, error)
+}
+
+// WorkloadReport is implemented by a ReportGenerator whose rows name workloads, such as pods, deployments, or
+// autoscalers. The report is not written when the reports are aggregated to namespaces, so that no workload names
+// are reported.
+type WorkloadReport interface {
+	NamesWorkloads() bool
+}
+
+// namesWorkloads returns true if the rows of the report name workloads
+func namesWorkloads(g ReportGenerator) bool {
+	w, ok := g.(WorkloadReport)
+	return ok && w.NamesWorkloads()
+}
+
+// namespaceColumn returns the index of the namespace column of a generated report, or -1 if the rows of the report
+// do not belong to a namespace. The rows of a namespaced report are partitioned by tenant.
+func namespaceColumn(columns []string) int {
+	for i, column := range columns {
+		if column == "namespace" {
+			return i
+		}
+	}
+	return -1
+}
+
+// validGeneratorName matches report names that are safe to use in file names
+var validGeneratorName = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
+var (
+	generatorsMu sync.RWMutex
+	generators   = map[string]ReportGenerator{}
+)
+
+// RegisterReportGenerator makes a report available to the collector. It panics if the generator is nil, the name is
+// not lower case letters, digits, and dashes, or a generator with the same name, or a built in report, is registered.
+func RegisterReportGenerator(g ReportGenerator) {
+	generatorsMu.Lock()
+	defer generatorsMu.Unlock()
+	if g == nil {
+		panic("collector: RegisterReportGenerator generator is nil")
+	}
+	name := g.Name()
+	if !validGeneratorName.MatchString(name) {
+		panic("collector: RegisterReportGenerator invalid name " + name)
+	}
+	if _, dup := generators[name]; dup || builtinReport(name) {
+		panic("collector: RegisterReportGenerator called twice for report " + name)
+	}
+	generators[name] = g
+}
+
+// ReportGenerators returns the registered report generators, sorted by name
+func ReportGenerators() []ReportGenerator {
+	generatorsMu.RLock()
+	defer generatorsMu.RUnlock()
+	var registered []ReportGenerator
+	for _, g := range generators {
+		registered = append(registered, g)
+	}
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name() < registered[j].Name() })
+	return registered
+}
+
+// builtinReport returns true if the name is used by a report the collector writes itself
+func builtinReport(name string) bool {
+	for _, prefix := range []string{nodeFilePrefix, podFilePrefix, volFilePrefix, namespaceFilePrefix, customFilePrefix} {
+		if prefix == generatorFilePrefix(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func generatorFilePrefix(name string) string {
+	return "cm-openshift-" + name + "-usage-"
+}
+
+// generatedRow is a row of a report from a ReportGenerator
+type generatedRow struct {
+	*dateTimes
+	columns []string
+	values  []string
+}
+
+func (row generatedRow) csvHeader() []string { return withPeriodColumns(row.columns...) }
+
+func (row generatedRow) csvRow() []string {
+	return append([]string{
+		row.ReportPeriodStart,
+		row.ReportPeriodEnd,
+		row.IntervalStart,
+		row.IntervalEnd,
+	}, row.values...)
+}
+
+func (row generatedRow) string() string { return strings.Join(row.csvRow(), ",") }
+
+// generatedReport is the rows of a report from a ReportGenerator
+type generatedReport struct {
+	generator ReportGenerator
+	rows      mappedCSVStruct
+	// duration is how long the queries of the report took
+	duration time.Duration
+}
+
+// reportGenerators returns the generators used by the collector
+func (c *PromCollector) reportGenerators() []ReportGenerator {
+	if c.Generators != nil {
+		return c.Generators
+	}
+	return ReportGenerators()
+}
+
+// generateReports queries prometheus for each report generator and returns the rows of each report. A generator
+// whose queries fail or whose rows are invalid is logged and skipped, so that it does not prevent the other reports
+// from being written.
+func (c *PromCollector) generateReports() []generatedReport {
+	log := c.Log.WithValues("kokumetricsconfig", "generateReports")
+	var reports []generatedReport
+	for _, g := range c.reportGenerators() {
+		start := time.Now()
+		rows, err := c.generateReport(g)
+		if err != nil {
+			log.Error(err, "failed to generate report", "report", g.Name())
+			continue
+		}
+		reports = append(reports, generatedReport{generator: g, rows: rows, duration: time.Since(start)})
+	}
+	return reports
+}
+
+// generatorQueryString returns the PromQL of the generator query: the query of the report query it shares, or its
+// own query
+func (c *PromCollector) generatorQueryString(q GeneratorQuery) string {
+	if q.Shares == "" {
+		return q.Query
+	}
+	for _, rq := range reportQueries() {
+		if rq.Name == q.Shares {
+			return c.queryString(rq)
+		}
+	}
+	return q.Query
+}
+
+func (c *PromCollector) generateReport(g ReportGenerator) (mappedCSVStruct, error) {
+	results := map[string]model.Matrix{}
+	for _, q := range g.Queries() {
+		matrix, err := c.queryRange(g.Name()+"/"+q.Name, c.generatorQueryString(q))
+		if err != nil {
+			return nil, err
+		}
+		results[q.Name] = matrix
+	}
+	values, err := g.Rows(results)
+	if err != nil {
+		return nil, fmt.Errorf("generateReport: %v", err)
+	}
+	columns := g.Columns()
+	rows := mappedCSVStruct{}
+	for _, v := range values {
+		if len(v) != len(columns) {
+			return nil, fmt.Errorf("generateReport: row has %d values, want %d", len(v), len(columns))
+		}
+		row := &generatedRow{dateTimes: c.dates(), columns: columns, values: v}
+		rows[row.string()] = row
+	}
+	return rows, nil
+}
+
+// generatedReportFiles returns the report file of each generated report, in the dir of the reports directory
+func (c *PromCollector) generatedReportFiles(reports []generatedReport, dir, yearMonth string) []ReportFile {
+	dates := c.dates()
+	files := make([]ReportFile, 0, len(reports))
+	for _, r := range reports {
+		name := r.generator.Name()
+		files = append(files, ReportFile{
+			Report:   name,
+			Name:     filepath.Join(dir, generatorFilePrefix(name)+yearMonth+".csv"),
+			Headers:  withPeriodColumns(r.generator.Columns()...),
+			Interval: dates.string(),
+			Rows:     sortedRows(r.rows),
+		})
+	}
+	return files
+}
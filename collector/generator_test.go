@@ -0,0 +1,167 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/model"
+)
+
+type fakeGenerator struct {
+	name string
+	err  error
+}
+
+func (g fakeGenerator) Name() string { return g.name }
+
+func (g fakeGenerator) Queries() []GeneratorQuery {
+	return []GeneratorQuery{{Name: "gpu-memory", Query: "gpu_memory_bytes"}}
+}
+
+func (g fakeGenerator) Columns() []string { return []string{"node", "gpu_memory_bytes"} }
+
+func (g fakeGenerator) Rows(results map[string]model.Matrix) ([][]string, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	var rows [][]string
+	for _, stream := range results["gpu-memory"] {
+		rows = append(rows, []string{string(stream.Metric["node"]), floatToString(maxSlice(stream.Values))})
+	}
+	return rows, nil
+}
+
+func TestRegisterReportGenerator(t *testing.T) {
+	RegisterReportGenerator(fakeGenerator{name: "test-gpu"})
+	defer func() {
+		generatorsMu.Lock()
+		delete(generators, "test-gpu")
+		generatorsMu.Unlock()
+	}()
+	var names []string
+	for _, g := range ReportGenerators() {
+		names = append(names, g.Name())
+	}
+	if want := []string{"autoscaler", "node-capacity-change", "test-gpu"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReportGenerators() = %v, want %v", names, want)
+	}
+
+	registerTests := []struct {
+		name      string
+		generator ReportGenerator
+	}{
+		{name: "nil generator", generator: nil},
+		{name: "invalid name", generator: fakeGenerator{name: "GPU usage"}},
+		{name: "built in report", generator: fakeGenerator{name: "pod"}},
+		{name: "duplicate", generator: fakeGenerator{name: "test-gpu"}},
+	}
+	for _, tt := range registerTests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s got no panic want panic", tt.name)
+				}
+			}()
+			RegisterReportGenerator(tt.generator)
+		})
+	}
+}
+
+func TestGenerateReportsGenerators(t *testing.T) {
+	mapResults := make(mappedMockPromResult)
+	queryList := []*querys{nodeQueries, namespaceQueries, podQueries, workloadQueries, volQueries}
+	for _, q := range queryList {
+		for _, query := range *q {
+			res := &model.Matrix{}
+			Load(filepath.Join("test_files", "test_data", query.Name), res, t)
+			mapResults[query.QueryString] = &mockPromResult{value: *res}
+		}
+	}
+	mapResults["gpu_memory_bytes"] = &mockPromResult{value: model.Matrix{
+		{
+			Metric: model.Metric{"node": "node-a"},
+			Values: []model.SamplePair{{Value: 1024}, {Value: 2048}},
+		},
+	}}
+
+	fakeCollector := &PromCollector{
+		PromConn: mockPrometheusConnection{
+			mappedResults: &mapResults,
+			t:             t,
+		},
+		TimeSeries: &fakeTimeRange,
+		Log:        testLogger,
+		Generators: []ReportGenerator{
+			fakeGenerator{name: "gpu"},
+			fakeGenerator{name: "broken", err: errors.New("no gpus")},
+		},
+	}
+	defer func() {
+		if err := fakeDirCfg.Reports.RemoveContents(); err != nil {
+			t.Fatal("failed to cleanup reports directory")
+		}
+	}()
+	if err := GenerateReports(fakeKMCfg, fakeDirCfg, fakeCollector); err != nil {
+		t.Fatalf("Failed to generate reports: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(fakeDirCfg.Reports.Path, "cm-openshift-gpu-usage-202011.csv"))
+	if err != nil {
+		t.Fatalf("failed to read generated report: %v", err)
+	}
+	want := "report_period_start,report_period_end,interval_start,interval_end,node,gpu_memory_bytes\n" +
+		"2020-11-01 00:00:00 +0000 UTC,2020-12-01 00:00:00 +0000 UTC,2020-11-06 18:00:00 +0000 UTC,2020-11-06 18:59:59 +0000 UTC,node-a,2048.000000\n"
+	if string(data) != want {
+		t.Errorf("generated report got %q want %q", data, want)
+	}
+
+	files, err := fakeDirCfg.Reports.GetFiles()
+	if err != nil {
+		t.Fatalf("failed to list reports: %v", err)
+	}
+	for _, f := range files {
+		if strings.Contains(f, "broken") {
+			t.Errorf("report %s was written for a generator that failed", f)
+		}
+	}
+	var generated bool
+	for _, stat := range fakeCollector.QueryStats {
+		if stat.Name == "gpu/gpu-memory" {
+			generated = true
+		}
+	}
+	if !generated {
+		t.Errorf("query stats got %v want a gpu/gpu-memory stat", fakeCollector.QueryStats)
+	}
+	for _, stat := range fakeKMCfg.Status.Reports.ReportStats {
+		if stat.Report == "broken" {
+			t.Errorf("report stats got a stat for a generator that failed")
+		}
+		if stat.Report == "gpu" && (stat.Rows != 1 || stat.Bytes <= 0) {
+			t.Errorf("gpu report stat got %d rows and %d bytes want 1 row and more than 0 bytes", stat.Rows, stat.Bytes)
+		}
+	}
+}
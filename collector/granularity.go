@@ -0,0 +1,126 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import "strconv"
+
+// aggregateNamespaces aggregates the pod, storage, and custom metric rows of a report set to each namespace, so
+// that no pod, persistent volume claim, or persistent volume names or labels are reported. Pod rows are aggregated
+// for each namespace and node so that node cost can still be distributed, storage rows for each namespace and storage
+// class, and custom metric rows for each namespace and metric. Usage, request, limit, and capacity values are summed.
+// Node and namespace rows are not changed. Generated reports that name workloads are left out.
+func aggregateNamespaces(all reportSet) reportSet {
+	agg := reportSet{node: all.node, pod: mappedCSVStruct{}, storage: mappedCSVStruct{}, namespace: all.namespace}
+	for _, r := range all.generated {
+		if !namesWorkloads(r.generator) {
+			agg.generated = append(agg.generated, r)
+		}
+	}
+
+	for _, row := range all.pod {
+		r := row.(*podRow)
+		key := r.Namespace + "/" + r.Node
+		existing, ok := agg.pod[key]
+		if !ok {
+			agg.pod[key] = &podRow{
+				dateTimes:                   r.dateTimes,
+				nodeRow:                     r.nodeRow,
+				Namespace:                   r.Namespace,
+				PodUsageCPUCoreSeconds:      r.PodUsageCPUCoreSeconds,
+				PodRequestCPUCoreSeconds:    r.PodRequestCPUCoreSeconds,
+				PodLimitCPUCoreSeconds:      r.PodLimitCPUCoreSeconds,
+				PodUsageMemoryByteSeconds:   r.PodUsageMemoryByteSeconds,
+				PodRequestMemoryByteSeconds: r.PodRequestMemoryByteSeconds,
+				PodLimitMemoryByteSeconds:   r.PodLimitMemoryByteSeconds,
+			}
+			continue
+		}
+		e := existing.(*podRow)
+		e.PodUsageCPUCoreSeconds = sumValues(e.PodUsageCPUCoreSeconds, r.PodUsageCPUCoreSeconds)
+		e.PodRequestCPUCoreSeconds = sumValues(e.PodRequestCPUCoreSeconds, r.PodRequestCPUCoreSeconds)
+		e.PodLimitCPUCoreSeconds = sumValues(e.PodLimitCPUCoreSeconds, r.PodLimitCPUCoreSeconds)
+		e.PodUsageMemoryByteSeconds = sumValues(e.PodUsageMemoryByteSeconds, r.PodUsageMemoryByteSeconds)
+		e.PodRequestMemoryByteSeconds = sumValues(e.PodRequestMemoryByteSeconds, r.PodRequestMemoryByteSeconds)
+		e.PodLimitMemoryByteSeconds = sumValues(e.PodLimitMemoryByteSeconds, r.PodLimitMemoryByteSeconds)
+	}
+
+	for _, row := range all.storage {
+		r := row.(*storageRow)
+		key := r.Namespace + "/" + r.StorageClass
+		existing, ok := agg.storage[key]
+		if !ok {
+			agg.storage[key] = &storageRow{
+				dateTimes:                                r.dateTimes,
+				Namespace:                                r.Namespace,
+				StorageClass:                             r.StorageClass,
+				PersistentVolumeClaimCapacityBytes:       r.PersistentVolumeClaimCapacityBytes,
+				PersistentVolumeClaimCapacityByteSeconds: r.PersistentVolumeClaimCapacityByteSeconds,
+				VolumeRequestStorageByteSeconds:          r.VolumeRequestStorageByteSeconds,
+				PersistentVolumeClaimUsageByteSeconds:    r.PersistentVolumeClaimUsageByteSeconds,
+			}
+			continue
+		}
+		e := existing.(*storageRow)
+		e.PersistentVolumeClaimCapacityBytes = sumValues(e.PersistentVolumeClaimCapacityBytes, r.PersistentVolumeClaimCapacityBytes)
+		e.PersistentVolumeClaimCapacityByteSeconds = sumValues(e.PersistentVolumeClaimCapacityByteSeconds, r.PersistentVolumeClaimCapacityByteSeconds)
+		e.VolumeRequestStorageByteSeconds = sumValues(e.VolumeRequestStorageByteSeconds, r.VolumeRequestStorageByteSeconds)
+		e.PersistentVolumeClaimUsageByteSeconds = sumValues(e.PersistentVolumeClaimUsageByteSeconds, r.PersistentVolumeClaimUsageByteSeconds)
+	}
+
+	if all.custom != nil {
+		agg.custom = mappedCSVStruct{}
+		for _, row := range all.custom {
+			r := row.(*customMetricRow)
+			key := r.Namespace + "/" + r.MetricName
+			existing, ok := agg.custom[key]
+			if !ok {
+				agg.custom[key] = &customMetricRow{
+					dateTimes:   r.dateTimes,
+					Namespace:   r.Namespace,
+					MetricName:  r.MetricName,
+					MetricValue: r.MetricValue,
+				}
+				continue
+			}
+			e := existing.(*customMetricRow)
+			e.MetricValue = sumValues(e.MetricValue, r.MetricValue)
+		}
+	}
+	return agg
+}
+
+// sumValues returns the sum of two numeric report values. Empty values are left out of the sum.
+func sumValues(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	x, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return b
+	}
+	y, err := strconv.ParseFloat(b, 64)
+	if err != nil {
+		return a
+	}
+	return floatToString(x + y)
+}
@@ -0,0 +1,120 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregateNamespaces(t *testing.T) {
+	dates := &dateTimes{IntervalStart: "2021-01-01 00:00:00 +0000 UTC"}
+	node := nodeRow{Node: "node1", NodeCapacityCPUCores: "4.000000"}
+	all := reportSet{
+		node: mappedCSVStruct{"node1": &node},
+		pod: mappedCSVStruct{
+			"pod-a": &podRow{dateTimes: dates, nodeRow: node, Namespace: "ns", Pod: "pod-a", PodUsageCPUCoreSeconds: "1.500000", PodRequestCPUCoreSeconds: "", PodLabels: "label_app:a"},
+			"pod-b": &podRow{dateTimes: dates, nodeRow: node, Namespace: "ns", Pod: "pod-b", PodUsageCPUCoreSeconds: "2.000000", PodRequestCPUCoreSeconds: "3.000000", PodLabels: "label_app:b"},
+			"pod-c": &podRow{dateTimes: dates, nodeRow: node, Namespace: "other", Pod: "pod-c", PodUsageCPUCoreSeconds: "4.000000"},
+		},
+		storage: mappedCSVStruct{
+			"pvc-a": &storageRow{dateTimes: dates, Namespace: "ns", Pod: "pod-a", PersistentVolumeClaim: "pvc-a", PersistentVolume: "pv-a", StorageClass: "gp2", PersistentVolumeClaimCapacityBytes: "10.000000"},
+			"pvc-b": &storageRow{dateTimes: dates, Namespace: "ns", Pod: "pod-b", PersistentVolumeClaim: "pvc-b", PersistentVolume: "pv-b", StorageClass: "gp2", PersistentVolumeClaimCapacityBytes: "5.000000"},
+		},
+		namespace: mappedCSVStruct{"ns": &namespaceRow{Namespace: "ns"}},
+		custom: mappedCSVStruct{
+			"pod-a/gpu": &customMetricRow{dateTimes: dates, Namespace: "ns", Pod: "pod-a", MetricName: "gpu", MetricValue: "1.000000"},
+			"pod-b/gpu": &customMetricRow{dateTimes: dates, Namespace: "ns", Pod: "pod-b", MetricName: "gpu", MetricValue: "2.000000"},
+		},
+	}
+
+	got := aggregateNamespaces(all)
+
+	wantPod := mappedCSVStruct{
+		"ns/node1":    &podRow{dateTimes: dates, nodeRow: node, Namespace: "ns", PodUsageCPUCoreSeconds: "3.500000", PodRequestCPUCoreSeconds: "3.000000"},
+		"other/node1": &podRow{dateTimes: dates, nodeRow: node, Namespace: "other", PodUsageCPUCoreSeconds: "4.000000"},
+	}
+	wantStorage := mappedCSVStruct{
+		"ns/gp2": &storageRow{dateTimes: dates, Namespace: "ns", StorageClass: "gp2", PersistentVolumeClaimCapacityBytes: "15.000000"},
+	}
+	wantCustom := mappedCSVStruct{
+		"ns/gpu": &customMetricRow{dateTimes: dates, Namespace: "ns", MetricName: "gpu", MetricValue: "3.000000"},
+	}
+	aggregationTests := []struct {
+		name string
+		got  mappedCSVStruct
+		want mappedCSVStruct
+	}{
+		{name: "node rows are unchanged", got: got.node, want: all.node},
+		{name: "pod rows are aggregated by namespace and node", got: got.pod, want: wantPod},
+		{name: "storage rows are aggregated by namespace and storage class", got: got.storage, want: wantStorage},
+		{name: "namespace rows are unchanged", got: got.namespace, want: all.namespace},
+		{name: "custom rows are aggregated by namespace and metric", got: got.custom, want: wantCustom},
+	}
+	for _, tt := range aggregationTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("%s got %v want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+
+	t.Run("no custom metrics", func(t *testing.T) {
+		all.custom = nil
+		if got := aggregateNamespaces(all); got.custom != nil {
+			t.Errorf("custom rows got %v want nil", got.custom)
+		}
+	})
+}
+
+func TestAggregateNamespacesGeneratedReports(t *testing.T) {
+	all := reportSet{
+		generated: []generatedReport{
+			{generator: autoscalerReport{}, rows: mappedCSVStruct{}},
+			{generator: capacityChangeReport{}, rows: mappedCSVStruct{}},
+		},
+	}
+	got := aggregateNamespaces(all)
+	var names []string
+	for _, r := range got.generated {
+		names = append(names, r.generator.Name())
+	}
+	// the autoscaler report names autoscalers and the workloads they scale
+	if want := []string{"node-capacity-change"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got generated reports %v want %v", names, want)
+	}
+}
+
+func TestSumValues(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{a: "", b: "", want: ""},
+		{a: "1.000000", b: "", want: "1.000000"},
+		{a: "", b: "2.000000", want: "2.000000"},
+		{a: "1.500000", b: "2.000000", want: "3.500000"},
+	}
+	for _, tt := range tests {
+		if got := sumValues(tt.a, tt.b); got != tt.want {
+			t.Errorf("sumValues(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
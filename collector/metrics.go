@@ -0,0 +1,104 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	reportRows = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_report_rows",
+			Help: "Number of rows written to each report for the last hour queried.",
+		},
+		[]string{"report"},
+	)
+
+	emptyReportsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "koku_metrics_empty_reports_total",
+			Help: "Number of times a report that is expected to contain data was generated with zero rows.",
+		},
+		[]string{"report"},
+	)
+
+	promClientRebuilds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "koku_metrics_prometheus_client_rebuilds_total",
+			Help: "Number of times the prometheus client was built, by reason: initial, config, service_ca, error, or fallback.",
+		},
+		[]string{"reason"},
+	)
+
+	promFallbacks = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "koku_metrics_prometheus_fallbacks_total",
+			Help: "Number of times the queries were sent to the fallback address because the service address could not be queried.",
+		},
+	)
+
+	queryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "koku_metrics_prometheus_query_duration_seconds",
+			Help:    "Duration of the queries sent to prometheus, by query.",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+		},
+		[]string{"query"},
+	)
+
+	querySamples = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_prometheus_query_samples",
+			Help: "Number of samples returned by each query for the last window queried.",
+		},
+		[]string{"query"},
+	)
+
+	sanitizedValues = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "koku_metrics_sanitized_label_values_total",
+			Help: "Number of label and annotation values changed before they were written to the reports, by change: truncated, control_characters, or escaped.",
+		},
+		[]string{"change"},
+	)
+
+	inconsistentPods = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_inconsistent_pod_rows",
+			Help: "Number of pod rows for the last hour queried that reference a namespace or node missing from the namespace or node report, by reference.",
+		},
+		[]string{"reference"},
+	)
+
+	queryWarnings = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "koku_metrics_prometheus_query_warnings_total",
+			Help: "Number of warnings prometheus returned with each query.",
+		},
+		[]string{"query"},
+	)
+)
+
+func init() {
+	// register the collector metrics with the controller-runtime registry so they are served on the metrics endpoint
+	metrics.Registry.MustRegister(reportRows, emptyReportsTotal, promClientRebuilds, promFallbacks, queryDuration, querySamples, queryWarnings, sanitizedValues, inconsistentPods)
+}
@@ -21,11 +21,14 @@ package collector
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -36,6 +39,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/logging"
 )
 
 var (
@@ -48,14 +53,108 @@ var (
 )
 
 type PromCollector struct {
-	PromConn   prometheusConnection
+	PromConn   PrometheusConnection
 	PromCfg    *PrometheusConfig
 	TimeSeries *promv1.Range
 	Log        logr.Logger
 	InCluster  bool
+	QueryStats []QueryStat
+
+	// Lightweight reduces the query set for the lightweight profile
+	Lightweight bool
+
+	// MaxParallelQueries is the number of queries of a report that are sent to prometheus at a time. When not
+	// positive, the queries are sent one at a time.
+	MaxParallelQueries int
+
+	// TenantLabel is the namespace label used to partition reports by tenant
+	TenantLabel string
+
+	// AnnotationPrefixes are the prefixes of the node, pod, and namespace annotations that are collected
+	AnnotationPrefixes []string
+
+	// ExtraSelectors are the label matchers added to every query, to scope the queries of a multi-cluster Thanos
+	ExtraSelectors []string
+
+	// QueryOverrides are the PromQL that replaces the default queries, by query name
+	QueryOverrides map[string]string
+
+	// MaxLabelValueLength is the length, in bytes, that label and annotation values are truncated to. When not
+	// positive, the default length is used.
+	MaxLabelValueLength int
+
+	// ExpectedPodLabels are the pod labels that are expected to be found on at least one pod
+	ExpectedPodLabels []string
+
+	// PeriodStartDay is the day of the month that billing periods start on
+	PeriodStartDay int
+
+	// NamespaceGranularity aggregates the pod, storage, and custom metric rows to each namespace
+	NamespaceGranularity bool
+
+	// Index records the windows that reports were generated for. When set, reports are only generated for a
+	// window that is already in the index if Overwrite is true.
+	Index     *WindowIndex
+	Overwrite bool
+
+	// UWMConn is the connection to the user workload monitoring prometheus used for custom metrics
+	UWMConn PrometheusConnection
+	// CustomMetrics are the queries for the custom usage report
+	CustomMetrics []kokumetricscfgv1beta1.CustomMetricQuery
+	uwmSpec       *kokumetricscfgv1beta1.CustomMetricsSpec
+	uwmTLS        *kokumetricscfgv1beta1.TLSSpec
+
+	// Generators are the additional report generators. When nil, the registered report generators are used.
+	Generators []ReportGenerator
+
+	// NamespaceUsage is the per-namespace usage from the last report generation
+	NamespaceUsage *HourlyUsage
+
+	// Fixtures are recorded prometheus responses that reports are collected from instead of prometheus
+	Fixtures Fixtures
+	// StaticConn, when set, is queried instead of connecting to prometheus and the user workload monitoring
+	// prometheus. It lets tests and downstream builds generate reports from a fake connection.
+	StaticConn PrometheusConnection
+
+	// ServingAddress is the address of the prometheus that the queries are sent to: the service address, or the
+	// fallback address when the service address cannot be queried
+	ServingAddress string
+	// onFallback is true while the queries are sent to the fallback address
+	onFallback bool
+
+	// cache holds the query results of the current window
+	cache queryCache
+	// caChecksum is the checksum of the service CA that the prometheus connection was built with
+	caChecksum string
+	// allowed are the queries the collector may run. When nil, every query may be run.
+	allowed map[PlannedQuery]bool
+	// sanitizer cleans the label and annotation values of the window
+	sanitizer *labelSanitizer
+}
+
+// QueryStat records the outcome of a single prometheus query from the last report generation
+type QueryStat struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Series          int     `json:"series"`
+	// Samples is the number of samples of the series returned
+	Samples  int      `json:"samples"`
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	// Cached is true if the result was reused from an earlier query of the same window
+	Cached bool `json:"cached,omitempty"`
+
+	// sent is true if the query was sent to prometheus, and not refused by the query allow-list
+	sent bool
+}
+
+// dates returns the report period and interval of the current time series
+func (c *PromCollector) dates() *dateTimes {
+	return newDates(c.TimeSeries, c.PeriodStartDay)
 }
 
-type prometheusConnection interface {
+// PrometheusConnection is the part of the prometheus API that reports are collected with
+type PrometheusConnection interface {
 	QueryRange(ctx context.Context, query string, r promv1.Range) (model.Value, promv1.Warnings, error)
 	Query(ctx context.Context, query string, ts time.Time) (model.Value, promv1.Warnings, error)
 }
@@ -70,6 +169,10 @@ type PrometheusConfig struct {
 	CAFile string
 	// SkipTLS skips cert verification
 	SkipTLS bool
+	// TLS is the minimum TLS version and the cipher suites
+	TLS crhchttp.TLSSettings
+	// Log receives the negotiated TLS version of the connection. When nil, it is not logged.
+	Log logr.Logger
 }
 
 func getBearerToken(tokenFile string) (config.Secret, error) {
@@ -80,38 +183,62 @@ func getBearerToken(tokenFile string) (config.Secret, error) {
 	return config.Secret(encodedSecret), nil
 }
 
-func getPrometheusConfig(kmCfg *kokumetricscfgv1beta1.PrometheusSpec, inCluster bool) (*PrometheusConfig, error) {
+// ServiceAccountToken returns the service account token that is used to query prometheus
+func ServiceAccountToken(inCluster bool) (string, error) {
 	if !inCluster {
 		val, ok := os.LookupEnv("SECRET_ABSPATH")
 		if ok {
 			serviceaccountPath = val
 		}
 	}
-	promCfg := &PrometheusConfig{
-		Address: kmCfg.SvcAddress,
-		CAFile:  filepath.Join(serviceaccountPath, certKey),
-		SkipTLS: *kmCfg.SkipTLSVerification,
-	}
+	token, err := getBearerToken(filepath.Join(serviceaccountPath, tokenKey))
+	return string(token), err
+}
 
-	tokenFile := filepath.Join(serviceaccountPath, tokenKey)
-	token, err := getBearerToken(tokenFile)
+func getPrometheusConfig(kmCfg *kokumetricscfgv1beta1.PrometheusSpec, inCluster bool) (*PrometheusConfig, error) {
+	token, err := ServiceAccountToken(inCluster)
 	if err != nil {
 		return nil, err
 	}
-	promCfg.BearerToken = token
+	tlsSettings, err := crhchttp.ParseTLSSettings(string(kmCfg.TLS.MinVersion), kmCfg.TLS.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	promCfg := &PrometheusConfig{
+		Address:     kmCfg.SvcAddress,
+		BearerToken: config.Secret(token),
+		CAFile:      filepath.Join(serviceaccountPath, certKey),
+		SkipTLS:     *kmCfg.SkipTLSVerification,
+		TLS:         tlsSettings,
+	}
 
 	return promCfg, nil
 }
 
-func getPrometheusConnFromCfg(cfg *PrometheusConfig) (promv1.API, error) {
-	promconf := config.HTTPClientConfig{
-		BearerToken: cfg.BearerToken,
-		TLSConfig:   config.TLSConfig{CAFile: cfg.CAFile, InsecureSkipVerify: cfg.SkipTLS},
+// fileChecksum returns the hex sha256 of the contents of the file, or "" if the file cannot be read
+func fileChecksum(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
 	}
-	roundTripper, err := config.NewRoundTripperFromConfig(promconf, "promconf", false, false)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewPrometheusConnection returns a connection to the prometheus described by cfg. It lets tools other than the
+// operator query prometheus the way the operator does.
+func NewPrometheusConnection(cfg *PrometheusConfig) (PrometheusConnection, error) {
+	return getPrometheusConnFromCfg(cfg)
+}
+
+func getPrometheusConnFromCfg(cfg *PrometheusConfig) (promv1.API, error) {
+	roundTripper, err := newPrometheusRoundTripper(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create roundTripper: %v", err)
 	}
+	if cfg.Log != nil {
+		roundTripper = &tlsLoggingRoundTripper{next: roundTripper, log: cfg.Log}
+	}
 	client, err := promapi.NewClient(promapi.Config{
 		Address:      cfg.Address,
 		RoundTripper: roundTripper,
@@ -143,7 +270,7 @@ func statusHelper(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, status string,
 	}
 }
 
-func testPrometheusConnection(promConn prometheusConnection) error {
+func testPrometheusConnection(promConn PrometheusConnection) error {
 	return wait.Poll(1*time.Second, 15*time.Second, func() (bool, error) {
 		_, _, err := promConn.Query(context.TODO(), "up", time.Now())
 		if err != nil {
@@ -153,37 +280,102 @@ func testPrometheusConnection(promConn prometheusConnection) error {
 	})
 }
 
+// rebuildReason returns why the prometheus connection must be rebuilt, or "" if it can be reused
+func (c *PromCollector) rebuildReason(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, updated, caChanged bool) string {
+	switch {
+	case c.PromConn == nil:
+		return "initial"
+	case updated:
+		return "config"
+	case caChanged:
+		return "service_ca"
+	case kmCfg.Status.Prometheus.ConnectionError != "":
+		return "error"
+	case c.onFallback:
+		return "fallback"
+	}
+	return ""
+}
+
 // GetPromConn returns the prometheus connection
 func (c *PromCollector) GetPromConn(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) error {
 	log := c.Log.WithValues("kokumetricsconfig", "GetPromConn")
 	var err error
 
+	if err := validateSelectors(kmCfg.Spec.PrometheusConfig.ExtraSelectors); err != nil {
+		statusHelper(kmCfg, "configuration", err)
+		return fmt.Errorf("cannot get prometheus configuration: %v", err)
+	}
+
+	if c.Fixtures != nil {
+		if _, ok := c.PromConn.(fixtureConnection); !ok {
+			log.Info("collecting reports from prometheus fixtures")
+			c.PromConn = newFixtureConnection(c.Fixtures)
+			c.cache = queryCache{}
+		}
+		statusHelper(kmCfg, "configuration", nil)
+		statusHelper(kmCfg, "connection", nil)
+		return nil
+	}
+
 	updated := true
 	if promSpec != nil {
 		updated = !reflect.DeepEqual(*promSpec, kmCfg.Spec.PrometheusConfig)
 	}
 	promSpec = kmCfg.Spec.PrometheusConfig.DeepCopy()
 
-	if updated || c.PromCfg == nil || kmCfg.Status.Prometheus.ConfigError != "" {
+	// the client is reused across reconciles, and only rebuilt when the configuration or the service CA changes, or
+	// the previous connection failed
+	caChanged := c.PromCfg != nil && c.caChecksum != fileChecksum(c.PromCfg.CAFile)
+	if caChanged {
+		log.Info("the prometheus service CA changed")
+	}
+
+	if c.StaticConn != nil {
+		c.PromConn = c.StaticConn
+		statusHelper(kmCfg, "configuration", nil)
+	} else if updated || caChanged || c.PromCfg == nil || kmCfg.Status.Prometheus.ConfigError != "" {
 		log.Info("getting prometheus configuration")
 		c.PromCfg, err = getPrometheusConfig(&kmCfg.Spec.PrometheusConfig, c.InCluster)
 		statusHelper(kmCfg, "configuration", err)
 		if err != nil {
 			return fmt.Errorf("cannot get prometheus configuration: %v", err)
 		}
+		c.PromCfg.Log = c.Log
 	}
 
-	if updated || c.PromConn == nil || kmCfg.Status.Prometheus.ConnectionError != "" {
-		log.Info("getting prometheus connection")
-		c.PromConn, err = getPrometheusConnFromCfg(c.PromCfg)
+	if reason := c.rebuildReason(kmCfg, updated, caChanged); c.StaticConn == nil && reason != "" {
+		log.Info("getting prometheus connection", "reason", reason)
+		if shards := kmCfg.Spec.PrometheusConfig.ShardAddresses; len(shards) > 0 {
+			log.Info("querying a sharded prometheus", "shards", shards)
+			c.PromConn, err = newShardedConnection(c.PromCfg, shards)
+		} else {
+			c.PromConn, err = newPrometheusConnection(c.PromCfg)
+		}
+		c.ServingAddress = c.PromCfg.Address
+		c.onFallback = false
+		c.cache = queryCache{}
 		statusHelper(kmCfg, "configuration", err)
 		if err != nil {
 			return err
 		}
+		c.caChecksum = fileChecksum(c.PromCfg.CAFile)
+		promClientRebuilds.WithLabelValues(reason).Inc()
 	}
 
 	log.Info("testing the ability to query prometheus")
 	err = testPrometheusConnection(c.PromConn)
+	if err != nil && c.canFallback(kmCfg) {
+		log.Info("prometheus test query failed, trying the fallback address", "error", err.Error(),
+			"fallback", kmCfg.Spec.PrometheusConfig.FallbackAddress)
+		if fallbackErr := c.connectFallback(kmCfg.Spec.PrometheusConfig.FallbackAddress); fallbackErr != nil {
+			err = fmt.Errorf("%v; %v", err, fallbackErr)
+		} else {
+			log.Info("querying the fallback address", "fallback", c.ServingAddress)
+			err = nil
+		}
+	}
+	kmCfg.Status.Prometheus.ServingAddress = c.ServingAddress
 	statusHelper(kmCfg, "connection", err)
 	if err != nil {
 		return fmt.Errorf("prometheus test query failed: %v", err)
@@ -193,25 +385,143 @@ func (c *PromCollector) GetPromConn(kmCfg *kokumetricscfgv1beta1.KokuMetricsConf
 	return nil
 }
 
-func (c *PromCollector) getQueryResults(queries *querys, results *mappedResults) error {
-	log := c.Log.WithValues("kokumetricsconfig", "getQueryResults")
-	for _, query := range *queries {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+// rangeQuery is a named query of a batch of range queries
+type rangeQuery struct {
+	name  string
+	query string
+}
 
-		queryResult, warnings, err := c.PromConn.QueryRange(ctx, query.QueryString, *c.TimeSeries)
-		if err != nil {
-			return fmt.Errorf("query: %s: error querying prometheus: %v", query.QueryString, err)
+// rangeResult is the outcome of a single range query
+type rangeResult struct {
+	// query is the query with the extra selectors that was sent, and the key of the cached result
+	query  string
+	matrix model.Matrix
+	stat   QueryStat
+	err    error
+}
+
+// parallelQueries returns the number of queries that are sent to prometheus at a time
+func (c *PromCollector) parallelQueries() int {
+	if c.MaxParallelQueries <= 0 {
+		return 1
+	}
+	return c.MaxParallelQueries
+}
+
+// queryRange queries prometheus over the time series of the collector. Results are cached for the window, and
+// the outcome of each query is recorded in the query stats.
+func (c *PromCollector) queryRange(name, queryString string) (model.Matrix, error) {
+	matrices, err := c.queryRanges([]rangeQuery{{name: name, query: queryString}})
+	if err != nil {
+		return nil, err
+	}
+	return matrices[0], nil
+}
+
+// queryRanges runs the queries in batches of up to MaxParallelQueries queries that are sent to prometheus at
+// the same time. The results and query stats are recorded in the order of the queries, and no batch is sent
+// after a batch with a failed query, so that the queries fail the same way as when they are sent one at a time.
+func (c *PromCollector) queryRanges(queries []rangeQuery) ([]model.Matrix, error) {
+	matrices := make([]model.Matrix, 0, len(queries))
+	batch := c.parallelQueries()
+	for start := 0; start < len(queries); start += batch {
+		end := start + batch
+		if end > len(queries) {
+			end = len(queries)
 		}
-		if len(warnings) > 0 {
-			log.Info("query warnings", "Warnings", warnings)
+		results := make([]rangeResult, end-start)
+		var wg sync.WaitGroup
+		for i, q := range queries[start:end] {
+			q.query = addSelectors(q.query, c.ExtraSelectors)
+			if err := c.checkAllowed(PrometheusTarget, q.query); err != nil {
+				results[i] = rangeResult{
+					query: q.query,
+					stat:  QueryStat{Name: q.name, Error: err.Error()},
+					err:   fmt.Errorf("query: %s: %v", q.query, err),
+				}
+				continue
+			}
+			if matrix, ok := c.cache.get(q.query, *c.TimeSeries); ok {
+				c.Log.Info("using cached query result", logging.QueryName, q.name)
+				stat := QueryStat{Name: q.name, Cached: true}
+				stat.setResult(matrix, nil)
+				results[i] = rangeResult{query: q.query, matrix: matrix, stat: stat}
+				continue
+			}
+			wg.Add(1)
+			go func(i int, q rangeQuery) {
+				defer wg.Done()
+				results[i] = c.sendRangeQuery(q)
+			}(i, q)
 		}
-		matrix, ok := queryResult.(model.Matrix)
-		if !ok {
-			return fmt.Errorf("expected a matrix in response to query, got a %v", queryResult.Type())
+		wg.Wait()
+
+		for _, result := range results {
+			c.recordQueryStat(result.stat)
+			if result.err != nil {
+				return nil, result.err
+			}
+			if !result.stat.Cached {
+				c.cache.put(result.query, *c.TimeSeries, result.matrix)
+			}
+			matrices = append(matrices, result.matrix)
 		}
+	}
+	return matrices, nil
+}
+
+// sendRangeQuery sends a single query to prometheus. It does not change the collector, so that the queries of a
+// batch can be sent at the same time.
+func (c *PromCollector) sendRangeQuery(q rangeQuery) rangeResult {
+	log := c.Log.WithValues("kokumetricsconfig", "queryRange")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	queryResult, warnings, err := c.PromConn.QueryRange(ctx, q.query, *c.TimeSeries)
+	stat := QueryStat{Name: q.name, DurationSeconds: time.Since(start).Seconds(), sent: true}
+	if err != nil {
+		stat.Error = err.Error()
+		log.Error(err, "error querying prometheus", logging.QueryName, q.name)
+		return rangeResult{query: q.query, stat: stat, err: fmt.Errorf("query: %s: error querying prometheus: %v", q.query, err)}
+	}
+	if len(warnings) > 0 {
+		log.Info("query warnings", logging.QueryName, q.name, "Warnings", warnings)
+	}
+	matrix, ok := queryResult.(model.Matrix)
+	if !ok {
+		stat.Error = fmt.Sprintf("unexpected result type %v", queryResult.Type())
+		return rangeResult{query: q.query, stat: stat, err: fmt.Errorf("expected a matrix in response to query, got a %v", queryResult.Type())}
+	}
+	stat.setResult(matrix, warnings)
+	return rangeResult{query: q.query, matrix: matrix, stat: stat}
+}
 
-		results.iterateMatrix(matrix, query)
+func (c *PromCollector) getQueryResults(queries *querys, results *mappedResults) error {
+	if c.sanitizer == nil {
+		c.sanitizer = newLabelSanitizer(c.MaxLabelValueLength)
+	}
+	var run querys
+	var ranges []rangeQuery
+	for _, query := range *queries {
+		if c.Lightweight && query.SkipInLightweight {
+			continue
+		}
+		if query.AnnotationKey != "" {
+			if len(c.AnnotationPrefixes) <= 0 {
+				continue
+			}
+			query.MetricKeyRegex = regexFields{query.AnnotationKey: annotationRegex(c.AnnotationPrefixes)}
+		}
+		run = append(run, query)
+		ranges = append(ranges, rangeQuery{name: query.Name, query: c.queryString(query)})
+	}
+	matrices, err := c.queryRanges(ranges)
+	if err != nil {
+		return err
+	}
+	for i, query := range run {
+		results.iterateMatrix(matrices[i], query, c.TimeSeries.Step, c.sanitizer)
 	}
 	return nil
 }
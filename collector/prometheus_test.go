@@ -21,9 +21,12 @@ package collector
 
 import (
 	"context"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -196,6 +199,260 @@ func TestGetQueryResultsSuccess(t *testing.T) {
 	}
 }
 
+func TestGetQueryResultsLightweight(t *testing.T) {
+	queries := &querys{
+		query{
+			Name:        "usage-cpu-cores",
+			QueryString: "query1",
+			MetricKey:   staticFields{"id": "id"},
+			RowKey:      "id",
+		},
+		query{
+			Name:              "labels",
+			QueryString:       "query2",
+			MetricKeyRegex:    regexFields{"labels": "label_*"},
+			RowKey:            "id",
+			SkipInLightweight: true,
+		},
+	}
+	// query2 is not in the mocked results, so the test fails if it is run
+	queriesResult := mappedMockPromResult{
+		"query1": &mockPromResult{
+			value: model.Matrix{
+				{
+					Metric: model.Metric{"id": "1"},
+					Values: []model.SamplePair{{Timestamp: 1604339340, Value: 2}},
+				},
+			},
+		},
+	}
+	col := PromCollector{
+		PromConn: mockPrometheusConnection{
+			mappedResults: &queriesResult,
+			t:             t,
+		},
+		TimeSeries:  &promv1.Range{},
+		Log:         testLogger,
+		Lightweight: true,
+	}
+	got := mappedResults{}
+	if err := col.getQueryResults(queries, &got); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if len(col.QueryStats) != 1 || col.QueryStats[0].Name != "usage-cpu-cores" {
+		t.Errorf("expected only usage-cpu-cores to be queried, got %+v", col.QueryStats)
+	}
+	if _, ok := got["1"]["labels"]; ok {
+		t.Errorf("labels should not be collected with the lightweight profile, got %v", got)
+	}
+}
+
+// concurrentPrometheusConnection records the queries it is sent, and the most queries it was sent at a time
+type concurrentPrometheusConnection struct {
+	mockPrometheusConnection
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+	sent     []string
+}
+
+func (m *concurrentPrometheusConnection) QueryRange(ctx context.Context, query string, r promv1.Range) (model.Value, promv1.Warnings, error) {
+	m.mu.Lock()
+	m.inFlight++
+	if m.inFlight > m.maxSeen {
+		m.maxSeen = m.inFlight
+	}
+	m.sent = append(m.sent, query)
+	m.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	m.mu.Lock()
+	m.inFlight--
+	m.mu.Unlock()
+	return m.mockPrometheusConnection.QueryRange(ctx, query, r)
+}
+
+func TestGetQueryResultsParallel(t *testing.T) {
+	queries := &querys{}
+	queriesResult := mappedMockPromResult{}
+	for i := 1; i <= 5; i++ {
+		id := strconv.Itoa(i)
+		*queries = append(*queries, query{
+			Name:        "query" + id,
+			QueryString: "query" + id,
+			MetricKey:   staticFields{"id": "id"},
+			RowKey:      "id",
+		})
+		queriesResult["query"+id] = &mockPromResult{
+			value: model.Matrix{
+				{
+					Metric: model.Metric{"id": model.LabelValue(id)},
+					Values: []model.SamplePair{{Timestamp: 1604339340, Value: 1}},
+				},
+			},
+		}
+	}
+	failing := mappedMockPromResult{}
+	for k, v := range queriesResult {
+		failing[k] = v
+	}
+	failing["query3"] = &mockPromResult{err: errTest}
+
+	parallelTests := []struct {
+		name      string
+		parallel  int
+		results   mappedMockPromResult
+		wantMax   int
+		wantStats []string
+		wantErr   bool
+	}{
+		{
+			name:      "one query at a time",
+			parallel:  0,
+			results:   queriesResult,
+			wantMax:   1,
+			wantStats: []string{"query1", "query2", "query3", "query4", "query5"},
+		},
+		{
+			name:      "two queries at a time",
+			parallel:  2,
+			results:   queriesResult,
+			wantMax:   2,
+			wantStats: []string{"query1", "query2", "query3", "query4", "query5"},
+		},
+		{
+			name:      "more queries at a time than queries",
+			parallel:  8,
+			results:   queriesResult,
+			wantMax:   5,
+			wantStats: []string{"query1", "query2", "query3", "query4", "query5"},
+		},
+		{
+			name:      "no batch is sent after a failed batch",
+			parallel:  2,
+			results:   failing,
+			wantMax:   2,
+			wantStats: []string{"query1", "query2", "query3"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range parallelTests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &concurrentPrometheusConnection{
+				mockPrometheusConnection: mockPrometheusConnection{mappedResults: &tt.results, t: t},
+			}
+			col := PromCollector{
+				PromConn:           conn,
+				TimeSeries:         &promv1.Range{},
+				Log:                testLogger,
+				MaxParallelQueries: tt.parallel,
+			}
+			got := mappedResults{}
+			err := col.getQueryResults(queries, &got)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			if conn.maxSeen > tt.wantMax {
+				t.Errorf("%s got %d queries at a time want at most %d", tt.name, conn.maxSeen, tt.wantMax)
+			}
+			var stats []string
+			for _, stat := range col.QueryStats {
+				stats = append(stats, stat.Name)
+			}
+			if !reflect.DeepEqual(stats, tt.wantStats) {
+				t.Errorf("%s got query stats %v want %v", tt.name, stats, tt.wantStats)
+			}
+			if tt.wantErr {
+				for _, query := range conn.sent {
+					if query == "query5" {
+						t.Errorf("%s got query5 sent after the batch of the failed query", tt.name)
+					}
+				}
+				return
+			}
+			if len(got) != 5 {
+				t.Errorf("%s got %d rows want 5: %v", tt.name, len(got), got)
+			}
+		})
+	}
+}
+
+type countingPrometheusConnection struct {
+	mockPrometheusConnection
+	calls map[string]int
+}
+
+func (m *countingPrometheusConnection) QueryRange(ctx context.Context, query string, r promv1.Range) (model.Value, promv1.Warnings, error) {
+	m.calls[query]++
+	return m.mockPrometheusConnection.QueryRange(ctx, query, r)
+}
+
+func TestGetQueryResultsCached(t *testing.T) {
+	nodeQueries := &querys{
+		query{
+			Name:        "node-labels",
+			QueryString: "query1",
+			MetricKey:   staticFields{"node": "node"},
+			RowKey:      "node",
+		},
+	}
+	podQueries := &querys{
+		query{
+			Name:           "pod-labels",
+			QueryString:    "query1",
+			MetricKeyRegex: regexFields{"labels": "label_*"},
+			RowKey:         "pod",
+		},
+	}
+	queriesResult := mappedMockPromResult{
+		"query1": &mockPromResult{
+			value: model.Matrix{
+				{
+					Metric: model.Metric{"node": "node1", "pod": "pod1", "label_app": "test"},
+					Values: []model.SamplePair{{Timestamp: 1604339340, Value: 1}},
+				},
+			},
+		},
+	}
+	conn := &countingPrometheusConnection{
+		mockPrometheusConnection: mockPrometheusConnection{mappedResults: &queriesResult, t: t},
+		calls:                    map[string]int{},
+	}
+	window := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	col := PromCollector{
+		PromConn:   conn,
+		TimeSeries: &promv1.Range{Start: window, End: window.Add(59 * time.Minute), Step: time.Minute},
+		Log:        testLogger,
+	}
+
+	nodes := mappedResults{}
+	if err := col.getQueryResults(nodeQueries, &nodes); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	pods := mappedResults{}
+	if err := col.getQueryResults(podQueries, &pods); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if conn.calls["query1"] != 1 {
+		t.Errorf("expected query1 to be sent once in the same window, got %d", conn.calls["query1"])
+	}
+	if len(col.QueryStats) != 2 || col.QueryStats[0].Cached || !col.QueryStats[1].Cached {
+		t.Errorf("expected the second query to be cached, got %+v", col.QueryStats)
+	}
+	if pods["pod1"]["labels"] != "label_app:test" || nodes["node1"]["node"] != "node1" {
+		t.Errorf("cached results were not mapped: nodes %v, pods %v", nodes, pods)
+	}
+
+	col.TimeSeries = &promv1.Range{Start: window.Add(time.Hour), End: window.Add(119 * time.Minute), Step: time.Minute}
+	if err := col.getQueryResults(podQueries, &mappedResults{}); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if conn.calls["query1"] != 2 {
+		t.Errorf("expected query1 to be sent again for a new window, got %d", conn.calls["query1"])
+	}
+}
+
 func TestGetQueryResultsError(t *testing.T) {
 	col := PromCollector{
 		TimeSeries: &promv1.Range{},
@@ -262,6 +519,7 @@ func TestGetQueryResultsError(t *testing.T) {
 				singleResult: tt.queryResult,
 				t:            t,
 			}
+			col.cache = queryCache{}
 			got := mappedResults{}
 			err := col.getQueryResults(&querys{query{QueryString: "fake-query"}}, &got)
 			if tt.wantedError != nil && err == nil {
@@ -421,7 +679,7 @@ func TestGetPromConn(t *testing.T) {
 		cfg          *PrometheusConfig
 		createTokCrt bool
 		cfgErr       string
-		con          prometheusConnection
+		con          PrometheusConnection
 		conErr       string
 		wantedError  error
 	}{
@@ -515,6 +773,92 @@ func TestGetPromConn(t *testing.T) {
 	}
 }
 
+func TestRebuildReason(t *testing.T) {
+	rebuildTests := []struct {
+		name      string
+		conn      PrometheusConnection
+		updated   bool
+		caChanged bool
+		connErr   string
+		want      string
+	}{
+		{name: "no connection", conn: nil, want: "initial"},
+		{name: "spec updated", conn: &mockPrometheusConnection{}, updated: true, want: "config"},
+		{name: "service CA changed", conn: &mockPrometheusConnection{}, caChanged: true, want: "service_ca"},
+		{name: "connection error", conn: &mockPrometheusConnection{}, connErr: "error", want: "error"},
+		{name: "connection is reused", conn: &mockPrometheusConnection{}, want: ""},
+	}
+	for _, tt := range rebuildTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Status.Prometheus.ConnectionError = tt.connErr
+			col := &PromCollector{PromConn: tt.conn}
+			if got := col.rebuildReason(kmCfg, tt.updated, tt.caChanged); got != tt.want {
+				t.Errorf("%s got %q want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPromConnReuse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prom-ca")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, certKey)
+	if err := ioutil.WriteFile(caFile, []byte("ca-1"), 0644); err != nil {
+		t.Fatalf("failed to write CA: %v", err)
+	}
+
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Spec.PrometheusConfig.SkipTLSVerification = &trueDef
+	conn := &mockPrometheusConnection{singleResult: &mockPromResult{err: nil}}
+	col := &PromCollector{
+		PromConn:   conn,
+		PromCfg:    &PrometheusConfig{Address: "%gh&%ij", CAFile: caFile},
+		Log:        testLogger,
+		caChecksum: fileChecksum(caFile),
+	}
+	promSpec = kmCfg.Spec.PrometheusConfig.DeepCopy()
+	if err := col.GetPromConn(kmCfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if col.PromConn != conn {
+		t.Errorf("the prometheus connection was rebuilt without a change")
+	}
+
+	// a new service CA rebuilds the configuration, which fails without a service account token
+	if err := ioutil.WriteFile(caFile, []byte("ca-2"), 0644); err != nil {
+		t.Fatalf("failed to write CA: %v", err)
+	}
+	tmpBase := serviceaccountPath
+	serviceaccountPath = dir
+	defer func() { serviceaccountPath = tmpBase }()
+	if err := col.GetPromConn(kmCfg); err == nil {
+		t.Errorf("expected the configuration to be rebuilt after the service CA changed")
+	}
+}
+
+func TestFileChecksum(t *testing.T) {
+	if got := fileChecksum(filepath.Join("test_files", "does-not-exist")); got != "" {
+		t.Errorf("fileChecksum of a missing file got %q want empty", got)
+	}
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	dir, err := ioutil.TempDir("", "checksum")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if got := fileChecksum(path); got != want {
+		t.Errorf("fileChecksum got %q want %q", got, want)
+	}
+}
+
 func TestGetPrometheusConfig(t *testing.T) {
 	cwd, err := os.Getwd()
 	if err != nil {
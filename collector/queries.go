@@ -77,10 +77,17 @@ var (
 			RowKey: "node",
 		},
 		query{
-			Name:           "node-labels",
-			QueryString:    "kube_node_labels",
-			MetricKeyRegex: regexFields{"node_labels": "label_*"},
-			RowKey:         "node",
+			Name:              "node-labels",
+			QueryString:       "kube_node_labels",
+			MetricKeyRegex:    regexFields{"node_labels": "label_*"},
+			RowKey:            "node",
+			SkipInLightweight: true,
+		},
+		query{
+			Name:          "node-annotations",
+			QueryString:   "kube_node_annotations",
+			AnnotationKey: "node_annotations",
+			RowKey:        "node",
 		},
 	}
 	volQueries = &querys{
@@ -212,10 +219,31 @@ var (
 			RowKey: "pod",
 		},
 		query{
-			Name:           "pod-labels",
-			QueryString:    "kube_pod_labels",
-			MetricKeyRegex: regexFields{"pod_labels": "label_*"},
-			RowKey:         "pod",
+			Name:              "pod-labels",
+			QueryString:       "kube_pod_labels",
+			MetricKeyRegex:    regexFields{"pod_labels": "label_*"},
+			RowKey:            "pod",
+			SkipInLightweight: true,
+		},
+		query{
+			Name:              "pod-qos-class",
+			QueryString:       "max(kube_pod_status_qos_class > 0) by (pod, namespace, qos_class)",
+			MetricKey:         staticFields{"qos_class": "qos_class"},
+			RowKey:            "pod",
+			SkipInLightweight: true,
+		},
+		query{
+			Name:              "pod-priority-class",
+			QueryString:       "max(kube_pod_info{priority_class!=''}) by (pod, namespace, priority_class)",
+			MetricKey:         staticFields{"priority_class": "priority_class"},
+			RowKey:            "pod",
+			SkipInLightweight: true,
+		},
+		query{
+			Name:          "pod-annotations",
+			QueryString:   "kube_pod_annotations",
+			AnnotationKey: "pod_annotations",
+			RowKey:        "pod",
 		},
 	}
 	namespaceQueries = &querys{
@@ -226,6 +254,41 @@ var (
 			MetricKeyRegex: regexFields{"namespace_labels": "label_*"},
 			RowKey:         "namespace",
 		},
+		query{
+			Name:          "namespace-annotations",
+			QueryString:   "kube_namespace_annotations",
+			MetricKey:     staticFields{"namespace": "namespace"},
+			AnnotationKey: "namespace_annotations",
+			RowKey:        "namespace",
+		},
+	}
+	// workloadQueries are the controller owners of pods, and of the replica sets, replication controllers, and jobs
+	// that own pods. The RowKey of each query is the label with the name of the owned object.
+	workloadQueries = &querys{
+		query{
+			Name:              "pod-owner",
+			QueryString:       "kube_pod_owner{owner_is_controller='true'}",
+			RowKey:            "pod",
+			SkipInLightweight: true,
+		},
+		query{
+			Name:              "replicaset-owner",
+			QueryString:       "kube_replicaset_owner{owner_is_controller='true'}",
+			RowKey:            "replicaset",
+			SkipInLightweight: true,
+		},
+		query{
+			Name:              "replicationcontroller-owner",
+			QueryString:       "kube_replicationcontroller_owner{owner_is_controller='true'}",
+			RowKey:            "replicationcontroller",
+			SkipInLightweight: true,
+		},
+		query{
+			Name:              "job-owner",
+			QueryString:       "kube_job_owner{owner_is_controller='true'}",
+			RowKey:            "job_name",
+			SkipInLightweight: true,
+		},
 	}
 )
 
@@ -238,6 +301,11 @@ type query struct {
 	MetricKeyRegex regexFields
 	QueryValue     *saveQueryValue
 	RowKey         model.LabelName
+	// SkipInLightweight marks queries that are not run with the lightweight profile
+	SkipInLightweight bool
+	// AnnotationKey is the field that annotations matching the configured prefixes are saved to. Queries with an
+	// AnnotationKey are only run when annotation prefixes are configured.
+	AnnotationKey string
 }
 
 type staticFields map[string]model.LabelName
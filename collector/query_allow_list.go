@@ -0,0 +1,98 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The prometheus instances that planned queries are sent to
+const (
+	PrometheusTarget   = "prometheus"
+	UserWorkloadTarget = "user-workload-monitoring"
+)
+
+// ErrQueryNotAllowed is returned for a query that is not in the allow-list of the collector
+var ErrQueryNotAllowed = errors.New("the query is not in the query allow-list")
+
+// PlannedQuery is a query that the collector runs for each window, exactly as it is sent to prometheus
+type PlannedQuery struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	Query  string `json:"query"`
+}
+
+// QueryPlan returns the queries that the collector runs for each window with its current configuration, in the
+// order they are run. The extra selectors are applied, so the queries are exactly those sent to prometheus.
+func (c *PromCollector) QueryPlan() []PlannedQuery {
+	var plan []PlannedQuery
+	for _, queries := range []*querys{nodeQueries, podQueries, volQueries, namespaceQueries, workloadQueries} {
+		for _, q := range *queries {
+			if c.Lightweight && q.SkipInLightweight {
+				continue
+			}
+			if q.AnnotationKey != "" && len(c.AnnotationPrefixes) <= 0 {
+				continue
+			}
+			plan = append(plan, PlannedQuery{Name: q.Name, Target: PrometheusTarget, Query: addSelectors(c.queryString(q), c.ExtraSelectors)})
+		}
+	}
+	for _, g := range c.reportGenerators() {
+		for _, q := range g.Queries() {
+			plan = append(plan, PlannedQuery{Name: g.Name() + "/" + q.Name, Target: PrometheusTarget, Query: addSelectors(c.generatorQueryString(q), c.ExtraSelectors)})
+		}
+	}
+	for _, metric := range c.CustomMetrics {
+		plan = append(plan, PlannedQuery{Name: "custom-" + metric.Name, Target: UserWorkloadTarget, Query: metric.Query})
+	}
+	return plan
+}
+
+// QueryPlanHash returns the hex encoded sha256 checksum of the query plan, which changes whenever a query is added,
+// removed, or changed
+func QueryPlanHash(plan []PlannedQuery) (string, error) {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return "", fmt.Errorf("QueryPlanHash: failed to marshal query plan: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AllowQueries restricts the collector to the queries of the plan. Every other query is refused with
+// ErrQueryNotAllowed, and an empty plan refuses every query.
+func (c *PromCollector) AllowQueries(plan []PlannedQuery) {
+	c.allowed = map[PlannedQuery]bool{}
+	for _, q := range plan {
+		c.allowed[PlannedQuery{Target: q.Target, Query: q.Query}] = true
+	}
+}
+
+// checkAllowed returns ErrQueryNotAllowed if the collector is restricted to an allow-list that the query is not in
+func (c *PromCollector) checkAllowed(target, query string) error {
+	if c.allowed == nil || c.allowed[PlannedQuery{Target: target, Query: query}] {
+		return nil
+	}
+	return ErrQueryNotAllowed
+}
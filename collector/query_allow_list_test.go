@@ -0,0 +1,112 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestQueryPlan(t *testing.T) {
+	all := len(*nodeQueries) + len(*podQueries) + len(*volQueries) + len(*namespaceQueries) + len(*workloadQueries)
+	annotations, lightweight := 0, 0
+	for _, queries := range []*querys{nodeQueries, podQueries, volQueries, namespaceQueries, workloadQueries} {
+		for _, q := range *queries {
+			if q.AnnotationKey != "" {
+				annotations++
+			} else if q.SkipInLightweight {
+				lightweight++
+			}
+		}
+	}
+	custom := []kokumetricscfgv1beta1.CustomMetricQuery{{Name: "gpu", Query: "sum(gpu_utilization) by (namespace, pod)"}}
+	tests := []struct {
+		name string
+		c    *PromCollector
+		want int
+	}{
+		{name: "default", c: &PromCollector{}, want: all - annotations},
+		{name: "lightweight", c: &PromCollector{Lightweight: true}, want: all - annotations - lightweight},
+		{name: "annotations", c: &PromCollector{AnnotationPrefixes: []string{"example.com/"}}, want: all},
+		{name: "custom metrics", c: &PromCollector{CustomMetrics: custom}, want: all - annotations + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.c.Generators = []ReportGenerator{}
+			if got := tt.c.QueryPlan(); len(got) != tt.want {
+				t.Errorf("%s got %d queries want %d", tt.name, len(got), tt.want)
+			}
+		})
+	}
+
+	c := &PromCollector{Generators: []ReportGenerator{}, ExtraSelectors: []string{`cluster="prod"`}, CustomMetrics: custom}
+	plan := c.QueryPlan()
+	for _, q := range plan {
+		if q.Target == PrometheusTarget && !strings.Contains(q.Query, `cluster="prod"`) {
+			t.Errorf("got query %s want the extra selectors applied", q.Query)
+		}
+	}
+	if last := plan[len(plan)-1]; last.Target != UserWorkloadTarget || last.Query != custom[0].Query {
+		t.Errorf("got last query %+v want the custom metric", last)
+	}
+
+	hash, err := QueryPlanHash(plan)
+	if err != nil {
+		t.Fatalf("failed to hash query plan: %v", err)
+	}
+	c.ExtraSelectors = []string{`cluster="test"`}
+	if changed, _ := QueryPlanHash(c.QueryPlan()); changed == hash {
+		t.Error("got the same hash for a changed query plan")
+	}
+}
+
+func TestAllowQueries(t *testing.T) {
+	matrix := model.Matrix{}
+	c := &PromCollector{
+		TimeSeries: &promv1.Range{},
+		Log:        testLogger,
+		PromConn:   mockPrometheusConnection{singleResult: &mockPromResult{value: matrix}, t: t},
+		Generators: []ReportGenerator{},
+	}
+	if _, err := c.queryRange("up", "up"); err != nil {
+		t.Errorf("got error %v want every query allowed without an allow-list", err)
+	}
+
+	c.AllowQueries(c.QueryPlan())
+	if _, err := c.queryRange("node-labels", "kube_node_labels"); err != nil {
+		t.Errorf("got error %v for a planned query", err)
+	}
+	if _, err := c.queryRange("up", "up"); err == nil || !strings.Contains(err.Error(), ErrQueryNotAllowed.Error()) {
+		t.Errorf("got error %v want %v", err, ErrQueryNotAllowed)
+	}
+	if stat := c.QueryStats[len(c.QueryStats)-1]; stat.Error == "" {
+		t.Errorf("got query stat %+v want the refusal recorded", stat)
+	}
+
+	c.AllowQueries(nil)
+	if _, err := c.queryRange("node-labels", "kube_node_labels"); err == nil {
+		t.Error("got no error with an empty allow-list")
+	}
+}
@@ -0,0 +1,52 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// queryCache holds the results of the range queries of a single window, so that a query that several reports
+// share, such as the node capacity queries of the node and node capacity change reports, is only sent to prometheus
+// once. The cache is emptied each time reports are generated, so that a window that is collected again is queried
+// again, and when a different window is queried.
+type queryCache struct {
+	window  promv1.Range
+	results map[string]model.Matrix
+}
+
+// get returns the cached result of the query over the range
+func (q *queryCache) get(query string, r promv1.Range) (model.Matrix, bool) {
+	if q.window != r {
+		return nil, false
+	}
+	matrix, ok := q.results[query]
+	return matrix, ok
+}
+
+// put caches the result of the query over the range. Results of other windows are dropped.
+func (q *queryCache) put(query string, r promv1.Range, matrix model.Matrix) {
+	if q.window != r || q.results == nil {
+		q.window = r
+		q.results = map[string]model.Matrix{}
+	}
+	q.results[query] = matrix
+}
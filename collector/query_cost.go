@@ -0,0 +1,99 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"sort"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// slowestQueries is the number of queries that the query cost status lists
+const slowestQueries = 5
+
+// setResult records the size of the result of the query, and the warnings prometheus returned with it
+func (s *QueryStat) setResult(matrix model.Matrix, warnings promv1.Warnings) {
+	s.Series = len(matrix)
+	s.Samples = 0
+	for _, series := range matrix {
+		s.Samples += len(series.Values)
+	}
+	s.Warnings = warnings
+}
+
+// recordQueryStat adds the stat to the query stats of the window. The cost of the queries that were sent to
+// prometheus is published as metrics.
+func (c *PromCollector) recordQueryStat(stat QueryStat) {
+	c.QueryStats = append(c.QueryStats, stat)
+	if !stat.sent {
+		return
+	}
+	queryDuration.WithLabelValues(stat.Name).Observe(stat.DurationSeconds)
+	if stat.Error == "" {
+		querySamples.WithLabelValues(stat.Name).Set(float64(stat.Samples))
+	}
+	if len(stat.Warnings) > 0 {
+		queryWarnings.WithLabelValues(stat.Name).Add(float64(len(stat.Warnings)))
+	}
+}
+
+// queryCost sums the cost of the queries of a window, and lists the queries that took the longest
+func queryCost(stats []QueryStat) kokumetricscfgv1beta1.QueryCostStatus {
+	cost := kokumetricscfgv1beta1.QueryCostStatus{}
+	var sent []QueryStat
+	var duration float64
+	for _, stat := range stats {
+		if stat.Cached {
+			cost.CachedQueries++
+			continue
+		}
+		if !stat.sent {
+			continue
+		}
+		sent = append(sent, stat)
+		cost.Queries++
+		cost.Samples += int64(stat.Samples)
+		cost.Warnings += int64(len(stat.Warnings))
+		duration += stat.DurationSeconds
+	}
+	cost.DurationMilliseconds = milliseconds(duration)
+
+	sort.SliceStable(sent, func(i, j int) bool { return sent[i].DurationSeconds > sent[j].DurationSeconds })
+	if len(sent) > slowestQueries {
+		sent = sent[:slowestQueries]
+	}
+	for _, stat := range sent {
+		cost.SlowestQueries = append(cost.SlowestQueries, kokumetricscfgv1beta1.QueryCost{
+			Name:                 stat.Name,
+			DurationMilliseconds: milliseconds(stat.DurationSeconds),
+			Series:               int64(stat.Series),
+			Samples:              int64(stat.Samples),
+			Warnings:             stat.Warnings,
+		})
+	}
+	return cost
+}
+
+func milliseconds(seconds float64) int64 {
+	return int64(seconds * 1000)
+}
@@ -0,0 +1,92 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"testing"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+func TestQueryRangeRecordsCost(t *testing.T) {
+	matrix := model.Matrix{
+		{Metric: model.Metric{"node": "node-1"}, Values: []model.SamplePair{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}}},
+		{Metric: model.Metric{"node": "node-2"}, Values: []model.SamplePair{{Timestamp: 1, Value: 1}}},
+	}
+	c := &PromCollector{
+		PromConn: mockPrometheusConnection{
+			singleResult: &mockPromResult{value: matrix, warnings: promv1.Warnings{"partial response"}},
+			t:            t,
+		},
+		TimeSeries: &promv1.Range{},
+		Log:        testLogger,
+		cache:      queryCache{},
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.queryRange("node-capacity", "query"); err != nil {
+			t.Fatalf("queryRange got unexpected error: %v", err)
+		}
+	}
+	if len(c.QueryStats) != 2 {
+		t.Fatalf("queryRange got %d query stats want 2", len(c.QueryStats))
+	}
+	sent, cached := c.QueryStats[0], c.QueryStats[1]
+	if sent.Series != 2 || sent.Samples != 3 || len(sent.Warnings) != 1 || !sent.sent {
+		t.Errorf("queryRange got stat %+v want 2 series, 3 samples, and 1 warning", sent)
+	}
+	if !cached.Cached || cached.sent || cached.Samples != 3 {
+		t.Errorf("queryRange got stat %+v want a cached result of 3 samples", cached)
+	}
+}
+
+func TestQueryCost(t *testing.T) {
+	stats := []QueryStat{
+		{Name: "refused", Error: "not allowed"},
+		{Name: "cached", Series: 4, Samples: 40, Cached: true},
+		{Name: "warned", DurationSeconds: 0.5, Series: 2, Samples: 20, Warnings: []string{"partial response"}, sent: true},
+	}
+	for i := 0; i < slowestQueries+1; i++ {
+		stats = append(stats, QueryStat{Name: fmt.Sprintf("query-%d", i), DurationSeconds: float64(i+1) * 0.1, Series: 1, Samples: 10, sent: true})
+	}
+
+	cost := queryCost(stats)
+	if cost.Queries != int64(slowestQueries+2) || cost.CachedQueries != 1 {
+		t.Errorf("queryCost got %d queries and %d cached want %d and 1", cost.Queries, cost.CachedQueries, slowestQueries+2)
+	}
+	if cost.Samples != 80 || cost.Warnings != 1 {
+		t.Errorf("queryCost got %d samples and %d warnings want 80 and 1", cost.Samples, cost.Warnings)
+	}
+	if cost.DurationMilliseconds != 2600 {
+		t.Errorf("queryCost got duration %dms want 2600ms", cost.DurationMilliseconds)
+	}
+	if len(cost.SlowestQueries) != slowestQueries {
+		t.Fatalf("queryCost got %d slowest queries want %d", len(cost.SlowestQueries), slowestQueries)
+	}
+	if cost.SlowestQueries[0].Name != "query-5" || cost.SlowestQueries[0].DurationMilliseconds != 600 {
+		t.Errorf("queryCost got slowest query %+v want query-5 of 600ms", cost.SlowestQueries[0])
+	}
+	for _, query := range cost.SlowestQueries {
+		if query.Name == "query-0" {
+			t.Errorf("queryCost got query-0 in the slowest queries")
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// queryColumnAliases are the report columns whose names differ from the values of the query that fills them
+var queryColumnAliases = map[string]string{
+	"volume_request_storage_byte_seconds": "persistentvolumeclaim-request-bytes",
+}
+
+// columns returns the report columns that the query fills
+func (q query) columns() []string {
+	var columns []string
+	if q.QueryValue != nil {
+		columns = append(columns, columnName(q.QueryValue.ValName), columnName(q.QueryValue.TransformedName))
+	}
+	for column := range q.MetricKeyRegex {
+		columns = append(columns, column)
+	}
+	if q.AnnotationKey != "" {
+		columns = append(columns, q.AnnotationKey)
+	}
+	return columns
+}
+
+func columnName(valName string) string {
+	return strings.ReplaceAll(valName, "-", "_")
+}
+
+// ResolveQueryOverrides maps each override, keyed by a report column or a query name, to the name of the default
+// query that it replaces. The overrides that do not match a query, or have no PromQL, are left out and returned in
+// the error.
+func ResolveQueryOverrides(overrides map[string]string) (map[string]string, error) {
+	if len(overrides) <= 0 {
+		return nil, nil
+	}
+	queryNames := map[string]string{}
+	for _, queries := range []*querys{nodeQueries, podQueries, volQueries, namespaceQueries, workloadQueries} {
+		for _, q := range *queries {
+			queryNames[q.Name] = q.Name
+			for _, column := range q.columns() {
+				queryNames[column] = q.Name
+			}
+		}
+	}
+	for column, name := range queryColumnAliases {
+		queryNames[column] = name
+	}
+
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resolved := map[string]string{}
+	var errs []string
+	for _, key := range keys {
+		promQL := strings.TrimSpace(overrides[key])
+		name, ok := queryNames[key]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Sprintf("%s is not a report column or query", key))
+		case promQL == "":
+			errs = append(errs, fmt.Sprintf("%s has no query", key))
+		case resolved[name] != "" && resolved[name] != promQL:
+			errs = append(errs, fmt.Sprintf("%s overrides the %s query, which is already overridden", key, name))
+		default:
+			resolved[name] = promQL
+		}
+	}
+	if len(errs) > 0 {
+		return resolved, fmt.Errorf("ResolveQueryOverrides: %s", strings.Join(errs, "; "))
+	}
+	return resolved, nil
+}
+
+// queryString returns the PromQL of the query, or the PromQL that overrides it
+func (c *PromCollector) queryString(q query) string {
+	if override, ok := c.QueryOverrides[q.Name]; ok {
+		return override
+	}
+	return q.QueryString
+}
@@ -0,0 +1,128 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"testing"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+func TestResolveQueryOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      map[string]string
+		err       bool
+	}{
+		{
+			name: "no overrides",
+		},
+		{
+			name:      "value column",
+			overrides: map[string]string{"pod_usage_cpu_core_seconds": "sum(rate(cpu[5m])) by (pod, namespace, node)"},
+			want:      map[string]string{"pod-usage-cpu-cores": "sum(rate(cpu[5m])) by (pod, namespace, node)"},
+		},
+		{
+			name:      "label column",
+			overrides: map[string]string{"node_labels": "custom_node_labels"},
+			want:      map[string]string{"node-labels": "custom_node_labels"},
+		},
+		{
+			name:      "aliased column",
+			overrides: map[string]string{"volume_request_storage_byte_seconds": "pvc_requests"},
+			want:      map[string]string{"persistentvolumeclaim-request-bytes": "pvc_requests"},
+		},
+		{
+			name:      "query name",
+			overrides: map[string]string{"persistentvolume_pod_info": " pvc_info "},
+			want:      map[string]string{"persistentvolume_pod_info": "pvc_info"},
+		},
+		{
+			name:      "unknown column",
+			overrides: map[string]string{"node_labels": "custom_node_labels", "gpu_seconds": "gpu"},
+			want:      map[string]string{"node-labels": "custom_node_labels"},
+			err:       true,
+		},
+		{
+			name:      "empty query",
+			overrides: map[string]string{"node_labels": " "},
+			want:      map[string]string{},
+			err:       true,
+		},
+		{
+			name:      "conflicting overrides",
+			overrides: map[string]string{"pod_usage_cpu_cores": "a", "pod_usage_cpu_core_seconds": "b"},
+			want:      map[string]string{"pod-usage-cpu-cores": "b"},
+			err:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveQueryOverrides(tt.overrides)
+			if (err != nil) != tt.err {
+				t.Errorf("%s got error %v want error %t", tt.name, err, tt.err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%s got %v want %v", tt.name, got, tt.want)
+			}
+			for name, query := range tt.want {
+				if got[name] != query {
+					t.Errorf("%s got %v want %v", tt.name, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryOverridesApplied(t *testing.T) {
+	matrix := model.Matrix{{Metric: model.Metric{"node": "node-1"}, Values: []model.SamplePair{{Timestamp: 1, Value: 1}}}}
+	queries := &querys{
+		query{Name: "node-labels", QueryString: "kube_node_labels", MetricKeyRegex: regexFields{"node_labels": "label_*"}, RowKey: "node"},
+	}
+	c := &PromCollector{
+		PromConn: mockPrometheusConnection{
+			mappedResults: &mappedMockPromResult{"custom_node_labels": &mockPromResult{value: matrix}},
+			t:             t,
+		},
+		TimeSeries:     &promv1.Range{},
+		Log:            testLogger,
+		QueryOverrides: map[string]string{"node-labels": "custom_node_labels"},
+		Generators:     []ReportGenerator{},
+	}
+	results := mappedResults{}
+	if err := c.getQueryResults(queries, &results); err != nil {
+		t.Fatalf("getQueryResults got unexpected error: %v", err)
+	}
+	if _, ok := results["node-1"]; !ok {
+		t.Errorf("getQueryResults got %v want the rows of the override", results)
+	}
+
+	found := false
+	for _, q := range c.QueryPlan() {
+		if q.Name == "node-labels" {
+			found = q.Query == "custom_node_labels"
+		}
+	}
+	if !found {
+		t.Errorf("QueryPlan got no node-labels query with the override")
+	}
+}
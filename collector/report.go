@@ -26,6 +26,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/project-koku/koku-metrics-operator/strset"
@@ -34,6 +35,7 @@ import (
 type dataInterface interface {
 	writeToFile(io.Writer, *strset.Set, bool) error
 	getPrefix() string
+	getHeaders() []string
 }
 
 type fileInterface interface {
@@ -56,6 +58,10 @@ type report struct {
 	data dataInterface
 	file fileInterface
 	size int64
+	// written is the number of bytes appended to the file by the last write
+	written int64
+	// upgradedFrom are the columns of the file before it was upgraded to the current schema
+	upgradedFrom []string
 }
 
 // writeToFile writes the rows to file. Writes headers if file was created.
@@ -66,9 +72,19 @@ func (d *data) writeToFile(file io.Writer, set *strset.Set, created bool) error
 			return fmt.Errorf("writeToFile: failed to write headers: %v", err)
 		}
 	}
+	// rows are written in order so that the same data always produces the same file
+	lines := make(map[string]csvStruct, len(d.queryData))
 	for _, row := range d.queryData {
-		if !set.Contains(row.string()) {
-			if err := cw.Write(row.csvRow()); err != nil {
+		lines[row.string()] = row
+	}
+	sorted := make([]string, 0, len(lines))
+	for line := range lines {
+		sorted = append(sorted, line)
+	}
+	sort.Strings(sorted)
+	for _, line := range sorted {
+		if !set.Contains(line) {
+			if err := cw.Write(lines[line].csvRow()); err != nil {
 				return fmt.Errorf("writeToFile: failed to write data row: %v", err)
 			}
 		}
@@ -81,6 +97,10 @@ func (d *data) getPrefix() string {
 	return d.prefix
 }
 
+func (d *data) getHeaders() []string {
+	return d.headers
+}
+
 func (f *file) getName() string {
 	return f.name
 }
@@ -110,10 +130,21 @@ func (r *report) writeReport() error {
 		return fmt.Errorf("writeReport: failed to get or create csv: %v", err)
 	}
 	defer csvFile.Close()
+	if headers := r.data.getHeaders(); !fileCreated && len(headers) > 0 {
+		previous, err := upgradeFile(csvFile, headers)
+		if err != nil {
+			return fmt.Errorf("writeReport: failed to upgrade csv: %v", err)
+		}
+		r.upgradedFrom = previous
+	}
 	set, err := readCSV(csvFile, strset.NewSet(), r.data.getPrefix())
 	if err != nil {
 		return fmt.Errorf("writeReport: failed to read csv: %v", err)
 	}
+	before, err := csvFile.Stat()
+	if err != nil {
+		return fmt.Errorf("writeReport: failed to get file size: %v", err)
+	}
 	if err := r.data.writeToFile(csvFile, set, fileCreated); err != nil {
 		return fmt.Errorf("writeReport: failed to write to file: %v", err)
 	}
@@ -122,6 +153,7 @@ func (r *report) writeReport() error {
 		return fmt.Errorf("writeReport: failed to get file size: %v", err)
 	}
 	r.size = fileInfo.Size()
+	r.written = r.size - before.Size()
 	return csvFile.Sync()
 }
 
@@ -48,6 +48,10 @@ func (f *fakeData) getPrefix() string {
 	return f.prefix
 }
 
+func (f *fakeData) getHeaders() []string {
+	return nil
+}
+
 func (f *fakeData) writeToFile(w io.Writer, s *strset.Set, b bool) error {
 	return f.writeErr
 }
@@ -298,6 +302,25 @@ func TestWriteToFile(t *testing.T) {
 	}
 }
 
+func TestWriteToFileSorted(t *testing.T) {
+	dates := &dateTimes{ReportPeriodStart: "s", ReportPeriodEnd: "e", IntervalStart: "is", IntervalEnd: "ie"}
+	queryData := mappedCSVStruct{}
+	for _, namespace := range []string{"zeta", "alpha", "mu", "beta"} {
+		queryData[namespace] = namespaceRow{dateTimes: dates, Namespace: namespace}
+	}
+	want := "s,e,is,ie,alpha,,\ns,e,is,ie,beta,,\ns,e,is,ie,mu,,\ns,e,is,ie,zeta,,\n"
+	for i := 0; i < 5; i++ {
+		builder := &strings.Builder{}
+		d := &data{queryData: queryData}
+		if err := d.writeToFile(builder, strset.NewSet(), false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if builder.String() != want {
+			t.Errorf("got %q want %q", builder.String(), want)
+		}
+	}
+}
+
 func TestReadCSV(t *testing.T) {
 	testHeaders := "report_period_start,report_period_end,interval_start,interval_end,node\n"
 	testRow := "2020-11-01 00:00:00 +0000 UTC,2020-12-01 00:00:00 +0000 UTC,2020-11-06 15:00:00 +0000 UTC,2020-11-06 15:59:59 +0000 UTC,ip-10-0-208-111.us-east-2.compute.internal,openshift-machine-config-operator"
@@ -0,0 +1,117 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// ReportFile is a report file of a single window
+type ReportFile struct {
+	// Report is the name of the report, such as pod, or the name of a report generator
+	Report string
+	// Name is the path of the report file, relative to the reports directory
+	Name string
+	// Headers are the columns of the report
+	Headers []string
+	// Interval is the interval that the rows of the window start with
+	Interval string
+	// Rows are the rows of the window, sorted, without duplicates
+	Rows [][]string
+}
+
+// ReportWriter writes the report files of a window. It lets tools other than the operator generate the same
+// reports into any destination.
+type ReportWriter interface {
+	// WriteReport writes the report file, and returns where it was written and the number of bytes written
+	WriteReport(f ReportFile) (string, int64, error)
+}
+
+// DirWriter writes the report files into a reports directory. The rows are appended to the existing report files,
+// skipping the rows of the window that are already in the file, and files written with an earlier schema version
+// are upgraded to the current schema version.
+type DirWriter struct {
+	Path string
+	Log  logr.Logger
+}
+
+// WriteReport appends the rows of the report file to the file in the reports directory
+func (w *DirWriter) WriteReport(f ReportFile) (string, int64, error) {
+	filename := filepath.Join(w.Path, f.Name)
+	rows := make(mappedCSVStruct, len(f.Rows))
+	for _, row := range f.Rows {
+		rows[strings.Join(row, ",")] = rowValues(row)
+	}
+	rpt := report{
+		file: &file{
+			name: filepath.Base(filename),
+			path: filepath.Dir(filename),
+		},
+		data: &data{
+			queryData: rows,
+			headers:   f.Headers,
+			prefix:    f.Interval,
+		},
+	}
+	w.Log.Info(fmt.Sprintf("writing %s results to file", f.Report), "filename", filename)
+	if err := rpt.writeReport(); err != nil {
+		return "", 0, fmt.Errorf("failed to write %s report: %v", f.Report, err)
+	}
+	if len(rpt.upgradedFrom) > 0 {
+		added, removed := columnChanges(rpt.upgradedFrom, f.Headers)
+		previous, ok := schemaVersion(f.Report, rpt.upgradedFrom)
+		if !ok {
+			previous = "unknown"
+		}
+		w.Log.Info(fmt.Sprintf("upgraded %s report from schema version %s to %s", f.Report, previous, ReportSchemaVersion),
+			"filename", filename, "addedColumns", added, "removedColumns", removed)
+	}
+	return filename, rpt.written, nil
+}
+
+// rowValues is a report row that was already formatted
+type rowValues []string
+
+func (row rowValues) csvHeader() []string { return nil }
+func (row rowValues) csvRow() []string    { return row }
+func (row rowValues) string() string      { return strings.Join(row, ",") }
+
+// sortedRows returns the formatted rows, sorted and without duplicates, in the order they are written
+func sortedRows(rows mappedCSVStruct) [][]string {
+	lines := make(map[string][]string, len(rows))
+	for _, row := range rows {
+		lines[row.string()] = row.csvRow()
+	}
+	sorted := make([]string, 0, len(lines))
+	for line := range lines {
+		sorted = append(sorted, line)
+	}
+	sort.Strings(sorted)
+	result := make([][]string, 0, len(sorted))
+	for _, line := range sorted {
+		result = append(result, lines[line])
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/common/model"
+)
+
+// memoryWriter keeps the report files it is given instead of writing them
+type memoryWriter struct {
+	files map[string]ReportFile
+}
+
+func (w *memoryWriter) WriteReport(f ReportFile) (string, int64, error) {
+	w.files[f.Name] = f
+	return f.Name, int64(len(f.Rows)), nil
+}
+
+// countRows returns the number of rows of a report file, without the header
+func countRows(t *testing.T, path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	rows := -1
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rows++
+	}
+	return rows
+}
+
+func TestGenerateWithReportWriter(t *testing.T) {
+	mapResults := make(mappedMockPromResult)
+	for _, q := range []*querys{nodeQueries, namespaceQueries, podQueries, workloadQueries, volQueries} {
+		for _, query := range *q {
+			res := &model.Matrix{}
+			Load(filepath.Join("test_files", "test_data", query.Name), res, t)
+			mapResults[query.QueryString] = &mockPromResult{value: *res}
+		}
+	}
+	addGeneratorResults(mapResults)
+	c := &PromCollector{
+		PromConn:   mockPrometheusConnection{mappedResults: &mapResults, t: t},
+		TimeSeries: &fakeTimeRange,
+		Log:        testLogger,
+	}
+	w := &memoryWriter{files: map[string]ReportFile{}}
+	result, err := c.Generate(w)
+	if err != nil {
+		t.Fatalf("Generate got unexpected error: %v", err)
+	}
+	if !result.DataCollected || result.ReportMonth != "11" || len(result.Files) != len(w.files) {
+		t.Errorf("Generate got result %+v want collected data of month 11 with a file per report", result)
+	}
+
+	expected, err := ioutil.ReadDir(filepath.Join("test_files", "expected_reports"))
+	if err != nil {
+		t.Fatalf("failed to read expected reports: %v", err)
+	}
+	if len(w.files) != len(expected) {
+		t.Errorf("Generate got %d report files want %d", len(w.files), len(expected))
+	}
+	for _, info := range expected {
+		f, ok := w.files[info.Name()]
+		if !ok {
+			t.Errorf("Generate got no %s report file", info.Name())
+			continue
+		}
+		if want := countRows(t, filepath.Join("test_files", "expected_reports", info.Name())); len(f.Rows) != want {
+			t.Errorf("%s got %d rows want %d", info.Name(), len(f.Rows), want)
+		}
+		for _, row := range f.Rows {
+			if len(row) != len(f.Headers) {
+				t.Errorf("%s got a row of %d columns want %d", info.Name(), len(row), len(f.Headers))
+				break
+			}
+		}
+	}
+}
+
+func TestGenerateNoData(t *testing.T) {
+	mapResults := make(mappedMockPromResult)
+	for _, query := range *nodeQueries {
+		mapResults[query.QueryString] = &mockPromResult{value: model.Matrix{}}
+	}
+	c := &PromCollector{
+		PromConn:   mockPrometheusConnection{mappedResults: &mapResults, t: t},
+		TimeSeries: &fakeTimeRange,
+		Log:        testLogger,
+	}
+	w := &memoryWriter{files: map[string]ReportFile{}}
+	result, err := c.Generate(w)
+	if err != nil {
+		t.Fatalf("Generate got unexpected error: %v", err)
+	}
+	if result.DataCollected || len(w.files) != 0 {
+		t.Errorf("Generate got data collected %t and %d files want no data and no files", result.DataCollected, len(w.files))
+	}
+}
+
+func TestDirWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dir-writer")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := &DirWriter{Path: dir, Log: testLogger}
+	f := ReportFile{
+		Report:   "test",
+		Name:     filepath.Join("tenants", "a", "test-report.csv"),
+		Headers:  []string{"interval", "value"},
+		Interval: "2020-11-06",
+		Rows:     [][]string{{"2020-11-06", "1"}, {"2020-11-06", "2"}},
+	}
+	location, written, err := w.WriteReport(f)
+	if err != nil {
+		t.Fatalf("WriteReport got unexpected error: %v", err)
+	}
+	if want := filepath.Join(dir, f.Name); location != want || written <= 0 {
+		t.Errorf("WriteReport got %s and %d bytes want %s and more than 0 bytes", location, written, want)
+	}
+	// the rows of the window are only written once
+	if _, written, err = w.WriteReport(f); err != nil || written != 0 {
+		t.Errorf("WriteReport got %d bytes and error %v want 0 bytes written again", written, err)
+	}
+	if rows := countRows(t, location); rows != 2 {
+		t.Errorf("WriteReport got %d rows want 2", rows)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// intervalLayout is the format of the interval columns of the reports
+const intervalLayout = "2006-01-02 15:04:05 -0700 MST"
+
+// gaugeColumns are the numeric columns that hold a value at a point in time. A daily row has the largest value of its
+// hourly rows. The other numeric columns, which end in _seconds, are usage over the interval and are summed.
+var gaugeColumns = map[string]bool{
+	"node_capacity_cpu_cores":              true,
+	"node_capacity_memory_bytes":           true,
+	"persistentvolumeclaim_capacity_bytes": true,
+	"metric_value":                         true,
+}
+
+// dailyRow is a row of a rolled up report
+type dailyRow struct {
+	values     []string
+	start, end time.Time
+	// numbers are the sum or largest value of each numeric column, by column index
+	numbers map[int]float64
+}
+
+// RollupReportFile rewrites the report at path with one row for each day instead of one row for each hour. Rows are
+// grouped by the day of their interval start, in the time zone of the interval, and by the values of every column
+// other than the numeric columns. The usage columns are summed, and the largest value of the capacity columns is kept.
+// The interval of a daily row runs from the earliest interval start to the latest interval end of its rows. Files
+// without interval columns are not changed.
+func RollupReportFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("RollupReportFile: failed to open report: %v", err)
+	}
+	defer in.Close()
+	reader := csv.NewReader(in)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("RollupReportFile: failed to read header: %v", err)
+	}
+	startIdx, endIdx := -1, -1
+	numeric := map[int]bool{}
+	for i, column := range header {
+		switch {
+		case column == "interval_start":
+			startIdx = i
+		case column == "interval_end":
+			endIdx = i
+		case gaugeColumns[column] || strings.HasSuffix(column, "_seconds"):
+			numeric[i] = true
+		}
+	}
+	if startIdx < 0 || endIdx < 0 {
+		return nil
+	}
+
+	days := map[string]*dailyRow{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("RollupReportFile: failed to read row: %v", err)
+		}
+		start, err := time.Parse(intervalLayout, row[startIdx])
+		if err != nil {
+			return fmt.Errorf("RollupReportFile: failed to parse interval start %q: %v", row[startIdx], err)
+		}
+		end, err := time.Parse(intervalLayout, row[endIdx])
+		if err != nil {
+			return fmt.Errorf("RollupReportFile: failed to parse interval end %q: %v", row[endIdx], err)
+		}
+
+		key := []string{start.Format("2006-01-02")}
+		for i, value := range row {
+			if i != startIdx && i != endIdx && !numeric[i] {
+				key = append(key, value)
+			}
+		}
+		day, ok := days[strings.Join(key, "\x00")]
+		if !ok {
+			day = &dailyRow{values: append([]string{}, row...), start: start, end: end, numbers: map[int]float64{}}
+			days[strings.Join(key, "\x00")] = day
+		}
+		if start.Before(day.start) {
+			day.start = start
+			day.values[startIdx] = row[startIdx]
+		}
+		if end.After(day.end) {
+			day.end = end
+			day.values[endIdx] = row[endIdx]
+		}
+		for i := range numeric {
+			value, err := strconv.ParseFloat(row[i], 64)
+			if err != nil {
+				// empty values stay empty unless another row of the day has a value
+				continue
+			}
+			current, seen := day.numbers[i]
+			switch {
+			case !seen:
+				day.numbers[i] = value
+			case gaugeColumns[header[i]]:
+				if value > current {
+					day.numbers[i] = value
+				}
+			default:
+				day.numbers[i] = current + value
+			}
+		}
+	}
+
+	rows := make([][]string, 0, len(days))
+	for _, day := range days {
+		for i, value := range day.numbers {
+			day.values[i] = floatToString(value)
+		}
+		rows = append(rows, day.values)
+	}
+	sort.Slice(rows, func(i, j int) bool { return strings.Join(rows[i], ",") < strings.Join(rows[j], ",") })
+
+	tmp := path + ".daily"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("RollupReportFile: failed to create daily report: %v", err)
+	}
+	defer os.Remove(tmp)
+	defer out.Close()
+	w := csv.NewWriter(out)
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("RollupReportFile: failed to write header: %v", err)
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("RollupReportFile: failed to write rows: %v", err)
+	}
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("RollupReportFile: failed to write daily report: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("RollupReportFile: failed to replace report: %v", err)
+	}
+	return nil
+}
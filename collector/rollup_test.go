@@ -0,0 +1,107 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollupReportFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rollup")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	rollupTests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "empty file",
+			contents: "",
+			want:     "",
+		},
+		{
+			name:     "no interval columns",
+			contents: "a,b\n1,2\n1,3\n",
+			want:     "a,b\n1,2\n1,3\n",
+		},
+		{
+			name: "usage is summed and capacity is the largest value",
+			contents: "interval_start,interval_end,pod,pod_usage_cpu_core_seconds,node_capacity_cpu_cores,pod_labels\n" +
+				"2021-01-01 01:00:00 +0000 UTC,2021-01-01 01:59:59 +0000 UTC,a,10.000000,4.000000,app:x\n" +
+				"2021-01-01 00:00:00 +0000 UTC,2021-01-01 00:59:59 +0000 UTC,a,5.500000,8.000000,app:x\n" +
+				"2021-01-01 02:00:00 +0000 UTC,2021-01-01 02:59:59 +0000 UTC,b,1.000000,8.000000,app:y\n",
+			want: "interval_start,interval_end,pod,pod_usage_cpu_core_seconds,node_capacity_cpu_cores,pod_labels\n" +
+				"2021-01-01 00:00:00 +0000 UTC,2021-01-01 01:59:59 +0000 UTC,a,15.500000,8.000000,app:x\n" +
+				"2021-01-01 02:00:00 +0000 UTC,2021-01-01 02:59:59 +0000 UTC,b,1.000000,8.000000,app:y\n",
+		},
+		{
+			name: "days and labels are kept apart",
+			contents: "interval_start,interval_end,pod,pod_usage_cpu_core_seconds,pod_labels\n" +
+				"2021-01-01 23:00:00 +0000 UTC,2021-01-01 23:59:59 +0000 UTC,a,1.000000,app:x\n" +
+				"2021-01-02 00:00:00 +0000 UTC,2021-01-02 00:59:59 +0000 UTC,a,2.000000,app:x\n" +
+				"2021-01-02 01:00:00 +0000 UTC,2021-01-02 01:59:59 +0000 UTC,a,4.000000,app:z\n",
+			want: "interval_start,interval_end,pod,pod_usage_cpu_core_seconds,pod_labels\n" +
+				"2021-01-01 23:00:00 +0000 UTC,2021-01-01 23:59:59 +0000 UTC,a,1.000000,app:x\n" +
+				"2021-01-02 00:00:00 +0000 UTC,2021-01-02 00:59:59 +0000 UTC,a,2.000000,app:x\n" +
+				"2021-01-02 01:00:00 +0000 UTC,2021-01-02 01:59:59 +0000 UTC,a,4.000000,app:z\n",
+		},
+		{
+			name: "days follow the interval time zone",
+			contents: "interval_start,interval_end,node,node_labels\n" +
+				"2021-01-01 23:00:00 -0500 EST,2021-01-01 23:59:59 -0500 EST,n,\n" +
+				"2021-01-01 22:00:00 -0500 EST,2021-01-01 22:59:59 -0500 EST,n,\n",
+			want: "interval_start,interval_end,node,node_labels\n" +
+				"2021-01-01 22:00:00 -0500 EST,2021-01-01 23:59:59 -0500 EST,n,\n",
+		},
+		{
+			name: "empty values stay empty",
+			contents: "interval_start,interval_end,namespace,pod,metric_name,metric_value\n" +
+				"2021-01-01 00:00:00 +0000 UTC,2021-01-01 00:59:59 +0000 UTC,ns,a,m,\n" +
+				"2021-01-01 01:00:00 +0000 UTC,2021-01-01 01:59:59 +0000 UTC,ns,a,m,\n",
+			want: "interval_start,interval_end,namespace,pod,metric_name,metric_value\n" +
+				"2021-01-01 00:00:00 +0000 UTC,2021-01-01 01:59:59 +0000 UTC,ns,a,m,\n",
+		},
+	}
+	for _, tt := range rollupTests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "report.csv")
+			if err := ioutil.WriteFile(path, []byte(tt.contents), 0644); err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+			if err := RollupReportFile(path); err != nil {
+				t.Fatalf("%s got unexpected error: %v", tt.name, err)
+			}
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read report: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("%s got\n%s\nwant\n%s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
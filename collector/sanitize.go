@@ -0,0 +1,86 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// labelSeparator separates the label and annotation pairs of a labels column, and escapedLabelSeparator replaces
+// it in the values
+const (
+	labelSeparator        = "|"
+	escapedLabelSeparator = "%7C"
+)
+
+// labelSanitizer makes label and annotation values safe to write to the labels columns of the reports, and counts
+// the values it changed
+type labelSanitizer struct {
+	maxLength int
+	counts    kokumetricscfgv1beta1.SanitizedValuesStatus
+}
+
+// newLabelSanitizer returns a sanitizer that truncates values to maxLength bytes. When maxLength is not positive,
+// the default length is used.
+func newLabelSanitizer(maxLength int) *labelSanitizer {
+	if maxLength <= 0 {
+		maxLength = int(kokumetricscfgv1beta1.DefaultMaxLabelValueLength)
+	}
+	return &labelSanitizer{maxLength: maxLength}
+}
+
+// sanitize strips control characters and invalid UTF-8 from the value, truncates it to the maximum length, and
+// escapes the label separator, so that a value cannot break the row or the labels column it is written to
+func (s *labelSanitizer) sanitize(value string) string {
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(value, ""))
+	if stripped != value {
+		s.counts.ControlCharacters++
+		sanitizedValues.WithLabelValues("control_characters").Inc()
+		value = stripped
+	}
+	if len(value) > s.maxLength {
+		s.counts.Truncated++
+		sanitizedValues.WithLabelValues("truncated").Inc()
+		value = truncateUTF8(value, s.maxLength)
+	}
+	if strings.Contains(value, labelSeparator) {
+		s.counts.Escaped++
+		sanitizedValues.WithLabelValues("escaped").Inc()
+		value = strings.ReplaceAll(value, labelSeparator, escapedLabelSeparator)
+	}
+	return value
+}
+
+// truncateUTF8 returns the longest prefix of the value of at most n bytes that does not split a character
+func truncateUTF8(value string, n int) string {
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return value[:n]
+}
@@ -0,0 +1,81 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/model"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestLabelSanitizer(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxLength int
+		value     string
+		want      string
+		counts    kokumetricscfgv1beta1.SanitizedValuesStatus
+	}{
+		{name: "clean value", value: "frontend", want: "frontend"},
+		{name: "newline", value: "line1\nline2\r\n", want: "line1line2", counts: kokumetricscfgv1beta1.SanitizedValuesStatus{ControlCharacters: 1}},
+		{name: "invalid utf-8", value: "caf\xe9", want: "caf", counts: kokumetricscfgv1beta1.SanitizedValuesStatus{ControlCharacters: 1}},
+		{name: "separator", value: "a|b", want: "a%7Cb", counts: kokumetricscfgv1beta1.SanitizedValuesStatus{Escaped: 1}},
+		{name: "truncated", maxLength: 16, value: strings.Repeat("x", 20), want: strings.Repeat("x", 16), counts: kokumetricscfgv1beta1.SanitizedValuesStatus{Truncated: 1}},
+		{name: "truncated within a character", maxLength: 16, value: strings.Repeat("x", 15) + "é", want: strings.Repeat("x", 15), counts: kokumetricscfgv1beta1.SanitizedValuesStatus{Truncated: 1}},
+		{name: "default length", value: strings.Repeat("x", 2000), want: strings.Repeat("x", 1024), counts: kokumetricscfgv1beta1.SanitizedValuesStatus{Truncated: 1}},
+		{
+			name:      "every change",
+			maxLength: 16,
+			value:     "a|b\t" + strings.Repeat("x", 20),
+			want:      "a%7Cb" + strings.Repeat("x", 13),
+			counts:    kokumetricscfgv1beta1.SanitizedValuesStatus{Truncated: 1, ControlCharacters: 1, Escaped: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newLabelSanitizer(tt.maxLength)
+			if got := s.sanitize(tt.value); got != tt.want {
+				t.Errorf("%s got %q want %q", tt.name, got, tt.want)
+			}
+			if s.counts != tt.counts {
+				t.Errorf("%s got counts %+v want %+v", tt.name, s.counts, tt.counts)
+			}
+		})
+	}
+}
+
+func TestFindFieldsSanitized(t *testing.T) {
+	metric := model.Metric{
+		"label_app":         "web\nserver",
+		"label_team":        "a|b",
+		"label_cost_center": "1234",
+	}
+	s := newLabelSanitizer(0)
+	want := "label_app:webserver|label_cost_center:1234|label_team:a%7Cb"
+	if got := findFields(metric, "label_*", s); got != want {
+		t.Errorf("findFields got %q want %q", got, want)
+	}
+	if s.counts.ControlCharacters != 1 || s.counts.Escaped != 1 {
+		t.Errorf("findFields got counts %+v want 1 value with control characters and 1 escaped", s.counts)
+	}
+}
@@ -0,0 +1,419 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ReportSchemaVersion is the version of the report columns written by the collector. It is written to the manifest of
+// each payload. When columns are added or removed, a new schema is added to reportSchemas and the version is increased.
+const ReportSchemaVersion = "4"
+
+// the names of the reports in the schemas
+const (
+	nodeReport      = "node"
+	podReport       = "pod"
+	volumeReport    = "volume"
+	namespaceReport = "namespace"
+	customReport    = "custom"
+)
+
+var reportPeriodColumns = []string{
+	"report_period_start",
+	"report_period_end",
+	"interval_start",
+	"interval_end",
+}
+
+func withPeriodColumns(columns ...string) []string {
+	return append(append([]string{}, reportPeriodColumns...), columns...)
+}
+
+// reportSchema is the set of columns of each report at a schema version
+type reportSchema struct {
+	version string
+	reports map[string][]string
+}
+
+// reportSchemas are the report columns of each schema version, oldest first. Existing versions must not be changed.
+var reportSchemas = []reportSchema{
+	{
+		version: "1",
+		reports: map[string][]string{
+			nodeReport: withPeriodColumns(
+				"node",
+				"node_labels"),
+			podReport: withPeriodColumns(
+				"node",
+				"namespace",
+				"pod",
+				"pod_usage_cpu_core_seconds",
+				"pod_request_cpu_core_seconds",
+				"pod_limit_cpu_core_seconds",
+				"pod_usage_memory_byte_seconds",
+				"pod_request_memory_byte_seconds",
+				"pod_limit_memory_byte_seconds",
+				"node_capacity_cpu_cores",
+				"node_capacity_cpu_core_seconds",
+				"node_capacity_memory_bytes",
+				"node_capacity_memory_byte_seconds",
+				"resource_id",
+				"pod_labels"),
+			volumeReport: withPeriodColumns(
+				"namespace",
+				"pod",
+				"persistentvolumeclaim",
+				"persistentvolume",
+				"storageclass",
+				"persistentvolumeclaim_capacity_bytes",
+				"persistentvolumeclaim_capacity_byte_seconds",
+				"volume_request_storage_byte_seconds",
+				"persistentvolumeclaim_usage_byte_seconds",
+				"persistentvolume_labels",
+				"persistentvolumeclaim_labels"),
+			namespaceReport: withPeriodColumns(
+				"namespace",
+				"namespace_labels"),
+		},
+	},
+	{
+		// version 2 adds the annotation columns and the custom metrics report
+		version: "2",
+		reports: map[string][]string{
+			nodeReport: withPeriodColumns(
+				"node",
+				"node_labels",
+				"node_annotations"),
+			podReport: withPeriodColumns(
+				"node",
+				"namespace",
+				"pod",
+				"pod_usage_cpu_core_seconds",
+				"pod_request_cpu_core_seconds",
+				"pod_limit_cpu_core_seconds",
+				"pod_usage_memory_byte_seconds",
+				"pod_request_memory_byte_seconds",
+				"pod_limit_memory_byte_seconds",
+				"node_capacity_cpu_cores",
+				"node_capacity_cpu_core_seconds",
+				"node_capacity_memory_bytes",
+				"node_capacity_memory_byte_seconds",
+				"resource_id",
+				"pod_labels",
+				"pod_annotations"),
+			volumeReport: withPeriodColumns(
+				"namespace",
+				"pod",
+				"persistentvolumeclaim",
+				"persistentvolume",
+				"storageclass",
+				"persistentvolumeclaim_capacity_bytes",
+				"persistentvolumeclaim_capacity_byte_seconds",
+				"volume_request_storage_byte_seconds",
+				"persistentvolumeclaim_usage_byte_seconds",
+				"persistentvolume_labels",
+				"persistentvolumeclaim_labels"),
+			namespaceReport: withPeriodColumns(
+				"namespace",
+				"namespace_labels",
+				"namespace_annotations"),
+			customReport: withPeriodColumns(
+				"namespace",
+				"pod",
+				"metric_name",
+				"metric_value"),
+		},
+	},
+	{
+		// version 3 adds the workload columns to the pod report
+		version: "3",
+		reports: map[string][]string{
+			nodeReport: withPeriodColumns(
+				"node",
+				"node_labels",
+				"node_annotations"),
+			podReport: withPeriodColumns(
+				"node",
+				"namespace",
+				"pod",
+				"pod_usage_cpu_core_seconds",
+				"pod_request_cpu_core_seconds",
+				"pod_limit_cpu_core_seconds",
+				"pod_usage_memory_byte_seconds",
+				"pod_request_memory_byte_seconds",
+				"pod_limit_memory_byte_seconds",
+				"node_capacity_cpu_cores",
+				"node_capacity_cpu_core_seconds",
+				"node_capacity_memory_bytes",
+				"node_capacity_memory_byte_seconds",
+				"resource_id",
+				"pod_labels",
+				"pod_annotations",
+				"workload_kind",
+				"workload_name"),
+			volumeReport: withPeriodColumns(
+				"namespace",
+				"pod",
+				"persistentvolumeclaim",
+				"persistentvolume",
+				"storageclass",
+				"persistentvolumeclaim_capacity_bytes",
+				"persistentvolumeclaim_capacity_byte_seconds",
+				"volume_request_storage_byte_seconds",
+				"persistentvolumeclaim_usage_byte_seconds",
+				"persistentvolume_labels",
+				"persistentvolumeclaim_labels"),
+			namespaceReport: withPeriodColumns(
+				"namespace",
+				"namespace_labels",
+				"namespace_annotations"),
+			customReport: withPeriodColumns(
+				"namespace",
+				"pod",
+				"metric_name",
+				"metric_value"),
+		},
+	},
+	{
+		// version 4 adds the QoS class and priority class columns to the pod report
+		version: "4",
+		reports: map[string][]string{
+			nodeReport: withPeriodColumns(
+				"node",
+				"node_labels",
+				"node_annotations"),
+			podReport: withPeriodColumns(
+				"node",
+				"namespace",
+				"pod",
+				"pod_usage_cpu_core_seconds",
+				"pod_request_cpu_core_seconds",
+				"pod_limit_cpu_core_seconds",
+				"pod_usage_memory_byte_seconds",
+				"pod_request_memory_byte_seconds",
+				"pod_limit_memory_byte_seconds",
+				"node_capacity_cpu_cores",
+				"node_capacity_cpu_core_seconds",
+				"node_capacity_memory_bytes",
+				"node_capacity_memory_byte_seconds",
+				"resource_id",
+				"pod_labels",
+				"pod_annotations",
+				"workload_kind",
+				"workload_name",
+				"qos_class",
+				"priority_class"),
+			volumeReport: withPeriodColumns(
+				"namespace",
+				"pod",
+				"persistentvolumeclaim",
+				"persistentvolume",
+				"storageclass",
+				"persistentvolumeclaim_capacity_bytes",
+				"persistentvolumeclaim_capacity_byte_seconds",
+				"volume_request_storage_byte_seconds",
+				"persistentvolumeclaim_usage_byte_seconds",
+				"persistentvolume_labels",
+				"persistentvolumeclaim_labels"),
+			namespaceReport: withPeriodColumns(
+				"namespace",
+				"namespace_labels",
+				"namespace_annotations"),
+			customReport: withPeriodColumns(
+				"namespace",
+				"pod",
+				"metric_name",
+				"metric_value"),
+		},
+	},
+}
+
+// reportColumns returns the columns of the report in the current schema version
+func reportColumns(report string) []string {
+	columns := reportSchemas[len(reportSchemas)-1].reports[report]
+	return append([]string{}, columns...)
+}
+
+// IsPeriodColumn returns true if the column is one of the columns of the reporting period and interval of a row,
+// which every report starts with
+func IsPeriodColumn(column string) bool {
+	for _, c := range reportPeriodColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportName returns the name of the report with the columns, in any schema version or of a registered report
+// generator. False is returned if the columns do not match any report.
+func ReportName(columns []string) (string, bool) {
+	for _, g := range ReportGenerators() {
+		if equalColumns(withPeriodColumns(g.Columns()...), columns) {
+			return g.Name(), true
+		}
+	}
+	for i := len(reportSchemas) - 1; i >= 0; i-- {
+		for report, reportColumns := range reportSchemas[i].reports {
+			if equalColumns(reportColumns, columns) {
+				return report, true
+			}
+		}
+	}
+	return "", false
+}
+
+// schemaVersion returns the schema version of the report with the columns
+func schemaVersion(report string, columns []string) (string, bool) {
+	for i := len(reportSchemas) - 1; i >= 0; i-- {
+		if equalColumns(reportSchemas[i].reports[report], columns) {
+			return reportSchemas[i].version, true
+		}
+	}
+	return "", false
+}
+
+func equalColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// columnChanges returns the columns added and removed between two sets of columns
+func columnChanges(from, to []string) ([]string, []string) {
+	fromSet := map[string]bool{}
+	for _, c := range from {
+		fromSet[c] = true
+	}
+	toSet := map[string]bool{}
+	var added, removed []string
+	for _, c := range to {
+		toSet[c] = true
+		if !fromSet[c] {
+			added = append(added, c)
+		}
+	}
+	for _, c := range from {
+		if !toSet[c] {
+			removed = append(removed, c)
+		}
+	}
+	return added, removed
+}
+
+// projectRow returns the values of row, in the order of from, in the order of to. Columns that are not in from are empty.
+func projectRow(row, from, to []string) []string {
+	index := map[string]int{}
+	for i, c := range from {
+		index[c] = i
+	}
+	projected := make([]string, len(to))
+	for i, c := range to {
+		if j, ok := index[c]; ok && j < len(row) {
+			projected[i] = row[j]
+		}
+	}
+	return projected
+}
+
+// upgradeFile rewrites a report file that was written with different columns, for example by a previous version of
+// the operator, so that the rows already in the file use the columns that are appended to it. The previous columns
+// are returned if the file was rewritten.
+func upgradeFile(f *os.File, columns []string) ([]string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("upgradeFile: failed to seek: %v", err)
+	}
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("upgradeFile: failed to read csv: %v", err)
+	}
+	if len(rows) == 0 || equalColumns(rows[0], columns) {
+		_, err := f.Seek(0, io.SeekStart)
+		return nil, err
+	}
+
+	header := rows[0]
+	if err := f.Truncate(0); err != nil {
+		return nil, fmt.Errorf("upgradeFile: failed to truncate: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("upgradeFile: failed to seek: %v", err)
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.Write(columns); err != nil {
+		return nil, fmt.Errorf("upgradeFile: failed to write headers: %v", err)
+	}
+	for _, row := range rows[1:] {
+		if err := cw.Write(projectRow(row, header, columns)); err != nil {
+			return nil, fmt.Errorf("upgradeFile: failed to write data row: %v", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return nil, fmt.Errorf("upgradeFile: failed to write csv: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("upgradeFile: failed to seek: %v", err)
+	}
+	return header, nil
+}
+
+// UpgradeReportFile rewrites the report file at path with the columns of the current schema version if it was written
+// with the columns of an older version. The schema version the file was written with is returned. An empty version is
+// returned if the columns do not match any version, and the file is not changed.
+func UpgradeReportFile(path string) (string, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return "", fmt.Errorf("UpgradeReportFile: failed to open file: %v", err)
+	}
+	defer f.Close()
+	header, err := csv.NewReader(f).Read()
+	if err == io.EOF {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("UpgradeReportFile: failed to read csv headers: %v", err)
+	}
+	for i := len(reportSchemas) - 1; i >= 0; i-- {
+		for report, columns := range reportSchemas[i].reports {
+			if !equalColumns(columns, header) {
+				continue
+			}
+			if reportSchemas[i].version != ReportSchemaVersion {
+				if _, err := upgradeFile(f, reportColumns(report)); err != nil {
+					return "", fmt.Errorf("UpgradeReportFile: %v", err)
+				}
+			}
+			return reportSchemas[i].version, f.Sync()
+		}
+	}
+	return "", nil
+}
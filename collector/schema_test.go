@@ -0,0 +1,203 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/project-koku/koku-metrics-operator/strset"
+)
+
+func TestReportSchemas(t *testing.T) {
+	for i, schema := range reportSchemas {
+		if schema.version != strconv.Itoa(i+1) {
+			t.Errorf("schema %d has version %s, versions must increase by one", i, schema.version)
+		}
+		for report, columns := range schema.reports {
+			seen := strset.NewSet()
+			for _, c := range columns {
+				if seen.Contains(c) {
+					t.Errorf("schema %s %s report has duplicate column %s", schema.version, report, c)
+				}
+				seen.Add(c)
+			}
+		}
+	}
+	if latest := reportSchemas[len(reportSchemas)-1].version; latest != ReportSchemaVersion {
+		t.Errorf("latest schema is version %s but ReportSchemaVersion is %s", latest, ReportSchemaVersion)
+	}
+
+	dates := &dateTimes{}
+	rows := map[string]csvStruct{
+		nodeReport:      newNodeRow(dates),
+		podReport:       newPodRow(dates),
+		volumeReport:    newStorageRow(dates),
+		namespaceReport: newNamespaceRow(dates),
+		customReport:    newCustomMetricRow(dates),
+	}
+	current := reportSchemas[len(reportSchemas)-1].reports
+	if len(rows) != len(current) {
+		t.Errorf("got %d row types, the current schema has %d reports", len(rows), len(current))
+	}
+	for report, row := range rows {
+		if !reflect.DeepEqual(row.csvHeader(), current[report]) {
+			t.Errorf("%s report header does not match the current schema", report)
+		}
+		if len(row.csvHeader()) != len(row.csvRow()) {
+			t.Errorf("%s report has %d columns but rows have %d values", report, len(row.csvHeader()), len(row.csvRow()))
+		}
+	}
+}
+
+func TestColumnChanges(t *testing.T) {
+	added, removed := columnChanges([]string{"a", "b", "c"}, []string{"a", "c", "d"})
+	if !reflect.DeepEqual(added, []string{"d"}) || !reflect.DeepEqual(removed, []string{"b"}) {
+		t.Errorf("got added %v removed %v, want [d] [b]", added, removed)
+	}
+	if got := projectRow([]string{"1", "2", "3"}, []string{"a", "b", "c"}, []string{"c", "d", "a"}); !reflect.DeepEqual(got, []string{"3", "", "1"}) {
+		t.Errorf("got projected row %v, want [3  1]", got)
+	}
+}
+
+func TestReportName(t *testing.T) {
+	reportNameTests := []struct {
+		name    string
+		columns []string
+		want    string
+		wantOK  bool
+	}{
+		{name: "current pod report", columns: reportColumns(podReport), want: podReport, wantOK: true},
+		{name: "pod report of schema version 1", columns: reportSchemas[0].reports[podReport], want: podReport, wantOK: true},
+		{name: "generator report", columns: withPeriodColumns(capacityChangeReport{}.Columns()...), want: capacityChangeReport{}.Name(), wantOK: true},
+		{name: "unknown columns", columns: []string{"a", "b"}},
+	}
+	for _, tt := range reportNameTests {
+		got, ok := ReportName(tt.columns)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s got (%q, %t) want (%q, %t)", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if !IsPeriodColumn("interval_start") || IsPeriodColumn("pod") {
+		t.Errorf("IsPeriodColumn does not match the period columns %v", reportPeriodColumns)
+	}
+}
+
+func TestUpgradeReportFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schema")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	v1 := strings.Join(reportSchemas[0].reports[nodeReport], ",")
+	v2 := strings.Join(reportColumns(nodeReport), ",")
+	upgradeTests := []struct {
+		name        string
+		contents    string
+		wantVersion string
+		want        string
+	}{
+		{
+			name:        "older schema is upgraded",
+			contents:    v1 + "\ns,e,is,ie,node1,label_a:b\n",
+			wantVersion: "1",
+			want:        v2 + "\ns,e,is,ie,node1,label_a:b,\n",
+		},
+		{
+			name:        "current schema is not changed",
+			contents:    v2 + "\ns,e,is,ie,node1,label_a:b,annotation_c:d\n",
+			wantVersion: ReportSchemaVersion,
+			want:        v2 + "\ns,e,is,ie,node1,label_a:b,annotation_c:d\n",
+		},
+		{
+			name:        "unknown columns are not changed",
+			contents:    "a,b\n1,2\n",
+			wantVersion: "",
+			want:        "a,b\n1,2\n",
+		},
+		{
+			name:        "empty file",
+			contents:    "",
+			wantVersion: "",
+			want:        "",
+		},
+	}
+	for i, tt := range upgradeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strconv.Itoa(i)+".csv")
+			if err := ioutil.WriteFile(path, []byte(tt.contents), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			version, err := UpgradeReportFile(path)
+			if err != nil {
+				t.Fatalf("%s got unexpected error: %v", tt.name, err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("%s got version %q want %q", tt.name, version, tt.wantVersion)
+			}
+			got, _ := ioutil.ReadFile(path)
+			if string(got) != tt.want {
+				t.Errorf("%s got contents %q want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteReportUpgradesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schema")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := "report.csv"
+	v1 := strings.Join(reportSchemas[0].reports[namespaceReport], ",")
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(v1+"\ns,e,is,ie,ns1,label_a:b\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	dates := &dateTimes{ReportPeriodStart: "s", ReportPeriodEnd: "e", IntervalStart: "is2", IntervalEnd: "ie2"}
+	row := newNamespaceRow(dates)
+	row.Namespace = "ns2"
+	rpt := report{
+		file: &file{name: name, path: dir},
+		data: &data{
+			queryData: mappedCSVStruct{"ns2": row},
+			headers:   row.csvHeader(),
+			prefix:    dates.string(),
+		},
+	}
+	if err := rpt.writeReport(); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	if !reflect.DeepEqual(rpt.upgradedFrom, reportSchemas[0].reports[namespaceReport]) {
+		t.Errorf("got upgraded from %v, want the version 1 columns", rpt.upgradedFrom)
+	}
+	got, _ := ioutil.ReadFile(filepath.Join(dir, name))
+	want := strings.Join(reportColumns(namespaceReport), ",") + "\ns,e,is,ie,ns1,label_a:b,\ns,e,is2,ie2,ns2,,\n"
+	if string(got) != want {
+		t.Errorf("got contents %q want %q", got, want)
+	}
+}
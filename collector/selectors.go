@@ -0,0 +1,168 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// selectorPattern matches a single label matcher, such as `cluster=""` or `tenant=~"a|b"`
+var selectorPattern = regexp.MustCompile(`^\s*[a-zA-Z_][a-zA-Z0-9_]*\s*(=~|!~|!=|=)\s*"(?:[^"\\]|\\.)*"\s*$`)
+
+// labelListKeywords are followed by a parenthesized list of label names instead of an expression
+var labelListKeywords = map[string]bool{
+	"by": true, "without": true, "on": true, "ignoring": true, "group_left": true, "group_right": true,
+}
+
+// nonMetricKeywords are the operators, aggregations, and literals that look like metric names
+var nonMetricKeywords = map[string]bool{
+	"and": true, "or": true, "unless": true, "bool": true, "offset": true, "inf": true, "nan": true,
+	"sum": true, "min": true, "max": true, "avg": true, "group": true, "stddev": true, "stdvar": true, "count": true,
+	"count_values": true, "bottomk": true, "topk": true, "quantile": true,
+}
+
+// validateSelectors returns an error for the first selector that is not a single label matcher
+func validateSelectors(selectors []string) error {
+	for _, selector := range selectors {
+		if !selectorPattern.MatchString(selector) {
+			return fmt.Errorf("invalid extra selector %q: expected a label matcher such as cluster=\"name\"", selector)
+		}
+	}
+	return nil
+}
+
+// addSelectors adds the label matchers to every vector selector of the query, so that a query sent to a
+// multi-cluster Thanos is scoped to a single cluster. Strings, durations, function names, and the label lists
+// of aggregations and vector matching are left untouched.
+func addSelectors(query string, selectors []string) string {
+	if len(selectors) == 0 {
+		return query
+	}
+	var trimmed []string
+	for _, selector := range selectors {
+		trimmed = append(trimmed, strings.TrimSpace(selector))
+	}
+	matchers := strings.Join(trimmed, ", ")
+
+	var b strings.Builder
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			end := skipString(query, i)
+			b.WriteString(query[i:end])
+			i = end
+		case c == '[':
+			end := skipUntil(query, i, ']')
+			b.WriteString(query[i:end])
+			i = end
+		case c == '{':
+			end := skipUntil(query, i, '}')
+			existing := strings.TrimSpace(query[i+1 : end-1])
+			if existing == "" {
+				b.WriteString("{" + matchers + "}")
+			} else {
+				b.WriteString("{" + existing + ", " + matchers + "}")
+			}
+			i = end
+		case isIdentifierStart(c):
+			end := i
+			for end < len(query) && isIdentifierChar(query[end]) {
+				end++
+			}
+			word := query[i:end]
+			b.WriteString(word)
+			i = end
+			next := nextNonSpace(query, i)
+			keyword := strings.ToLower(word)
+			switch {
+			case labelListKeywords[keyword]:
+				if next < len(query) && query[next] == '(' {
+					end = skipUntil(query, next, ')')
+					b.WriteString(query[i:end])
+					i = end
+				}
+			case nonMetricKeywords[keyword]:
+			case next < len(query) && (query[next] == '(' || query[next] == '{'):
+				// a function call, or a metric name that is followed by its own matchers
+			default:
+				b.WriteString("{" + matchers + "}")
+			}
+		case c >= '0' && c <= '9' || c == '.':
+			// numbers and durations, such as 1e3, 0x1f, and 5m, are not metric names
+			end := i
+			for end < len(query) && (isIdentifierChar(query[end]) || query[end] == '.') {
+				end++
+			}
+			b.WriteString(query[i:end])
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isIdentifierStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':'
+}
+
+func isIdentifierChar(c byte) bool {
+	return isIdentifierStart(c) || c >= '0' && c <= '9'
+}
+
+func nextNonSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// skipString returns the index after the string literal that starts at i
+func skipString(s string, i int) int {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\' && quote != '`':
+			j++
+		case s[j] == quote:
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// skipUntil returns the index after the first closing character that follows i and is not inside a string literal
+func skipUntil(s string, i int, closing byte) int {
+	for j := i + 1; j < len(s); {
+		switch s[j] {
+		case closing:
+			return j + 1
+		case '"', '\'', '`':
+			j = skipString(s, j)
+		default:
+			j++
+		}
+	}
+	return len(s)
+}
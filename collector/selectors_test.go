@@ -0,0 +1,91 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"testing"
+)
+
+func TestAddSelectors(t *testing.T) {
+	selectors := []string{`cluster="prod"`, ` tenant=~"a|b" `}
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "bare metric",
+			query: "kube_node_labels",
+			want:  `kube_node_labels{cluster="prod", tenant=~"a|b"}`,
+		},
+		{
+			name:  "existing matchers and range",
+			query: "sum(rate(container_cpu_usage_seconds_total{container!='POD',container!='',pod!=''}[5m])) BY (pod, namespace, node)",
+			want:  `sum(rate(container_cpu_usage_seconds_total{container!='POD',container!='',pod!='', cluster="prod", tenant=~"a|b"}[5m])) BY (pod, namespace, node)`,
+		},
+		{
+			name:  "vector matching",
+			query: "kube_node_status_capacity_cpu_cores * on(node) group_left(provider_id) max(kube_node_info) by (node, provider_id)",
+			want:  `kube_node_status_capacity_cpu_cores{cluster="prod", tenant=~"a|b"} * on(node) group_left(provider_id) max(kube_node_info{cluster="prod", tenant=~"a|b"}) by (node, provider_id)`,
+		},
+		{
+			name:  "aggregation before its expression",
+			query: "sum by (namespace) (kube_pod_info offset 1h) > bool 0",
+			want:  `sum by (namespace) (kube_pod_info{cluster="prod", tenant=~"a|b"} offset 1h) > bool 0`,
+		},
+		{
+			name:  "strings and empty matchers",
+			query: `label_replace(up{}, "dst", "$1 up", "src", "(.*)")`,
+			want:  `label_replace(up{cluster="prod", tenant=~"a|b"}, "dst", "$1 up", "src", "(.*)")`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addSelectors(tt.query, selectors); got != tt.want {
+				t.Errorf("%s got %s want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+	if got := addSelectors("kube_node_labels", nil); got != "kube_node_labels" {
+		t.Errorf("without selectors got %s want kube_node_labels", got)
+	}
+}
+
+func TestValidateSelectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []string
+		wantErr   bool
+	}{
+		{name: "no selectors"},
+		{name: "valid selectors", selectors: []string{`cluster=""`, `tenant!~"a|\"b\""`}},
+		{name: "missing quotes", selectors: []string{`cluster=prod`}, wantErr: true},
+		{name: "several matchers", selectors: []string{`cluster="a", tenant="b"`}, wantErr: true},
+		{name: "invalid label", selectors: []string{`1cluster="a"`}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSelectors(tt.selectors)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// shard is one endpoint of a sharded prometheus
+type shard struct {
+	address     string
+	conn        PrometheusConnection
+	err         error
+	lastSuccess metav1.Time
+}
+
+// shardedConnection queries a federating querier, such as Thanos Query or a Prometheus federation endpoint, that
+// evaluates each query across every shard. Queries cannot be evaluated on each shard separately, since joins and
+// aggregations would miss the series held by the other shards. The shards are checked when the connection is tested,
+// and the test fails if any shard fails, so that a window is never collected while part of the cluster is unavailable.
+// The first shard is the querier.
+type shardedConnection struct {
+	mu     sync.Mutex
+	shards []*shard
+}
+
+// newShardedConnection connects to the querier at the address of the configuration and to each shard address
+func newShardedConnection(cfg *PrometheusConfig, addresses []string) (PrometheusConnection, error) {
+	sc := &shardedConnection{}
+	for _, address := range append([]string{cfg.Address}, addresses...) {
+		shardCfg := *cfg
+		shardCfg.Address = address
+		conn, err := getPrometheusConnFromCfg(&shardCfg)
+		if err != nil {
+			return nil, fmt.Errorf("newShardedConnection: %s: %v", address, err)
+		}
+		sc.shards = append(sc.shards, &shard{address: address, conn: conn})
+	}
+	return sc, nil
+}
+
+// queryShards runs the query against the shards in parallel and records the health of each shard
+func (sc *shardedConnection) queryShards(shards []*shard, query func(PrometheusConnection) (model.Value, promv1.Warnings, error)) ([]model.Value, promv1.Warnings, error) {
+	values := make([]model.Value, len(shards))
+	warnings := make([]promv1.Warnings, len(shards))
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, s := range shards {
+		wg.Add(1)
+		go func(i int, s *shard) {
+			defer wg.Done()
+			values[i], warnings[i], errs[i] = query(s.conn)
+		}(i, s)
+	}
+	wg.Wait()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	var allWarnings promv1.Warnings
+	var firstErr error
+	for i, s := range shards {
+		s.err = errs[i]
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("shard %s: %v", s.address, errs[i])
+			}
+			continue
+		}
+		s.lastSuccess = metav1.Now()
+		allWarnings = append(allWarnings, warnings[i]...)
+	}
+	return values, allWarnings, firstErr
+}
+
+// QueryRange runs the range query against the querier
+func (sc *shardedConnection) QueryRange(ctx context.Context, query string, r promv1.Range) (model.Value, promv1.Warnings, error) {
+	values, warnings, err := sc.queryShards(sc.shards[:1], func(conn PrometheusConnection) (model.Value, promv1.Warnings, error) {
+		return conn.QueryRange(ctx, query, r)
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+	return values[0], warnings, nil
+}
+
+// Query runs the instant query against the querier and every shard, and returns the result of the querier. The query
+// fails if any shard fails.
+func (sc *shardedConnection) Query(ctx context.Context, query string, ts time.Time) (model.Value, promv1.Warnings, error) {
+	values, warnings, err := sc.queryShards(sc.shards, func(conn PrometheusConnection) (model.Value, promv1.Warnings, error) {
+		return conn.Query(ctx, query, ts)
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+	return values[0], warnings, nil
+}
+
+// status returns the health of each shard
+func (sc *shardedConnection) status() []kokumetricscfgv1beta1.PrometheusEndpointStatus {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	endpoints := []kokumetricscfgv1beta1.PrometheusEndpointStatus{}
+	for _, s := range sc.shards {
+		endpoint := kokumetricscfgv1beta1.PrometheusEndpointStatus{
+			Address:              s.address,
+			Connected:            s.err == nil && !s.lastSuccess.IsZero(),
+			LastQuerySuccessTime: s.lastSuccess,
+		}
+		if s.err != nil {
+			endpoint.Error = s.err.Error()
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// SetEndpointStatus reflects the health of each endpoint of a sharded prometheus in the status
+func (c *PromCollector) SetEndpointStatus(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	sc, ok := c.PromConn.(*shardedConnection)
+	if !ok {
+		kmCfg.Status.Prometheus.Endpoints = nil
+		return
+	}
+	kmCfg.Status.Prometheus.Endpoints = sc.status()
+}
@@ -0,0 +1,122 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func stream(node string, samples ...int64) *model.SampleStream {
+	s := &model.SampleStream{Metric: model.Metric{"node": model.LabelValue(node)}}
+	for _, ts := range samples {
+		s.Values = append(s.Values, model.SamplePair{Timestamp: model.Time(ts), Value: model.SampleValue(ts)})
+	}
+	return s
+}
+
+func TestShardedConnection(t *testing.T) {
+	shardResult := func(value model.Value, err error) *shard {
+		return &shard{address: "shard", conn: mockPrometheusConnection{singleResult: &mockPromResult{value: value, err: err}, t: t}}
+	}
+	querier := model.Matrix{stream("node1", 1), stream("node2", 1)}
+	shardedTests := []struct {
+		name          string
+		shards        []*shard
+		instant       bool
+		wantSeries    int
+		wantErr       bool
+		wantConnected []bool
+	}{
+		{
+			name:          "range queries are evaluated by the querier only",
+			shards:        []*shard{shardResult(querier, nil), shardResult(nil, errors.New("unavailable"))},
+			wantSeries:    2,
+			wantConnected: []bool{true, false},
+		},
+		{
+			name:          "a failing querier fails the range query",
+			shards:        []*shard{shardResult(nil, errors.New("unavailable")), shardResult(model.Matrix{stream("node1", 1)}, nil)},
+			wantErr:       true,
+			wantConnected: []bool{false, false},
+		},
+		{
+			name:          "instant queries check every shard",
+			shards:        []*shard{shardResult(querier, nil), shardResult(model.Matrix{stream("node1", 1)}, nil)},
+			instant:       true,
+			wantSeries:    2,
+			wantConnected: []bool{true, true},
+		},
+		{
+			name:          "a failing shard fails the instant query",
+			shards:        []*shard{shardResult(querier, nil), shardResult(nil, errors.New("unavailable"))},
+			instant:       true,
+			wantErr:       true,
+			wantConnected: []bool{true, false},
+		},
+	}
+	for _, tt := range shardedTests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &shardedConnection{shards: tt.shards}
+			var value model.Value
+			var err error
+			if tt.instant {
+				value, _, err = sc.Query(context.Background(), "up", time.Now())
+			} else {
+				value, _, err = sc.QueryRange(context.Background(), "query", promv1.Range{})
+			}
+			if err != nil != tt.wantErr {
+				t.Fatalf("%s got error %v want error %v", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && len(value.(model.Matrix)) != tt.wantSeries {
+				t.Errorf("%s got %d series want %d", tt.name, len(value.(model.Matrix)), tt.wantSeries)
+			}
+
+			col := &PromCollector{PromConn: sc}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			col.SetEndpointStatus(kmCfg)
+			if len(kmCfg.Status.Prometheus.Endpoints) != len(tt.wantConnected) {
+				t.Fatalf("%s got endpoints %+v", tt.name, kmCfg.Status.Prometheus.Endpoints)
+			}
+			for i, endpoint := range kmCfg.Status.Prometheus.Endpoints {
+				if endpoint.Connected != tt.wantConnected[i] {
+					t.Errorf("%s got endpoint %+v want connected %v", tt.name, endpoint, tt.wantConnected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSetEndpointStatusUnsharded(t *testing.T) {
+	col := &PromCollector{PromConn: mockPrometheusConnection{}}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.Prometheus.Endpoints = []kokumetricscfgv1beta1.PrometheusEndpointStatus{{Address: "old"}}
+	col.SetEndpointStatus(kmCfg)
+	if kmCfg.Status.Prometheus.Endpoints != nil {
+		t.Errorf("expected no endpoints without shards, got %+v", kmCfg.Status.Prometheus.Endpoints)
+	}
+}
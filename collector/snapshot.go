@@ -0,0 +1,48 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import "fmt"
+
+// SnapshotCollector returns a copy of the collector that queries the prometheus at address, such as one restored
+// from a snapshot or a backup, with the token and CA of the configured prometheus. The connection of the collector
+// is not modified, so that the regular collection continues to query the service address.
+func (c *PromCollector) SnapshotCollector(address string, skipTLS bool) (*PromCollector, error) {
+	if c.PromCfg == nil {
+		return nil, fmt.Errorf("SnapshotCollector: the prometheus configuration is not loaded")
+	}
+	cfg := *c.PromCfg
+	cfg.Address = address
+	cfg.SkipTLS = skipTLS
+	conn, err := newPrometheusConnection(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("SnapshotCollector: %s: %v", address, err)
+	}
+	if err := testPrometheusConnection(conn); err != nil {
+		return nil, fmt.Errorf("SnapshotCollector: %s: prometheus test query failed: %v", address, err)
+	}
+	snapshot := *c
+	snapshot.PromCfg = &cfg
+	snapshot.PromConn = conn
+	snapshot.ServingAddress = address
+	snapshot.onFallback = false
+	snapshot.cache = queryCache{}
+	return &snapshot, nil
+}
@@ -0,0 +1,58 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import "testing"
+
+func TestSnapshotCollector(t *testing.T) {
+	tmpNew := newPrometheusConnection
+	defer func() { newPrometheusConnection = tmpNew }()
+	var gotCfg PrometheusConfig
+	newPrometheusConnection = func(cfg *PrometheusConfig) (PrometheusConnection, error) {
+		gotCfg = *cfg
+		return &mockPrometheusConnection{singleResult: &mockPromResult{}}, nil
+	}
+
+	if _, err := (&PromCollector{Log: testLogger}).SnapshotCollector("https://snapshot", false); err == nil {
+		t.Error("expected error without a prometheus configuration but got nil")
+	}
+
+	live := &mockPrometheusConnection{}
+	col := &PromCollector{
+		Log:            testLogger,
+		PromCfg:        &PrometheusConfig{Address: "https://thanos-querier", BearerToken: "token", CAFile: "ca.crt"},
+		PromConn:       live,
+		ServingAddress: "https://thanos-querier",
+		PeriodStartDay: 15,
+	}
+	snapshot, err := col.SnapshotCollector("https://snapshot", true)
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if gotCfg.Address != "https://snapshot" || !gotCfg.SkipTLS || gotCfg.BearerToken != "token" || gotCfg.CAFile != "ca.crt" {
+		t.Errorf("got config %+v want the snapshot address with the token and CA of the service address", gotCfg)
+	}
+	if snapshot.ServingAddress != "https://snapshot" || snapshot.PromConn == PrometheusConnection(live) || snapshot.PeriodStartDay != 15 {
+		t.Errorf("got snapshot collector serving %s with period start day %d", snapshot.ServingAddress, snapshot.PeriodStartDay)
+	}
+	if col.ServingAddress != "https://thanos-querier" || col.PromConn != PrometheusConnection(live) || col.PromCfg.Address != "https://thanos-querier" {
+		t.Errorf("the collector was modified: serving %s with config %+v", col.ServingAddress, col.PromCfg)
+	}
+}
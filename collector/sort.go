@@ -0,0 +1,219 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// sortMemoryLimit is the size of the rows that are sorted in memory. Larger reports are sorted in runs of this
+// size that are merged from disk.
+var sortMemoryLimit int64 = 64 * 1024 * 1024
+
+// SortReportFile sorts the rows of the report at path, keeping the header first, so that the same rows always
+// produce the same file no matter the order in which they were collected. Reports larger than the memory limit
+// are sorted with an external merge. The file is not rewritten if the rows are already sorted.
+func SortReportFile(path string) error {
+	return sortReportFile(path, sortMemoryLimit)
+}
+
+func sortReportFile(path string, limit int64) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("SortReportFile: failed to open report: %v", err)
+	}
+	defer in.Close()
+
+	reader := bufio.NewReader(in)
+	header, err := readLine(reader)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("SortReportFile: failed to read header: %v", err)
+	}
+
+	var runs []string
+	defer func() {
+		for _, run := range runs {
+			os.Remove(run)
+		}
+	}()
+	var lines []string
+	var size int64
+	ordered := true
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("SortReportFile: failed to read row: %v", err)
+		}
+		if len(lines) > 0 && line < lines[len(lines)-1] {
+			ordered = false
+		}
+		lines = append(lines, line)
+		size += int64(len(line))
+		if size >= limit {
+			run, err := writeRun(path, lines)
+			if err != nil {
+				return fmt.Errorf("SortReportFile: %v", err)
+			}
+			runs = append(runs, run)
+			lines, size = nil, 0
+		}
+	}
+	if ordered && len(runs) == 0 {
+		return nil
+	}
+
+	tmp := path + ".sorted"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("SortReportFile: failed to create sorted report: %v", err)
+	}
+	defer os.Remove(tmp)
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	if _, err := w.WriteString(header); err != nil {
+		return fmt.Errorf("SortReportFile: failed to write header: %v", err)
+	}
+	if len(runs) == 0 {
+		sort.Strings(lines)
+		for _, line := range lines {
+			if _, err := w.WriteString(line); err != nil {
+				return fmt.Errorf("SortReportFile: failed to write row: %v", err)
+			}
+		}
+	} else {
+		if len(lines) > 0 {
+			run, err := writeRun(path, lines)
+			if err != nil {
+				return fmt.Errorf("SortReportFile: %v", err)
+			}
+			runs = append(runs, run)
+		}
+		if err := mergeRuns(runs, w); err != nil {
+			return fmt.Errorf("SortReportFile: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("SortReportFile: failed to write sorted report: %v", err)
+	}
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("SortReportFile: failed to write sorted report: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("SortReportFile: failed to replace report: %v", err)
+	}
+	return nil
+}
+
+// readLine returns the next line of the reader, ending in a newline
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		return line + "\n", nil
+	}
+	return line, err
+}
+
+// writeRun sorts the lines and writes them to a temporary file next to the report
+func writeRun(path string, lines []string) (string, error) {
+	sort.Strings(lines)
+	run, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".run")
+	if err != nil {
+		return "", fmt.Errorf("writeRun: failed to create run: %v", err)
+	}
+	defer run.Close()
+	w := bufio.NewWriter(run)
+	for _, line := range lines {
+		if _, err := w.WriteString(line); err != nil {
+			return run.Name(), fmt.Errorf("writeRun: failed to write run: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return run.Name(), fmt.Errorf("writeRun: failed to write run: %v", err)
+	}
+	return run.Name(), nil
+}
+
+// runHead is the next line of a sorted run
+type runHead struct {
+	line   string
+	reader *bufio.Reader
+}
+
+// runHeap orders the runs by their next line
+type runHeap []runHead
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return h[i].line < h[j].line }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(runHead)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return head
+}
+
+// mergeRuns writes the lines of the sorted runs to w in order
+func mergeRuns(runs []string, w io.StringWriter) error {
+	h := &runHeap{}
+	for _, run := range runs {
+		f, err := os.Open(run)
+		if err != nil {
+			return fmt.Errorf("mergeRuns: failed to open run: %v", err)
+		}
+		defer f.Close()
+		reader := bufio.NewReader(f)
+		line, err := readLine(reader)
+		if err == io.EOF {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("mergeRuns: failed to read run: %v", err)
+		}
+		*h = append(*h, runHead{line: line, reader: reader})
+	}
+	heap.Init(h)
+	for h.Len() > 0 {
+		head := (*h)[0]
+		if _, err := w.WriteString(head.line); err != nil {
+			return fmt.Errorf("mergeRuns: failed to write row: %v", err)
+		}
+		line, err := readLine(head.reader)
+		if err == io.EOF {
+			heap.Pop(h)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("mergeRuns: failed to read run: %v", err)
+		}
+		(*h)[0].line = line
+		heap.Fix(h, 0)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSortReportFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sort")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		contents string
+		limit    int64
+		want     string
+	}{
+		{
+			name:     "empty file",
+			contents: "",
+			limit:    1024,
+			want:     "",
+		},
+		{
+			name:     "header only",
+			contents: "b,a\n",
+			limit:    1024,
+			want:     "b,a\n",
+		},
+		{
+			name:     "sorted in memory",
+			contents: "h1,h2\nc,3\na,1\nb,2\n",
+			limit:    1024,
+			want:     "h1,h2\na,1\nb,2\nc,3\n",
+		},
+		{
+			name:     "missing trailing newline",
+			contents: "h1,h2\nc,3\na,1",
+			limit:    1024,
+			want:     "h1,h2\na,1\nc,3\n",
+		},
+		{
+			name:     "external merge",
+			contents: "h1,h2\ne,5\nc,3\ng,7\na,1\nf,6\nb,2\nd,4\n",
+			limit:    8,
+			want:     "h1,h2\na,1\nb,2\nc,3\nd,4\ne,5\nf,6\ng,7\n",
+		},
+		{
+			name:     "external merge with duplicate rows",
+			contents: "h1,h2\nb,2\na,1\nb,2\na,1\n",
+			limit:    4,
+			want:     "h1,h2\na,1\na,1\nb,2\nb,2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "report.csv")
+			if err := ioutil.WriteFile(path, []byte(tt.contents), 0644); err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+			if err := sortReportFile(path, tt.limit); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read report: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q want %q", got, tt.want)
+			}
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("failed to read dir: %v", err)
+			}
+			if len(files) != 1 {
+				t.Errorf("expected temporary files to be removed, got %d files", len(files))
+			}
+		})
+	}
+}
+
+func TestSortReportFileAlreadySorted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sort")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "report.csv")
+	if err := ioutil.WriteFile(path, []byte("h1,h2\na,1\nb,2\n"), 0644); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	modTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mod time: %v", err)
+	}
+	if err := SortReportFile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat report: %v", err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("expected a sorted report not to be rewritten")
+	}
+}
+
+func TestSortReportFileMissing(t *testing.T) {
+	if err := SortReportFile(filepath.Join(os.TempDir(), "nonexistent", "report.csv")); err == nil {
+		t.Errorf("expected an error for a missing report")
+	}
+}
@@ -0,0 +1,172 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// invalidLabelChars matches the characters kube-state-metrics replaces when exposing labels
+	invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	// validTenant matches valid label values, which are safe to use as directory names
+	validTenant = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+)
+
+// reportSet is the rows of each report for a single report directory. The custom report is only written when
+// custom metrics were queried.
+type reportSet struct {
+	node      mappedCSVStruct
+	pod       mappedCSVStruct
+	storage   mappedCSVStruct
+	namespace mappedCSVStruct
+	custom    mappedCSVStruct
+	generated []generatedReport
+}
+
+// ksmLabelKey returns the name of a label as kube-state-metrics exposes it, and as it appears in the labels columns
+func ksmLabelKey(label string) string {
+	return "label_" + invalidLabelChars.ReplaceAllString(label, "_")
+}
+
+// namespaceTenant returns the value of the tenant label in the namespace labels, or "" if the label is not set
+func namespaceTenant(labels, key string) string {
+	for _, label := range strings.Split(labels, "|") {
+		if strings.HasPrefix(label, key+":") {
+			tenant := strings.TrimPrefix(label, key+":")
+			if validTenant.MatchString(tenant) {
+				return tenant
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// rowNamespace returns the namespace of a pod, storage, or namespace row
+func rowNamespace(row csvStruct) string {
+	switch r := row.(type) {
+	case *podRow:
+		return r.Namespace
+	case *storageRow:
+		return r.Namespace
+	case *namespaceRow:
+		return r.Namespace
+	case *customMetricRow:
+		return r.Namespace
+	case *generatedRow:
+		if i := namespaceColumn(r.columns); i >= 0 && i < len(r.values) {
+			return r.values[i]
+		}
+	}
+	return ""
+}
+
+// partitionReports splits the rows of namespaces with the tenant label into a report set per tenant. The set for the
+// cluster has the key "". Node rows are included in every set so that cost can be distributed within each tenant.
+// Tenants without any pod rows are reported with the cluster, since the pod report determines the report interval.
+// The rows of generated reports with a namespace column are split the same way, and every tenant set has these
+// reports. Generated reports without a namespace column are only in the set for the cluster.
+func partitionReports(label string, all reportSet) map[string]reportSet {
+	if label == "" {
+		return map[string]reportSet{"": all}
+	}
+	key := ksmLabelKey(label)
+	tenants := map[string]string{}
+	for _, row := range all.namespace {
+		r := row.(*namespaceRow)
+		if tenant := namespaceTenant(r.NamespaceLabels, key); tenant != "" {
+			tenants[r.Namespace] = tenant
+		}
+	}
+	hasPods := map[string]bool{}
+	for _, row := range all.pod {
+		if tenant, ok := tenants[rowNamespace(row)]; ok {
+			hasPods[tenant] = true
+		}
+	}
+
+	sets := map[string]reportSet{}
+	getSet := func(tenant string) reportSet {
+		set, ok := sets[tenant]
+		if !ok {
+			set = reportSet{node: all.node, pod: mappedCSVStruct{}, storage: mappedCSVStruct{}, namespace: mappedCSVStruct{}}
+			if all.custom != nil {
+				set.custom = mappedCSVStruct{}
+			}
+			sets[tenant] = set
+		}
+		return set
+	}
+	getSet("")
+	assign := func(rows mappedCSVStruct, reportOf func(reportSet) mappedCSVStruct) {
+		for k, row := range rows {
+			tenant := tenants[rowNamespace(row)]
+			if !hasPods[tenant] {
+				tenant = ""
+			}
+			reportOf(getSet(tenant))[k] = row
+		}
+	}
+	assign(all.pod, func(s reportSet) mappedCSVStruct { return s.pod })
+	assign(all.storage, func(s reportSet) mappedCSVStruct { return s.storage })
+	assign(all.namespace, func(s reportSet) mappedCSVStruct { return s.namespace })
+	assign(all.custom, func(s reportSet) mappedCSVStruct { return s.custom })
+
+	for _, r := range all.generated {
+		if namespaceColumn(r.generator.Columns()) < 0 {
+			set := sets[""]
+			set.generated = append(set.generated, r)
+			sets[""] = set
+			continue
+		}
+		rowsOf := map[string]mappedCSVStruct{}
+		for tenant := range sets {
+			rowsOf[tenant] = mappedCSVStruct{}
+		}
+		for k, row := range r.rows {
+			tenant := tenants[rowNamespace(row)]
+			if !hasPods[tenant] {
+				tenant = ""
+			}
+			rowsOf[tenant][k] = row
+		}
+		for tenant, rows := range rowsOf {
+			set := sets[tenant]
+			set.generated = append(set.generated, generatedReport{generator: r.generator, rows: rows, duration: r.duration})
+			sets[tenant] = set
+		}
+	}
+	return sets
+}
+
+// tenantNames returns the sorted tenants of the report sets
+func tenantNames(sets map[string]reportSet) []string {
+	var names []string
+	for tenant := range sets {
+		if tenant != "" {
+			names = append(names, tenant)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
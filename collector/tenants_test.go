@@ -0,0 +1,190 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKSMLabelKey(t *testing.T) {
+	tests := []struct {
+		label string
+		want  string
+	}{
+		{label: "tenant", want: "label_tenant"},
+		{label: "cost-center", want: "label_cost_center"},
+		{label: "example.com/team", want: "label_example_com_team"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got := ksmLabelKey(tt.label); got != tt.want {
+				t.Errorf("ksmLabelKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceTenant(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels string
+		want   string
+	}{
+		{name: "label set", labels: "label_app:web|label_tenant:team-a", want: "team-a"},
+		{name: "label not set", labels: "label_app:web", want: ""},
+		{name: "empty labels", labels: "", want: ""},
+		{name: "prefix of another label", labels: "label_tenant_id:team-b", want: ""},
+		{name: "invalid value", labels: "label_tenant:../team", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := namespaceTenant(tt.labels, "label_tenant"); got != tt.want {
+				t.Errorf("namespaceTenant() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartitionReports(t *testing.T) {
+	all := reportSet{
+		node: mappedCSVStruct{"node1": &nodeRow{Node: "node1"}},
+		pod: mappedCSVStruct{
+			"pod-a":       &podRow{Namespace: "ns-a", Pod: "pod-a"},
+			"pod-b":       &podRow{Namespace: "ns-b", Pod: "pod-b"},
+			"pod-default": &podRow{Namespace: "default", Pod: "pod-default"},
+		},
+		storage: mappedCSVStruct{
+			"pvc-a": &storageRow{Namespace: "ns-a", PersistentVolumeClaim: "pvc-a"},
+			"pvc-c": &storageRow{Namespace: "ns-c", PersistentVolumeClaim: "pvc-c"},
+		},
+		namespace: mappedCSVStruct{
+			"ns-a":    &namespaceRow{Namespace: "ns-a", NamespaceLabels: "label_tenant:team-a"},
+			"ns-b":    &namespaceRow{Namespace: "ns-b", NamespaceLabels: "label_tenant:team-b"},
+			"ns-c":    &namespaceRow{Namespace: "ns-c", NamespaceLabels: "label_tenant:team-c"},
+			"default": &namespaceRow{Namespace: "default", NamespaceLabels: ""},
+		},
+	}
+
+	t.Run("no tenant label", func(t *testing.T) {
+		got := partitionReports("", all)
+		if len(got) != 1 || !reflect.DeepEqual(got[""], all) {
+			t.Errorf("partitionReports() = %v, want only the cluster set", got)
+		}
+	})
+
+	t.Run("tenant label", func(t *testing.T) {
+		got := partitionReports("tenant", all)
+		if names := tenantNames(got); !reflect.DeepEqual(names, []string{"team-a", "team-b"}) {
+			t.Fatalf("tenantNames() = %v, want [team-a team-b]", names)
+		}
+		wantKeys := map[string]reportSet{
+			"": {
+				pod:       mappedCSVStruct{"pod-default": nil},
+				storage:   mappedCSVStruct{"pvc-c": nil},
+				namespace: mappedCSVStruct{"ns-c": nil, "default": nil},
+			},
+			"team-a": {
+				pod:       mappedCSVStruct{"pod-a": nil},
+				storage:   mappedCSVStruct{"pvc-a": nil},
+				namespace: mappedCSVStruct{"ns-a": nil},
+			},
+			"team-b": {
+				pod:       mappedCSVStruct{"pod-b": nil},
+				storage:   mappedCSVStruct{},
+				namespace: mappedCSVStruct{"ns-b": nil},
+			},
+		}
+		for tenant, want := range wantKeys {
+			set := got[tenant]
+			if !reflect.DeepEqual(set.node, all.node) {
+				t.Errorf("%q node rows = %v, want all node rows", tenant, set.node)
+			}
+			for report, pair := range map[string][2]mappedCSVStruct{
+				"pod":       {set.pod, want.pod},
+				"storage":   {set.storage, want.storage},
+				"namespace": {set.namespace, want.namespace},
+			} {
+				if len(pair[0]) != len(pair[1]) {
+					t.Errorf("%q %s rows = %v, want %v", tenant, report, pair[0], pair[1])
+				}
+				for k := range pair[1] {
+					if _, ok := pair[0][k]; !ok {
+						t.Errorf("%q %s rows missing %q", tenant, report, k)
+					}
+				}
+			}
+		}
+	})
+}
+
+func TestPartitionGeneratedReports(t *testing.T) {
+	autoscalerRow := func(namespace, name string) *generatedRow {
+		return &generatedRow{
+			columns: autoscalerReport{}.Columns(),
+			values:  []string{namespace, horizontalPodAutoscalerKind, name, "Deployment", name, "", "min_replicas", "1"},
+		}
+	}
+	all := reportSet{
+		node: mappedCSVStruct{"node1": &nodeRow{Node: "node1"}},
+		pod: mappedCSVStruct{
+			"pod-a":       &podRow{Namespace: "ns-a", Pod: "pod-a"},
+			"pod-default": &podRow{Namespace: "default", Pod: "pod-default"},
+		},
+		storage: mappedCSVStruct{},
+		namespace: mappedCSVStruct{
+			"ns-a":    &namespaceRow{Namespace: "ns-a", NamespaceLabels: "label_tenant:team-a"},
+			"default": &namespaceRow{Namespace: "default"},
+		},
+		generated: []generatedReport{
+			{generator: autoscalerReport{}, rows: mappedCSVStruct{
+				"hpa-a":       autoscalerRow("ns-a", "web"),
+				"hpa-default": autoscalerRow("default", "api"),
+			}},
+			{generator: capacityChangeReport{}, rows: mappedCSVStruct{"node1": &generatedRow{columns: capacityChangeReport{}.Columns()}}},
+		},
+	}
+
+	got := partitionReports("tenant", all)
+	wantKeys := map[string]map[string][]string{
+		"":       {"autoscaler": {"hpa-default"}, "node-capacity-change": {"node1"}},
+		"team-a": {"autoscaler": {"hpa-a"}},
+	}
+	for tenant, want := range wantKeys {
+		reports := map[string]mappedCSVStruct{}
+		for _, r := range got[tenant].generated {
+			reports[r.generator.Name()] = r.rows
+		}
+		if len(reports) != len(want) {
+			t.Errorf("%q got generated reports %v want %v", tenant, reports, want)
+		}
+		for name, keys := range want {
+			rows := reports[name]
+			if len(rows) != len(keys) {
+				t.Errorf("%q %s rows = %v, want %v", tenant, name, rows, keys)
+			}
+			for _, k := range keys {
+				if _, ok := rows[k]; !ok {
+					t.Errorf("%q %s rows missing %q", tenant, name, k)
+				}
+			}
+		}
+	}
+}
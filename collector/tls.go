@@ -0,0 +1,74 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/common/config"
+
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+)
+
+// newPrometheusRoundTripper returns the round tripper that authenticates to prometheus with the bearer token. Without
+// TLS settings, the round tripper of the prometheus client is used, which reloads the CA file when it changes. The
+// prometheus client cannot set the TLS version or cipher suites, so with TLS settings the transport is built here.
+func newPrometheusRoundTripper(cfg *PrometheusConfig) (http.RoundTripper, error) {
+	tlsConfig := config.TLSConfig{CAFile: cfg.CAFile, InsecureSkipVerify: cfg.SkipTLS}
+	if cfg.TLS.IsZero() {
+		promconf := config.HTTPClientConfig{BearerToken: cfg.BearerToken, TLSConfig: tlsConfig}
+		return config.NewRoundTripperFromConfig(promconf, "promconf", false, false)
+	}
+	tlsClientConfig, err := config.NewTLSConfig(&tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	cfg.TLS.Apply(tlsClientConfig)
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSClientConfig:       tlsClientConfig,
+		DisableCompression:    true,
+		IdleConnTimeout:       5 * time.Minute,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	return config.NewBearerAuthRoundTripper(cfg.BearerToken, transport), nil
+}
+
+// tlsLoggingRoundTripper writes the negotiated TLS version of the first response of a connection to the debug log
+type tlsLoggingRoundTripper struct {
+	next http.RoundTripper
+	log  logr.Logger
+	once sync.Once
+}
+
+// RoundTrip sends the request with the next round tripper
+func (rt *tlsLoggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.next.RoundTrip(req)
+	if err == nil && resp.TLS != nil {
+		rt.once.Do(func() {
+			crhchttp.LogNegotiatedTLS(rt.log, req.URL.Host, resp.TLS)
+		})
+	}
+	return resp, err
+}
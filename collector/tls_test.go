@@ -0,0 +1,76 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+)
+
+func TestPrometheusRoundTripperTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	tlsTests := []struct {
+		name    string
+		tls     crhchttp.TLSSettings
+		wantErr bool
+	}{
+		{name: "default settings", tls: crhchttp.TLSSettings{}},
+		{name: "minimum version met", tls: crhchttp.TLSSettings{MinVersion: tls.VersionTLS12}},
+		{name: "minimum version not met", tls: crhchttp.TLSSettings{MinVersion: tls.VersionTLS13}, wantErr: true},
+		{
+			name: "cipher suite offered",
+			tls:  crhchttp.TLSSettings{CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}},
+		},
+	}
+	for _, tt := range tlsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, err := newPrometheusRoundTripper(&PrometheusConfig{BearerToken: "token", SkipTLS: true, TLS: tt.tls})
+			if err != nil {
+				t.Fatalf("%s failed to create round tripper: %v", tt.name, err)
+			}
+			resp, err := (&http.Client{Transport: rt}).Get(server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s got error %v want error %v", tt.name, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("%s got status %d want 200", tt.name, resp.StatusCode)
+			}
+			if resp.TLS.Version != tls.VersionTLS12 {
+				t.Errorf("%s got version %s want VersionTLS12", tt.name, crhchttp.TLSVersionName(resp.TLS.Version))
+			}
+		})
+	}
+}
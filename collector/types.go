@@ -33,13 +33,26 @@ type dateTimes struct {
 	IntervalEnd       string
 }
 
-func newDates(ts *promv1.Range) *dateTimes {
+// BillingPeriod returns the start and end of the billing period containing t. Billing periods start at midnight on
+// startDay of each month, in the location of t.
+func BillingPeriod(t time.Time, startDay int) (time.Time, time.Time) {
+	if startDay < 1 {
+		startDay = 1
+	}
+	start := time.Date(t.Year(), t.Month(), startDay, 0, 0, 0, 0, t.Location())
+	if t.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start, start.AddDate(0, 1, 0)
+}
+
+func newDates(ts *promv1.Range, startDay int) *dateTimes {
 	d := new(dateTimes)
 	d.IntervalStart = ts.Start.String()
 	d.IntervalEnd = ts.End.String()
-	t := ts.Start
-	d.ReportPeriodStart = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).String()
-	d.ReportPeriodEnd = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location()).String()
+	start, end := BillingPeriod(ts.Start, startDay)
+	d.ReportPeriodStart = start.String()
+	d.ReportPeriodEnd = end.String()
 	return d
 }
 
@@ -60,26 +73,19 @@ type csvStruct interface {
 	string() string
 }
 
-func newNamespaceRow(ts *promv1.Range) namespaceRow { return namespaceRow{dateTimes: newDates(ts)} }
-func newNodeRow(ts *promv1.Range) nodeRow           { return nodeRow{dateTimes: newDates(ts)} }
-func newPodRow(ts *promv1.Range) podRow             { return podRow{dateTimes: newDates(ts)} }
-func newStorageRow(ts *promv1.Range) storageRow     { return storageRow{dateTimes: newDates(ts)} }
+func newNamespaceRow(d *dateTimes) namespaceRow { return namespaceRow{dateTimes: d} }
+func newNodeRow(d *dateTimes) nodeRow           { return nodeRow{dateTimes: d} }
+func newPodRow(d *dateTimes) podRow             { return podRow{dateTimes: d} }
+func newStorageRow(d *dateTimes) storageRow     { return storageRow{dateTimes: d} }
 
 type namespaceRow struct {
 	*dateTimes
-	Namespace       string `mapstructure:"namespace"`
-	NamespaceLabels string `mapstructure:"namespace_labels"`
+	Namespace            string `mapstructure:"namespace"`
+	NamespaceLabels      string `mapstructure:"namespace_labels"`
+	NamespaceAnnotations string `mapstructure:"namespace_annotations"`
 }
 
-func (namespaceRow) csvHeader() []string {
-	return []string{
-		"report_period_start",
-		"report_period_end",
-		"interval_start",
-		"interval_end",
-		"namespace",
-		"namespace_labels"}
-}
+func (namespaceRow) csvHeader() []string { return reportColumns(namespaceReport) }
 
 func (row namespaceRow) csvRow() []string {
 	return []string{
@@ -89,6 +95,7 @@ func (row namespaceRow) csvRow() []string {
 		row.IntervalEnd,
 		row.Namespace,
 		row.NamespaceLabels,
+		row.NamespaceAnnotations,
 	}
 }
 
@@ -103,22 +110,10 @@ type nodeRow struct {
 	NodeCapacityMemoryByteSeconds string `mapstructure:"node-capacity-memory-byte-seconds"`
 	ResourceID                    string `mapstructure:"resource_id"`
 	NodeLabels                    string `mapstructure:"node_labels"`
+	NodeAnnotations               string `mapstructure:"node_annotations"`
 }
 
-func (nodeRow) csvHeader() []string {
-	return []string{
-		"report_period_start",
-		"report_period_end",
-		"interval_start",
-		"interval_end",
-		"node",
-		// "node_capacity_cpu_cores",  // if Node and Pod reports are ever separated, these lines can be uncommented
-		// "node_capacity_cpu_core_seconds",
-		// "node_capacity_memory_bytes",
-		// "node_capacity_memory_byte_seconds",
-		// "resource_id",
-		"node_labels"}
-}
+func (nodeRow) csvHeader() []string { return reportColumns(nodeReport) }
 
 func (row nodeRow) csvRow() []string {
 	return []string{
@@ -133,6 +128,7 @@ func (row nodeRow) csvRow() []string {
 		// row.NodeCapacityMemoryByteSeconds,
 		// row.ResourceID,
 		row.NodeLabels,
+		row.NodeAnnotations,
 	}
 }
 
@@ -150,30 +146,14 @@ type podRow struct {
 	PodRequestMemoryByteSeconds string `mapstructure:"pod-request-memory-byte-seconds"`
 	PodLimitMemoryByteSeconds   string `mapstructure:"pod-limit-memory-byte-seconds"`
 	PodLabels                   string `mapstructure:"pod_labels"`
+	PodAnnotations              string `mapstructure:"pod_annotations"`
+	WorkloadKind                string `mapstructure:"workload_kind"`
+	WorkloadName                string `mapstructure:"workload_name"`
+	QoSClass                    string `mapstructure:"qos_class"`
+	PriorityClass               string `mapstructure:"priority_class"`
 }
 
-func (podRow) csvHeader() []string {
-	return []string{
-		"report_period_start",
-		"report_period_end",
-		"interval_start",
-		"interval_end",
-		"node",
-		"namespace",
-		"pod",
-		"pod_usage_cpu_core_seconds",
-		"pod_request_cpu_core_seconds",
-		"pod_limit_cpu_core_seconds",
-		"pod_usage_memory_byte_seconds",
-		"pod_request_memory_byte_seconds",
-		"pod_limit_memory_byte_seconds",
-		"node_capacity_cpu_cores",
-		"node_capacity_cpu_core_seconds",
-		"node_capacity_memory_bytes",
-		"node_capacity_memory_byte_seconds",
-		"resource_id",
-		"pod_labels"}
-}
+func (podRow) csvHeader() []string { return reportColumns(podReport) }
 
 func (row podRow) csvRow() []string {
 	return []string{
@@ -196,6 +176,11 @@ func (row podRow) csvRow() []string {
 		row.NodeCapacityMemoryByteSeconds,
 		row.ResourceID,
 		row.PodLabels,
+		row.PodAnnotations,
+		row.WorkloadKind,
+		row.WorkloadName,
+		row.QoSClass,
+		row.PriorityClass,
 	}
 }
 
@@ -216,24 +201,7 @@ type storageRow struct {
 	PersistentVolumeClaimLabels              string `mapstructure:"persistentvolumeclaim_labels"`
 }
 
-func (storageRow) csvHeader() []string {
-	return []string{
-		"report_period_start",
-		"report_period_end",
-		"interval_start",
-		"interval_end",
-		"namespace",
-		"pod",
-		"persistentvolumeclaim",
-		"persistentvolume",
-		"storageclass",
-		"persistentvolumeclaim_capacity_bytes",
-		"persistentvolumeclaim_capacity_byte_seconds",
-		"volume_request_storage_byte_seconds",
-		"persistentvolumeclaim_usage_byte_seconds",
-		"persistentvolume_labels",
-		"persistentvolumeclaim_labels"}
-}
+func (storageRow) csvHeader() []string { return reportColumns(volumeReport) }
 
 func (row storageRow) csvRow() []string {
 	return []string{
@@ -0,0 +1,80 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	secondsPerHour = 3600
+	bytesPerGiB    = 1024 * 1024 * 1024
+)
+
+// NamespaceUsage is the cpu and memory consumed by a namespace
+type NamespaceUsage struct {
+	CPUUsageCoreHours     float64 `json:"cpu_usage_core_hours"`
+	CPURequestCoreHours   float64 `json:"cpu_request_core_hours"`
+	MemoryUsageGiBHours   float64 `json:"memory_usage_gib_hours"`
+	MemoryRequestGiBHours float64 `json:"memory_request_gib_hours"`
+}
+
+// Add returns the sum of the two usages
+func (u NamespaceUsage) Add(other NamespaceUsage) NamespaceUsage {
+	return NamespaceUsage{
+		CPUUsageCoreHours:     u.CPUUsageCoreHours + other.CPUUsageCoreHours,
+		CPURequestCoreHours:   u.CPURequestCoreHours + other.CPURequestCoreHours,
+		MemoryUsageGiBHours:   u.MemoryUsageGiBHours + other.MemoryUsageGiBHours,
+		MemoryRequestGiBHours: u.MemoryRequestGiBHours + other.MemoryRequestGiBHours,
+	}
+}
+
+// HourlyUsage is the usage of each namespace for a single queried hour
+type HourlyUsage struct {
+	Start      time.Time                 `json:"start"`
+	Namespaces map[string]NamespaceUsage `json:"namespaces"`
+}
+
+func parseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// summarizeNamespaceUsage totals the pod usage for each namespace
+func summarizeNamespaceUsage(podRows mappedCSVStruct, start time.Time) *HourlyUsage {
+	usage := &HourlyUsage{Start: start.UTC(), Namespaces: map[string]NamespaceUsage{}}
+	for _, row := range podRows {
+		pod, ok := row.(*podRow)
+		if !ok {
+			continue
+		}
+		usage.Namespaces[pod.Namespace] = usage.Namespaces[pod.Namespace].Add(NamespaceUsage{
+			CPUUsageCoreHours:     parseFloat(pod.PodUsageCPUCoreSeconds) / secondsPerHour,
+			CPURequestCoreHours:   parseFloat(pod.PodRequestCPUCoreSeconds) / secondsPerHour,
+			MemoryUsageGiBHours:   parseFloat(pod.PodUsageMemoryByteSeconds) / secondsPerHour / bytesPerGiB,
+			MemoryRequestGiBHours: parseFloat(pod.PodRequestMemoryByteSeconds) / secondsPerHour / bytesPerGiB,
+		})
+	}
+	return usage
+}
@@ -0,0 +1,85 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// ValidateReportFile checks that the report file at path has the columns of a report in the current schema version, or
+// of a registered report generator, and that every row has a value for each column. The name of the report and the number of data rows are returned.
+func ValidateReportFile(path string) (string, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("ValidateReportFile: failed to open file: %v", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return "", 0, fmt.Errorf("ValidateReportFile: file has no header")
+	} else if err != nil {
+		return "", 0, fmt.Errorf("ValidateReportFile: failed to read csv headers: %v", err)
+	}
+
+	report := ""
+	current := map[string][]string{}
+	for name, columns := range reportSchemas[len(reportSchemas)-1].reports {
+		current[name] = columns
+	}
+	for _, g := range ReportGenerators() {
+		current[g.Name()] = withPeriodColumns(g.Columns()...)
+	}
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if equalColumns(current[name], header) {
+			report = name
+			break
+		}
+	}
+	if report == "" {
+		return "", 0, fmt.Errorf("ValidateReportFile: columns do not match any report of schema version %s", ReportSchemaVersion)
+	}
+
+	rows := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return report, rows, fmt.Errorf("ValidateReportFile: failed to read csv: %v", err)
+		}
+		rows++
+		if len(row) != len(header) {
+			return report, rows, fmt.Errorf("ValidateReportFile: row %d has %d values, expected %d", rows, len(row), len(header))
+		}
+	}
+	return report, rows, nil
+}
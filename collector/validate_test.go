@@ -0,0 +1,67 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateReportFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	header := strings.Join(reportColumns(customReport), ",")
+	row := "2021-01-01,2021-02-01,2021-01-05 18:00:00,2021-01-05 18:59:59,default,pod-a,metric,1"
+	tests := []struct {
+		name       string
+		content    string
+		wantReport string
+		wantRows   int
+		wantErr    bool
+	}{
+		{name: "valid report", content: header + "\n" + row + "\n" + row + "\n", wantReport: customReport, wantRows: 2},
+		{name: "header only", content: header + "\n", wantReport: customReport},
+		{name: "empty file", content: "", wantErr: true},
+		{name: "unknown columns", content: "a,b\n1,2\n", wantErr: true},
+		{name: "short row", content: header + "\n" + row + "\n2021-01-01,2021-02-01\n", wantReport: customReport, wantRows: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "report.csv")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			report, rows, err := ValidateReportFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			if report != tt.wantReport || rows != tt.wantRows {
+				t.Errorf("%s got (%s, %d) want (%s, %d)", tt.name, report, rows, tt.wantReport, tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// WindowIndexFile is the name of the window index on the report volume
+const WindowIndexFile = "window-index.json"
+
+// windowRetention is how long windows are kept in the index. It covers the longest range that can be re-collected.
+const windowRetention = 93 * 24 * time.Hour
+
+// ErrWindowCollected is returned by GenerateReports when the reports for the window were already generated and
+// the collector is not set to overwrite them
+var ErrWindowCollected = errors.New("reports were already generated for the window")
+
+// WindowEntry records the reports generated for a single hourly window
+type WindowEntry struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Files       []string  `json:"files"`
+	Checksum    string    `json:"checksum"`
+	CollectedAt time.Time `json:"collected_at"`
+	Overwrites  int       `json:"overwrites,omitempty"`
+	// Endpoint is the address of the prometheus that served the window
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// WindowIndex records the windows that reports were generated for, so that generating the reports for a window
+// again is an explicit overwrite instead of a duplicate that is counted twice downstream
+type WindowIndex struct {
+	path    string
+	Windows map[string]WindowEntry `json:"windows"`
+}
+
+func windowKey(start time.Time) string { return start.UTC().Format(time.RFC3339) }
+
+// LoadWindowIndex reads the window index at path. An empty index is returned if the file does not exist.
+func LoadWindowIndex(path string) (*WindowIndex, error) {
+	index := &WindowIndex{path: path, Windows: map[string]WindowEntry{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return index, fmt.Errorf("LoadWindowIndex: failed to read index: %v", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return &WindowIndex{path: path, Windows: map[string]WindowEntry{}}, fmt.Errorf("LoadWindowIndex: failed to parse index: %v", err)
+	}
+	if index.Windows == nil {
+		index.Windows = map[string]WindowEntry{}
+	}
+	return index, nil
+}
+
+// Get returns the entry for the window starting at start
+func (w *WindowIndex) Get(start time.Time) (WindowEntry, bool) {
+	entry, ok := w.Windows[windowKey(start)]
+	return entry, ok
+}
+
+// Record adds the entry to the index, counting an overwrite if the window was already recorded. Windows that start
+// more than the retention before the newest window are removed.
+func (w *WindowIndex) Record(entry WindowEntry) {
+	key := windowKey(entry.Start)
+	if previous, ok := w.Windows[key]; ok {
+		entry.Overwrites = previous.Overwrites + 1
+	}
+	w.Windows[key] = entry
+	newest := entry.Start
+	for _, e := range w.Windows {
+		if e.Start.After(newest) {
+			newest = e.Start
+		}
+	}
+	cutoff := newest.Add(-windowRetention)
+	for k, e := range w.Windows {
+		if k != key && e.Start.Before(cutoff) {
+			delete(w.Windows, k)
+		}
+	}
+}
+
+// Save writes the index. The index is written to a temporary file and renamed so that it is never left partially written.
+func (w *WindowIndex) Save() error {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return fmt.Errorf("Save: failed to marshal index: %v", err)
+	}
+	tmp := w.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("Save: failed to write index: %v", err)
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return fmt.Errorf("Save: failed to replace index: %v", err)
+	}
+	return nil
+}
+
+// rowsChecksum returns a checksum of the rows that does not depend on the order of the rows
+func rowsChecksum(reports ...mappedCSVStruct) string {
+	var rows []string
+	for _, report := range reports {
+		for _, row := range report {
+			rows = append(rows, row.string())
+		}
+	}
+	sort.Strings(rows)
+	hash := sha256.New()
+	for _, row := range rows {
+		hash.Write([]byte(row))
+		hash.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
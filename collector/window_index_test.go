@@ -0,0 +1,187 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestLoadWindowIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "window-index")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := ioutil.WriteFile(corrupt, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("failed to write corrupt index: %v", err)
+	}
+
+	loadTests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "missing file returns empty index", path: filepath.Join(dir, "missing.json"), wantErr: false},
+		{name: "corrupt file returns empty index and error", path: corrupt, wantErr: true},
+	}
+	for _, tt := range loadTests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := LoadWindowIndex(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if index == nil || index.Windows == nil || len(index.Windows) != 0 {
+				t.Errorf("%s expected an empty index, got %#v", tt.name, index)
+			}
+		})
+	}
+}
+
+func TestWindowIndexRecordAndSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "window-index")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, WindowIndexFile)
+	index, err := LoadWindowIndex(path)
+	if err != nil {
+		t.Fatalf("failed to load index: %v", err)
+	}
+
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-windowRetention - time.Hour)
+	index.Record(WindowEntry{Start: old, End: old.Add(time.Hour), CollectedAt: now})
+	index.Record(WindowEntry{Start: now, End: now.Add(time.Hour), Checksum: "first", CollectedAt: now})
+	index.Record(WindowEntry{Start: now, End: now.Add(time.Hour), Checksum: "second", CollectedAt: now})
+
+	if _, ok := index.Get(old); ok {
+		t.Errorf("window older than the retention was not removed")
+	}
+	entry, ok := index.Get(now)
+	if !ok {
+		t.Fatalf("window was not recorded")
+	}
+	if entry.Overwrites != 1 || entry.Checksum != "second" {
+		t.Errorf("got overwrites %d checksum %s, want 1 second", entry.Overwrites, entry.Checksum)
+	}
+
+	if err := index.Save(); err != nil {
+		t.Fatalf("failed to save index: %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary index file was not renamed")
+	}
+	loaded, err := LoadWindowIndex(path)
+	if err != nil {
+		t.Fatalf("failed to load saved index: %v", err)
+	}
+	// the key is independent of the location of the start time
+	got, ok := loaded.Get(now.In(time.FixedZone("test", -5*3600)))
+	if !ok {
+		t.Fatalf("saved window was not loaded")
+	}
+	if got.Overwrites != 1 || got.Checksum != "second" {
+		t.Errorf("got overwrites %d checksum %s, want 1 second", got.Overwrites, got.Checksum)
+	}
+}
+
+func TestRowsChecksum(t *testing.T) {
+	a := customMetricRow{dateTimes: &dateTimes{}, Namespace: "a", MetricName: "m", MetricValue: "1"}
+	b := customMetricRow{dateTimes: &dateTimes{}, Namespace: "b", MetricName: "m", MetricValue: "2"}
+
+	first := rowsChecksum(mappedCSVStruct{"a": a}, mappedCSVStruct{"b": b})
+	second := rowsChecksum(mappedCSVStruct{"b": b, "a": a})
+	if first != second {
+		t.Errorf("checksum depends on the order of the rows: %s != %s", first, second)
+	}
+	if first == rowsChecksum(mappedCSVStruct{"a": a}) {
+		t.Errorf("checksum did not change when rows changed")
+	}
+}
+
+func TestGenerateReportsWindowCollected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "window-index")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	mapResults := make(mappedMockPromResult)
+	queryList := []*querys{nodeQueries, namespaceQueries, podQueries, workloadQueries, volQueries}
+	for _, q := range queryList {
+		for _, query := range *q {
+			res := &model.Matrix{}
+			Load(filepath.Join("test_files", "test_data", query.Name), res, t)
+			mapResults[query.QueryString] = &mockPromResult{value: *res}
+		}
+	}
+	addGeneratorResults(mapResults)
+
+	index, err := LoadWindowIndex(filepath.Join(dir, WindowIndexFile))
+	if err != nil {
+		t.Fatalf("failed to load index: %v", err)
+	}
+	fakeCollector := &PromCollector{
+		PromConn: mockPrometheusConnection{
+			mappedResults: &mapResults,
+			t:             t,
+		},
+		TimeSeries: &fakeTimeRange,
+		Log:        testLogger,
+		Index:      index,
+	}
+	if err := GenerateReports(fakeKMCfg, fakeDirCfg, fakeCollector); err != nil {
+		t.Fatalf("Failed to generate reports: %v", err)
+	}
+	entry, ok := index.Get(fakeTimeRange.Start)
+	if !ok {
+		t.Fatalf("window was not recorded in the index")
+	}
+	if len(entry.Files) == 0 || entry.Checksum == "" {
+		t.Errorf("window entry is missing files or checksum: %#v", entry)
+	}
+
+	if err := GenerateReports(fakeKMCfg, fakeDirCfg, fakeCollector); err != ErrWindowCollected {
+		t.Errorf("got error %v, want %v", err, ErrWindowCollected)
+	}
+
+	fakeCollector.Overwrite = true
+	if err := GenerateReports(fakeKMCfg, fakeDirCfg, fakeCollector); err != nil {
+		t.Errorf("Failed to overwrite reports: %v", err)
+	}
+	entry, _ = index.Get(fakeTimeRange.Start)
+	if entry.Overwrites != 1 {
+		t.Errorf("got overwrites %d, want 1", entry.Overwrites)
+	}
+
+	if err := fakeDirCfg.Reports.RemoveContents(); err != nil {
+		t.Fatal("failed to cleanup reports directory")
+	}
+}
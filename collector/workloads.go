@@ -0,0 +1,105 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// the kinds of owners that are resolved to the workload that owns them
+const (
+	replicaSetKind            = "ReplicaSet"
+	replicationControllerKind = "ReplicationController"
+	jobKind                   = "Job"
+)
+
+// workload is the controller that owns a pod, such as a Deployment, StatefulSet, DaemonSet, or CronJob
+type workload struct {
+	kind string
+	name string
+}
+
+// workloadKey is the key of an object in a namespace
+func workloadKey(namespace, name string) string { return namespace + "/" + name }
+
+// ownerIndex returns the controller owner of each object of the matrix, keyed by namespace and the value of the
+// nameLabel. Objects without a controller owner are left out.
+func ownerIndex(matrix model.Matrix, nameLabel model.LabelName) map[string]workload {
+	owners := map[string]workload{}
+	for _, stream := range matrix {
+		kind, name := string(stream.Metric["owner_kind"]), string(stream.Metric["owner_name"])
+		if kind == "" || kind == "<none>" || name == "" {
+			continue
+		}
+		owners[workloadKey(string(stream.Metric["namespace"]), string(stream.Metric[nameLabel]))] = workload{kind: kind, name: name}
+	}
+	return owners
+}
+
+// resolveWorkloads follows the owner of each pod to the workload that created it: the Deployment of a ReplicaSet, the
+// DeploymentConfig of a ReplicationController, and the CronJob of a Job. An owner without an owner of its own, such as
+// a StatefulSet or a Job that is not created by a CronJob, is the workload. The workloads are keyed by namespace and
+// pod name.
+func resolveWorkloads(owners map[string]map[string]workload) map[string]workload {
+	parents := map[string]string{
+		replicaSetKind:            "replicaset-owner",
+		replicationControllerKind: "replicationcontroller-owner",
+		jobKind:                   "job-owner",
+	}
+	workloads := map[string]workload{}
+	for key, owner := range owners["pod-owner"] {
+		namespace := strings.SplitN(key, "/", 2)[0]
+		if query, ok := parents[owner.kind]; ok {
+			if parent, ok := owners[query][workloadKey(namespace, owner.name)]; ok {
+				owner = parent
+			}
+		}
+		workloads[key] = owner
+	}
+	return workloads
+}
+
+// podWorkloads queries the owners of the pods, and of their owners, and returns the workload of each pod, keyed by
+// namespace and pod name. No workloads are queried with the lightweight profile.
+func (c *PromCollector) podWorkloads() (map[string]workload, error) {
+	var run querys
+	var ranges []rangeQuery
+	for _, query := range *workloadQueries {
+		if c.Lightweight && query.SkipInLightweight {
+			continue
+		}
+		run = append(run, query)
+		ranges = append(ranges, rangeQuery{name: query.Name, query: c.queryString(query)})
+	}
+	if len(run) == 0 {
+		return nil, nil
+	}
+	matrices, err := c.queryRanges(ranges)
+	if err != nil {
+		return nil, err
+	}
+	owners := map[string]map[string]workload{}
+	for i, query := range run {
+		owners[query.Name] = ownerIndex(matrices[i], query.RowKey)
+	}
+	return resolveWorkloads(owners), nil
+}
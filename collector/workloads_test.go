@@ -0,0 +1,83 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/common/model"
+)
+
+func ownerStream(name, namespace, nameLabel, owned, kind, owner string) *model.SampleStream {
+	return &model.SampleStream{
+		Metric: model.Metric{
+			"__name__":                 model.LabelValue(name),
+			"namespace":                model.LabelValue(namespace),
+			model.LabelName(nameLabel): model.LabelValue(owned),
+			"owner_kind":               model.LabelValue(kind),
+			"owner_name":               model.LabelValue(owner),
+			"owner_is_controller":      "true",
+		},
+		Values: []model.SamplePair{{Timestamp: 1604685600000, Value: 1}},
+	}
+}
+
+func TestResolveWorkloads(t *testing.T) {
+	owners := map[string]map[string]workload{
+		"pod-owner": ownerIndex(model.Matrix{
+			ownerStream("kube_pod_owner", "web", "pod", "frontend-6b74f489cb-tqsrm", "ReplicaSet", "frontend-6b74f489cb"),
+			ownerStream("kube_pod_owner", "other", "pod", "frontend-6b74f489cb-x2k9p", "ReplicaSet", "frontend-6b74f489cb"),
+			ownerStream("kube_pod_owner", "web", "pod", "db-0", "StatefulSet", "db"),
+			ownerStream("kube_pod_owner", "web", "pod", "report-1604685600-abcde", "Job", "report-1604685600"),
+			ownerStream("kube_pod_owner", "web", "pod", "migrate-xyz12", "Job", "migrate"),
+			ownerStream("kube_pod_owner", "web", "pod", "api-1-deploy", "ReplicationController", "api-1"),
+			ownerStream("kube_pod_owner", "web", "pod", "standalone", "<none>", "<none>"),
+		}, "pod"),
+		"replicaset-owner": ownerIndex(model.Matrix{
+			ownerStream("kube_replicaset_owner", "web", "replicaset", "frontend-6b74f489cb", "Deployment", "frontend"),
+		}, "replicaset"),
+		"replicationcontroller-owner": ownerIndex(model.Matrix{
+			ownerStream("kube_replicationcontroller_owner", "web", "replicationcontroller", "api-1", "DeploymentConfig", "api"),
+		}, "replicationcontroller"),
+		"job-owner": ownerIndex(model.Matrix{
+			ownerStream("kube_job_owner", "web", "job_name", "report-1604685600", "CronJob", "report"),
+		}, "job_name"),
+	}
+	want := map[string]workload{
+		"web/frontend-6b74f489cb-tqsrm":   {kind: "Deployment", name: "frontend"},
+		"other/frontend-6b74f489cb-x2k9p": {kind: "ReplicaSet", name: "frontend-6b74f489cb"},
+		"web/db-0":                        {kind: "StatefulSet", name: "db"},
+		"web/report-1604685600-abcde":     {kind: "CronJob", name: "report"},
+		"web/migrate-xyz12":               {kind: "Job", name: "migrate"},
+		"web/api-1-deploy":                {kind: "DeploymentConfig", name: "api"},
+	}
+	if got := resolveWorkloads(owners); !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveWorkloads got %+v want %+v", got, want)
+	}
+}
+
+func TestPodWorkloadsLightweight(t *testing.T) {
+	c := &PromCollector{Lightweight: true, Log: testLogger}
+	got, err := c.podWorkloads()
+	if err != nil || got != nil {
+		t.Errorf("podWorkloads got %v, %v want no workloads with the lightweight profile", got, err)
+	}
+}
@@ -0,0 +1,268 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const (
+	// reasonQueryStepCoarsened is the reason of the event emitted when the query step is coarsened
+	reasonQueryStepCoarsened = "QueryStepCoarsened"
+	// reasonQueryStepRefined is the reason of the event emitted when a coarsened query step is made finer again
+	reasonQueryStepRefined = "QueryStepRefined"
+	// operatorContainerName is the name of the container of the operator in the operator pod
+	operatorContainerName = "manager"
+	// oomKilledReason is the termination reason of a container that ran out of memory
+	oomKilledReason = "OOMKilled"
+	// collectionMarkerFile is written to the report volume while a window is collected. It holds the query step of
+	// the collection, and is only left behind when the operator stopped during the collection.
+	collectionMarkerFile = "collection-in-progress"
+)
+
+// querySteps are the query steps of the adaptive query step, from the finest to the coarsest
+var querySteps = []time.Duration{time.Minute, 2 * time.Minute, 5 * time.Minute}
+
+// reflectAdaptiveStep sets the thresholds of the adaptive query step in the status. Disabling it resets the query step.
+func reflectAdaptiveStep(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	spec := kmCfg.Spec.PrometheusConfig.AdaptiveStep
+	status := &kmCfg.Status.Prometheus.AdaptiveStep
+	status.Enabled = spec.Enabled != nil && *spec.Enabled
+	maxDuration := kokumetricscfgv1beta1.DefaultAdaptiveStepMaxDurationSeconds
+	if spec.MaxDurationSeconds != nil {
+		maxDuration = *spec.MaxDurationSeconds
+	}
+	status.MaxDurationSeconds = &maxDuration
+	maxMemory := kokumetricscfgv1beta1.DefaultAdaptiveStepMaxMemoryMB
+	if spec.MaxMemoryMB != nil {
+		maxMemory = *spec.MaxMemoryMB
+	}
+	status.MaxMemoryMB = &maxMemory
+	recovery := kokumetricscfgv1beta1.DefaultAdaptiveStepRecoveryCollections
+	if spec.RecoveryCollections != nil {
+		recovery = *spec.RecoveryCollections
+	}
+	status.RecoveryCollections = &recovery
+	if !status.Enabled {
+		status.StepSeconds = int64(querySteps[0] / time.Second)
+		status.Reason = ""
+		status.CollectionsWithinThresholds = 0
+	} else if status.StepSeconds <= 0 {
+		status.StepSeconds = int64(querySteps[0] / time.Second)
+	}
+}
+
+// queryStep returns the query step of the windows that are collected
+func queryStep(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) time.Duration {
+	if kmCfg.Status.Prometheus.AdaptiveStep.StepSeconds <= 0 {
+		return querySteps[0]
+	}
+	return time.Duration(kmCfg.Status.Prometheus.AdaptiveStep.StepSeconds) * time.Second
+}
+
+// nextQueryStep returns the query step that follows step, or false if step is the coarsest query step
+func nextQueryStep(step time.Duration) (time.Duration, bool) {
+	for _, next := range querySteps {
+		if next > step {
+			return next, true
+		}
+	}
+	return step, false
+}
+
+// previousQueryStep returns the query step that precedes step, or false if step is the finest query step
+func previousQueryStep(step time.Duration) (time.Duration, bool) {
+	for i := len(querySteps) - 1; i >= 0; i-- {
+		if querySteps[i] < step {
+			return querySteps[i], true
+		}
+	}
+	return step, false
+}
+
+// memoryInUseMB returns the memory, in megabytes, that the operator has obtained from the system and not released
+func memoryInUseMB() int64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return int64((m.Sys - m.HeapReleased) / (1 << 20))
+}
+
+// coarsenQueryStep moves the query step to the next coarser step, starting from the coarser of the status step and
+// from, and records the reason. Nothing is changed if the query step is already the coarsest.
+func coarsenQueryStep(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, from time.Duration, reason string, now time.Time, log logr.Logger) {
+	kmCfg.Status.Prometheus.AdaptiveStep.CollectionsWithinThresholds = 0
+	step := queryStep(kmCfg)
+	if from > step {
+		step = from
+	}
+	next, ok := nextQueryStep(step)
+	if !ok {
+		log.Info("the query step is already the coarsest", "step", step, "reason", reason)
+		return
+	}
+	msg := fmt.Sprintf("the query step was coarsened from %s to %s: %s", step, next, reason)
+	status := &kmCfg.Status.Prometheus.AdaptiveStep
+	status.StepSeconds = int64(next / time.Second)
+	status.Reason = reason
+	status.LastChangeTime = metav1.Time{Time: now}
+	log.Info(msg)
+	if r.Recorder != nil {
+		r.Recorder.Event(kmCfg, corev1.EventTypeWarning, reasonQueryStepCoarsened, msg)
+	}
+}
+
+// refineQueryStep moves a coarsened query step to the next finer step once the collections have stayed within the
+// thresholds, so that a single slow or large collection does not lower the resolution for good
+func refineQueryStep(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, now time.Time, log logr.Logger) {
+	status := &kmCfg.Status.Prometheus.AdaptiveStep
+	step := queryStep(kmCfg)
+	previous, ok := previousQueryStep(step)
+	if !ok {
+		status.CollectionsWithinThresholds = 0
+		return
+	}
+	reason := fmt.Sprintf("%d consecutive collections were within the thresholds", status.CollectionsWithinThresholds)
+	msg := fmt.Sprintf("the query step was made finer from %s to %s: %s", step, previous, reason)
+	status.StepSeconds = int64(previous / time.Second)
+	status.Reason = reason
+	status.LastChangeTime = metav1.Time{Time: now}
+	status.CollectionsWithinThresholds = 0
+	log.Info(msg)
+	if r.Recorder != nil {
+		r.Recorder.Event(kmCfg, corev1.EventTypeNormal, reasonQueryStepRefined, msg)
+	}
+}
+
+// oomKilled returns true if the last termination of the operator container in the pod was caused by running out of
+// memory
+func oomKilled(pod *corev1.Pod) bool {
+	for _, container := range pod.Status.ContainerStatuses {
+		if container.Name != operatorContainerName {
+			continue
+		}
+		terminated := container.LastTerminationState.Terminated
+		return terminated != nil && terminated.Reason == oomKilledReason
+	}
+	return false
+}
+
+// operatorOOMKilled returns true if the operator container was last terminated because it ran out of memory. The
+// operator pod is named after the host name of the container.
+func operatorOOMKilled(r *KokuMetricsConfigReconciler, namespace string) (bool, error) {
+	if r.Clientset == nil {
+		return false, nil
+	}
+	name, err := os.Hostname()
+	if err != nil {
+		return false, fmt.Errorf("operatorOOMKilled: failed to get the pod name: %v", err)
+	}
+	pod, err := r.Clientset.CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("operatorOOMKilled: failed to get the operator pod: %v", err)
+	}
+	return oomKilled(pod), nil
+}
+
+// reviewInterruptedCollection coarsens the query step when the previous collection, whose query step is step, was
+// interrupted because the operator ran out of memory. Collections interrupted by a rollout, an eviction, or a node
+// drain keep the query step.
+func reviewInterruptedCollection(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, step time.Duration, now time.Time, log logr.Logger) {
+	oom, err := operatorOOMKilled(r, kmCfg.Namespace)
+	if err != nil {
+		log.Error(err, "failed to check why the previous collection was interrupted")
+		return
+	}
+	if !oom {
+		log.Info("the previous collection was interrupted without running out of memory, the query step is kept")
+		return
+	}
+	coarsenQueryStep(r, kmCfg, step, "the previous collection was interrupted because the operator ran out of memory", now, log)
+}
+
+// startCollection writes the collection marker to dir. If a marker is already there, the previous collection was
+// interrupted, usually because the operator ran out of memory, and the query step of that collection is returned.
+func startCollection(dir string, step time.Duration) (time.Duration, bool, error) {
+	path := filepath.Join(dir, collectionMarkerFile)
+	var interrupted time.Duration
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		seconds, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		interrupted = time.Duration(seconds) * time.Second
+	} else if !os.IsNotExist(err) {
+		return 0, false, fmt.Errorf("startCollection: failed to read the collection marker: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(strconv.FormatInt(int64(step/time.Second), 10)), 0644); err != nil {
+		return 0, false, fmt.Errorf("startCollection: failed to write the collection marker: %v", err)
+	}
+	return interrupted, err == nil, nil
+}
+
+// finishCollection removes the collection marker from dir
+func finishCollection(dir string) error {
+	if err := os.Remove(filepath.Join(dir, collectionMarkerFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("finishCollection: failed to remove the collection marker: %v", err)
+	}
+	return nil
+}
+
+// adaptQueryStep records the duration and memory of a collection, and coarsens the query step of the following
+// windows when one of them exceeds its threshold. A coarsened query step is made finer after RecoveryCollections
+// consecutive collections within the thresholds.
+func adaptQueryStep(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, duration time.Duration, memoryMB int64, now time.Time, log logr.Logger) {
+	status := &kmCfg.Status.Prometheus.AdaptiveStep
+	status.LastDurationSeconds = int64(duration / time.Second)
+	status.LastMemoryMB = memoryMB
+	if !status.Enabled {
+		return
+	}
+	var reasons []string
+	if status.MaxDurationSeconds != nil && status.LastDurationSeconds > *status.MaxDurationSeconds {
+		reasons = append(reasons, fmt.Sprintf("the collection took %ds, more than %ds", status.LastDurationSeconds, *status.MaxDurationSeconds))
+	}
+	if status.MaxMemoryMB != nil && memoryMB > *status.MaxMemoryMB {
+		reasons = append(reasons, fmt.Sprintf("the collection used %dMB, more than %dMB", memoryMB, *status.MaxMemoryMB))
+	}
+	if len(reasons) > 0 {
+		coarsenQueryStep(r, kmCfg, 0, strings.Join(reasons, ", "), now, log)
+		return
+	}
+	if queryStep(kmCfg) <= querySteps[0] {
+		status.CollectionsWithinThresholds = 0
+		return
+	}
+	status.CollectionsWithinThresholds++
+	if status.RecoveryCollections != nil && status.CollectionsWithinThresholds >= *status.RecoveryCollections {
+		refineQueryStep(r, kmCfg, now, log)
+	}
+}
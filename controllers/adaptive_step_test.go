@@ -0,0 +1,190 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestReflectAdaptiveStep(t *testing.T) {
+	enabled, disabled := true, false
+	maxDuration := int64(60)
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	reflectAdaptiveStep(kmCfg)
+	status := kmCfg.Status.Prometheus.AdaptiveStep
+	if status.Enabled || status.StepSeconds != 60 || *status.MaxDurationSeconds != kokumetricscfgv1beta1.DefaultAdaptiveStepMaxDurationSeconds ||
+		*status.MaxMemoryMB != kokumetricscfgv1beta1.DefaultAdaptiveStepMaxMemoryMB ||
+		*status.RecoveryCollections != kokumetricscfgv1beta1.DefaultAdaptiveStepRecoveryCollections {
+		t.Errorf("got default status %+v", status)
+	}
+
+	// a coarsened step is kept while the adaptive step is enabled
+	kmCfg.Spec.PrometheusConfig.AdaptiveStep.Enabled = &enabled
+	kmCfg.Status.Prometheus.AdaptiveStep.StepSeconds = 300
+	kmCfg.Spec.PrometheusConfig.AdaptiveStep.MaxDurationSeconds = &maxDuration
+	reflectAdaptiveStep(kmCfg)
+	if got := queryStep(kmCfg); got != 5*time.Minute {
+		t.Errorf("got query step %s want 5m0s", got)
+	}
+	if *kmCfg.Status.Prometheus.AdaptiveStep.MaxDurationSeconds != 60 {
+		t.Errorf("got max duration %d want 60", *kmCfg.Status.Prometheus.AdaptiveStep.MaxDurationSeconds)
+	}
+
+	// disabling the adaptive step resets the step
+	kmCfg.Status.Prometheus.AdaptiveStep.CollectionsWithinThresholds = 3
+	kmCfg.Spec.PrometheusConfig.AdaptiveStep.Enabled = &disabled
+	reflectAdaptiveStep(kmCfg)
+	status = kmCfg.Status.Prometheus.AdaptiveStep
+	if got := queryStep(kmCfg); got != time.Minute || status.Enabled || status.CollectionsWithinThresholds != 0 {
+		t.Errorf("got query step %s, enabled %t and %d collections within thresholds want 1m0s, false and 0",
+			got, status.Enabled, status.CollectionsWithinThresholds)
+	}
+}
+
+func TestAdaptQueryStep(t *testing.T) {
+	now := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+	adaptTests := []struct {
+		name       string
+		step       int64
+		duration   time.Duration
+		memoryMB   int64
+		enabled    bool
+		within     int64
+		wantStep   int64
+		wantWithin int64
+		wantEvent  bool
+	}{
+		{name: "within thresholds", step: 60, duration: time.Minute, memoryMB: 100, enabled: true, wantStep: 60},
+		{name: "slow collection", step: 60, duration: 10 * time.Minute, memoryMB: 100, enabled: true, wantStep: 120, wantEvent: true},
+		{name: "memory exceeded", step: 120, duration: time.Minute, memoryMB: 450, enabled: true, wantStep: 300, wantEvent: true},
+		{name: "already coarsest", step: 300, duration: 10 * time.Minute, memoryMB: 450, enabled: true, wantStep: 300},
+		{name: "disabled", step: 60, duration: 10 * time.Minute, memoryMB: 450, wantStep: 60},
+		{name: "coarsened step within thresholds", step: 300, duration: time.Minute, memoryMB: 100, enabled: true, within: 5, wantStep: 300, wantWithin: 6},
+		{name: "coarsened step recovers", step: 300, duration: time.Minute, memoryMB: 100, enabled: true, within: 23, wantStep: 120, wantEvent: true},
+		{name: "exceeded threshold resets recovery", step: 120, duration: 10 * time.Minute, memoryMB: 100, enabled: true, within: 23, wantStep: 300, wantEvent: true},
+	}
+	for _, tt := range adaptTests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: recorder}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			reflectAdaptiveStep(kmCfg)
+			kmCfg.Status.Prometheus.AdaptiveStep.Enabled = tt.enabled
+			kmCfg.Status.Prometheus.AdaptiveStep.StepSeconds = tt.step
+			kmCfg.Status.Prometheus.AdaptiveStep.CollectionsWithinThresholds = tt.within
+			adaptQueryStep(r, kmCfg, tt.duration, tt.memoryMB, now, r.Log)
+			status := kmCfg.Status.Prometheus.AdaptiveStep
+			if status.StepSeconds != tt.wantStep {
+				t.Errorf("%s got step %d want %d", tt.name, status.StepSeconds, tt.wantStep)
+			}
+			if status.CollectionsWithinThresholds != tt.wantWithin {
+				t.Errorf("%s got %d collections within thresholds want %d", tt.name, status.CollectionsWithinThresholds, tt.wantWithin)
+			}
+			if status.LastDurationSeconds != int64(tt.duration/time.Second) || status.LastMemoryMB != tt.memoryMB {
+				t.Errorf("%s got measurements %ds %dMB", tt.name, status.LastDurationSeconds, status.LastMemoryMB)
+			}
+			if got := len(recorder.Events) > 0; got != tt.wantEvent {
+				t.Errorf("%s got event %t want %t", tt.name, got, tt.wantEvent)
+			}
+			if tt.wantEvent && (status.Reason == "" || !status.LastChangeTime.Time.Equal(now)) {
+				t.Errorf("%s got reason %q and change time %v", tt.name, status.Reason, status.LastChangeTime)
+			}
+		})
+	}
+}
+
+func TestOOMKilled(t *testing.T) {
+	terminated := func(container, reason string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			Name: container,
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Reason: reason},
+			},
+		}
+	}
+	oomTests := []struct {
+		name     string
+		statuses []corev1.ContainerStatus
+		want     bool
+	}{
+		{name: "never terminated", statuses: []corev1.ContainerStatus{{Name: operatorContainerName}}, want: false},
+		{name: "out of memory", statuses: []corev1.ContainerStatus{terminated(operatorContainerName, oomKilledReason)}, want: true},
+		{name: "rollout or eviction", statuses: []corev1.ContainerStatus{terminated(operatorContainerName, "Error")}, want: false},
+		{name: "other container out of memory", statuses: []corev1.ContainerStatus{terminated("kube-rbac-proxy", oomKilledReason), {Name: operatorContainerName}}, want: false},
+		{name: "no container status", want: false},
+	}
+	for _, tt := range oomTests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: tt.statuses}}
+			if got := oomKilled(pod); got != tt.want {
+				t.Errorf("%s got %t want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectionMarker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "adaptive-step")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a finished collection leaves no marker
+	if _, interrupted, err := startCollection(dir, time.Minute); err != nil || interrupted {
+		t.Fatalf("got interrupted %t and error %v want a clean start", interrupted, err)
+	}
+	if err := finishCollection(dir); err != nil {
+		t.Fatalf("failed to finish collection: %v", err)
+	}
+	if _, interrupted, _ := startCollection(dir, 2*time.Minute); interrupted {
+		t.Error("got an interrupted collection after a finished collection")
+	}
+
+	// the marker of an unfinished collection holds its step
+	step, interrupted, err := startCollection(dir, time.Minute)
+	if err != nil || !interrupted || step != 2*time.Minute {
+		t.Errorf("got step %s, interrupted %t and error %v want 2m0s, true and no error", step, interrupted, err)
+	}
+
+	// an interrupted collection that was not an OOM kill keeps the step
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: record.NewFakeRecorder(10)}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	reflectAdaptiveStep(kmCfg)
+	reviewInterruptedCollection(r, kmCfg, step, time.Now(), r.Log)
+	if got := queryStep(kmCfg); got != time.Minute {
+		t.Errorf("got query step %s want 1m0s", got)
+	}
+
+	// an interrupted collection coarsens the step of that collection
+	coarsenQueryStep(r, kmCfg, step, "interrupted", time.Now(), r.Log)
+	if got := queryStep(kmCfg); got != 5*time.Minute {
+		t.Errorf("got query step %s want 5m0s", got)
+	}
+}
@@ -0,0 +1,292 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+const (
+	// AdminPathPrefix is the path prefix of the endpoints of the admin API
+	AdminPathPrefix = "/admin/v1/"
+
+	adminCollect = "collect"
+	adminUpload  = "upload"
+	adminState   = "state"
+)
+
+// adminActions are the actions requested through the admin API that have not been run by a reconcile yet
+type adminActions struct {
+	mu      sync.Mutex
+	pending map[types.NamespacedName]map[string]bool
+	// events wake the reconciler for the KokuMetricsConfig of an action
+	events chan event.GenericEvent
+}
+
+var admin = &adminActions{
+	pending: map[types.NamespacedName]map[string]bool{},
+	events:  make(chan event.GenericEvent, 16),
+}
+
+// request records the action and wakes the reconciler for the KokuMetricsConfig
+func (a *adminActions) request(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, action string) {
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: kmCfg.Name}
+	a.mu.Lock()
+	if a.pending[key] == nil {
+		a.pending[key] = map[string]bool{}
+	}
+	a.pending[key][action] = true
+	a.mu.Unlock()
+
+	select {
+	case a.events <- event.GenericEvent{Meta: kmCfg, Object: kmCfg}:
+	default:
+		// the reconciler has not caught up with the previous requests, which reconcile the pending actions too
+	}
+}
+
+// take returns and clears the pending action of the KokuMetricsConfig. An action is taken where the reconcile runs it,
+// so that an action is kept for the next reconcile when the reconcile returns before it.
+func (a *adminActions) take(key types.NamespacedName, action string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	requested := a.pending[key][action]
+	delete(a.pending[key], action)
+	if len(a.pending[key]) == 0 {
+		delete(a.pending, key)
+	}
+	return requested
+}
+
+// takeAdminUpload returns true if an upload was requested through the admin API, and clears the request
+func takeAdminUpload(key types.NamespacedName, log logr.Logger) bool {
+	if !admin.take(key, adminUpload) {
+		return false
+	}
+	log.Info("uploading as requested through the admin API")
+	return true
+}
+
+// list returns the pending actions of the KokuMetricsConfig
+func (a *adminActions) list(key types.NamespacedName) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	actions := []string{}
+	for action := range a.pending[key] {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// adminActionResponse is the response of the collect and upload endpoints
+type adminActionResponse struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Action    string `json:"action"`
+	Queued    bool   `json:"queued"`
+}
+
+// adminUploadQueue is the state of the upload queue in the state dump
+type adminUploadQueue struct {
+	Critical       int       `json:"critical"`
+	Backfill       int       `json:"backfill"`
+	InProgress     bool      `json:"in_progress"`
+	LastBatchStart time.Time `json:"last_batch_start,omitempty"`
+	LastBatchEnd   time.Time `json:"last_batch_end,omitempty"`
+}
+
+// adminStateResponse is the response of the state endpoint
+type adminStateResponse struct {
+	Namespace      string                                        `json:"namespace"`
+	Name           string                                        `json:"name"`
+	PendingActions []string                                      `json:"pending_actions"`
+	CollectorState *collectorState                               `json:"collector_state"`
+	UploadQueue    adminUploadQueue                              `json:"upload_queue"`
+	Status         kokumetricscfgv1beta1.KokuMetricsConfigStatus `json:"status"`
+}
+
+// AdminServer serves the admin API on a loopback address, so that it can only be reached from the operator pod,
+// for example with a port-forward. It implements the controller-runtime Runnable.
+type AdminServer struct {
+	Addr string
+	// Client reads the KokuMetricsConfigs that the actions are requested for
+	Client client.Client
+	// Namespace is the namespace of the KokuMetricsConfigs when a request does not name one
+	Namespace string
+	Log       logr.Logger
+}
+
+// isLoopback returns true if the host is a loopback address or localhost
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Start serves the admin API until stop is closed
+func (s *AdminServer) Start(stop <-chan struct{}) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("Start: invalid admin address %s: %v", s.Addr, err)
+	}
+	if !isLoopback(host) {
+		return fmt.Errorf("Start: admin address %s is not a loopback address", s.Addr)
+	}
+	srv := &http.Server{Addr: s.Addr, Handler: s}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.Log.Info("starting admin API", "addr", s.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case <-stop:
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	case err := <-errCh:
+		return err
+	}
+}
+
+func (s *AdminServer) reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.Log.Error(err, "failed to write admin API response")
+	}
+}
+
+func (s *AdminServer) fail(w http.ResponseWriter, status int, msg string) {
+	s.reply(w, status, map[string]string{"error": msg})
+}
+
+// ServeHTTP runs the action of the path for the KokuMetricsConfig in the name and namespace query parameters.
+// The name can be left out when the namespace has a single KokuMetricsConfig.
+func (s *AdminServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err != nil || !isLoopback(host) {
+		s.fail(w, http.StatusForbidden, "the admin API only accepts requests from the operator pod")
+		return
+	}
+	action := strings.TrimPrefix(req.URL.Path, AdminPathPrefix)
+	method := http.MethodPost
+	switch action {
+	case adminCollect, adminUpload:
+	case adminState:
+		method = http.MethodGet
+	default:
+		s.fail(w, http.StatusNotFound, fmt.Sprintf("unknown admin endpoint %s", req.URL.Path))
+		return
+	}
+	if req.Method != method {
+		w.Header().Set("Allow", method)
+		s.fail(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s requires %s", req.URL.Path, method))
+		return
+	}
+
+	kmCfg, status, err := s.getConfig(req.Context(), req.URL.Query().Get("namespace"), req.URL.Query().Get("name"))
+	if err != nil {
+		s.fail(w, status, err.Error())
+		return
+	}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: kmCfg.Name}
+	log := s.Log.WithValues("KokuMetricsConfig", key, "action", action)
+
+	if action == adminState {
+		stats := uploader.DefaultQueue.Stats()
+		s.reply(w, http.StatusOK, adminStateResponse{
+			Namespace:      kmCfg.Namespace,
+			Name:           kmCfg.Name,
+			PendingActions: admin.list(key),
+			CollectorState: newCollectorState(kmCfg),
+			UploadQueue: adminUploadQueue{
+				Critical:       stats.Critical,
+				Backfill:       stats.Backfill,
+				InProgress:     stats.InProgress,
+				LastBatchStart: stats.LastBatchStart,
+				LastBatchEnd:   stats.LastBatchEnd,
+			},
+			Status: kmCfg.Status,
+		})
+		return
+	}
+
+	if isCronJobMode(kmCfg) {
+		// the reconciler does not collect or upload in the cronjob execution mode, so the action would never run
+		s.fail(w, http.StatusConflict, "actions are not available in the cronjob execution mode")
+		return
+	}
+	log.Info("admin action requested")
+	admin.request(kmCfg, action)
+	s.reply(w, http.StatusAccepted, adminActionResponse{Namespace: kmCfg.Namespace, Name: kmCfg.Name, Action: action, Queued: true})
+}
+
+// getConfig returns the named KokuMetricsConfig, or the only KokuMetricsConfig of the namespace when no name is
+// given, with the http status of the error
+func (s *AdminServer) getConfig(ctx context.Context, namespace, name string) (*kokumetricscfgv1beta1.KokuMetricsConfig, int, error) {
+	if namespace == "" {
+		namespace = s.Namespace
+	}
+	if name != "" {
+		kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+		if err := s.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, kmCfg); errors.IsNotFound(err) {
+			return nil, http.StatusNotFound, fmt.Errorf("KokuMetricsConfig %s/%s not found", namespace, name)
+		} else if err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to get KokuMetricsConfig: %v", err)
+		}
+		return kmCfg, http.StatusOK, nil
+	}
+
+	list := &kokumetricscfgv1beta1.KokuMetricsConfigList{}
+	if err := s.Client.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to list KokuMetricsConfigs: %v", err)
+	}
+	switch len(list.Items) {
+	case 0:
+		return nil, http.StatusNotFound, fmt.Errorf("no KokuMetricsConfig found in namespace %q", namespace)
+	case 1:
+		return &list.Items[0], http.StatusOK, nil
+	}
+	return nil, http.StatusBadRequest, fmt.Errorf("%d KokuMetricsConfigs found in namespace %q: set the name", len(list.Items), namespace)
+}
@@ -0,0 +1,195 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func adminServer(t *testing.T, names ...string) *AdminServer {
+	s := runtime.NewScheme()
+	if err := kokumetricscfgv1beta1.AddToScheme(s); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	var objs []runtime.Object
+	for _, name := range names {
+		kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: name}}
+		kmCfg.Status.ClusterID = "cluster-id"
+		objs = append(objs, kmCfg)
+	}
+	return &AdminServer{Client: fake.NewFakeClientWithScheme(s, objs...), Namespace: "koku-metrics-operator", Log: testutils.TestLogger{}}
+}
+
+// resetAdminActions clears the pending actions and the events of previous tests
+func resetAdminActions() {
+	admin.mu.Lock()
+	admin.pending = map[types.NamespacedName]map[string]bool{}
+	admin.mu.Unlock()
+	for {
+		select {
+		case <-admin.events:
+		default:
+			return
+		}
+	}
+}
+
+func TestAdminServer(t *testing.T) {
+	adminTests := []struct {
+		name        string
+		configs     []string
+		method      string
+		target      string
+		remote      string
+		wantStatus  int
+		wantCollect bool
+		wantUpload  bool
+	}{
+		{name: "collect is queued", configs: []string{"cfg"}, method: "POST", target: "/admin/v1/collect", wantStatus: http.StatusAccepted, wantCollect: true},
+		{name: "upload is queued for the named config", configs: []string{"cfg", "other"}, method: "POST", target: "/admin/v1/upload?name=cfg", wantStatus: http.StatusAccepted, wantUpload: true},
+		{name: "remote requests are rejected", configs: []string{"cfg"}, method: "POST", target: "/admin/v1/collect", remote: "10.0.0.1:4000", wantStatus: http.StatusForbidden},
+		{name: "unknown endpoint", configs: []string{"cfg"}, method: "POST", target: "/admin/v1/delete", wantStatus: http.StatusNotFound},
+		{name: "actions require post", configs: []string{"cfg"}, method: "GET", target: "/admin/v1/collect", wantStatus: http.StatusMethodNotAllowed},
+		{name: "name is required with several configs", configs: []string{"cfg", "other"}, method: "POST", target: "/admin/v1/collect", wantStatus: http.StatusBadRequest},
+		{name: "missing config", configs: []string{"cfg"}, method: "POST", target: "/admin/v1/collect?name=missing", wantStatus: http.StatusNotFound},
+		{name: "no config in the namespace", method: "POST", target: "/admin/v1/collect", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range adminTests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetAdminActions()
+			server := adminServer(t, tt.configs...)
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.RemoteAddr = "127.0.0.1:4000"
+			if tt.remote != "" {
+				req.RemoteAddr = tt.remote
+			}
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s got status %d want %d: %s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+			}
+			key := types.NamespacedName{Namespace: "koku-metrics-operator", Name: "cfg"}
+			collect, upload := admin.take(key, adminCollect), admin.take(key, adminUpload)
+			if collect != tt.wantCollect || upload != tt.wantUpload {
+				t.Errorf("%s got collect %t upload %t want collect %t upload %t", tt.name, collect, upload, tt.wantCollect, tt.wantUpload)
+			}
+			if queued := len(admin.events) > 0; queued != (tt.wantCollect || tt.wantUpload) {
+				t.Errorf("%s got reconcile queued %t", tt.name, queued)
+			}
+		})
+	}
+}
+
+func TestAdminServerState(t *testing.T) {
+	resetAdminActions()
+	defer resetAdminActions()
+	server := adminServer(t, "cfg")
+	for _, target := range []string{"/admin/v1/upload", "/admin/v1/collect"} {
+		req := httptest.NewRequest("POST", target, nil)
+		req.RemoteAddr = "[::1]:4000"
+		server.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest("GET", "/admin/v1/state", nil)
+	req.RemoteAddr = "127.0.0.1:4000"
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	got := adminStateResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse state: %v", err)
+	}
+	if got.Name != "cfg" || !reflect.DeepEqual(got.PendingActions, []string{"collect", "upload"}) {
+		t.Errorf("got state of %s with pending actions %v want cfg with collect and upload", got.Name, got.PendingActions)
+	}
+	if got.CollectorState == nil || got.CollectorState.ClusterID != "cluster-id" || got.Status.ClusterID != "cluster-id" {
+		t.Errorf("got collector state %+v and status cluster ID %q want the state of cluster-id", got.CollectorState, got.Status.ClusterID)
+	}
+}
+
+func TestAdminServerLoopbackAddress(t *testing.T) {
+	for _, addr := range []string{":8083", "0.0.0.0:8083", "10.0.0.1:8083", "8083"} {
+		server := &AdminServer{Addr: addr, Log: testutils.TestLogger{}}
+		if err := server.Start(make(chan struct{})); err == nil {
+			t.Errorf("%s got no error want the address rejected", addr)
+		}
+	}
+}
+
+func TestAdminActionsTake(t *testing.T) {
+	resetAdminActions()
+	defer resetAdminActions()
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "cfg"}}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: kmCfg.Name}
+	admin.request(kmCfg, adminCollect)
+	admin.request(kmCfg, adminUpload)
+
+	// a reconcile that returns before the upload only takes the collection
+	if !admin.take(key, adminCollect) {
+		t.Errorf("got no collect action want the requested collection")
+	}
+	if got := admin.list(key); !reflect.DeepEqual(got, []string{adminUpload}) {
+		t.Errorf("got pending actions %v want the upload kept for the next reconcile", got)
+	}
+	if !admin.take(key, adminUpload) || admin.take(key, adminUpload) {
+		t.Errorf("got the upload taken more or less than once")
+	}
+	if got := admin.list(key); len(got) != 0 {
+		t.Errorf("got pending actions %v want none", got)
+	}
+}
+
+func TestAdminServerCronJobMode(t *testing.T) {
+	resetAdminActions()
+	defer resetAdminActions()
+	s := runtime.NewScheme()
+	if err := kokumetricscfgv1beta1.AddToScheme(s); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "cfg"}}
+	kmCfg.Status.ExecutionMode = kokumetricscfgv1beta1.CronJobExecution
+	server := &AdminServer{Client: fake.NewFakeClientWithScheme(s, kmCfg), Namespace: "koku-metrics-operator", Log: testutils.TestLogger{}}
+
+	req := httptest.NewRequest("POST", "/admin/v1/collect", nil)
+	req.RemoteAddr = "127.0.0.1:4000"
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("got status %d want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+	}
+	if got := admin.list(types.NamespacedName{Namespace: "koku-metrics-operator", Name: "cfg"}); len(got) != 0 {
+		t.Errorf("got pending actions %v want none", got)
+	}
+}
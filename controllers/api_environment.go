@@ -0,0 +1,49 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// environmentValue returns the spec value if it was changed from its default, otherwise the value of the environment
+func environmentValue(specVal, defaultVal, environmentVal string) string {
+	if specVal != "" && specVal != defaultVal {
+		return specVal
+	}
+	return environmentVal
+}
+
+// reflectAPIEnvironment sets the API URL, ingress path, sources path, and SSO token URL in the status from the API
+// environment. The API URL and paths have defaults in the spec, so only a spec value that differs from its default
+// overrides the environment.
+func reflectAPIEnvironment(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	environment := kmCfg.Spec.APIEnvironment
+	if environment == "" {
+		environment = kokumetricscfgv1beta1.DefaultAPIEnvironment
+	}
+	endpoints := kokumetricscfgv1beta1.APIEnvironments[environment]
+	kmCfg.Status.APIEnvironment = environment
+
+	kmCfg.Status.APIURL = environmentValue(kmCfg.Spec.APIURL, kokumetricscfgv1beta1.DefaultAPIURL, endpoints.APIURL)
+	kmCfg.Status.Upload.IngressAPIPath = environmentValue(kmCfg.Spec.Upload.IngressAPIPath, kokumetricscfgv1beta1.DefaultIngressPath, endpoints.IngressPath)
+	kmCfg.Status.Source.SourcesAPIPath = environmentValue(kmCfg.Spec.Source.SourcesAPIPath, kokumetricscfgv1beta1.DefaultSourcesPath, endpoints.SourcesPath)
+	kmCfg.Status.Authentication.SSOTokenURL = endpoints.SSOTokenURL
+}
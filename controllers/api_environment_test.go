@@ -0,0 +1,92 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestReflectAPIEnvironment(t *testing.T) {
+	fedramp := kokumetricscfgv1beta1.APIEnvironments[kokumetricscfgv1beta1.FedRAMPEnvironment]
+	environmentTests := []struct {
+		name            string
+		environment     kokumetricscfgv1beta1.APIEnvironment
+		apiURL          string
+		ingressPath     string
+		wantEnvironment kokumetricscfgv1beta1.APIEnvironment
+		wantAPIURL      string
+		wantIngressPath string
+		wantSSOTokenURL string
+	}{
+		{
+			name:            "no environment is production",
+			wantEnvironment: kokumetricscfgv1beta1.ProductionEnvironment,
+			wantAPIURL:      kokumetricscfgv1beta1.DefaultAPIURL,
+			wantIngressPath: kokumetricscfgv1beta1.DefaultIngressPath,
+			wantSSOTokenURL: kokumetricscfgv1beta1.DefaultSSOTokenURL,
+		},
+		{
+			name:            "defaulted spec values use the environment",
+			environment:     kokumetricscfgv1beta1.FedRAMPEnvironment,
+			apiURL:          kokumetricscfgv1beta1.DefaultAPIURL,
+			ingressPath:     kokumetricscfgv1beta1.DefaultIngressPath,
+			wantEnvironment: kokumetricscfgv1beta1.FedRAMPEnvironment,
+			wantAPIURL:      fedramp.APIURL,
+			wantIngressPath: fedramp.IngressPath,
+			wantSSOTokenURL: fedramp.SSOTokenURL,
+		},
+		{
+			name:            "changed spec values override the environment",
+			environment:     kokumetricscfgv1beta1.FedRAMPEnvironment,
+			apiURL:          "https://api.example.com",
+			ingressPath:     "/upload",
+			wantEnvironment: kokumetricscfgv1beta1.FedRAMPEnvironment,
+			wantAPIURL:      "https://api.example.com",
+			wantIngressPath: "/upload",
+			wantSSOTokenURL: fedramp.SSOTokenURL,
+		},
+	}
+	for _, tt := range environmentTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.APIEnvironment = tt.environment
+			kmCfg.Spec.APIURL = tt.apiURL
+			kmCfg.Spec.Upload.IngressAPIPath = tt.ingressPath
+			reflectAPIEnvironment(kmCfg)
+			if kmCfg.Status.APIEnvironment != tt.wantEnvironment {
+				t.Errorf("%s got environment %s want %s", tt.name, kmCfg.Status.APIEnvironment, tt.wantEnvironment)
+			}
+			if kmCfg.Status.APIURL != tt.wantAPIURL {
+				t.Errorf("%s got api url %s want %s", tt.name, kmCfg.Status.APIURL, tt.wantAPIURL)
+			}
+			if kmCfg.Status.Upload.IngressAPIPath != tt.wantIngressPath {
+				t.Errorf("%s got ingress path %s want %s", tt.name, kmCfg.Status.Upload.IngressAPIPath, tt.wantIngressPath)
+			}
+			if kmCfg.Status.Source.SourcesAPIPath != kokumetricscfgv1beta1.DefaultSourcesPath {
+				t.Errorf("%s got sources path %s", tt.name, kmCfg.Status.Source.SourcesAPIPath)
+			}
+			if kmCfg.Status.Authentication.SSOTokenURL != tt.wantSSOTokenURL {
+				t.Errorf("%s got sso token url %s want %s", tt.name, kmCfg.Status.Authentication.SSOTokenURL, tt.wantSSOTokenURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+// serviceAccountTokenMargin is how long before it expires a service account token is replaced
+const serviceAccountTokenMargin = time.Minute
+
+// cachedServiceAccountToken is the token issued for the client ID at the token URL, reused until it expires
+type cachedServiceAccountToken struct {
+	tokenURL string
+	clientID string
+	token    crhchttp.ServiceAccountToken
+}
+
+var (
+	// serviceAccountTokenMu guards serviceAccountToken, which is shared by the reconciles and the diagnostics
+	serviceAccountTokenMu sync.Mutex
+	serviceAccountToken   *cachedServiceAccountToken
+
+	// requestServiceAccountToken is replaced in tests
+	requestServiceAccountToken = crhchttp.RequestServiceAccountToken
+)
+
+// reflectAuthenticationMethods reflects the list of authentication methods in the status. When the list is set, the
+// type is the first method until setAuthentication finds the credentials of a method.
+func reflectAuthenticationMethods(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	kmCfg.Status.Authentication.Methods = kmCfg.Spec.Authentication.Methods
+	if len(kmCfg.Spec.Authentication.Methods) == 0 {
+		kmCfg.Status.Authentication.FallbackErrors = nil
+		kmCfg.Status.Authentication.RejectedMethods = nil
+		return
+	}
+	kmCfg.Status.Authentication.AuthType = kmCfg.Spec.Authentication.Methods[0]
+	var rejected []kokumetricscfgv1beta1.AuthenticationType
+	for _, method := range kmCfg.Status.Authentication.RejectedMethods {
+		if hasAuthenticationMethod(kmCfg.Spec.Authentication.Methods, method) {
+			rejected = append(rejected, method)
+		}
+	}
+	kmCfg.Status.Authentication.RejectedMethods = rejected
+}
+
+// hasAuthenticationMethod returns true if the method is in the list
+func hasAuthenticationMethod(methods []kokumetricscfgv1beta1.AuthenticationType, method kokumetricscfgv1beta1.AuthenticationType) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectAuthenticationMethod records that the credentials of the method were rejected, so that setAuthentication
+// advances to the next method. Nothing is recorded without a list of methods.
+func rejectAuthenticationMethod(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, method kokumetricscfgv1beta1.AuthenticationType, reason string) {
+	status := &kmCfg.Status.Authentication
+	if !hasAuthenticationMethod(status.Methods, method) || hasAuthenticationMethod(status.RejectedMethods, method) {
+		return
+	}
+	r.Log.Info("the credentials of the authentication method were rejected, the next method is used", "method", method, "reason", reason)
+	status.RejectedMethods = append(status.RejectedMethods, method)
+	if status.SucceededMethod == method {
+		status.SucceededMethod = ""
+	}
+}
+
+// recordUploadMethod records the authentication method of an ingress upload as succeeded when the upload is accepted,
+// and as rejected when ingress rejects its credentials with a 401 or 403
+func recordUploadMethod(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, result uploader.Result) {
+	ingress, ok := result.Exporter.(*exporter.Ingress)
+	if !ok || ingress.AuthConfig == nil {
+		return
+	}
+	method := ingress.AuthConfig.Authentication
+	if result.Err == nil {
+		kmCfg.Status.Authentication.SucceededMethod = method
+		return
+	}
+	var respErr *crhchttp.ResponseError
+	if errors.As(result.Err, &respErr) && (respErr.StatusCode == http.StatusUnauthorized || respErr.StatusCode == http.StatusForbidden) {
+		rejectAuthenticationMethod(r, kmCfg, method, fmt.Sprintf("ingress responded %d %s", respErr.StatusCode, http.StatusText(respErr.StatusCode)))
+	}
+}
+
+// setAuthentication obtains the credentials of the authentication type. When a list of methods is set, the methods are
+// tried in order, and the first method whose credentials are found is used and recorded as the authentication type.
+// Methods whose credentials were rejected are skipped, until every method has been rejected and they are all tried again.
+func setAuthentication(r *KokuMetricsConfigReconciler, authConfig *crhchttp.AuthConfig, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, reqNamespace types.NamespacedName) error {
+	methods := kmCfg.Status.Authentication.Methods
+	if len(methods) == 0 {
+		return setAuthenticationMethod(r, authConfig, kmCfg, reqNamespace)
+	}
+	log := r.Log.WithValues("KokuMetricsConfig", "setAuthentication")
+
+	errorMessage, validBasicAuth := kmCfg.Status.Authentication.AuthErrorMessage, kmCfg.Status.Authentication.ValidBasicAuth
+	if len(kmCfg.Status.Authentication.RejectedMethods) >= len(methods) {
+		log.Info("the credentials of every authentication method were rejected, trying them all again")
+		kmCfg.Status.Authentication.RejectedMethods = nil
+	}
+	var fallbackErrors []string
+	var err error
+	for _, method := range methods {
+		if hasAuthenticationMethod(kmCfg.Status.Authentication.RejectedMethods, method) {
+			fallbackErrors = append(fallbackErrors, fmt.Sprintf("%s: the credentials were rejected", method))
+			continue
+		}
+		kmCfg.Status.Authentication.AuthType = method
+		kmCfg.Status.Authentication.AuthErrorMessage = errorMessage
+		kmCfg.Status.Authentication.ValidBasicAuth = validBasicAuth
+		authConfig.Authentication = method
+		if method == kokumetricscfgv1beta1.Static {
+			err = fmt.Errorf("static authentication cannot be part of the authentication methods")
+		} else {
+			err = setAuthenticationMethod(r, authConfig, kmCfg, reqNamespace)
+		}
+		if err == nil {
+			if len(fallbackErrors) > 0 {
+				log.Info("falling back to the next authentication method", "method", method, "errors", fallbackErrors)
+			}
+			kmCfg.Status.Authentication.FallbackErrors = fallbackErrors
+			return nil
+		}
+		fallbackErrors = append(fallbackErrors, fmt.Sprintf("%s: %v", method, err))
+	}
+	log.Info("the credentials of every authentication method were not found", "errors", fallbackErrors)
+	kmCfg.Status.Authentication.FallbackErrors = fallbackErrors
+	return err
+}
+
+// setServiceAccountToken sets the bearer token issued by SSO for the client credentials of the service account.
+// The token is reused until shortly before it expires.
+func setServiceAccountToken(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, authConfig *crhchttp.AuthConfig, clientID, clientSecret string) error {
+	log := r.Log.WithValues("KokuMetricsConfig", "setServiceAccountToken")
+	tokenURL := kmCfg.Status.Authentication.SSOTokenURL
+	if tokenURL == "" {
+		tokenURL = kokumetricscfgv1beta1.DefaultSSOTokenURL
+	}
+
+	serviceAccountTokenMu.Lock()
+	defer serviceAccountTokenMu.Unlock()
+	cached := serviceAccountToken
+	if cached == nil || cached.tokenURL != tokenURL || cached.clientID != clientID ||
+		time.Now().Add(serviceAccountTokenMargin).After(cached.token.Expiry) {
+		log.Info("requesting a service account token", "client_id", clientID)
+		token, err := requestServiceAccountToken(authConfig, tokenURL, clientID, clientSecret)
+		if err != nil {
+			return err
+		}
+		cached = &cachedServiceAccountToken{tokenURL: tokenURL, clientID: clientID, token: token}
+		serviceAccountToken = cached
+	}
+	authConfig.BearerTokenString = cached.token.AccessToken
+	return nil
+}
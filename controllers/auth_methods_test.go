@@ -0,0 +1,269 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+func authMethodsReconciler(t *testing.T, data map[string]string) *KokuMetricsConfigReconciler {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	var objs []runtime.Object
+	if data != nil {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "auth-secret"},
+			Data:       map[string][]byte{},
+		}
+		for k, v := range data {
+			secret.Data[k] = []byte(v)
+		}
+		objs = append(objs, secret)
+	}
+	return &KokuMetricsConfigReconciler{
+		Client: fake.NewFakeClientWithScheme(s, objs...),
+		Scheme: s,
+		Log:    testutils.TestLogger{},
+	}
+}
+
+func stubServiceAccountToken(t *testing.T, calls *int, err error) func() {
+	previous := requestServiceAccountToken
+	requestServiceAccountToken = func(authConfig *crhchttp.AuthConfig, tokenURL, clientID, clientSecret string) (crhchttp.ServiceAccountToken, error) {
+		*calls++
+		if err != nil {
+			return crhchttp.ServiceAccountToken{}, err
+		}
+		return crhchttp.ServiceAccountToken{AccessToken: "sa-token-" + clientID, Expiry: time.Now().Add(15 * time.Minute)}, nil
+	}
+	serviceAccountToken = nil
+	previousValidation = nil
+	return func() {
+		requestServiceAccountToken = previous
+		serviceAccountToken = nil
+		previousValidation = nil
+	}
+}
+
+func TestSetAuthenticationMethods(t *testing.T) {
+	basicKeys := map[string]string{"username": "user", "password": "pass"}
+	serviceAccountKeys := map[string]string{"client_id": "id", "client_secret": "secret", "username": "user", "password": "pass"}
+	methodTests := []struct {
+		name          string
+		methods       []kokumetricscfgv1beta1.AuthenticationType
+		secret        map[string]string
+		rejected      []kokumetricscfgv1beta1.AuthenticationType
+		tokenErr      error
+		want          kokumetricscfgv1beta1.AuthenticationType
+		wantFallbacks []string
+		wantBearer    string
+		wantErr       bool
+	}{
+		{
+			name:       "first method is used",
+			methods:    []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.ServiceAccount, kokumetricscfgv1beta1.Basic},
+			secret:     serviceAccountKeys,
+			want:       kokumetricscfgv1beta1.ServiceAccount,
+			wantBearer: "sa-token-id",
+		},
+		{
+			name:          "missing service account keys fall back to basic",
+			methods:       []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.ServiceAccount, kokumetricscfgv1beta1.Basic},
+			secret:        basicKeys,
+			want:          kokumetricscfgv1beta1.Basic,
+			wantFallbacks: []string{"service-account"},
+		},
+		{
+			name:          "rejected service account falls back to basic",
+			methods:       []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.ServiceAccount, kokumetricscfgv1beta1.Basic},
+			secret:        serviceAccountKeys,
+			tokenErr:      errors.New("SSO rejected the service account credentials"),
+			want:          kokumetricscfgv1beta1.Basic,
+			wantFallbacks: []string{"service-account"},
+		},
+		{
+			name:          "method rejected by ingress is skipped",
+			methods:       []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.ServiceAccount, kokumetricscfgv1beta1.Basic},
+			secret:        serviceAccountKeys,
+			rejected:      []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.ServiceAccount},
+			want:          kokumetricscfgv1beta1.Basic,
+			wantFallbacks: []string{"service-account"},
+		},
+		{
+			name:       "every method rejected tries them all again",
+			methods:    []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.ServiceAccount, kokumetricscfgv1beta1.Basic},
+			secret:     serviceAccountKeys,
+			rejected:   []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.Basic, kokumetricscfgv1beta1.ServiceAccount},
+			want:       kokumetricscfgv1beta1.ServiceAccount,
+			wantBearer: "sa-token-id",
+		},
+		{
+			name:          "static is not part of the methods",
+			methods:       []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.Static, kokumetricscfgv1beta1.Basic},
+			secret:        basicKeys,
+			want:          kokumetricscfgv1beta1.Basic,
+			wantFallbacks: []string{"static"},
+		},
+		{
+			name:          "no credentials are found",
+			methods:       []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.ServiceAccount, kokumetricscfgv1beta1.Basic},
+			want:          kokumetricscfgv1beta1.Basic,
+			wantFallbacks: []string{"service-account", "basic"},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range methodTests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			defer stubServiceAccountToken(t, &calls, tt.tokenErr)()
+			r := authMethodsReconciler(t, tt.secret)
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Authentication.AuthType = kokumetricscfgv1beta1.Token
+			kmCfg.Spec.Authentication.AuthenticationSecretName = "auth-secret"
+			kmCfg.Spec.Authentication.Methods = tt.methods
+			kmCfg.Status.Authentication.AuthenticationSecretName = "auth-secret"
+			kmCfg.Status.Authentication.RejectedMethods = tt.rejected
+			reflectAuthenticationMethods(kmCfg)
+			authConfig := &crhchttp.AuthConfig{Log: testutils.TestLogger{}}
+
+			err := setAuthentication(r, authConfig, kmCfg, types.NamespacedName{Namespace: "koku-metrics-operator"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			status := kmCfg.Status.Authentication
+			if status.AuthType != tt.want || authConfig.Authentication != tt.want {
+				t.Errorf("%s got type %s and request type %s want %s", tt.name, status.AuthType, authConfig.Authentication, tt.want)
+			}
+			if len(status.FallbackErrors) != len(tt.wantFallbacks) {
+				t.Fatalf("%s got fallback errors %v want errors of %v", tt.name, status.FallbackErrors, tt.wantFallbacks)
+			}
+			for i, method := range tt.wantFallbacks {
+				if !strings.HasPrefix(status.FallbackErrors[i], method+": ") {
+					t.Errorf("%s got fallback error %q want an error of %s", tt.name, status.FallbackErrors[i], method)
+				}
+			}
+			if authConfig.BearerTokenString != tt.wantBearer {
+				t.Errorf("%s got bearer token %q want %q", tt.name, authConfig.BearerTokenString, tt.wantBearer)
+			}
+			if found := status.AuthenticationCredentialsFound != nil && *status.AuthenticationCredentialsFound; found == tt.wantErr {
+				t.Errorf("%s got credentials found %t want %t", tt.name, found, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReflectAuthenticationMethods(t *testing.T) {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.Authentication.AuthType = kokumetricscfgv1beta1.Static
+	kmCfg.Status.Authentication.FallbackErrors = []string{"token: not found"}
+	reflectAuthenticationMethods(kmCfg)
+	if kmCfg.Status.Authentication.AuthType != kokumetricscfgv1beta1.Static || kmCfg.Status.Authentication.FallbackErrors != nil {
+		t.Errorf("got %+v want the type kept and no fallback errors without methods", kmCfg.Status.Authentication)
+	}
+
+	kmCfg.Spec.Authentication.Methods = []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.ServiceAccount, kokumetricscfgv1beta1.Basic}
+	reflectAuthenticationMethods(kmCfg)
+	if kmCfg.Status.Authentication.AuthType != kokumetricscfgv1beta1.ServiceAccount || len(kmCfg.Status.Authentication.Methods) != 2 {
+		t.Errorf("got %+v want the first method as the type", kmCfg.Status.Authentication)
+	}
+
+	kmCfg.Status.Authentication.RejectedMethods = []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.Token, kokumetricscfgv1beta1.Basic}
+	reflectAuthenticationMethods(kmCfg)
+	if rejected := kmCfg.Status.Authentication.RejectedMethods; len(rejected) != 1 || rejected[0] != kokumetricscfgv1beta1.Basic {
+		t.Errorf("got rejected methods %v want only the listed method kept", rejected)
+	}
+}
+
+func TestRecordUploadMethod(t *testing.T) {
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.Authentication.Methods = []kokumetricscfgv1beta1.AuthenticationType{kokumetricscfgv1beta1.ServiceAccount, kokumetricscfgv1beta1.Basic}
+	ingress := &exporter.Ingress{AuthConfig: &crhchttp.AuthConfig{Authentication: kokumetricscfgv1beta1.ServiceAccount}}
+
+	recordUploadMethod(r, kmCfg, uploader.Result{Exporter: ingress})
+	if kmCfg.Status.Authentication.SucceededMethod != kokumetricscfgv1beta1.ServiceAccount {
+		t.Errorf("got succeeded method %q want %q", kmCfg.Status.Authentication.SucceededMethod, kokumetricscfgv1beta1.ServiceAccount)
+	}
+
+	recordUploadMethod(r, kmCfg, uploader.Result{Exporter: ingress, Err: errors.New("connection refused")})
+	if len(kmCfg.Status.Authentication.RejectedMethods) != 0 {
+		t.Errorf("got rejected methods %v want none for a transport error", kmCfg.Status.Authentication.RejectedMethods)
+	}
+
+	for i := 0; i < 2; i++ {
+		recordUploadMethod(r, kmCfg, uploader.Result{Exporter: ingress, Err: &crhchttp.ResponseError{StatusCode: 403}})
+	}
+	status := kmCfg.Status.Authentication
+	if len(status.RejectedMethods) != 1 || status.RejectedMethods[0] != kokumetricscfgv1beta1.ServiceAccount || status.SucceededMethod != "" {
+		t.Errorf("got rejected methods %v and succeeded method %q want the service account rejected once", status.RejectedMethods, status.SucceededMethod)
+	}
+
+	ingress.AuthConfig.Authentication = kokumetricscfgv1beta1.Token
+	recordUploadMethod(r, kmCfg, uploader.Result{Exporter: ingress, Err: &crhchttp.ResponseError{StatusCode: 401}})
+	if len(kmCfg.Status.Authentication.RejectedMethods) != 1 {
+		t.Errorf("got rejected methods %v want a method outside the list ignored", kmCfg.Status.Authentication.RejectedMethods)
+	}
+}
+
+func TestSetServiceAccountTokenCache(t *testing.T) {
+	calls := 0
+	defer stubServiceAccountToken(t, &calls, nil)()
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	authConfig := &crhchttp.AuthConfig{Log: testutils.TestLogger{}}
+
+	for i := 0; i < 2; i++ {
+		if err := setServiceAccountToken(r, kmCfg, authConfig, "id", "secret"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if calls != 1 || authConfig.BearerTokenString != "sa-token-id" {
+		t.Errorf("got %d token requests and token %q want 1 request for the cached token", calls, authConfig.BearerTokenString)
+	}
+
+	if err := setServiceAccountToken(r, kmCfg, authConfig, "other", "secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	serviceAccountToken.token.Expiry = time.Now()
+	if err := setServiceAccountToken(r, kmCfg, authConfig, "other", "secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d token requests want a new token for another client and for an expiring token", calls)
+	}
+}
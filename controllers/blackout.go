@@ -0,0 +1,234 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// the reasons of the events emitted when a blackout window starts and ends
+const (
+	reasonBlackoutStarted = "BlackoutStarted"
+	reasonBlackoutEnded   = "BlackoutEnded"
+)
+
+// cronSchedule is a parsed five field cron schedule. Each field is a bit set of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAll and dowAll are true when the day of the month or the day of the week field is `*`
+	domAll, dowAll bool
+}
+
+// cronFieldRanges are the minimum and maximum values of the minute, hour, day of month, month, and day of week fields.
+// A day of week of 7 is Sunday, like 0.
+var cronFieldRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// parseCronField parses a comma separated list of `*`, values, and ranges, each with an optional `/step`
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng = part[:i]
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid range in %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseCronSchedule parses a standard five field cron schedule: minute, hour, day of month, month, and day of week
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("parseCronSchedule: %q must have 5 fields", spec)
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		var err error
+		if bits[i], err = parseCronField(field, cronFieldRanges[i][0], cronFieldRanges[i][1]); err != nil {
+			return nil, fmt.Errorf("parseCronSchedule: %v", err)
+		}
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &cronSchedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAll: fields[2] == "*", dowAll: fields[4] == "*",
+	}, nil
+}
+
+// matches returns true if the schedule fires at the minute of t. As in cron, when both the day of the month and the
+// day of the week are restricted, a day matching either one matches.
+func (s *cronSchedule) matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 || s.hour&(1<<uint(t.Hour())) == 0 || s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAll || s.dowAll {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// windowBounds returns the start and end of the occurrence of the window that now falls in. ok is false when the
+// window is not in progress.
+func windowBounds(window kokumetricscfgv1beta1.BlackoutWindow, now time.Time) (start, end time.Time, ok bool, err error) {
+	if window.Schedule != "" {
+		if window.Start != nil || window.End != nil {
+			return start, end, false, fmt.Errorf("window %s has both a schedule and a start or end", window.Name)
+		}
+		schedule, err := parseCronSchedule(window.Schedule)
+		if err != nil {
+			return start, end, false, fmt.Errorf("window %s: %v", window.Name, err)
+		}
+		duration := kokumetricscfgv1beta1.DefaultBlackoutDurationMinutes
+		if window.DurationMinutes != nil {
+			duration = *window.DurationMinutes
+		}
+		// the window is in progress if it started within its duration
+		minute := now.UTC().Truncate(time.Minute)
+		for m := int64(0); m < duration; m++ {
+			start = minute.Add(-time.Duration(m) * time.Minute)
+			if schedule.matches(start) {
+				return start, start.Add(time.Duration(duration) * time.Minute), true, nil
+			}
+		}
+		return time.Time{}, time.Time{}, false, nil
+	}
+	if window.Start == nil || window.End == nil || !window.Start.Before(window.End) {
+		return start, end, false, fmt.Errorf("window %s must have a schedule, or a start before its end", window.Name)
+	}
+	start, end = window.Start.Time, window.End.Time
+	return start, end, !now.Before(start) && now.Before(end), nil
+}
+
+// activeBlackout returns the blackout window in progress, the one that ends last if several are. Invalid windows
+// are ignored and returned in the error.
+func activeBlackout(windows []kokumetricscfgv1beta1.BlackoutWindow, now time.Time) (name string, start, end time.Time, err error) {
+	var invalid []string
+	for _, window := range windows {
+		wStart, wEnd, ok, wErr := windowBounds(window, now)
+		if wErr != nil {
+			invalid = append(invalid, wErr.Error())
+			continue
+		}
+		if ok && wEnd.After(end) {
+			name, start, end = window.Name, wStart, wEnd
+		}
+	}
+	if len(invalid) > 0 {
+		err = fmt.Errorf("invalid blackout windows are ignored: %s", strings.Join(invalid, "; "))
+	}
+	return name, start, end, err
+}
+
+// reconcileBlackout returns true if a blackout window is in progress, in which case reports are neither collected
+// nor uploaded. When a window ends, the back-fill of the hours that were not collected during it is started.
+func reconcileBlackout(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, now time.Time, logger logr.Logger) bool {
+	log := logger.WithValues("KokuMetricsConfig", "reconcileBlackout")
+	status := &kmCfg.Status.Blackout
+	name, start, end, err := activeBlackout(kmCfg.Spec.Reporting.BlackoutWindows, now)
+	status.Error = ""
+	if err != nil {
+		log.Info(err.Error())
+		status.Error = err.Error()
+	}
+
+	if name != "" {
+		started := !status.Active || status.Window != name || !status.Start.Time.Equal(start)
+		status.Active = true
+		status.Window = name
+		status.Start = metav1.NewTime(start)
+		status.End = metav1.NewTime(end)
+		if started {
+			msg := fmt.Sprintf("blackout window %s is in progress until %s, reports are not collected or uploaded", name, end.UTC().Format(time.RFC3339))
+			log.Info(msg)
+			if r.Recorder != nil {
+				r.Recorder.Event(kmCfg, corev1.EventTypeNormal, reasonBlackoutStarted, msg)
+			}
+		}
+		return true
+	}
+	if !status.Active {
+		return false
+	}
+
+	status.Active = false
+	msg := fmt.Sprintf("blackout window %s ended", status.Window)
+	if bStart, bEnd, hours := missedHours(kmCfg.Status.Prometheus.LastQuerySuccessTime.Time, now, BillingLocation(kmCfg)); hours > 0 {
+		status.Backfill = kokumetricscfgv1beta1.RecollectionStatus{
+			Start:    metav1.NewTime(bStart),
+			End:      metav1.NewTime(bEnd),
+			NextHour: metav1.NewTime(bStart),
+		}
+		msg = fmt.Sprintf("%s, the %d missed hours are back-filled", msg, hours)
+	}
+	log.Info(msg)
+	if r.Recorder != nil {
+		r.Recorder.Event(kmCfg, corev1.EventTypeNormal, reasonBlackoutEnded, msg)
+	}
+	return false
+}
+
+// backfillBlackout collects the hours missed during the last blackout window
+func backfillBlackout(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, logger logr.Logger) {
+	status := &kmCfg.Status.Blackout.Backfill
+	if status.Start.IsZero() || status.Complete {
+		return
+	}
+	recollectHours(r, kmCfg, dirCfg, status, false, logger.WithValues("KokuMetricsConfig", "backfillBlackout"))
+}
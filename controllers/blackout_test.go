@@ -0,0 +1,185 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestCronSchedule(t *testing.T) {
+	// 2021-01-02 is a Saturday
+	saturday := time.Date(2021, 1, 2, 2, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		schedule string
+		times    map[time.Time]bool
+		wantErr  bool
+	}{
+		{
+			name:     "weekly",
+			schedule: "0 2 * * 6",
+			times:    map[time.Time]bool{saturday: true, saturday.Add(time.Minute): false, saturday.Add(24 * time.Hour): false},
+		},
+		{
+			name:     "steps and ranges",
+			schedule: "*/15 1-3 * * *",
+			times:    map[time.Time]bool{saturday.Add(45 * time.Minute): true, saturday.Add(50 * time.Minute): false, saturday.Add(2 * time.Hour): false},
+		},
+		{
+			name:     "sunday as 7",
+			schedule: "0 2 * * 7",
+			times:    map[time.Time]bool{saturday.Add(24 * time.Hour): true, saturday: false},
+		},
+		{
+			name:     "day of month or day of week",
+			schedule: "0 2 15 * 6",
+			times:    map[time.Time]bool{saturday: true, time.Date(2021, 1, 15, 2, 0, 0, 0, time.UTC): true, time.Date(2021, 1, 14, 2, 0, 0, 0, time.UTC): false},
+		},
+		{
+			name:     "lists",
+			schedule: "0,30 2 1,2 1 *",
+			times:    map[time.Time]bool{saturday.Add(30 * time.Minute): true, saturday.Add(24 * time.Hour): false},
+		},
+		{name: "too few fields", schedule: "0 2 * *", wantErr: true},
+		{name: "out of range", schedule: "0 24 * * *", wantErr: true},
+		{name: "invalid step", schedule: "*/0 * * * *", wantErr: true},
+		{name: "reversed range", schedule: "0 5-3 * * *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCronSchedule(tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			for at, want := range tt.times {
+				if got := schedule.matches(at); got != want {
+					t.Errorf("%s got match %t at %v want %t", tt.name, got, at, want)
+				}
+			}
+		})
+	}
+}
+
+func TestActiveBlackout(t *testing.T) {
+	now := time.Date(2021, 1, 2, 2, 30, 0, 0, time.UTC)
+	hour := int64(60)
+	start := metav1.NewTime(now.Add(-time.Hour))
+	end := metav1.NewTime(now.Add(time.Hour))
+	tests := []struct {
+		name     string
+		windows  []kokumetricscfgv1beta1.BlackoutWindow
+		wantName string
+		wantEnd  time.Time
+		wantErr  bool
+	}{
+		{name: "no windows"},
+		{
+			name:     "recurring window in progress",
+			windows:  []kokumetricscfgv1beta1.BlackoutWindow{{Name: "weekly", Schedule: "0 2 * * 6", DurationMinutes: &hour}},
+			wantName: "weekly",
+			wantEnd:  time.Date(2021, 1, 2, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "recurring window ended",
+			windows: []kokumetricscfgv1beta1.BlackoutWindow{{Name: "weekly", Schedule: "0 1 * * 6"}},
+		},
+		{
+			name:     "one-time window in progress",
+			windows:  []kokumetricscfgv1beta1.BlackoutWindow{{Name: "upgrade", Start: &start, End: &end}},
+			wantName: "upgrade",
+			wantEnd:  end.Time,
+		},
+		{
+			name: "window that ends last",
+			windows: []kokumetricscfgv1beta1.BlackoutWindow{
+				{Name: "weekly", Schedule: "0 2 * * 6"},
+				{Name: "upgrade", Start: &start, End: &end},
+			},
+			wantName: "upgrade",
+			wantEnd:  end.Time,
+		},
+		{
+			name: "invalid windows are ignored",
+			windows: []kokumetricscfgv1beta1.BlackoutWindow{
+				{Name: "both", Schedule: "0 2 * * 6", Start: &start},
+				{Name: "reversed", Start: &end, End: &start},
+				{Name: "weekly", Schedule: "0 2 * * 6"},
+			},
+			wantName: "weekly",
+			wantEnd:  time.Date(2021, 1, 2, 3, 0, 0, 0, time.UTC),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, _, gotEnd, err := activeBlackout(tt.windows, now)
+			if name != tt.wantName || !gotEnd.Equal(tt.wantEnd) {
+				t.Errorf("%s got window %q ending %v want %q ending %v", tt.name, name, gotEnd, tt.wantName, tt.wantEnd)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReconcileBlackout(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: recorder}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Spec.Reporting.BlackoutWindows = []kokumetricscfgv1beta1.BlackoutWindow{{Name: "weekly", Schedule: "0 2 * * 6"}}
+	kmCfg.Status.Prometheus.LastQuerySuccessTime = metav1.NewTime(time.Date(2021, 1, 2, 1, 5, 0, 0, time.UTC))
+	logger := testutils.TestLogger{}
+
+	// the window starts, and is only reported once
+	for _, at := range []time.Time{time.Date(2021, 1, 2, 2, 5, 0, 0, time.UTC), time.Date(2021, 1, 2, 2, 10, 0, 0, time.UTC)} {
+		if !reconcileBlackout(r, kmCfg, at, logger) {
+			t.Fatalf("got no blackout at %v", at)
+		}
+	}
+	if !kmCfg.Status.Blackout.Active || kmCfg.Status.Blackout.Window != "weekly" || len(recorder.Events) != 1 {
+		t.Errorf("got status %+v and %d events want the weekly window reported once", kmCfg.Status.Blackout, len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, reasonBlackoutStarted) {
+		t.Errorf("got event %s want %s", event, reasonBlackoutStarted)
+	}
+
+	// the hours missed during the window are back-filled once it ends
+	if reconcileBlackout(r, kmCfg, time.Date(2021, 1, 2, 4, 5, 0, 0, time.UTC), logger) {
+		t.Fatal("got a blackout after the window ended")
+	}
+	backfill := kmCfg.Status.Blackout.Backfill
+	wantStart := time.Date(2021, 1, 2, 1, 0, 0, 0, time.UTC)
+	if kmCfg.Status.Blackout.Active || !backfill.Start.Time.Equal(wantStart) || !backfill.NextHour.Time.Equal(wantStart) ||
+		!backfill.End.Time.Equal(time.Date(2021, 1, 2, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("got status %+v want a back-fill from %v", kmCfg.Status.Blackout, wantStart)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, reasonBlackoutEnded) || !strings.Contains(event, "2 missed hours") {
+		t.Errorf("got event %s want %s with 2 missed hours", event, reasonBlackoutEnded)
+	}
+}
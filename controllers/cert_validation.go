@@ -0,0 +1,94 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+)
+
+// certPolicy is the certificate validation that was last reflected, so that skipped validations are logged once
+var certPolicy *crhchttp.CertPolicy
+
+// reflectCertValidation sets whether the certificate of each endpoint is validated. Certificates are validated unless
+// the validation of the endpoint is turned off in cert_validation. validate_cert is reflected for compatibility, but
+// never turns off the validation. The certificate of the SSO token endpoint is always validated. A condition is set
+// while the certificate of any endpoint is not validated.
+func reflectCertValidation(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, log logr.Logger) {
+	validateCert := kokumetricscfgv1beta1.DefaultValidateCert
+	if kmCfg.Spec.Upload.ValidateCert != nil {
+		validateCert = *kmCfg.Spec.Upload.ValidateCert
+	}
+	kmCfg.Status.Upload.ValidateCert = &validateCert
+
+	validate := func(endpoint *bool) bool {
+		return endpoint == nil || *endpoint
+	}
+	kmCfg.Status.Upload.CertValidation = kokumetricscfgv1beta1.CertValidationStatus{
+		Ingress: validate(kmCfg.Spec.Upload.CertValidation.Ingress),
+		Sources: validate(kmCfg.Spec.Upload.CertValidation.Sources),
+		SSO:     true,
+	}
+
+	policy := CertPolicy(kmCfg)
+	var skipped []string
+	for _, endpoint := range []crhchttp.Endpoint{crhchttp.IngressEndpoint, crhchttp.SourcesEndpoint} {
+		if !policy.Validate(endpoint) {
+			skipped = append(skipped, string(endpoint))
+		}
+	}
+	if certPolicy == nil || *certPolicy != policy {
+		for _, endpoint := range skipped {
+			log.Info("the certificate of the endpoint is not validated", "endpoint", endpoint)
+		}
+	}
+	certPolicy = &policy
+
+	if len(skipped) == 0 {
+		kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+			Type:    kokumetricscfgv1beta1.ConditionCertValidationDisabled,
+			Status:  corev1.ConditionFalse,
+			Reason:  kokumetricscfgv1beta1.ReasonCertificatesValidated,
+			Message: "the certificates of every endpoint are validated",
+		})
+		return
+	}
+	kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+		Type:   kokumetricscfgv1beta1.ConditionCertValidationDisabled,
+		Status: corev1.ConditionTrue,
+		Reason: kokumetricscfgv1beta1.ReasonInsecureEndpoints,
+		Message: fmt.Sprintf("certificates are not validated for the %s endpoint, so credentials and payloads may be sent to an impersonated server",
+			strings.Join(skipped, " and ")),
+	})
+}
+
+// CertPolicy returns the certificate validation of each endpoint of the KokuMetricsConfig
+func CertPolicy(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) crhchttp.CertPolicy {
+	return crhchttp.CertPolicy{
+		SkipIngress: !kmCfg.Status.Upload.CertValidation.Ingress,
+		SkipSources: !kmCfg.Status.Upload.CertValidation.Sources,
+	}
+}
@@ -0,0 +1,84 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestReflectCertValidation(t *testing.T) {
+	trueValue, falseValue := true, false
+	certTests := []struct {
+		name         string
+		validateCert *bool
+		spec         kokumetricscfgv1beta1.CertValidationSpec
+		want         kokumetricscfgv1beta1.CertValidationStatus
+		wantPolicy   crhchttp.CertPolicy
+	}{
+		{
+			name: "nil validate cert validates",
+			want: kokumetricscfgv1beta1.CertValidationStatus{Ingress: true, Sources: true, SSO: true},
+		},
+		{
+			name:         "validate cert false still validates",
+			validateCert: &falseValue,
+			want:         kokumetricscfgv1beta1.CertValidationStatus{Ingress: true, Sources: true, SSO: true},
+		},
+		{
+			name:         "insecure relay with validated sources",
+			validateCert: &trueValue,
+			spec:         kokumetricscfgv1beta1.CertValidationSpec{Ingress: &falseValue},
+			want:         kokumetricscfgv1beta1.CertValidationStatus{Ingress: false, Sources: true, SSO: true},
+			wantPolicy:   crhchttp.CertPolicy{SkipIngress: true},
+		},
+		{
+			name:         "endpoints opt out explicitly",
+			validateCert: &falseValue,
+			spec:         kokumetricscfgv1beta1.CertValidationSpec{Ingress: &falseValue, Sources: &falseValue},
+			want:         kokumetricscfgv1beta1.CertValidationStatus{Ingress: false, Sources: false, SSO: true},
+			wantPolicy:   crhchttp.CertPolicy{SkipIngress: true, SkipSources: true},
+		},
+	}
+	for _, tt := range certTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Upload.ValidateCert = tt.validateCert
+			kmCfg.Spec.Upload.CertValidation = tt.spec
+			reflectCertValidation(kmCfg, testutils.TestLogger{})
+			if kmCfg.Status.Upload.ValidateCert == nil {
+				t.Errorf("%s got a nil validate cert in the status", tt.name)
+			}
+			if kmCfg.Status.Upload.CertValidation != tt.want {
+				t.Errorf("%s got %+v want %+v", tt.name, kmCfg.Status.Upload.CertValidation, tt.want)
+			}
+			if got := CertPolicy(kmCfg); got != tt.wantPolicy {
+				t.Errorf("%s policy got %+v want %+v", tt.name, got, tt.wantPolicy)
+			}
+			wantDisabled := tt.wantPolicy != crhchttp.CertPolicy{}
+			if got := kokumetricscfgv1beta1.IsConditionTrue(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionCertValidationDisabled); got != wantDisabled {
+				t.Errorf("%s got the cert validation disabled condition %t want %t", tt.name, got, wantDisabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+)
+
+// checkClockSkew reflects the skew measured from the most recent ingress responses. Nothing is reflected until a
+// response has been received.
+func checkClockSkew(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	if offset, measured := crhchttp.ClockSkew(); measured {
+		setClockSkew(r, kmCfg, offset)
+	}
+}
+
+// setClockSkew reflects the skew between the local clock and the API clock in the status, and sets the ClockSkew
+// condition. An event is emitted when the skew first exceeds the threshold.
+func setClockSkew(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, offset time.Duration) {
+	kmCfg.Status.ClockSkewSeconds = int64(offset / time.Second)
+
+	threshold := time.Duration(kokumetricscfgv1beta1.DefaultClockSkewThreshold) * time.Second
+	if offset <= threshold && offset >= -threshold {
+		kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+			Type:    kokumetricscfgv1beta1.ConditionClockSkew,
+			Status:  corev1.ConditionFalse,
+			Reason:  kokumetricscfgv1beta1.ReasonClockSynchronized,
+			Message: fmt.Sprintf("the local clock is within %s of the API clock", threshold),
+		})
+		return
+	}
+
+	direction := "ahead of"
+	if offset < 0 {
+		direction = "behind"
+		offset = -offset
+	}
+	msg := fmt.Sprintf("the local clock is %s %s the API clock, the manifest date is corrected", offset, direction)
+	wasSkewed := kokumetricscfgv1beta1.IsConditionTrue(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionClockSkew)
+	kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+		Type:    kokumetricscfgv1beta1.ConditionClockSkew,
+		Status:  corev1.ConditionTrue,
+		Reason:  kokumetricscfgv1beta1.ReasonClockSkewed,
+		Message: msg,
+	})
+	r.Log.Info("detected clock skew", "skewSeconds", kmCfg.Status.ClockSkewSeconds)
+	if !wasSkewed && r.Recorder != nil {
+		r.Recorder.Event(kmCfg, corev1.EventTypeWarning, kokumetricscfgv1beta1.ReasonClockSkewed, msg)
+	}
+}
+
+// manifestClockSkew returns the skew that the manifest date is corrected by. The date is only corrected while
+// the skew exceeds the threshold.
+func manifestClockSkew(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) time.Duration {
+	if !kokumetricscfgv1beta1.IsConditionTrue(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionClockSkew) {
+		return 0
+	}
+	return time.Duration(kmCfg.Status.ClockSkewSeconds) * time.Second
+}
@@ -0,0 +1,72 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestSetClockSkew(t *testing.T) {
+	skewTests := []struct {
+		name         string
+		offset       time.Duration
+		prevSkewed   bool
+		wantSkewed   bool
+		wantEvent    bool
+		wantSeconds  int64
+		wantManifest time.Duration
+	}{
+		{name: "synchronized clock", offset: 2 * time.Second, wantSeconds: 2},
+		{name: "skew at the threshold", offset: -5 * time.Minute, wantSeconds: -300},
+		{name: "local clock ahead", offset: 10 * time.Minute, wantSkewed: true, wantEvent: true, wantSeconds: 600, wantManifest: 10 * time.Minute},
+		{name: "local clock behind", offset: -time.Hour, wantSkewed: true, wantEvent: true, wantSeconds: -3600, wantManifest: -time.Hour},
+		{name: "event is not repeated", offset: 10 * time.Minute, prevSkewed: true, wantSkewed: true, wantSeconds: 600, wantManifest: 10 * time.Minute},
+	}
+	for _, tt := range skewTests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: recorder}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			if tt.prevSkewed {
+				setClockSkew(r, kmCfg, tt.offset)
+				<-recorder.Events
+			}
+			setClockSkew(r, kmCfg, tt.offset)
+			if kmCfg.Status.ClockSkewSeconds != tt.wantSeconds {
+				t.Errorf("%s got %d seconds want %d", tt.name, kmCfg.Status.ClockSkewSeconds, tt.wantSeconds)
+			}
+			if got := kokumetricscfgv1beta1.IsConditionTrue(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionClockSkew); got != tt.wantSkewed {
+				t.Errorf("%s got skewed %t want %t", tt.name, got, tt.wantSkewed)
+			}
+			if got := len(recorder.Events) > 0; got != tt.wantEvent {
+				t.Errorf("%s got event %t want %t", tt.name, got, tt.wantEvent)
+			}
+			if got := manifestClockSkew(kmCfg); got != tt.wantManifest {
+				t.Errorf("%s got manifest skew %s want %s", tt.name, got, tt.wantManifest)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// previousHour returns the start of the UTC hour before now, in the billing time zone. Reports are collected for UTC
+// hours: in the billing time zone, the hour that is repeated when daylight saving time ends would never be collected.
+// The time zone only sets the boundaries of the billing periods.
+func previousHour(now time.Time, loc *time.Location) time.Time {
+	return now.UTC().Truncate(time.Hour).Add(-time.Hour).In(loc)
+}
+
+// hourlyWindow returns the range of the hour before now that is collected by a reconcile
+func hourlyWindow(now time.Time, loc *time.Location, step time.Duration) promv1.Range {
+	start := previousHour(now, loc)
+	return promv1.Range{
+		Start: start,
+		End:   start.Add(time.Hour - time.Second),
+		Step:  step,
+	}
+}
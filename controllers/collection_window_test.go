@@ -0,0 +1,50 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHourlyWindow(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	// daylight saving time ends in New York at 2026-11-01 06:00 UTC, when 01:00 EDT is followed by 01:00 EST
+	start := time.Date(2026, 11, 1, 3, 0, 0, 0, time.UTC)
+	seen := map[time.Time]bool{}
+	for hour := 0; hour < 6; hour++ {
+		now := start.Add(time.Duration(hour)*time.Hour + 5*time.Minute)
+		window := hourlyWindow(now, newYork, time.Minute)
+		want := start.Add(time.Duration(hour-1) * time.Hour)
+		if !window.Start.Equal(want) || !window.End.Equal(want.Add(time.Hour-time.Second)) {
+			t.Errorf("window at %s got %s to %s want the hour starting %s", now, window.Start.UTC(), window.End.UTC(), want)
+		}
+		if window.Start.Location() != newYork {
+			t.Errorf("window at %s got location %s want %s", now, window.Start.Location(), newYork)
+		}
+		seen[window.Start.UTC()] = true
+	}
+	if len(seen) != 6 {
+		t.Errorf("got %d distinct hours across the end of daylight saving time want 6", len(seen))
+	}
+}
@@ -0,0 +1,190 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const (
+	collectorStateConfigMapName = "koku-metrics-collector-state"
+	collectorStateKey           = "state.json"
+
+	// collectorStateSchemaVersion is the version of the collector state that this operator writes. It is
+	// incremented whenever the meaning of a field changes, and migrateCollectorState upgrades older versions.
+	collectorStateSchemaVersion = 1
+)
+
+// collectorUploadQueue is a summary of the payloads waiting to be uploaded
+type collectorUploadQueue struct {
+	PackagedFiles    int       `json:"packaged_files"`
+	CriticalPayloads int64     `json:"critical_payloads"`
+	BackfillPayloads int64     `json:"backfill_payloads"`
+	LastUploadTime   time.Time `json:"last_upload_time,omitempty"`
+}
+
+// collectorState is the state that recovery relies on. It is kept in a ConfigMap, instead of only the status, so
+// that it survives the loss of the PVC and the re-creation of the KokuMetricsConfig.
+type collectorState struct {
+	SchemaVersion int    `json:"schema_version"`
+	ClusterID     string `json:"cluster_id"`
+	// LastCollectedHour is the start of the last hour that reports were generated for
+	LastCollectedHour time.Time `json:"last_collected_hour,omitempty"`
+	// PendingRecollection is the re-collection that has not finished
+	PendingRecollection *kokumetricscfgv1beta1.RecollectionStatus `json:"pending_recollection,omitempty"`
+	UploadQueue         collectorUploadQueue                      `json:"upload_queue"`
+	UpdatedTime         time.Time                                 `json:"updated_time"`
+}
+
+// migrateCollectorState upgrades state written by an older operator to the current schema version
+func migrateCollectorState(state *collectorState) error {
+	switch {
+	case state.SchemaVersion > collectorStateSchemaVersion:
+		return fmt.Errorf("migrateCollectorState: schema version %d is newer than %d", state.SchemaVersion, collectorStateSchemaVersion)
+	case state.SchemaVersion < 1:
+		// state written before the schema was versioned has the same fields as version 1
+		state.SchemaVersion = 1
+	}
+	return nil
+}
+
+// newCollectorState returns the collector state described by the status
+func newCollectorState(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) *collectorState {
+	state := &collectorState{
+		SchemaVersion: collectorStateSchemaVersion,
+		ClusterID:     kmCfg.Status.ClusterID,
+		UploadQueue: collectorUploadQueue{
+			PackagedFiles:    len(kmCfg.Status.Packaging.PackagedFiles),
+			CriticalPayloads: kmCfg.Status.Upload.Queue.CriticalPayloads,
+			BackfillPayloads: kmCfg.Status.Upload.Queue.BackfillPayloads,
+			LastUploadTime:   kmCfg.Status.Upload.LastSuccessfulUploadTime.UTC(),
+		},
+	}
+	if success := kmCfg.Status.Prometheus.LastQuerySuccessTime; !success.IsZero() {
+		// reports are generated for the hour before the hour they are collected in
+		state.LastCollectedHour = previousHour(success.Time, time.UTC)
+	}
+	if recollection := kmCfg.Status.Recollection; recollection.Trigger != "" && !recollection.Complete {
+		state.PendingRecollection = recollection.DeepCopy()
+	}
+	return state
+}
+
+// restore sets the status from the collector state where the status has lost it
+func (s *collectorState) restore(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, log logr.Logger) {
+	if s.ClusterID != kmCfg.Status.ClusterID {
+		return
+	}
+	if kmCfg.Status.Prometheus.LastQuerySuccessTime.IsZero() && !s.LastCollectedHour.IsZero() {
+		log.Info("restoring the last collected hour from the collector state", "hour", s.LastCollectedHour)
+		kmCfg.Status.Prometheus.LastQuerySuccessTime = metav1.NewTime(s.LastCollectedHour.Add(time.Hour))
+	}
+	if kmCfg.Status.Recollection.Trigger == "" && s.PendingRecollection != nil {
+		log.Info("restoring the pending re-collection from the collector state", "trigger", s.PendingRecollection.Trigger)
+		kmCfg.Status.Recollection = *s.PendingRecollection.DeepCopy()
+	}
+}
+
+// getCollectorState reads the collector state ConfigMap. A nil state is returned if it does not exist. State
+// written by a newer operator is returned with an error.
+func getCollectorState(r *KokuMetricsConfigReconciler, namespace string) (*corev1.ConfigMap, *collectorState, error) {
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: namespace, Name: collectorStateConfigMapName}
+	if err := r.Get(context.Background(), key, cm); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("getCollectorState: failed to get ConfigMap: %v", err)
+	}
+	state := &collectorState{}
+	if err := json.Unmarshal([]byte(cm.Data[collectorStateKey]), state); err != nil {
+		return cm, nil, fmt.Errorf("getCollectorState: failed to parse state: %v", err)
+	}
+	if err := migrateCollectorState(state); err != nil {
+		return cm, state, fmt.Errorf("getCollectorState: %v", err)
+	}
+	return cm, state, nil
+}
+
+// recoverCollectorState restores the status from the collector state ConfigMap
+func recoverCollectorState(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, logger logr.Logger) {
+	log := logger.WithValues("KokuMetricsConfig", "recoverCollectorState")
+	_, state, err := getCollectorState(r, kmCfg.Namespace)
+	if err != nil {
+		log.Error(err, "failed to read the collector state")
+		return
+	}
+	if state != nil {
+		state.restore(kmCfg, log)
+	}
+}
+
+// saveCollectorState writes the collector state described by the status to the ConfigMap. The ConfigMap is not
+// owned by the KokuMetricsConfig, so that the state is kept if the KokuMetricsConfig is re-created.
+func saveCollectorState(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) error {
+	ctx := context.Background()
+	cm, existing, err := getCollectorState(r, kmCfg.Namespace)
+	if err != nil {
+		if cm == nil || existing != nil {
+			// the ConfigMap could not be read, or was written by a newer operator and is not downgraded
+			return err
+		}
+		// unreadable state is replaced
+	}
+
+	state := newCollectorState(kmCfg)
+	if existing != nil {
+		// the ConfigMap is only updated when the stored state, including its schema version, changes
+		state.UpdatedTime = existing.UpdatedTime
+		if desired, err := json.MarshalIndent(state, "", "  "); err == nil && string(desired) == cm.Data[collectorStateKey] {
+			return nil
+		}
+	}
+	state.UpdatedTime = time.Now().UTC()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("saveCollectorState: failed to marshal state: %v", err)
+	}
+
+	if cm == nil {
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: kmCfg.Namespace, Name: collectorStateConfigMapName}}
+		cm.Data = map[string]string{collectorStateKey: string(data)}
+		if err := r.Create(ctx, cm); err != nil {
+			return fmt.Errorf("saveCollectorState: failed to create ConfigMap: %v", err)
+		}
+		return nil
+	}
+	cm.Data = map[string]string{collectorStateKey: string(data)}
+	if err := r.Update(ctx, cm); err != nil {
+		return fmt.Errorf("saveCollectorState: failed to update ConfigMap: %v", err)
+	}
+	return nil
+}
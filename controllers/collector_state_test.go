@@ -0,0 +1,150 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func collectorStateConfig(clusterID string) *kokumetricscfgv1beta1.KokuMetricsConfig {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator"}}
+	kmCfg.Status.ClusterID = clusterID
+	kmCfg.Status.Reporting.BillingTimezone = "UTC"
+	return kmCfg
+}
+
+func getCollectorStateConfigMap(t *testing.T, r *KokuMetricsConfigReconciler) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: "koku-metrics-operator", Name: collectorStateConfigMapName}
+	if err := r.Get(context.Background(), key, cm); err != nil {
+		t.Fatalf("failed to get collector state: %v", err)
+	}
+	return cm
+}
+
+func TestCollectorStateRecovery(t *testing.T) {
+	r := &KokuMetricsConfigReconciler{Client: fake.NewFakeClient(), Log: testutils.TestLogger{}}
+	collected := time.Date(2021, 1, 2, 10, 5, 0, 0, time.UTC)
+
+	kmCfg := collectorStateConfig("cluster-id")
+	kmCfg.Status.Prometheus.LastQuerySuccessTime = metav1.NewTime(collected)
+	kmCfg.Status.Recollection = kokumetricscfgv1beta1.RecollectionStatus{
+		Trigger:  "2021-01-01/2021-01-02",
+		NextHour: metav1.NewTime(time.Date(2021, 1, 1, 5, 0, 0, 0, time.UTC)),
+	}
+	kmCfg.Status.Packaging.PackagedFiles = []string{"a.tar.gz", "b.tar.gz"}
+	if err := saveCollectorState(r, kmCfg); err != nil {
+		t.Fatalf("failed to save collector state: %v", err)
+	}
+	_, state, err := getCollectorState(r, kmCfg.Namespace)
+	if err != nil {
+		t.Fatalf("failed to get collector state: %v", err)
+	}
+	if !state.LastCollectedHour.Equal(time.Date(2021, 1, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("got last collected hour %v", state.LastCollectedHour)
+	}
+	if state.SchemaVersion != collectorStateSchemaVersion || state.UploadQueue.PackagedFiles != 2 {
+		t.Errorf("got state %+v", state)
+	}
+
+	// saving the same state does not update the ConfigMap
+	version := getCollectorStateConfigMap(t, r).ResourceVersion
+	if err := saveCollectorState(r, kmCfg); err != nil {
+		t.Fatalf("failed to save collector state: %v", err)
+	}
+	if got := getCollectorStateConfigMap(t, r).ResourceVersion; got != version {
+		t.Errorf("expected the unchanged state not to be written, resource version %s became %s", version, got)
+	}
+
+	// a re-created KokuMetricsConfig gets the state back
+	recreated := collectorStateConfig("cluster-id")
+	recoverCollectorState(r, recreated, testutils.TestLogger{})
+	if got := recreated.Status.Prometheus.LastQuerySuccessTime.UTC().Format(promCompareFormat); got != collected.Format(promCompareFormat) {
+		t.Errorf("got last query success hour %s want %s", got, collected.Format(promCompareFormat))
+	}
+	if recreated.Status.Recollection.Trigger != kmCfg.Status.Recollection.Trigger ||
+		!recreated.Status.Recollection.NextHour.Equal(&kmCfg.Status.Recollection.NextHour) {
+		t.Errorf("got recollection %+v", recreated.Status.Recollection)
+	}
+
+	// the state of another cluster is not restored
+	other := collectorStateConfig("other-cluster-id")
+	recoverCollectorState(r, other, testutils.TestLogger{})
+	if !other.Status.Prometheus.LastQuerySuccessTime.IsZero() || other.Status.Recollection.Trigger != "" {
+		t.Errorf("expected the state of another cluster not to be restored, got %+v", other.Status)
+	}
+}
+
+func TestCollectorStateLastCollectedHour(t *testing.T) {
+	// the last collected hour is a UTC hour, also when the billing time zone is not aligned to UTC hours
+	kmCfg := collectorStateConfig("cluster-id")
+	kmCfg.Status.Reporting.BillingTimezone = "Asia/Kolkata"
+	kmCfg.Status.Prometheus.LastQuerySuccessTime = metav1.NewTime(time.Date(2021, 1, 2, 10, 5, 0, 0, time.UTC))
+	state := newCollectorState(kmCfg)
+	if want := time.Date(2021, 1, 2, 9, 0, 0, 0, time.UTC); !state.LastCollectedHour.Equal(want) {
+		t.Errorf("got last collected hour %v want %v", state.LastCollectedHour, want)
+	}
+}
+
+func TestCollectorStateSchemaVersion(t *testing.T) {
+	stateTests := []struct {
+		name        string
+		data        string
+		wantRestore bool
+		wantWritten bool
+	}{
+		{name: "unversioned state is migrated", data: `{"cluster_id":"cluster-id","last_collected_hour":"2021-01-02T09:00:00Z"}`, wantRestore: true, wantWritten: true},
+		{name: "newer state is not used or overwritten", data: `{"schema_version":99,"cluster_id":"cluster-id","last_collected_hour":"2021-01-02T09:00:00Z"}`},
+		{name: "unreadable state is replaced", data: `{`, wantWritten: true},
+	}
+	for _, tt := range stateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: collectorStateConfigMapName},
+				Data:       map[string]string{collectorStateKey: tt.data},
+			}
+			r := &KokuMetricsConfigReconciler{Client: fake.NewFakeClient(cm), Log: testutils.TestLogger{}}
+			kmCfg := collectorStateConfig("cluster-id")
+			recoverCollectorState(r, kmCfg, testutils.TestLogger{})
+			if restored := !kmCfg.Status.Prometheus.LastQuerySuccessTime.IsZero(); restored != tt.wantRestore {
+				t.Errorf("%s got restored %v want %v", tt.name, restored, tt.wantRestore)
+			}
+
+			err := saveCollectorState(r, kmCfg)
+			if (err == nil) != tt.wantWritten {
+				t.Errorf("%s got error %v want written %v", tt.name, err, tt.wantWritten)
+			}
+			written := getCollectorStateConfigMap(t, r).Data[collectorStateKey] != tt.data
+			if written != tt.wantWritten {
+				t.Errorf("%s got written %v want %v", tt.name, written, tt.wantWritten)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+const (
+	// costEstimateDir is the directory, relative to the parent report directory, where estimates are written
+	costEstimateDir = "cost-estimates"
+	// maxCostEstimateFiles is the number of daily estimate reports retained on the volume
+	maxCostEstimateFiles = 31
+
+	rateCardCurrencyKey = "currency"
+	defaultCurrency     = "USD"
+)
+
+var costEstimateHeader = []string{
+	"interval_start",
+	"interval_end",
+	"namespace",
+	"cpu_usage_core_hours",
+	"cpu_request_core_hours",
+	"memory_usage_gib_hours",
+	"memory_request_gib_hours",
+	"cpu_cost",
+	"memory_cost",
+	"total_cost",
+	"currency",
+}
+
+var namespaceEstimatedCost = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "koku_metrics_namespace_estimated_cost_24h",
+		Help: "Estimated cost of each namespace over the last 24 hours of reports, using the rate card of the KokuMetricsConfig.",
+	},
+	[]string{"namespace", "currency"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(namespaceEstimatedCost)
+}
+
+// rateCard is the price of each unit of usage
+type rateCard struct {
+	Currency               string
+	CPUCoreUsagePerHour    float64
+	CPUCoreRequestPerHour  float64
+	MemoryGBUsagePerHour   float64
+	MemoryGBRequestPerHour float64
+}
+
+// namespaceCost is the estimated cost of a namespace's usage
+type namespaceCost struct {
+	CPU    float64
+	Memory float64
+}
+
+func (c namespaceCost) Total() float64 { return c.CPU + c.Memory }
+
+// parseRateCard reads the rate card from the data of a ConfigMap
+func parseRateCard(data map[string]string) (*rateCard, error) {
+	card := &rateCard{Currency: defaultCurrency}
+	rates := map[string]*float64{
+		"cpu_core_usage_per_hour":    &card.CPUCoreUsagePerHour,
+		"cpu_core_request_per_hour":  &card.CPUCoreRequestPerHour,
+		"memory_gb_usage_per_hour":   &card.MemoryGBUsagePerHour,
+		"memory_gb_request_per_hour": &card.MemoryGBRequestPerHour,
+	}
+	found := false
+	for key, value := range data {
+		if key == rateCardCurrencyKey {
+			card.Currency = strings.TrimSpace(value)
+			continue
+		}
+		rate, ok := rates[key]
+		if !ok {
+			return nil, fmt.Errorf("parseRateCard: unknown rate %q", key)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || f < 0 {
+			return nil, fmt.Errorf("parseRateCard: rate %s must be a non-negative decimal, got %q", key, value)
+		}
+		*rate = f
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("parseRateCard: rate card does not contain any rates")
+	}
+	return card, nil
+}
+
+// estimate returns the cost of the usage
+func (c *rateCard) estimate(usage collector.NamespaceUsage) namespaceCost {
+	return namespaceCost{
+		CPU:    usage.CPUUsageCoreHours*c.CPUCoreUsagePerHour + usage.CPURequestCoreHours*c.CPUCoreRequestPerHour,
+		Memory: usage.MemoryUsageGiBHours*c.MemoryGBUsagePerHour + usage.MemoryRequestGiBHours*c.MemoryGBRequestPerHour,
+	}
+}
+
+func formatFloat(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+// costEstimateRows returns the estimate rows for an hour of usage, sorted by namespace
+func costEstimateRows(card *rateCard, hour *collector.HourlyUsage) [][]string {
+	namespaces := make([]string, 0, len(hour.Namespaces))
+	for namespace := range hour.Namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	start := hour.Start.UTC()
+	var rows [][]string
+	for _, namespace := range namespaces {
+		usage := hour.Namespaces[namespace]
+		cost := card.estimate(usage)
+		rows = append(rows, []string{
+			start.Format("2006-01-02 15:04:05 +0000 UTC"),
+			start.Add(time.Hour - time.Second).Format("2006-01-02 15:04:05 +0000 UTC"),
+			namespace,
+			formatFloat(usage.CPUUsageCoreHours),
+			formatFloat(usage.CPURequestCoreHours),
+			formatFloat(usage.MemoryUsageGiBHours),
+			formatFloat(usage.MemoryRequestGiBHours),
+			formatFloat(cost.CPU),
+			formatFloat(cost.Memory),
+			formatFloat(cost.Total()),
+			card.Currency,
+		})
+	}
+	return rows
+}
+
+// writeCostEstimate appends the rows to the daily estimate report and returns the path of the report
+func writeCostEstimate(dir string, start time.Time, rows [][]string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("writeCostEstimate: failed to create directory: %v", err)
+	}
+	path := filepath.Join(dir, start.UTC().Format("20060102")+"-cost-estimate.csv")
+	_, err := os.Stat(path)
+	newFile := os.IsNotExist(err)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("writeCostEstimate: failed to open report: %v", err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if newFile {
+		if err := w.Write(costEstimateHeader); err != nil {
+			return "", fmt.Errorf("writeCostEstimate: failed to write header: %v", err)
+		}
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return "", fmt.Errorf("writeCostEstimate: failed to write rows: %v", err)
+	}
+	return path, nil
+}
+
+// trimCostEstimates removes the oldest daily reports beyond the retention limit
+func trimCostEstimates(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("trimCostEstimates: failed to read directory: %v", err)
+	}
+	var reports []string
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), "-cost-estimate.csv") {
+			reports = append(reports, file.Name())
+		}
+	}
+	// the report names start with the date, so they sort oldest first
+	sort.Strings(reports)
+	for len(reports) > maxCostEstimateFiles {
+		if err := os.Remove(filepath.Join(dir, reports[0])); err != nil {
+			return fmt.Errorf("trimCostEstimates: failed to remove %s: %v", reports[0], err)
+		}
+		reports = reports[1:]
+	}
+	return nil
+}
+
+func setEstimatedCostMetrics(card *rateCard, summary *usageSummary) {
+	namespaceEstimatedCost.Reset()
+	if card == nil || summary == nil {
+		return
+	}
+	for namespace, usage := range summary.Namespaces {
+		namespaceEstimatedCost.WithLabelValues(namespace, card.Currency).Set(card.estimate(usage).Total())
+	}
+}
+
+// updateCostEstimate multiplies the latest hour of usage by the rate card and writes the estimate to the PVC. The
+// estimated cost of the usage summary is exposed as a metric when enabled.
+func updateCostEstimate(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, latest *collector.HourlyUsage, summary *usageSummary, logger logr.Logger) error {
+	log := logger.WithValues("KokuMetricsConfig", "updateCostEstimate")
+	spec := kmCfg.Spec.CostEstimation
+	status := &kmCfg.Status.CostEstimation
+	if spec == nil {
+		*status = kokumetricscfgv1beta1.CostEstimationStatus{}
+		setEstimatedCostMetrics(nil, nil)
+		return nil
+	}
+	status.RateCardConfigMap = spec.RateCardConfigMap
+	status.EstimationError = ""
+
+	card, err := getRateCard(r, kmCfg, spec.RateCardConfigMap)
+	if err != nil {
+		status.EstimationError = err.Error()
+		return err
+	}
+	status.Currency = card.Currency
+
+	if spec.ExposeMetrics != nil && *spec.ExposeMetrics {
+		setEstimatedCostMetrics(card, summary)
+	} else {
+		setEstimatedCostMetrics(nil, nil)
+	}
+
+	dir := filepath.Join(dirCfg.Parent.Path, costEstimateDir)
+	path, err := writeCostEstimate(dir, latest.Start, costEstimateRows(card, latest))
+	if err != nil {
+		status.EstimationError = err.Error()
+		return err
+	}
+	log.Info("wrote cost estimate", "file", path)
+	status.LastEstimateFile = path
+	status.LastEstimateTime = metav1.Now()
+	return trimCostEstimates(dir)
+}
+
+// getRateCard reads the rate card from its ConfigMap
+func getRateCard(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, name string) (*rateCard, error) {
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: name}
+	if err := r.Get(context.Background(), key, cm); err != nil {
+		return nil, fmt.Errorf("getRateCard: failed to get ConfigMap %s: %v", name, err)
+	}
+	card, err := parseRateCard(cm.Data)
+	if err != nil {
+		return nil, fmt.Errorf("getRateCard: %v", err)
+	}
+	return card, nil
+}
@@ -0,0 +1,143 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/project-koku/koku-metrics-operator/collector"
+)
+
+func TestParseRateCard(t *testing.T) {
+	parseRateCardTests := []struct {
+		name      string
+		data      map[string]string
+		want      *rateCard
+		wantError bool
+	}{
+		{
+			name: "all rates",
+			data: map[string]string{
+				"cpu_core_usage_per_hour":    "0.07",
+				"cpu_core_request_per_hour":  "0.01",
+				"memory_gb_usage_per_hour":   "0.009",
+				"memory_gb_request_per_hour": " 0.002 ",
+				"currency":                   "EUR",
+			},
+			want: &rateCard{Currency: "EUR", CPUCoreUsagePerHour: 0.07, CPUCoreRequestPerHour: 0.01, MemoryGBUsagePerHour: 0.009, MemoryGBRequestPerHour: 0.002},
+		},
+		{
+			name: "default currency",
+			data: map[string]string{"cpu_core_usage_per_hour": "1"},
+			want: &rateCard{Currency: defaultCurrency, CPUCoreUsagePerHour: 1},
+		},
+		{name: "no rates", data: map[string]string{"currency": "USD"}, wantError: true},
+		{name: "unknown rate", data: map[string]string{"cpu_usage": "1"}, wantError: true},
+		{name: "invalid rate", data: map[string]string{"cpu_core_usage_per_hour": "cheap"}, wantError: true},
+		{name: "negative rate", data: map[string]string{"cpu_core_usage_per_hour": "-1"}, wantError: true},
+	}
+	for _, tt := range parseRateCardTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRateCard(tt.data)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s got %+v want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostEstimateRows(t *testing.T) {
+	card := &rateCard{Currency: "USD", CPUCoreUsagePerHour: 2, CPUCoreRequestPerHour: 1, MemoryGBUsagePerHour: 0.5, MemoryGBRequestPerHour: 0.25}
+	hour := &collector.HourlyUsage{
+		Start: time.Date(2021, 1, 5, 18, 0, 0, 0, time.UTC),
+		Namespaces: map[string]collector.NamespaceUsage{
+			"ns-b": {CPUUsageCoreHours: 1, MemoryUsageGiBHours: 4},
+			"ns-a": {CPUUsageCoreHours: 0.5, CPURequestCoreHours: 1, MemoryUsageGiBHours: 2, MemoryRequestGiBHours: 4},
+		},
+	}
+	want := [][]string{
+		{"2021-01-05 18:00:00 +0000 UTC", "2021-01-05 18:59:59 +0000 UTC", "ns-a", "0.5", "1", "2", "4", "2", "2", "4", "USD"},
+		{"2021-01-05 18:00:00 +0000 UTC", "2021-01-05 18:59:59 +0000 UTC", "ns-b", "1", "0", "4", "0", "2", "2", "4", "USD"},
+	}
+	if got := costEstimateRows(card, hour); !reflect.DeepEqual(got, want) {
+		t.Errorf("got rows %v want %v", got, want)
+	}
+}
+
+func TestWriteCostEstimate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cost-estimate")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	estimateDir := filepath.Join(dir, costEstimateDir)
+
+	start := time.Date(2021, 1, 5, 18, 0, 0, 0, time.UTC)
+	row := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}
+	for i := 0; i < 2; i++ {
+		path, err := writeCostEstimate(estimateDir, start.Add(time.Duration(i)*time.Hour), [][]string{row})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if filepath.Base(path) != "20210105-cost-estimate.csv" {
+			t.Errorf("got report %s", path)
+		}
+	}
+	f, err := os.Open(filepath.Join(estimateDir, "20210105-cost-estimate.csv"))
+	if err != nil {
+		t.Fatalf("failed to open report: %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	if len(records) != 3 || !reflect.DeepEqual(records[0], costEstimateHeader) {
+		t.Errorf("expected the header to be written once, got %v", records)
+	}
+
+	for i := 0; i < maxCostEstimateFiles+2; i++ {
+		if _, err := writeCostEstimate(estimateDir, start.AddDate(0, 0, i+1), [][]string{row}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := trimCostEstimates(estimateDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := ioutil.ReadDir(estimateDir)
+	if len(files) != maxCostEstimateFiles {
+		t.Errorf("got %d reports want %d", len(files), maxCostEstimateFiles)
+	}
+	if _, err := os.Stat(filepath.Join(estimateDir, "20210105-cost-estimate.csv")); !os.IsNotExist(err) {
+		t.Errorf("expected the oldest report to be removed")
+	}
+}
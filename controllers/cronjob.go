@@ -0,0 +1,347 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+const (
+	// cronJobName is the name of the CronJob of the cronjob execution mode
+	cronJobName = "koku-metrics-collector"
+	// operatorDeploymentName is the name of the operator Deployment that the Jobs are created from
+	operatorDeploymentName = "koku-metrics-controller-manager"
+	// reportsVolumeName is the name of the volume the reports are written to
+	reportsVolumeName = "koku-metrics-operator-reports"
+	// collectorClaimName is the name of the PVC that the Jobs write their reports to
+	collectorClaimName = "koku-metrics-collector-data"
+	// runOnceFlag is the flag of the operator binary that runs a single collection for a KokuMetricsConfig
+	runOnceFlag = "--run-once"
+)
+
+// collectorClaimSize is the size of the PVC of the Jobs. The Jobs only keep the reports and payloads that have not
+// been uploaded yet, so it is much smaller than the PVC of the operator.
+var collectorClaimSize = *resource.NewQuantity(1024*1024*1024, resource.BinarySI)
+
+// isCronJobMode returns true if the reports are collected by the Jobs of a CronJob
+func isCronJobMode(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	return kmCfg.Status.ExecutionMode == kokumetricscfgv1beta1.CronJobExecution
+}
+
+// reflectExecutionMode sets the execution mode and the schedule of the CronJob in the status
+func reflectExecutionMode(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	kmCfg.Status.ExecutionMode = kmCfg.Spec.ExecutionMode
+	if kmCfg.Status.ExecutionMode == "" {
+		kmCfg.Status.ExecutionMode = kokumetricscfgv1beta1.DefaultExecutionMode
+	}
+	kmCfg.Status.CronJob.Schedule = kmCfg.Spec.CronJob.Schedule
+	if kmCfg.Status.CronJob.Schedule == "" {
+		kmCfg.Status.CronJob.Schedule = kokumetricscfgv1beta1.DefaultCronJobSchedule
+	}
+	deadline := kokumetricscfgv1beta1.DefaultCronJobActiveDeadlineSeconds
+	if kmCfg.Spec.CronJob.ActiveDeadlineSeconds != nil {
+		deadline = *kmCfg.Spec.CronJob.ActiveDeadlineSeconds
+	}
+	kmCfg.Status.CronJob.ActiveDeadlineSeconds = &deadline
+}
+
+// buildCronJob returns the CronJob that runs the operator image once for the KokuMetricsConfig on each schedule. The
+// pod of the Job is built from the pod of the operator Deployment, so that it has the same service account,
+// environment, and trusted CA bundle, but the reports are written to the PVC of the Jobs instead of the PVC of the
+// operator, which is mounted by the operator pod.
+func buildCronJob(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dep *appsv1.Deployment) (*batchv1beta1.CronJob, error) {
+	podSpec := dep.Spec.Template.Spec.DeepCopy()
+	if len(podSpec.Containers) == 0 {
+		return nil, fmt.Errorf("buildCronJob: the Deployment %s has no containers", dep.Name)
+	}
+	manager := podSpec.Containers[0]
+	for _, c := range podSpec.Containers {
+		if c.Name == "manager" {
+			manager = c
+		}
+	}
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == reportsVolumeName {
+			podSpec.Volumes[i].VolumeSource = corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: collectorClaimName},
+			}
+		}
+	}
+	podSpec.Containers = []corev1.Container{{
+		Name:                     "collector",
+		Image:                    manager.Image,
+		ImagePullPolicy:          manager.ImagePullPolicy,
+		Command:                  manager.Command,
+		Args:                     []string{runOnceFlag, kmCfg.Name},
+		Env:                      manager.Env,
+		EnvFrom:                  manager.EnvFrom,
+		Resources:                manager.Resources,
+		VolumeMounts:             manager.VolumeMounts,
+		SecurityContext:          manager.SecurityContext,
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	}}
+	podSpec.InitContainers = nil
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+
+	// a failed Job is not retried: the payloads that it did not upload are kept on the PVC and uploaded by the next
+	// Job, which also collects the hours that it missed
+	backoffLimit := int32(0)
+	historyLimit := int32(1)
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: kmCfg.Namespace,
+			Name:      cronJobName,
+			Labels:    map[string]string{kokumetricscfgv1beta1.PayloadConfigLabel: kmCfg.Name},
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   kmCfg.Status.CronJob.Schedule,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					BackoffLimit:          &backoffLimit,
+					ActiveDeadlineSeconds: kmCfg.Status.CronJob.ActiveDeadlineSeconds,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{kokumetricscfgv1beta1.PayloadConfigLabel: kmCfg.Name},
+						},
+						Spec: *podSpec,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// reconcileCollectorClaim creates the PVC of the Jobs, so that the reports and payloads that a Job did not upload
+// are kept for the next Job
+func reconcileCollectorClaim(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) error {
+	ctx := context.Background()
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: kmCfg.Namespace, Name: collectorClaimName}, pvc)
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("reconcileCollectorClaim: failed to get PVC: %v", err)
+	}
+	pvc = &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: kmCfg.Namespace,
+			Name:      collectorClaimName,
+			Labels:    map[string]string{kokumetricscfgv1beta1.PayloadConfigLabel: kmCfg.Name},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: collectorClaimSize},
+			},
+		},
+	}
+	if err := ctrl.SetControllerReference(kmCfg, pvc, r.Scheme); err != nil {
+		return fmt.Errorf("reconcileCollectorClaim: failed to set owner reference: %v", err)
+	}
+	r.Log.Info("creating the PVC of the cronjob execution mode", "name", pvc.Name)
+	if err := r.Create(ctx, pvc); err != nil {
+		return fmt.Errorf("reconcileCollectorClaim: failed to create PVC: %v", err)
+	}
+	return nil
+}
+
+// reconcileCronJob creates the CronJob of the cronjob execution mode and its PVC, and updates the CronJob when it
+// differs from the desired CronJob
+func reconcileCronJob(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) error {
+	ctx := context.Background()
+
+	if err := reconcileCollectorClaim(r, kmCfg); err != nil {
+		return err
+	}
+
+	dep := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: kmCfg.Namespace, Name: operatorDeploymentName}, dep); err != nil {
+		return fmt.Errorf("reconcileCronJob: failed to get the operator Deployment: %v", err)
+	}
+	desired, err := buildCronJob(kmCfg, dep)
+	if err != nil {
+		return err
+	}
+
+	cronJob := &batchv1beta1.CronJob{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: desired.Namespace, Name: desired.Name}, cronJob); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("reconcileCronJob: failed to get CronJob: %v", err)
+		}
+		if err := ctrl.SetControllerReference(kmCfg, desired, r.Scheme); err != nil {
+			return fmt.Errorf("reconcileCronJob: failed to set owner reference: %v", err)
+		}
+		r.Log.Info("creating the CronJob of the cronjob execution mode", "name", desired.Name, "schedule", desired.Spec.Schedule)
+		if err := r.Create(ctx, desired); err != nil {
+			return fmt.Errorf("reconcileCronJob: failed to create CronJob: %v", err)
+		}
+		kmCfg.Status.CronJob.Name = desired.Name
+		return nil
+	}
+
+	kmCfg.Status.CronJob.Name = cronJob.Name
+	if cronJob.Status.LastScheduleTime != nil {
+		kmCfg.Status.CronJob.LastScheduleTime = *cronJob.Status.LastScheduleTime
+	}
+	// the API server sets defaults in the spec, so only the fields of the desired spec are compared
+	if reflect.DeepEqual(cronJob.Labels, desired.Labels) && equality.Semantic.DeepDerivative(desired.Spec, cronJob.Spec) {
+		return nil
+	}
+	r.Log.Info("updating the CronJob of the cronjob execution mode", "name", cronJob.Name)
+	cronJob.Labels = desired.Labels
+	cronJob.Spec = desired.Spec
+	if err := r.Update(ctx, cronJob); err != nil {
+		return fmt.Errorf("reconcileCronJob: failed to update CronJob: %v", err)
+	}
+	return nil
+}
+
+// removeCronJob deletes the CronJob when the execution mode is changed back to controller
+func removeCronJob(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) error {
+	ctx := context.Background()
+
+	cronJob := &batchv1beta1.CronJob{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: kmCfg.Namespace, Name: cronJobName}, cronJob); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("removeCronJob: failed to get CronJob: %v", err)
+	}
+	if !metav1.IsControlledBy(cronJob, kmCfg) {
+		return nil
+	}
+	r.Log.Info("deleting the CronJob of the cronjob execution mode", "name", cronJob.Name)
+	propagation := metav1.DeletePropagationBackground
+	if err := r.Delete(ctx, cronJob, &client.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("removeCronJob: failed to delete CronJob: %v", err)
+	}
+	if err := removeCollectorClaim(r, kmCfg); err != nil {
+		return err
+	}
+	kmCfg.Status.CronJob = kokumetricscfgv1beta1.CronJobStatus{
+		Schedule:              kmCfg.Status.CronJob.Schedule,
+		ActiveDeadlineSeconds: kmCfg.Status.CronJob.ActiveDeadlineSeconds,
+	}
+	return nil
+}
+
+// removeCollectorClaim deletes the PVC of the Jobs when the execution mode is changed back to controller
+func removeCollectorClaim(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) error {
+	ctx := context.Background()
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: kmCfg.Namespace, Name: collectorClaimName}, pvc); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("removeCollectorClaim: failed to get PVC: %v", err)
+	}
+	if !metav1.IsControlledBy(pvc, kmCfg) {
+		return nil
+	}
+	r.Log.Info("deleting the PVC of the cronjob execution mode", "name", pvc.Name)
+	if err := r.Delete(ctx, pvc); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("removeCollectorClaim: failed to delete PVC: %v", err)
+	}
+	return nil
+}
+
+// reconcileExecutionMode manages the CronJob of the execution mode. In cronjob mode, the reports are collected by
+// the Jobs, so a result is returned to end the reconcile once the CronJob is up to date.
+func reconcileExecutionMode(r *KokuMetricsConfigReconciler, status *statusWriter, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) (*ctrl.Result, error) {
+	if !isCronJobMode(kmCfg) {
+		if err := removeCronJob(r, kmCfg); err != nil {
+			r.Log.Error(err, "failed to remove the CronJob")
+		}
+		return nil, nil
+	}
+
+	kmCfg.Status.CronJob.Error = ""
+	if err := reconcileCronJob(r, kmCfg); err != nil {
+		r.Log.Error(err, "failed to reconcile the CronJob")
+		kmCfg.Status.CronJob.Error = err.Error()
+	}
+	if err := status.update(context.Background(), kmCfg); err != nil {
+		r.Log.Error(err, "failed to update KokuMetricsConfig status")
+		return &ctrl.Result{}, err
+	}
+	return &ctrl.Result{RequeueAfter: requeueInterval(kmCfg)}, nil
+}
+
+// jobUploadDelay caps the upload wait so that a Job starts its upload within the first half of its active deadline
+func jobUploadDelay(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, wait time.Duration) time.Duration {
+	if kmCfg.Status.CronJob.ActiveDeadlineSeconds == nil {
+		return wait
+	}
+	max := time.Duration(*kmCfg.Status.CronJob.ActiveDeadlineSeconds) * time.Second / 2
+	if wait > max {
+		return max
+	}
+	return wait
+}
+
+// RunOnce collects, packages, and uploads the reports of the KokuMetricsConfig a single time, and returns once the
+// payloads have been uploaded. It is run by the Jobs of the cronjob execution mode, where no upload worker runs.
+func (r *KokuMetricsConfigReconciler) RunOnce(name types.NamespacedName, stop <-chan struct{}) error {
+	r.runOnce = true
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: name}); err != nil {
+		return fmt.Errorf("RunOnce: %v", err)
+	}
+	if uploader.DefaultQueue.Busy() {
+		uploader.DefaultQueue.Drain(stop)
+	}
+
+	// the results of the uploads are written to the status
+	ctx := context.Background()
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	if err := r.Get(ctx, name, kmCfg); err != nil {
+		return fmt.Errorf("RunOnce: failed to get KokuMetricsConfig: %v", err)
+	}
+	status := newStatusWriter(r, kmCfg)
+	reflectUploadQueue(r, kmCfg, dirCfg)
+	kmCfg.Status.CronJob.LastRunTime = metav1.NewTime(time.Now())
+	if err := status.update(ctx, kmCfg); err != nil {
+		return fmt.Errorf("RunOnce: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func cronJobReconciler(t *testing.T, objs ...runtime.Object) *KokuMetricsConfigReconciler {
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{kokumetricscfgv1beta1.AddToScheme, appsv1.AddToScheme, batchv1beta1.AddToScheme, corev1.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatalf("failed to build scheme: %v", err)
+		}
+	}
+	return &KokuMetricsConfigReconciler{Client: fake.NewFakeClientWithScheme(s, objs...), Scheme: s, Log: testutils.TestLogger{}}
+}
+
+func cronJobConfig() *kokumetricscfgv1beta1.KokuMetricsConfig {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "kokumetricscfg-sample", UID: "uid"},
+	}
+	kmCfg.Spec.ExecutionMode = kokumetricscfgv1beta1.CronJobExecution
+	reflectExecutionMode(kmCfg)
+	return kmCfg
+}
+
+func operatorDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: operatorDeploymentName},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					ServiceAccountName: "koku-metrics-manager-role",
+					Containers: []corev1.Container{
+						{Name: "kube-rbac-proxy", Image: "proxy"},
+						{
+							Name:    "manager",
+							Image:   "koku-metrics-operator:v1",
+							Command: []string{"/manager"},
+							Args:    []string{"--enable-leader-election"},
+							Env:     []corev1.EnvVar{{Name: "IN_CLUSTER", Value: "true"}},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: reportsVolumeName, MountPath: "/tmp/koku-metrics-operator-reports"},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: reportsVolumeName,
+							VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+								ClaimName: "koku-metrics-operator-data",
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestReflectExecutionMode(t *testing.T) {
+	deadline := int64(600)
+	reflectTests := []struct {
+		name         string
+		spec         kokumetricscfgv1beta1.KokuMetricsConfigSpec
+		wantMode     kokumetricscfgv1beta1.ExecutionMode
+		wantSchedule string
+		wantDeadline int64
+	}{
+		{
+			name:         "defaults",
+			wantMode:     kokumetricscfgv1beta1.ControllerExecution,
+			wantSchedule: kokumetricscfgv1beta1.DefaultCronJobSchedule,
+			wantDeadline: kokumetricscfgv1beta1.DefaultCronJobActiveDeadlineSeconds,
+		},
+		{
+			name: "cronjob mode",
+			spec: kokumetricscfgv1beta1.KokuMetricsConfigSpec{
+				ExecutionMode: kokumetricscfgv1beta1.CronJobExecution,
+				CronJob:       kokumetricscfgv1beta1.CronJobSpec{Schedule: "*/30 * * * *", ActiveDeadlineSeconds: &deadline},
+			},
+			wantMode:     kokumetricscfgv1beta1.CronJobExecution,
+			wantSchedule: "*/30 * * * *",
+			wantDeadline: 600,
+		},
+	}
+	for _, tt := range reflectTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{Spec: tt.spec}
+			reflectExecutionMode(kmCfg)
+			if kmCfg.Status.ExecutionMode != tt.wantMode {
+				t.Errorf("%s got mode %s want %s", tt.name, kmCfg.Status.ExecutionMode, tt.wantMode)
+			}
+			if kmCfg.Status.CronJob.Schedule != tt.wantSchedule {
+				t.Errorf("%s got schedule %s want %s", tt.name, kmCfg.Status.CronJob.Schedule, tt.wantSchedule)
+			}
+			if *kmCfg.Status.CronJob.ActiveDeadlineSeconds != tt.wantDeadline {
+				t.Errorf("%s got deadline %d want %d", tt.name, *kmCfg.Status.CronJob.ActiveDeadlineSeconds, tt.wantDeadline)
+			}
+		})
+	}
+}
+
+func TestBuildCronJob(t *testing.T) {
+	kmCfg := cronJobConfig()
+	cronJob, err := buildCronJob(kmCfg, operatorDeployment())
+	if err != nil {
+		t.Fatalf("failed to build CronJob: %v", err)
+	}
+	if cronJob.Namespace != kmCfg.Namespace || cronJob.Name != cronJobName {
+		t.Errorf("got CronJob %s/%s", cronJob.Namespace, cronJob.Name)
+	}
+	if cronJob.Spec.Schedule != kokumetricscfgv1beta1.DefaultCronJobSchedule || cronJob.Spec.ConcurrencyPolicy != batchv1beta1.ForbidConcurrent {
+		t.Errorf("got schedule %s and concurrency policy %s", cronJob.Spec.Schedule, cronJob.Spec.ConcurrencyPolicy)
+	}
+	job := cronJob.Spec.JobTemplate.Spec
+	if *job.ActiveDeadlineSeconds != kokumetricscfgv1beta1.DefaultCronJobActiveDeadlineSeconds {
+		t.Errorf("got active deadline %d want %d", *job.ActiveDeadlineSeconds, kokumetricscfgv1beta1.DefaultCronJobActiveDeadlineSeconds)
+	}
+	pod := job.Template.Spec
+	if pod.RestartPolicy != corev1.RestartPolicyNever || pod.ServiceAccountName != "koku-metrics-manager-role" {
+		t.Errorf("got restart policy %s and service account %s", pod.RestartPolicy, pod.ServiceAccountName)
+	}
+	if len(pod.Containers) != 1 {
+		t.Fatalf("got %d containers want 1", len(pod.Containers))
+	}
+	c := pod.Containers[0]
+	if c.Image != "koku-metrics-operator:v1" || len(c.Env) != 1 || len(c.VolumeMounts) != 1 {
+		t.Errorf("got container %+v", c)
+	}
+	if len(c.Args) != 2 || c.Args[0] != runOnceFlag || c.Args[1] != kmCfg.Name {
+		t.Errorf("got args %v want [%s %s]", c.Args, runOnceFlag, kmCfg.Name)
+	}
+	if len(pod.Volumes) != 1 || pod.Volumes[0].PersistentVolumeClaim == nil || pod.Volumes[0].PersistentVolumeClaim.ClaimName != collectorClaimName {
+		t.Errorf("got volumes %+v want the reports on the %s PVC", pod.Volumes, collectorClaimName)
+	}
+
+	if _, err := buildCronJob(kmCfg, &appsv1.Deployment{}); err == nil {
+		t.Error("got no error for a Deployment without containers")
+	}
+}
+
+func TestReconcileExecutionMode(t *testing.T) {
+	ctx := context.Background()
+	kmCfg := cronJobConfig()
+	r := cronJobReconciler(t, kmCfg.DeepCopy(), operatorDeployment())
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: cronJobName}
+
+	// the CronJob is created in cronjob mode
+	res, err := reconcileExecutionMode(r, newStatusWriter(r, kmCfg), kmCfg)
+	if err != nil || res == nil || res.RequeueAfter == 0 {
+		t.Fatalf("got result %v and error %v want a requeue", res, err)
+	}
+	cronJob := &batchv1beta1.CronJob{}
+	if err := r.Get(ctx, key, cronJob); err != nil {
+		t.Fatalf("failed to get CronJob: %v", err)
+	}
+	if !metav1.IsControlledBy(cronJob, kmCfg) || kmCfg.Status.CronJob.Name != cronJobName || kmCfg.Status.CronJob.Error != "" {
+		t.Errorf("got owners %v and status %+v", cronJob.OwnerReferences, kmCfg.Status.CronJob)
+	}
+	claimKey := types.NamespacedName{Namespace: kmCfg.Namespace, Name: collectorClaimName}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, claimKey, pvc); err != nil {
+		t.Fatalf("failed to get the PVC of the Jobs: %v", err)
+	}
+	if !metav1.IsControlledBy(pvc, kmCfg) {
+		t.Errorf("got PVC owners %v", pvc.OwnerReferences)
+	}
+
+	// the CronJob is not updated when it has not changed
+	version := cronJob.ResourceVersion
+	if _, err := reconcileExecutionMode(r, newStatusWriter(r, kmCfg), kmCfg); err != nil {
+		t.Fatalf("failed to reconcile execution mode: %v", err)
+	}
+	if err := r.Get(ctx, key, cronJob); err != nil {
+		t.Fatalf("failed to get CronJob: %v", err)
+	}
+	if cronJob.ResourceVersion != version {
+		t.Errorf("got CronJob resource version %s want %s", cronJob.ResourceVersion, version)
+	}
+
+	// the CronJob is updated when the schedule changes, and the last schedule time is reflected
+	lastSchedule := metav1.NewTime(time.Date(2021, 1, 2, 10, 5, 0, 0, time.UTC))
+	cronJob.Status.LastScheduleTime = &lastSchedule
+	if err := r.Update(ctx, cronJob); err != nil {
+		t.Fatalf("failed to update CronJob: %v", err)
+	}
+	kmCfg.Spec.CronJob.Schedule = "*/30 * * * *"
+	reflectExecutionMode(kmCfg)
+	if _, err := reconcileExecutionMode(r, newStatusWriter(r, kmCfg), kmCfg); err != nil {
+		t.Fatalf("failed to reconcile execution mode: %v", err)
+	}
+	cronJob = &batchv1beta1.CronJob{}
+	if err := r.Get(ctx, key, cronJob); err != nil {
+		t.Fatalf("failed to get CronJob: %v", err)
+	}
+	if cronJob.Spec.Schedule != "*/30 * * * *" {
+		t.Errorf("got schedule %s want */30 * * * *", cronJob.Spec.Schedule)
+	}
+	if !kmCfg.Status.CronJob.LastScheduleTime.Equal(&lastSchedule) {
+		t.Errorf("got last schedule time %v want %v", kmCfg.Status.CronJob.LastScheduleTime, lastSchedule)
+	}
+
+	// the CronJob is removed when the mode is changed back to controller
+	kmCfg.Spec.ExecutionMode = kokumetricscfgv1beta1.ControllerExecution
+	reflectExecutionMode(kmCfg)
+	if res, err := reconcileExecutionMode(r, newStatusWriter(r, kmCfg), kmCfg); res != nil || err != nil {
+		t.Fatalf("got result %v and error %v want the reconcile to continue", res, err)
+	}
+	if err := r.Get(ctx, key, &batchv1beta1.CronJob{}); !errors.IsNotFound(err) {
+		t.Errorf("got error %v want the CronJob to be removed", err)
+	}
+	if err := r.Get(ctx, claimKey, &corev1.PersistentVolumeClaim{}); !errors.IsNotFound(err) {
+		t.Errorf("got error %v want the PVC of the Jobs to be removed", err)
+	}
+	if kmCfg.Status.CronJob.Name != "" {
+		t.Errorf("got CronJob name %s want it to be cleared", kmCfg.Status.CronJob.Name)
+	}
+}
+
+func TestReconcileExecutionModeMissingDeployment(t *testing.T) {
+	kmCfg := cronJobConfig()
+	r := cronJobReconciler(t, kmCfg.DeepCopy())
+	if _, err := reconcileExecutionMode(r, newStatusWriter(r, kmCfg), kmCfg); err != nil {
+		t.Fatalf("failed to reconcile execution mode: %v", err)
+	}
+	if kmCfg.Status.CronJob.Error == "" {
+		t.Error("got no CronJob error for a missing operator Deployment")
+	}
+}
+
+func TestJobUploadDelay(t *testing.T) {
+	kmCfg := cronJobConfig()
+	if got := jobUploadDelay(kmCfg, time.Hour); got != 15*time.Minute {
+		t.Errorf("got upload delay %s want 15m0s", got)
+	}
+	if got := jobUploadDelay(kmCfg, time.Minute); got != time.Minute {
+		t.Errorf("got upload delay %s want 1m0s", got)
+	}
+}
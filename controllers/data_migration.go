@@ -0,0 +1,66 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// dataMigrations are the migrations of the data on the volume, in the order of the data format versions. A change
+// to the layout or the format of the staged reports, index files, or manifests on the volume is added as the next
+// version, so that the data written by older operators is upgraded before it is packaged or uploaded.
+var dataMigrations = []dirconfig.DataMigration{
+	{
+		Version: 1,
+		Name:    "legacy-layout",
+		Migrate: func(dirCfg *dirconfig.DirectoryConfig) (*dirconfig.Migration, error) {
+			return dirCfg.MigrateLegacyLayout()
+		},
+	},
+}
+
+// migrateData upgrades the data on the volume to the current data format when the operator starts, and records the
+// migration in the status. The migration time and error are left untouched when there was nothing to migrate.
+func migrateData(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig) {
+	log := r.Log.WithValues("kokumetricsconfig", "migrateData")
+
+	result, err := dirCfg.MigrateData(dataMigrations)
+	kmCfg.Status.Storage.DataVersion = int64(result.Version)
+	if err == nil && result.Version == result.FromVersion {
+		return
+	}
+
+	kmCfg.Status.Storage.LastMigrationTime = metav1.Now()
+	kmCfg.Status.Storage.AppliedMigrations = result.Applied
+	kmCfg.Status.Storage.MigratedLayouts = result.Layouts
+	kmCfg.Status.Storage.MigratedFiles = int64(result.Files)
+	kmCfg.Status.Storage.MigrationError = ""
+	if err != nil {
+		log.Error(err, "failed to migrate the data on the volume", "version", result.Version)
+		kmCfg.Status.Storage.MigrationError = err.Error()
+	}
+	if len(result.Applied) > 0 {
+		log.Info("migrated the data on the volume", "from", result.FromVersion, "to", result.Version,
+			"migrations", result.Applied, "layouts", result.Layouts, "files", result.Files)
+	}
+}
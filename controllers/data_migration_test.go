@@ -0,0 +1,101 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestMigrateData(t *testing.T) {
+	migrationTests := []struct {
+		name        string
+		files       []string
+		version     string
+		missing     bool
+		wantFiles   int64
+		wantVersion int64
+		wantError   bool
+		wantRecord  bool
+	}{
+		{name: "unversioned volume is upgraded", wantVersion: 1, wantRecord: true},
+		{name: "legacy files are migrated", files: []string{"a.csv", "b.tar.gz"}, wantFiles: 2, wantVersion: 1, wantRecord: true},
+		{name: "migration error is recorded", files: []string{"a.csv"}, missing: true, wantError: true, wantRecord: true},
+		{name: "current volume is not migrated", files: []string{"a.csv"}, version: `{"version":1}`, wantVersion: 1},
+		{name: "newer data version is not migrated", files: []string{"a.csv"}, version: `{"version":9}`, wantVersion: 9, wantError: true, wantRecord: true},
+	}
+	for _, tt := range migrationTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "layout-migration")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			dirCfg := &dirconfig.DirectoryConfig{
+				Parent:  dirconfig.Directory{Path: dir},
+				Reports: dirconfig.Directory{Path: filepath.Join(dir, "data")},
+				Staging: dirconfig.Directory{Path: filepath.Join(dir, "staging")},
+				Upload:  dirconfig.Directory{Path: filepath.Join(dir, "upload")},
+			}
+			for _, d := range []dirconfig.Directory{dirCfg.Reports, dirCfg.Staging, dirCfg.Upload} {
+				if tt.missing && d == dirCfg.Reports {
+					continue
+				}
+				if err := os.Mkdir(d.Path, 0755); err != nil {
+					t.Fatalf("failed to create dir: %v", err)
+				}
+			}
+			for _, f := range tt.files {
+				if err := ioutil.WriteFile(filepath.Join(dir, f), nil, 0644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			}
+			if tt.version != "" {
+				if err := ioutil.WriteFile(filepath.Join(dir, dirconfig.DataVersionFile), []byte(tt.version), 0644); err != nil {
+					t.Fatalf("failed to write data version: %v", err)
+				}
+			}
+			r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+
+			migrateData(r, kmCfg, dirCfg)
+
+			got := kmCfg.Status.Storage
+			if got.MigratedFiles != tt.wantFiles {
+				t.Errorf("%s got %d migrated files want %d", tt.name, got.MigratedFiles, tt.wantFiles)
+			}
+			if got.DataVersion != tt.wantVersion {
+				t.Errorf("%s got data version %d want %d", tt.name, got.DataVersion, tt.wantVersion)
+			}
+			if (got.MigrationError != "") != tt.wantError {
+				t.Errorf("%s got error %q want error %t", tt.name, got.MigrationError, tt.wantError)
+			}
+			if got.LastMigrationTime.IsZero() == tt.wantRecord {
+				t.Errorf("%s got migration time %v want recorded %t", tt.name, got.LastMigrationTime, tt.wantRecord)
+			}
+		})
+	}
+}
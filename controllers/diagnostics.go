@@ -0,0 +1,155 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/mustgather"
+	"github.com/project-koku/koku-metrics-operator/sources"
+)
+
+// annotationTriggered returns the value of the annotation if it is set and differs from the last processed value
+func annotationTriggered(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, annotation, lastTrigger string) (string, bool) {
+	trigger, ok := kmCfg.GetAnnotations()[annotation]
+	if !ok || trigger == "" || trigger == lastTrigger {
+		return "", false
+	}
+	return trigger, true
+}
+
+// connectionTestRequested returns the value of the connection-test annotation if it has changed since the last test
+func connectionTestRequested(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) (string, bool) {
+	return annotationTriggered(kmCfg, kokumetricscfgv1beta1.ConnectionTestAnnotation, kmCfg.Status.ConnectionTest.Trigger)
+}
+
+// checkResult converts the outcome of a connection check into its status representation
+func checkResult(err error, success string) kokumetricscfgv1beta1.ConnectionCheck {
+	if err != nil {
+		return kokumetricscfgv1beta1.ConnectionCheck{Result: kokumetricscfgv1beta1.ConnectionCheckFailed, Message: err.Error()}
+	}
+	return kokumetricscfgv1beta1.ConnectionCheck{Result: kokumetricscfgv1beta1.ConnectionCheckSucceeded, Message: success}
+}
+
+// runConnectionTest checks connectivity to prometheus, the ingress endpoint, and the Sources API
+// without generating or uploading any data. The test is run each time the connection-test annotation
+// changes, and the results are written to the status.
+func runConnectionTest(r *KokuMetricsConfigReconciler, req ctrl.Request, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, logger logr.Logger) {
+	trigger, requested := connectionTestRequested(kmCfg)
+	if !requested {
+		return
+	}
+	log := logger.WithValues("KokuMetricsConfig", "runConnectionTest", "trigger", trigger)
+	log.Info("running connection test")
+
+	result := kokumetricscfgv1beta1.ConnectionTestStatus{
+		Trigger:     trigger,
+		LastRunTime: metav1.Now(),
+	}
+
+	setPromCollector(r, kmCfg)
+	err := r.promCollector.GetPromConn(kmCfg)
+	health.setPrometheusStatus(err)
+	result.Prometheus = checkResult(err, "prometheus test query succeeded")
+
+	if kmCfg.Spec.Upload.UploadToggle == nil || !*kmCfg.Spec.Upload.UploadToggle {
+		skipped := kokumetricscfgv1beta1.ConnectionCheck{
+			Result:  kokumetricscfgv1beta1.ConnectionCheckSkipped,
+			Message: "upload is disabled",
+		}
+		result.Ingress = skipped
+		result.Sources = skipped
+	} else {
+		result.Ingress, result.Sources = checkUploadEndpoints(r, req, kmCfg, logger)
+	}
+
+	log.Info("connection test complete",
+		"prometheus", result.Prometheus.Result,
+		"ingress", result.Ingress.Result,
+		"sources", result.Sources.Result)
+	kmCfg.Status.ConnectionTest = result
+}
+
+// checkUploadEndpoints sends an authenticated request to the ingress endpoint and to the Sources API, and returns
+// the result of each request
+func checkUploadEndpoints(r *KokuMetricsConfigReconciler, req ctrl.Request, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, logger logr.Logger) (ingress, sourcesCheck kokumetricscfgv1beta1.ConnectionCheck) {
+	authConfig := newAuthConfig(r, kmCfg, logger)
+	if err := setAuthentication(r, authConfig, kmCfg, req.NamespacedName); err != nil {
+		failed := checkResult(fmt.Errorf("failed to obtain credentials: %v", err), "")
+		return failed, failed
+	}
+
+	ingressURL := IngressURL(kmCfg)
+	status, err := r.uploader().CheckIngress(authConfig, ingressURL)
+	ingress = checkResult(err, fmt.Sprintf("ingress responded with %s", status))
+
+	if kmCfg.Status.Authentication.AuthType == kokumetricscfgv1beta1.Static {
+		sourcesCheck = kokumetricscfgv1beta1.ConnectionCheck{
+			Result:  kokumetricscfgv1beta1.ConnectionCheckSkipped,
+			Message: "sources are not checked with static token authentication",
+		}
+		return ingress, sourcesCheck
+	}
+	sSpec := &sources.SourceSpec{
+		APIURL: kmCfg.Status.APIURL,
+		Auth:   authConfig,
+		Spec:   kmCfg.Status.Source,
+		Log:    logger,
+	}
+	_, err = r.sourcesClient().GetSources(sSpec)
+	return ingress, checkResult(err, "Sources API request succeeded")
+}
+
+// writeDebugBundle assembles a sanitized support bundle on the report volume. A bundle is written
+// each time the debug-bundle annotation changes, and the location is written to the status.
+func writeDebugBundle(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, logger logr.Logger) {
+	trigger, requested := annotationTriggered(kmCfg, kokumetricscfgv1beta1.DebugBundleAnnotation, kmCfg.Status.DebugBundle.Trigger)
+	if !requested {
+		return
+	}
+	log := logger.WithValues("KokuMetricsConfig", "writeDebugBundle", "trigger", trigger)
+	log.Info("generating debug bundle")
+
+	gatherer := &mustgather.Gatherer{
+		KMCfg:  kmCfg,
+		DirCfg: dirCfg,
+		Logs:   mustgather.RecentLogs,
+		Log:    logger,
+	}
+	if r.promCollector != nil {
+		gatherer.QueryStats = r.promCollector.QueryStats
+	}
+
+	kmCfg.Status.DebugBundle.Trigger = trigger
+	kmCfg.Status.DebugBundle.LastGeneratedTime = metav1.Now()
+	path, err := gatherer.WriteBundle()
+	if err != nil {
+		log.Error(err, "failed to generate debug bundle")
+		kmCfg.Status.DebugBundle.Error = err.Error()
+		return
+	}
+	kmCfg.Status.DebugBundle.Path = path
+	kmCfg.Status.DebugBundle.Error = ""
+}
@@ -0,0 +1,151 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/testutils/fakes"
+)
+
+func TestConnectionTestRequested(t *testing.T) {
+	connectionTestRequestedTests := []struct {
+		name          string
+		annotations   map[string]string
+		lastTrigger   string
+		wantTrigger   string
+		wantRequested bool
+	}{
+		{name: "no annotations", annotations: nil, wantRequested: false},
+		{name: "empty annotation", annotations: map[string]string{kokumetricscfgv1beta1.ConnectionTestAnnotation: ""}, wantRequested: false},
+		{name: "new annotation", annotations: map[string]string{kokumetricscfgv1beta1.ConnectionTestAnnotation: "1"}, wantTrigger: "1", wantRequested: true},
+		{name: "annotation already processed", annotations: map[string]string{kokumetricscfgv1beta1.ConnectionTestAnnotation: "1"}, lastTrigger: "1", wantRequested: false},
+		{name: "annotation changed", annotations: map[string]string{kokumetricscfgv1beta1.ConnectionTestAnnotation: "2"}, lastTrigger: "1", wantTrigger: "2", wantRequested: true},
+	}
+	for _, tt := range connectionTestRequestedTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			kmCfg.Status.ConnectionTest.Trigger = tt.lastTrigger
+			gotTrigger, gotRequested := connectionTestRequested(kmCfg)
+			if gotTrigger != tt.wantTrigger || gotRequested != tt.wantRequested {
+				t.Errorf("%s got (%s, %t) want (%s, %t)", tt.name, gotTrigger, gotRequested, tt.wantTrigger, tt.wantRequested)
+			}
+		})
+	}
+}
+
+func TestRunConnectionTestUploadDisabled(t *testing.T) {
+	os.Setenv("SECRET_ABSPATH", "does-not-exist")
+	defer os.Unsetenv("SECRET_ABSPATH")
+	defer health.reset()
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{kokumetricscfgv1beta1.ConnectionTestAnnotation: "run"},
+		},
+	}
+	kmCfg.Spec.Upload.UploadToggle = &falseDef
+	kmCfg.Spec.PrometheusConfig.SkipTLSVerification = &falseDef
+
+	runConnectionTest(r, ctrl.Request{}, kmCfg, r.Log)
+
+	got := kmCfg.Status.ConnectionTest
+	if got.Trigger != "run" {
+		t.Errorf("trigger not recorded: got %s want run", got.Trigger)
+	}
+	if got.LastRunTime.IsZero() {
+		t.Error("last run time was not set")
+	}
+	if got.Prometheus.Result != kokumetricscfgv1beta1.ConnectionCheckFailed {
+		t.Errorf("prometheus check: got %s want %s", got.Prometheus.Result, kokumetricscfgv1beta1.ConnectionCheckFailed)
+	}
+	for name, check := range map[string]kokumetricscfgv1beta1.ConnectionCheck{"ingress": got.Ingress, "sources": got.Sources} {
+		if check.Result != kokumetricscfgv1beta1.ConnectionCheckSkipped {
+			t.Errorf("%s check: got %s want %s", name, check.Result, kokumetricscfgv1beta1.ConnectionCheckSkipped)
+		}
+	}
+}
+
+func TestRunConnectionTestFakes(t *testing.T) {
+	defer health.reset()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "basic-auth"},
+		Data:       map[string][]byte{authSecretUserKey: []byte("user"), authSecretPasswordKey: []byte("password")},
+	}
+	promConn := &fakes.PrometheusConnection{}
+	sourcesClient := &fakes.SourcesClient{}
+	uploader := &fakes.Uploader{Status: "405 Method Not Allowed"}
+	r := &KokuMetricsConfigReconciler{
+		Client:        fake.NewFakeClient(secret),
+		Log:           testutils.TestLogger{},
+		PromConn:      promConn,
+		SourcesClient: sourcesClient,
+		Uploader:      uploader,
+	}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "koku-metrics-operator",
+			Annotations: map[string]string{kokumetricscfgv1beta1.ConnectionTestAnnotation: "run"},
+		},
+	}
+	kmCfg.Spec.Upload.UploadToggle = &trueDef
+	kmCfg.Spec.Authentication.AuthenticationSecretName = secret.Name
+	kmCfg.Status.Authentication.AuthenticationSecretName = secret.Name
+	kmCfg.Status.Authentication.AuthType = kokumetricscfgv1beta1.Basic
+	kmCfg.Status.Upload.ValidateCert = &trueDef
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: kmCfg.Namespace}}
+	runConnectionTest(r, req, kmCfg, r.Log)
+
+	got := kmCfg.Status.ConnectionTest
+	for name, check := range map[string]kokumetricscfgv1beta1.ConnectionCheck{"prometheus": got.Prometheus, "ingress": got.Ingress, "sources": got.Sources} {
+		if check.Result != kokumetricscfgv1beta1.ConnectionCheckSucceeded {
+			t.Errorf("%s check: got %s (%s) want %s", name, check.Result, check.Message, kokumetricscfgv1beta1.ConnectionCheckSucceeded)
+		}
+	}
+	if queries := promConn.Queries(); len(queries) != 1 || queries[0] != "up" {
+		t.Errorf("prometheus queries: got %v want [up]", queries)
+	}
+	if requests := sourcesClient.Requests(); len(requests) != 1 || requests[0].Auth.BasicAuthUser != "user" {
+		t.Errorf("sources requests: got %+v want one request with the secret credentials", requests)
+	}
+	if uploads := uploader.Uploads(); len(uploads) != 0 {
+		t.Errorf("uploads: got %d want 0", len(uploads))
+	}
+}
+
+func TestCheckResult(t *testing.T) {
+	if got := checkResult(nil, "ok"); got.Result != kokumetricscfgv1beta1.ConnectionCheckSucceeded || got.Message != "ok" {
+		t.Errorf("unexpected result for success: %+v", got)
+	}
+	if got := checkResult(errors.New("boom"), "ok"); got.Result != kokumetricscfgv1beta1.ConnectionCheckFailed || got.Message != "boom" {
+		t.Errorf("unexpected result for failure: %+v", got)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// dryRunDir is the scratch directory, in the parent directory, that dry-run reports are generated into
+const dryRunDir = "dry-run"
+
+// isDryRun returns true when the operator only evaluates report generation
+func isDryRun(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	return kmCfg.Spec.DryRun != nil && *kmCfg.Spec.DryRun
+}
+
+// runDryRun generates the reports of the previous hour into a scratch directory, validates them, and writes a summary
+// to the status. The scratch directory is removed afterwards, and the window index and report status are left
+// untouched, so nothing is packaged or uploaded. The dry run is run once for each hour, or again after an error.
+func runDryRun(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig) {
+	log := r.Log.WithValues("kokumetricsconfig", "runDryRun")
+
+	timeRange := hourlyWindow(time.Now(), BillingLocation(kmCfg), queryStep(kmCfg))
+	last := kmCfg.Status.DryRun
+	if last.Error == "" && !last.LastRunTime.IsZero() && last.IntervalStart.Time.Equal(timeRange.Start) {
+		log.Info("dry run already run for range", "start", timeRange.Start, "end", timeRange.End)
+		return
+	}
+
+	status := kokumetricscfgv1beta1.DryRunStatus{
+		Enabled:       true,
+		LastRunTime:   metav1.Now(),
+		IntervalStart: metav1.Time{Time: timeRange.Start},
+	}
+	defer func() { kmCfg.Status.DryRun = status }()
+
+	setPromCollector(r, kmCfg)
+	r.promCollector.TimeSeries = nil
+	defer r.promCollector.SetEndpointStatus(kmCfg)
+	if err := reconcileQueryAllowList(r, kmCfg, log); err != nil {
+		status.Error = err.Error()
+		return
+	}
+	if err := r.promCollector.GetPromConn(kmCfg); err != nil {
+		log.Error(err, "failed to get prometheus connection")
+		status.Error = fmt.Sprintf("failed to get prometheus connection: %v", err)
+		return
+	}
+	if err := r.promCollector.GetUWMConn(kmCfg); err != nil {
+		log.Error(err, "failed to get user workload monitoring prometheus connection, custom metrics will not be collected")
+	}
+	r.promCollector.Index = nil
+	r.promCollector.TimeSeries = &timeRange
+
+	scratch := filepath.Join(dirCfg.Parent.Path, dryRunDir)
+	if err := os.RemoveAll(scratch); err != nil {
+		status.Error = fmt.Sprintf("failed to clear the scratch directory: %v", err)
+		return
+	}
+	if err := os.MkdirAll(scratch, os.ModePerm); err != nil {
+		status.Error = fmt.Sprintf("failed to create the scratch directory: %v", err)
+		return
+	}
+	defer os.RemoveAll(scratch)
+
+	// the reports are generated for a copy of the config, so that the report status and conditions are not changed
+	generated := kmCfg.DeepCopy()
+	scratchCfg := &dirconfig.DirectoryConfig{Parent: dirCfg.Parent, Reports: dirconfig.Directory{Path: scratch}}
+	log.Info("generating dry-run reports for range", "start", timeRange.Start, "end", timeRange.End)
+	if err := collector.GenerateReports(generated, scratchCfg, r.promCollector); err != nil {
+		log.Error(err, "failed to generate dry-run reports")
+		status.Error = fmt.Sprintf("failed to generate reports: %v", err)
+		return
+	}
+	if !generated.Status.Reports.DataCollected {
+		status.Error = generated.Status.Reports.DataCollectionMessage
+		return
+	}
+	status.EmptyReports = generated.Status.Reports.EmptyReports
+
+	reports, err := validateDryRunReports(scratch)
+	if err != nil {
+		status.Error = err.Error()
+		return
+	}
+	status.Reports = reports
+	status.Valid = len(reports) > 0 && len(status.EmptyReports) == 0
+	for _, report := range reports {
+		if report.Error != "" {
+			status.Valid = false
+		}
+	}
+	log.Info("dry run complete", "reports", len(reports), "valid", status.Valid)
+}
+
+// validateDryRunReports validates every report file in the scratch directory, including the reports of each tenant
+func validateDryRunReports(scratch string) ([]kokumetricscfgv1beta1.DryRunReport, error) {
+	var reports []kokumetricscfgv1beta1.DryRunReport
+	err := filepath.Walk(scratch, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".csv") {
+			return nil
+		}
+		name, err := filepath.Rel(scratch, path)
+		if err != nil {
+			return err
+		}
+		report := kokumetricscfgv1beta1.DryRunReport{File: name}
+		kind, rows, err := collector.ValidateReportFile(path)
+		report.Report = kind
+		report.Rows = int64(rows)
+		if err != nil {
+			report.Error = err.Error()
+		}
+		reports = append(reports, report)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("validateDryRunReports: %v", err)
+	}
+	return reports, nil
+}
@@ -0,0 +1,137 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/testutils/fakes"
+)
+
+func TestRunDryRun(t *testing.T) {
+	defer health.reset()
+	now := time.Now().UTC()
+	lastHour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour()-1, 0, 0, 0, time.UTC)
+	dryRunTests := []struct {
+		name      string
+		promErr   error
+		last      kokumetricscfgv1beta1.DryRunStatus
+		wantError string
+		wantRun   bool
+	}{
+		{name: "prometheus is unreachable", promErr: errors.New("connection refused"), wantError: "failed to get prometheus connection", wantRun: true},
+		{name: "no data to report", wantError: "No data to report", wantRun: true},
+		{
+			name:      "hour already run",
+			last:      kokumetricscfgv1beta1.DryRunStatus{Enabled: true, Valid: true, LastRunTime: metav1.Now(), IntervalStart: metav1.Time{Time: lastHour}},
+			wantError: "",
+		},
+		{
+			name:      "hour is run again after an error",
+			last:      kokumetricscfgv1beta1.DryRunStatus{Enabled: true, LastRunTime: metav1.Now(), IntervalStart: metav1.Time{Time: lastHour}, Error: "boom"},
+			wantError: "No data to report",
+			wantRun:   true,
+		},
+	}
+	for _, tt := range dryRunTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "dry-run")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			r := queryAllowListReconciler(t)
+			r.PromConn = &fakes.PrometheusConnection{Err: tt.promErr}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "kokumetricscfg-sample", UID: "uid"},
+			}
+			kmCfg.Spec.DryRun = &trueDef
+			kmCfg.Spec.PrometheusConfig.SkipTLSVerification = &falseDef
+			kmCfg.Status.DryRun = tt.last
+			dirCfg := &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: dir}}
+
+			runDryRun(r, kmCfg, dirCfg)
+
+			got := kmCfg.Status.DryRun
+			if !strings.Contains(got.Error, tt.wantError) || (tt.wantError == "") != (got.Error == "") {
+				t.Errorf("%s got error %q want %q", tt.name, got.Error, tt.wantError)
+			}
+			if ran := !got.LastRunTime.Equal(&tt.last.LastRunTime); ran != tt.wantRun {
+				t.Errorf("%s got run %t want %t", tt.name, ran, tt.wantRun)
+			}
+			if tt.wantRun && got.Valid {
+				t.Errorf("%s dry run is valid without reports", tt.name)
+			}
+			if _, err := os.Stat(filepath.Join(dir, dryRunDir)); !os.IsNotExist(err) {
+				t.Errorf("%s scratch directory was not removed: %v", tt.name, err)
+			}
+			if !kmCfg.Status.Prometheus.LastQuerySuccessTime.IsZero() || kmCfg.Status.Reports.DataCollectionMessage != "" {
+				t.Errorf("%s dry run changed the report status", tt.name)
+			}
+		})
+	}
+}
+
+func TestValidateDryRunReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dry-run-reports")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	header := "report_period_start,report_period_end,interval_start,interval_end,namespace,pod,metric_name,metric_value\n"
+	files := map[string]string{
+		"cm-openshift-custom-usage-202101.csv":                    header + "a,b,c,d,e,f,g,h\n",
+		filepath.Join(dirconfig.TenantDir, "team-a", "other.csv"): "a,b\n",
+		"notes.txt": "ignored",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	got, err := validateDryRunReports(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got reports %+v want 2", got)
+	}
+	if got[0].File != "cm-openshift-custom-usage-202101.csv" || got[0].Report != "custom" || got[0].Rows != 1 || got[0].Error != "" {
+		t.Errorf("got report %+v want a valid custom report with 1 row", got[0])
+	}
+	if got[1].File != filepath.Join(dirconfig.TenantDir, "team-a", "other.csv") || got[1].Error == "" {
+		t.Errorf("got report %+v want an invalid tenant report", got[1])
+	}
+}
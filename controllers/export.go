@@ -0,0 +1,207 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+// getDestinationCredentials returns the data of a destination's secret with lowercase keys
+func getDestinationCredentials(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, secretName string) (map[string]string, error) {
+	keys := make(map[string]string)
+	if secretName == "" {
+		return keys, nil
+	}
+	secret := &corev1.Secret{}
+	namespace := types.NamespacedName{Namespace: kmCfg.Namespace, Name: secretName}
+	if err := r.getSecret(context.Background(), namespace, secret); err != nil {
+		return nil, fmt.Errorf("getDestinationCredentials: failed to get secret %s: %v", secretName, err)
+	}
+	for k, v := range secret.Data {
+		keys[strings.ToLower(k)] = string(v)
+	}
+	return keys, nil
+}
+
+// findDestinationStatus returns the status for the named destination, adding it if needed
+func findDestinationStatus(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dest kokumetricscfgv1beta1.DestinationSpec) *kokumetricscfgv1beta1.DestinationStatus {
+	for i := range kmCfg.Status.Upload.Destinations {
+		if kmCfg.Status.Upload.Destinations[i].Name == dest.Name {
+			kmCfg.Status.Upload.Destinations[i].Type = dest.Type
+			return &kmCfg.Status.Upload.Destinations[i]
+		}
+	}
+	kmCfg.Status.Upload.Destinations = append(kmCfg.Status.Upload.Destinations,
+		kokumetricscfgv1beta1.DestinationStatus{Name: dest.Name, Type: dest.Type})
+	return &kmCfg.Status.Upload.Destinations[len(kmCfg.Status.Upload.Destinations)-1]
+}
+
+// pruneDestinationStatus removes the status of destinations that are no longer in the spec
+func pruneDestinationStatus(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	var kept []kokumetricscfgv1beta1.DestinationStatus
+	for _, status := range kmCfg.Status.Upload.Destinations {
+		for _, dest := range kmCfg.Spec.Upload.Destinations {
+			if dest.Name == status.Name {
+				kept = append(kept, status)
+				break
+			}
+		}
+	}
+	kmCfg.Status.Upload.Destinations = kept
+}
+
+// buildExporters returns the exporters for the CR. Ingress is first when authConfig is set. Destinations
+// that cannot be created are skipped, and the error is written to the destination status.
+func buildExporters(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, authConfig *crhchttp.AuthConfig, log logr.Logger) []exporter.Exporter {
+	var exporters []exporter.Exporter
+	if authConfig != nil {
+		exporters = append(exporters, &exporter.Ingress{
+			AuthConfig: authConfig,
+			URL:        activeAPIURL(kmCfg, log) + kmCfg.Status.Upload.IngressAPIPath,
+			Format:     kmCfg.Status.Upload.PayloadFormat,
+			Uploader:   r.uploader(),
+		})
+	}
+
+	pruneDestinationStatus(kmCfg)
+	for _, dest := range kmCfg.Spec.Upload.Destinations {
+		status := findDestinationStatus(kmCfg, dest)
+		credentials, err := getDestinationCredentials(r, kmCfg, dest.SecretName)
+		if err == nil {
+			var exp exporter.Exporter
+			if exp, err = exporter.New(dest, credentials); err == nil {
+				exporters = append(exporters, exp)
+				continue
+			}
+		}
+		log.Error(err, "skipping destination", "destination", dest.Name)
+		status.ExportError = err.Error()
+	}
+	return exporters
+}
+
+// recordExport writes the outcome of an export to the status
+func recordExport(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, result uploader.Result) {
+	exp, err := result.Exporter, result.Err
+	if _, ok := exp.(*exporter.Ingress); ok {
+		kmCfg.Status.Upload.LastUploadStatus = result.Status
+		kmCfg.Status.Upload.UploadError = ""
+		switch {
+		case err == nil:
+			kmCfg.Status.Upload.LastSuccessfulUploadTime = result.UploadTime
+		case !exporter.IsNotAccepted(err):
+			kmCfg.Status.Upload.UploadError = err.Error()
+		}
+		return
+	}
+	for i := range kmCfg.Status.Upload.Destinations {
+		status := &kmCfg.Status.Upload.Destinations[i]
+		if status.Name != exp.Name() {
+			continue
+		}
+		status.ExportError = ""
+		if err != nil {
+			status.ExportError = err.Error()
+			return
+		}
+		status.LastExportTime = metav1.Now()
+		return
+	}
+}
+
+// recordUploadedDimensions records the dimension reports of the payloads that ingress accepted as sent, so that later
+// payloads in delta mode only reference dimension reports that were received
+func recordUploadedDimensions(log logr.Logger, dirCfg *dirconfig.DirectoryConfig, results []uploader.Result) {
+	var uploaded []uploader.Result
+	for _, result := range results {
+		if _, ok := result.Exporter.(*exporter.Ingress); ok && result.Err == nil && result.Payload.PayloadID != "" {
+			uploaded = append(uploaded, result)
+		}
+	}
+	if len(uploaded) == 0 {
+		return
+	}
+	index, err := packaging.LoadDimensionIndex(filepath.Join(dirCfg.Parent.Path, packaging.DimensionIndexFile))
+	if err != nil {
+		log.Error(err, "failed to load the dimension index, dimension reports will be sent in full")
+		return
+	}
+	recorded := false
+	for _, result := range uploaded {
+		sentAt := result.UploadTime.Time
+		if sentAt.IsZero() {
+			sentAt = time.Now()
+		}
+		if index.RecordUploaded(result.Payload.PayloadID, sentAt) {
+			recorded = true
+		}
+	}
+	if !recorded {
+		return
+	}
+	if err := index.Save(); err != nil {
+		log.Error(err, "failed to record the uploaded dimension reports, they will be sent in full")
+	}
+}
+
+// lastExportTime returns the time used to schedule the upload cycle. Without ingress, the oldest
+// destination export is used so that every destination is exported to on the cycle.
+func lastExportTime(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, exporters []exporter.Exporter) metav1.Time {
+	for _, exp := range exporters {
+		if _, ok := exp.(*exporter.Ingress); ok {
+			return kmCfg.Status.Upload.LastSuccessfulUploadTime
+		}
+	}
+	var oldest metav1.Time
+	for i, status := range kmCfg.Status.Upload.Destinations {
+		if status.LastExportTime.IsZero() {
+			return metav1.Time{}
+		}
+		if i == 0 || status.LastExportTime.Before(&oldest) {
+			oldest = status.LastExportTime
+		}
+	}
+	return oldest
+}
+
+// uploadDelay returns the time to wait before the queued payloads are uploaded. An upload wait of 0 uploads as soon as the
+// upload cycle is due.
+func uploadDelay(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) time.Duration {
+	if kmCfg.Status.Upload.UploadWait == nil || *kmCfg.Status.Upload.UploadWait <= 0 {
+		return 0
+	}
+	return time.Duration(*kmCfg.Status.Upload.UploadWait) * time.Second
+}
@@ -0,0 +1,150 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+func TestUploadDelay(t *testing.T) {
+	zero := int64(0)
+	twenty := int64(20)
+	delayTests := []struct {
+		name       string
+		specWait   *int64
+		statusWait *int64
+		want       time.Duration
+	}{
+		{name: "upload wait of 0 uploads immediately", specWait: &zero, want: 0},
+		{name: "upload wait of 0 replaces a generated wait", specWait: &zero, statusWait: &twenty, want: 0},
+		{name: "upload wait from spec", specWait: &twenty, want: 20 * time.Second},
+		{name: "generated upload wait is kept", statusWait: &twenty, want: 20 * time.Second},
+	}
+	for _, tt := range delayTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Upload.UploadWait = tt.specWait
+			kmCfg.Status.Upload.UploadWait = tt.statusWait
+			ReflectSpec(&KokuMetricsConfigReconciler{}, kmCfg)
+			if got := uploadDelay(kmCfg); got != tt.want {
+				t.Errorf("%s got %s want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	ReflectSpec(&KokuMetricsConfigReconciler{}, kmCfg)
+	if got := uploadDelay(kmCfg); got < 0 || got >= 35*time.Second {
+		t.Errorf("generated upload wait %s is not between 0 and 34 seconds", got)
+	}
+	if got := uploadDelay(&kokumetricscfgv1beta1.KokuMetricsConfig{}); got != 0 {
+		t.Errorf("got %s for a nil upload wait, want 0", got)
+	}
+}
+
+func TestGetDestinationCredentialsSecretReader(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dest-secret", Namespace: "koku-metrics-operator"},
+		Data:       map[string][]byte{"Token": []byte("abc")},
+	}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator"}}
+	secretReaderTests := []struct {
+		name    string
+		r       *KokuMetricsConfigReconciler
+		wantErr bool
+	}{
+		{name: "secret read through the client", r: &KokuMetricsConfigReconciler{Client: fake.NewFakeClient(secret)}},
+		{
+			name: "secret read through the secret reader",
+			r:    &KokuMetricsConfigReconciler{Client: fake.NewFakeClient(), SecretReader: fake.NewFakeClient(secret)},
+		},
+		{
+			name:    "secret reader does not fall back to the client",
+			r:       &KokuMetricsConfigReconciler{Client: fake.NewFakeClient(secret), SecretReader: fake.NewFakeClient()},
+			wantErr: true,
+		},
+	}
+	for _, tt := range secretReaderTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getDestinationCredentials(tt.r, kmCfg, "dest-secret")
+			if err != nil != tt.wantErr {
+				t.Fatalf("%s got error %v want error %v", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && got["token"] != "abc" {
+				t.Errorf("%s got %v", tt.name, got)
+			}
+		})
+	}
+}
+
+func TestRecordUploadedDimensions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dimensions")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, packaging.DimensionIndexFile)
+	index, _ := packaging.LoadDimensionIndex(path)
+	for _, id := range []string{"uploaded", "failed", "exported"} {
+		index.Pend(id, []packaging.PendingDimension{{ClusterID: "cluster", Report: id, Hash: "hash", PackagedAt: time.Now()}})
+	}
+	if err := index.Save(); err != nil {
+		t.Fatalf("failed to save index: %v", err)
+	}
+
+	uploadTime := metav1.NewTime(time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC))
+	recordUploadedDimensions(testutils.TestLogger{}, &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: dir}}, []uploader.Result{
+		{Exporter: &exporter.Ingress{}, Payload: packaging.PayloadSummary{PayloadID: "uploaded"}, UploadTime: uploadTime},
+		{Exporter: &exporter.Ingress{}, Payload: packaging.PayloadSummary{PayloadID: "failed"}, Err: errors.New("connection refused")},
+		{Exporter: &exporter.Filesystem{}, Payload: packaging.PayloadSummary{PayloadID: "exported"}},
+	})
+
+	index, err = packaging.LoadDimensionIndex(path)
+	if err != nil {
+		t.Fatalf("failed to load index: %v", err)
+	}
+	if entry, ok := index.Get("cluster", "uploaded"); !ok || entry.PayloadID != "uploaded" || !entry.SentAt.Equal(uploadTime.Time) {
+		t.Errorf("got entry %+v, %t want the dimensions of the uploaded payload sent at the upload time", entry, ok)
+	}
+	for _, id := range []string{"failed", "exported"} {
+		if _, ok := index.Get("cluster", id); ok {
+			t.Errorf("got the dimensions of the %s payload recorded, want them pending", id)
+		}
+		if _, ok := index.Pending[id]; !ok {
+			t.Errorf("got no pending dimensions of the %s payload", id)
+		}
+	}
+}
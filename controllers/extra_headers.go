@@ -0,0 +1,96 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+)
+
+// extraHeaders returns the additional headers of the requests to cloud.redhat.com, and the names of the headers whose
+// values are read from secrets. The headers with an error are left out, and the errors are written to the status.
+func extraHeaders(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) (http.Header, []string) {
+	kmCfg.Status.Upload.ExtraHeaders = nil
+	kmCfg.Status.Upload.ExtraHeadersError = ""
+	if len(kmCfg.Spec.Upload.ExtraHeaders) <= 0 {
+		return nil, nil
+	}
+
+	staticTokenHeader := ""
+	if kmCfg.Status.Authentication.AuthType == kokumetricscfgv1beta1.Static {
+		staticTokenHeader = kmCfg.Status.Authentication.TokenHeader
+	}
+	headers := http.Header{}
+	var sensitive, errs []string
+	for _, header := range kmCfg.Spec.Upload.ExtraHeaders {
+		if crhchttp.ReservedHeader(header.Name, staticTokenHeader) {
+			errs = append(errs, fmt.Sprintf("header %s is set by the operator and cannot be overridden", header.Name))
+			continue
+		}
+		value, err := extraHeaderValue(r, kmCfg, header)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("header %s: %v", header.Name, err))
+			continue
+		}
+		if header.SecretName != "" {
+			sensitive = append(sensitive, header.Name)
+		}
+		headers.Add(header.Name, value)
+		kmCfg.Status.Upload.ExtraHeaders = append(kmCfg.Status.Upload.ExtraHeaders, header.Name)
+	}
+	kmCfg.Status.Upload.ExtraHeadersError = strings.Join(errs, "; ")
+	return headers, sensitive
+}
+
+// extraHeaderValue returns the value of the header, reading it from its secret when it is secret-backed
+func extraHeaderValue(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, header kokumetricscfgv1beta1.UploadHeader) (string, error) {
+	value := header.Value
+	if header.SecretName != "" {
+		if header.SecretKey == "" {
+			return "", fmt.Errorf("secret_key is required with secret_name")
+		}
+		secret := &corev1.Secret{}
+		key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: header.SecretName}
+		if err := r.getSecret(context.Background(), key, secret); err != nil {
+			return "", fmt.Errorf("failed to get secret %s: %v", header.SecretName, err)
+		}
+		data, ok := secret.Data[header.SecretKey]
+		if !ok {
+			return "", fmt.Errorf("secret %s does not contain %s", header.SecretName, header.SecretKey)
+		}
+		// the trailing newline that files created with kubectl often have is not part of the value
+		value = strings.TrimSpace(string(data))
+	}
+	if value == "" {
+		return "", fmt.Errorf("the value is empty")
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return "", fmt.Errorf("the value contains a line break")
+	}
+	return value, nil
+}
@@ -0,0 +1,94 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestExtraHeaders(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "relay-headers"},
+		Data:       map[string][]byte{"org_id": []byte("12345\n")},
+	}
+	r := &KokuMetricsConfigReconciler{Client: fake.NewFakeClient(secret), Log: testutils.TestLogger{}}
+	headerTests := []struct {
+		name          string
+		headers       []kokumetricscfgv1beta1.UploadHeader
+		wantHeaders   map[string]string
+		wantSensitive int
+		wantError     bool
+	}{
+		{
+			name:        "value",
+			headers:     []kokumetricscfgv1beta1.UploadHeader{{Name: "X-Route", Value: "east"}},
+			wantHeaders: map[string]string{"X-Route": "east"},
+		},
+		{
+			name:          "secret-backed value",
+			headers:       []kokumetricscfgv1beta1.UploadHeader{{Name: "X-Org-ID", SecretName: "relay-headers", SecretKey: "org_id"}},
+			wantHeaders:   map[string]string{"X-Org-Id": "12345"},
+			wantSensitive: 1,
+		},
+		{
+			name: "errors leave out the header",
+			headers: []kokumetricscfgv1beta1.UploadHeader{
+				{Name: "Authorization", Value: "Bearer other"},
+				{Name: "X-Missing", SecretName: "missing", SecretKey: "key"},
+				{Name: "X-Key", SecretName: "relay-headers", SecretKey: "missing"},
+				{Name: "X-Empty"},
+				{Name: "X-Route", Value: "east"},
+			},
+			wantHeaders: map[string]string{"X-Route": "east"},
+			wantError:   true,
+		},
+	}
+	for _, tt := range headerTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator"}}
+			kmCfg.Spec.Upload.ExtraHeaders = tt.headers
+			headers, sensitive := extraHeaders(r, kmCfg)
+			if len(headers) != len(tt.wantHeaders) {
+				t.Errorf("%s got headers %v want %v", tt.name, headers, tt.wantHeaders)
+			}
+			for name, value := range tt.wantHeaders {
+				if got := headers.Get(name); got != value {
+					t.Errorf("%s got %s %q want %q", tt.name, name, got, value)
+				}
+			}
+			if len(sensitive) != tt.wantSensitive {
+				t.Errorf("%s got sensitive headers %v want %d", tt.name, sensitive, tt.wantSensitive)
+			}
+			if len(kmCfg.Status.Upload.ExtraHeaders) != len(tt.wantHeaders) {
+				t.Errorf("%s got status headers %v", tt.name, kmCfg.Status.Upload.ExtraHeaders)
+			}
+			if got := kmCfg.Status.Upload.ExtraHeadersError != ""; got != tt.wantError {
+				t.Errorf("%s got error %q want error %t", tt.name, kmCfg.Status.Upload.ExtraHeadersError, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// fieldSet is the set of the fields of a struct of the spec that were written by a client. The fields are read from
+// the managed fields of the object, which the API server records for every create, update, and patch: a field that
+// was filled from its CRD default is not managed. When the object has no managed spec fields, for example because
+// it was created before the API server recorded them, every field that is not empty is set.
+type fieldSet struct {
+	managed map[string]interface{}
+	tracked bool
+}
+
+// specFieldSet returns the set of the spec fields of an object that were written by a client
+func specFieldSet(objMeta metav1.ObjectMeta) fieldSet {
+	set := fieldSet{managed: map[string]interface{}{}}
+	for _, entry := range objMeta.ManagedFields {
+		if entry.FieldsType != "FieldsV1" || entry.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if spec, ok := fields["f:spec"].(map[string]interface{}); ok {
+			mergeManagedFields(set.managed, spec)
+			set.tracked = true
+		}
+	}
+	return set
+}
+
+// mergeManagedFields adds the fields of src, in the FieldsV1 format, to dst
+func mergeManagedFields(dst, src map[string]interface{}) {
+	for key, value := range src {
+		child, ok := dst[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			dst[key] = child
+		}
+		if fields, ok := value.(map[string]interface{}); ok {
+			mergeManagedFields(child, fields)
+		}
+	}
+}
+
+// isSet returns true if the field with the given json name, and value, is set
+func (s fieldSet) isSet(name string, value reflect.Value) bool {
+	if !s.tracked {
+		return !value.IsZero()
+	}
+	_, ok := s.managed["f:"+name]
+	return ok
+}
+
+// child returns the set of the fields of the struct field with the given json name
+func (s fieldSet) child(name string) fieldSet {
+	if !s.tracked {
+		return s
+	}
+	managed, _ := s.managed["f:"+name].(map[string]interface{})
+	return fieldSet{managed: managed, tracked: true}
+}
+
+// jsonName returns the name of a struct field in json, or an empty string if it is not serialized
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// layerFields sets each field of dst that is not set to the value of the same field of src, if it is set in src.
+// Structs are layered field by field, while pointers, slices, and maps are replaced as a whole. The paths of the
+// fields that were taken from src are returned.
+func layerFields(dst, src reflect.Value, dstSet, srcSet fieldSet, path string) []string {
+	var fields []string
+	for i := 0; i < dst.NumField(); i++ {
+		name := jsonName(dst.Type().Field(i))
+		if name == "" {
+			continue
+		}
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		dstField, srcField := dst.Field(i), src.Field(i)
+		if dstField.Kind() == reflect.Struct {
+			fields = append(fields, layerFields(dstField, srcField, dstSet.child(name), srcSet.child(name), fieldPath)...)
+			continue
+		}
+		if dstSet.isSet(name, dstField) || !srcSet.isSet(name, srcField) {
+			continue
+		}
+		dstField.Set(srcField)
+		fields = append(fields, fieldPath)
+	}
+	return fields
+}
+
+// layerFleetDefaults layers the spec of the KokuMetricsConfig over the spec of the KokuMetricsDefault: each field
+// that is not set in the KokuMetricsConfig is taken from the KokuMetricsDefault. The paths of the fields that were
+// taken from the KokuMetricsDefault are returned.
+func layerFleetDefaults(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, defaults *kokumetricscfgv1beta1.KokuMetricsDefault) []string {
+	src := defaults.Spec.DeepCopy()
+	return layerFields(reflect.ValueOf(&kmCfg.Spec).Elem(), reflect.ValueOf(src).Elem(),
+		specFieldSet(kmCfg.ObjectMeta), specFieldSet(defaults.ObjectMeta), "")
+}
+
+// applyFleetDefaults reads the cluster-scoped KokuMetricsDefault, if there is one, and layers the spec of the
+// KokuMetricsConfig over it. The spec is only changed in memory, so the KokuMetricsConfig is not modified.
+func applyFleetDefaults(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, logger logr.Logger) {
+	log := logger.WithValues("KokuMetricsConfig", "applyFleetDefaults")
+	status := &kmCfg.Status.FleetDefaults
+	*status = kokumetricscfgv1beta1.FleetDefaultsStatus{}
+
+	defaults := &kokumetricscfgv1beta1.KokuMetricsDefault{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: kokumetricscfgv1beta1.FleetDefaultsName}, defaults); err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return
+		}
+		log.Error(err, "failed to get the KokuMetricsDefault")
+		status.Error = fmt.Sprintf("failed to get the KokuMetricsDefault: %v", err)
+		return
+	}
+	status.Name = defaults.Name
+	status.ResourceVersion = defaults.ResourceVersion
+	status.Fields = layerFleetDefaults(kmCfg, defaults)
+	if len(status.Fields) > 0 {
+		log.Info("applied the fleet defaults", "KokuMetricsDefault", defaults.Name, "fields", status.Fields)
+	}
+}
+
+// fleetDefaultsRequests maps a KokuMetricsDefault event to a reconcile of every KokuMetricsConfig, so that a change
+// to the fleet defaults is applied without waiting for the next reconcile
+func (r *KokuMetricsConfigReconciler) fleetDefaultsRequests(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetName() != kokumetricscfgv1beta1.FleetDefaultsName {
+		return nil
+	}
+	return r.configRequests("KokuMetricsDefault")
+}
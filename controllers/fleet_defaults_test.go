@@ -0,0 +1,165 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func fleetDefaultsReconciler(t *testing.T, objs ...runtime.Object) *KokuMetricsConfigReconciler {
+	s := runtime.NewScheme()
+	if err := kokumetricscfgv1beta1.AddToScheme(s); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	return &KokuMetricsConfigReconciler{Client: fake.NewFakeClientWithScheme(s, objs...), Scheme: s, Log: testutils.TestLogger{}}
+}
+
+// managedFields returns the managed fields of a manager that wrote the given spec fields, in the FieldsV1 format
+func managedFields(spec string) []metav1.ManagedFieldsEntry {
+	return []metav1.ManagedFieldsEntry{
+		{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":` + spec + `}`)}},
+		{Manager: "koku-metrics-operator", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:clusterID":{}}}`)}},
+	}
+}
+
+func fleetDefaults() *kokumetricscfgv1beta1.KokuMetricsDefault {
+	cycle := int64(720)
+	disabled := false
+	defaults := &kokumetricscfgv1beta1.KokuMetricsDefault{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          kokumetricscfgv1beta1.FleetDefaultsName,
+			ManagedFields: managedFields(`{"f:api_url":{},"f:upload":{".":{},"f:upload_cycle":{},"f:upload_toggle":{}},"f:prometheus_config":{".":{},"f:extra_selectors":{}}}`),
+		},
+	}
+	defaults.Spec.APIURL = "https://console.stage.redhat.com"
+	defaults.Spec.Upload.UploadCycle = &cycle
+	defaults.Spec.Upload.UploadToggle = &disabled
+	defaults.Spec.Upload.IngressAPIPath = kokumetricscfgv1beta1.DefaultIngressPath
+	defaults.Spec.PrometheusConfig.ExtraSelectors = []string{`cluster="a"`}
+	return defaults
+}
+
+func TestLayerFleetDefaults(t *testing.T) {
+	cycle := int64(360)
+	enabled := true
+
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.ManagedFields = managedFields(`{"f:upload":{".":{},"f:upload_toggle":{}},"f:prometheus_config":{".":{}}}`)
+	// upload_cycle and ingress_path are filled from their CRD defaults, so they are not managed
+	kmCfg.Spec.Upload.UploadCycle = &cycle
+	kmCfg.Spec.Upload.IngressAPIPath = kokumetricscfgv1beta1.DefaultIngressPath
+	kmCfg.Spec.Upload.UploadToggle = &enabled
+	kmCfg.Spec.APIURL = kokumetricscfgv1beta1.DefaultAPIURL
+
+	fields := layerFleetDefaults(kmCfg, fleetDefaults())
+	wantFields := []string{"api_url", "upload.upload_cycle", "prometheus_config.extra_selectors"}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("fleet default fields got %v want %v", fields, wantFields)
+	}
+	if kmCfg.Spec.APIURL != "https://console.stage.redhat.com" {
+		t.Errorf("api_url got %q want the fleet default", kmCfg.Spec.APIURL)
+	}
+	if *kmCfg.Spec.Upload.UploadCycle != 720 {
+		t.Errorf("upload_cycle got %d want the fleet default 720", *kmCfg.Spec.Upload.UploadCycle)
+	}
+	if !*kmCfg.Spec.Upload.UploadToggle {
+		t.Errorf("upload_toggle set in the KokuMetricsConfig was overridden by the fleet default")
+	}
+	// ingress_path is filled from its CRD default in the KokuMetricsDefault as well, so it is not a fleet default
+	if kmCfg.Spec.Upload.IngressAPIPath != kokumetricscfgv1beta1.DefaultIngressPath {
+		t.Errorf("ingress_path got %q want %q", kmCfg.Spec.Upload.IngressAPIPath, kokumetricscfgv1beta1.DefaultIngressPath)
+	}
+	if !reflect.DeepEqual(kmCfg.Spec.PrometheusConfig.ExtraSelectors, []string{`cluster="a"`}) {
+		t.Errorf("extra_selectors got %v want the fleet default", kmCfg.Spec.PrometheusConfig.ExtraSelectors)
+	}
+}
+
+func TestLayerFleetDefaultsWithoutManagedFields(t *testing.T) {
+	enabled := true
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Spec.Upload.UploadToggle = &enabled
+	kmCfg.Spec.Upload.IngressAPIPath = "/custom/upload"
+
+	defaults := fleetDefaults()
+	defaults.ManagedFields = nil
+	fields := layerFleetDefaults(kmCfg, defaults)
+	wantFields := []string{"api_url", "upload.upload_cycle", "prometheus_config.extra_selectors"}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("fleet default fields got %v want %v", fields, wantFields)
+	}
+	if !*kmCfg.Spec.Upload.UploadToggle || kmCfg.Spec.Upload.IngressAPIPath != "/custom/upload" {
+		t.Errorf("fields that are not empty in the KokuMetricsConfig were overridden by the fleet defaults")
+	}
+
+	// the fleet defaults are copied, so the KokuMetricsDefault is not modified through the KokuMetricsConfig
+	kmCfg.Spec.PrometheusConfig.ExtraSelectors[0] = `cluster="b"`
+	if defaults.Spec.PrometheusConfig.ExtraSelectors[0] != `cluster="a"` {
+		t.Errorf("the KokuMetricsDefault was modified through the KokuMetricsConfig")
+	}
+}
+
+func TestApplyFleetDefaults(t *testing.T) {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.FleetDefaults.Name = "stale"
+	applyFleetDefaults(fleetDefaultsReconciler(t), kmCfg, testutils.TestLogger{})
+	if !reflect.DeepEqual(kmCfg.Status.FleetDefaults, kokumetricscfgv1beta1.FleetDefaultsStatus{}) {
+		t.Errorf("fleet defaults status without a KokuMetricsDefault got %+v want empty", kmCfg.Status.FleetDefaults)
+	}
+
+	other := fleetDefaults()
+	other.Name = "other"
+	r := fleetDefaultsReconciler(t, fleetDefaults(), other)
+	kmCfg = &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	applyFleetDefaults(r, kmCfg, testutils.TestLogger{})
+	status := kmCfg.Status.FleetDefaults
+	if status.Name != kokumetricscfgv1beta1.FleetDefaultsName || status.Error != "" {
+		t.Errorf("fleet defaults status got %+v want the %s KokuMetricsDefault", status, kokumetricscfgv1beta1.FleetDefaultsName)
+	}
+	if len(status.Fields) != 4 || kmCfg.Spec.APIURL != "https://console.stage.redhat.com" {
+		t.Errorf("fleet defaults fields got %v want the fields of the KokuMetricsDefault", status.Fields)
+	}
+}
+
+func TestFleetDefaultsRequests(t *testing.T) {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "kokumetricscfg-sample"}}
+	r := fleetDefaultsReconciler(t, kmCfg)
+
+	defaults := fleetDefaults()
+	requests := r.fleetDefaultsRequests(handler.MapObject{Meta: defaults, Object: defaults})
+	if len(requests) != 1 || requests[0].Name != kmCfg.Name || requests[0].Namespace != kmCfg.Namespace {
+		t.Errorf("requests for the fleet defaults got %v want the KokuMetricsConfig", requests)
+	}
+
+	defaults.Name = "other"
+	if requests := r.fleetDefaultsRequests(handler.MapObject{Meta: defaults, Object: defaults}); len(requests) != 0 {
+		t.Errorf("requests for a KokuMetricsDefault that is not %s got %v want none", kokumetricscfgv1beta1.FleetDefaultsName, requests)
+	}
+}
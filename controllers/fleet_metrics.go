@@ -0,0 +1,95 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// The fleet summary metrics have stable names and no labels so that they can be added to the allowlist of ACM
+// observability and compared across clusters.
+var (
+	lastUpload = &lastUploadTime{}
+
+	fleetLastUploadAge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_fleet_last_upload_age_seconds",
+			Help: "Seconds since the last successful upload to cloud.redhat.com, or -1 if no upload has succeeded.",
+		},
+		lastUpload.age,
+	)
+
+	fleetPayloadsPending = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_fleet_payloads_pending",
+			Help: "Number of packaged payloads waiting to be uploaded.",
+		},
+	)
+
+	fleetDataCollected = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_fleet_data_collected",
+			Help: "1 if the last collection found usage data in Prometheus, 0 otherwise.",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(fleetLastUploadAge, fleetPayloadsPending, fleetDataCollected)
+}
+
+// lastUploadTime is the time of the last successful upload. The age is computed when the metrics are scraped
+// so that it keeps growing between reconciles.
+type lastUploadTime struct {
+	mu   sync.RWMutex
+	time time.Time
+}
+
+func (l *lastUploadTime) set(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.time = t
+}
+
+func (l *lastUploadTime) age() float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.time.IsZero() {
+		return -1
+	}
+	return time.Since(l.time).Seconds()
+}
+
+// setFleetMetrics updates the fleet summary metrics from the status
+func setFleetMetrics(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	lastUpload.set(kmCfg.Status.Upload.LastSuccessfulUploadTime.Time)
+	fleetPayloadsPending.Set(float64(len(kmCfg.Status.Packaging.PackagedFiles)))
+	dataCollected := 0.0
+	if kmCfg.Status.Reports.DataCollected {
+		dataCollected = 1
+	}
+	fleetDataCollected.Set(dataCollected)
+}
@@ -0,0 +1,57 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestSetFleetMetrics(t *testing.T) {
+	defer lastUpload.set(time.Time{})
+
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	setFleetMetrics(kmCfg)
+	if got := testutil.ToFloat64(fleetLastUploadAge); got != -1 {
+		t.Errorf("got last upload age %v before any upload, want -1", got)
+	}
+	if got := testutil.ToFloat64(fleetDataCollected); got != 0 {
+		t.Errorf("got data collected %v, want 0", got)
+	}
+
+	kmCfg.Status.Upload.LastSuccessfulUploadTime = metav1.Time{Time: time.Now().Add(-time.Hour)}
+	kmCfg.Status.Packaging.PackagedFiles = []string{"a.tar.gz", "b.tar.gz"}
+	kmCfg.Status.Reports.DataCollected = true
+	setFleetMetrics(kmCfg)
+	if got := testutil.ToFloat64(fleetLastUploadAge); got < 3600 || got > 3660 {
+		t.Errorf("got last upload age %v, want about 3600", got)
+	}
+	if got := testutil.ToFloat64(fleetPayloadsPending); got != 2 {
+		t.Errorf("got payloads pending %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(fleetDataCollected); got != 1 {
+		t.Errorf("got data collected %v, want 1", got)
+	}
+}
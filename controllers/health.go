@@ -0,0 +1,141 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// reconcileStaleAfter is the amount of time a reconcile may run, or the amount of time
+// between completed reconciles, before the operator is considered wedged. Reconciles
+// are requeued every 5 minutes, so this allows for several missed cycles.
+var reconcileStaleAfter = 30 * time.Minute
+
+// reconcileWaitInterval is how often WaitForReconcile checks if the reconcile in progress is done
+var reconcileWaitInterval = time.Second
+
+// pipelineHealth tracks the state of the reconcile pipeline for the health probes
+type pipelineHealth struct {
+	mu sync.RWMutex
+
+	reconcileStart time.Time
+	reconcileEnd   time.Time
+	promChecked    bool
+	promErr        error
+}
+
+var health = &pipelineHealth{}
+
+func (h *pipelineHealth) startReconcile() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reconcileStart = time.Now()
+}
+
+func (h *pipelineHealth) finishReconcile() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reconcileEnd = time.Now()
+}
+
+// reconciling returns true if a reconcile has started and is not done yet
+func (h *pipelineHealth) reconciling() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.reconcileStart.After(h.reconcileEnd)
+}
+
+// reset clears the pipeline state. It is used when there is no longer a CR to reconcile.
+func (h *pipelineHealth) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reconcileStart = time.Time{}
+	h.reconcileEnd = time.Time{}
+	h.promChecked = false
+	h.promErr = nil
+}
+
+func (h *pipelineHealth) setPrometheusStatus(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.promChecked = true
+	h.promErr = err
+}
+
+// ReconcileCheck is a liveness check that fails when a reconcile has been running for too long,
+// or when reconciles have stopped completing for a CR that was previously reconciled.
+func ReconcileCheck(_ *http.Request) error {
+	health.mu.RLock()
+	defer health.mu.RUnlock()
+
+	if health.reconcileStart.IsZero() {
+		// nothing has been reconciled yet
+		return nil
+	}
+	if health.reconcileStart.After(health.reconcileEnd) {
+		if running := time.Since(health.reconcileStart); running > reconcileStaleAfter {
+			return fmt.Errorf("reconcile has been running for %s", running.Round(time.Second))
+		}
+		return nil
+	}
+	if idle := time.Since(health.reconcileEnd); idle > reconcileStaleAfter {
+		return fmt.Errorf("no reconcile has completed in %s", idle.Round(time.Second))
+	}
+	return nil
+}
+
+// PrometheusCheck is a readiness check that fails when the last attempt to query prometheus failed.
+func PrometheusCheck(_ *http.Request) error {
+	health.mu.RLock()
+	defer health.mu.RUnlock()
+
+	if health.promChecked && health.promErr != nil {
+		return fmt.Errorf("prometheus is not reachable: %v", health.promErr)
+	}
+	return nil
+}
+
+// StorageCheck is a readiness check that fails when the report directory is not writable.
+func StorageCheck(_ *http.Request) error {
+	if dirCfg == nil || dirCfg.Parent.Path == "" {
+		// the directory configuration has not been created yet
+		return nil
+	}
+	f, err := ioutil.TempFile(dirCfg.Parent.Path, ".healthcheck")
+	if err != nil {
+		return fmt.Errorf("report directory %s is not writable: %v", dirCfg.Parent.Path, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// WaitForReconcile returns once the reconcile in progress, if any, is done. It is called when the operator is
+// stopped, so that the reports being collected, packaged, or uploaded are not left incomplete. The pod is killed at the
+// end of its termination grace period if the reconcile takes longer.
+func WaitForReconcile() {
+	for health.reconciling() {
+		time.Sleep(reconcileWaitInterval)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+func TestReconcileCheck(t *testing.T) {
+	now := time.Now()
+	stale := now.Add(-2 * reconcileStaleAfter)
+	recent := now.Add(-time.Minute)
+	reconcileCheckTests := []struct {
+		name      string
+		start     time.Time
+		end       time.Time
+		wantError bool
+	}{
+		{name: "never reconciled", wantError: false},
+		{name: "reconcile in progress", start: recent, wantError: false},
+		{name: "reconcile stuck", start: stale, end: stale.Add(-time.Minute), wantError: true},
+		{name: "reconcile recently finished", start: recent, end: recent.Add(time.Second), wantError: false},
+		{name: "reconcile not finished recently", start: stale, end: stale.Add(time.Second), wantError: true},
+	}
+	defer health.reset()
+	for _, tt := range reconcileCheckTests {
+		t.Run(tt.name, func(t *testing.T) {
+			health.reconcileStart = tt.start
+			health.reconcileEnd = tt.end
+			err := ReconcileCheck(nil)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+		})
+	}
+}
+
+func TestWaitForReconcile(t *testing.T) {
+	defer health.reset()
+	defer func(interval time.Duration) { reconcileWaitInterval = interval }(reconcileWaitInterval)
+	reconcileWaitInterval = time.Millisecond
+
+	// nothing is waited for between reconciles
+	WaitForReconcile()
+
+	health.startReconcile()
+	done := make(chan struct{})
+	go func() {
+		WaitForReconcile()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("WaitForReconcile returned while a reconcile was in progress")
+	case <-time.After(20 * time.Millisecond):
+	}
+	time.Sleep(time.Millisecond)
+	health.finishReconcile()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("WaitForReconcile did not return once the reconcile was done")
+	}
+}
+
+func TestPrometheusCheck(t *testing.T) {
+	prometheusCheckTests := []struct {
+		name      string
+		checked   bool
+		err       error
+		wantError bool
+	}{
+		{name: "not yet checked", checked: false, wantError: false},
+		{name: "connection succeeded", checked: true, wantError: false},
+		{name: "connection failed", checked: true, err: errors.New("connection refused"), wantError: true},
+	}
+	defer health.reset()
+	for _, tt := range prometheusCheckTests {
+		t.Run(tt.name, func(t *testing.T) {
+			health.reset()
+			if tt.checked {
+				health.setPrometheusStatus(tt.err)
+			}
+			err := PrometheusCheck(nil)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+		})
+	}
+}
+
+func TestStorageCheck(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "storage-check")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	storageCheckTests := []struct {
+		name      string
+		path      string
+		wantError bool
+	}{
+		{name: "directory not configured", path: "", wantError: false},
+		{name: "directory writable", path: tmpDir, wantError: false},
+		{name: "directory missing", path: tmpDir + "/does-not-exist", wantError: true},
+	}
+	original := dirCfg
+	defer func() { dirCfg = original }()
+	for _, tt := range storageCheckTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirCfg = &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: tt.path}}
+			err := StorageCheck(nil)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+		})
+	}
+	files, _ := ioutil.ReadDir(tmpDir)
+	if len(files) != 0 {
+		t.Errorf("storage check left %d file(s) behind", len(files))
+	}
+}
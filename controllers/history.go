@@ -0,0 +1,198 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+// HistoryFile is the name of the collection and upload history on the report volume. Each line is a JSON object, so
+// that the history survives the loss of the KokuMetricsConfig status, for example when the operator is reinstalled.
+const HistoryFile = "history.jsonl"
+
+// historyGenerations is the number of rotated history files that are kept, as history.jsonl.1 to history.jsonl.3
+const historyGenerations = 3
+
+// maxHistoryBytes is the size at which the history file is rotated. It is a variable so that it can be lowered in tests.
+var maxHistoryBytes int64 = 5 * 1024 * 1024
+
+// the events of the history
+const (
+	historyCollection   = "collection"
+	historyRecollection = "recollection"
+	historyUpload       = "upload"
+	historyQuarantine   = "quarantine"
+)
+
+// historyEntry is a line of the history file
+type historyEntry struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	ClusterID string    `json:"cluster_id,omitempty"`
+	// Start and End are the window of the collected reports, or of the reports in the payload
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+
+	DataCollected bool                               `json:"data_collected,omitempty"`
+	Reports       []kokumetricscfgv1beta1.ReportStat `json:"reports,omitempty"`
+
+	File        string `json:"file,omitempty"`
+	PayloadID   string `json:"payload_id,omitempty"`
+	SizeBytes   int64  `json:"size_bytes,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Status      string `json:"status,omitempty"`
+	RequestID   string `json:"request_id,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// historyTime returns a pointer to the time, or nil for the zero time so that it is left out of the entry
+func historyTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// collectionHistory returns the history entry of the collection of the window that starts at start. kmCfg is the
+// config whose reports status was set by the collection.
+func collectionHistory(event string, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, start time.Time, err error) historyEntry {
+	entry := historyEntry{
+		Event:         event,
+		Start:         historyTime(start),
+		End:           historyTime(start.Add(time.Hour)),
+		DataCollected: err == nil && kmCfg.Status.Reports.DataCollected,
+		Reports:       kmCfg.Status.Reports.ReportStats,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// uploadHistory returns the history entries of the upload results and of the payloads that were quarantined
+func uploadHistory(results []uploader.Result, quarantined []string) []historyEntry {
+	var entries []historyEntry
+	for _, result := range results {
+		entry := historyEntry{
+			Event:     historyUpload,
+			Start:     historyTime(result.Payload.Start),
+			End:       historyTime(result.Payload.End),
+			File:      result.File,
+			PayloadID: result.Payload.PayloadID,
+			SizeBytes: result.Payload.SizeBytes,
+		}
+		if result.Exporter != nil {
+			entry.Destination = result.Exporter.Name()
+		}
+		// the status and request ID are only set for ingress uploads
+		entry.Status = result.Status
+		entry.RequestID = result.RequestID
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+		}
+		entries = append(entries, entry)
+	}
+	for _, file := range quarantined {
+		entries = append(entries, historyEntry{Event: historyQuarantine, File: file})
+	}
+	return entries
+}
+
+// rotateHistory moves the history file to history.jsonl.1, and the older generations up by one, once it reaches
+// maxHistoryBytes. The oldest generation is dropped.
+func rotateHistory(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("rotateHistory: failed to stat history: %v", err)
+	}
+	if info.Size() < maxHistoryBytes {
+		return nil
+	}
+	for i := historyGenerations - 1; i >= 1; i-- {
+		older := fmt.Sprintf("%s.%d", path, i)
+		if err := os.Rename(older, fmt.Sprintf("%s.%d", path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotateHistory: failed to rotate %s: %v", older, err)
+		}
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return fmt.Errorf("rotateHistory: failed to rotate history: %v", err)
+	}
+	return nil
+}
+
+// appendHistory appends the entries to the history file in dir, rotating the file first if it is full
+func appendHistory(dir string, entries []historyEntry) error {
+	path := filepath.Join(dir, HistoryFile)
+	if err := rotateHistory(path); err != nil {
+		return fmt.Errorf("appendHistory: %v", err)
+	}
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("appendHistory: failed to marshal entry: %v", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("appendHistory: failed to open history: %v", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("appendHistory: failed to write history: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("appendHistory: failed to close history: %v", err)
+	}
+	return nil
+}
+
+// recordHistory appends the entries to the history on the report volume. The history is best effort, so a failure
+// is only logged.
+func recordHistory(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, log logr.Logger, entries ...historyEntry) {
+	if len(entries) == 0 || dirCfg == nil || dirCfg.Parent.Path == "" {
+		return
+	}
+	now := time.Now().UTC()
+	for i := range entries {
+		if entries[i].Time.IsZero() {
+			entries[i].Time = now
+		}
+		entries[i].ClusterID = kmCfg.Status.ClusterID
+	}
+	if err := appendHistory(dirCfg.Parent.Path, entries); err != nil {
+		log.Error(err, "failed to record history")
+	}
+}
@@ -0,0 +1,164 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+func readHistory(t *testing.T, path string) []historyEntry {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open history: %v", err)
+	}
+	defer f.Close()
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("failed to unmarshal history line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRecordHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dirCfg := &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: dir}}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.ClusterID = "cluster-id"
+	kmCfg.Status.Reports.DataCollected = true
+	kmCfg.Status.Reports.ReportStats = []kokumetricscfgv1beta1.ReportStat{{Report: "pod", Rows: 10, Bytes: 2048}}
+	start := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	recordHistory(kmCfg, dirCfg, testutils.TestLogger{}, collectionHistory(historyCollection, kmCfg, start, nil))
+	recordHistory(kmCfg, dirCfg, testutils.TestLogger{}, uploadHistory(
+		[]uploader.Result{
+			{File: "a.tar.gz", Payload: packaging.PayloadSummary{PayloadID: "a", Start: start, End: start.Add(time.Hour), SizeBytes: 512},
+				Status: "202 Accepted", RequestID: "req-a"},
+			{File: "b.tar.gz", Err: errors.New("upload failed"), Status: "500 Internal Server Error", RequestID: "req-b"},
+		},
+		[]string{"c.tar.gz"},
+	)...)
+
+	entries := readHistory(t, filepath.Join(dir, HistoryFile))
+	if len(entries) != 4 {
+		t.Fatalf("got %d history entries want 4", len(entries))
+	}
+	collection := entries[0]
+	if collection.Event != historyCollection || collection.ClusterID != "cluster-id" || !collection.Start.Equal(start) ||
+		!collection.End.Equal(start.Add(time.Hour)) || !collection.DataCollected || len(collection.Reports) != 1 ||
+		collection.Reports[0].Bytes != 2048 || collection.Time.IsZero() {
+		t.Errorf("got collection entry %+v want the window and report stats", collection)
+	}
+	tests := []struct {
+		name  string
+		entry historyEntry
+		want  historyEntry
+	}{
+		{
+			name:  "accepted upload",
+			entry: entries[1],
+			want:  historyEntry{Event: historyUpload, File: "a.tar.gz", PayloadID: "a", SizeBytes: 512, Status: "202 Accepted", RequestID: "req-a"},
+		},
+		{
+			name:  "failed upload",
+			entry: entries[2],
+			want:  historyEntry{Event: historyUpload, File: "b.tar.gz", Error: "upload failed", Status: "500 Internal Server Error", RequestID: "req-b"},
+		},
+		{
+			name:  "quarantined payload",
+			entry: entries[3],
+			want:  historyEntry{Event: historyQuarantine, File: "c.tar.gz"},
+		},
+	}
+	for _, tt := range tests {
+		got := tt.entry
+		if got.Event != tt.want.Event || got.File != tt.want.File || got.PayloadID != tt.want.PayloadID ||
+			got.SizeBytes != tt.want.SizeBytes || got.Error != tt.want.Error || got.Status != tt.want.Status || got.RequestID != tt.want.RequestID {
+			t.Errorf("%s got %+v want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecordHistoryNoVolume(t *testing.T) {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	recordHistory(kmCfg, &dirconfig.DirectoryConfig{}, testutils.TestLogger{}, historyEntry{Event: historyUpload})
+	if _, err := os.Stat(HistoryFile); !os.IsNotExist(err) {
+		os.Remove(HistoryFile)
+		t.Errorf("got history in the working directory want no history without a volume")
+	}
+}
+
+func TestAppendHistoryRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	previous := maxHistoryBytes
+	maxHistoryBytes = 1
+	defer func() { maxHistoryBytes = previous }()
+
+	for _, file := range []string{"a", "b", "c", "d", "e"} {
+		if err := appendHistory(dir, []historyEntry{{Event: historyUpload, File: file}}); err != nil {
+			t.Fatalf("failed to append history: %v", err)
+		}
+	}
+
+	path := filepath.Join(dir, HistoryFile)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: path, want: "e"},
+		{path: path + ".1", want: "d"},
+		{path: path + ".2", want: "c"},
+		{path: path + ".3", want: "b"},
+	}
+	for _, tt := range tests {
+		entries := readHistory(t, tt.path)
+		if len(entries) != 1 || entries[0].File != tt.want {
+			t.Errorf("%s got %+v want the entry of %s", tt.path, entries, tt.want)
+		}
+	}
+	if _, err := os.Stat(path + ".4"); !os.IsNotExist(err) {
+		t.Errorf("got %s.4 want at most %d rotated history files", path, historyGenerations)
+	}
+}
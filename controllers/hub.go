@@ -0,0 +1,95 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/hub"
+)
+
+// hubReceiver is the receiver configured by the reconciler. It is a variable so tests can replace it.
+var hubReceiver = hub.DefaultReceiver
+
+// getHubCredentials returns the user and password spoke clusters must use to upload to the hub
+func getHubCredentials(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) (string, string, error) {
+	secret := &corev1.Secret{}
+	namespace := types.NamespacedName{Namespace: kmCfg.Namespace, Name: kmCfg.Spec.Hub.SecretName}
+	if err := r.getSecret(context.Background(), namespace, secret); err != nil {
+		return "", "", fmt.Errorf("getHubCredentials: failed to get secret %s: %v", namespace.Name, err)
+	}
+
+	keys := make(map[string]string)
+	for k, v := range secret.Data {
+		keys[strings.ToLower(k)] = string(v)
+	}
+	for _, k := range []string{authSecretUserKey, authSecretPasswordKey} {
+		if len(keys[k]) <= 0 {
+			return "", "", fmt.Errorf("getHubCredentials: secret %s not found with expected %s data", namespace.Name, k)
+		}
+	}
+	return keys[authSecretUserKey], keys[authSecretPasswordKey], nil
+}
+
+// configureHub enables or disables the receiver for payloads uploaded by spoke clusters, and reflects
+// the receiver statistics into the status.
+func configureHub(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, logger logr.Logger) {
+	log := logger.WithValues("KokuMetricsConfig", "configureHub")
+
+	kmCfg.Status.Hub.SecretName = kmCfg.Spec.Hub.SecretName
+	kmCfg.Status.Hub.HubError = ""
+	if kmCfg.Spec.Hub.Enabled == nil || !*kmCfg.Spec.Hub.Enabled {
+		hubReceiver.Disable()
+		kmCfg.Status.Hub.Enabled = false
+		return
+	}
+
+	user, password, err := getHubCredentials(r, kmCfg)
+	if err != nil {
+		log.Error(err, "hub aggregation is disabled")
+		hubReceiver.Disable()
+		kmCfg.Status.Hub.Enabled = false
+		kmCfg.Status.Hub.HubError = err.Error()
+		return
+	}
+	hubReceiver.Configure(hub.Config{
+		UploadDir: dirCfg.Upload.Path,
+		Username:  user,
+		Password:  password,
+	})
+	kmCfg.Status.Hub.Enabled = true
+
+	stats := hubReceiver.Stats()
+	kmCfg.Status.Hub.ReceivedPayloads = stats.Received
+	kmCfg.Status.Hub.RejectedPayloads = stats.Rejected
+	kmCfg.Status.Hub.LastSpokeClusterID = stats.LastSpokeClusterID
+	if !stats.LastReceivedTime.IsZero() {
+		kmCfg.Status.Hub.LastReceivedTime = metav1.Time{Time: stats.LastReceivedTime}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+
+	"github.com/go-logr/logr"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+// ingressAPIURL returns the API URL that payloads are uploaded to when no failover has happened: the region-specific
+// API URL of the data residency, or the api_url
+func ingressAPIURL(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) string {
+	if kmCfg.Status.Reporting.IngressAPIURL != "" {
+		return kmCfg.Status.Reporting.IngressAPIURL
+	}
+	return kmCfg.Status.APIURL
+}
+
+// IngressURL returns the URL that the payloads of the KokuMetricsConfig are uploaded to when no failover has happened
+func IngressURL(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) string {
+	return ingressAPIURL(kmCfg) + kmCfg.Status.Upload.IngressAPIPath
+}
+
+// apiEndpoints returns the API URLs that payloads can be uploaded to, the api_url first. Payloads of a cluster with a
+// region-specific API URL are only uploaded to it, and never fail over to an endpoint outside of the region.
+func apiEndpoints(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) []string {
+	if kmCfg.Status.Reporting.IngressAPIURL != "" {
+		return []string{kmCfg.Status.Reporting.IngressAPIURL}
+	}
+	return append([]string{kmCfg.Status.APIURL}, kmCfg.Spec.Upload.FallbackAPIURLs...)
+}
+
+// activeAPIURL returns the API URL that payloads are uploaded to. Uploads fail over to the next endpoint once the
+// active endpoint has been unreachable for the failover threshold, and wrap around to the api_url after the last one.
+func activeAPIURL(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, log logr.Logger) string {
+	endpoints := apiEndpoints(kmCfg)
+	status := &kmCfg.Status.Upload
+	current := -1
+	for i, endpoint := range endpoints {
+		if endpoint == status.ActiveAPIURL {
+			current = i
+			break
+		}
+	}
+	if current < 0 {
+		// the active endpoint was removed from the spec, or no endpoint has been used yet
+		status.ActiveAPIURL = endpoints[0]
+		status.EndpointFailures = 0
+		return status.ActiveAPIURL
+	}
+
+	threshold := kokumetricscfgv1beta1.DefaultFailoverThreshold
+	if status.FailoverThreshold != nil {
+		threshold = *status.FailoverThreshold
+	}
+	if len(endpoints) > 1 && status.EndpointFailures >= threshold {
+		next := endpoints[(current+1)%len(endpoints)]
+		log.Info("failing over to the next API URL", "from", status.ActiveAPIURL, "to", next, "failedCycles", status.EndpointFailures)
+		status.ActiveAPIURL = next
+		status.EndpointFailures = 0
+	}
+	return status.ActiveAPIURL
+}
+
+// recordEndpointResults counts the upload cycles in which the active API URL could not be reached. A batch of uploads
+// counts once, and only when an upload failed to reach the endpoint because of a transport or resolver error. Any
+// response from the endpoint, even one that does not accept the payload, shows that it is reachable.
+func recordEndpointResults(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, results []uploader.Result) {
+	unreachable := false
+	for _, result := range results {
+		if _, ok := result.Exporter.(*exporter.Ingress); !ok {
+			continue
+		}
+		switch {
+		case crhchttp.IsSendError(result.Err):
+			unreachable = true
+		case result.Err == nil || exporter.IsNotAccepted(result.Err) || isResponseError(result.Err):
+			// the endpoint responded
+			kmCfg.Status.Upload.EndpointFailures = 0
+			return
+		}
+	}
+	if unreachable {
+		kmCfg.Status.Upload.EndpointFailures++
+	}
+}
+
+// isResponseError returns true if the error is a response of the endpoint with a status other than 2xx
+func isResponseError(err error) bool {
+	var respErr *crhchttp.ResponseError
+	return errors.As(err, &respErr)
+}
@@ -0,0 +1,105 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+func TestActiveAPIURL(t *testing.T) {
+	primary := "https://cloud.redhat.com"
+	relay := "https://relay-a.example.com"
+	backup := "https://relay-b.example.com"
+	regional := "https://eu.cloud.example.com"
+	activeTests := []struct {
+		name         string
+		fallbacks    []string
+		regional     string
+		active       string
+		failures     int64
+		want         string
+		wantFailures int64
+	}{
+		{name: "api url is used first", fallbacks: []string{relay}, want: primary},
+		{name: "active endpoint is kept below the threshold", fallbacks: []string{relay}, active: primary, failures: 2, want: primary, wantFailures: 2},
+		{name: "fails over at the threshold", fallbacks: []string{relay, backup}, active: primary, failures: 3, want: relay},
+		{name: "fails over to the next fallback", fallbacks: []string{relay, backup}, active: relay, failures: 3, want: backup},
+		{name: "wraps around to the api url", fallbacks: []string{relay, backup}, active: backup, failures: 3, want: primary},
+		{name: "no fallbacks keeps the api url", active: primary, failures: 5, want: primary, wantFailures: 5},
+		{name: "removed endpoint resets to the api url", fallbacks: []string{backup}, active: relay, failures: 3, want: primary},
+		{name: "region api url replaces the api url", fallbacks: []string{relay}, regional: regional, want: regional},
+		{name: "region api url never fails over", fallbacks: []string{relay}, regional: regional, active: regional, failures: 3, want: regional, wantFailures: 3},
+		{name: "active fallback resets to the region api url", fallbacks: []string{relay}, regional: regional, active: relay, failures: 1, want: regional},
+	}
+	for _, tt := range activeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			threshold := kokumetricscfgv1beta1.DefaultFailoverThreshold
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Upload.FallbackAPIURLs = tt.fallbacks
+			kmCfg.Status.APIURL = primary
+			kmCfg.Status.Reporting.IngressAPIURL = tt.regional
+			kmCfg.Status.Upload.FailoverThreshold = &threshold
+			kmCfg.Status.Upload.ActiveAPIURL = tt.active
+			kmCfg.Status.Upload.EndpointFailures = tt.failures
+			if got := activeAPIURL(kmCfg, testutils.TestLogger{}); got != tt.want {
+				t.Errorf("%s got %s want %s", tt.name, got, tt.want)
+			}
+			if kmCfg.Status.Upload.EndpointFailures != tt.wantFailures {
+				t.Errorf("%s got %d failures want %d", tt.name, kmCfg.Status.Upload.EndpointFailures, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestRecordEndpointResults(t *testing.T) {
+	unreachable := uploader.Result{Exporter: &exporter.Ingress{}, Err: &crhchttp.SendError{Err: errors.New("connection refused")}}
+	recordTests := []struct {
+		name         string
+		results      []uploader.Result
+		wantFailures int64
+	}{
+		{name: "unreachable endpoint is counted", results: []uploader.Result{unreachable}, wantFailures: 3},
+		{name: "unreachable batch is counted once", results: []uploader.Result{unreachable, unreachable, unreachable}, wantFailures: 3},
+		{name: "not accepted payload resets the count", results: []uploader.Result{unreachable, {Exporter: &exporter.Ingress{}, Err: fmt.Errorf("rejected: %w", exporter.ErrNotAccepted)}}, wantFailures: 0},
+		{name: "error response resets the count", results: []uploader.Result{{Exporter: &exporter.Ingress{}, Err: &crhchttp.ResponseError{StatusCode: 500}}}, wantFailures: 0},
+		{name: "successful upload resets the count", results: []uploader.Result{{Exporter: &exporter.Ingress{}}}, wantFailures: 0},
+		{name: "payload errors are not counted", results: []uploader.Result{{Exporter: &exporter.Ingress{}, Err: errors.New("failed to set request body")}}, wantFailures: 2},
+		{name: "other destinations are ignored", results: []uploader.Result{{Exporter: &exporter.Filesystem{}, Err: &crhchttp.SendError{Err: errors.New("disk full")}}}, wantFailures: 2},
+		{name: "no uploads keep the count", wantFailures: 2},
+	}
+	for _, tt := range recordTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Status.Upload.EndpointFailures = 2
+			recordEndpointResults(kmCfg, tt.results)
+			if kmCfg.Status.Upload.EndpointFailures != tt.wantFailures {
+				t.Errorf("%s got %d failures want %d", tt.name, kmCfg.Status.Upload.EndpointFailures, tt.wantFailures)
+			}
+		})
+	}
+}
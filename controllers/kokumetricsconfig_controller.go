@@ -23,7 +23,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -31,37 +30,46 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
-	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	configv1 "github.com/openshift/api/config/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
 	cv "github.com/project-koku/koku-metrics-operator/clusterversion"
 	"github.com/project-koku/koku-metrics-operator/collector"
 	"github.com/project-koku/koku-metrics-operator/crhchttp"
 	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/logging"
 	"github.com/project-koku/koku-metrics-operator/packaging"
 	"github.com/project-koku/koku-metrics-operator/sources"
 	"github.com/project-koku/koku-metrics-operator/storage"
+	"github.com/project-koku/koku-metrics-operator/uploader"
 )
 
 var (
 	GitCommit string
 
-	openShiftConfigNamespace = "openshift-config"
-	pullSecretName           = "pull-secret"
-	pullSecretDataKey        = ".dockerconfigjson"
-	pullSecretAuthKey        = "cloud.openshift.com"
-	authSecretUserKey        = "username"
-	authSecretPasswordKey    = "password"
-	promCompareFormat        = "2006-01-02T15"
+	openShiftConfigNamespace  = "openshift-config"
+	pullSecretName            = "pull-secret"
+	pullSecretDataKey         = ".dockerconfigjson"
+	pullSecretAuthKey         = "cloud.openshift.com"
+	authSecretUserKey         = "username"
+	authSecretPasswordKey     = "password"
+	authSecretTokenKey        = "token"
+	authSecretClientIDKey     = "client_id"
+	authSecretClientSecretKey = "client_secret"
+	promCompareFormat         = "2006-01-02T15"
 
 	falseDef = false
 	trueDef  = true
@@ -79,9 +87,29 @@ type KokuMetricsConfigReconciler struct {
 	Clientset *kubernetes.Clientset
 	InCluster bool
 	Namespace string
+	Recorder  record.EventRecorder
+
+	// Fixtures are recorded prometheus responses that reports are collected from instead of prometheus
+	Fixtures collector.Fixtures
+	// RecordFixtures is the directory that the prometheus responses of each collected window are recorded to
+	RecordFixtures string
+	// SecretReader reads secrets directly from the API server by name, so that the operator does not need to
+	// list and watch secrets. When nil, secrets are read through the cached client.
+	SecretReader client.Reader
+
+	// PromConn, SourcesClient, and Uploader replace prometheus, the sources API, and the ingress endpoint. They let
+	// tests and downstream builds run Reconcile without a live cluster or cloud.redhat.com. When nil, the real
+	// services are used.
+	PromConn      collector.PrometheusConnection
+	SourcesClient sources.Client
+	Uploader      crhchttp.Uploader
 
 	cvClientBuilder cv.ClusterVersionBuilder
 	promCollector   *collector.PromCollector
+	// pauseChecked is set once the first reconcile of the process has checked for hours missed while it was not running
+	pauseChecked bool
+	// runOnce is set when the reconcile is run by a Job of the cronjob execution mode
+	runOnce bool
 }
 
 type previousAuthValidation struct {
@@ -120,38 +148,65 @@ func StringReflectSpec(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1be
 // ReflectSpec Determine if the Status item reflects the Spec item if not empty, otherwise set a default value if applicable.
 func ReflectSpec(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
 
-	StringReflectSpec(r, kmCfg, &kmCfg.Spec.APIURL, &kmCfg.Status.APIURL, kokumetricscfgv1beta1.DefaultAPIURL)
+	// set the API URL, ingress path, and sources path from the API environment
+	reflectAPIEnvironment(kmCfg)
 	StringReflectSpec(r, kmCfg, &kmCfg.Spec.Authentication.AuthenticationSecretName, &kmCfg.Status.Authentication.AuthenticationSecretName, "")
+	StringReflectSpec(r, kmCfg, &kmCfg.Spec.Authentication.TokenHeader, &kmCfg.Status.Authentication.TokenHeader, kokumetricscfgv1beta1.DefaultTokenHeader)
 
 	if !reflect.DeepEqual(kmCfg.Spec.Authentication.AuthType, kmCfg.Status.Authentication.AuthType) {
 		kmCfg.Status.Authentication.AuthType = kmCfg.Spec.Authentication.AuthType
 	}
-	kmCfg.Status.Upload.ValidateCert = kmCfg.Spec.Upload.ValidateCert
+	reflectAuthenticationMethods(kmCfg)
+	reflectCertValidation(kmCfg, r.Log)
 
-	StringReflectSpec(r, kmCfg, &kmCfg.Spec.Upload.IngressAPIPath, &kmCfg.Status.Upload.IngressAPIPath, kokumetricscfgv1beta1.DefaultIngressPath)
 	kmCfg.Status.Upload.UploadToggle = kmCfg.Spec.Upload.UploadToggle
 
-	// set the default max file size for packaging
-	kmCfg.Status.Packaging.MaxSize = &kmCfg.Spec.Packaging.MaxSize
-	kmCfg.Status.Packaging.MaxReports = &kmCfg.Spec.Packaging.MaxReports
-
-	// set the upload wait to whatever is in the spec, if the spec is defined
-	if kmCfg.Spec.Upload.UploadWait != nil {
-		kmCfg.Status.Upload.UploadWait = kmCfg.Spec.Upload.UploadWait
+	kmCfg.Status.Upload.PayloadFormat = kmCfg.Spec.Upload.PayloadFormat
+	if kmCfg.Status.Upload.PayloadFormat == "" {
+		kmCfg.Status.Upload.PayloadFormat = kokumetricscfgv1beta1.DefaultPayloadFormat
 	}
+	kmCfg.Status.Upload.IPFamily = kmCfg.Spec.Upload.IPFamily
+	kmCfg.Status.Upload.DNSServer = kmCfg.Spec.Upload.DNSServer
+	kmCfg.Status.Upload.TLS = *kmCfg.Spec.Upload.TLS.DeepCopy()
 
-	// if the status is nil, generate an upload wait
-	if kmCfg.Status.Upload.UploadWait == nil {
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-		uploadWait := r.Int63() % 35
-		kmCfg.Status.Upload.UploadWait = &uploadWait
-	}
+	// set the max file size for packaging, clamped to the upload limit
+	reflectMaxSize(r, kmCfg)
+	kmCfg.Status.Packaging.MaxReports = &kmCfg.Spec.Packaging.MaxReports
+
+	// set the upload wait from the spec, or from the cluster ID
+	reflectUploadWait(kmCfg)
 
 	if !reflect.DeepEqual(kmCfg.Spec.Upload.UploadCycle, kmCfg.Status.Upload.UploadCycle) {
 		kmCfg.Status.Upload.UploadCycle = kmCfg.Spec.Upload.UploadCycle
 	}
+	reflectPackagingCycle(kmCfg)
+	kmCfg.Status.Packaging.DeltaReports = kmCfg.Spec.Packaging.DeltaReports != nil && *kmCfg.Spec.Packaging.DeltaReports
+	kmCfg.Status.Packaging.PayloadCadence = kokumetricscfgv1beta1.DefaultPayloadCadence
+	if kmCfg.Spec.Packaging.PayloadCadence != "" {
+		kmCfg.Status.Packaging.PayloadCadence = kmCfg.Spec.Packaging.PayloadCadence
+	}
+
+	uploadInterval := kokumetricscfgv1beta1.DefaultUploadInterval
+	if kmCfg.Spec.Upload.UploadInterval != nil {
+		uploadInterval = *kmCfg.Spec.Upload.UploadInterval
+	}
+	kmCfg.Status.Upload.UploadInterval = &uploadInterval
+	maxBackfillUploads := kokumetricscfgv1beta1.DefaultMaxBackfillUploads
+	if kmCfg.Spec.Upload.MaxBackfillUploads != nil {
+		maxBackfillUploads = *kmCfg.Spec.Upload.MaxBackfillUploads
+	}
+	kmCfg.Status.Upload.MaxBackfillUploads = &maxBackfillUploads
+	quarantineAfterRejections := kokumetricscfgv1beta1.DefaultQuarantineAfterRejections
+	if kmCfg.Spec.Upload.QuarantineAfterRejections != nil {
+		quarantineAfterRejections = *kmCfg.Spec.Upload.QuarantineAfterRejections
+	}
+	kmCfg.Status.Upload.QuarantineAfterRejections = &quarantineAfterRejections
+	failoverThreshold := kokumetricscfgv1beta1.DefaultFailoverThreshold
+	if kmCfg.Spec.Upload.FailoverThreshold != nil {
+		failoverThreshold = *kmCfg.Spec.Upload.FailoverThreshold
+	}
+	kmCfg.Status.Upload.FailoverThreshold = &failoverThreshold
 
-	StringReflectSpec(r, kmCfg, &kmCfg.Spec.Source.SourcesAPIPath, &kmCfg.Status.Source.SourcesAPIPath, kokumetricscfgv1beta1.DefaultSourcesPath)
 	StringReflectSpec(r, kmCfg, &kmCfg.Spec.Source.SourceName, &kmCfg.Status.Source.SourceName, "")
 
 	kmCfg.Status.Source.CreateSource = kmCfg.Spec.Source.CreateSource
@@ -162,6 +217,63 @@ func ReflectSpec(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.Ko
 
 	StringReflectSpec(r, kmCfg, &kmCfg.Spec.PrometheusConfig.SvcAddress, &kmCfg.Status.Prometheus.SvcAddress, kokumetricscfgv1beta1.DefaultPrometheusSvcAddress)
 	kmCfg.Status.Prometheus.SkipTLSVerification = kmCfg.Spec.PrometheusConfig.SkipTLSVerification
+	kmCfg.Status.Prometheus.ExtraSelectors = kmCfg.Spec.PrometheusConfig.ExtraSelectors
+	reflectQueryOverrides(r, kmCfg)
+	kmCfg.Status.Prometheus.TLS = *kmCfg.Spec.PrometheusConfig.TLS.DeepCopy()
+	kmCfg.Status.Prometheus.MonitoringBinding.Managed = kmCfg.Spec.PrometheusConfig.ManageMonitoringBinding != nil &&
+		*kmCfg.Spec.PrometheusConfig.ManageMonitoringBinding
+
+	kmCfg.Status.DryRun.Enabled = isDryRun(kmCfg)
+
+	kmCfg.Status.PayloadAudit.Enabled = isPayloadAuditEnabled(kmCfg)
+	payloadAuditTTL := kokumetricscfgv1beta1.DefaultPayloadAuditTTLHours
+	if kmCfg.Spec.PayloadAudit.TTLHours != nil {
+		payloadAuditTTL = *kmCfg.Spec.PayloadAudit.TTLHours
+	}
+	kmCfg.Status.PayloadAudit.TTLHours = &payloadAuditTTL
+	reflectProcessingStatus(kmCfg)
+
+	kmCfg.Status.Profile = kmCfg.Spec.Profile
+	if kmCfg.Status.Profile == "" {
+		kmCfg.Status.Profile = kokumetricscfgv1beta1.DefaultOperatorProfile
+	}
+	kmCfg.Status.Resources = resourceHints(kmCfg)
+
+	// the cost model hints are written to the manifest of each payload
+	kmCfg.Status.CostModel = kmCfg.Spec.CostModel.DeepCopy()
+
+	kmCfg.Status.Reporting.BillingTimezone = kmCfg.Spec.Reporting.BillingTimezone
+	kmCfg.Status.Reporting.Error = ""
+	if kmCfg.Status.Reporting.BillingTimezone == "" {
+		kmCfg.Status.Reporting.BillingTimezone = kokumetricscfgv1beta1.DefaultBillingTimezone
+	} else if _, err := time.LoadLocation(kmCfg.Status.Reporting.BillingTimezone); err != nil {
+		kmCfg.Status.Reporting.Error = fmt.Sprintf("invalid billing time zone, using %s: %v", kokumetricscfgv1beta1.DefaultBillingTimezone, err)
+		kmCfg.Status.Reporting.BillingTimezone = kokumetricscfgv1beta1.DefaultBillingTimezone
+	}
+	kmCfg.Status.Reporting.PeriodStartDay = kokumetricscfgv1beta1.DefaultPeriodStartDay
+	if kmCfg.Spec.Reporting.PeriodStartDay != nil {
+		kmCfg.Status.Reporting.PeriodStartDay = *kmCfg.Spec.Reporting.PeriodStartDay
+	}
+	kmCfg.Status.Reporting.Granularity = kokumetricscfgv1beta1.DefaultReportGranularity
+	if kmCfg.Spec.Reporting.Granularity != "" {
+		kmCfg.Status.Reporting.Granularity = kmCfg.Spec.Reporting.Granularity
+	}
+	kmCfg.Status.Reporting.DataResidency = kmCfg.Spec.Reporting.DataResidency.Region
+	kmCfg.Status.Reporting.IngressAPIURL = kmCfg.Spec.Reporting.DataResidency.IngressAPIURL
+
+	reflectAdaptiveStep(kmCfg)
+	reflectExecutionMode(kmCfg)
+
+	reflectStatusTimestamps(r, kmCfg)
+}
+
+// BillingLocation returns the location that report windows are aligned to. The time zone is validated by ReflectSpec.
+func BillingLocation(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) *time.Location {
+	loc, err := time.LoadLocation(kmCfg.Status.Reporting.BillingTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 // GetClientset returns a clientset based on rest.config
@@ -243,6 +355,14 @@ func GetPullSecretToken(r *KokuMetricsConfigReconciler, authConfig *crhchttp.Aut
 	return nil
 }
 
+// getSecret reads the named secret, using the uncached secret reader when the operator runs with minimal RBAC
+func (r *KokuMetricsConfigReconciler) getSecret(ctx context.Context, key types.NamespacedName, secret *corev1.Secret) error {
+	if r.SecretReader != nil {
+		return r.SecretReader.Get(ctx, key, secret)
+	}
+	return r.Get(ctx, key, secret)
+}
+
 // GetAuthSecret Obtain the username and password from the authentication secret provided in the current namespace
 func GetAuthSecret(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, authConfig *crhchttp.AuthConfig, reqNamespace types.NamespacedName) error {
 	ctx := context.Background()
@@ -257,7 +377,7 @@ func GetAuthSecret(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.
 	namespace := types.NamespacedName{
 		Namespace: reqNamespace.Namespace,
 		Name:      kmCfg.Status.Authentication.AuthenticationSecretName}
-	err := r.Get(ctx, namespace, secret)
+	err := r.getSecret(ctx, namespace, secret)
 	if err != nil {
 		switch {
 		case errors.IsNotFound(err):
@@ -275,7 +395,14 @@ func GetAuthSecret(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.
 		keys[strings.ToLower(k)] = string(v)
 	}
 
-	for _, k := range []string{authSecretUserKey, authSecretPasswordKey} {
+	expectedKeys := []string{authSecretUserKey, authSecretPasswordKey}
+	switch kmCfg.Status.Authentication.AuthType {
+	case kokumetricscfgv1beta1.Static:
+		expectedKeys = []string{authSecretTokenKey}
+	case kokumetricscfgv1beta1.ServiceAccount:
+		expectedKeys = []string{authSecretClientIDKey, authSecretClientSecretKey}
+	}
+	for _, k := range expectedKeys {
 		if len(keys[k]) <= 0 {
 			msg := fmt.Sprintf("secret not found with expected %s data", k)
 			log.Info(msg)
@@ -285,7 +412,12 @@ func GetAuthSecret(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.
 
 	authConfig.BasicAuthUser = keys[authSecretUserKey]
 	authConfig.BasicAuthPassword = keys[authSecretPasswordKey]
+	authConfig.StaticToken = keys[authSecretTokenKey]
+	authConfig.StaticTokenHeader = kmCfg.Status.Authentication.TokenHeader
 
+	if kmCfg.Status.Authentication.AuthType == kokumetricscfgv1beta1.ServiceAccount {
+		return setServiceAccountToken(r, kmCfg, authConfig, keys[authSecretClientIDKey], keys[authSecretClientSecretKey])
+	}
 	return nil
 }
 
@@ -317,8 +449,41 @@ func setClusterID(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.K
 	return nil
 }
 
-func setAuthentication(r *KokuMetricsConfigReconciler, authConfig *crhchttp.AuthConfig, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, reqNamespace types.NamespacedName) error {
-	log := r.Log.WithValues("KokuMetricsConfig", "setAuthentication")
+// configureDialer sets how connections to the upload endpoint are dialed
+func configureDialer(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, log logr.Logger) {
+	dial := crhchttp.DialConfig{IPFamily: kmCfg.Status.Upload.IPFamily, Resolver: kmCfg.Status.Upload.DNSServer}
+	changed, err := crhchttp.SetDialConfig(dial)
+	if err != nil {
+		log.Error(err, "failed to configure the upload dialer")
+		return
+	}
+	if changed {
+		log.Info("configured the upload dialer", "ipFamily", dial.IPFamily, "dnsServer", dial.Resolver)
+	}
+}
+
+// configureTLS sets the TLS settings of the connections to cloud.redhat.com. Invalid settings are reported in the
+// status, and the previous settings are kept.
+func configureTLS(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, log logr.Logger) {
+	spec := kmCfg.Status.Upload.TLS
+	kmCfg.Status.Upload.TLSError = ""
+	settings, err := crhchttp.ParseTLSSettings(string(spec.MinVersion), spec.CipherSuites)
+	if err == nil {
+		var changed bool
+		changed, err = crhchttp.SetTLSSettings(settings)
+		if changed {
+			log.Info("configured the upload TLS settings", "minVersion", spec.MinVersion, "cipherSuites", spec.CipherSuites)
+		}
+	}
+	if err != nil {
+		log.Error(err, "failed to configure the upload TLS settings")
+		kmCfg.Status.Upload.TLSError = err.Error()
+	}
+}
+
+// setAuthenticationMethod obtains the credentials of the authentication type in the status
+func setAuthenticationMethod(r *KokuMetricsConfigReconciler, authConfig *crhchttp.AuthConfig, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, reqNamespace types.NamespacedName) error {
+	log := r.Log.WithValues("KokuMetricsConfig", "setAuthenticationMethod")
 	kmCfg.Status.Authentication.AuthenticationCredentialsFound = &trueDef
 	if kmCfg.Status.Authentication.AuthType == kokumetricscfgv1beta1.Token {
 		kmCfg.Status.Authentication.ValidBasicAuth = nil
@@ -343,9 +508,9 @@ func setAuthentication(r *KokuMetricsConfigReconciler, authConfig *crhchttp.Auth
 		}
 		return err
 	} else {
-		// No authentication secret name set when using basic auth
+		// No authentication secret name set when using basic or static auth
 		kmCfg.Status.Authentication.AuthenticationCredentialsFound = &falseDef
-		err := fmt.Errorf("no authentication secret name set when using basic auth")
+		err := fmt.Errorf("no authentication secret name set when using %s auth", kmCfg.Status.Authentication.AuthType)
 		kmCfg.Status.Authentication.AuthErrorMessage = err.Error()
 		kmCfg.Status.Authentication.ValidBasicAuth = &falseDef
 		return err
@@ -353,8 +518,8 @@ func setAuthentication(r *KokuMetricsConfigReconciler, authConfig *crhchttp.Auth
 }
 
 func validateCredentials(r *KokuMetricsConfigReconciler, sSpec *sources.SourceSpec, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, cycle int64) error {
-	if kmCfg.Spec.Authentication.AuthType == kokumetricscfgv1beta1.Token {
-		// no need to validate token auth
+	if kmCfg.Status.Authentication.AuthType != kokumetricscfgv1beta1.Basic {
+		// no need to validate token auth. Static tokens are used with relays, which do not serve the Sources API.
 		return nil
 	}
 
@@ -371,8 +536,7 @@ func validateCredentials(r *KokuMetricsConfigReconciler, sSpec *sources.SourceSp
 	}
 
 	log.Info("validating credentials")
-	client := crhchttp.GetClient(sSpec.Auth)
-	_, err := sources.GetSources(sSpec, client)
+	_, err := r.sourcesClient().GetSources(sSpec)
 
 	previousValidation.username = sSpec.Auth.BasicAuthUser
 	previousValidation.password = sSpec.Auth.BasicAuthPassword
@@ -381,16 +545,20 @@ func validateCredentials(r *KokuMetricsConfigReconciler, sSpec *sources.SourceSp
 
 	kmCfg.Status.Authentication.LastVerificationTime = &previousValidation.timestamp
 
-	if err != nil && strings.Contains(err.Error(), "401") {
+	if err != nil && (strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "403")) {
 		msg := fmt.Sprintf("cloud.redhat.com credentials are invalid. Correct the username/password in `%s`. Updated credentials will be re-verified during the next reconciliation.", kmCfg.Spec.Authentication.AuthenticationSecretName)
 		log.Info(msg)
 		kmCfg.Status.Authentication.AuthErrorMessage = msg
 		kmCfg.Status.Authentication.ValidBasicAuth = &falseDef
+		rejectAuthenticationMethod(r, kmCfg, kokumetricscfgv1beta1.Basic, "the credential validation was rejected")
 		return err
 	}
 	log.Info("credentials are valid")
 	kmCfg.Status.Authentication.AuthErrorMessage = ""
 	kmCfg.Status.Authentication.ValidBasicAuth = &trueDef
+	if err == nil {
+		kmCfg.Status.Authentication.SucceededMethod = kokumetricscfgv1beta1.Basic
+	}
 	return nil
 }
 
@@ -417,9 +585,8 @@ func checkSource(r *KokuMetricsConfigReconciler, sSpec *sources.SourceSpec, kmCf
 
 	log := r.Log.WithValues("KokuMetricsConfig", "checkSource")
 	if sSpec.Spec.SourceName != "" && (updated || checkCycle(r.Log, *sSpec.Spec.CheckCycle, sSpec.Spec.LastSourceCheckTime, "source check")) {
-		client := crhchttp.GetClient(sSpec.Auth)
 		kmCfg.Status.Source.SourceError = ""
-		defined, lastCheck, err := sources.SourceGetOrCreate(sSpec, client)
+		defined, lastCheck, err := r.sourcesClient().SourceGetOrCreate(sSpec)
 		if err != nil {
 			kmCfg.Status.Source.SourceError = err.Error()
 			log.Info("source get or create message", "error", err)
@@ -429,11 +596,11 @@ func checkSource(r *KokuMetricsConfigReconciler, sSpec *sources.SourceSpec, kmCf
 	}
 }
 
-func packageFiles(p *packaging.FilePackager) {
+func packageFiles(p *packaging.FilePackager, force bool) {
 	log := p.Log.WithValues("KokuMetricsConfig", "packageAndUpload")
 
-	// if its time to package
-	if !checkCycle(p.Log, *p.KMCfg.Status.Upload.UploadCycle, p.KMCfg.Status.Packaging.LastSuccessfulPackagingTime, "file packaging") {
+	// if its time to package. Packaging has its own cycle so that it is not held up by uploads.
+	if !force && !checkCycle(p.Log, *p.KMCfg.Status.Packaging.PackagingCycle, p.KMCfg.Status.Packaging.LastSuccessfulPackagingTime, "file packaging") {
 		return
 	}
 
@@ -446,15 +613,31 @@ func packageFiles(p *packaging.FilePackager) {
 	}
 }
 
-func uploadFiles(r *KokuMetricsConfigReconciler, authConfig *crhchttp.AuthConfig, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig) error {
-	log := r.Log.WithValues("kokumetricsconfig", "uploadFiles")
+// uploadFiles queues the packaged files for export to ingress, when authConfig is set, and to the additional destinations.
+// The files are uploaded by the upload worker, and the results of the previous uploads are written to the status.
+// When force is set, for example by an upload requested through the admin API, the upload cycle is not waited for.
+func uploadFiles(r *KokuMetricsConfigReconciler, authConfig *crhchttp.AuthConfig, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, force bool) error {
+	log := r.Log.WithValues("kokumetricsconfig", "uploadFiles", logging.ClusterID, kmCfg.Status.ClusterID)
 
 	// if its time to upload/package
 	if !*kmCfg.Spec.Upload.UploadToggle {
+		authConfig = nil
+	}
+	// the results are reflected first so that the exporters use the API URL failed over to
+	reflectUploadQueue(r, kmCfg, dirCfg)
+	exporters := buildExporters(r, kmCfg, authConfig, log)
+	if len(exporters) <= 0 {
 		log.Info("operator is configured to not upload reports")
+		uploader.DefaultQueue.Cancel()
 		return nil
 	}
-	if !checkCycle(r.Log, *kmCfg.Status.Upload.UploadCycle, kmCfg.Status.Upload.LastSuccessfulUploadTime, "upload") {
+	if uploader.DefaultQueue.Busy() {
+		log.Info("payloads are being uploaded")
+		return nil
+	}
+	if uploader.Interrupted(dirCfg.Parent.Path) {
+		log.Info("resuming upload that was interrupted")
+	} else if !r.runOnce && !force && !checkCycle(r.Log, *kmCfg.Status.Upload.UploadCycle, lastExportTime(kmCfg, exporters), "upload") {
 		return nil
 	}
 
@@ -470,70 +653,225 @@ func uploadFiles(r *KokuMetricsConfigReconciler, authConfig *crhchttp.AuthConfig
 	}
 
 	log.Info("files ready for upload: " + strings.Join(uploadFiles, ", "))
-	log.Info("pausing for " + fmt.Sprintf("%d", *kmCfg.Status.Upload.UploadWait) + " seconds before uploading")
-	time.Sleep(time.Duration(*kmCfg.Status.Upload.UploadWait) * time.Second)
-	for _, file := range uploadFiles {
-		if !strings.Contains(file, "tar.gz") {
-			continue
-		}
-		log.Info(fmt.Sprintf("uploading file: %s", file))
-		// grab the body and the multipart file header
-		body, contentType, err := crhchttp.GetMultiPartBodyAndHeaders(filepath.Join(dirCfg.Upload.Path, file))
-		if err != nil {
-			log.Error(err, "failed to set multipart body and headers")
-			return err
-		}
-		ingressURL := kmCfg.Status.APIURL + kmCfg.Status.Upload.IngressAPIPath
-		uploadStatus, uploadTime, err := crhchttp.Upload(authConfig, contentType, "POST", ingressURL, body)
-		kmCfg.Status.Upload.LastUploadStatus = uploadStatus
-		kmCfg.Status.Upload.UploadError = ""
-		if err != nil {
-			log.Error(err, "upload failed")
-			kmCfg.Status.Upload.UploadError = err.Error()
-			return nil
-		}
-		if strings.Contains(uploadStatus, "202") {
-			kmCfg.Status.Upload.LastSuccessfulUploadTime = uploadTime
-			// remove the tar.gz after a successful upload
-			log.Info("removing tar file since upload was successful")
-			if err := os.Remove(filepath.Join(dirCfg.Upload.Path, file)); err != nil {
-				log.Error(err, "error removing tar file")
-			}
-		}
+	// the upload is scheduled on the queue instead of blocking the reconcile for the upload wait
+	var notBefore time.Time
+	wait := uploadDelay(kmCfg)
+	if r.runOnce {
+		wait = jobUploadDelay(kmCfg, wait)
 	}
+	if wait > 0 {
+		log.Info(fmt.Sprintf("uploading in %d seconds", int64(wait/time.Second)))
+		notBefore = time.Now().Add(wait)
+	}
+	uploader.DefaultQueue.Submit(uploader.Batch{
+		UploadDir:   dirCfg.Upload.Path,
+		StateDir:    dirCfg.Parent.Path,
+		Exporters:   exporters,
+		NotBefore:   notBefore,
+		Interval:    time.Duration(*kmCfg.Status.Upload.UploadInterval) * time.Second,
+		MaxBackfill: int(*kmCfg.Status.Upload.MaxBackfillUploads),
+		// payloads that ingress keeps rejecting are set aside for review so that they do not hold up the queue
+		QuarantineDir: filepath.Join(dirCfg.Parent.Path, dirconfig.QuarantineDir),
+		MaxRejections: int(*kmCfg.Status.Upload.QuarantineAfterRejections),
+		Log:           log,
+	})
+	kmCfg.Status.Upload.Queue.InProgress = true
 	return nil
 }
 
-func collectPromStats(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig) {
-	log := r.Log.WithValues("KokuMetricsConfig", "collectPromStats")
+// reflectUploadQueue writes the results of the uploads since the last reconcile, and the state of the upload queue, to
+// the status and the history
+func reflectUploadQueue(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig) {
+	results := uploader.DefaultQueue.Results()
+	for _, result := range results {
+		recordExport(kmCfg, result)
+		recordRejectedUpload(r, kmCfg, result.Exporter, result.Err)
+		recordUploadAuthorization(kmCfg, result)
+		recordUploadMethod(r, kmCfg, result)
+	}
+	recordEndpointResults(kmCfg, results)
+	recordUploadedDimensions(r.Log, dirCfg, results)
+	recordReplayResults(kmCfg, results)
+	auditPayloads(r, kmCfg, results)
+	quarantined := uploader.DefaultQueue.Quarantined()
+	recordQuarantined(r, kmCfg, quarantined, time.Now())
+	recordHistory(kmCfg, dirCfg, r.Log, uploadHistory(results, quarantined)...)
+	accountUploads(kmCfg, results, time.Now())
+	stats := uploader.DefaultQueue.Stats()
+	kmCfg.Status.Upload.Queue.CriticalPayloads = int64(stats.Critical)
+	kmCfg.Status.Upload.Queue.BackfillPayloads = int64(stats.Backfill)
+	kmCfg.Status.Upload.Queue.InProgress = uploader.DefaultQueue.Busy()
+	kmCfg.Status.Upload.Queue.PostProcessError = stats.PostProcessError
+	if !stats.LastBatchStart.IsZero() {
+		kmCfg.Status.Upload.Queue.LastBatchStartTime = metav1.Time{Time: stats.LastBatchStart}
+	}
+	if !stats.LastBatchEnd.IsZero() {
+		kmCfg.Status.Upload.Queue.LastBatchEndTime = metav1.Time{Time: stats.LastBatchEnd}
+	}
+}
+
+// setPromCollector creates the prometheus collector if needed, tags its logs with the cluster ID, and sets the query profile
+func setPromCollector(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
 	if r.promCollector == nil {
 		r.promCollector = &collector.PromCollector{
-			Log:       r.Log,
-			InCluster: r.InCluster,
+			InCluster:  r.InCluster,
+			Fixtures:   r.Fixtures,
+			StaticConn: r.PromConn,
 		}
 	}
+	r.promCollector.Log = r.Log.WithValues(logging.ClusterID, kmCfg.Status.ClusterID)
+	ConfigureCollector(r.promCollector, kmCfg)
+}
+
+// ConfigureCollector sets the report options of the collector from the KokuMetricsConfig, so that the reports
+// generated outside of the operator match the reports of the operator
+func ConfigureCollector(c *collector.PromCollector, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	c.Lightweight = isLightweight(kmCfg)
+	c.MaxParallelQueries = int(kmCfg.Status.Resources.MaxParallelQueries)
+	c.TenantLabel = kmCfg.Spec.ReportFilters.TenantLabel
+	c.AnnotationPrefixes = kmCfg.Spec.ReportFilters.AnnotationPrefixes
+	c.ExtraSelectors = kmCfg.Spec.PrometheusConfig.ExtraSelectors
+	c.QueryOverrides = kmCfg.Status.Prometheus.QueryOverrides
+	c.CustomMetrics = nil
+	if kmCfg.Spec.CustomMetrics != nil {
+		c.CustomMetrics = kmCfg.Spec.CustomMetrics.Queries
+	}
+	c.ExpectedPodLabels = kmCfg.Spec.ReportFilters.ExpectedPodLabels
+	c.MaxLabelValueLength = int(kokumetricscfgv1beta1.DefaultMaxLabelValueLength)
+	if kmCfg.Spec.ReportFilters.MaxLabelValueLength != nil {
+		c.MaxLabelValueLength = int(*kmCfg.Spec.ReportFilters.MaxLabelValueLength)
+	}
+	c.PeriodStartDay = int(kmCfg.Status.Reporting.PeriodStartDay)
+	c.NamespaceGranularity = kmCfg.Status.Reporting.Granularity == kokumetricscfgv1beta1.NamespaceGranularity
+}
+
+// sourcesClient returns the client used to reach the sources API
+func (r *KokuMetricsConfigReconciler) sourcesClient() sources.Client {
+	if r.SourcesClient != nil {
+		return r.SourcesClient
+	}
+	return sources.APIClient{}
+}
+
+// uploader returns the uploader used to reach the ingress endpoint
+func (r *KokuMetricsConfigReconciler) uploader() crhchttp.Uploader {
+	if r.Uploader != nil {
+		return r.Uploader
+	}
+	return crhchttp.APIUploader{}
+}
+
+// newAuthConfig returns the configuration used to communicate with cloud.redhat.com. The credentials are set by setAuthentication.
+func newAuthConfig(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, log logr.Logger) *crhchttp.AuthConfig {
+	headers, sensitive := extraHeaders(r, kmCfg)
+	if kmCfg.Status.Upload.ExtraHeadersError != "" {
+		log.Info("some extra headers will not be sent", "error", kmCfg.Status.Upload.ExtraHeadersError)
+	}
+	return &crhchttp.AuthConfig{
+		Log:              log,
+		CertPolicy:       CertPolicy(kmCfg),
+		Authentication:   kmCfg.Status.Authentication.AuthType,
+		OperatorCommit:   kmCfg.Status.OperatorCommit,
+		ClusterID:        kmCfg.Status.ClusterID,
+		Client:           r.Client,
+		ExtraHeaders:     headers,
+		SensitiveHeaders: sensitive,
+	}
+}
+
+// UploadAuthConfig returns the configuration used to upload the payloads of the KokuMetricsConfig, with the credentials
+// of its authentication type
+func UploadAuthConfig(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, reqNamespace types.NamespacedName) (*crhchttp.AuthConfig, error) {
+	authConfig := newAuthConfig(r, kmCfg, r.Log.WithValues(logging.ClusterID, kmCfg.Status.ClusterID))
+	if err := setAuthentication(r, authConfig, kmCfg, reqNamespace); err != nil {
+		return nil, fmt.Errorf("UploadAuthConfig: %v", err)
+	}
+	return authConfig, nil
+}
+
+func collectPromStats(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, force bool) {
+	log := r.Log.WithValues("KokuMetricsConfig", "collectPromStats", logging.ClusterID, kmCfg.Status.ClusterID)
+	setPromCollector(r, kmCfg)
 	r.promCollector.TimeSeries = nil
+	defer r.promCollector.SetEndpointStatus(kmCfg)
+
+	t := metav1.Time{Time: metav1.Now().In(BillingLocation(kmCfg))}
+	timeRange := hourlyWindow(t.Time, t.Location(), queryStep(kmCfg))
+	// the hours are compared in UTC, since an hour is repeated in the billing time zone when daylight saving time ends.
+	// A forced collection collects the hour again, and overwrites its reports.
+	collected := !force && kmCfg.Status.Prometheus.LastQuerySuccessTime.UTC().Format(promCompareFormat) == t.UTC().Format(promCompareFormat)
+
+	// the queries are published and restricted before any is run, including the queries of re-collection and backfill
+	if err := reconcileQueryAllowList(r, kmCfg, log); err != nil {
+		log.Error(err, "queries will not be run")
+		if !collected {
+			kmCfg.Status.Reports.DataCollected = false
+			kmCfg.Status.Reports.DataCollectionMessage = fmt.Sprintf("error: %v", err)
+			accountCollection(kmCfg, timeRange.Start, false, err, t.Time)
+		}
+		return
+	}
 
-	if err := r.promCollector.GetPromConn(kmCfg); err != nil {
+	err := r.promCollector.GetPromConn(kmCfg)
+	health.setPrometheusStatus(err)
+	if err != nil {
 		log.Error(err, "failed to get prometheus connection")
+		if !collected {
+			accountCollection(kmCfg, timeRange.Start, false, fmt.Errorf("failed to get prometheus connection: %v", err), t.Time)
+		}
 		return
 	}
-	timeUTC := metav1.Now().UTC()
-	t := metav1.Time{Time: timeUTC}
-	timeRange := promv1.Range{
-		Start: time.Date(t.Year(), t.Month(), t.Day(), t.Hour()-1, 0, 0, 0, t.Location()),
-		End:   time.Date(t.Year(), t.Month(), t.Day(), t.Hour()-1, 59, 59, 0, t.Location()),
-		Step:  time.Minute,
+	if err := r.promCollector.GetUWMConn(kmCfg); err != nil {
+		log.Error(err, "failed to get user workload monitoring prometheus connection, custom metrics will not be collected")
+	}
+	index, err := collector.LoadWindowIndex(filepath.Join(dirCfg.Parent.Path, collector.WindowIndexFile))
+	if err != nil {
+		log.Error(err, "failed to load window index, starting a new index")
 	}
+	r.promCollector.Index = index
+	r.promCollector.Overwrite = force
 	r.promCollector.TimeSeries = &timeRange
 
-	if kmCfg.Status.Prometheus.LastQuerySuccessTime.UTC().Format(promCompareFormat) == t.Format(promCompareFormat) {
+	if collected {
 		log.Info("reports already generated for range", "start", timeRange.Start, "end", timeRange.End)
 		return
 	}
 	kmCfg.Status.Prometheus.LastQueryStartTime = t
-	log.Info("generating reports for range", "start", timeRange.Start, "end", timeRange.End)
-	if err := collector.GenerateReports(kmCfg, dirCfg, r.promCollector); err != nil {
+	if !checkMonitoringAccess(r, kmCfg) {
+		condition := kokumetricscfgv1beta1.FindCondition(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionMonitoringAccess)
+		kmCfg.Status.Reports.DataCollected = false
+		kmCfg.Status.Reports.DataCollectionMessage = condition.Message
+		log.Info("reports will not be generated", "reason", condition.Message)
+		accountCollection(kmCfg, timeRange.Start, false, fmt.Errorf("%s", condition.Message), t.Time)
+		r.Recorder.Event(kmCfg, corev1.EventTypeWarning, condition.Reason, condition.Message)
+		return
+	}
+	// a collection interrupted by the previous process because it ran out of memory coarsens the query step before
+	// this window is collected
+	if kmCfg.Status.Prometheus.AdaptiveStep.Enabled {
+		if step, interrupted, err := startCollection(dirCfg.Parent.Path, timeRange.Step); err != nil {
+			log.Error(err, "failed to check for an interrupted collection")
+		} else if interrupted {
+			reviewInterruptedCollection(r, kmCfg, step, t.Time, log)
+			timeRange.Step = queryStep(kmCfg)
+		}
+	}
+	log.Info("generating reports for range", "start", timeRange.Start, "end", timeRange.End, "step", timeRange.Step)
+	collectionStart := time.Now()
+	err = collector.GenerateReports(kmCfg, dirCfg, r.promCollector)
+	if err := finishCollection(dirCfg.Parent.Path); err != nil {
+		log.Error(err, "failed to finish the collection")
+	}
+	adaptQueryStep(r, kmCfg, time.Since(collectionStart), memoryInUseMB(), time.Now(), log)
+	if err != collector.ErrWindowCollected {
+		accountCollection(kmCfg, timeRange.Start, kmCfg.Status.Reports.DataCollected, err, time.Now())
+		recordHistory(kmCfg, dirCfg, log, collectionHistory(historyCollection, kmCfg, timeRange.Start, err))
+	}
+	if err == collector.ErrWindowCollected {
+		log.Info("reports were already generated for range, the window will not be collected again", "start", timeRange.Start, "end", timeRange.End)
+		kmCfg.Status.Prometheus.LastQuerySuccessTime = t
+		return
+	} else if err != nil {
 		kmCfg.Status.Reports.DataCollected = false
 		kmCfg.Status.Reports.DataCollectionMessage = fmt.Sprintf("error: %v", err)
 		log.Error(err, "failed to generate reports")
@@ -541,9 +879,32 @@ func collectPromStats(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1bet
 	}
 	log.Info("reports generated for range", "start", timeRange.Start, "end", timeRange.End)
 	kmCfg.Status.Prometheus.LastQuerySuccessTime = t
+
+	if r.RecordFixtures != "" {
+		dir := filepath.Join(r.RecordFixtures, timeRange.Start.UTC().Format("20060102T150405"))
+		if err := r.promCollector.RecordFixtures(dir); err != nil {
+			log.Error(err, "failed to record prometheus fixtures")
+		}
+	}
+
+	if r.promCollector.NamespaceUsage != nil {
+		summary, err := updateUsageSummary(r, kmCfg, dirCfg, r.promCollector.NamespaceUsage, log)
+		if err != nil {
+			log.Error(err, "failed to update usage summary")
+		}
+		if err := updateCostEstimate(r, kmCfg, dirCfg, r.promCollector.NamespaceUsage, summary, log); err != nil {
+			log.Error(err, "failed to estimate cost")
+		}
+	}
+
+	if degraded := kokumetricscfgv1beta1.FindCondition(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionDegraded); degraded != nil &&
+		degraded.Status == corev1.ConditionTrue && (degraded.Reason == kokumetricscfgv1beta1.ReasonEmptyReports ||
+		degraded.Reason == kokumetricscfgv1beta1.ReasonInconsistentReports || degraded.Reason == kokumetricscfgv1beta1.ReasonMissingLabels) {
+		r.Recorder.Event(kmCfg, corev1.EventTypeWarning, degraded.Reason, degraded.Message)
+	}
 }
 
-func configurePVC(r *KokuMetricsConfigReconciler, req ctrl.Request, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) (*ctrl.Result, error) {
+func configurePVC(r *KokuMetricsConfigReconciler, status *statusWriter, req ctrl.Request, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) (*ctrl.Result, error) {
 	ctx := context.Background()
 	log := r.Log.WithValues("kokumetricsconfig", "configurePVC")
 	pvcTemplate := kmCfg.Spec.VolumeClaimTemplate
@@ -578,7 +939,7 @@ func configurePVC(r *KokuMetricsConfigReconciler, req ctrl.Request, kmCfg *kokum
 
 	if strings.Contains(kmCfg.Status.Storage.VolumeType, "EmptyDir") {
 		kmCfg.Status.Storage.VolumeMounted = false
-		if err := r.Status().Update(ctx, kmCfg); err != nil {
+		if err := status.update(ctx, kmCfg); err != nil {
 			log.Error(err, "failed to update KokuMetricsConfig status")
 		}
 		return &ctrl.Result{}, fmt.Errorf("PVC not mounted")
@@ -588,12 +949,19 @@ func configurePVC(r *KokuMetricsConfigReconciler, req ctrl.Request, kmCfg *kokum
 
 // +kubebuilder:rbac:groups=koku-metrics-cfg.openshift.io,namespace=koku-metrics-operator,resources=kokumetricsconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=koku-metrics-cfg.openshift.io,namespace=koku-metrics-operator,resources=kokumetricsconfigs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=koku-metrics-cfg.openshift.io,namespace=koku-metrics-operator,resources=kokumetricspayloads,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=koku-metrics-cfg.openshift.io,namespace=koku-metrics-operator,resources=kokumetricspayloads/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operators.coreos.com,namespace=koku-metrics-operator,resources=clusterserviceversions,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=config.openshift.io,resources=clusterversions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=config.openshift.io,resources=infrastructures,verbs=get
+// +kubebuilder:rbac:groups=koku-metrics-cfg.openshift.io,resources=kokumetricsdefaults,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 // +kubebuilder:rbac:groups=core,namespace=koku-metrics-operator,resources=pods;services;services/finalizers;endpoints;persistentvolumeclaims;events;configmaps;secrets;serviceaccounts,verbs=create;delete;get;list;patch;update;watch
 // +kubebuilder:rbac:groups=apps,namespace=koku-metrics-operator,resources=deployments,verbs=get;list;patch;watch
+// +kubebuilder:rbac:groups=batch,namespace=koku-metrics-operator,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile Process the KokuMetricsConfig custom resource based on changes or requeue
 func (r *KokuMetricsConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -606,19 +974,51 @@ func (r *KokuMetricsConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 
 	if err := r.Get(ctx, req.NamespacedName, kmCfgOriginal); err != nil {
 		log.Info(fmt.Sprintf("unable to fetch KokuMetricsConfigCR: %v", err))
+		if errors.IsNotFound(err) {
+			// the CR was removed, so there is no pipeline left to report on
+			health.reset()
+			hubReceiver.Disable()
+		}
 		// we'll ignore not-found errors, since they cannot be fixed by an immediate
 		// requeue (we'll need to wait for a new notification), and we can get them
 		// on deleted requests.
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 	kmCfg := kmCfgOriginal.DeepCopy()
+	// the status is patched with the changes made since it was read, so that a change to the KokuMetricsConfig
+	// made during the reconcile is kept
+	status := newStatusWriter(r, kmCfgOriginal)
 	log.Info("reconciling custom resource", "KokuMetricsConfig", kmCfg)
 
+	health.startReconcile()
+	defer health.finishReconcile()
+
+	// the spec is layered over the fleet defaults before it is reflected into status
+	applyFleetDefaults(r, kmCfg, log)
+
 	// reflect the spec values into status
 	ReflectSpec(r, kmCfg)
+	applyProfile(kmCfg)
+	configureDialer(kmCfg, log)
+	configureTLS(kmCfg, log)
+
+	// the operator pod is given time to finish the reconcile in progress when it is stopped
+	if r.InCluster && !r.runOnce {
+		reconcileScheduling(r, kmCfg)
+	}
 
-	if r.InCluster {
-		res, err := configurePVC(r, req, kmCfg)
+	if r.runOnce {
+		if !isCronJobMode(kmCfg) {
+			log.Info("the KokuMetricsConfig is not in the cronjob execution mode: nothing to collect")
+			return ctrl.Result{}, nil
+		}
+	} else if res, err := reconcileExecutionMode(r, status, kmCfg); res != nil {
+		return *res, err
+	}
+
+	// the Jobs of the cronjob execution mode write their reports to the PVC of the Jobs
+	if r.InCluster && !useEmptyDir(kmCfg) && !r.runOnce {
+		res, err := configurePVC(r, status, req, kmCfg)
 		if err != nil || res != nil {
 			return *res, err
 		}
@@ -627,12 +1027,23 @@ func (r *KokuMetricsConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 	// set the cluster ID & return if there are errors
 	if err := setClusterID(r, kmCfg); err != nil {
 		log.Error(err, "failed to obtain clusterID")
-		if err := r.Status().Update(ctx, kmCfg); err != nil {
+		if err := status.update(ctx, kmCfg); err != nil {
 			log.Error(err, "failed to update KokuMetricsConfig status")
 		}
 		return ctrl.Result{}, err
 	}
+	// the upload wait is derived from the cluster ID once it is known
+	reflectUploadWait(kmCfg)
+
+	// detect managed service clusters, which are registered by the managed service
+	setClusterType(r, kmCfg)
 
+	// restore the state that the status has lost, for example when the KokuMetricsConfig was re-created
+	recoverCollectorState(r, kmCfg, log)
+
+	// all subsequent logs are tagged with the cluster ID
+	clusterLog := r.Log.WithValues(logging.ClusterID, kmCfg.Status.ClusterID)
+	log = log.WithValues(logging.ClusterID, kmCfg.Status.ClusterID)
 	log.Info("using the following inputs", "KokuMetricsConfigConfig", kmCfg.Status)
 
 	// set the Operator git commit and reflect it in the upload status & return if there are errors
@@ -645,40 +1056,108 @@ func (r *KokuMetricsConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 			log.Error(err, "failed to get directory configuration")
 			return ctrl.Result{}, err // without this directory, it is pointless to continue
 		}
+		migrateData(r, kmCfg, dirCfg)
+	}
+
+	// accept payloads from spoke clusters if hub aggregation is enabled
+	configureHub(r, kmCfg, dirCfg, clusterLog)
+
+	// run the connection test if it has been requested
+	runConnectionTest(r, req, kmCfg, clusterLog)
+
+	// check that the upload endpoints are reachable before the first collection, and write the result right away
+	if runPreflight(r, req, kmCfg, clusterLog) {
+		status.checkpoint(ctx, kmCfg, "preflight")
+	}
+
+	// generate a debug bundle if it has been requested
+	writeDebugBundle(r, kmCfg, clusterLog)
+
+	// reports are not collected or uploaded during a blackout window
+	blackout := reconcileBlackout(r, kmCfg, time.Now(), clusterLog)
+
+	// reports are not collected, and by default not uploaded, while the cluster is upgrading
+	upgradeCollection, upgradeUpload := checkUpgrade(r, kmCfg, time.Now(), clusterLog)
+
+	// in dry-run mode, reports are only generated and validated: nothing is packaged or uploaded
+	if isDryRun(kmCfg) {
+		if !blackout && !upgradeCollection {
+			runDryRun(r, kmCfg, dirCfg)
+		}
+		if err := status.update(ctx, kmCfg); err != nil {
+			log.Error(err, "failed to update KokuMetricsConfig status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: requeueInterval(kmCfg)}, nil
+	}
+
+	// a collection requested through the admin API waits for the end of a blackout window or an upgrade
+	adminCollectRequested := false
+	if !blackout && !upgradeCollection {
+		// detect the hours missed while the operator was not running, before the current hour is collected
+		detectPause(r, kmCfg, time.Now(), clusterLog)
+
+		// attempt to collect prometheus stats and create reports. A collection requested through the admin API
+		// collects the last hour again.
+		adminCollectRequested = admin.take(req.NamespacedName, adminCollect)
+		if adminCollectRequested {
+			log.Info("collecting the last hour as requested through the admin API")
+		}
+		collectPromStats(r, kmCfg, dirCfg, adminCollectRequested)
+
+		// regenerate reports for past hours if it has been requested
+		recollectReports(r, kmCfg, dirCfg, clusterLog)
+
+		// regenerate reports for past hours from the snapshot prometheus if one is configured
+		recollectSnapshot(r, kmCfg, dirCfg, clusterLog)
+
+		// collect the hours missed while the operator was not running, during the last blackout window, or during the last upgrade
+		backfillPause(r, kmCfg, dirCfg, clusterLog)
+		backfillBlackout(r, kmCfg, dirCfg, clusterLog)
+		backfillUpgrade(r, kmCfg, dirCfg, clusterLog)
 	}
 
-	// attempt to collect prometheus stats and create reports
-	collectPromStats(r, kmCfg, dirCfg)
+	// detect a skewed local clock from the Date header of API responses
+	checkClockSkew(r, kmCfg)
+	status.checkpoint(ctx, kmCfg, "collection")
 
 	// package report files
 	packager := &packaging.FilePackager{
-		KMCfg:  kmCfg,
-		DirCfg: dirCfg,
-		Log:    r.Log,
+		KMCfg:     kmCfg,
+		DirCfg:    dirCfg,
+		Log:       clusterLog,
+		ClockSkew: manifestClockSkew(kmCfg),
+	}
+	// payloads are not packaged unsigned when signing is configured but the key cannot be read
+	if signingKey, err := getSigningKey(r, kmCfg); err != nil {
+		log.Error(err, "failed to get the payload signing key")
+		kmCfg.Status.Packaging.PackagingError = err.Error()
+	} else {
+		packager.SigningKey = signingKey
+		// the reports of a Job are lost when it exits, so they are packaged on every run. The reports are also
+		// packaged right away when a collection is requested through the admin API.
+		packageFiles(packager, r.runOnce || adminCollectRequested)
 	}
-	packageFiles(packager)
+	status.checkpoint(ctx, kmCfg, "packaging")
 
-	// Initial returned result -> requeue reconcile after 5 min.
+	// Initial returned result -> requeue reconcile after 5 min (15 min with the lightweight profile).
 	// This result is replaced if upload or status update results in error.
-	var result = ctrl.Result{RequeueAfter: time.Minute * 5}
+	var result = ctrl.Result{RequeueAfter: requeueInterval(kmCfg)}
 	var errors []error
 
-	if kmCfg.Spec.Upload.UploadToggle != nil && *kmCfg.Spec.Upload.UploadToggle {
+	if blackout {
+		log.Info("blackout window in progress, payloads will be uploaded after it ends", "window", kmCfg.Status.Blackout.Window)
+	} else if upgradeUpload {
+		log.Info("cluster upgrade in progress, payloads will be uploaded after it completes", "version", kmCfg.Status.Upgrade.Version)
+	} else if kmCfg.Spec.Upload.UploadToggle != nil && *kmCfg.Spec.Upload.UploadToggle {
 
 		log.Info("configuration is for connected cluster")
 
-		authConfig := &crhchttp.AuthConfig{
-			Log:            r.Log,
-			ValidateCert:   *kmCfg.Status.Upload.ValidateCert,
-			Authentication: kmCfg.Status.Authentication.AuthType,
-			OperatorCommit: kmCfg.Status.OperatorCommit,
-			ClusterID:      kmCfg.Status.ClusterID,
-			Client:         r.Client,
-		}
+		authConfig := newAuthConfig(r, kmCfg, clusterLog)
 
 		// obtain credentials token/basic & return if there are authentication credential errors
 		if err := setAuthentication(r, authConfig, kmCfg, req.NamespacedName); err != nil {
-			if err := r.Status().Update(ctx, kmCfg); err != nil {
+			if err := status.update(ctx, kmCfg); err != nil {
 				log.Error(err, "failed to update KokuMetricsConfig status")
 			}
 			return ctrl.Result{}, err
@@ -688,21 +1167,34 @@ func (r *KokuMetricsConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 			APIURL: kmCfg.Status.APIURL,
 			Auth:   authConfig,
 			Spec:   kmCfg.Status.Source,
-			Log:    r.Log,
+			Log:    clusterLog,
 		}
 
 		if err := validateCredentials(r, sSpec, kmCfg, 1440); err == nil {
 			// Block will run when creds are valid.
 
-			// Check if source is defined and update the status to confirmed/created
-			checkSource(r, sSpec, kmCfg)
+			// Check if source is defined and update the status to confirmed/created.
+			// Relays do not serve the Sources API, so sources are not checked with static token authentication.
+			if kmCfg.Status.Authentication.AuthType != kokumetricscfgv1beta1.Static {
+				setDefaultSourceName(r, sSpec, kmCfg)
+				checkSource(r, sSpec, kmCfg)
+			}
 
 			// attempt upload
-			if err := uploadFiles(r, authConfig, kmCfg, dirCfg); err != nil {
+			if err := uploadFiles(r, authConfig, kmCfg, dirCfg, takeAdminUpload(req.NamespacedName, log)); err != nil {
 				result = ctrl.Result{}
 				errors = append(errors, err)
 			}
 
+			// upload the payloads in the retry directory if it has been requested
+			replayPayloads(r, authConfig, kmCfg, dirCfg, clusterLog)
+
+			// poll cost management for the processing status of the uploaded payloads.
+			// Relays do not serve the cost management API, so it is not polled with static token authentication.
+			if kmCfg.Status.Authentication.AuthType != kokumetricscfgv1beta1.Static {
+				pollProcessingStatus(r, sSpec, kmCfg, time.Now())
+			}
+
 			// revalidate if an upload fails due to 401
 			if strings.Contains(kmCfg.Status.Upload.LastUploadStatus, "401") {
 				_ = validateCredentials(r, sSpec, kmCfg, 0)
@@ -710,13 +1202,26 @@ func (r *KokuMetricsConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 		}
 	} else {
 		log.Info("configuration is for restricted-network cluster")
+
+		// export to the additional destinations
+		if len(kmCfg.Spec.Upload.Destinations) > 0 {
+			if err := uploadFiles(r, nil, kmCfg, dirCfg, takeAdminUpload(req.NamespacedName, log)); err != nil {
+				result = ctrl.Result{}
+				errors = append(errors, err)
+			}
+		}
+
+		// export the payloads in the retry directory to the additional destinations if it has been requested
+		replayPayloads(r, nil, kmCfg, dirCfg, clusterLog)
 	}
+	status.checkpoint(ctx, kmCfg, "upload")
 
 	// remove old reports if maximum report count has been exceeded
 	if err := packager.TrimPackages(); err != nil {
 		result = ctrl.Result{}
 		errors = append(errors, err)
 	}
+	accountTrimmedPayloads(kmCfg, packager.Trimmed, time.Now())
 
 	uploadFiles, err := dirCfg.Upload.GetFilesFullPath()
 	if err != nil {
@@ -724,8 +1229,15 @@ func (r *KokuMetricsConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 		errors = append(errors, err)
 	}
 	kmCfg.Status.Packaging.PackagedFiles = uploadFiles
+	reflectQuarantine(kmCfg, dirCfg)
+	setFleetMetrics(kmCfg)
+	setMissedWindowMetrics(kmCfg)
+
+	if err := saveCollectorState(r, kmCfg); err != nil {
+		log.Error(err, "failed to save the collector state")
+	}
 
-	if err := r.Status().Update(ctx, kmCfg); err != nil {
+	if err := status.update(ctx, kmCfg); err != nil {
 		log.Error(err, "failed to update KokuMetricsConfig status")
 		result = ctrl.Result{}
 		errors = append(errors, err)
@@ -735,10 +1247,18 @@ func (r *KokuMetricsConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 	return result, concatErrs(errors...)
 }
 
-// SetupWithManager Setup reconciliation with manager object
+// SetupWithManager Setup reconciliation with manager object. The ClusterVersion is watched so that collection is
+// deferred as soon as an upgrade starts, and resumes when it completes. The KokuMetricsDefault is watched so that a
+// change to the fleet defaults is applied immediately.
 func (r *KokuMetricsConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kokumetricscfgv1beta1.KokuMetricsConfig{}).
+		Watches(&source.Kind{Type: &configv1.ClusterVersion{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.clusterVersionRequests)},
+			builder.WithPredicates(upgradeChanged)).
+		Watches(&source.Kind{Type: &kokumetricscfgv1beta1.KokuMetricsDefault{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.fleetDefaultsRequests)}).
+		Watches(&source.Channel{Source: admin.events}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
 
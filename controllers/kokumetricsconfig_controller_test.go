@@ -52,6 +52,7 @@ var (
 	badAuthSecretName           = "baduserpass"
 	badAuthPassSecretName       = "badpass"
 	badAuthUserSecretName       = "baduser"
+	staticTokenSecretName       = "relay-token"
 	falseValue            bool  = false
 	trueValue             bool  = true
 	defaultUploadCycle    int64 = 360
@@ -562,6 +563,32 @@ var _ = Describe("KokuMetricsConfigController - CRD Handling", func() {
 
 			Expect(k8sClient.Delete(ctx, fetched)).To(Succeed())
 		})
+		It("should find the static token for a relay upload CRD case", func() {
+			instCopy := instance.DeepCopy()
+			instCopy.ObjectMeta.Name = namePrefix + "static-token-relay"
+			instCopy.Spec.APIURL = validTS.URL
+			instCopy.Spec.Authentication.AuthType = kokumetricscfgv1beta1.Static
+			instCopy.Spec.Authentication.AuthenticationSecretName = staticTokenSecretName
+			instCopy.Spec.Upload.PayloadFormat = kokumetricscfgv1beta1.PassthroughPayload
+			instCopy.Spec.Upload.UploadWait = &defaultUploadWait
+			Expect(k8sClient.Create(ctx, instCopy)).Should(Succeed())
+			fetched := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+
+			// wait until the cluster ID is set
+			Eventually(func() bool {
+				_ = k8sClient.Get(ctx, types.NamespacedName{Name: instCopy.Name, Namespace: namespace}, fetched)
+				return fetched.Status.ClusterID != ""
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(fetched.Status.Authentication.AuthType).To(Equal(kokumetricscfgv1beta1.Static))
+			Expect(fetched.Status.Authentication.TokenHeader).To(Equal(kokumetricscfgv1beta1.DefaultTokenHeader))
+			Expect(*fetched.Status.Authentication.AuthenticationCredentialsFound).To(BeTrue())
+			Expect(fetched.Status.Authentication.ValidBasicAuth).To(BeNil())
+			Expect(fetched.Status.Upload.PayloadFormat).To(Equal(kokumetricscfgv1beta1.PassthroughPayload))
+			Expect(fetched.Status.Source.SourceDefined).To(BeNil())
+
+			Expect(k8sClient.Delete(ctx, fetched)).To(Succeed())
+		})
 		It("should find basic auth creds for good basic auth CRD case but fail because creds are wrong", func() {
 			instCopy := instance.DeepCopy()
 			instCopy.ObjectMeta.Name = namePrefix + "basicauthgood-unauthorized"
@@ -0,0 +1,109 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const (
+	// managed service clusters tag their cloud resources with these keys
+	managedTagKey     = "red-hat-managed"
+	clusterTypeTagKey = "red-hat-clustertype"
+	// managed service clusters run the osd metrics exporter, including clusters without resource tags
+	managedNamespace = "openshift-osd-metrics"
+)
+
+// managedClusterType returns the type of the cluster from the resource tags of the Infrastructure, or from
+// whether the namespace of the managed service is found
+func managedClusterType(infra *unstructured.Unstructured, managedNamespaceFound bool) kokumetricscfgv1beta1.ClusterType {
+	tags, _, _ := unstructured.NestedSlice(infra.Object, "status", "platformStatus", "aws", "resourceTags")
+	values := make(map[string]string)
+	for _, tag := range tags {
+		if t, ok := tag.(map[string]interface{}); ok {
+			key, _ := t["key"].(string)
+			value, _ := t["value"].(string)
+			values[key] = strings.ToLower(value)
+		}
+	}
+	if values[managedTagKey] == "true" {
+		if kokumetricscfgv1beta1.ClusterType(values[clusterTypeTagKey]) == kokumetricscfgv1beta1.ROSACluster {
+			return kokumetricscfgv1beta1.ROSACluster
+		}
+		return kokumetricscfgv1beta1.OSDCluster
+	}
+	if managedNamespaceFound {
+		return kokumetricscfgv1beta1.OSDCluster
+	}
+	return kokumetricscfgv1beta1.SelfManagedCluster
+}
+
+// getClusterType detects if the cluster is a managed service cluster
+func getClusterType(r *KokuMetricsConfigReconciler) (kokumetricscfgv1beta1.ClusterType, error) {
+	ctx := context.Background()
+	infra := &unstructured.Unstructured{}
+	infra.SetAPIVersion("config.openshift.io/v1")
+	infra.SetKind("Infrastructure")
+	// unstructured objects are read from the api server, so that fields missing from the vendored api are kept
+	if err := r.Get(ctx, types.NamespacedName{Name: "cluster"}, infra); err != nil {
+		return "", fmt.Errorf("getClusterType: failed to get infrastructure: %v", err)
+	}
+
+	managedNamespaceFound := false
+	if r.Clientset != nil {
+		_, err := r.Clientset.CoreV1().Namespaces().Get(ctx, managedNamespace, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", fmt.Errorf("getClusterType: failed to get namespace %s: %v", managedNamespace, err)
+		}
+		managedNamespaceFound = err == nil
+	}
+	return managedClusterType(infra, managedNamespaceFound), nil
+}
+
+// setClusterType detects the type of the cluster once, and adjusts the defaults for managed service clusters. The
+// defaults only apply to the fields that are not set in the spec.
+func setClusterType(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	log := r.Log.WithValues("KokuMetricsConfig", "setClusterType")
+	if kmCfg.Status.ClusterType == "" {
+		clusterType, err := getClusterType(r)
+		if err != nil {
+			// detection is retried on the next reconcile
+			log.Error(err, "failed to detect the cluster type")
+			return
+		}
+		log.Info("detected cluster type", "type", clusterType)
+		kmCfg.Status.ClusterType = clusterType
+	}
+
+	if kmCfg.Status.ClusterType != kokumetricscfgv1beta1.SelfManagedCluster && kmCfg.Spec.Source.CreateSource == nil {
+		// the managed service registers the cluster, and creating a source would duplicate it
+		log.Info("sources are not created on managed service clusters", "type", kmCfg.Status.ClusterType)
+		kmCfg.Status.Source.CreateSource = &falseDef
+	}
+}
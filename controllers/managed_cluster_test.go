@@ -0,0 +1,107 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func infrastructureWithTags(tags map[string]string) *unstructured.Unstructured {
+	resourceTags := []interface{}{}
+	for k, v := range tags {
+		resourceTags = append(resourceTags, map[string]interface{}{"key": k, "value": v})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"platformStatus": map[string]interface{}{
+				"type": "AWS",
+				"aws":  map[string]interface{}{"region": "us-east-1", "resourceTags": resourceTags},
+			},
+		},
+	}}
+}
+
+func TestManagedClusterType(t *testing.T) {
+	clusterTypeTests := []struct {
+		name            string
+		infra           *unstructured.Unstructured
+		namespaceFound  bool
+		wantClusterType kokumetricscfgv1beta1.ClusterType
+	}{
+		{name: "no tags", infra: infrastructureWithTags(nil), wantClusterType: kokumetricscfgv1beta1.SelfManagedCluster},
+		{name: "no platform status", infra: &unstructured.Unstructured{Object: map[string]interface{}{}}, wantClusterType: kokumetricscfgv1beta1.SelfManagedCluster},
+		{
+			name:            "rosa tags",
+			infra:           infrastructureWithTags(map[string]string{managedTagKey: "true", clusterTypeTagKey: "rosa"}),
+			wantClusterType: kokumetricscfgv1beta1.ROSACluster,
+		},
+		{
+			name:            "osd tags",
+			infra:           infrastructureWithTags(map[string]string{managedTagKey: "True", clusterTypeTagKey: "osd"}),
+			wantClusterType: kokumetricscfgv1beta1.OSDCluster,
+		},
+		{
+			name:            "customer tags",
+			infra:           infrastructureWithTags(map[string]string{"team": "cost"}),
+			wantClusterType: kokumetricscfgv1beta1.SelfManagedCluster,
+		},
+		{name: "managed namespace without tags", infra: infrastructureWithTags(nil), namespaceFound: true, wantClusterType: kokumetricscfgv1beta1.OSDCluster},
+	}
+	for _, tt := range clusterTypeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := managedClusterType(tt.infra, tt.namespaceFound); got != tt.wantClusterType {
+				t.Errorf("%s got %s want %s", tt.name, got, tt.wantClusterType)
+			}
+		})
+	}
+}
+
+func TestSetClusterTypeSkipsSourceCreation(t *testing.T) {
+	trueValue := true
+	setClusterTypeTests := []struct {
+		name             string
+		clusterType      kokumetricscfgv1beta1.ClusterType
+		specCreateSource *bool
+		wantCreateSource bool
+	}{
+		{name: "self-managed clusters create sources", clusterType: kokumetricscfgv1beta1.SelfManagedCluster, specCreateSource: &trueValue, wantCreateSource: true},
+		{name: "rosa clusters do not create sources by default", clusterType: kokumetricscfgv1beta1.ROSACluster, wantCreateSource: false},
+		{name: "osd clusters do not create sources by default", clusterType: kokumetricscfgv1beta1.OSDCluster, wantCreateSource: false},
+		{name: "rosa clusters create sources when requested", clusterType: kokumetricscfgv1beta1.ROSACluster, specCreateSource: &trueValue, wantCreateSource: true},
+	}
+	for _, tt := range setClusterTypeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Status.ClusterType = tt.clusterType
+			kmCfg.Spec.Source.CreateSource = tt.specCreateSource
+			kmCfg.Status.Source.CreateSource = &trueValue
+			setClusterType(r, kmCfg)
+			if *kmCfg.Status.Source.CreateSource != tt.wantCreateSource {
+				t.Errorf("%s got create source %v want %v", tt.name, *kmCfg.Status.Source.CreateSource, tt.wantCreateSource)
+			}
+		})
+	}
+}
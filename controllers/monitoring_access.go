@@ -0,0 +1,134 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+)
+
+// monitoringAccessAttributes are the attributes authorized by the proxy in front of the cluster monitoring
+// stack. They are granted by the cluster-monitoring-view cluster role.
+var monitoringAccessAttributes = authorizationv1.ResourceAttributes{
+	Namespace:   "openshift-monitoring",
+	Verb:        "get",
+	Group:       "monitoring.coreos.com",
+	Resource:    "prometheuses",
+	Subresource: "api",
+}
+
+// reviewMonitoringAccess reviews the token to find the user it belongs to, and checks whether that user is
+// allowed to query the cluster monitoring stack
+func reviewMonitoringAccess(clientset kubernetes.Interface, token string) (string, bool, error) {
+	ctx := context.Background()
+	tokenReview, err := clientset.AuthenticationV1().TokenReviews().Create(ctx,
+		&authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}, metav1.CreateOptions{})
+	if err != nil {
+		return "", false, fmt.Errorf("reviewMonitoringAccess: failed to review token: %v", err)
+	}
+	if !tokenReview.Status.Authenticated {
+		return "", false, fmt.Errorf("reviewMonitoringAccess: token is not authenticated: %s", tokenReview.Status.Error)
+	}
+
+	user := tokenReview.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue)
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	attributes := monitoringAccessAttributes
+	accessReview, err := clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx,
+		&authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &attributes,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		}}, metav1.CreateOptions{})
+	if err != nil {
+		return user.Username, false, fmt.Errorf("reviewMonitoringAccess: failed to review access of %s: %v", user.Username, err)
+	}
+	return user.Username, accessReview.Status.Allowed, nil
+}
+
+// setMonitoringAccessCondition reflects the result of the access review in the MonitoringAccess condition. It
+// returns false when the review found that the operator may not query the cluster monitoring stack.
+func setMonitoringAccessCondition(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, user string, allowed bool, err error) bool {
+	condition := kokumetricscfgv1beta1.Condition{
+		Type:    kokumetricscfgv1beta1.ConditionMonitoringAccess,
+		Status:  corev1.ConditionTrue,
+		Reason:  kokumetricscfgv1beta1.ReasonMonitoringAccessAllowed,
+		Message: fmt.Sprintf("%s may query the cluster monitoring stack", user),
+	}
+	switch {
+	case err != nil:
+		// the review is best effort, collection is still attempted when it cannot be made
+		condition.Status = corev1.ConditionUnknown
+		condition.Reason = kokumetricscfgv1beta1.ReasonAccessReviewFailed
+		condition.Message = err.Error()
+	case !allowed:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = kokumetricscfgv1beta1.ReasonMissingMonitoringBinding
+		condition.Message = fmt.Sprintf("missing cluster-monitoring-view binding: %s cannot %s %s/%s in %s",
+			user, monitoringAccessAttributes.Verb, monitoringAccessAttributes.Resource,
+			monitoringAccessAttributes.Subresource, monitoringAccessAttributes.Namespace)
+	}
+	kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, condition)
+	return err != nil || allowed
+}
+
+// queriesClusterMonitoring returns true if the reports are collected from the default thanos-querier of the cluster
+// monitoring stack, which authorizes queries with the cluster-monitoring-view binding
+func queriesClusterMonitoring(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	return r.Fixtures == nil && r.PromConn == nil &&
+		kmCfg.Status.Prometheus.SvcAddress == kokumetricscfgv1beta1.DefaultPrometheusSvcAddress &&
+		len(kmCfg.Spec.PrometheusConfig.ShardAddresses) == 0
+}
+
+// checkMonitoringAccess verifies that the operator may still query the cluster monitoring stack before collecting.
+// Other Prometheus addresses do not authorize with the cluster-monitoring-view binding, so they are not checked.
+func checkMonitoringAccess(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	if !queriesClusterMonitoring(r, kmCfg) {
+		kokumetricscfgv1beta1.RemoveCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionMonitoringAccess)
+		return true
+	}
+	if r.Clientset == nil {
+		return true
+	}
+	token, err := collector.ServiceAccountToken(r.InCluster)
+	if err != nil {
+		return setMonitoringAccessCondition(kmCfg, "", false, fmt.Errorf("checkMonitoringAccess: %v", err))
+	}
+	user, allowed, err := reviewMonitoringAccess(r.Clientset, token)
+	if err == nil && kmCfg.Status.Prometheus.MonitoringBinding.Managed {
+		if repaired := manageMonitoringBinding(r, kmCfg, user); repaired && !allowed {
+			user, allowed, err = reviewMonitoringAccess(r.Clientset, token)
+		}
+	}
+	return setMonitoringAccessCondition(kmCfg, user, allowed, err)
+}
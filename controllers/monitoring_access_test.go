@@ -0,0 +1,153 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const testServiceAccount = "system:serviceaccount:koku-metrics-operator:koku-metrics-manager-role"
+
+func TestReviewMonitoringAccess(t *testing.T) {
+	reviewTests := []struct {
+		name          string
+		authenticated bool
+		allowed       bool
+		sarErr        error
+		wantAllowed   bool
+		wantErr       bool
+	}{
+		{name: "service account is bound", authenticated: true, allowed: true, wantAllowed: true},
+		{name: "service account is not bound", authenticated: true, allowed: false, wantAllowed: false},
+		{name: "token is not authenticated", authenticated: false, wantErr: true},
+		{name: "access review fails", authenticated: true, sarErr: errors.New("forbidden"), wantErr: true},
+	}
+	for _, tt := range reviewTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSAR *authorizationv1.SubjectAccessReview
+			clientset := fake.NewSimpleClientset()
+			clientset.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				review := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				if review.Spec.Token != "token" {
+					t.Errorf("%s got token %q", tt.name, review.Spec.Token)
+				}
+				review.Status = authenticationv1.TokenReviewStatus{
+					Authenticated: tt.authenticated,
+					User:          authenticationv1.UserInfo{Username: testServiceAccount, Groups: []string{"system:serviceaccounts"}},
+				}
+				return true, review, nil
+			})
+			clientset.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				gotSAR = action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				if tt.sarErr != nil {
+					return true, nil, tt.sarErr
+				}
+				review := gotSAR.DeepCopy()
+				review.Status.Allowed = tt.allowed
+				return true, review, nil
+			})
+
+			user, allowed, err := reviewMonitoringAccess(clientset, "token")
+			if err != nil != tt.wantErr {
+				t.Fatalf("%s got error %v want error %v", tt.name, err, tt.wantErr)
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("%s got allowed %v want %v", tt.name, allowed, tt.wantAllowed)
+			}
+			if !tt.authenticated {
+				return
+			}
+			if user != testServiceAccount {
+				t.Errorf("%s got user %q", tt.name, user)
+			}
+			if gotSAR == nil || gotSAR.Spec.User != testServiceAccount || len(gotSAR.Spec.Groups) != 1 ||
+				*gotSAR.Spec.ResourceAttributes != monitoringAccessAttributes {
+				t.Errorf("%s got subject access review %+v", tt.name, gotSAR)
+			}
+		})
+	}
+}
+
+func TestSetMonitoringAccessCondition(t *testing.T) {
+	conditionTests := []struct {
+		name        string
+		allowed     bool
+		err         error
+		wantCollect bool
+		wantStatus  corev1.ConditionStatus
+		wantReason  string
+	}{
+		{name: "allowed", allowed: true, wantCollect: true, wantStatus: corev1.ConditionTrue, wantReason: kokumetricscfgv1beta1.ReasonMonitoringAccessAllowed},
+		{name: "missing binding", allowed: false, wantCollect: false, wantStatus: corev1.ConditionFalse, wantReason: kokumetricscfgv1beta1.ReasonMissingMonitoringBinding},
+		{name: "review failed", err: errors.New("forbidden"), wantCollect: true, wantStatus: corev1.ConditionUnknown, wantReason: kokumetricscfgv1beta1.ReasonAccessReviewFailed},
+	}
+	for _, tt := range conditionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			if got := setMonitoringAccessCondition(kmCfg, testServiceAccount, tt.allowed, tt.err); got != tt.wantCollect {
+				t.Errorf("%s got collect %v want %v", tt.name, got, tt.wantCollect)
+			}
+			condition := kokumetricscfgv1beta1.FindCondition(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionMonitoringAccess)
+			if condition == nil {
+				t.Fatalf("%s condition was not set", tt.name)
+			}
+			if condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("%s got condition %+v", tt.name, condition)
+			}
+		})
+	}
+}
+
+func TestCheckMonitoringAccessOtherPrometheus(t *testing.T) {
+	addressTests := []struct {
+		name    string
+		address string
+		shards  []string
+	}{
+		{name: "custom address", address: "https://prometheus.example.com:9091"},
+		{name: "sharded prometheus", address: kokumetricscfgv1beta1.DefaultPrometheusSvcAddress, shards: []string{"https://shard-0:9091"}},
+	}
+	for _, tt := range addressTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &KokuMetricsConfigReconciler{}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Status.Prometheus.SvcAddress = tt.address
+			kmCfg.Spec.PrometheusConfig.ShardAddresses = tt.shards
+			setMonitoringAccessCondition(kmCfg, testServiceAccount, false, nil)
+
+			if !checkMonitoringAccess(r, kmCfg) {
+				t.Errorf("%s got collection skipped want the access check skipped", tt.name)
+			}
+			if kokumetricscfgv1beta1.FindCondition(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionMonitoringAccess) != nil {
+				t.Errorf("%s got the MonitoringAccess condition kept", tt.name)
+			}
+		})
+	}
+}
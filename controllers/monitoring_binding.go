@@ -0,0 +1,135 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const (
+	monitoringBindingName = "koku-metrics-operator-cluster-monitoring-view"
+	monitoringViewRole    = "cluster-monitoring-view"
+	serviceAccountPrefix  = "system:serviceaccount:"
+)
+
+// serviceAccountFromUser returns the namespace and name of the service account a user name belongs to
+func serviceAccountFromUser(user string) (string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(user, serviceAccountPrefix), ":")
+	if !strings.HasPrefix(user, serviceAccountPrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("serviceAccountFromUser: %s is not a service account", user)
+	}
+	return parts[0], parts[1], nil
+}
+
+// monitoringBinding returns the ClusterRoleBinding that grants the service account the cluster-monitoring-view role
+func monitoringBinding(namespace, name string) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: monitoringBindingName},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: monitoringViewRole},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: namespace, Name: name}},
+	}
+}
+
+// hasSubject returns true if the binding includes the subject
+func hasSubject(binding *rbacv1.ClusterRoleBinding, subject rbacv1.Subject) bool {
+	for _, s := range binding.Subjects {
+		if s.Kind == subject.Kind && s.Namespace == subject.Namespace && s.Name == subject.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureMonitoringBinding creates or repairs the cluster-monitoring-view binding of the service account. It returns
+// a description of how the existing binding differed from the expected binding, or an empty string if it did not.
+func ensureMonitoringBinding(clientset kubernetes.Interface, namespace, name string) (string, error) {
+	ctx := context.Background()
+	bindings := clientset.RbacV1().ClusterRoleBindings()
+	desired := monitoringBinding(namespace, name)
+
+	existing, err := bindings.Get(ctx, monitoringBindingName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if _, err := bindings.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return "", fmt.Errorf("ensureMonitoringBinding: failed to create binding %s: %v", monitoringBindingName, err)
+		}
+		return fmt.Sprintf("binding %s did not exist", monitoringBindingName), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("ensureMonitoringBinding: failed to get binding %s: %v", monitoringBindingName, err)
+	}
+
+	if existing.RoleRef != desired.RoleRef {
+		// the role of a binding cannot be changed, so the binding is recreated
+		drift := fmt.Sprintf("binding %s referenced %s %s", monitoringBindingName, existing.RoleRef.Kind, existing.RoleRef.Name)
+		if err := bindings.Delete(ctx, monitoringBindingName, metav1.DeleteOptions{}); err != nil {
+			return "", fmt.Errorf("ensureMonitoringBinding: failed to delete binding %s: %v", monitoringBindingName, err)
+		}
+		if _, err := bindings.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return "", fmt.Errorf("ensureMonitoringBinding: failed to recreate binding %s: %v", monitoringBindingName, err)
+		}
+		return drift, nil
+	}
+
+	if !hasSubject(existing, desired.Subjects[0]) {
+		existing.Subjects = append(existing.Subjects, desired.Subjects[0])
+		if _, err := bindings.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return "", fmt.Errorf("ensureMonitoringBinding: failed to update binding %s: %v", monitoringBindingName, err)
+		}
+		return fmt.Sprintf("binding %s did not include service account %s/%s", monitoringBindingName, namespace, name), nil
+	}
+	return "", nil
+}
+
+// manageMonitoringBinding repairs the cluster-monitoring-view binding of the user and reflects the result in the
+// status. It returns true if the binding was created or repaired.
+func manageMonitoringBinding(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, user string) bool {
+	log := r.Log.WithValues("KokuMetricsConfig", "manageMonitoringBinding")
+	status := &kmCfg.Status.Prometheus.MonitoringBinding
+	status.Name = monitoringBindingName
+	status.Error = ""
+
+	namespace, name, err := serviceAccountFromUser(user)
+	if err == nil {
+		var drift string
+		drift, err = ensureMonitoringBinding(r.Clientset, namespace, name)
+		if err == nil && drift != "" {
+			log.Info("repaired cluster-monitoring-view binding", "drift", drift)
+			status.Drift = drift
+			status.LastRepairTime = metav1.Now()
+			r.Recorder.Event(kmCfg, corev1.EventTypeNormal, "MonitoringBindingRepaired", drift)
+			return true
+		}
+	}
+	if err != nil {
+		log.Error(err, "failed to manage cluster-monitoring-view binding")
+		status.Error = err.Error()
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServiceAccountFromUser(t *testing.T) {
+	userTests := []struct {
+		name          string
+		user          string
+		wantNamespace string
+		wantName      string
+		wantErr       bool
+	}{
+		{name: "service account", user: testServiceAccount, wantNamespace: "koku-metrics-operator", wantName: "koku-metrics-manager-role"},
+		{name: "user", user: "kube:admin", wantErr: true},
+		{name: "incomplete service account", user: "system:serviceaccount:koku-metrics-operator", wantErr: true},
+	}
+	for _, tt := range userTests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, name, err := serviceAccountFromUser(tt.user)
+			if err != nil != tt.wantErr {
+				t.Fatalf("%s got error %v want error %v", tt.name, err, tt.wantErr)
+			}
+			if namespace != tt.wantNamespace || name != tt.wantName {
+				t.Errorf("%s got %s/%s want %s/%s", tt.name, namespace, name, tt.wantNamespace, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestEnsureMonitoringBinding(t *testing.T) {
+	namespace, name := "koku-metrics-operator", "koku-metrics-manager-role"
+	otherSubject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "other", Name: "other"}
+	wrongRole := monitoringBinding(namespace, name)
+	wrongRole.RoleRef.Name = "view"
+	missingSubject := monitoringBinding(namespace, name)
+	missingSubject.Subjects = []rbacv1.Subject{otherSubject}
+
+	bindingTests := []struct {
+		name         string
+		existing     []runtime.Object
+		wantDrift    bool
+		wantSubjects int
+	}{
+		{name: "binding is created", wantDrift: true, wantSubjects: 1},
+		{name: "binding is unchanged", existing: []runtime.Object{monitoringBinding(namespace, name)}, wantSubjects: 1},
+		{name: "binding with the wrong role is recreated", existing: []runtime.Object{wrongRole}, wantDrift: true, wantSubjects: 1},
+		{name: "missing subject is added", existing: []runtime.Object{missingSubject}, wantDrift: true, wantSubjects: 2},
+	}
+	for _, tt := range bindingTests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tt.existing...)
+			drift, err := ensureMonitoringBinding(clientset, namespace, name)
+			if err != nil {
+				t.Fatalf("%s got unexpected error: %v", tt.name, err)
+			}
+			if (drift != "") != tt.wantDrift {
+				t.Errorf("%s got drift %q want drift %v", tt.name, drift, tt.wantDrift)
+			}
+			got, err := clientset.RbacV1().ClusterRoleBindings().Get(context.Background(), monitoringBindingName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("%s failed to get binding: %v", tt.name, err)
+			}
+			if got.RoleRef.Name != monitoringViewRole || len(got.Subjects) != tt.wantSubjects {
+				t.Errorf("%s got binding %+v", tt.name, got)
+			}
+			if !hasSubject(got, rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: namespace, Name: name}) {
+				t.Errorf("%s binding does not include the service account: %+v", tt.name, got.Subjects)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// reflectMaxSize sets the packaging max size in the status. The size in the spec is clamped to the largest report size
+// accepted by the upload endpoint, because larger payloads are rejected by ingress. An event is recorded when the spec
+// value starts being clamped.
+func reflectMaxSize(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	limit := kokumetricscfgv1beta1.DefaultUploadMaxSize
+	if kmCfg.Spec.Upload.MaxSize != nil && *kmCfg.Spec.Upload.MaxSize > 0 {
+		limit = *kmCfg.Spec.Upload.MaxSize
+	}
+
+	maxSize := kmCfg.Spec.Packaging.MaxSize
+	warning := ""
+	switch {
+	case maxSize <= 0:
+		maxSize = kokumetricscfgv1beta1.DefaultMaxSize
+		if maxSize > limit {
+			maxSize = limit
+		}
+	case maxSize > limit:
+		warning = fmt.Sprintf("packaging max_size_MB %d is larger than the upload limit of %d MB, %d MB is used", maxSize, limit, limit)
+		maxSize = limit
+	}
+
+	if warning != "" && warning != kmCfg.Status.Packaging.MaxSizeWarning && r.Recorder != nil {
+		r.Recorder.Event(kmCfg, corev1.EventTypeWarning, kokumetricscfgv1beta1.ReasonMaxSizeClamped, warning)
+	}
+	kmCfg.Status.Packaging.MaxSize = &maxSize
+	kmCfg.Status.Packaging.MaxSizeLimit = &limit
+	kmCfg.Status.Packaging.MaxSizeWarning = warning
+}
+
+// reflectPackagingCycle sets the packaging cycle in the status. Reports are packaged on the upload cycle unless
+// the spec sets a packaging cycle, so that hourly packaging does not have to wait for the next upload.
+func reflectPackagingCycle(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	cycle := kokumetricscfgv1beta1.DefaultUploadCycle
+	if kmCfg.Status.Upload.UploadCycle != nil {
+		cycle = *kmCfg.Status.Upload.UploadCycle
+	}
+	if kmCfg.Spec.Packaging.PackagingCycle != nil {
+		cycle = *kmCfg.Spec.Packaging.PackagingCycle
+	}
+	kmCfg.Status.Packaging.PackagingCycle = &cycle
+}
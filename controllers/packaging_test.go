@@ -0,0 +1,105 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestReflectMaxSize(t *testing.T) {
+	fifty := int64(50)
+	twoHundred := int64(200)
+	maxSizeTests := []struct {
+		name          string
+		maxSize       int64
+		uploadMaxSize *int64
+		prevWarning   bool
+		want          int64
+		wantLimit     int64
+		wantWarning   bool
+		wantEvent     bool
+	}{
+		{name: "max size within the default limit", maxSize: 20, want: 20, wantLimit: 100},
+		{name: "unset max size uses the default", maxSize: 0, want: 100, wantLimit: 100},
+		{name: "unset max size with a lower limit", maxSize: 0, uploadMaxSize: &fifty, want: 50, wantLimit: 50},
+		{name: "max size above the default limit is clamped", maxSize: 150, want: 100, wantLimit: 100, wantWarning: true, wantEvent: true},
+		{name: "max size above a configured limit is clamped", maxSize: 80, uploadMaxSize: &fifty, want: 50, wantLimit: 50, wantWarning: true, wantEvent: true},
+		{name: "max size within a raised limit", maxSize: 150, uploadMaxSize: &twoHundred, want: 150, wantLimit: 200},
+		{name: "event is not repeated", maxSize: 150, prevWarning: true, want: 100, wantLimit: 100, wantWarning: true},
+	}
+	for _, tt := range maxSizeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &KokuMetricsConfigReconciler{Recorder: recorder}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Packaging.MaxSize = tt.maxSize
+			kmCfg.Spec.Upload.MaxSize = tt.uploadMaxSize
+			if tt.prevWarning {
+				reflectMaxSize(r, kmCfg)
+				<-recorder.Events
+			}
+			reflectMaxSize(r, kmCfg)
+			if got := *kmCfg.Status.Packaging.MaxSize; got != tt.want {
+				t.Errorf("%s got max size %d want %d", tt.name, got, tt.want)
+			}
+			if got := *kmCfg.Status.Packaging.MaxSizeLimit; got != tt.wantLimit {
+				t.Errorf("%s got limit %d want %d", tt.name, got, tt.wantLimit)
+			}
+			if got := kmCfg.Status.Packaging.MaxSizeWarning != ""; got != tt.wantWarning {
+				t.Errorf("%s got warning %q, want warning %t", tt.name, kmCfg.Status.Packaging.MaxSizeWarning, tt.wantWarning)
+			}
+			if got := len(recorder.Events) > 0; got != tt.wantEvent {
+				t.Errorf("%s got event %t want %t", tt.name, got, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestReflectPackagingCycle(t *testing.T) {
+	sixty := int64(60)
+	zero := int64(0)
+	uploadCycle := int64(360)
+	packagingCycleTests := []struct {
+		name           string
+		packagingCycle *int64
+		uploadCycle    *int64
+		want           int64
+	}{
+		{name: "unset packaging cycle follows the upload cycle", uploadCycle: &uploadCycle, want: 360},
+		{name: "unset cycles use the default", want: kokumetricscfgv1beta1.DefaultUploadCycle},
+		{name: "packaging cycle is independent of the upload cycle", packagingCycle: &sixty, uploadCycle: &uploadCycle, want: 60},
+		{name: "packaging cycle of 0 packages every reconcile", packagingCycle: &zero, uploadCycle: &uploadCycle, want: 0},
+	}
+	for _, tt := range packagingCycleTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Packaging.PackagingCycle = tt.packagingCycle
+			kmCfg.Status.Upload.UploadCycle = tt.uploadCycle
+			reflectPackagingCycle(kmCfg)
+			if got := *kmCfg.Status.Packaging.PackagingCycle; got != tt.want {
+				t.Errorf("%s got %d want %d", tt.name, got, tt.want)
+			}
+		})
+	}
+}
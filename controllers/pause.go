@@ -0,0 +1,95 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// reasonCollectionResumed is the reason of the event emitted when the operator resumes after a pause
+const reasonCollectionResumed = "CollectionResumed"
+
+// missedHours returns the range of the UTC hours that were not collected between the last collection and now, in the
+// billing time zone. The collection in a reconcile collects the previous hour, so the hour before the current hour is
+// not missed.
+func missedHours(lastQuerySuccess, now time.Time, loc *time.Location) (time.Time, time.Time, int64) {
+	if lastQuerySuccess.IsZero() {
+		return time.Time{}, time.Time{}, 0
+	}
+	start := lastQuerySuccess.UTC().Truncate(time.Hour).In(loc)
+	end := previousHour(now, loc)
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, 0
+	}
+	if end.Sub(start) > maxRecollectDays*24*time.Hour {
+		start = end.Add(-maxRecollectDays * 24 * time.Hour)
+	}
+	return start, end, int64(end.Sub(start) / time.Hour)
+}
+
+// detectPause checks, in the first reconcile of the process, for the hours that were not collected while the operator
+// was not running, for example because its deployment was scaled to zero. A pause is expected, so it is recorded in
+// the status and an event instead of as an error, and the missed hours are back-filled by backfillPause.
+func detectPause(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, now time.Time, logger logr.Logger) {
+	if r.pauseChecked {
+		return
+	}
+	r.pauseChecked = true
+
+	start, end, hours := missedHours(kmCfg.Status.Prometheus.LastQuerySuccessTime.Time, now, BillingLocation(kmCfg))
+	if hours == 0 {
+		return
+	}
+	status := &kmCfg.Status.Pause
+	status.Start = metav1.NewTime(start)
+	status.End = metav1.NewTime(end)
+	status.MissedHours = hours
+	status.Pauses++
+	status.Backfill = kokumetricscfgv1beta1.RecollectionStatus{
+		Start:    metav1.NewTime(start),
+		End:      metav1.NewTime(end),
+		NextHour: metav1.NewTime(start),
+	}
+
+	msg := fmt.Sprintf("the operator was not running from %s to %s, the %d missed hours are back-filled",
+		start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339), hours)
+	logger.Info(msg)
+	if r.Recorder != nil {
+		r.Recorder.Event(kmCfg, corev1.EventTypeNormal, reasonCollectionResumed, msg)
+	}
+}
+
+// backfillPause collects the hours missed during the last pause. The hours that were collected in the meantime, for
+// example by a re-collection, are skipped.
+func backfillPause(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, logger logr.Logger) {
+	status := &kmCfg.Status.Pause.Backfill
+	if status.Start.IsZero() || status.Complete {
+		return
+	}
+	recollectHours(r, kmCfg, dirCfg, status, false, logger.WithValues("KokuMetricsConfig", "backfillPause"))
+}
@@ -0,0 +1,99 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestMissedHours(t *testing.T) {
+	now := time.Date(2021, 1, 2, 10, 5, 0, 0, time.UTC)
+	missedHoursTests := []struct {
+		name      string
+		last      time.Time
+		wantStart time.Time
+		wantHours int64
+	}{
+		{name: "never collected", last: time.Time{}},
+		{name: "collected this hour", last: time.Date(2021, 1, 2, 10, 1, 0, 0, time.UTC)},
+		{name: "collected last hour", last: time.Date(2021, 1, 2, 9, 1, 0, 0, time.UTC)},
+		{name: "scaled down for 5 hours", last: time.Date(2021, 1, 2, 4, 1, 0, 0, time.UTC), wantStart: time.Date(2021, 1, 2, 4, 0, 0, 0, time.UTC), wantHours: 5},
+		{name: "long pause is limited", last: time.Date(2020, 1, 2, 4, 1, 0, 0, time.UTC), wantStart: time.Date(2021, 1, 2, 9, 0, 0, 0, time.UTC).Add(-maxRecollectDays * 24 * time.Hour), wantHours: maxRecollectDays * 24},
+	}
+	for _, tt := range missedHoursTests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, hours := missedHours(tt.last, now, time.UTC)
+			if hours != tt.wantHours {
+				t.Errorf("%s got %d hours want %d", tt.name, hours, tt.wantHours)
+			}
+			if hours > 0 && (!start.Equal(tt.wantStart) || !end.Equal(time.Date(2021, 1, 2, 9, 0, 0, 0, time.UTC))) {
+				t.Errorf("%s got range %v - %v want start %v", tt.name, start, end, tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestMissedHoursDaylightSavingTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	// daylight saving time ends in New York at 2026-11-01 06:00 UTC, when 01:00 EDT is followed by 01:00 EST. At
+	// 01:05 EST, the hour from 04:00 UTC is missed and the hour from 05:00 UTC is collected by the reconcile.
+	last := time.Date(2026, 11, 1, 4, 10, 0, 0, time.UTC)
+	now := time.Date(2026, 11, 1, 6, 5, 0, 0, time.UTC)
+	start, end, hours := missedHours(last, now, newYork)
+	if hours != 1 || !start.Equal(time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2026, 11, 1, 5, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %d hours from %v to %v want 1 hour from 04:00 to 05:00 UTC", hours, start.UTC(), end.UTC())
+	}
+}
+
+func TestDetectPause(t *testing.T) {
+	now := time.Now()
+	recorder := record.NewFakeRecorder(10)
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: recorder}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.Prometheus.LastQuerySuccessTime = metav1.NewTime(now.Add(-6 * time.Hour))
+
+	detectPause(r, kmCfg, now, testutils.TestLogger{})
+	pause := kmCfg.Status.Pause
+	if pause.MissedHours != 5 || pause.Pauses != 1 {
+		t.Errorf("got pause %+v want 5 missed hours", pause)
+	}
+	if !pause.Backfill.NextHour.Equal(&pause.Start) || !pause.Backfill.End.Equal(&pause.End) || pause.Backfill.Complete {
+		t.Errorf("got backfill %+v", pause.Backfill)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("got %d events want 1", len(recorder.Events))
+	}
+
+	// the pause is only detected in the first reconcile
+	detectPause(r, kmCfg, now.Add(3*time.Hour), testutils.TestLogger{})
+	if kmCfg.Status.Pause.Pauses != 1 || len(recorder.Events) != 1 {
+		t.Errorf("expected the pause to be detected once, got %+v", kmCfg.Status.Pause)
+	}
+}
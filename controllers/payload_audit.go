@@ -0,0 +1,170 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+// maxPayloadRequestIDs is the number of upload attempts whose request IDs are kept in a KokuMetricsPayload
+const maxPayloadRequestIDs = 10
+
+// payloadAuditName is the name of the KokuMetricsPayload of a payload
+func payloadAuditName(payloadID string) string {
+	return "payload-" + strings.ToLower(payloadID)
+}
+
+// isPayloadAuditEnabled returns true unless the payload audit is disabled in the spec
+func isPayloadAuditEnabled(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	return kmCfg.Spec.PayloadAudit.Enabled == nil || *kmCfg.Spec.PayloadAudit.Enabled
+}
+
+// auditPayloads records the ingress upload results in KokuMetricsPayloads, and deletes the records older than the TTL
+func auditPayloads(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, results []uploader.Result) {
+	if !kmCfg.Status.PayloadAudit.Enabled || r.Client == nil {
+		return
+	}
+	log := r.Log.WithValues("kokumetricsconfig", "auditPayloads")
+	kmCfg.Status.PayloadAudit.Error = ""
+	for _, result := range results {
+		ingress, ok := result.Exporter.(*exporter.Ingress)
+		if !ok || result.Payload.PayloadID == "" {
+			continue
+		}
+		if err := recordPayload(r, kmCfg, ingress, result, time.Now()); err != nil {
+			log.Error(err, "failed to record payload", "payload", result.File)
+			kmCfg.Status.PayloadAudit.Error = err.Error()
+		}
+	}
+	records, err := prunePayloads(r, kmCfg, time.Now())
+	if err != nil {
+		log.Error(err, "failed to prune payload records")
+		kmCfg.Status.PayloadAudit.Error = err.Error()
+		return
+	}
+	kmCfg.Status.PayloadAudit.Records = int64(records)
+}
+
+// recordPayload creates or updates the KokuMetricsPayload of an upload result
+func recordPayload(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, ingress *exporter.Ingress, result uploader.Result, now time.Time) error {
+	ctx := context.Background()
+
+	record := &kokumetricscfgv1beta1.KokuMetricsPayload{}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: payloadAuditName(result.Payload.PayloadID)}
+	exists := true
+	if err := r.Get(ctx, key, record); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("recordPayload: failed to get KokuMetricsPayload: %v", err)
+		}
+		exists = false
+		labels := map[string]string{kokumetricscfgv1beta1.PayloadConfigLabel: kmCfg.Name}
+		if result.IdempotencyKey != "" {
+			labels[kokumetricscfgv1beta1.PayloadIdempotencyKeyLabel] = result.IdempotencyKey
+		}
+		record = &kokumetricscfgv1beta1.KokuMetricsPayload{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: key.Namespace,
+				Name:      key.Name,
+				Labels:    labels,
+			},
+			Spec: kokumetricscfgv1beta1.KokuMetricsPayloadSpec{
+				PayloadID: result.Payload.PayloadID,
+				ClusterID: kmCfg.Status.ClusterID,
+				File:      result.File,
+				Start:     metav1.Time{Time: result.Payload.Start},
+				End:       metav1.Time{Time: result.Payload.End},
+				Files:     result.Payload.Files,
+				SizeBytes: result.Payload.SizeBytes,
+
+				IdempotencyKey: result.IdempotencyKey,
+			},
+		}
+		if err := ctrl.SetControllerReference(kmCfg, record, r.Scheme); err != nil {
+			return fmt.Errorf("recordPayload: failed to set owner reference: %v", err)
+		}
+		if err := r.Create(ctx, record); err != nil {
+			return fmt.Errorf("recordPayload: failed to create KokuMetricsPayload: %v", err)
+		}
+	}
+
+	status := &record.Status
+	status.Attempts++
+	status.UploadStatus = result.Status
+	status.RequestID = result.RequestID
+	if result.RequestID != "" {
+		status.RequestIDs = append(status.RequestIDs, result.RequestID)
+		if len(status.RequestIDs) > maxPayloadRequestIDs {
+			status.RequestIDs = status.RequestIDs[len(status.RequestIDs)-maxPayloadRequestIDs:]
+		}
+	}
+	status.Endpoint = ingress.URL
+	status.LastUploadTime = metav1.Time{Time: now}
+	status.UploadError = ""
+	if result.Err != nil {
+		status.UploadError = result.Err.Error()
+	} else {
+		status.Uploaded = true
+	}
+	if err := r.Status().Update(ctx, record); err != nil {
+		if exists {
+			return fmt.Errorf("recordPayload: failed to update KokuMetricsPayload status: %v", err)
+		}
+		return fmt.Errorf("recordPayload: failed to set KokuMetricsPayload status: %v", err)
+	}
+	return nil
+}
+
+// prunePayloads deletes the KokuMetricsPayloads of the config that are older than the TTL. The number of records
+// that are kept is returned.
+func prunePayloads(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, now time.Time) (int, error) {
+	ctx := context.Background()
+
+	records := &kokumetricscfgv1beta1.KokuMetricsPayloadList{}
+	if err := r.List(ctx, records, client.InNamespace(kmCfg.Namespace),
+		client.MatchingLabels{kokumetricscfgv1beta1.PayloadConfigLabel: kmCfg.Name}); err != nil {
+		return 0, fmt.Errorf("prunePayloads: failed to list KokuMetricsPayloads: %v", err)
+	}
+	ttl := time.Duration(*kmCfg.Status.PayloadAudit.TTLHours) * time.Hour
+	kept := 0
+	for i := range records.Items {
+		record := &records.Items[i]
+		if now.Sub(record.CreationTimestamp.Time) < ttl {
+			kept++
+			continue
+		}
+		if err := r.Delete(ctx, record); err != nil && !errors.IsNotFound(err) {
+			return kept, fmt.Errorf("prunePayloads: failed to delete KokuMetricsPayload %s: %v", record.Name, err)
+		}
+	}
+	return kept, nil
+}
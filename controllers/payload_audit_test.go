@@ -0,0 +1,200 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+func payloadAuditReconciler(t *testing.T, objs ...runtime.Object) *KokuMetricsConfigReconciler {
+	s := runtime.NewScheme()
+	if err := kokumetricscfgv1beta1.AddToScheme(s); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	return &KokuMetricsConfigReconciler{Client: fake.NewFakeClientWithScheme(s, objs...), Scheme: s, Log: testutils.TestLogger{}}
+}
+
+func payloadAuditConfig() *kokumetricscfgv1beta1.KokuMetricsConfig {
+	ttl := int64(24)
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "kokumetricscfg-sample", UID: "uid"},
+	}
+	kmCfg.Status.ClusterID = "cluster-id"
+	kmCfg.Status.PayloadAudit = kokumetricscfgv1beta1.PayloadAuditStatus{Enabled: true, TTLHours: &ttl}
+	return kmCfg
+}
+
+func TestRecordPayload(t *testing.T) {
+	start := time.Date(2021, 1, 2, 9, 0, 0, 0, time.UTC)
+	result := uploader.Result{
+		File: "20210102T100000-cost-mgmt.tar.gz",
+		Payload: packaging.PayloadSummary{
+			PayloadID: "ABCD-1234",
+			Start:     start,
+			End:       start.Add(time.Hour),
+			Files:     []string{"a.csv", "b.csv"},
+			SizeBytes: 512,
+		},
+		IdempotencyKey: "0123456789abcdef0123456789abcdef",
+	}
+	r := payloadAuditReconciler(t)
+	kmCfg := payloadAuditConfig()
+	now := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	// a rejected upload creates the record
+	ingress := &exporter.Ingress{URL: "https://ingress"}
+	result.Exporter = ingress
+	result.Status, result.RequestID = "400 Bad Request", "req-1"
+	result.Err = errors.New("rejected")
+	if err := recordPayload(r, kmCfg, ingress, result, now); err != nil {
+		t.Fatalf("failed to record payload: %v", err)
+	}
+	record := &kokumetricscfgv1beta1.KokuMetricsPayload{}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: "payload-abcd-1234"}
+	if err := r.Get(context.Background(), key, record); err != nil {
+		t.Fatalf("failed to get payload record: %v", err)
+	}
+	if record.Spec.PayloadID != "ABCD-1234" || record.Spec.ClusterID != "cluster-id" || record.Spec.SizeBytes != 512 ||
+		len(record.Spec.Files) != 2 || !record.Spec.Start.Equal(&metav1.Time{Time: start}) {
+		t.Errorf("got spec %+v", record.Spec)
+	}
+	if record.Labels[kokumetricscfgv1beta1.PayloadConfigLabel] != kmCfg.Name || len(record.OwnerReferences) != 1 ||
+		record.Labels[kokumetricscfgv1beta1.PayloadIdempotencyKeyLabel] != result.IdempotencyKey {
+		t.Errorf("got labels %v and owners %v", record.Labels, record.OwnerReferences)
+	}
+	if record.Status.Uploaded || record.Status.UploadError != "rejected" || record.Status.RequestID != "req-1" || record.Status.Attempts != 1 {
+		t.Errorf("got status %+v", record.Status)
+	}
+
+	// an accepted retry updates the status
+	ingress = &exporter.Ingress{URL: "https://ingress"}
+	result.Exporter = ingress
+	result.Status, result.RequestID = "202 Accepted", "req-2"
+	result.Err = nil
+	if err := recordPayload(r, kmCfg, ingress, result, now.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to record payload: %v", err)
+	}
+	record = &kokumetricscfgv1beta1.KokuMetricsPayload{}
+	if err := r.Get(context.Background(), key, record); err != nil {
+		t.Fatalf("failed to get payload record: %v", err)
+	}
+	if !record.Status.Uploaded || record.Status.UploadError != "" || record.Status.RequestID != "req-2" ||
+		record.Status.Attempts != 2 || record.Status.UploadStatus != "202 Accepted" {
+		t.Errorf("got status %+v", record.Status)
+	}
+	if !reflect.DeepEqual(record.Status.RequestIDs, []string{"req-1", "req-2"}) {
+		t.Errorf("got request ids %v want [req-1 req-2]", record.Status.RequestIDs)
+	}
+}
+
+func TestRecordPayloadRequestIDsLimit(t *testing.T) {
+	result := uploader.Result{File: "payload.tar.gz", Payload: packaging.PayloadSummary{PayloadID: "ABCD-1234"}}
+	r := payloadAuditReconciler(t)
+	kmCfg := payloadAuditConfig()
+	now := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < maxPayloadRequestIDs+2; i++ {
+		ingress := &exporter.Ingress{URL: "https://ingress"}
+		result.Exporter = ingress
+		result.Status, result.RequestID = "500 Internal Server Error", fmt.Sprintf("req-%d", i)
+		result.Err = errors.New("failed")
+		if err := recordPayload(r, kmCfg, ingress, result, now); err != nil {
+			t.Fatalf("failed to record payload: %v", err)
+		}
+	}
+	record := &kokumetricscfgv1beta1.KokuMetricsPayload{}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: "payload-abcd-1234"}
+	if err := r.Get(context.Background(), key, record); err != nil {
+		t.Fatalf("failed to get payload record: %v", err)
+	}
+	ids := record.Status.RequestIDs
+	if len(ids) != maxPayloadRequestIDs || ids[0] != "req-2" || ids[len(ids)-1] != fmt.Sprintf("req-%d", maxPayloadRequestIDs+1) {
+		t.Errorf("got request ids %v", ids)
+	}
+	if _, ok := record.Labels[kokumetricscfgv1beta1.PayloadIdempotencyKeyLabel]; ok {
+		t.Errorf("got idempotency key label without a key")
+	}
+}
+
+func TestPrunePayloads(t *testing.T) {
+	now := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+	record := func(name, config string, age time.Duration) *kokumetricscfgv1beta1.KokuMetricsPayload {
+		return &kokumetricscfgv1beta1.KokuMetricsPayload{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "koku-metrics-operator",
+			Name:              name,
+			Labels:            map[string]string{kokumetricscfgv1beta1.PayloadConfigLabel: config},
+			CreationTimestamp: metav1.NewTime(now.Add(-age)),
+		}}
+	}
+	r := payloadAuditReconciler(t,
+		record("payload-new", "kokumetricscfg-sample", time.Hour),
+		record("payload-old", "kokumetricscfg-sample", 25*time.Hour),
+		record("payload-other", "other", 25*time.Hour),
+	)
+	kmCfg := payloadAuditConfig()
+	kept, err := prunePayloads(r, kmCfg, now)
+	if err != nil {
+		t.Fatalf("failed to prune payload records: %v", err)
+	}
+	if kept != 1 {
+		t.Errorf("got %d records kept want 1", kept)
+	}
+	records := &kokumetricscfgv1beta1.KokuMetricsPayloadList{}
+	if err := r.List(context.Background(), records); err != nil {
+		t.Fatalf("failed to list payload records: %v", err)
+	}
+	got := map[string]bool{}
+	for _, item := range records.Items {
+		got[item.Name] = true
+	}
+	if !got["payload-new"] || got["payload-old"] || !got["payload-other"] {
+		t.Errorf("got records %v want payload-new and payload-other", got)
+	}
+}
+
+func TestAuditPayloadsDisabled(t *testing.T) {
+	r := payloadAuditReconciler(t)
+	kmCfg := payloadAuditConfig()
+	kmCfg.Status.PayloadAudit.Enabled = false
+	ingress := &exporter.Ingress{LastStatus: "202 Accepted"}
+	auditPayloads(r, kmCfg, []uploader.Result{{Exporter: ingress, Payload: packaging.PayloadSummary{PayloadID: "id"}}})
+	records := &kokumetricscfgv1beta1.KokuMetricsPayloadList{}
+	if err := r.List(context.Background(), records); err != nil {
+		t.Fatalf("failed to list payload records: %v", err)
+	}
+	if len(records.Items) != 0 {
+		t.Errorf("expected no records when the audit is disabled, got %d", len(records.Items))
+	}
+}
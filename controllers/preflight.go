@@ -0,0 +1,185 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+// preflightRetryInterval is how long a failed pre-flight check waits before it is repeated
+const preflightRetryInterval = 5 * time.Minute
+
+// preflightAuth returns the authentication type, or the list of authentication methods, that the pre-flight check
+// uses. The configured methods are returned rather than the method that was found, so that a fallback does not look
+// like a change.
+func preflightAuth(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) string {
+	methods := kmCfg.Status.Authentication.Methods
+	if len(methods) == 0 {
+		return string(kmCfg.Status.Authentication.AuthType)
+	}
+	names := make([]string, len(methods))
+	for i, method := range methods {
+		names[i] = string(method)
+	}
+	return strings.Join(names, ",")
+}
+
+// credentialsVersion returns the resource versions of the secrets that hold the upload credentials, so that the
+// pre-flight check is repeated when the credentials are rotated. A secret that cannot be read has no version.
+func credentialsVersion(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, namespace string) string {
+	ctx := context.Background()
+	var versions []string
+	if name := kmCfg.Status.Authentication.AuthenticationSecretName; name != "" {
+		secret := &corev1.Secret{}
+		if err := r.getSecret(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err == nil {
+			versions = append(versions, secret.ResourceVersion)
+		}
+	}
+	usesToken := kmCfg.Status.Authentication.AuthType == kokumetricscfgv1beta1.Token
+	for _, method := range kmCfg.Status.Authentication.Methods {
+		usesToken = usesToken || method == kokumetricscfgv1beta1.Token
+	}
+	if usesToken && r.Clientset != nil {
+		if secret, err := r.Clientset.CoreV1().Secrets(openShiftConfigNamespace).Get(ctx, pullSecretName, metav1.GetOptions{}); err == nil {
+			versions = append(versions, secret.ResourceVersion)
+		}
+	}
+	return strings.Join(versions, ",")
+}
+
+// preflightRequired returns true if the ingress URL, the authentication type, or the credentials changed since the
+// last pre-flight check, or if the last check failed more than preflightRetryInterval ago. A failed check is not
+// repeated on every reconcile, so that the status update of each attempt does not trigger the next one.
+func preflightRequired(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, credentials string, now time.Time) bool {
+	preflight := kmCfg.Status.Preflight
+	if preflight.IngressAPIURL != IngressURL(kmCfg) || preflight.AuthType != preflightAuth(kmCfg) ||
+		preflight.CredentialsVersion != credentials {
+		return true
+	}
+	return !kokumetricscfgv1beta1.IsConditionTrue(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionReadyToUpload) &&
+		now.Sub(preflight.LastRunTime.Time) >= preflightRetryInterval
+}
+
+// setReadyToUploadCondition reflects the result of the pre-flight check in the ReadyToUpload condition. The operator
+// is ready to upload when ingress accepted the request, and the Sources API did not fail it.
+func setReadyToUploadCondition(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	preflight := kmCfg.Status.Preflight
+	condition := kokumetricscfgv1beta1.Condition{
+		Type:    kokumetricscfgv1beta1.ConditionReadyToUpload,
+		Status:  corev1.ConditionTrue,
+		Reason:  kokumetricscfgv1beta1.ReasonPreflightSucceeded,
+		Message: fmt.Sprintf("%s: %s", preflight.IngressAPIURL, preflight.Ingress.Message),
+	}
+	var failures []string
+	if preflight.Ingress.Result != kokumetricscfgv1beta1.ConnectionCheckSucceeded {
+		failures = append(failures, fmt.Sprintf("ingress %s: %s", preflight.IngressAPIURL, preflight.Ingress.Message))
+	}
+	if preflight.Sources.Result == kokumetricscfgv1beta1.ConnectionCheckFailed {
+		failures = append(failures, fmt.Sprintf("sources: %s", preflight.Sources.Message))
+	}
+	if len(failures) > 0 {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = kokumetricscfgv1beta1.ReasonPreflightFailed
+		condition.Message = strings.Join(failures, "; ")
+	}
+	kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, condition)
+}
+
+// runPreflight sends an authenticated request to the ingress endpoint and to the Sources API before the first
+// collection, so that an installation that cannot upload is reported within minutes instead of after the first
+// upload cycle. A failed check is repeated every preflightRetryInterval until it succeeds, and the check is run again
+// when the ingress URL, the authentication type, or the credentials change. No data is uploaded. It returns true if
+// the status changed.
+func runPreflight(r *KokuMetricsConfigReconciler, req ctrl.Request, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, logger logr.Logger) bool {
+	if kmCfg.Spec.Upload.UploadToggle == nil || !*kmCfg.Spec.Upload.UploadToggle {
+		condition := kokumetricscfgv1beta1.FindCondition(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionReadyToUpload)
+		if condition != nil && condition.Reason == kokumetricscfgv1beta1.ReasonUploadDisabled {
+			return false
+		}
+		kmCfg.Status.Preflight = kokumetricscfgv1beta1.PreflightStatus{}
+		kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+			Type:    kokumetricscfgv1beta1.ConditionReadyToUpload,
+			Status:  corev1.ConditionFalse,
+			Reason:  kokumetricscfgv1beta1.ReasonUploadDisabled,
+			Message: "upload is disabled",
+		})
+		return true
+	}
+	credentials := credentialsVersion(r, kmCfg, req.Namespace)
+	if !preflightRequired(kmCfg, credentials, time.Now()) {
+		return false
+	}
+	log := logger.WithValues("KokuMetricsConfig", "runPreflight")
+	log.Info("running the pre-flight check of the upload endpoints")
+
+	preflight := kokumetricscfgv1beta1.PreflightStatus{
+		LastRunTime:        metav1.Now(),
+		IngressAPIURL:      IngressURL(kmCfg),
+		AuthType:           preflightAuth(kmCfg),
+		CredentialsVersion: credentials,
+	}
+	preflight.Ingress, preflight.Sources = checkUploadEndpoints(r, req, kmCfg, logger)
+	kmCfg.Status.Preflight = preflight
+	setReadyToUploadCondition(kmCfg)
+
+	log.Info("pre-flight check complete",
+		"ingress", preflight.Ingress.Result,
+		"sources", preflight.Sources.Result,
+		"ready", kokumetricscfgv1beta1.IsConditionTrue(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionReadyToUpload))
+	return true
+}
+
+// recordUploadAuthorization sets the ReadyToUpload condition to False when ingress rejects the credentials of an
+// upload, so that the condition does not stay True after the credentials expire or are revoked. The pre-flight check
+// is then repeated on the next reconcile.
+func recordUploadAuthorization(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, result uploader.Result) {
+	if _, ok := result.Exporter.(*exporter.Ingress); !ok {
+		return
+	}
+	var respErr *crhchttp.ResponseError
+	if !errors.As(result.Err, &respErr) {
+		return
+	}
+	if respErr.StatusCode != http.StatusUnauthorized && respErr.StatusCode != http.StatusForbidden {
+		return
+	}
+	kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+		Type:    kokumetricscfgv1beta1.ConditionReadyToUpload,
+		Status:  corev1.ConditionFalse,
+		Reason:  kokumetricscfgv1beta1.ReasonUploadUnauthorized,
+		Message: fmt.Sprintf("ingress rejected the credentials of an upload with %d %s", respErr.StatusCode, http.StatusText(respErr.StatusCode)),
+	})
+}
@@ -0,0 +1,215 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/testutils/fakes"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+func preflightReconciler(uploader *fakes.Uploader, sourcesClient *fakes.SourcesClient) (*KokuMetricsConfigReconciler, *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "basic-auth"},
+		Data:       map[string][]byte{authSecretUserKey: []byte("user"), authSecretPasswordKey: []byte("password")},
+	}
+	r := &KokuMetricsConfigReconciler{
+		Client:        fake.NewFakeClient(secret),
+		Log:           testutils.TestLogger{},
+		SourcesClient: sourcesClient,
+		Uploader:      uploader,
+	}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator"}}
+	kmCfg.Spec.Upload.UploadToggle = &trueDef
+	kmCfg.Spec.Authentication.AuthenticationSecretName = secret.Name
+	kmCfg.Status.Authentication.AuthenticationSecretName = secret.Name
+	kmCfg.Status.Authentication.AuthType = kokumetricscfgv1beta1.Basic
+	kmCfg.Status.APIURL = kokumetricscfgv1beta1.DefaultAPIURL
+	kmCfg.Status.Upload.IngressAPIPath = kokumetricscfgv1beta1.DefaultIngressPath
+	return r, kmCfg
+}
+
+func TestRunPreflight(t *testing.T) {
+	preflightTests := []struct {
+		name       string
+		uploadErr  error
+		sourcesErr error
+		want       corev1.ConditionStatus
+		wantReason string
+	}{
+		{name: "endpoints reachable", want: corev1.ConditionTrue, wantReason: kokumetricscfgv1beta1.ReasonPreflightSucceeded},
+		{name: "ingress rejects credentials", uploadErr: errors.New("credentials were rejected by the ingress endpoint: 401 Unauthorized"), want: corev1.ConditionFalse, wantReason: kokumetricscfgv1beta1.ReasonPreflightFailed},
+		{name: "sources unreachable", sourcesErr: errors.New("connection refused"), want: corev1.ConditionFalse, wantReason: kokumetricscfgv1beta1.ReasonPreflightFailed},
+	}
+	for _, tt := range preflightTests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploader := &fakes.Uploader{Err: tt.uploadErr}
+			sourcesClient := &fakes.SourcesClient{Err: tt.sourcesErr}
+			r, kmCfg := preflightReconciler(uploader, sourcesClient)
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: kmCfg.Namespace}}
+
+			runPreflight(r, req, kmCfg, r.Log)
+			condition := kokumetricscfgv1beta1.FindCondition(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionReadyToUpload)
+			if condition == nil || condition.Status != tt.want || condition.Reason != tt.wantReason {
+				t.Fatalf("%s ReadyToUpload condition got %+v want %s %s", tt.name, condition, tt.want, tt.wantReason)
+			}
+			if tt.want == corev1.ConditionFalse && !strings.Contains(condition.Message, "refused") && !strings.Contains(condition.Message, "401") {
+				t.Errorf("%s ReadyToUpload message %q does not explain the failure", tt.name, condition.Message)
+			}
+			if kmCfg.Status.Preflight.LastRunTime.IsZero() || kmCfg.Status.Preflight.IngressAPIURL != IngressURL(kmCfg) {
+				t.Errorf("%s preflight status got %+v want the run time and ingress URL", tt.name, kmCfg.Status.Preflight)
+			}
+			if uploads := uploader.Uploads(); len(uploads) != 0 {
+				t.Errorf("%s uploads got %d want 0", tt.name, len(uploads))
+			}
+
+			// neither a failed nor a successful pre-flight is repeated on the next reconcile
+			requests := len(sourcesClient.Requests())
+			if runPreflight(r, req, kmCfg, r.Log) {
+				t.Errorf("%s second reconcile changed the status", tt.name)
+			}
+			if got := len(sourcesClient.Requests()); got != requests {
+				t.Errorf("%s sources requests after a second reconcile got %d want %d", tt.name, got, requests)
+			}
+
+			// a failed pre-flight is repeated once the retry interval has passed, a successful one is not
+			kmCfg.Status.Preflight.LastRunTime = metav1.NewTime(time.Now().Add(-preflightRetryInterval))
+			runPreflight(r, req, kmCfg, r.Log)
+			wantRequests := requests
+			if tt.want == corev1.ConditionFalse {
+				wantRequests++
+			}
+			if got := len(sourcesClient.Requests()); got != wantRequests {
+				t.Errorf("%s sources requests after the retry interval got %d want %d", tt.name, got, wantRequests)
+			}
+		})
+	}
+}
+
+func TestRunPreflightIngressChanged(t *testing.T) {
+	sourcesClient := &fakes.SourcesClient{}
+	r, kmCfg := preflightReconciler(&fakes.Uploader{}, sourcesClient)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: kmCfg.Namespace}}
+	runPreflight(r, req, kmCfg, r.Log)
+
+	kmCfg.Status.APIURL = "https://console.stage.redhat.com"
+	runPreflight(r, req, kmCfg, r.Log)
+	if got := len(sourcesClient.Requests()); got != 2 {
+		t.Errorf("sources requests after the ingress URL changed got %d want 2", got)
+	}
+	if kmCfg.Status.Preflight.IngressAPIURL != IngressURL(kmCfg) {
+		t.Errorf("preflight ingress URL got %s want %s", kmCfg.Status.Preflight.IngressAPIURL, IngressURL(kmCfg))
+	}
+}
+
+func TestRunPreflightCredentialsChanged(t *testing.T) {
+	sourcesClient := &fakes.SourcesClient{}
+	r, kmCfg := preflightReconciler(&fakes.Uploader{}, sourcesClient)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: kmCfg.Namespace}}
+	runPreflight(r, req, kmCfg, r.Log)
+
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: "basic-auth"}
+	if err := r.Get(context.Background(), key, secret); err != nil {
+		t.Fatalf("failed to get the secret: %v", err)
+	}
+	secret.Data[authSecretPasswordKey] = []byte("rotated")
+	if err := r.Update(context.Background(), secret); err != nil {
+		t.Fatalf("failed to update the secret: %v", err)
+	}
+	runPreflight(r, req, kmCfg, r.Log)
+	if got := len(sourcesClient.Requests()); got != 2 {
+		t.Errorf("sources requests after the secret was rotated got %d want 2", got)
+	}
+	if kmCfg.Status.Preflight.CredentialsVersion != secret.ResourceVersion {
+		t.Errorf("preflight credentials version got %s want %s", kmCfg.Status.Preflight.CredentialsVersion, secret.ResourceVersion)
+	}
+
+	kmCfg.Status.Authentication.AuthType = kokumetricscfgv1beta1.Static
+	runPreflight(r, req, kmCfg, r.Log)
+	if kmCfg.Status.Preflight.AuthType != string(kokumetricscfgv1beta1.Static) {
+		t.Errorf("preflight auth type after the authentication type changed got %s want %s", kmCfg.Status.Preflight.AuthType, kokumetricscfgv1beta1.Static)
+	}
+}
+
+func TestRecordUploadAuthorization(t *testing.T) {
+	uploadTests := []struct {
+		name      string
+		exp       exporter.Exporter
+		err       error
+		wantReady bool
+	}{
+		{name: "upload accepted", exp: &exporter.Ingress{}, wantReady: true},
+		{name: "credentials rejected", exp: &exporter.Ingress{}, err: &crhchttp.ResponseError{StatusCode: http.StatusUnauthorized}},
+		{name: "access forbidden", exp: &exporter.Ingress{}, err: fmt.Errorf("upload failed: %w", &crhchttp.ResponseError{StatusCode: http.StatusForbidden})},
+		{name: "payload rejected", exp: &exporter.Ingress{}, err: &crhchttp.ResponseError{StatusCode: http.StatusRequestEntityTooLarge}, wantReady: true},
+		{name: "other destinations are ignored", exp: &exporter.Filesystem{}, err: &crhchttp.ResponseError{StatusCode: http.StatusUnauthorized}, wantReady: true},
+	}
+	for _, tt := range uploadTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, kokumetricscfgv1beta1.Condition{
+				Type:   kokumetricscfgv1beta1.ConditionReadyToUpload,
+				Status: corev1.ConditionTrue,
+				Reason: kokumetricscfgv1beta1.ReasonPreflightSucceeded,
+			})
+			recordUploadAuthorization(kmCfg, uploader.Result{Exporter: tt.exp, Err: tt.err})
+			ready := kokumetricscfgv1beta1.IsConditionTrue(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionReadyToUpload)
+			if ready != tt.wantReady {
+				t.Errorf("%s ReadyToUpload got %t want %t", tt.name, ready, tt.wantReady)
+			}
+			if !tt.wantReady && !preflightRequired(kmCfg, "", time.Now()) {
+				t.Errorf("%s pre-flight is not repeated after the credentials were rejected", tt.name)
+			}
+		})
+	}
+}
+
+func TestRunPreflightUploadDisabled(t *testing.T) {
+	sourcesClient := &fakes.SourcesClient{}
+	r, kmCfg := preflightReconciler(&fakes.Uploader{}, sourcesClient)
+	kmCfg.Spec.Upload.UploadToggle = &falseDef
+	runPreflight(r, ctrl.Request{}, kmCfg, r.Log)
+
+	condition := kokumetricscfgv1beta1.FindCondition(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionReadyToUpload)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != kokumetricscfgv1beta1.ReasonUploadDisabled {
+		t.Errorf("ReadyToUpload condition with upload disabled got %+v want False %s", condition, kokumetricscfgv1beta1.ReasonUploadDisabled)
+	}
+	if got := len(sourcesClient.Requests()); got != 0 {
+		t.Errorf("sources requests with upload disabled got %d want 0", got)
+	}
+}
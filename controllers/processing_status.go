@@ -0,0 +1,158 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/sources"
+)
+
+// maxProcessingStatusPayloads is the number of the most recent payloads whose processing status is polled
+const maxProcessingStatusPayloads = 50
+
+// reasonPayloadProcessingFailed is the reason of the event emitted when cost management fails to process a payload
+const reasonPayloadProcessingFailed = "PayloadProcessingFailed"
+
+var dataCurrentThrough = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "koku_metrics_data_current_through_timestamp_seconds",
+		Help: "End of the latest window of the payloads that cost management has processed, as a unix timestamp.",
+	},
+)
+
+func init() {
+	metrics.Registry.MustRegister(dataCurrentThrough)
+}
+
+// isProcessingStatusEnabled returns true when polling the report status API is enabled in the spec
+func isProcessingStatusEnabled(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	return kmCfg.Spec.ProcessingStatus.Enabled != nil && *kmCfg.Spec.ProcessingStatus.Enabled
+}
+
+// reflectProcessingStatus writes the configuration of the processing status poller to the status
+func reflectProcessingStatus(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	status := &kmCfg.Status.ProcessingStatus
+	status.Enabled = isProcessingStatusEnabled(kmCfg)
+	status.StatusAPIPath = kokumetricscfgv1beta1.DefaultReportStatusPath
+	if kmCfg.Spec.ProcessingStatus.StatusAPIPath != "" {
+		status.StatusAPIPath = kmCfg.Spec.ProcessingStatus.StatusAPIPath
+	}
+	pollCycle := kokumetricscfgv1beta1.DefaultProcessingStatusPollCycle
+	if kmCfg.Spec.ProcessingStatus.PollCycle != nil {
+		pollCycle = *kmCfg.Spec.ProcessingStatus.PollCycle
+	}
+	status.PollCycle = &pollCycle
+}
+
+// pollProcessingStatus polls the report status API for the processing status of the most recent payloads of the
+// cluster once every poll cycle, and records it in the status and in the KokuMetricsPayloads of the payloads
+func pollProcessingStatus(r *KokuMetricsConfigReconciler, sSpec *sources.SourceSpec, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, now time.Time) {
+	status := &kmCfg.Status.ProcessingStatus
+	if !status.Enabled || !checkCycle(r.Log, *status.PollCycle, status.LastPollTime, "processing status poll") {
+		return
+	}
+	log := r.Log.WithValues("kokumetricsconfig", "pollProcessingStatus")
+	status.LastPollTime = metav1.NewTime(now)
+	items, err := r.sourcesClient().GetReportStatus(sSpec, status.StatusAPIPath, maxProcessingStatusPayloads)
+	if err != nil {
+		log.Error(err, "failed to poll the report status API")
+		status.Error = err.Error()
+		return
+	}
+	status.Error = ""
+
+	states := map[string]string{}
+	status.Processed, status.Pending, status.Failed = 0, 0, 0
+	status.FailedPayloads = nil
+	for _, item := range items {
+		states[strings.ToLower(item.AssemblyID)] = item.State
+		switch item.State {
+		case sources.ReportStateProcessed:
+			status.Processed++
+		case sources.ReportStateFailed:
+			status.Failed++
+			status.FailedPayloads = append(status.FailedPayloads, item.AssemblyID)
+		default:
+			status.Pending++
+		}
+	}
+	sort.Strings(status.FailedPayloads)
+	log.Info("polled the report status API", "processed", status.Processed, "pending", status.Pending, "failed", status.Failed)
+
+	if err := recordProcessingStatus(r, kmCfg, states); err != nil {
+		log.Error(err, "failed to record the processing status of the payloads")
+		status.Error = err.Error()
+	}
+	if !status.DataCurrentThrough.IsZero() {
+		dataCurrentThrough.Set(float64(status.DataCurrentThrough.Unix()))
+	}
+}
+
+// recordProcessingStatus writes the processing state of each payload to its KokuMetricsPayload, and emits an event
+// for each payload that failed processing. The time the cost data is current through is advanced to the end of the
+// window of the latest processed payload. The payloads are matched to their records by payload ID, so nothing is
+// recorded when the payload audit is disabled.
+func recordProcessingStatus(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, states map[string]string) error {
+	if !kmCfg.Status.PayloadAudit.Enabled || r.Client == nil || len(states) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+
+	records := &kokumetricscfgv1beta1.KokuMetricsPayloadList{}
+	if err := r.List(ctx, records, client.InNamespace(kmCfg.Namespace),
+		client.MatchingLabels{kokumetricscfgv1beta1.PayloadConfigLabel: kmCfg.Name}); err != nil {
+		return fmt.Errorf("recordProcessingStatus: failed to list KokuMetricsPayloads: %v", err)
+	}
+	status := &kmCfg.Status.ProcessingStatus
+	for i := range records.Items {
+		record := &records.Items[i]
+		state, ok := states[strings.ToLower(record.Spec.PayloadID)]
+		if !ok {
+			continue
+		}
+		if state == sources.ReportStateProcessed && record.Spec.End.After(status.DataCurrentThrough.Time) {
+			status.DataCurrentThrough = record.Spec.End
+		}
+		if record.Status.ProcessingStatus == state {
+			continue
+		}
+		if state == sources.ReportStateFailed && r.Recorder != nil {
+			r.Recorder.Event(kmCfg, corev1.EventTypeWarning, reasonPayloadProcessingFailed,
+				fmt.Sprintf("cost management failed to process payload %s (%s)", record.Spec.PayloadID, record.Spec.File))
+		}
+		record.Status.ProcessingStatus = state
+		if err := r.Status().Update(ctx, record); err != nil {
+			return fmt.Errorf("recordProcessingStatus: failed to update KokuMetricsPayload %s status: %v", record.Name, err)
+		}
+	}
+	return nil
+}
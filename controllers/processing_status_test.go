@@ -0,0 +1,155 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/sources"
+	"github.com/project-koku/koku-metrics-operator/testutils/fakes"
+)
+
+func processingStatusRecord(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, payloadID string, end time.Time) *kokumetricscfgv1beta1.KokuMetricsPayload {
+	return &kokumetricscfgv1beta1.KokuMetricsPayload{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: kmCfg.Namespace,
+			Name:      payloadAuditName(payloadID),
+			Labels:    map[string]string{kokumetricscfgv1beta1.PayloadConfigLabel: kmCfg.Name},
+		},
+		Spec: kokumetricscfgv1beta1.KokuMetricsPayloadSpec{PayloadID: payloadID, File: payloadID + ".tar.gz", End: metav1.NewTime(end)},
+	}
+}
+
+func TestReflectProcessingStatus(t *testing.T) {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	reflectProcessingStatus(kmCfg)
+	status := kmCfg.Status.ProcessingStatus
+	if status.Enabled || status.StatusAPIPath != kokumetricscfgv1beta1.DefaultReportStatusPath ||
+		*status.PollCycle != kokumetricscfgv1beta1.DefaultProcessingStatusPollCycle {
+		t.Errorf("got status %+v want the defaults", status)
+	}
+
+	enabled, cycle := true, int64(30)
+	kmCfg.Spec.ProcessingStatus = kokumetricscfgv1beta1.ProcessingStatusSpec{Enabled: &enabled, StatusAPIPath: "/status/", PollCycle: &cycle}
+	reflectProcessingStatus(kmCfg)
+	status = kmCfg.Status.ProcessingStatus
+	if !status.Enabled || status.StatusAPIPath != "/status/" || *status.PollCycle != 30 {
+		t.Errorf("got status %+v want the spec", status)
+	}
+}
+
+func TestPollProcessingStatus(t *testing.T) {
+	kmCfg := payloadAuditConfig()
+	enabled := true
+	kmCfg.Spec.ProcessingStatus.Enabled = &enabled
+	reflectProcessingStatus(kmCfg)
+	now := time.Now().UTC().Truncate(time.Second)
+	end := now.Add(-4 * time.Hour)
+	r := payloadAuditReconciler(t,
+		processingStatusRecord(kmCfg, "PAYLOAD-1", end.Add(-time.Hour)),
+		processingStatusRecord(kmCfg, "payload-2", end),
+		processingStatusRecord(kmCfg, "payload-3", end.Add(time.Hour)),
+		processingStatusRecord(kmCfg, "payload-4", end.Add(2*time.Hour)),
+	)
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	client := &fakes.SourcesClient{ReportStatus: []sources.ReportStatusItem{
+		{AssemblyID: "payload-1", State: sources.ReportStateProcessed},
+		{AssemblyID: "payload-2", State: sources.ReportStateProcessed},
+		{AssemblyID: "payload-3", State: sources.ReportStateFailed},
+		{AssemblyID: "payload-4", State: "processing"},
+	}}
+	r.SourcesClient = client
+
+	pollProcessingStatus(r, &sources.SourceSpec{}, kmCfg, now)
+	status := kmCfg.Status.ProcessingStatus
+	if status.Processed != 2 || status.Pending != 1 || status.Failed != 1 || status.Error != "" ||
+		!reflect.DeepEqual(status.FailedPayloads, []string{"payload-3"}) {
+		t.Errorf("got status %+v want 2 processed, 1 pending, and 1 failed payload", status)
+	}
+	if !status.DataCurrentThrough.Time.Equal(end) || !status.LastPollTime.Time.Equal(now) {
+		t.Errorf("got data current through %v polled at %v want %v polled at %v", status.DataCurrentThrough, status.LastPollTime, end, now)
+	}
+	wantStates := map[string]string{
+		"PAYLOAD-1": sources.ReportStateProcessed,
+		"payload-2": sources.ReportStateProcessed,
+		"payload-3": sources.ReportStateFailed,
+		"payload-4": "processing",
+	}
+	for payloadID, want := range wantStates {
+		record := &kokumetricscfgv1beta1.KokuMetricsPayload{}
+		key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: payloadAuditName(payloadID)}
+		if err := r.Get(context.Background(), key, record); err != nil {
+			t.Fatalf("failed to get payload record: %v", err)
+		}
+		if record.Status.ProcessingStatus != want {
+			t.Errorf("%s got processing status %q want %q", payloadID, record.Status.ProcessingStatus, want)
+		}
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("got %d events want 1 for the failed payload", len(recorder.Events))
+	}
+
+	// the API is not polled again before the poll cycle has passed
+	client.Err = errors.New("unavailable")
+	pollProcessingStatus(r, &sources.SourceSpec{}, kmCfg, now)
+	if len(client.Requests()) != 1 || kmCfg.Status.ProcessingStatus.Error != "" {
+		t.Errorf("got %d requests and error %q want no new poll within the poll cycle", len(client.Requests()), kmCfg.Status.ProcessingStatus.Error)
+	}
+}
+
+func TestPollProcessingStatusError(t *testing.T) {
+	kmCfg := payloadAuditConfig()
+	enabled := true
+	kmCfg.Spec.ProcessingStatus.Enabled = &enabled
+	reflectProcessingStatus(kmCfg)
+	previous := metav1.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	kmCfg.Status.ProcessingStatus.DataCurrentThrough = previous
+	r := payloadAuditReconciler(t)
+	r.SourcesClient = &fakes.SourcesClient{Err: errors.New("unavailable")}
+
+	pollProcessingStatus(r, &sources.SourceSpec{}, kmCfg, time.Now())
+	status := kmCfg.Status.ProcessingStatus
+	if status.Error == "" || !status.DataCurrentThrough.Equal(&previous) {
+		t.Errorf("got status %+v want the error and the previous data current through", status)
+	}
+}
+
+func TestPollProcessingStatusDisabled(t *testing.T) {
+	kmCfg := payloadAuditConfig()
+	reflectProcessingStatus(kmCfg)
+	client := &fakes.SourcesClient{}
+	r := payloadAuditReconciler(t)
+	r.SourcesClient = client
+
+	pollProcessingStatus(r, &sources.SourceSpec{}, kmCfg, time.Now())
+	if len(client.Requests()) != 0 || !kmCfg.Status.ProcessingStatus.LastPollTime.IsZero() {
+		t.Errorf("got %d requests want no poll when disabled", len(client.Requests()))
+	}
+}
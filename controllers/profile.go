@@ -0,0 +1,106 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/mustgather"
+)
+
+const (
+	defaultRequeueAfter     = 5 * time.Minute
+	lightweightRequeueAfter = 15 * time.Minute
+	lightweightLogLines     = 500
+)
+
+// resourceSizes are the resource hints of each resource size
+var resourceSizes = map[kokumetricscfgv1beta1.ResourceSize]kokumetricscfgv1beta1.ResourcesStatus{
+	kokumetricscfgv1beta1.SmallResources: {
+		MaxParallelQueries: 1,
+		CompressionWorkers: 1,
+		LogBufferLines:     lightweightLogLines,
+		CopyBufferKiB:      32,
+	},
+	kokumetricscfgv1beta1.MediumResources: {
+		MaxParallelQueries: 2,
+		CompressionWorkers: 2,
+		LogBufferLines:     mustgather.DefaultLogLines,
+		CopyBufferKiB:      32,
+	},
+	kokumetricscfgv1beta1.LargeResources: {
+		MaxParallelQueries: 4,
+		CompressionWorkers: 4,
+		LogBufferLines:     4 * mustgather.DefaultLogLines,
+		CopyBufferKiB:      256,
+	},
+}
+
+// isLightweight returns true when the operator is running with the lightweight profile
+func isLightweight(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	return kmCfg.Status.Profile == kokumetricscfgv1beta1.LightweightProfile
+}
+
+// useEmptyDir returns true when reports should remain on the EmptyDir volume instead of a PVC
+func useEmptyDir(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	return isLightweight(kmCfg) && kmCfg.Spec.UseEmptyDir != nil && *kmCfg.Spec.UseEmptyDir
+}
+
+// requeueInterval returns the time between reconciles for the configured profile
+func requeueInterval(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) time.Duration {
+	if isLightweight(kmCfg) {
+		return lightweightRequeueAfter
+	}
+	return defaultRequeueAfter
+}
+
+// resourceHints returns the resource hints of the configured resource size, with the hints set in the spec
+// overriding the hints of the size. The lightweight profile is sized small unless a size is set.
+func resourceHints(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) kokumetricscfgv1beta1.ResourcesStatus {
+	spec := kmCfg.Spec.Resources
+	size := spec.Size
+	if size == "" {
+		size = kokumetricscfgv1beta1.DefaultResourceSize
+		if isLightweight(kmCfg) {
+			size = kokumetricscfgv1beta1.SmallResources
+		}
+	}
+	hints := resourceSizes[size]
+	hints.Size = size
+	if spec.MaxParallelQueries != nil {
+		hints.MaxParallelQueries = *spec.MaxParallelQueries
+	}
+	if spec.CompressionWorkers != nil {
+		hints.CompressionWorkers = *spec.CompressionWorkers
+	}
+	if spec.LogBufferLines != nil {
+		hints.LogBufferLines = *spec.LogBufferLines
+	}
+	if spec.CopyBufferKiB != nil {
+		hints.CopyBufferKiB = *spec.CopyBufferKiB
+	}
+	return hints
+}
+
+// applyProfile sizes the in-memory buffers for the configured profile and resource hints
+func applyProfile(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	mustgather.RecentLogs.Resize(int(kmCfg.Status.Resources.LogBufferLines))
+}
@@ -0,0 +1,138 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestProfileSettings(t *testing.T) {
+	trueValue := true
+	falseValue := false
+	profileTests := []struct {
+		name        string
+		profile     kokumetricscfgv1beta1.OperatorProfile
+		emptyDir    *bool
+		wantRequeue time.Duration
+		wantEmpty   bool
+	}{
+		{name: "default profile", profile: kokumetricscfgv1beta1.DefaultProfile, wantRequeue: defaultRequeueAfter},
+		{name: "default profile ignores empty dir", profile: kokumetricscfgv1beta1.DefaultProfile, emptyDir: &trueValue, wantRequeue: defaultRequeueAfter},
+		{name: "lightweight profile", profile: kokumetricscfgv1beta1.LightweightProfile, wantRequeue: lightweightRequeueAfter},
+		{name: "lightweight profile with pvc", profile: kokumetricscfgv1beta1.LightweightProfile, emptyDir: &falseValue, wantRequeue: lightweightRequeueAfter},
+		{name: "lightweight profile with empty dir", profile: kokumetricscfgv1beta1.LightweightProfile, emptyDir: &trueValue, wantRequeue: lightweightRequeueAfter, wantEmpty: true},
+	}
+	for _, tt := range profileTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Profile = tt.profile
+			kmCfg.Spec.UseEmptyDir = tt.emptyDir
+			ReflectSpec(&KokuMetricsConfigReconciler{}, kmCfg)
+			if got := requeueInterval(kmCfg); got != tt.wantRequeue {
+				t.Errorf("%s got requeue %s want %s", tt.name, got, tt.wantRequeue)
+			}
+			if got := useEmptyDir(kmCfg); got != tt.wantEmpty {
+				t.Errorf("%s got useEmptyDir %t want %t", tt.name, got, tt.wantEmpty)
+			}
+		})
+	}
+}
+
+func TestReflectSpecDefaultProfile(t *testing.T) {
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	ReflectSpec(&KokuMetricsConfigReconciler{}, kmCfg)
+	if kmCfg.Status.Profile != kokumetricscfgv1beta1.DefaultOperatorProfile {
+		t.Errorf("got profile %q want %q", kmCfg.Status.Profile, kokumetricscfgv1beta1.DefaultOperatorProfile)
+	}
+}
+
+func TestResourceHints(t *testing.T) {
+	queries := int64(8)
+	logLines := int64(100)
+	hintsTests := []struct {
+		name      string
+		profile   kokumetricscfgv1beta1.OperatorProfile
+		resources kokumetricscfgv1beta1.ResourcesSpec
+		want      kokumetricscfgv1beta1.ResourcesStatus
+	}{
+		{
+			name: "default profile is medium",
+			want: kokumetricscfgv1beta1.ResourcesStatus{
+				Size:               kokumetricscfgv1beta1.MediumResources,
+				MaxParallelQueries: 2,
+				CompressionWorkers: 2,
+				LogBufferLines:     2000,
+				CopyBufferKiB:      32,
+			},
+		},
+		{
+			name:    "lightweight profile is small",
+			profile: kokumetricscfgv1beta1.LightweightProfile,
+			want: kokumetricscfgv1beta1.ResourcesStatus{
+				Size:               kokumetricscfgv1beta1.SmallResources,
+				MaxParallelQueries: 1,
+				CompressionWorkers: 1,
+				LogBufferLines:     lightweightLogLines,
+				CopyBufferKiB:      32,
+			},
+		},
+		{
+			name:      "lightweight profile with a size",
+			profile:   kokumetricscfgv1beta1.LightweightProfile,
+			resources: kokumetricscfgv1beta1.ResourcesSpec{Size: kokumetricscfgv1beta1.LargeResources},
+			want: kokumetricscfgv1beta1.ResourcesStatus{
+				Size:               kokumetricscfgv1beta1.LargeResources,
+				MaxParallelQueries: 4,
+				CompressionWorkers: 4,
+				LogBufferLines:     8000,
+				CopyBufferKiB:      256,
+			},
+		},
+		{
+			name: "hints override the size",
+			resources: kokumetricscfgv1beta1.ResourcesSpec{
+				Size:               kokumetricscfgv1beta1.SmallResources,
+				MaxParallelQueries: &queries,
+				LogBufferLines:     &logLines,
+			},
+			want: kokumetricscfgv1beta1.ResourcesStatus{
+				Size:               kokumetricscfgv1beta1.SmallResources,
+				MaxParallelQueries: 8,
+				CompressionWorkers: 1,
+				LogBufferLines:     100,
+				CopyBufferKiB:      32,
+			},
+		},
+	}
+	for _, tt := range hintsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Profile = tt.profile
+			kmCfg.Spec.Resources = tt.resources
+			ReflectSpec(&KokuMetricsConfigReconciler{}, kmCfg)
+			if kmCfg.Status.Resources != tt.want {
+				t.Errorf("%s got resources %+v want %+v", tt.name, kmCfg.Status.Resources, tt.want)
+			}
+		})
+	}
+}
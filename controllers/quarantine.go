@@ -0,0 +1,114 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// reasonPayloadQuarantined is the reason of the event emitted when a payload is quarantined
+const reasonPayloadQuarantined = "PayloadQuarantined"
+
+var (
+	quarantinedPayloads = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_quarantined_payloads",
+			Help: "Number of payloads in the quarantine directory waiting for review.",
+		},
+	)
+
+	quarantinedPayloadsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "koku_metrics_payloads_quarantined_total",
+			Help: "Number of payloads quarantined after repeated 4xx rejections by ingress.",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(quarantinedPayloads, quarantinedPayloadsTotal)
+}
+
+// recordQuarantined counts the payloads that the upload queue quarantined, and emits an event for each of them
+func recordQuarantined(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, files []string, now time.Time) {
+	if len(files) == 0 {
+		return
+	}
+	status := &kmCfg.Status.Upload.Quarantine
+	status.Quarantined += int64(len(files))
+	status.LastQuarantineTime = metav1.NewTime(now)
+	quarantinedPayloadsTotal.Add(float64(len(files)))
+	for _, file := range files {
+		msg := fmt.Sprintf("payload %s was quarantined after ingress rejected it %d times", file, *kmCfg.Status.Upload.QuarantineAfterRejections)
+		r.Log.Info(msg)
+		if r.Recorder != nil {
+			r.Recorder.Event(kmCfg, corev1.EventTypeWarning, reasonPayloadQuarantined, msg)
+		}
+	}
+}
+
+// reflectQuarantine writes the payloads in the quarantine directory to the status, and sets the PayloadsQuarantined
+// condition while any are waiting for review
+func reflectQuarantine(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig) {
+	status := &kmCfg.Status.Upload.Quarantine
+	status.Error = ""
+	status.Files = nil
+	infos, err := ioutil.ReadDir(filepath.Join(dirCfg.Parent.Path, dirconfig.QuarantineDir))
+	if err != nil && !os.IsNotExist(err) {
+		status.Error = fmt.Sprintf("failed to read the quarantine directory: %v", err)
+		return
+	}
+	for _, info := range infos {
+		if !info.IsDir() && strings.Contains(info.Name(), "tar.gz") {
+			status.Files = append(status.Files, info.Name())
+		}
+	}
+	sort.Strings(status.Files)
+	status.Payloads = int64(len(status.Files))
+	quarantinedPayloads.Set(float64(status.Payloads))
+
+	condition := kokumetricscfgv1beta1.Condition{
+		Type:    kokumetricscfgv1beta1.ConditionPayloadsQuarantined,
+		Status:  corev1.ConditionFalse,
+		Reason:  kokumetricscfgv1beta1.ReasonNoQuarantinedPayloads,
+		Message: "no payloads are quarantined",
+	}
+	if status.Payloads > 0 {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = kokumetricscfgv1beta1.ReasonRejectedPayloads
+		condition.Message = fmt.Sprintf("%d payloads rejected by ingress are waiting for review in the %s directory",
+			status.Payloads, dirconfig.QuarantineDir)
+	}
+	kokumetricscfgv1beta1.SetCondition(&kmCfg.Status.Conditions, condition)
+}
@@ -0,0 +1,92 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestRecordQuarantined(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: recorder}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	rejections := int64(3)
+	kmCfg.Status.Upload.QuarantineAfterRejections = &rejections
+	now := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	recordQuarantined(r, kmCfg, nil, now)
+	recordQuarantined(r, kmCfg, []string{"a.tar.gz", "b.tar.gz"}, now)
+	status := kmCfg.Status.Upload.Quarantine
+	if status.Quarantined != 2 || !status.LastQuarantineTime.Time.Equal(now) || len(recorder.Events) != 2 {
+		t.Errorf("got status %+v and %d events want 2 payloads quarantined at %v", status, len(recorder.Events), now)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, reasonPayloadQuarantined) || !strings.Contains(event, "a.tar.gz") {
+		t.Errorf("got event %s want %s for a.tar.gz", event, reasonPayloadQuarantined)
+	}
+}
+
+func TestReflectQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dirCfg := &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: dir}}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+
+	// no quarantine directory
+	reflectQuarantine(kmCfg, dirCfg)
+	condition := kokumetricscfgv1beta1.FindCondition(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionPayloadsQuarantined)
+	if kmCfg.Status.Upload.Quarantine.Payloads != 0 || condition == nil || condition.Status != corev1.ConditionFalse {
+		t.Errorf("got status %+v and condition %+v want no quarantined payloads", kmCfg.Status.Upload.Quarantine, condition)
+	}
+
+	quarantineDir := filepath.Join(dir, dirconfig.QuarantineDir)
+	if err := os.MkdirAll(quarantineDir, 0755); err != nil {
+		t.Fatalf("failed to create quarantine directory: %v", err)
+	}
+	for _, name := range []string{"b.tar.gz", "a.tar.gz", "notes.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(quarantineDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	reflectQuarantine(kmCfg, dirCfg)
+	status := kmCfg.Status.Upload.Quarantine
+	if status.Payloads != 2 || !reflect.DeepEqual(status.Files, []string{"a.tar.gz", "b.tar.gz"}) || status.Error != "" {
+		t.Errorf("got status %+v want a.tar.gz and b.tar.gz", status)
+	}
+	condition = kokumetricscfgv1beta1.FindCondition(kmCfg.Status.Conditions, kokumetricscfgv1beta1.ConditionPayloadsQuarantined)
+	if condition.Status != corev1.ConditionTrue || condition.Reason != kokumetricscfgv1beta1.ReasonRejectedPayloads {
+		t.Errorf("got condition %+v want %s", condition, kokumetricscfgv1beta1.ReasonRejectedPayloads)
+	}
+}
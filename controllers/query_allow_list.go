@@ -0,0 +1,116 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+)
+
+const (
+	queryAllowListConfigMapName = "koku-metrics-query-allow-list"
+	queryAllowListKey           = "queries.json"
+	queryAllowListHashKey       = "hash"
+
+	reasonQueryAllowListMismatch = "QueryAllowListMismatch"
+)
+
+// publishQueryAllowList writes the queries and their hash to the allow-list ConfigMap, so that they can be reviewed
+func publishQueryAllowList(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, plan []collector.PlannedQuery, hash string) error {
+	ctx := context.Background()
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("publishQueryAllowList: failed to marshal queries: %v", err)
+	}
+	desired := map[string]string{queryAllowListKey: string(data), queryAllowListHashKey: hash}
+
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: queryAllowListConfigMapName}
+	if err := r.Get(ctx, key, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("publishQueryAllowList: failed to get ConfigMap: %v", err)
+		}
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}, Data: desired}
+		if err := ctrl.SetControllerReference(kmCfg, cm, r.Scheme); err != nil {
+			return fmt.Errorf("publishQueryAllowList: failed to set owner reference: %v", err)
+		}
+		if err := r.Create(ctx, cm); err != nil {
+			return fmt.Errorf("publishQueryAllowList: failed to create ConfigMap: %v", err)
+		}
+		return nil
+	}
+	if cm.Data[queryAllowListHashKey] == hash && cm.Data[queryAllowListKey] == desired[queryAllowListKey] {
+		return nil
+	}
+	cm.Data = desired
+	if err := r.Update(ctx, cm); err != nil {
+		return fmt.Errorf("publishQueryAllowList: failed to update ConfigMap: %v", err)
+	}
+	return nil
+}
+
+// reconcileQueryAllowList materializes the queries the collector would run, publishes them, and restricts the
+// collector to them. When a pinned hash is set and the queries do not match it, the collector is restricted to no
+// queries at all and an error is returned, so that no data is collected until the change is reviewed.
+func reconcileQueryAllowList(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, log logr.Logger) error {
+	plan := r.promCollector.QueryPlan()
+	hash, err := collector.QueryPlanHash(plan)
+	if err != nil {
+		r.promCollector.AllowQueries(nil)
+		kmCfg.Status.Prometheus.QueryAllowList.Error = err.Error()
+		return err
+	}
+
+	previous := kmCfg.Status.Prometheus.QueryAllowList
+	status := kokumetricscfgv1beta1.QueryAllowListStatus{
+		ConfigMap:  queryAllowListConfigMapName,
+		Hash:       hash,
+		Queries:    int64(len(plan)),
+		PinnedHash: kmCfg.Spec.PrometheusConfig.PinnedQueryHash,
+	}
+	defer func() { kmCfg.Status.Prometheus.QueryAllowList = status }()
+
+	if err := publishQueryAllowList(r, kmCfg, plan, hash); err != nil {
+		log.Error(err, "failed to publish the query allow-list")
+		status.Error = err.Error()
+	}
+
+	if status.PinnedHash != "" && status.PinnedHash != hash {
+		r.promCollector.AllowQueries(nil)
+		status.Error = fmt.Sprintf("the queries (hash %s) do not match the pinned hash %s: review the %s ConfigMap and update the pinned hash", hash, status.PinnedHash, queryAllowListConfigMapName)
+		if r.Recorder != nil && previous.Error != status.Error {
+			r.Recorder.Event(kmCfg, corev1.EventTypeWarning, reasonQueryAllowListMismatch, status.Error)
+		}
+		return fmt.Errorf("%s", status.Error)
+	}
+	r.promCollector.AllowQueries(plan)
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+// queryAllowListReconciler returns a reconciler that can publish the query allow-list
+func queryAllowListReconciler(t *testing.T) *KokuMetricsConfigReconciler {
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{kokumetricscfgv1beta1.AddToScheme, corev1.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatalf("failed to build scheme: %v", err)
+		}
+	}
+	return &KokuMetricsConfigReconciler{Client: fake.NewFakeClientWithScheme(s), Scheme: s, Log: testutils.TestLogger{}}
+}
+
+func TestReconcileQueryAllowList(t *testing.T) {
+	ctx := context.Background()
+	recorder := record.NewFakeRecorder(10)
+	r := queryAllowListReconciler(t)
+	r.Recorder = recorder
+	r.promCollector = &collector.PromCollector{Generators: []collector.ReportGenerator{}}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "kokumetricscfg-sample", UID: "uid"},
+	}
+	setPromCollector(r, kmCfg)
+
+	// the queries are published and their hash is reflected in the status
+	if err := reconcileQueryAllowList(r, kmCfg, r.Log); err != nil {
+		t.Fatalf("failed to reconcile the query allow-list: %v", err)
+	}
+	status := kmCfg.Status.Prometheus.QueryAllowList
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: kmCfg.Namespace, Name: queryAllowListConfigMapName}, cm); err != nil {
+		t.Fatalf("failed to get ConfigMap: %v", err)
+	}
+	var plan []collector.PlannedQuery
+	if err := json.Unmarshal([]byte(cm.Data[queryAllowListKey]), &plan); err != nil {
+		t.Fatalf("failed to unmarshal queries: %v", err)
+	}
+	if int64(len(plan)) != status.Queries || cm.Data[queryAllowListHashKey] != status.Hash || status.Error != "" {
+		t.Errorf("got %d queries with hash %s and status %+v", len(plan), cm.Data[queryAllowListHashKey], status)
+	}
+	if !metav1.IsControlledBy(cm, kmCfg) {
+		t.Errorf("got owners %v want the KokuMetricsConfig", cm.OwnerReferences)
+	}
+
+	// the queries are run while they match the pinned hash
+	kmCfg.Spec.PrometheusConfig.PinnedQueryHash = status.Hash
+	if err := reconcileQueryAllowList(r, kmCfg, r.Log); err != nil {
+		t.Errorf("got error %v for the pinned hash", err)
+	}
+
+	// a change to the queries is published, and refused until it is pinned
+	kmCfg.Spec.PrometheusConfig.ExtraSelectors = []string{`cluster="prod"`}
+	setPromCollector(r, kmCfg)
+	err := reconcileQueryAllowList(r, kmCfg, r.Log)
+	if err == nil || !strings.Contains(kmCfg.Status.Prometheus.QueryAllowList.Error, "pinned hash") {
+		t.Fatalf("got error %v and status %+v want a pinned hash mismatch", err, kmCfg.Status.Prometheus.QueryAllowList)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: kmCfg.Namespace, Name: queryAllowListConfigMapName}, cm); err != nil {
+		t.Fatalf("failed to get ConfigMap: %v", err)
+	}
+	if got := cm.Data[queryAllowListHashKey]; got != kmCfg.Status.Prometheus.QueryAllowList.Hash || got == status.Hash {
+		t.Errorf("got published hash %s want the changed hash %s", got, kmCfg.Status.Prometheus.QueryAllowList.Hash)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("got %d events want 1", len(recorder.Events))
+	}
+	// the mismatch is only reported once
+	if err := reconcileQueryAllowList(r, kmCfg, r.Log); err == nil || len(recorder.Events) != 1 {
+		t.Errorf("got error %v and %d events want the mismatch without a new event", err, len(recorder.Events))
+	}
+}
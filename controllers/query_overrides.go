@@ -0,0 +1,37 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+)
+
+// reflectQueryOverrides writes the default queries that the query overrides of the spec replace to the status. The
+// overrides that do not match a query are not applied, and are reported in the status.
+func reflectQueryOverrides(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	overrides, err := collector.ResolveQueryOverrides(kmCfg.Spec.PrometheusConfig.QueryOverrides)
+	kmCfg.Status.Prometheus.QueryOverrides = overrides
+	kmCfg.Status.Prometheus.QueryOverridesError = ""
+	if err != nil {
+		r.Log.Info("some query overrides are not applied", "error", err)
+		kmCfg.Status.Prometheus.QueryOverridesError = err.Error()
+	}
+}
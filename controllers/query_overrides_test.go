@@ -0,0 +1,56 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestReflectQueryOverrides(t *testing.T) {
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Spec.PrometheusConfig.QueryOverrides = map[string]string{
+		"node_labels": "custom_node_labels",
+		"gpu_seconds": "gpu",
+	}
+	reflectQueryOverrides(r, kmCfg)
+	if got := kmCfg.Status.Prometheus.QueryOverrides; len(got) != 1 || got["node-labels"] != "custom_node_labels" {
+		t.Errorf("reflectQueryOverrides got overrides %v want the node-labels override", got)
+	}
+	if kmCfg.Status.Prometheus.QueryOverridesError == "" {
+		t.Errorf("reflectQueryOverrides got no error want an error for gpu_seconds")
+	}
+
+	c := &collector.PromCollector{}
+	ConfigureCollector(c, kmCfg)
+	if c.QueryOverrides["node-labels"] != "custom_node_labels" {
+		t.Errorf("ConfigureCollector got overrides %v want the overrides of the status", c.QueryOverrides)
+	}
+
+	kmCfg.Spec.PrometheusConfig.QueryOverrides = nil
+	reflectQueryOverrides(r, kmCfg)
+	if kmCfg.Status.Prometheus.QueryOverrides != nil || kmCfg.Status.Prometheus.QueryOverridesError != "" {
+		t.Errorf("reflectQueryOverrides got %v, %q want no overrides", kmCfg.Status.Prometheus.QueryOverrides, kmCfg.Status.Prometheus.QueryOverridesError)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+const (
+	// recollectHoursPerReconcile limits the hours re-collected in a single reconcile so that a large range does
+	// not block the regular collection and upload
+	recollectHoursPerReconcile = 24
+	// maxRecollectDays is the longest range that can be re-collected
+	maxRecollectDays = 93
+)
+
+// parseRecollectTime parses an RFC 3339 time, or a date that is interpreted as midnight in loc
+func parseRecollectTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a YYYY-MM-DD date", value)
+	}
+	return t, nil
+}
+
+// parseRecollectRange parses a `<start>/<end>` range. The start is truncated to the hour, and the end is limited
+// to the start of the current hour, since only complete hours are collected.
+func parseRecollectRange(value string, loc *time.Location, now time.Time) (time.Time, time.Time, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("parseRecollectRange: %q is not a <start>/<end> range", value)
+	}
+	start, err := parseRecollectTime(strings.TrimSpace(parts[0]), loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parseRecollectRange: invalid start: %v", err)
+	}
+	end, err := parseRecollectTime(strings.TrimSpace(parts[1]), loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parseRecollectRange: invalid end: %v", err)
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, loc)
+	now = now.In(loc)
+	if currentHour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, loc); end.After(currentHour) {
+		end = currentHour
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("parseRecollectRange: the range does not contain a complete past hour")
+	}
+	if end.Sub(start) > maxRecollectDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("parseRecollectRange: the range is longer than %d days", maxRecollectDays)
+	}
+	return start, end, nil
+}
+
+// recollectReports regenerates the reports for the range requested by the recollect annotation. The reports are
+// written to a directory for each billing period, so that they are packaged as separate backdated payloads. Up to
+// recollectHoursPerReconcile hours are re-collected in each reconcile, and the progress is written to the status.
+func recollectReports(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, logger logr.Logger) {
+	loc := BillingLocation(kmCfg)
+	status := &kmCfg.Status.Recollection
+	if trigger, requested := annotationTriggered(kmCfg, kokumetricscfgv1beta1.RecollectAnnotation, status.Trigger); requested {
+		*status = kokumetricscfgv1beta1.RecollectionStatus{Trigger: trigger}
+		start, end, err := parseRecollectRange(trigger, loc, time.Now())
+		if err != nil {
+			logger.Error(err, "invalid re-collection range", "trigger", trigger)
+			status.Error = err.Error()
+			status.Complete = true
+			return
+		}
+		status.Start = metav1.NewTime(start)
+		status.End = metav1.NewTime(end)
+		status.NextHour = metav1.NewTime(start)
+	}
+	if status.Trigger == "" || status.Complete {
+		return
+	}
+	// re-collecting a window is an explicit overwrite of the reports in the window index
+	recollectHours(r, kmCfg, dirCfg, status, true, logger.WithValues("KokuMetricsConfig", "recollectReports", "trigger", status.Trigger))
+}
+
+// HourTimeSeries returns the time series that the reports of the hour are collected over
+func HourTimeSeries(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, hour time.Time) promv1.Range {
+	return promv1.Range{
+		Start: hour,
+		End:   hour.Add(time.Hour - time.Second),
+		Step:  queryStep(kmCfg),
+	}
+}
+
+// recollectHours generates the reports for up to recollectHoursPerReconcile hours of the range of status, starting
+// at its next hour. When overwrite is false, the hours that are in the window index are skipped.
+func recollectHours(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, status *kokumetricscfgv1beta1.RecollectionStatus, overwrite bool, log logr.Logger) {
+	if r.promCollector == nil || !kmCfg.Status.Prometheus.PrometheusConnected {
+		log.Info("prometheus is not connected, re-collection will resume in the next reconcile")
+		return
+	}
+	recollectHoursFrom(r.promCollector, kmCfg, dirCfg, status, overwrite, log)
+}
+
+// recollectHoursFrom generates the reports of the hours of the range of status like recollectHours, with the queries
+// sent to the prometheus of c
+func recollectHoursFrom(c *collector.PromCollector, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, status *kokumetricscfgv1beta1.RecollectionStatus, overwrite bool, log logr.Logger) {
+	loc := BillingLocation(kmCfg)
+
+	// a copy of the collector is used so that the state of the regular collection is not modified
+	rc := *c
+	rc.Overwrite = overwrite
+	for i := 0; i < recollectHoursPerReconcile && status.NextHour.Before(&status.End); i++ {
+		hour := status.NextHour.In(loc)
+		timeSeries := HourTimeSeries(kmCfg, hour)
+		rc.TimeSeries = &timeSeries
+		periodStart, _ := collector.BillingPeriod(hour, rc.PeriodStartDay)
+		recollectDirCfg := &dirconfig.DirectoryConfig{
+			Parent:  dirCfg.Parent,
+			Reports: dirconfig.Directory{Path: filepath.Join(dirCfg.Reports.Path, dirconfig.RecollectDir, periodStart.Format("200601"))},
+			Staging: dirCfg.Staging,
+			Upload:  dirCfg.Upload,
+		}
+		log.Info("re-collecting reports", "start", rc.TimeSeries.Start, "end", rc.TimeSeries.End)
+		// the reports status describes the regular collection, so the historical hour is generated with a copy
+		hourCfg := kmCfg.DeepCopy()
+		err := collector.GenerateReports(hourCfg, recollectDirCfg, &rc)
+		if err != collector.ErrWindowCollected {
+			accountCollection(kmCfg, hour, hourCfg.Status.Reports.DataCollected, err, time.Now())
+			recordHistory(kmCfg, dirCfg, log, collectionHistory(historyRecollection, hourCfg, hour, err))
+		}
+		if err == collector.ErrWindowCollected {
+			log.Info("reports were already generated for the hour", "start", rc.TimeSeries.Start)
+		} else if err != nil {
+			log.Error(err, "failed to re-collect reports", "start", rc.TimeSeries.Start)
+			status.Error = fmt.Sprintf("failed to re-collect %s: %v", hour.Format(time.RFC3339), err)
+			return
+		} else if hourCfg.Status.Reports.DataCollected {
+			status.HoursCollected++
+		} else {
+			status.HoursWithoutData++
+		}
+		status.Error = ""
+		status.NextHour = metav1.NewTime(hour.Add(time.Hour))
+	}
+	if !status.NextHour.Before(&status.End) {
+		log.Info("re-collection complete", "hoursCollected", status.HoursCollected, "hoursWithoutData", status.HoursWithoutData)
+		status.Complete = true
+	}
+}
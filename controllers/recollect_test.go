@@ -0,0 +1,117 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestParseRecollectRange(t *testing.T) {
+	now := time.Date(2021, 1, 10, 12, 30, 0, 0, time.UTC)
+	parseRecollectRangeTests := []struct {
+		name      string
+		value     string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantError bool
+	}{
+		{
+			name:      "rfc3339 range",
+			value:     "2021-01-05T10:15:00Z/2021-01-05T14:00:00Z",
+			wantStart: time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2021, 1, 5, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "date range",
+			value:     "2021-01-05/2021-01-07",
+			wantStart: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2021, 1, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "end in the future",
+			value:     "2021-01-09/2021-01-12",
+			wantStart: time.Date(2021, 1, 9, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2021, 1, 10, 12, 0, 0, 0, time.UTC),
+		},
+		{name: "missing end", value: "2021-01-05", wantError: true},
+		{name: "invalid start", value: "yesterday/2021-01-07", wantError: true},
+		{name: "invalid end", value: "2021-01-05/tomorrow", wantError: true},
+		{name: "end before start", value: "2021-01-07/2021-01-05", wantError: true},
+		{name: "current hour", value: "2021-01-10T12:00:00Z/2021-01-10T13:00:00Z", wantError: true},
+		{name: "too long", value: "2020-01-01/2021-01-07", wantError: true},
+	}
+	for _, tt := range parseRecollectRangeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStart, gotEnd, err := parseRecollectRange(tt.value, time.UTC, now)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+			if !gotStart.Equal(tt.wantStart) || !gotEnd.Equal(tt.wantEnd) {
+				t.Errorf("%s got %v - %v want %v - %v", tt.name, gotStart, gotEnd, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestRecollectReportsStatus(t *testing.T) {
+	recollectReportsTests := []struct {
+		name         string
+		trigger      string
+		wantError    bool
+		wantComplete bool
+	}{
+		{name: "invalid range", trigger: "not-a-range", wantError: true, wantComplete: true},
+		{name: "prometheus not connected", trigger: "2021-01-05/2021-01-07", wantError: false, wantComplete: false},
+	}
+	for _, tt := range recollectReportsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{kokumetricscfgv1beta1.RecollectAnnotation: tt.trigger},
+				},
+			}
+			recollectReports(r, kmCfg, nil, r.Log)
+
+			got := kmCfg.Status.Recollection
+			if got.Trigger != tt.trigger {
+				t.Errorf("%s trigger not recorded: got %s want %s", tt.name, got.Trigger, tt.trigger)
+			}
+			if (got.Error != "") != tt.wantError {
+				t.Errorf("%s got error %q, expected error: %t", tt.name, got.Error, tt.wantError)
+			}
+			if got.Complete != tt.wantComplete {
+				t.Errorf("%s got complete %t want %t", tt.name, got.Complete, tt.wantComplete)
+			}
+			if !tt.wantError && !got.NextHour.Equal(&got.Start) {
+				t.Errorf("%s next hour %v should be the start %v", tt.name, got.NextHour, got.Start)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+// maxReplayFileStatuses is the number of replayed payloads whose result is written to the status
+const maxReplayFileStatuses = 50
+
+// replayFiles returns the names of the payloads in the retry directory, creating the directory if it does not exist
+func replayFiles(retryDir string) ([]string, error) {
+	if err := os.MkdirAll(retryDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("replayFiles: failed to create retry directory: %v", err)
+	}
+	files, err := ioutil.ReadDir(retryDir)
+	if err != nil {
+		return nil, fmt.Errorf("replayFiles: failed to read retry directory: %v", err)
+	}
+	names := []string{}
+	for _, file := range files {
+		if file.Mode().IsRegular() && strings.HasSuffix(file.Name(), ".tar.gz") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// recordReplayResults writes the results of the replayed payloads to the replay status. A payload is uploaded once
+// every destination has accepted it.
+func recordReplayResults(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, results []uploader.Result) {
+	files := kmCfg.Status.Replay.Files
+	for _, result := range results {
+		for i := range files {
+			if files[i].Name != result.File {
+				continue
+			}
+			if result.Err != nil {
+				files[i].Uploaded = false
+				files[i].Error = fmt.Sprintf("%s: %v", result.Exporter.Name(), result.Err)
+			} else if files[i].Error == "" {
+				files[i].Uploaded = true
+			}
+		}
+	}
+}
+
+// replayPayloads queues the payloads in the retry directory of the report volume for upload, such as payloads copied
+// back to the volume after support analysis. The payloads are queued each time the replay annotation changes, and the
+// result for each payload is written to the status as the queue uploads it. Uploaded payloads are removed, and payloads
+// that fail are kept for the next replay.
+func replayPayloads(r *KokuMetricsConfigReconciler, authConfig *crhchttp.AuthConfig, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, logger logr.Logger) {
+	trigger, requested := annotationTriggered(kmCfg, kokumetricscfgv1beta1.ReplayAnnotation, kmCfg.Status.Replay.Trigger)
+	if !requested {
+		return
+	}
+	log := logger.WithValues("KokuMetricsConfig", "replayPayloads", "trigger", trigger)
+	if uploader.DefaultQueue.Busy() {
+		// the trigger is not recorded, so the replay is queued once the batch in the queue is uploaded
+		log.Info("payloads are being uploaded, the replay is queued on a later reconcile")
+		return
+	}
+	log.Info("replaying payloads")
+
+	kmCfg.Status.Replay = kokumetricscfgv1beta1.ReplayStatus{
+		Trigger:     trigger,
+		LastRunTime: metav1.Now(),
+	}
+	exporters := buildExporters(r, kmCfg, authConfig, log)
+	if len(exporters) <= 0 {
+		kmCfg.Status.Replay.Error = "operator is configured to not upload reports"
+		return
+	}
+
+	retryDir := filepath.Join(dirCfg.Parent.Path, dirconfig.RetryDir)
+	files, err := replayFiles(retryDir)
+	if err != nil {
+		log.Error(err, "failed to list payloads to replay")
+		kmCfg.Status.Replay.Error = err.Error()
+		return
+	}
+	if len(files) <= 0 {
+		log.Info("no payloads to replay")
+		kmCfg.Status.Replay.Error = fmt.Sprintf("no payloads were found in the %s directory", dirconfig.RetryDir)
+		return
+	}
+
+	// replays keep their own queue state and upload index, and the payloads are uploaded even if the upload index of
+	// the report volume records them, since a replay is an explicit request to send them again
+	stateDir := filepath.Join(dirCfg.Parent.Path, dirconfig.ReplayStateDir)
+	if err := os.MkdirAll(stateDir, os.ModePerm); err != nil {
+		log.Error(err, "failed to create replay state directory")
+		kmCfg.Status.Replay.Error = err.Error()
+		return
+	}
+
+	for i, name := range files {
+		if i >= maxReplayFileStatuses {
+			break
+		}
+		payloadID, err := packaging.ReadPayloadID(filepath.Join(retryDir, name))
+		if err != nil {
+			log.Error(err, "failed to read payload id", "file", name)
+		}
+		kmCfg.Status.Replay.Files = append(kmCfg.Status.Replay.Files, kokumetricscfgv1beta1.ReplayFileStatus{Name: name, PayloadID: payloadID})
+	}
+	uploader.DefaultQueue.Submit(uploader.Batch{
+		UploadDir:   retryDir,
+		StateDir:    stateDir,
+		Exporters:   exporters,
+		Interval:    time.Duration(*kmCfg.Status.Upload.UploadInterval) * time.Second,
+		IgnoreIndex: true,
+		Log:         log,
+	})
+	kmCfg.Status.Upload.Queue.InProgress = true
+	log.Info("payloads queued for replay", "files", len(files))
+}
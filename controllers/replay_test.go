@@ -0,0 +1,205 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/testutils/fakes"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+func TestReplayPayloads(t *testing.T) {
+	replayTests := []struct {
+		name        string
+		trigger     string
+		lastTrigger string
+		files       []string
+		upload      bool
+		uploadErr   error
+		busy        bool
+		wantFiles   []kokumetricscfgv1beta1.ReplayFileStatus
+		wantError   string
+		wantRemain  []string
+	}{
+		{name: "no annotation", files: []string{"a.tar.gz"}, upload: true, wantRemain: []string{"a.tar.gz"}},
+		{name: "annotation already processed", trigger: "1", lastTrigger: "1", files: []string{"a.tar.gz"}, upload: true, wantRemain: []string{"a.tar.gz"}},
+		{
+			name:    "payloads are uploaded and removed",
+			trigger: "1",
+			files:   []string{"b.tar.gz", "a.tar.gz", "notes.txt"},
+			upload:  true,
+			wantFiles: []kokumetricscfgv1beta1.ReplayFileStatus{
+				{Name: "a.tar.gz", Uploaded: true},
+				{Name: "b.tar.gz", Uploaded: true},
+			},
+			wantRemain: []string{"notes.txt"},
+		},
+		{
+			name:      "failed payloads are kept",
+			trigger:   "2",
+			files:     []string{"a.tar.gz"},
+			upload:    true,
+			uploadErr: errors.New("connection refused"),
+			wantFiles: []kokumetricscfgv1beta1.ReplayFileStatus{
+				{Name: "a.tar.gz", Error: "ingress: connection refused"},
+			},
+			wantRemain: []string{"a.tar.gz"},
+		},
+		{name: "queue busy", trigger: "1", files: []string{"a.tar.gz"}, upload: true, busy: true, wantRemain: []string{"a.tar.gz"}},
+		{name: "no payloads", trigger: "1", upload: true, wantError: "no payloads were found in the retry directory"},
+		{name: "upload disabled", trigger: "1", files: []string{"a.tar.gz"}, wantError: "operator is configured to not upload reports", wantRemain: []string{"a.tar.gz"}},
+	}
+	for _, tt := range replayTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "replay")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			retryDir := filepath.Join(dir, dirconfig.RetryDir)
+			if err := os.MkdirAll(retryDir, os.ModePerm); err != nil {
+				t.Fatalf("failed to create retry dir: %v", err)
+			}
+			for _, name := range tt.files {
+				if err := ioutil.WriteFile(filepath.Join(retryDir, name), []byte(name), 0644); err != nil {
+					t.Fatalf("failed to write payload: %v", err)
+				}
+			}
+
+			fakeUploader := &fakes.Uploader{Err: tt.uploadErr}
+			r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Uploader: fakeUploader}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			if tt.trigger != "" {
+				kmCfg.SetAnnotations(map[string]string{kokumetricscfgv1beta1.ReplayAnnotation: tt.trigger})
+			}
+			kmCfg.Status.Replay.Trigger = tt.lastTrigger
+			uploadInterval := int64(0)
+			kmCfg.Status.Upload.UploadInterval = &uploadInterval
+			var authConfig *crhchttp.AuthConfig
+			if tt.upload {
+				authConfig = &crhchttp.AuthConfig{Log: testutils.TestLogger{}}
+			}
+			dirCfg := &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: dir}}
+
+			if tt.busy {
+				uploader.DefaultQueue.Submit(uploader.Batch{UploadDir: dir, StateDir: dir, Log: r.Log})
+			}
+
+			replayPayloads(r, authConfig, kmCfg, dirCfg, r.Log)
+			if tt.busy {
+				uploader.DefaultQueue.Cancel()
+			}
+			uploader.DefaultQueue.Drain(make(chan struct{}))
+			recordReplayResults(kmCfg, uploader.DefaultQueue.Results())
+
+			got := kmCfg.Status.Replay
+			wantTrigger := tt.trigger
+			if tt.busy {
+				wantTrigger = tt.lastTrigger
+			}
+			if got.Trigger != wantTrigger {
+				t.Errorf("%s got trigger %q want %q", tt.name, got.Trigger, wantTrigger)
+			}
+			if got.Error != tt.wantError {
+				t.Errorf("%s got error %q want %q", tt.name, got.Error, tt.wantError)
+			}
+			if len(got.Files) != len(tt.wantFiles) {
+				t.Fatalf("%s got files %+v want %+v", tt.name, got.Files, tt.wantFiles)
+			}
+			for i, want := range tt.wantFiles {
+				if got.Files[i] != want {
+					t.Errorf("%s got file %+v want %+v", tt.name, got.Files[i], want)
+				}
+			}
+			if wantTrigger != tt.lastTrigger && got.LastRunTime.IsZero() {
+				t.Errorf("%s last run time was not set", tt.name)
+			}
+			if len(fakeUploader.Uploads()) != len(tt.wantFiles) {
+				t.Errorf("%s got %d uploads want %d", tt.name, len(fakeUploader.Uploads()), len(tt.wantFiles))
+			}
+
+			remain, err := ioutil.ReadDir(retryDir)
+			if err != nil {
+				t.Fatalf("failed to read retry dir: %v", err)
+			}
+			if len(remain) != len(tt.wantRemain) {
+				t.Fatalf("%s got %d remaining files want %v", tt.name, len(remain), tt.wantRemain)
+			}
+			for i, file := range remain {
+				if file.Name() != tt.wantRemain[i] {
+					t.Errorf("%s got remaining file %s want %s", tt.name, file.Name(), tt.wantRemain[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReplayPayloadsCapsFileStatuses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	retryDir := filepath.Join(dir, dirconfig.RetryDir)
+	if err := os.MkdirAll(retryDir, os.ModePerm); err != nil {
+		t.Fatalf("failed to create retry dir: %v", err)
+	}
+	for i := 0; i < maxReplayFileStatuses+5; i++ {
+		name := fmt.Sprintf("payload-%03d.tar.gz", i)
+		if err := ioutil.WriteFile(filepath.Join(retryDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write payload: %v", err)
+		}
+	}
+
+	fakeUploader := &fakes.Uploader{}
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Uploader: fakeUploader}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.SetAnnotations(map[string]string{kokumetricscfgv1beta1.ReplayAnnotation: "1"})
+	uploadInterval := int64(0)
+	kmCfg.Status.Upload.UploadInterval = &uploadInterval
+	authConfig := &crhchttp.AuthConfig{Log: testutils.TestLogger{}}
+	dirCfg := &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: dir}}
+
+	replayPayloads(r, authConfig, kmCfg, dirCfg, r.Log)
+	uploader.DefaultQueue.Drain(make(chan struct{}))
+	recordReplayResults(kmCfg, uploader.DefaultQueue.Results())
+
+	if got := len(kmCfg.Status.Replay.Files); got != maxReplayFileStatuses {
+		t.Errorf("got %d file statuses want %d", got, maxReplayFileStatuses)
+	}
+	if got := len(fakeUploader.Uploads()); got != maxReplayFileStatuses+5 {
+		t.Errorf("got %d uploads want %d", got, maxReplayFileStatuses+5)
+	}
+	for _, file := range kmCfg.Status.Replay.Files {
+		if !file.Uploaded {
+			t.Errorf("got file %+v want uploaded", file)
+		}
+	}
+}
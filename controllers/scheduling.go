@@ -0,0 +1,147 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// defaultTerminationGracePeriodSeconds is the termination grace period of the operator Deployment in its manifest. It
+// is restored when the termination grace period set by the operator is removed from the spec.
+const defaultTerminationGracePeriodSeconds = int64(10)
+
+// patchOperatorPod changes the pod template of the operator Deployment. When the operator was installed by OLM, the
+// Deployment is changed through the ClusterServiceVersion, which OLM would otherwise revert the Deployment to.
+func patchOperatorPod(r *KokuMetricsConfigReconciler, dep *appsv1.Deployment, change func(*corev1.PodSpec)) error {
+	ctx := context.Background()
+	var obj runtime.Object
+	var patch client.Patch
+	kind := "Deployment"
+	if len(dep.OwnerReferences) > 0 && dep.OwnerReferences[0].Kind == operatorsv1alpha1.ClusterServiceVersionKind {
+		csv := &operatorsv1alpha1.ClusterServiceVersion{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: dep.Namespace, Name: dep.OwnerReferences[0].Name}, csv); err != nil {
+			return fmt.Errorf("failed to get ClusterServiceVersion: %v", err)
+		}
+		patch = client.MergeFrom(csv.DeepCopy())
+		found := false
+		specs := csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs
+		for i := range specs {
+			if specs[i].Name == dep.Name {
+				change(&specs[i].Spec.Template.Spec)
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("the ClusterServiceVersion %s has no Deployment %s", csv.Name, dep.Name)
+		}
+		obj = csv
+		kind = "ClusterServiceVersion"
+	} else {
+		patch = client.MergeFrom(dep.DeepCopy())
+		change(&dep.Spec.Template.Spec)
+		obj = dep
+	}
+	if err := r.Patch(ctx, obj, patch); err != nil {
+		return fmt.Errorf("failed to patch %s: %v", kind, err)
+	}
+	return nil
+}
+
+// setPriorityClass sets the priority class of the operator pod. A priority class that was set by the operator is
+// removed when it is removed from the spec.
+func setPriorityClass(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dep *appsv1.Deployment) error {
+	desired := kmCfg.Spec.Scheduling.PriorityClassName
+	current := dep.Spec.Template.Spec.PriorityClassName
+	if desired == "" && current != kmCfg.Status.Scheduling.PriorityClassName {
+		// the priority class was not set by the operator
+		kmCfg.Status.Scheduling.PriorityClassName = ""
+		return nil
+	}
+	if current == desired {
+		kmCfg.Status.Scheduling.PriorityClassName = desired
+		return nil
+	}
+
+	r.Log.Info("setting the priority class of the operator pod", "priorityClassName", desired)
+	if err := patchOperatorPod(r, dep, func(pod *corev1.PodSpec) { pod.PriorityClassName = desired }); err != nil {
+		return fmt.Errorf("setPriorityClass: %v", err)
+	}
+	kmCfg.Status.Scheduling.PriorityClassName = desired
+	return nil
+}
+
+// setTerminationGracePeriod sets the termination grace period of the operator pod, which is how long the operator
+// has to finish the reconcile in progress when the pod is stopped. A grace period that was set by the operator is
+// reset to the default of the manifest when it is removed from the spec.
+func setTerminationGracePeriod(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dep *appsv1.Deployment) error {
+	desired := kmCfg.Spec.Scheduling.TerminationGracePeriodSeconds
+	applied := kmCfg.Status.Scheduling.TerminationGracePeriodSeconds
+	current := dep.Spec.Template.Spec.TerminationGracePeriodSeconds
+	if desired == nil {
+		kmCfg.Status.Scheduling.TerminationGracePeriodSeconds = nil
+		if applied == nil || current == nil || *current != *applied {
+			// the grace period was not set by the operator
+			return nil
+		}
+		reset := defaultTerminationGracePeriodSeconds
+		desired = &reset
+	} else if current != nil && *current == *desired {
+		kmCfg.Status.Scheduling.TerminationGracePeriodSeconds = desired
+		return nil
+	}
+
+	seconds := *desired
+	r.Log.Info("setting the termination grace period of the operator pod", "seconds", seconds)
+	if err := patchOperatorPod(r, dep, func(pod *corev1.PodSpec) { pod.TerminationGracePeriodSeconds = &seconds }); err != nil {
+		return fmt.Errorf("setTerminationGracePeriod: %v", err)
+	}
+	kmCfg.Status.Scheduling.TerminationGracePeriodSeconds = kmCfg.Spec.Scheduling.TerminationGracePeriodSeconds
+	return nil
+}
+
+// reconcileScheduling sets the priority class and the termination grace period of the operator pod. The Deployment
+// is only changed when they differ from the spec. Errors are written to the status, and do not stop the reconcile.
+func reconcileScheduling(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	kmCfg.Status.Scheduling.Error = ""
+	dep := &appsv1.Deployment{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: kmCfg.Namespace, Name: operatorDeploymentName}, dep); err != nil {
+		r.Log.Error(err, "failed to get the operator Deployment")
+		kmCfg.Status.Scheduling.Error = fmt.Sprintf("failed to get the operator Deployment: %v", err)
+		return
+	}
+	if err := setPriorityClass(r, kmCfg, dep); err != nil {
+		r.Log.Error(err, "failed to set the priority class of the operator pod")
+		kmCfg.Status.Scheduling.Error = err.Error()
+	}
+	if err := setTerminationGracePeriod(r, kmCfg, dep); err != nil {
+		r.Log.Error(err, "failed to set the termination grace period of the operator pod")
+		kmCfg.Status.Scheduling.Error = err.Error()
+	}
+}
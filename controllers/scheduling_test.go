@@ -0,0 +1,152 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func schedulingReconciler(t *testing.T, objs ...runtime.Object) *KokuMetricsConfigReconciler {
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{kokumetricscfgv1beta1.AddToScheme, appsv1.AddToScheme, operatorsv1alpha1.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatalf("failed to build scheme: %v", err)
+		}
+	}
+	return &KokuMetricsConfigReconciler{Client: fake.NewFakeClientWithScheme(s, objs...), Scheme: s, Log: testutils.TestLogger{}}
+}
+
+func selectedOperatorDeployment() *appsv1.Deployment {
+	dep := operatorDeployment()
+	dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"control-plane": "controller-manager"}}
+	return dep
+}
+
+func TestReconcileScheduling(t *testing.T) {
+	ctx := context.Background()
+	grace := int64(600)
+	kmCfg := cronJobConfig()
+	kmCfg.Spec.Scheduling = kokumetricscfgv1beta1.SchedulingSpec{PriorityClassName: "system-cluster-critical", TerminationGracePeriodSeconds: &grace}
+	r := schedulingReconciler(t, kmCfg.DeepCopy(), selectedOperatorDeployment())
+	depKey := types.NamespacedName{Namespace: kmCfg.Namespace, Name: operatorDeploymentName}
+
+	// the priority class and the termination grace period are set
+	reconcileScheduling(r, kmCfg)
+	if kmCfg.Status.Scheduling.Error != "" {
+		t.Fatalf("got scheduling error %s", kmCfg.Status.Scheduling.Error)
+	}
+	dep := &appsv1.Deployment{}
+	if err := r.Get(ctx, depKey, dep); err != nil {
+		t.Fatalf("failed to get Deployment: %v", err)
+	}
+	if got := dep.Spec.Template.Spec.PriorityClassName; got != "system-cluster-critical" || kmCfg.Status.Scheduling.PriorityClassName != got {
+		t.Errorf("got priority class %q and status %+v want system-cluster-critical", got, kmCfg.Status.Scheduling)
+	}
+	got := dep.Spec.Template.Spec.TerminationGracePeriodSeconds
+	if got == nil || *got != grace || kmCfg.Status.Scheduling.TerminationGracePeriodSeconds == nil || *kmCfg.Status.Scheduling.TerminationGracePeriodSeconds != grace {
+		t.Errorf("got termination grace period %v and status %+v want %d", got, kmCfg.Status.Scheduling, grace)
+	}
+
+	// the Deployment is not changed when it matches the spec
+	version := dep.ResourceVersion
+	reconcileScheduling(r, kmCfg)
+	if err := r.Get(ctx, depKey, dep); err != nil {
+		t.Fatalf("failed to get Deployment: %v", err)
+	}
+	if dep.ResourceVersion != version {
+		t.Errorf("got Deployment resource version %s want %s", dep.ResourceVersion, version)
+	}
+
+	// the priority class and the termination grace period are removed with the spec
+	kmCfg.Spec.Scheduling = kokumetricscfgv1beta1.SchedulingSpec{}
+	reconcileScheduling(r, kmCfg)
+	dep = &appsv1.Deployment{}
+	if err := r.Get(ctx, depKey, dep); err != nil {
+		t.Fatalf("failed to get Deployment: %v", err)
+	}
+	if dep.Spec.Template.Spec.PriorityClassName != "" || kmCfg.Status.Scheduling != (kokumetricscfgv1beta1.SchedulingStatus{}) {
+		t.Errorf("got priority class %q and status %+v want them removed", dep.Spec.Template.Spec.PriorityClassName, kmCfg.Status.Scheduling)
+	}
+	got = dep.Spec.Template.Spec.TerminationGracePeriodSeconds
+	if got == nil || *got != defaultTerminationGracePeriodSeconds {
+		t.Errorf("got termination grace period %v want %d", got, defaultTerminationGracePeriodSeconds)
+	}
+}
+
+func TestSetPriorityClass(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		current string
+		applied string
+		desired string
+		owned   bool
+		want    string
+	}{
+		{name: "priority class is set", desired: "high", want: "high"},
+		{name: "priority class is set through the ClusterServiceVersion", desired: "high", owned: true, want: "high"},
+		{name: "priority class set by the operator is removed", current: "high", applied: "high", want: ""},
+		{name: "priority class not set by the operator is kept", current: "other", want: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := cronJobConfig()
+			kmCfg.Spec.Scheduling.PriorityClassName = tt.desired
+			kmCfg.Status.Scheduling.PriorityClassName = tt.applied
+			dep := selectedOperatorDeployment()
+			dep.Spec.Template.Spec.PriorityClassName = tt.current
+			csv := &operatorsv1alpha1.ClusterServiceVersion{ObjectMeta: metav1.ObjectMeta{Namespace: dep.Namespace, Name: "koku-metrics-operator.v1"}}
+			csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = []operatorsv1alpha1.StrategyDeploymentSpec{{Name: dep.Name, Spec: dep.Spec}}
+			if tt.owned {
+				dep.OwnerReferences = []metav1.OwnerReference{{Kind: operatorsv1alpha1.ClusterServiceVersionKind, Name: csv.Name}}
+			}
+			r := schedulingReconciler(t, dep.DeepCopy(), csv)
+
+			if err := setPriorityClass(r, kmCfg, dep); err != nil {
+				t.Fatalf("%s failed to set priority class: %v", tt.name, err)
+			}
+			got := &appsv1.Deployment{}
+			if err := r.Get(ctx, types.NamespacedName{Namespace: dep.Namespace, Name: dep.Name}, got); err != nil {
+				t.Fatalf("failed to get Deployment: %v", err)
+			}
+			gotClass := got.Spec.Template.Spec.PriorityClassName
+			if tt.owned {
+				if err := r.Get(ctx, types.NamespacedName{Namespace: csv.Namespace, Name: csv.Name}, csv); err != nil {
+					t.Fatalf("failed to get ClusterServiceVersion: %v", err)
+				}
+				gotClass = csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs[0].Spec.Template.Spec.PriorityClassName
+			}
+			if gotClass != tt.want {
+				t.Errorf("%s got priority class %q want %q", tt.name, gotClass, tt.want)
+			}
+		})
+	}
+}
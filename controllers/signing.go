@@ -0,0 +1,64 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+)
+
+// signingKeySecretKey is the key of the private key in the signing key secret
+const signingKeySecretKey = "private_key"
+
+// getSigningKey returns the key that payloads are signed with, or nil if payloads are not signed. The fingerprint of
+// the public key is written to the status.
+func getSigningKey(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) (ed25519.PrivateKey, error) {
+	kmCfg.Status.Packaging.SigningKeyFingerprint = ""
+	secretName := kmCfg.Spec.Packaging.SigningKeySecretName
+	if secretName == "" {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: secretName}
+	if err := r.getSecret(context.Background(), key, secret); err != nil {
+		return nil, fmt.Errorf("getSigningKey: failed to get secret %s: %v", secretName, err)
+	}
+	data, ok := secret.Data[signingKeySecretKey]
+	if !ok {
+		return nil, fmt.Errorf("getSigningKey: secret %s does not contain %s", secretName, signingKeySecretKey)
+	}
+	signingKey, err := packaging.ParseSigningKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("getSigningKey: secret %s: %v", secretName, err)
+	}
+	fingerprint, err := packaging.KeyFingerprint(signingKey)
+	if err != nil {
+		return nil, fmt.Errorf("getSigningKey: %v", err)
+	}
+	kmCfg.Status.Packaging.SigningKeyFingerprint = fingerprint
+	return signingKey, nil
+}
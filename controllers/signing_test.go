@@ -0,0 +1,87 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestGetSigningKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	fingerprint, _ := packaging.KeyFingerprint(key)
+
+	signingKeyTests := []struct {
+		name            string
+		secretName      string
+		data            map[string][]byte
+		wantKey         bool
+		wantFingerprint string
+		wantErr         bool
+	}{
+		{name: "signing not configured"},
+		{name: "valid key", secretName: "signing-key", data: map[string][]byte{signingKeySecretKey: keyPEM}, wantKey: true, wantFingerprint: fingerprint},
+		{name: "missing secret", secretName: "missing", wantErr: true},
+		{name: "missing key", secretName: "signing-key", data: map[string][]byte{"other": keyPEM}, wantErr: true},
+		{name: "invalid key", secretName: "signing-key", data: map[string][]byte{signingKeySecretKey: []byte("invalid")}, wantErr: true},
+	}
+	for _, tt := range signingKeyTests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "signing-key"},
+				Data:       tt.data,
+			}
+			r := &KokuMetricsConfigReconciler{Client: fake.NewFakeClient(secret), Log: testutils.TestLogger{}}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator"}}
+			kmCfg.Spec.Packaging.SigningKeySecretName = tt.secretName
+			kmCfg.Status.Packaging.SigningKeyFingerprint = "stale"
+
+			got, err := getSigningKey(r, kmCfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			if (got != nil) != tt.wantKey {
+				t.Errorf("%s got key %t want key %t", tt.name, got != nil, tt.wantKey)
+			}
+			if kmCfg.Status.Packaging.SigningKeyFingerprint != tt.wantFingerprint {
+				t.Errorf("%s got fingerprint %q want %q", tt.name, kmCfg.Status.Packaging.SigningKeyFingerprint, tt.wantFingerprint)
+			}
+		})
+	}
+}
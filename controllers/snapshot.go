@@ -0,0 +1,83 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// snapshotCollector returns the collector that queries the snapshot prometheus. It is a variable so that it can be
+// replaced in tests.
+var snapshotCollector = func(c *collector.PromCollector, address string, skipTLS bool) (*collector.PromCollector, error) {
+	return c.SnapshotCollector(address, skipTLS)
+}
+
+// recollectSnapshot re-collects the range of the snapshot spec from the snapshot prometheus. A new re-collection
+// starts each time the address or the range changes, and up to recollectHoursPerReconcile hours are re-collected in
+// each reconcile. The reports of the range are overwritten, since the hours were usually collected without data.
+func recollectSnapshot(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, logger logr.Logger) {
+	spec := kmCfg.Spec.PrometheusConfig.Snapshot
+	status := &kmCfg.Status.Snapshot
+	if spec.ServiceAddress == "" || spec.Range == "" {
+		return
+	}
+	log := logger.WithValues("KokuMetricsConfig", "recollectSnapshot", "snapshot", spec.ServiceAddress, "range", spec.Range)
+
+	if status.ServiceAddress != spec.ServiceAddress || status.Recollection.Trigger != spec.Range {
+		*status = kokumetricscfgv1beta1.SnapshotStatus{
+			ServiceAddress: spec.ServiceAddress,
+			Recollection:   kokumetricscfgv1beta1.RecollectionStatus{Trigger: spec.Range},
+		}
+		start, end, err := parseRecollectRange(spec.Range, BillingLocation(kmCfg), time.Now())
+		if err != nil {
+			log.Error(err, "invalid snapshot range")
+			status.Recollection.Error = err.Error()
+			status.Recollection.Complete = true
+			return
+		}
+		status.Recollection.Start = metav1.NewTime(start)
+		status.Recollection.End = metav1.NewTime(end)
+		status.Recollection.NextHour = metav1.NewTime(start)
+		log.Info("re-collecting from the snapshot prometheus", "start", start, "end", end)
+	}
+	if status.Recollection.Complete {
+		return
+	}
+	if r.promCollector == nil {
+		log.Info("the prometheus collector is not configured, re-collection will resume in the next reconcile")
+		return
+	}
+
+	skipTLS := spec.SkipTLSVerification != nil && *spec.SkipTLSVerification
+	c, err := snapshotCollector(r.promCollector, spec.ServiceAddress, skipTLS)
+	if err != nil {
+		log.Error(err, "failed to connect to the snapshot prometheus, re-collection will resume in the next reconcile")
+		status.Recollection.Error = err.Error()
+		return
+	}
+	recollectHoursFrom(c, kmCfg, dirCfg, &status.Recollection, true, log)
+}
@@ -0,0 +1,129 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestRecollectSnapshot(t *testing.T) {
+	tmpSnapshot := snapshotCollector
+	defer func() { snapshotCollector = tmpSnapshot }()
+	var gotAddress string
+	var gotSkipTLS bool
+	snapshotCollector = func(c *collector.PromCollector, address string, skipTLS bool) (*collector.PromCollector, error) {
+		gotAddress, gotSkipTLS = address, skipTLS
+		return nil, errors.New("snapshot is unavailable")
+	}
+	skipTLS := true
+	next := metav1.NewTime(time.Date(2021, 1, 6, 0, 0, 0, 0, time.UTC))
+
+	snapshotTests := []struct {
+		name         string
+		spec         kokumetricscfgv1beta1.SnapshotSpec
+		status       kokumetricscfgv1beta1.SnapshotStatus
+		wantAddress  string
+		wantError    bool
+		wantComplete bool
+		wantNextHour time.Time
+	}{
+		{name: "not configured"},
+		{
+			name:         "invalid range",
+			spec:         kokumetricscfgv1beta1.SnapshotSpec{ServiceAddress: "https://snapshot", Range: "not-a-range"},
+			wantError:    true,
+			wantComplete: true,
+		},
+		{
+			name:         "new range",
+			spec:         kokumetricscfgv1beta1.SnapshotSpec{ServiceAddress: "https://snapshot", Range: "2021-01-05/2021-01-07", SkipTLSVerification: &skipTLS},
+			wantAddress:  "https://snapshot",
+			wantError:    true,
+			wantNextHour: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "range in progress",
+			spec: kokumetricscfgv1beta1.SnapshotSpec{ServiceAddress: "https://snapshot", Range: "2021-01-05/2021-01-07"},
+			status: kokumetricscfgv1beta1.SnapshotStatus{
+				ServiceAddress: "https://snapshot",
+				Recollection:   kokumetricscfgv1beta1.RecollectionStatus{Trigger: "2021-01-05/2021-01-07", NextHour: next},
+			},
+			wantAddress:  "https://snapshot",
+			wantError:    true,
+			wantNextHour: next.Time,
+		},
+		{
+			name: "range complete",
+			spec: kokumetricscfgv1beta1.SnapshotSpec{ServiceAddress: "https://snapshot", Range: "2021-01-05/2021-01-07"},
+			status: kokumetricscfgv1beta1.SnapshotStatus{
+				ServiceAddress: "https://snapshot",
+				Recollection:   kokumetricscfgv1beta1.RecollectionStatus{Trigger: "2021-01-05/2021-01-07", NextHour: next, Complete: true},
+			},
+			wantComplete: true,
+			wantNextHour: next.Time,
+		},
+		{
+			name: "new address",
+			spec: kokumetricscfgv1beta1.SnapshotSpec{ServiceAddress: "https://restored", Range: "2021-01-05/2021-01-07"},
+			status: kokumetricscfgv1beta1.SnapshotStatus{
+				ServiceAddress: "https://snapshot",
+				Recollection:   kokumetricscfgv1beta1.RecollectionStatus{Trigger: "2021-01-05/2021-01-07", NextHour: next, Complete: true},
+			},
+			wantAddress:  "https://restored",
+			wantError:    true,
+			wantNextHour: time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range snapshotTests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAddress, gotSkipTLS = "", false
+			r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, promCollector: &collector.PromCollector{}}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.PrometheusConfig.Snapshot = tt.spec
+			kmCfg.Status.Snapshot = tt.status
+			recollectSnapshot(r, kmCfg, nil, r.Log)
+
+			got := kmCfg.Status.Snapshot.Recollection
+			if gotAddress != tt.wantAddress {
+				t.Errorf("%s got snapshot address %q want %q", tt.name, gotAddress, tt.wantAddress)
+			}
+			if tt.spec.SkipTLSVerification != nil && gotSkipTLS != *tt.spec.SkipTLSVerification {
+				t.Errorf("%s got skip TLS verification %t want %t", tt.name, gotSkipTLS, *tt.spec.SkipTLSVerification)
+			}
+			if (got.Error != "") != tt.wantError {
+				t.Errorf("%s got error %q, expected error: %t", tt.name, got.Error, tt.wantError)
+			}
+			if got.Complete != tt.wantComplete {
+				t.Errorf("%s got complete %t want %t", tt.name, got.Complete, tt.wantComplete)
+			}
+			if !got.NextHour.Time.Equal(tt.wantNextHour) {
+				t.Errorf("%s got next hour %v want %v", tt.name, got.NextHour.Time, tt.wantNextHour)
+			}
+		})
+	}
+}
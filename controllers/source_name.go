@@ -0,0 +1,47 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/sources"
+)
+
+// setDefaultSourceName uses the display name of the cluster in OpenShift Cluster Manager as the source name when
+// the source name is not set in the spec
+func setDefaultSourceName(r *KokuMetricsConfigReconciler, sSpec *sources.SourceSpec, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	if kmCfg.Spec.Source.SourceName != "" {
+		return
+	}
+
+	log := r.Log.WithValues("KokuMetricsConfig", "setDefaultSourceName")
+	if kmCfg.Status.Source.ClusterDisplayName == "" {
+		name, err := r.sourcesClient().GetClusterDisplayName(sSpec)
+		if err != nil {
+			// the lookup is retried on the next reconcile
+			log.Info("failed to obtain the cluster display name", "error", err)
+			return
+		}
+		kmCfg.Status.Source.ClusterDisplayName = name
+	}
+
+	kmCfg.Status.Source.SourceName = kmCfg.Status.Source.ClusterDisplayName
+	sSpec.Spec.SourceName = kmCfg.Status.Source.ClusterDisplayName
+}
@@ -0,0 +1,89 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/sources"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/testutils/fakes"
+)
+
+func TestSetDefaultSourceName(t *testing.T) {
+	setDefaultSourceNameTests := []struct {
+		name         string
+		specName     string
+		cachedName   string
+		client       *fakes.SourcesClient
+		wantName     string
+		wantRequests int
+	}{
+		{
+			name:         "display name is the default source name",
+			client:       &fakes.SourcesClient{DisplayName: "my-cluster"},
+			wantName:     "my-cluster",
+			wantRequests: 1,
+		},
+		{
+			name:         "spec source name overrides the display name",
+			specName:     "my-source",
+			client:       &fakes.SourcesClient{DisplayName: "my-cluster"},
+			wantName:     "my-source",
+			wantRequests: 0,
+		},
+		{
+			name:         "display name is only looked up once",
+			cachedName:   "my-cluster",
+			client:       &fakes.SourcesClient{DisplayName: "renamed-cluster"},
+			wantName:     "my-cluster",
+			wantRequests: 0,
+		},
+		{
+			name:         "failed lookup leaves the source name unset",
+			client:       &fakes.SourcesClient{Err: errors.New("lookup failed")},
+			wantName:     "",
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range setDefaultSourceNameTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, SourcesClient: tt.client}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Source.SourceName = tt.specName
+			kmCfg.Status.Source.SourceName = tt.specName
+			kmCfg.Status.Source.ClusterDisplayName = tt.cachedName
+			sSpec := &sources.SourceSpec{Spec: kmCfg.Status.Source, Log: testutils.TestLogger{}}
+
+			setDefaultSourceName(r, sSpec, kmCfg)
+			if kmCfg.Status.Source.SourceName != tt.wantName {
+				t.Errorf("%s got status source name %q want %q", tt.name, kmCfg.Status.Source.SourceName, tt.wantName)
+			}
+			if sSpec.Spec.SourceName != tt.wantName {
+				t.Errorf("%s got source spec name %q want %q", tt.name, sSpec.Spec.SourceName, tt.wantName)
+			}
+			if got := len(tt.client.Requests()); got != tt.wantRequests {
+				t.Errorf("%s got %d requests want %d", tt.name, got, tt.wantRequests)
+			}
+		})
+	}
+}
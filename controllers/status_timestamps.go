@@ -0,0 +1,87 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// statusTimestamp is a status timestamp that drives a cycle check
+type statusTimestamp struct {
+	name string
+	time *metav1.Time
+}
+
+// cycleTimestamps returns the status timestamps that the cycle checks compare to the current time
+func cycleTimestamps(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) []statusTimestamp {
+	timestamps := []statusTimestamp{
+		{name: "upload.last_successful_upload_time", time: &kmCfg.Status.Upload.LastSuccessfulUploadTime},
+		{name: "packaging.last_successful_packaging_time", time: &kmCfg.Status.Packaging.LastSuccessfulPackagingTime},
+		{name: "source.last_check_time", time: &kmCfg.Status.Source.LastSourceCheckTime},
+		{name: "prometheus.last_query_start_time", time: &kmCfg.Status.Prometheus.LastQueryStartTime},
+		{name: "prometheus.last_query_success_time", time: &kmCfg.Status.Prometheus.LastQuerySuccessTime},
+	}
+	for i := range kmCfg.Status.Upload.Destinations {
+		dest := &kmCfg.Status.Upload.Destinations[i]
+		timestamps = append(timestamps, statusTimestamp{
+			name: fmt.Sprintf("upload.destinations[%s].last_export_time", dest.Name),
+			time: &dest.LastExportTime,
+		})
+	}
+	return timestamps
+}
+
+// repairStatusTimestamps validates the status timestamps after a restore of the cluster, which can bring back a status
+// written by a different clock. A timestamp further in the future than the clock skew threshold would stop its cycle
+// from running until that time, so it is cleared and the cycle runs on this reconcile. Old timestamps only make their
+// cycle run right away, so they are left alone. The names of the repaired timestamps are returned.
+func repairStatusTimestamps(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, now time.Time) []string {
+	future := now.Add(time.Duration(kokumetricscfgv1beta1.DefaultClockSkewThreshold) * time.Second)
+
+	var repaired []string
+	for _, ts := range cycleTimestamps(kmCfg) {
+		if ts.time.IsZero() || !ts.time.After(future) {
+			continue
+		}
+		repaired = append(repaired, fmt.Sprintf("%s %s is in the future", ts.name, ts.time.UTC().Format(time.RFC3339)))
+		*ts.time = metav1.Time{}
+	}
+	return repaired
+}
+
+// reflectStatusTimestamps repairs the status timestamps and records an event when a timestamp was repaired
+func reflectStatusTimestamps(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	repaired := repairStatusTimestamps(kmCfg, time.Now())
+	if len(repaired) == 0 {
+		return
+	}
+	msg := "repaired status timestamps: " + strings.Join(repaired, ", ")
+	r.Log.Info(msg)
+	if r.Recorder != nil {
+		r.Recorder.Event(kmCfg, corev1.EventTypeWarning, kokumetricscfgv1beta1.ReasonStatusTimestampRepaired, msg)
+	}
+}
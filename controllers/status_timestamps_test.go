@@ -0,0 +1,87 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestRepairStatusTimestamps(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	repairTests := []struct {
+		name         string
+		time         metav1.Time
+		want         metav1.Time
+		wantRepaired bool
+	}{
+		{name: "unset timestamp", time: metav1.Time{}, want: metav1.Time{}},
+		{name: "recent timestamp", time: metav1.NewTime(now.Add(-time.Hour)), want: metav1.NewTime(now.Add(-time.Hour))},
+		{name: "timestamp within the clock skew threshold", time: metav1.NewTime(now.Add(time.Minute)), want: metav1.NewTime(now.Add(time.Minute))},
+		{name: "future timestamp is cleared", time: metav1.NewTime(now.AddDate(1, 0, 0)), want: metav1.Time{}, wantRepaired: true},
+		{name: "ancient timestamp is left alone", time: metav1.NewTime(now.AddDate(-3, 0, 0)), want: metav1.NewTime(now.AddDate(-3, 0, 0))},
+	}
+	for _, tt := range repairTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Status.Upload.LastSuccessfulUploadTime = tt.time
+			kmCfg.Status.Upload.Destinations = []kokumetricscfgv1beta1.DestinationStatus{{Name: "s3", LastExportTime: tt.time}}
+			repaired := repairStatusTimestamps(kmCfg, now)
+			if got := len(repaired) > 0; got != tt.wantRepaired {
+				t.Errorf("%s got repaired %v want %v", tt.name, repaired, tt.wantRepaired)
+			}
+			if tt.wantRepaired && len(repaired) != 2 {
+				t.Errorf("%s got %d repaired timestamps want 2: %v", tt.name, len(repaired), repaired)
+			}
+			if got := kmCfg.Status.Upload.LastSuccessfulUploadTime; !got.Equal(&tt.want) {
+				t.Errorf("%s got upload time %v want %v", tt.name, got, tt.want)
+			}
+			if got := kmCfg.Status.Upload.Destinations[0].LastExportTime; !got.Equal(&tt.want) {
+				t.Errorf("%s got export time %v want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReflectStatusTimestampsEvent(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: recorder}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.Packaging.LastSuccessfulPackagingTime = metav1.NewTime(time.Now().Add(48 * time.Hour))
+	reflectStatusTimestamps(r, kmCfg)
+	if !kmCfg.Status.Packaging.LastSuccessfulPackagingTime.IsZero() {
+		t.Errorf("expected the future packaging time to be cleared, got %v", kmCfg.Status.Packaging.LastSuccessfulPackagingTime)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("got %d events want 1", len(recorder.Events))
+	}
+
+	// a valid status does not record an event
+	reflectStatusTimestamps(r, kmCfg)
+	if len(recorder.Events) != 1 {
+		t.Errorf("got %d events want 1", len(recorder.Events))
+	}
+}
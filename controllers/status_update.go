@@ -0,0 +1,108 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// statusWriter writes the changes that a reconcile makes to the status of a KokuMetricsConfig. Only the fields that
+// changed since the last write are sent, as a merge patch, so a change to the KokuMetricsConfig made while the
+// reconcile runs, such as an edit of the spec, is not overwritten and does not cause the write to fail.
+type statusWriter struct {
+	r    *KokuMetricsConfigReconciler
+	base *kokumetricscfgv1beta1.KokuMetricsConfigStatus
+}
+
+// newStatusWriter returns a statusWriter for the changes made to the status of kmCfg from now on
+func newStatusWriter(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) *statusWriter {
+	return &statusWriter{r: r, base: kmCfg.Status.DeepCopy()}
+}
+
+// statusPatch returns the merge patch of the changes to the status since the last write, or nil if nothing changed
+func (w *statusWriter) statusPatch(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) ([]byte, error) {
+	base := &kokumetricscfgv1beta1.KokuMetricsConfig{Status: *w.base}
+	current := &kokumetricscfgv1beta1.KokuMetricsConfig{Status: kmCfg.Status}
+	data, err := client.MergeFrom(base).Data(current)
+	if err != nil {
+		return nil, fmt.Errorf("statusPatch: failed to compute the status patch: %v", err)
+	}
+	if string(data) == "{}" {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// lockPatch adds the resource version to the patch, so that the API server rejects it with a conflict if the
+// KokuMetricsConfig changed since that version was read
+func lockPatch(data []byte, resourceVersion string) ([]byte, error) {
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, fmt.Errorf("lockPatch: failed to unmarshal patch: %v", err)
+	}
+	patch["metadata"] = map[string]interface{}{"resourceVersion": resourceVersion}
+	return json.Marshal(patch)
+}
+
+// update writes the changes to the status since the last write. The patch is locked to the latest version of the
+// KokuMetricsConfig, and is applied again to the new latest version if it conflicts.
+func (w *statusWriter) update(ctx context.Context, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) error {
+	data, err := w.statusPatch(kmCfg)
+	if err != nil || data == nil {
+		return err
+	}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: kmCfg.Name}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+		if err := w.r.Get(ctx, key, latest); err != nil {
+			return err
+		}
+		locked, err := lockPatch(data, latest.ResourceVersion)
+		if err != nil {
+			return err
+		}
+		if err := w.r.Status().Patch(ctx, latest, client.RawPatch(types.MergePatchType, locked)); err != nil {
+			return err
+		}
+		kmCfg.ResourceVersion = latest.ResourceVersion
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to patch KokuMetricsConfig status: %v", err)
+	}
+	w.base = kmCfg.Status.DeepCopy()
+	return nil
+}
+
+// checkpoint writes the changes to the status made by a stage of the pipeline, so that they are not lost if a later
+// stage fails or the operator restarts. A failed write is logged, and the changes are sent with the next write.
+func (w *statusWriter) checkpoint(ctx context.Context, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, stage string) {
+	if err := w.update(ctx, kmCfg); err != nil {
+		w.r.Log.Error(err, "failed to update KokuMetricsConfig status", "stage", stage)
+	}
+}
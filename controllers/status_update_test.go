@@ -0,0 +1,91 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestStatusWriter(t *testing.T) {
+	ctx := context.Background()
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "kokumetricscfg-sample"},
+	}
+	kmCfg.Status.ClusterID = "cluster-id"
+	kmCfg.Status.Upload.LastUploadStatus = "202 Accepted"
+	r := payloadAuditReconciler(t, kmCfg.DeepCopy())
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: kmCfg.Name}
+	if err := r.Get(ctx, key, kmCfg); err != nil {
+		t.Fatalf("failed to get KokuMetricsConfig: %v", err)
+	}
+	w := newStatusWriter(r, kmCfg)
+
+	// the KokuMetricsConfig is edited while the reconcile runs
+	edited := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	if err := r.Get(ctx, key, edited); err != nil {
+		t.Fatalf("failed to get KokuMetricsConfig: %v", err)
+	}
+	edited.Spec.Upload.IPFamily = "ipv6"
+	edited.Status.Upload.LastUploadStatus = "500 Internal Server Error"
+	if err := r.Update(ctx, edited); err != nil {
+		t.Fatalf("failed to update KokuMetricsConfig: %v", err)
+	}
+
+	// the stale copy only writes the fields it changed
+	kmCfg.Status.Prometheus.ServingAddress = "https://thanos-querier.openshift-monitoring.svc:9091"
+	kmCfg.Status.ClusterID = ""
+	if err := w.update(ctx, kmCfg); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	got := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	if err := r.Get(ctx, key, got); err != nil {
+		t.Fatalf("failed to get KokuMetricsConfig: %v", err)
+	}
+	if got.Spec.Upload.IPFamily != "ipv6" || got.Status.Upload.LastUploadStatus != "500 Internal Server Error" {
+		t.Errorf("got spec %+v and upload status %q want the concurrent edit kept", got.Spec.Upload, got.Status.Upload.LastUploadStatus)
+	}
+	if got.Status.Prometheus.ServingAddress != kmCfg.Status.Prometheus.ServingAddress || got.Status.ClusterID != "" {
+		t.Errorf("got serving address %q and cluster ID %q want the changes written", got.Status.Prometheus.ServingAddress, got.Status.ClusterID)
+	}
+	if kmCfg.ResourceVersion != got.ResourceVersion {
+		t.Errorf("got resource version %s want %s", kmCfg.ResourceVersion, got.ResourceVersion)
+	}
+
+	// nothing is written when nothing changed
+	if data, err := w.statusPatch(kmCfg); err != nil || data != nil {
+		t.Errorf("got patch %s and error %v want no patch", data, err)
+	}
+}
+
+func TestLockPatch(t *testing.T) {
+	got, err := lockPatch([]byte(`{"status":{"clusterID":"id"}}`), "42")
+	if err != nil {
+		t.Fatalf("failed to lock patch: %v", err)
+	}
+	if want := `{"metadata":{"resourceVersion":"42"},"status":{"clusterID":"id"}}`; string(got) != want {
+		t.Errorf("got patch %s want %s", got, want)
+	}
+}
@@ -222,6 +222,7 @@ var _ = BeforeSuite(func(done Done) {
 			Scheme:    scheme.Scheme,
 			Clientset: clientset,
 			InCluster: true,
+			Recorder:  k8sManager.GetEventRecorderFor("koku-metrics-operator"),
 		}).SetupWithManager(k8sManager)
 		Expect(err).ToNot(HaveOccurred())
 	}
@@ -349,6 +350,17 @@ func createBadAuthUserSecret(ctx context.Context, namespace string) {
 	Expect(k8sClient.Create(ctx, secret)).Should(Succeed())
 }
 
+func createStaticTokenSecret(ctx context.Context, namespace string) {
+	secret := &corev1.Secret{Data: map[string][]byte{
+		authSecretTokenKey: []byte("relay-token"),
+	},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      staticTokenSecretName,
+			Namespace: namespace,
+		}}
+	Expect(k8sClient.Create(ctx, secret)).Should(Succeed())
+}
+
 func deleteClusterVersion(ctx context.Context) {
 	instance := &configv1.ClusterVersion{
 		ObjectMeta: metav1.ObjectMeta{Name: "version"},
@@ -381,6 +393,9 @@ func clusterPrep(ctx context.Context) {
 		createBadAuthPassSecret(ctx, namespace)
 		createBadAuthUserSecret(ctx, namespace)
 
+		// Create a static token secret for relay uploads
+		createStaticTokenSecret(ctx, namespace)
+
 		// Create openshift config namespace and secret
 		createNamespace(ctx, openShiftConfigNamespace)
 		createPullSecret(ctx, openShiftConfigNamespace, fakeDockerConfig())
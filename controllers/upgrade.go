@@ -0,0 +1,239 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	cv "github.com/project-koku/koku-metrics-operator/clusterversion"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// the reasons of the events emitted when collection is deferred because of an upgrade, and when it resumes
+const (
+	reasonUpgradeDeferralStarted = "UpgradeDeferralStarted"
+	reasonUpgradeDeferralEnded   = "UpgradeDeferralEnded"
+	reasonUpgradeDeferralExpired = "UpgradeDeferralExpired"
+)
+
+// isUpgradeDeferralEnabled returns true unless deferring collection during upgrades is disabled in the spec
+func isUpgradeDeferralEnabled(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	if kmCfg.Spec.Reporting.UpgradeDeferral.Enabled == nil {
+		return kokumetricscfgv1beta1.DefaultUpgradeDeferralEnabled
+	}
+	return *kmCfg.Spec.Reporting.UpgradeDeferral.Enabled
+}
+
+// isUpgradeUploadDeferred returns true unless uploading during upgrades is allowed in the spec
+func isUpgradeUploadDeferred(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) bool {
+	if kmCfg.Spec.Reporting.UpgradeDeferral.DeferUpload == nil {
+		return kokumetricscfgv1beta1.DefaultUpgradeDeferUpload
+	}
+	return *kmCfg.Spec.Reporting.UpgradeDeferral.DeferUpload
+}
+
+// upgradeSettleTime returns how long collection is still deferred after an upgrade completes
+func upgradeSettleTime(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) time.Duration {
+	minutes := kokumetricscfgv1beta1.DefaultUpgradeSettleMinutes
+	if kmCfg.Spec.Reporting.UpgradeDeferral.SettleMinutes != nil {
+		minutes = *kmCfg.Spec.Reporting.UpgradeDeferral.SettleMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// maxUpgradeDeferral returns the longest that collection is deferred for an upgrade
+func maxUpgradeDeferral(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) time.Duration {
+	hours := kokumetricscfgv1beta1.DefaultUpgradeMaxHours
+	if kmCfg.Spec.Reporting.UpgradeDeferral.MaxHours != nil {
+		hours = *kmCfg.Spec.Reporting.UpgradeDeferral.MaxHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// upgradeDeferralExpired returns true if the deferral in progress has reached the maximum deferral
+func upgradeDeferralExpired(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, now time.Time) bool {
+	status := kmCfg.Status.Upgrade
+	return status.Active && now.Sub(status.Start.Time) >= maxUpgradeDeferral(kmCfg)
+}
+
+// upgradeProgress returns if the ClusterVersion is progressing, the version it is progressing to, and the time the
+// Progressing condition last changed: the start of the upgrade in progress, or the completion of the last one.
+func upgradeProgress(clusterVersion *configv1.ClusterVersion) (progressing bool, version string, since time.Time) {
+	version = clusterVersion.Status.Desired.Version
+	for _, condition := range clusterVersion.Status.Conditions {
+		if condition.Type == configv1.OperatorProgressing {
+			return condition.Status == configv1.ConditionTrue, version, condition.LastTransitionTime.Time
+		}
+	}
+	return false, version, time.Time{}
+}
+
+// checkUpgrade reads the ClusterVersion and returns if collection and upload are deferred by reconcileUpgrade. When
+// the ClusterVersion cannot be read, the deferral in progress, if any, continues until the maximum deferral.
+func checkUpgrade(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, now time.Time, logger logr.Logger) (bool, bool) {
+	status := &kmCfg.Status.Upgrade
+	status.Error = ""
+	if !isUpgradeDeferralEnabled(kmCfg) {
+		return reconcileUpgrade(r, kmCfg, nil, now, logger)
+	}
+	if r.cvClientBuilder == nil {
+		r.cvClientBuilder = cv.NewBuilder()
+	}
+	clusterVersion, err := r.cvClientBuilder.New(r).GetClusterVersion()
+	if err != nil {
+		logger.Error(err, "failed to get the ClusterVersion to check for an upgrade")
+		status.Error = fmt.Sprintf("failed to get the ClusterVersion: %v", err)
+		if !upgradeDeferralExpired(kmCfg, now) {
+			return status.Active, status.Active && status.UploadDeferred
+		}
+		return reconcileUpgrade(r, kmCfg, nil, now, logger)
+	}
+	return reconcileUpgrade(r, kmCfg, clusterVersion, now, logger)
+}
+
+// reconcileUpgrade returns if collection, and if upload, are deferred because the cluster is upgrading. Collection is
+// deferred while the ClusterVersion is progressing, and for the settle time after it completes. When the deferral
+// ends, the back-fill of the hours that were not collected during it is started. The deferral also ends when it reaches
+// the maximum deferral, and is not started again for the same upgrade. A nil ClusterVersion ends the deferral in
+// progress.
+func reconcileUpgrade(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, clusterVersion *configv1.ClusterVersion, now time.Time, logger logr.Logger) (bool, bool) {
+	log := logger.WithValues("KokuMetricsConfig", "reconcileUpgrade")
+	status := &kmCfg.Status.Upgrade
+
+	if clusterVersion != nil {
+		progressing, version, since := upgradeProgress(clusterVersion)
+		if progressing {
+			if status.Expired && status.Start.Time.Equal(since) {
+				return false, false
+			}
+			if !status.Active || !status.Start.Time.Equal(since) {
+				status.Active = true
+				status.Expired = false
+				status.Version = version
+				status.Start = metav1.NewTime(since)
+				status.End = metav1.Time{}
+				status.Upgrades++
+				msg := fmt.Sprintf("the cluster is upgrading to %s, reports are not collected until the upgrade completes, for at most %s", version, maxUpgradeDeferral(kmCfg))
+				log.Info(msg)
+				if r.Recorder != nil {
+					r.Recorder.Event(kmCfg, corev1.EventTypeNormal, reasonUpgradeDeferralStarted, msg)
+				}
+			}
+			if !upgradeDeferralExpired(kmCfg, now) {
+				status.UploadDeferred = isUpgradeUploadDeferred(kmCfg)
+				return true, status.UploadDeferred
+			}
+		} else if status.Active && now.Sub(since) < upgradeSettleTime(kmCfg) && !upgradeDeferralExpired(kmCfg, now) {
+			status.End = metav1.NewTime(since)
+			status.UploadDeferred = isUpgradeUploadDeferred(kmCfg)
+			return true, status.UploadDeferred
+		} else if status.Active {
+			status.End = metav1.NewTime(since)
+		}
+	}
+	if !status.Active {
+		return false, false
+	}
+
+	eventType, reason := corev1.EventTypeNormal, reasonUpgradeDeferralEnded
+	msg := fmt.Sprintf("the upgrade to %s completed", status.Version)
+	if status.End.IsZero() && upgradeDeferralExpired(kmCfg, now) {
+		status.Expired = true
+		eventType, reason = corev1.EventTypeWarning, reasonUpgradeDeferralExpired
+		msg = fmt.Sprintf("collection was deferred for the upgrade to %s for %s, reports are collected again", status.Version, maxUpgradeDeferral(kmCfg))
+	}
+	status.Active = false
+	status.UploadDeferred = false
+	if status.End.IsZero() {
+		status.End = metav1.NewTime(now)
+	}
+	if bStart, bEnd, hours := missedHours(kmCfg.Status.Prometheus.LastQuerySuccessTime.Time, now, BillingLocation(kmCfg)); hours > 0 {
+		status.Backfill = kokumetricscfgv1beta1.RecollectionStatus{
+			Start:    metav1.NewTime(bStart),
+			End:      metav1.NewTime(bEnd),
+			NextHour: metav1.NewTime(bStart),
+		}
+		msg = fmt.Sprintf("%s, the %d deferred hours are back-filled", msg, hours)
+	}
+	log.Info(msg)
+	if r.Recorder != nil {
+		r.Recorder.Event(kmCfg, eventType, reason, msg)
+	}
+	return false, false
+}
+
+// backfillUpgrade collects the hours deferred during the last upgrade
+func backfillUpgrade(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, logger logr.Logger) {
+	status := &kmCfg.Status.Upgrade.Backfill
+	if status.Start.IsZero() || status.Complete {
+		return
+	}
+	recollectHours(r, kmCfg, dirCfg, status, false, logger.WithValues("KokuMetricsConfig", "backfillUpgrade"))
+}
+
+// upgradeChanged filters the ClusterVersion events to the ones where the cluster starts or stops progressing
+var upgradeChanged = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldCV, okOld := e.ObjectOld.(*configv1.ClusterVersion)
+		newCV, okNew := e.ObjectNew.(*configv1.ClusterVersion)
+		if !okOld || !okNew {
+			return false
+		}
+		oldProgressing, _, _ := upgradeProgress(oldCV)
+		newProgressing, _, _ := upgradeProgress(newCV)
+		return oldProgressing != newProgressing
+	},
+}
+
+// clusterVersionRequests maps a ClusterVersion event to a reconcile of every KokuMetricsConfig, so that collection
+// is deferred as soon as an upgrade starts
+func (r *KokuMetricsConfigReconciler) clusterVersionRequests(handler.MapObject) []reconcile.Request {
+	return r.configRequests("ClusterVersion")
+}
+
+// configRequests returns a reconcile request for every KokuMetricsConfig, after a change to the given kind
+func (r *KokuMetricsConfigReconciler) configRequests(kind string) []reconcile.Request {
+	kmCfgs := &kokumetricscfgv1beta1.KokuMetricsConfigList{}
+	if err := r.List(context.Background(), kmCfgs); err != nil {
+		r.Log.Error(err, fmt.Sprintf("failed to list the KokuMetricsConfigs to reconcile after a %s change", kind))
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(kmCfgs.Items))
+	for _, kmCfg := range kmCfgs.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: kmCfg.Namespace, Name: kmCfg.Name}})
+	}
+	return requests
+}
@@ -0,0 +1,192 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func testClusterVersion(progressing bool, version string, since time.Time) *configv1.ClusterVersion {
+	status := configv1.ConditionFalse
+	if progressing {
+		status = configv1.ConditionTrue
+	}
+	return &configv1.ClusterVersion{
+		Status: configv1.ClusterVersionStatus{
+			Desired: configv1.Update{Version: version},
+			Conditions: []configv1.ClusterOperatorStatusCondition{
+				{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue},
+				{Type: configv1.OperatorProgressing, Status: status, LastTransitionTime: metav1.NewTime(since)},
+			},
+		},
+	}
+}
+
+func TestUpgradeProgress(t *testing.T) {
+	since := time.Date(2021, 1, 2, 2, 0, 0, 0, time.UTC)
+	progressTests := []struct {
+		name            string
+		clusterVersion  *configv1.ClusterVersion
+		wantProgressing bool
+		wantSince       time.Time
+	}{
+		{name: "progressing", clusterVersion: testClusterVersion(true, "4.7.1", since), wantProgressing: true, wantSince: since},
+		{name: "completed", clusterVersion: testClusterVersion(false, "4.7.1", since), wantSince: since},
+		{name: "no conditions", clusterVersion: &configv1.ClusterVersion{}},
+	}
+	for _, tt := range progressTests {
+		t.Run(tt.name, func(t *testing.T) {
+			progressing, _, got := upgradeProgress(tt.clusterVersion)
+			if progressing != tt.wantProgressing || !got.Equal(tt.wantSince) {
+				t.Errorf("%s got %t since %v want %t since %v", tt.name, progressing, got, tt.wantProgressing, tt.wantSince)
+			}
+		})
+	}
+}
+
+func TestReconcileUpgrade(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: recorder}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.Prometheus.LastQuerySuccessTime = metav1.NewTime(time.Date(2021, 1, 2, 1, 5, 0, 0, time.UTC))
+	logger := testutils.TestLogger{}
+	start := time.Date(2021, 1, 2, 1, 30, 0, 0, time.UTC)
+	end := time.Date(2021, 1, 2, 3, 50, 0, 0, time.UTC)
+
+	// the upgrade starts, and is only reported once
+	for _, at := range []time.Time{start.Add(5 * time.Minute), start.Add(10 * time.Minute)} {
+		collection, upload := reconcileUpgrade(r, kmCfg, testClusterVersion(true, "4.7.1", start), at, logger)
+		if !collection || !upload {
+			t.Fatalf("got collection %t and upload %t deferred at %v want both deferred", collection, upload, at)
+		}
+	}
+	status := kmCfg.Status.Upgrade
+	if !status.Active || !status.UploadDeferred || status.Version != "4.7.1" || status.Upgrades != 1 || len(recorder.Events) != 1 {
+		t.Errorf("got status %+v and %d events want the upgrade reported once", status, len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, reasonUpgradeDeferralStarted) {
+		t.Errorf("got event %s want %s", event, reasonUpgradeDeferralStarted)
+	}
+
+	// collection is still deferred while Prometheus settles after the upgrade
+	if collection, _ := reconcileUpgrade(r, kmCfg, testClusterVersion(false, "4.7.1", end), end.Add(5*time.Minute), logger); !collection {
+		t.Fatal("got collection during the settle time")
+	}
+
+	// the hours deferred during the upgrade are back-filled once it settles
+	if collection, upload := reconcileUpgrade(r, kmCfg, testClusterVersion(false, "4.7.1", end), end.Add(20*time.Minute), logger); collection || upload {
+		t.Fatalf("got collection %t and upload %t deferred after the upgrade settled", collection, upload)
+	}
+	status = kmCfg.Status.Upgrade
+	wantStart := time.Date(2021, 1, 2, 1, 0, 0, 0, time.UTC)
+	if status.Active || !status.End.Time.Equal(end) || !status.Backfill.Start.Time.Equal(wantStart) ||
+		!status.Backfill.End.Time.Equal(time.Date(2021, 1, 2, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("got status %+v want a back-fill from %v", status, wantStart)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, reasonUpgradeDeferralEnded) || !strings.Contains(event, "2 deferred hours") {
+		t.Errorf("got event %s want %s with 2 deferred hours", event, reasonUpgradeDeferralEnded)
+	}
+
+	// uploads continue during an upgrade when they are not deferred
+	deferUpload := false
+	kmCfg.Spec.Reporting.UpgradeDeferral.DeferUpload = &deferUpload
+	next := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	if collection, upload := reconcileUpgrade(r, kmCfg, testClusterVersion(true, "4.7.2", next), next, logger); !collection || upload {
+		t.Errorf("got collection %t and upload %t deferred want only collection", collection, upload)
+	}
+	if kmCfg.Status.Upgrade.Upgrades != 2 {
+		t.Errorf("got %d upgrades want 2", kmCfg.Status.Upgrade.Upgrades)
+	}
+
+	// disabling the deferral ends the deferral in progress
+	if collection, _ := reconcileUpgrade(r, kmCfg, nil, next.Add(time.Minute), logger); collection || kmCfg.Status.Upgrade.Active {
+		t.Errorf("got collection deferred with status %+v after the deferral was disabled", kmCfg.Status.Upgrade)
+	}
+}
+
+func TestReconcileUpgradeExpired(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: recorder}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.Prometheus.LastQuerySuccessTime = metav1.NewTime(time.Date(2021, 1, 2, 1, 5, 0, 0, time.UTC))
+	logger := testutils.TestLogger{}
+	start := time.Date(2021, 1, 2, 1, 30, 0, 0, time.UTC)
+	stuck := testClusterVersion(true, "4.7.1", start)
+
+	if collection, _ := reconcileUpgrade(r, kmCfg, stuck, start.Add(time.Hour), logger); !collection {
+		t.Fatal("got collection at the start of the upgrade")
+	}
+	<-recorder.Events
+
+	// collection resumes once the upgrade has been deferred for the maximum deferral
+	at := start.Add(time.Duration(kokumetricscfgv1beta1.DefaultUpgradeMaxHours) * time.Hour)
+	if collection, upload := reconcileUpgrade(r, kmCfg, stuck, at, logger); collection || upload {
+		t.Fatalf("got collection %t and upload %t deferred after the maximum deferral", collection, upload)
+	}
+	status := kmCfg.Status.Upgrade
+	if status.Active || !status.Expired || status.Backfill.Start.IsZero() {
+		t.Errorf("got status %+v want an expired deferral with a back-fill", status)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, reasonUpgradeDeferralExpired) {
+		t.Errorf("got event %s want %s", event, reasonUpgradeDeferralExpired)
+	}
+
+	// the same upgrade is not deferred again
+	if collection, _ := reconcileUpgrade(r, kmCfg, stuck, at.Add(time.Hour), logger); collection || kmCfg.Status.Upgrade.Upgrades != 1 {
+		t.Errorf("got collection deferred with status %+v for an expired upgrade", kmCfg.Status.Upgrade)
+	}
+
+	// the next upgrade is
+	next := at.Add(24 * time.Hour)
+	if collection, _ := reconcileUpgrade(r, kmCfg, testClusterVersion(true, "4.7.2", next), next, logger); !collection || kmCfg.Status.Upgrade.Expired {
+		t.Errorf("got collection %t with status %+v want the next upgrade deferred", collection, kmCfg.Status.Upgrade)
+	}
+}
+
+func TestUpgradeChanged(t *testing.T) {
+	since := time.Date(2021, 1, 2, 2, 0, 0, 0, time.UTC)
+	idle := testClusterVersion(false, "4.7.0", since)
+	changedTests := []struct {
+		name     string
+		old, new *configv1.ClusterVersion
+		want     bool
+	}{
+		{name: "upgrade started", old: idle, new: testClusterVersion(true, "4.7.1", since), want: true},
+		{name: "upgrade completed", old: testClusterVersion(true, "4.7.1", since), new: idle, want: true},
+		{name: "no change", old: idle, new: testClusterVersion(false, "4.7.0", since.Add(time.Hour))},
+	}
+	for _, tt := range changedTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := upgradeChanged.Update(event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}); got != tt.want {
+				t.Errorf("%s got %t want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+)
+
+const (
+	// reasonUploadRejected is the reason of the event emitted when ingress rejects an upload with a 4xx status
+	reasonUploadRejected = "UploadRejected"
+
+	// maxRejectionResponseLength is the number of bytes of a rejection response body that are kept in the status
+	maxRejectionResponseLength = 1024
+)
+
+// recordRejectedUpload writes the response body of an upload that ingress rejected with a 4xx status to the status,
+// and emits an event with it. The body is cleared by a successful upload.
+func recordRejectedUpload(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, exp exporter.Exporter, err error) {
+	if _, ok := exp.(*exporter.Ingress); !ok {
+		return
+	}
+	if err == nil {
+		kmCfg.Status.Upload.LastRejectionResponse = ""
+		return
+	}
+	var respErr *crhchttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode < 400 || respErr.StatusCode >= 500 {
+		return
+	}
+
+	body := truncateResponse(respErr.Body, maxRejectionResponseLength)
+	kmCfg.Status.Upload.LastRejectionResponse = body
+	if r.Recorder != nil {
+		msg := fmt.Sprintf("ingress rejected the upload with %d %s: %s", respErr.StatusCode, http.StatusText(respErr.StatusCode), body)
+		r.Recorder.Event(kmCfg, corev1.EventTypeWarning, reasonUploadRejected, msg)
+	}
+}
+
+// truncateResponse returns the response body trimmed of surrounding whitespace and cut to at most max bytes,
+// without splitting a multi-byte character.
+func truncateResponse(body []byte, max int) string {
+	s := strings.TrimSpace(string(body))
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "... (truncated)"
+}
@@ -0,0 +1,104 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestRecordRejectedUpload(t *testing.T) {
+	rejection := &crhchttp.ResponseError{StatusCode: 400, Body: []byte(` {"errors": [{"detail": "invalid manifest"}]}` + "\n")}
+	rejectionTests := []struct {
+		name      string
+		exp       exporter.Exporter
+		err       error
+		previous  string
+		want      string
+		wantEvent string
+	}{
+		{
+			name:      "4xx response is recorded",
+			exp:       &exporter.Ingress{},
+			err:       fmt.Errorf("Upload: %w", rejection),
+			want:      `{"errors": [{"detail": "invalid manifest"}]}`,
+			wantEvent: `Warning UploadRejected ingress rejected the upload with 400 Bad Request: {"errors": [{"detail": "invalid manifest"}]}`,
+		},
+		{name: "successful upload clears the response", exp: &exporter.Ingress{}, previous: "old"},
+		{name: "5xx response is not recorded", exp: &exporter.Ingress{}, err: &crhchttp.ResponseError{StatusCode: 503}, previous: "old", want: "old"},
+		{name: "other errors are not recorded", exp: &exporter.Ingress{}, err: errors.New("could not send the request"), previous: "old", want: "old"},
+		{name: "other destinations are ignored", exp: &exporter.Filesystem{}, err: rejection, previous: "old", want: "old"},
+	}
+	for _, tt := range rejectionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &KokuMetricsConfigReconciler{Log: testutils.TestLogger{}, Recorder: recorder}
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Status.Upload.LastRejectionResponse = tt.previous
+
+			recordRejectedUpload(r, kmCfg, tt.exp, tt.err)
+
+			if got := kmCfg.Status.Upload.LastRejectionResponse; got != tt.want {
+				t.Errorf("%s got response %q want %q", tt.name, got, tt.want)
+			}
+			var gotEvent string
+			select {
+			case gotEvent = <-recorder.Events:
+			default:
+			}
+			if gotEvent != tt.wantEvent {
+				t.Errorf("%s got event %q want %q", tt.name, gotEvent, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestTruncateResponse(t *testing.T) {
+	truncateTests := []struct {
+		name string
+		body string
+		max  int
+		want string
+	}{
+		{name: "short body", body: "bad request", max: 20, want: "bad request"},
+		{name: "long body", body: "abcdefghij", max: 4, want: "abcd... (truncated)"},
+		{name: "multi-byte character is not split", body: "abécd", max: 3, want: "ab... (truncated)"},
+	}
+	for _, tt := range truncateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateResponse([]byte(tt.body), tt.max)
+			if got != tt.want {
+				t.Errorf("%s got %q want %q", tt.name, got, tt.want)
+			}
+			if !strings.HasPrefix(tt.body, strings.TrimSuffix(got, "... (truncated)")) {
+				t.Errorf("%s got %q which is not a prefix of the body", tt.name, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// maxUploadWait is the number of seconds that generated upload waits are spread over
+const maxUploadWait = 35
+
+// clusterUploadWait returns the upload wait of the cluster, which is derived from the cluster ID so that it is the
+// same on every reconcile, and after the status is reset, while clusters still upload at different times
+func clusterUploadWait(clusterID string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(clusterID))
+	return int64(h.Sum32() % maxUploadWait)
+}
+
+// reflectUploadWait sets the upload wait to the wait in the spec. Without one, the wait is derived from the cluster
+// ID. Until the cluster ID is known, a random wait is generated so that clusters do not all upload at the same time.
+// An upload wait of 0 in the spec is honored and uploads without waiting.
+func reflectUploadWait(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	switch {
+	case kmCfg.Spec.Upload.UploadWait != nil:
+		kmCfg.Status.Upload.UploadWait = kmCfg.Spec.Upload.UploadWait
+		kmCfg.Status.Upload.UploadWaitSource = kokumetricscfgv1beta1.SpecUploadWait
+	case kmCfg.Status.ClusterID != "":
+		uploadWait := clusterUploadWait(kmCfg.Status.ClusterID)
+		kmCfg.Status.Upload.UploadWait = &uploadWait
+		kmCfg.Status.Upload.UploadWaitSource = kokumetricscfgv1beta1.ClusterIDUploadWait
+	case kmCfg.Status.Upload.UploadWait == nil:
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		uploadWait := r.Int63() % maxUploadWait
+		kmCfg.Status.Upload.UploadWait = &uploadWait
+		kmCfg.Status.Upload.UploadWaitSource = kokumetricscfgv1beta1.RandomUploadWait
+	}
+}
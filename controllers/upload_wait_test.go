@@ -0,0 +1,73 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestReflectUploadWait(t *testing.T) {
+	clusterID := "00000000-0000-0000-0000-000000000001"
+	clusterWait := clusterUploadWait(clusterID)
+	if clusterWait < 0 || clusterWait >= maxUploadWait {
+		t.Fatalf("cluster upload wait %d is not between 0 and %d", clusterWait, maxUploadWait-1)
+	}
+	if other := clusterUploadWait(clusterID); other != clusterWait {
+		t.Errorf("cluster upload wait got %d and %d for the same cluster, want the same wait", clusterWait, other)
+	}
+
+	twenty := int64(20)
+	uploadWaitTests := []struct {
+		name       string
+		specWait   *int64
+		statusWait *int64
+		clusterID  string
+		want       int64
+		wantSource kokumetricscfgv1beta1.UploadWaitSource
+	}{
+		{name: "spec wait", specWait: &twenty, clusterID: clusterID, want: 20, wantSource: kokumetricscfgv1beta1.SpecUploadWait},
+		{name: "cluster ID wait", clusterID: clusterID, want: clusterWait, wantSource: kokumetricscfgv1beta1.ClusterIDUploadWait},
+		{name: "cluster ID wait replaces a random wait", statusWait: &twenty, clusterID: clusterID, want: clusterWait, wantSource: kokumetricscfgv1beta1.ClusterIDUploadWait},
+		{name: "random wait is kept until the cluster ID is known", statusWait: &twenty, want: 20},
+	}
+	for _, tt := range uploadWaitTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Spec.Upload.UploadWait = tt.specWait
+			kmCfg.Status.Upload.UploadWait = tt.statusWait
+			kmCfg.Status.ClusterID = tt.clusterID
+			reflectUploadWait(kmCfg)
+			if kmCfg.Status.Upload.UploadWait == nil || *kmCfg.Status.Upload.UploadWait != tt.want {
+				t.Errorf("%s upload wait got %v want %d", tt.name, kmCfg.Status.Upload.UploadWait, tt.want)
+			}
+			if kmCfg.Status.Upload.UploadWaitSource != tt.wantSource {
+				t.Errorf("%s upload wait source got %q want %q", tt.name, kmCfg.Status.Upload.UploadWaitSource, tt.wantSource)
+			}
+		})
+	}
+
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	reflectUploadWait(kmCfg)
+	if kmCfg.Status.Upload.UploadWait == nil || kmCfg.Status.Upload.UploadWaitSource != kokumetricscfgv1beta1.RandomUploadWait {
+		t.Errorf("upload wait without a cluster ID got %v from %q want a random wait", kmCfg.Status.Upload.UploadWait, kmCfg.Status.Upload.UploadWaitSource)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+const (
+	usageSummaryConfigMapName = "koku-metrics-usage-summary"
+	usageSummaryKey           = "summary.json"
+	// usageHourlyKey is the key that older operators kept the hourly usage in. It is read once after an upgrade.
+	usageHourlyKey = "hourly.json"
+
+	// usageHourlyFile is the file, on the report volume, of the hourly usage that the summary is rolled up from. It
+	// is not kept in the ConfigMap, since 24 hours of every namespace pass the size limit of a ConfigMap.
+	usageHourlyFile = "usage-hourly.json"
+
+	// maxUsageSummaryBytes is the size of the summary published to the ConfigMap, well below the 1 MiB limit of a
+	// ConfigMap. The namespaces with the least usage are left out of larger summaries.
+	maxUsageSummaryBytes = 512 * 1024
+)
+
+// usageSummaryWindow is the amount of collected data rolled up into the usage summary
+var usageSummaryWindow = 24 * time.Hour
+
+var namespaceUsage = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "koku_metrics_namespace_usage_24h",
+		Help: "Usage collected for each namespace over the last 24 hours of reports. Cpu is in core-hours and memory is in GiB-hours.",
+	},
+	[]string{"namespace", "resource"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(namespaceUsage)
+}
+
+// usageSummary is the rollup of the collected usage that is published to the summary ConfigMap
+type usageSummary struct {
+	WindowStart time.Time                           `json:"window_start"`
+	WindowEnd   time.Time                           `json:"window_end"`
+	Hours       int                                 `json:"hours_collected"`
+	Namespaces  map[string]collector.NamespaceUsage `json:"namespaces"`
+	// OmittedNamespaces is the number of namespaces left out of the published summary to keep it under the size limit
+	OmittedNamespaces int `json:"namespaces_omitted,omitempty"`
+}
+
+// mergeHourlyUsage adds the latest hour to the collected hours, replacing an existing entry for
+// the same hour and dropping any hours that fall outside the summary window.
+func mergeHourlyUsage(hours []collector.HourlyUsage, latest collector.HourlyUsage) []collector.HourlyUsage {
+	cutoff := latest.Start.Add(-usageSummaryWindow)
+	merged := []collector.HourlyUsage{latest}
+	for _, hour := range hours {
+		if hour.Start.Equal(latest.Start) || !hour.Start.After(cutoff) {
+			continue
+		}
+		merged = append(merged, hour)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Start.Before(merged[j].Start) })
+	return merged
+}
+
+// summarizeUsage totals the usage of each namespace across the collected hours
+func summarizeUsage(hours []collector.HourlyUsage) usageSummary {
+	summary := usageSummary{Hours: len(hours), Namespaces: map[string]collector.NamespaceUsage{}}
+	if len(hours) == 0 {
+		return summary
+	}
+	summary.WindowStart = hours[0].Start
+	summary.WindowEnd = hours[len(hours)-1].Start.Add(time.Hour)
+	for _, hour := range hours {
+		for namespace, usage := range hour.Namespaces {
+			summary.Namespaces[namespace] = summary.Namespaces[namespace].Add(usage)
+		}
+	}
+	return summary
+}
+
+// publishedUsageSummary returns the summary published to the ConfigMap. When the summary of every namespace is larger
+// than maxUsageSummaryBytes, only the namespaces with the most cpu usage that fit are published.
+func publishedUsageSummary(summary usageSummary) ([]byte, error) {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil || len(data) <= maxUsageSummaryBytes {
+		return data, err
+	}
+	names := make([]string, 0, len(summary.Namespaces))
+	for namespace := range summary.Namespaces {
+		names = append(names, namespace)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := summary.Namespaces[names[i]], summary.Namespaces[names[j]]
+		if a.CPUUsageCoreHours != b.CPUUsageCoreHours {
+			return a.CPUUsageCoreHours > b.CPUUsageCoreHours
+		}
+		return names[i] < names[j]
+	})
+	top := func(n int) ([]byte, error) {
+		published := summary
+		published.Namespaces = make(map[string]collector.NamespaceUsage, n)
+		for _, namespace := range names[:n] {
+			published.Namespaces[namespace] = summary.Namespaces[namespace]
+		}
+		published.OmittedNamespaces = len(names) - n
+		return json.MarshalIndent(published, "", "  ")
+	}
+	// the largest number of namespaces that fits is searched for, since the size of each namespace varies
+	low, high := 0, len(names)-1
+	for low < high {
+		mid := (low + high + 1) / 2
+		data, err := top(mid)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) <= maxUsageSummaryBytes {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return top(low)
+}
+
+// loadHourlyUsage reads the hourly usage from path. A missing file returns no hours.
+func loadHourlyUsage(path string) ([]collector.HourlyUsage, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("loadHourlyUsage: failed to read hourly usage: %v", err)
+	}
+	var hours []collector.HourlyUsage
+	if err := json.Unmarshal(data, &hours); err != nil {
+		return nil, fmt.Errorf("loadHourlyUsage: failed to parse hourly usage: %v", err)
+	}
+	return hours, nil
+}
+
+// saveHourlyUsage writes the hourly usage to a temporary file and renames it so that it is never left partially written
+func saveHourlyUsage(path string, hours []collector.HourlyUsage) error {
+	data, err := json.Marshal(hours)
+	if err != nil {
+		return fmt.Errorf("saveHourlyUsage: failed to marshal hourly usage: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("saveHourlyUsage: failed to write hourly usage: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("saveHourlyUsage: failed to replace hourly usage: %v", err)
+	}
+	return nil
+}
+
+func setNamespaceUsageMetrics(summary usageSummary) {
+	namespaceUsage.Reset()
+	for namespace, usage := range summary.Namespaces {
+		namespaceUsage.WithLabelValues(namespace, "cpu_usage_core_hours").Set(usage.CPUUsageCoreHours)
+		namespaceUsage.WithLabelValues(namespace, "cpu_request_core_hours").Set(usage.CPURequestCoreHours)
+		namespaceUsage.WithLabelValues(namespace, "memory_usage_gib_hours").Set(usage.MemoryUsageGiBHours)
+		namespaceUsage.WithLabelValues(namespace, "memory_request_gib_hours").Set(usage.MemoryRequestGiBHours)
+	}
+}
+
+// updateUsageSummary publishes a rollup of the last 24 hours of collected usage to a ConfigMap
+// so that cluster admins can sanity-check what is being reported. The hourly usage is kept on the
+// report volume. The rollup of every namespace is returned.
+func updateUsageSummary(r *KokuMetricsConfigReconciler, kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, dirCfg *dirconfig.DirectoryConfig, latest *collector.HourlyUsage, logger logr.Logger) (*usageSummary, error) {
+	ctx := context.Background()
+	log := logger.WithValues("KokuMetricsConfig", "updateUsageSummary")
+
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: kmCfg.Namespace, Name: usageSummaryConfigMapName}
+	exists := true
+	if err := r.Get(ctx, key, cm); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("updateUsageSummary: failed to get ConfigMap: %v", err)
+		}
+		exists = false
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	}
+
+	hourlyPath := filepath.Join(dirCfg.Parent.Path, usageHourlyFile)
+	hours, err := loadHourlyUsage(hourlyPath)
+	if err != nil {
+		log.Info("discarding unreadable hourly usage", "error", err)
+		hours = nil
+	}
+	if data, ok := cm.Data[usageHourlyKey]; ok && hours == nil {
+		// the hourly usage of older operators is moved from the ConfigMap to the report volume
+		if err := json.Unmarshal([]byte(data), &hours); err != nil {
+			log.Info("discarding unreadable hourly usage", "error", err)
+			hours = nil
+		}
+	}
+	hours = mergeHourlyUsage(hours, *latest)
+	summary := summarizeUsage(hours)
+	setNamespaceUsageMetrics(summary)
+
+	if err := saveHourlyUsage(hourlyPath, hours); err != nil {
+		return nil, fmt.Errorf("updateUsageSummary: %v", err)
+	}
+	summaryData, err := publishedUsageSummary(summary)
+	if err != nil {
+		return nil, fmt.Errorf("updateUsageSummary: failed to marshal usage summary: %v", err)
+	}
+	cm.Data = map[string]string{
+		usageSummaryKey: string(summaryData),
+	}
+
+	if !exists {
+		if err := ctrl.SetControllerReference(kmCfg, cm, r.Scheme); err != nil {
+			return nil, fmt.Errorf("updateUsageSummary: failed to set owner reference: %v", err)
+		}
+		log.Info("creating usage summary ConfigMap", "name", key.Name)
+		if err := r.Create(ctx, cm); err != nil {
+			return nil, fmt.Errorf("updateUsageSummary: failed to create ConfigMap: %v", err)
+		}
+		return &summary, nil
+	}
+	if err := r.Update(ctx, cm); err != nil {
+		return nil, fmt.Errorf("updateUsageSummary: failed to update ConfigMap: %v", err)
+	}
+	return &summary, nil
+}
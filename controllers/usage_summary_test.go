@@ -0,0 +1,177 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestMergeHourlyUsage(t *testing.T) {
+	now := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+	hourOf := func(hoursAgo int, cpu float64) collector.HourlyUsage {
+		return collector.HourlyUsage{
+			Start:      now.Add(-time.Duration(hoursAgo) * time.Hour),
+			Namespaces: map[string]collector.NamespaceUsage{"ns1": {CPUUsageCoreHours: cpu}},
+		}
+	}
+	mergeHourlyUsageTests := []struct {
+		name      string
+		hours     []collector.HourlyUsage
+		latest    collector.HourlyUsage
+		wantHours int
+		wantCPU   float64
+	}{
+		{name: "first hour", hours: nil, latest: hourOf(0, 1), wantHours: 1, wantCPU: 1},
+		{name: "new hour appended", hours: []collector.HourlyUsage{hourOf(1, 2)}, latest: hourOf(0, 1), wantHours: 2, wantCPU: 3},
+		{name: "same hour replaced", hours: []collector.HourlyUsage{hourOf(1, 2), hourOf(0, 5)}, latest: hourOf(0, 1), wantHours: 2, wantCPU: 3},
+		{name: "hours outside window dropped", hours: []collector.HourlyUsage{hourOf(24, 10), hourOf(23, 2), hourOf(30, 10)}, latest: hourOf(0, 1), wantHours: 2, wantCPU: 3},
+	}
+	for _, tt := range mergeHourlyUsageTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeHourlyUsage(tt.hours, tt.latest)
+			if len(got) != tt.wantHours {
+				t.Fatalf("%s got %d hours want %d", tt.name, len(got), tt.wantHours)
+			}
+			for i := 1; i < len(got); i++ {
+				if !got[i-1].Start.Before(got[i].Start) {
+					t.Errorf("%s hours are not sorted: %v", tt.name, got)
+				}
+			}
+			summary := summarizeUsage(got)
+			if summary.Hours != tt.wantHours {
+				t.Errorf("%s summary got %d hours want %d", tt.name, summary.Hours, tt.wantHours)
+			}
+			if cpu := summary.Namespaces["ns1"].CPUUsageCoreHours; cpu != tt.wantCPU {
+				t.Errorf("%s got %v core-hours want %v", tt.name, cpu, tt.wantCPU)
+			}
+			if !summary.WindowStart.Equal(got[0].Start) || !summary.WindowEnd.Equal(now.Add(time.Hour)) {
+				t.Errorf("%s got window %v - %v", tt.name, summary.WindowStart, summary.WindowEnd)
+			}
+		})
+	}
+}
+
+func TestSummarizeUsageEmpty(t *testing.T) {
+	summary := summarizeUsage(nil)
+	if summary.Hours != 0 || len(summary.Namespaces) != 0 || !summary.WindowStart.IsZero() {
+		t.Errorf("expected empty summary, got %+v", summary)
+	}
+}
+
+func manyNamespaceUsage(start time.Time, namespaces int) *collector.HourlyUsage {
+	usage := &collector.HourlyUsage{Start: start, Namespaces: map[string]collector.NamespaceUsage{}}
+	for i := 0; i < namespaces; i++ {
+		usage.Namespaces[fmt.Sprintf("namespace-with-a-long-name-%05d", i)] = collector.NamespaceUsage{
+			CPUUsageCoreHours:     float64(i) + 0.123456789,
+			CPURequestCoreHours:   float64(i) + 0.987654321,
+			MemoryUsageGiBHours:   float64(i) + 0.123456789,
+			MemoryRequestGiBHours: float64(i) + 0.987654321,
+		}
+	}
+	return usage
+}
+
+func TestUpdateUsageSummaryManyNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usage-summary")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{kokumetricscfgv1beta1.AddToScheme, corev1.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatalf("failed to build scheme: %v", err)
+		}
+	}
+	r := &KokuMetricsConfigReconciler{Client: fake.NewFakeClientWithScheme(s), Scheme: s, Log: testutils.TestLogger{}}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "koku-metrics-operator", Name: "cfg"}}
+	dirCfg := &dirconfig.DirectoryConfig{Parent: dirconfig.Directory{Path: dir}}
+
+	// 24 hours of 1000 namespaces are several MB, well past the size limit of a ConfigMap
+	namespaces := 1000
+	start := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	var summary *usageSummary
+	for hour := 0; hour < 24; hour++ {
+		summary, err = updateUsageSummary(r, kmCfg, dirCfg, manyNamespaceUsage(start.Add(time.Duration(hour)*time.Hour), namespaces), r.Log)
+		if err != nil {
+			t.Fatalf("hour %d got unexpected error: %v", hour, err)
+		}
+	}
+	if summary.Hours != 24 || len(summary.Namespaces) != namespaces {
+		t.Errorf("got %d hours and %d namespaces want 24 hours and %d namespaces", summary.Hours, len(summary.Namespaces), namespaces)
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: kmCfg.Namespace, Name: usageSummaryConfigMapName}, cm); err != nil {
+		t.Fatalf("failed to get ConfigMap: %v", err)
+	}
+	if _, ok := cm.Data[usageHourlyKey]; ok {
+		t.Errorf("hourly usage is kept in the ConfigMap")
+	}
+	if size := len(cm.Data[usageSummaryKey]); size > maxUsageSummaryBytes {
+		t.Errorf("got summary of %d bytes want at most %d", size, maxUsageSummaryBytes)
+	}
+	hours, err := loadHourlyUsage(filepath.Join(dir, usageHourlyFile))
+	if err != nil || len(hours) != 24 {
+		t.Errorf("got %d hours on the report volume want 24: %v", len(hours), err)
+	}
+}
+
+func TestPublishedUsageSummaryLimit(t *testing.T) {
+	namespaces := 10000
+	usage := manyNamespaceUsage(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), namespaces)
+	summary := summarizeUsage([]collector.HourlyUsage{*usage})
+
+	data, err := publishedUsageSummary(summary)
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if len(data) > maxUsageSummaryBytes {
+		t.Errorf("got summary of %d bytes want at most %d", len(data), maxUsageSummaryBytes)
+	}
+	published := usageSummary{}
+	if err := json.Unmarshal(data, &published); err != nil {
+		t.Fatalf("failed to parse summary: %v", err)
+	}
+	if published.OmittedNamespaces == 0 || len(published.Namespaces)+published.OmittedNamespaces != namespaces {
+		t.Errorf("got %d namespaces and %d omitted want %d in total", len(published.Namespaces), published.OmittedNamespaces, namespaces)
+	}
+	if _, ok := published.Namespaces[fmt.Sprintf("namespace-with-a-long-name-%05d", namespaces-1)]; !ok {
+		t.Errorf("the namespace with the most usage was left out")
+	}
+}
@@ -0,0 +1,189 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+// maxMissedWindows is the number of missed hours kept in the status, one week of hours
+const maxMissedWindows = 168
+
+var (
+	missedWindows = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_missed_windows",
+			Help: "Number of hours in the status that were not collected or not uploaded, by stage.",
+		},
+		[]string{"stage"},
+	)
+
+	missedWindowsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "koku_metrics_missed_windows_total",
+			Help: "Number of times an hour was added to the missed hours of the status, by stage.",
+		},
+		[]string{"stage"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(missedWindows, missedWindowsTotal)
+}
+
+// findMissedWindow returns the index of the missed hour, or -1 if the hour is not missed
+func findMissedWindow(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, start time.Time, stage kokumetricscfgv1beta1.MissedWindowStage, destination string) int {
+	for i, w := range kmCfg.Status.MissedWindows.Windows {
+		if w.Start.Time.Equal(start) && w.Stage == stage && w.Destination == destination {
+			return i
+		}
+	}
+	return -1
+}
+
+// recordMissedWindow adds the hour to the missed hours, or updates its reason if it is already missed. The oldest
+// hours are removed when the list is full.
+func recordMissedWindow(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, start time.Time, stage kokumetricscfgv1beta1.MissedWindowStage, destination, reason string, now time.Time) {
+	status := &kmCfg.Status.MissedWindows
+	if i := findMissedWindow(kmCfg, start, stage, destination); i >= 0 {
+		status.Windows[i].Reason = reason
+		status.Windows[i].LastAttemptTime = metav1.Time{Time: now}
+		return
+	}
+	window := kokumetricscfgv1beta1.MissedWindow{
+		Start:           metav1.Time{Time: start},
+		Stage:           stage,
+		Destination:     destination,
+		Reason:          reason,
+		LastAttemptTime: metav1.Time{Time: now},
+	}
+	// the hours are kept in order, so that the oldest hours are removed first
+	i := len(status.Windows)
+	for i > 0 && status.Windows[i-1].Start.Time.After(start) {
+		i--
+	}
+	status.Windows = append(status.Windows, kokumetricscfgv1beta1.MissedWindow{})
+	copy(status.Windows[i+1:], status.Windows[i:])
+	status.Windows[i] = window
+	if overflow := len(status.Windows) - maxMissedWindows; overflow > 0 {
+		status.Windows = status.Windows[overflow:]
+		status.Overflow += int64(overflow)
+	}
+	missedWindowsTotal.WithLabelValues(string(stage)).Inc()
+	setMissedWindowMetrics(kmCfg)
+}
+
+// clearMissedWindow removes the hour from the missed hours once it is collected or uploaded
+func clearMissedWindow(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, start time.Time, stage kokumetricscfgv1beta1.MissedWindowStage, destination string) {
+	i := findMissedWindow(kmCfg, start, stage, destination)
+	if i < 0 {
+		return
+	}
+	status := &kmCfg.Status.MissedWindows
+	status.Windows = append(status.Windows[:i], status.Windows[i+1:]...)
+	setMissedWindowMetrics(kmCfg)
+}
+
+// setMissedWindowMetrics sets the number of missed hours of each stage
+func setMissedWindowMetrics(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig) {
+	counts := map[kokumetricscfgv1beta1.MissedWindowStage]int{
+		kokumetricscfgv1beta1.CollectionStage: 0,
+		kokumetricscfgv1beta1.UploadStage:     0,
+	}
+	for _, w := range kmCfg.Status.MissedWindows.Windows {
+		counts[w.Stage]++
+	}
+	for stage, count := range counts {
+		missedWindows.WithLabelValues(string(stage)).Set(float64(count))
+	}
+}
+
+// accountCollection records the result of the collection of the hour that starts at start. An hour without data in
+// Prometheus is missed, although its reports are written.
+func accountCollection(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, start time.Time, dataCollected bool, err error, now time.Time) {
+	switch {
+	case err == nil && dataCollected:
+		clearMissedWindow(kmCfg, start, kokumetricscfgv1beta1.CollectionStage, "")
+	case err == nil:
+		recordMissedWindow(kmCfg, start, kokumetricscfgv1beta1.CollectionStage, "", "prometheus had no data for the hour", now)
+	default:
+		recordMissedWindow(kmCfg, start, kokumetricscfgv1beta1.CollectionStage, "", err.Error(), now)
+	}
+}
+
+// payloadHours returns the start of each hour covered by the payload
+func payloadHours(summary packaging.PayloadSummary) []time.Time {
+	var hours []time.Time
+	if summary.Start.IsZero() {
+		return hours
+	}
+	for hour := summary.Start.Truncate(time.Hour); hour.Before(summary.End); hour = hour.Add(time.Hour) {
+		hours = append(hours, hour)
+	}
+	return hours
+}
+
+// accountUploads records the hours of the payloads that a destination did not accept, and clears the hours of the
+// payloads that it accepted
+func accountUploads(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, results []uploader.Result, now time.Time) {
+	for _, result := range results {
+		if result.Exporter == nil {
+			continue
+		}
+		destination := result.Exporter.Name()
+		for _, hour := range payloadHours(result.Payload) {
+			if result.Err == nil {
+				clearMissedWindow(kmCfg, hour, kokumetricscfgv1beta1.UploadStage, destination)
+			} else {
+				recordMissedWindow(kmCfg, hour, kokumetricscfgv1beta1.UploadStage, destination, result.Err.Error(), now)
+			}
+		}
+	}
+}
+
+// accountTrimmedPayloads records the hours of the payloads that were removed before they were uploaded. These hours
+// replace the upload failures of the hours, since the payloads will not be uploaded again.
+func accountTrimmedPayloads(kmCfg *kokumetricscfgv1beta1.KokuMetricsConfig, trimmed []packaging.PayloadSummary, now time.Time) {
+	if len(trimmed) <= 0 {
+		return
+	}
+	reason := fmt.Sprintf("the payload was removed before it was uploaded because more than %d reports were stored", kmCfg.Spec.Packaging.MaxReports)
+	for _, summary := range trimmed {
+		for _, hour := range payloadHours(summary) {
+			windows := kmCfg.Status.MissedWindows.Windows[:0]
+			for _, w := range kmCfg.Status.MissedWindows.Windows {
+				if w.Stage != kokumetricscfgv1beta1.UploadStage || !w.Start.Time.Equal(hour) {
+					windows = append(windows, w)
+				}
+			}
+			kmCfg.Status.MissedWindows.Windows = windows
+			recordMissedWindow(kmCfg, hour, kokumetricscfgv1beta1.UploadStage, "", reason, now)
+		}
+	}
+}
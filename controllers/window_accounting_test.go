@@ -0,0 +1,131 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/uploader"
+)
+
+func TestRecordMissedWindow(t *testing.T) {
+	now := time.Date(2021, 1, 10, 0, 0, 0, 0, time.UTC)
+	hour := func(i int) time.Time {
+		return time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Hour)
+	}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+
+	// the hours are kept in order, and an hour that is missed again is updated
+	recordMissedWindow(kmCfg, hour(2), kokumetricscfgv1beta1.CollectionStage, "", "timeout", now)
+	recordMissedWindow(kmCfg, hour(1), kokumetricscfgv1beta1.CollectionStage, "", "timeout", now)
+	recordMissedWindow(kmCfg, hour(1), kokumetricscfgv1beta1.UploadStage, exporter.IngressName, "rejected", now)
+	recordMissedWindow(kmCfg, hour(2), kokumetricscfgv1beta1.CollectionStage, "", "connection refused", now)
+	windows := kmCfg.Status.MissedWindows.Windows
+	if len(windows) != 3 {
+		t.Fatalf("got %d missed windows want 3", len(windows))
+	}
+	if !windows[0].Start.Time.Equal(hour(1)) || !windows[2].Start.Time.Equal(hour(2)) || windows[2].Reason != "connection refused" {
+		t.Errorf("got missed windows %+v", windows)
+	}
+
+	// collected hours are removed
+	clearMissedWindow(kmCfg, hour(1), kokumetricscfgv1beta1.CollectionStage, "")
+	if i := findMissedWindow(kmCfg, hour(1), kokumetricscfgv1beta1.CollectionStage, ""); i >= 0 {
+		t.Errorf("got the collected hour at index %d", i)
+	}
+	if i := findMissedWindow(kmCfg, hour(1), kokumetricscfgv1beta1.UploadStage, exporter.IngressName); i < 0 {
+		t.Error("got the upload of the collected hour removed")
+	}
+
+	// the oldest hours are removed when the list is full
+	for i := 3; i < maxMissedWindows+5; i++ {
+		recordMissedWindow(kmCfg, hour(i), kokumetricscfgv1beta1.CollectionStage, "", "timeout", now)
+	}
+	status := kmCfg.Status.MissedWindows
+	if len(status.Windows) != maxMissedWindows || status.Overflow != 4 {
+		t.Errorf("got %d missed windows and overflow %d want %d and 4", len(status.Windows), status.Overflow, maxMissedWindows)
+	}
+	if !status.Windows[len(status.Windows)-1].Start.Time.Equal(hour(maxMissedWindows + 4)) {
+		t.Errorf("got newest missed window %v", status.Windows[len(status.Windows)-1].Start)
+	}
+}
+
+func TestAccountCollection(t *testing.T) {
+	now := time.Date(2021, 1, 2, 11, 0, 0, 0, time.UTC)
+	start := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+	accountTests := []struct {
+		name          string
+		dataCollected bool
+		err           error
+		wantReason    string
+	}{
+		{name: "query error", err: errors.New("query timed out"), wantReason: "query timed out"},
+		{name: "no data", wantReason: "prometheus had no data for the hour"},
+		{name: "collected", dataCollected: true},
+	}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	for _, tt := range accountTests {
+		t.Run(tt.name, func(t *testing.T) {
+			accountCollection(kmCfg, start, tt.dataCollected, tt.err, now)
+			windows := kmCfg.Status.MissedWindows.Windows
+			if tt.wantReason == "" {
+				if len(windows) != 0 {
+					t.Errorf("%s got missed windows %+v want none", tt.name, windows)
+				}
+				return
+			}
+			if len(windows) != 1 || windows[0].Reason != tt.wantReason || windows[0].Stage != kokumetricscfgv1beta1.CollectionStage {
+				t.Errorf("%s got missed windows %+v want reason %q", tt.name, windows, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestAccountUploads(t *testing.T) {
+	now := time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2021, 1, 2, 10, 0, 0, 0, time.UTC)
+	payload := packaging.PayloadSummary{PayloadID: "id", Start: start, End: start.Add(2*time.Hour - time.Second)}
+	ingress := &exporter.Ingress{}
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+
+	accountUploads(kmCfg, []uploader.Result{{Exporter: ingress, Payload: payload, Err: errors.New("413 Request Entity Too Large")}}, now)
+	windows := kmCfg.Status.MissedWindows.Windows
+	if len(windows) != 2 || windows[0].Destination != exporter.IngressName || windows[1].Reason != "413 Request Entity Too Large" {
+		t.Fatalf("got missed windows %+v want the 2 hours of the payload", windows)
+	}
+
+	accountUploads(kmCfg, []uploader.Result{{Exporter: ingress, Payload: payload}}, now)
+	if len(kmCfg.Status.MissedWindows.Windows) != 0 {
+		t.Errorf("got missed windows %+v after the upload", kmCfg.Status.MissedWindows.Windows)
+	}
+
+	// a removed payload replaces the upload failures of its hours
+	accountUploads(kmCfg, []uploader.Result{{Exporter: ingress, Payload: payload, Err: errors.New("timeout")}}, now)
+	accountTrimmedPayloads(kmCfg, []packaging.PayloadSummary{payload}, now)
+	windows = kmCfg.Status.MissedWindows.Windows
+	if len(windows) != 2 || windows[0].Destination != "" || windows[0].Reason == "timeout" {
+		t.Errorf("got missed windows %+v want the removed payload", windows)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+// Endpoint is a kind of cloud.redhat.com endpoint that the operator sends requests to
+type Endpoint string
+
+const (
+	// IngressEndpoint is the Ingress API, or the relay, that payloads are uploaded to
+	IngressEndpoint Endpoint = "ingress"
+	// SourcesEndpoint is the Sources API
+	SourcesEndpoint Endpoint = "sources"
+	// SSOEndpoint is the SSO token endpoint of service accounts
+	SSOEndpoint Endpoint = "sso"
+)
+
+// CertPolicy is the certificate validation of each endpoint. The zero value validates every endpoint. The SSO
+// endpoint is sent the client secret of service accounts, so its certificate is always validated.
+type CertPolicy struct {
+	// SkipIngress skips the validation of the certificate of the ingress endpoint
+	SkipIngress bool
+	// SkipSources skips the validation of the certificate of the sources endpoint
+	SkipSources bool
+}
+
+// Validate returns true if the certificate of the endpoint is validated
+func (p CertPolicy) Validate(endpoint Endpoint) bool {
+	switch endpoint {
+	case IngressEndpoint:
+		return !p.SkipIngress
+	case SourcesEndpoint:
+		return !p.SkipSources
+	}
+	return true
+}
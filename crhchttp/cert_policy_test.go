@@ -0,0 +1,65 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetClientCertPolicy(t *testing.T) {
+	// the certificate of the test server is not signed by a trusted CA
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	policy := CertPolicy{SkipIngress: true}
+	clientTests := []struct {
+		name     string
+		endpoint Endpoint
+		wantErr  bool
+	}{
+		{name: "ingress is not validated", endpoint: IngressEndpoint, wantErr: false},
+		{name: "sources are validated", endpoint: SourcesEndpoint, wantErr: true},
+		{name: "sso is validated", endpoint: SSOEndpoint, wantErr: true},
+	}
+	for _, tt := range clientTests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", server.URL, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			resp, err := GetClient(&AuthConfig{CertPolicy: policy}, tt.endpoint).Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+
+	if !(CertPolicy{}).Validate(IngressEndpoint) || !(CertPolicy{}).Validate(SourcesEndpoint) {
+		t.Errorf("the zero certificate policy does not validate every endpoint")
+	}
+	if !(CertPolicy{SkipIngress: true, SkipSources: true}).Validate(SSOEndpoint) {
+		t.Errorf("the certificate policy does not validate the sso endpoint")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// clockSkewSamples is the number of measurements that the skew is the median of, so that a single delayed or wrong
+// Date header does not move the skew
+const clockSkewSamples = 5
+
+// maxClockSkew bounds the measurements. A Date header further than this from the local clock is not a skew that can
+// be corrected and is ignored.
+const maxClockSkew = 24 * time.Hour
+
+// clockSkew is the difference between the local clock and the clock of the API, measured from the Date header of
+// the most recent responses of the ingress endpoint
+type clockSkew struct {
+	mu       sync.RWMutex
+	samples  []time.Duration
+	offset   time.Duration
+	measured bool
+}
+
+var skew = &clockSkew{}
+
+// recordIngressClockSkew measures the skew from a response of the ingress endpoint. Only responses over TLS whose
+// certificate was validated are measured, so that a server that is not verified cannot move the manifest date.
+func recordIngressClockSkew(authConfig *AuthConfig, resp *http.Response, sent, received time.Time) {
+	if resp == nil || resp.TLS == nil {
+		return
+	}
+	if authConfig != nil && !authConfig.CertPolicy.Validate(IngressEndpoint) {
+		return
+	}
+	recordClockSkew(resp, sent, received)
+}
+
+// recordClockSkew measures the skew from the Date header of the response. The server time is compared with the
+// middle of the round trip, and the offset is rounded to the second that the Date header is precise to. The skew is
+// the median of the most recent measurements.
+func recordClockSkew(resp *http.Response, sent, received time.Time) {
+	if resp == nil {
+		return
+	}
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	local := sent.Add(received.Sub(sent) / 2)
+	offset := local.Sub(date).Round(time.Second)
+	if offset > maxClockSkew || offset < -maxClockSkew {
+		return
+	}
+	skew.mu.Lock()
+	defer skew.mu.Unlock()
+	skew.samples = append(skew.samples, offset)
+	if len(skew.samples) > clockSkewSamples {
+		skew.samples = skew.samples[len(skew.samples)-clockSkewSamples:]
+	}
+	sorted := append([]time.Duration{}, skew.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	skew.offset = sorted[len(sorted)/2]
+	skew.measured = true
+}
+
+// ClockSkew returns how far the local clock is ahead of the clock of the API. A negative skew means that the local
+// clock is behind. false is returned if no response with a Date header has been received.
+func ClockSkew() (time.Duration, bool) {
+	skew.mu.RLock()
+	defer skew.mu.RUnlock()
+	return skew.offset, skew.measured
+}
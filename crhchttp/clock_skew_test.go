@@ -0,0 +1,131 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRecordClockSkew(t *testing.T) {
+	defer func() { skew = &clockSkew{} }()
+	server := time.Date(2021, 1, 5, 18, 0, 0, 0, time.UTC)
+	skewTests := []struct {
+		name       string
+		date       string
+		sent       time.Time
+		received   time.Time
+		want       time.Duration
+		wantRecord bool
+	}{
+		{
+			name:       "local clock is ahead",
+			date:       server.Format(http.TimeFormat),
+			sent:       server.Add(10 * time.Minute),
+			received:   server.Add(10*time.Minute + 2*time.Second),
+			want:       10*time.Minute + time.Second,
+			wantRecord: true,
+		},
+		{
+			name:       "local clock is behind",
+			date:       server.Format(http.TimeFormat),
+			sent:       server.Add(-time.Hour),
+			received:   server.Add(-time.Hour),
+			want:       -time.Hour,
+			wantRecord: true,
+		},
+		{
+			name:     "missing date header is ignored",
+			sent:     server,
+			received: server,
+		},
+	}
+	for _, tt := range skewTests {
+		t.Run(tt.name, func(t *testing.T) {
+			skew = &clockSkew{}
+			resp := &http.Response{Header: http.Header{}}
+			if tt.date != "" {
+				resp.Header.Set("Date", tt.date)
+			}
+			recordClockSkew(resp, tt.sent, tt.received)
+			got, recorded := ClockSkew()
+			if recorded != tt.wantRecord {
+				t.Errorf("%s got recorded %t want %t", tt.name, recorded, tt.wantRecord)
+			}
+			if got != tt.want {
+				t.Errorf("%s got skew %s want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordClockSkewMedian(t *testing.T) {
+	defer func() { skew = &clockSkew{} }()
+	skew = &clockSkew{}
+	server := time.Date(2021, 1, 5, 18, 0, 0, 0, time.UTC)
+	resp := &http.Response{Header: http.Header{"Date": []string{server.Format(http.TimeFormat)}}}
+
+	// a measurement beyond the bound is ignored, and a single outlier does not move the median
+	for _, offset := range []time.Duration{10 * time.Minute, 48 * time.Hour, 11 * time.Minute, 2 * time.Hour, 9 * time.Minute, 10 * time.Minute} {
+		recordClockSkew(resp, server.Add(offset), server.Add(offset))
+	}
+	if got, _ := ClockSkew(); got != 10*time.Minute {
+		t.Errorf("got skew %s want 10m0s", got)
+	}
+
+	// only the most recent measurements are kept
+	for i := 0; i < clockSkewSamples; i++ {
+		recordClockSkew(resp, server, server)
+	}
+	if got, _ := ClockSkew(); got != 0 {
+		t.Errorf("got skew %s want 0s", got)
+	}
+}
+
+func TestRecordIngressClockSkew(t *testing.T) {
+	defer func() { skew = &clockSkew{} }()
+	server := time.Date(2021, 1, 5, 18, 0, 0, 0, time.UTC)
+	local := server.Add(10 * time.Minute)
+	ingressTests := []struct {
+		name       string
+		tls        bool
+		authConfig *AuthConfig
+		wantRecord bool
+	}{
+		{name: "verified response", tls: true, authConfig: &AuthConfig{}, wantRecord: true},
+		{name: "plain http response", authConfig: &AuthConfig{}},
+		{name: "unverified response", tls: true, authConfig: &AuthConfig{CertPolicy: CertPolicy{SkipIngress: true}}},
+	}
+	for _, tt := range ingressTests {
+		t.Run(tt.name, func(t *testing.T) {
+			skew = &clockSkew{}
+			resp := &http.Response{Header: http.Header{"Date": []string{server.Format(http.TimeFormat)}}}
+			if tt.tls {
+				resp.TLS = &tls.ConnectionState{}
+			}
+			recordIngressClockSkew(tt.authConfig, resp, local, local)
+			if _, recorded := ClockSkew(); recorded != tt.wantRecord {
+				t.Errorf("%s got recorded %t want %t", tt.name, recorded, tt.wantRecord)
+			}
+		})
+	}
+}
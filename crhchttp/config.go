@@ -20,6 +20,8 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package crhchttp
 
 import (
+	"net/http"
+
 	"github.com/go-logr/logr"
 	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,7 +35,15 @@ type AuthConfig struct {
 	BearerTokenString string
 	BasicAuthUser     string
 	BasicAuthPassword string
-	ValidateCert      bool
-	OperatorCommit    string
-	Log               logr.Logger
+	StaticTokenHeader string
+	StaticToken       string
+	// CertPolicy is the certificate validation of each endpoint
+	CertPolicy     CertPolicy
+	OperatorCommit string
+	Log            logr.Logger
+	// ExtraHeaders are added to every request. The values of the SensitiveHeaders are masked in the logs.
+	ExtraHeaders     http.Header
+	SensitiveHeaders []string
+	// IdempotencyKey, when set, is sent in the IdempotencyKeyHeader so that the retries of an upload can be deduplicated
+	IdempotencyKey string
 }
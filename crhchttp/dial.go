@@ -0,0 +1,224 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// IP families that can be preferred when dialing
+const (
+	IPv4 = "ipv4"
+	IPv6 = "ipv6"
+)
+
+// defaultFallbackDelay is how long the preferred address family is tried before the other family is
+// raced against it, as recommended by RFC 8305
+const defaultFallbackDelay = 300 * time.Millisecond
+
+// DialConfig configures how connections are dialed on IPv6-only and dual-stack clusters
+type DialConfig struct {
+	// IPFamily is the address family tried first. Addresses of the other family are raced against it after
+	// the fallback delay. When empty, the order returned by the resolver is used.
+	IPFamily string
+	// Resolver is the address of a DNS server that is used instead of the system resolver.
+	Resolver string
+	// FallbackDelay is how long the preferred family is tried before the other family is raced against it.
+	FallbackDelay time.Duration
+}
+
+// DNSError is returned when the host of a request cannot be resolved, so that resolver failures are reported
+// apart from connection failures.
+type DNSError struct {
+	Host     string
+	Resolver string
+	Err      error
+}
+
+func (e *DNSError) Error() string {
+	resolver := "the system resolver"
+	if e.Resolver != "" {
+		resolver = "resolver " + e.Resolver
+	}
+	return fmt.Sprintf("failed to resolve %s with %s: %v", e.Host, resolver, e.Err)
+}
+
+// Unwrap returns the resolver error
+func (e *DNSError) Unwrap() error { return e.Err }
+
+// IsDNSError returns true if the error is caused by a failure to resolve a host
+func IsDNSError(err error) bool {
+	var dnsErr *DNSError
+	var netDNSErr *net.DNSError
+	return errors.As(err, &dnsErr) || errors.As(err, &netDNSErr)
+}
+
+// resolver returns the resolver that is used to look up hosts
+func (c DialConfig) resolver(dialer *net.Dialer) *net.Resolver {
+	if c.Resolver == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, c.Resolver)
+		},
+	}
+}
+
+// partition splits the addresses into those of the preferred family and the others. Without a preferred
+// family, the family of the first address is preferred.
+func (c DialConfig) partition(addrs []net.IPAddr) ([]net.IPAddr, []net.IPAddr) {
+	if len(addrs) == 0 {
+		return nil, nil
+	}
+	preferIPv4 := addrs[0].IP.To4() != nil
+	switch c.IPFamily {
+	case IPv4:
+		preferIPv4 = true
+	case IPv6:
+		preferIPv4 = false
+	}
+	var primaries, fallbacks []net.IPAddr
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == preferIPv4 {
+			primaries = append(primaries, addr)
+		} else {
+			fallbacks = append(fallbacks, addr)
+		}
+	}
+	if len(primaries) == 0 {
+		return fallbacks, nil
+	}
+	return primaries, fallbacks
+}
+
+// dialContext returns the function the transport dials connections with
+func (c DialConfig) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	resolver := c.resolver(dialer)
+	delay := c.FallbackDelay
+	if delay <= 0 {
+		delay = defaultFallbackDelay
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, &DNSError{Host: host, Resolver: c.Resolver, Err: err}
+		}
+		primaries, fallbacks := c.partition(addrs)
+		if len(primaries) == 0 {
+			return nil, &DNSError{Host: host, Resolver: c.Resolver, Err: errors.New("no addresses found")}
+		}
+		return dialParallel(ctx, dialer, network, port, primaries, fallbacks, delay)
+	}
+}
+
+// dialSerial dials the addresses in order until one connects
+func dialSerial(ctx context.Context, dialer *net.Dialer, network, port string, addrs []net.IPAddr) (net.Conn, error) {
+	var firstErr error
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// dialParallel dials the primary addresses, and races the fallback addresses against them once the primaries
+// fail or the fallback delay expires
+func dialParallel(ctx context.Context, dialer *net.Dialer, network, port string, primaries, fallbacks []net.IPAddr, delay time.Duration) (net.Conn, error) {
+	if len(fallbacks) == 0 {
+		return dialSerial(ctx, dialer, network, port, primaries)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dialResult, 2)
+	start := func(addrs []net.IPAddr) {
+		go func() {
+			conn, err := dialSerial(ctx, dialer, network, port, addrs)
+			results <- dialResult{conn: conn, err: err}
+		}()
+	}
+
+	start(primaries)
+	pending := 1
+	fallbackStarted := false
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks)
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				// close a connection that the other race may still establish
+				go func(pending int) {
+					for i := 0; i < pending; i++ {
+						if other := <-results; other.conn != nil {
+							other.conn.Close()
+						}
+					}
+				}(pending)
+				return res.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if !fallbackStarted {
+				fallbackStarted = true
+				pending++
+				start(fallbacks)
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
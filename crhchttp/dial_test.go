@@ -0,0 +1,123 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ipAddrs(ips ...string) []net.IPAddr {
+	addrs := []net.IPAddr{}
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs
+}
+
+func TestPartition(t *testing.T) {
+	partitionTests := []struct {
+		name          string
+		family        string
+		addrs         []net.IPAddr
+		wantPrimaries string
+		wantFallbacks string
+	}{
+		{name: "resolver order", addrs: ipAddrs("10.0.0.1", "fd00::1"), wantPrimaries: "[10.0.0.1]", wantFallbacks: "[fd00::1]"},
+		{name: "prefer ipv6", family: IPv6, addrs: ipAddrs("10.0.0.1", "fd00::1", "10.0.0.2"), wantPrimaries: "[fd00::1]", wantFallbacks: "[10.0.0.1 10.0.0.2]"},
+		{name: "prefer ipv4", family: IPv4, addrs: ipAddrs("fd00::1", "10.0.0.1"), wantPrimaries: "[10.0.0.1]", wantFallbacks: "[fd00::1]"},
+		{name: "preferred family missing", family: IPv4, addrs: ipAddrs("fd00::1", "fd00::2"), wantPrimaries: "[fd00::1 fd00::2]", wantFallbacks: "[]"},
+	}
+	for _, tt := range partitionTests {
+		t.Run(tt.name, func(t *testing.T) {
+			primaries, fallbacks := DialConfig{IPFamily: tt.family}.partition(tt.addrs)
+			if got := fmt.Sprint(addrStrings(primaries)); got != tt.wantPrimaries {
+				t.Errorf("%s got primaries %s want %s", tt.name, got, tt.wantPrimaries)
+			}
+			if got := fmt.Sprint(addrStrings(fallbacks)); got != tt.wantFallbacks {
+				t.Errorf("%s got fallbacks %s want %s", tt.name, got, tt.wantFallbacks)
+			}
+		})
+	}
+}
+
+func addrStrings(addrs []net.IPAddr) []string {
+	s := []string{}
+	for _, addr := range addrs {
+		s = append(s, addr.String())
+	}
+	return s
+}
+
+func TestDialParallelFallback(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	// nothing listens on 127.0.0.2, so the fallback address connects
+	conn, err := dialParallel(context.Background(), &net.Dialer{Timeout: time.Second}, "tcp", port,
+		ipAddrs("127.0.0.2"), ipAddrs("127.0.0.1"), time.Minute)
+	if err != nil {
+		t.Fatalf("expected the fallback to connect, got: %v", err)
+	}
+	conn.Close()
+
+	_, err = dialParallel(context.Background(), &net.Dialer{Timeout: time.Second}, "tcp", port,
+		ipAddrs("127.0.0.2"), ipAddrs("127.0.0.3"), time.Minute)
+	if err == nil {
+		t.Error("expected an error when no address connects")
+	}
+}
+
+func TestDialContextDNSError(t *testing.T) {
+	// nothing answers on the resolver address, so the host cannot be resolved
+	dial := DialConfig{Resolver: "127.0.0.1:1"}.dialContext(&net.Dialer{Timeout: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := dial(ctx, "tcp", "ingress.example.com:443")
+	if err == nil {
+		t.Fatal("expected a resolver error")
+	}
+	if !IsDNSError(err) {
+		t.Errorf("expected a dns error, got: %v", err)
+	}
+	if sendErr := sendError(fmt.Errorf("Post: %w", err)); !strings.HasPrefix(sendErr.Error(), "could not resolve the host") {
+		t.Errorf("unexpected send error: %v", sendErr)
+	}
+	if wrapped := fmt.Errorf("Export: %w", sendError(err)); !IsSendError(wrapped) || !IsDNSError(wrapped) {
+		t.Errorf("expected a wrapped send error of a dns error, got: %v", wrapped)
+	}
+}
@@ -22,8 +22,7 @@ package crhchttp
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
-	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -38,20 +37,25 @@ import (
 
 	"github.com/go-logr/logr"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/project-koku/koku-metrics-operator/logging"
 )
 
 // Client is an http.Client
 var Client HTTPClient
 var cacerts = "/etc/ssl/certs/ca-certificates.crt"
 
+// defaultDialer is the dialer of the golang http package
+var defaultDialer = &net.Dialer{
+	Timeout:   30 * time.Second,
+	KeepAlive: 30 * time.Second,
+	DualStack: true,
+}
+
 // DefaultTransport is a copy from the golang http package
 var DefaultTransport = &http.Transport{
-	Proxy: http.ProxyFromEnvironment,
-	DialContext: (&net.Dialer{
-		Timeout:   30 * time.Second,
-		KeepAlive: 30 * time.Second,
-		DualStack: true,
-	}).DialContext,
+	Proxy:                 http.ProxyFromEnvironment,
+	DialContext:           defaultDialer.DialContext,
 	ForceAttemptHTTP2:     true,
 	MaxIdleConns:          100,
 	IdleConnTimeout:       90 * time.Second,
@@ -64,10 +68,54 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-func scrubAuthorization(b []byte) string {
+// Uploader sends payloads to, and checks the reachability of, the ingress endpoint
+type Uploader interface {
+	Upload(authConfig *AuthConfig, contentType, method, uri string, body *bytes.Buffer) (UploadResponse, error)
+	CheckIngress(authConfig *AuthConfig, uri string) (string, error)
+}
+
+// UploadResponse is the outcome of an upload request
+type UploadResponse struct {
+	// Status is the http status of the response, such as "202 Accepted"
+	Status string
+	// Time is the time the response was received, or the time the request was sent if the upload failed
+	Time metav1.Time
+	// RequestID is the ID that ingress assigned to the upload, from the x-rh-insights-request-id header
+	RequestID string
+}
+
+// APIUploader is the Uploader that sends requests with the client returned by GetClient
+type APIUploader struct{}
+
+var _ Uploader = APIUploader{}
+
+// Upload sends the payload with Upload
+func (APIUploader) Upload(authConfig *AuthConfig, contentType, method, uri string, body *bytes.Buffer) (UploadResponse, error) {
+	return Upload(authConfig, contentType, method, uri, body)
+}
+
+// CheckIngress checks the ingress endpoint with CheckIngress
+func (APIUploader) CheckIngress(authConfig *AuthConfig, uri string) (string, error) {
+	return CheckIngress(authConfig, uri)
+}
+
+// IdempotencyKeyHeader is the header of the key that identifies the uploads of the same payload
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ReservedHeader returns true if the header is set by the operator and cannot be set as an extra header
+func ReservedHeader(name, staticTokenHeader string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Content-Type", "Content-Length", "Host", "User-Agent", IdempotencyKeyHeader:
+		return true
+	}
+	return staticTokenHeader != "" && http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(staticTokenHeader)
+}
+
+func scrubAuthorization(b []byte, headers ...string) string {
+	headers = append(headers, "Authorization")
 	str := strings.Split(string(b), "\r\n")
 	for i, s := range str {
-		if strings.Contains(s, "Authorization") {
+		if containsHeader(s, headers) {
 			slice := strings.Split(s, " ")
 			idx := len(slice) - 1
 			slice[idx] = strings.Repeat("*", len(slice[idx]))
@@ -101,6 +149,24 @@ func GetMultiPartBodyAndHeaders(filename string) (*bytes.Buffer, string, error)
 	return buf, mw.FormDataContentType(), mw.Close()
 }
 
+func containsHeader(line string, headers []string) bool {
+	for _, h := range headers {
+		if h != "" && strings.HasPrefix(strings.ToLower(line), strings.ToLower(h)+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPassthroughBodyAndHeaders returns the raw payload as the request body, for relays that do not accept multipart uploads
+func GetPassthroughBodyAndHeaders(filename string) (*bytes.Buffer, string, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %v", err)
+	}
+	return bytes.NewBuffer(contents), "application/vnd.redhat.hccm.tar+tgz", nil
+}
+
 // SetupRequest creates a new request, adds headers to request object for communication to cloud.redhat.com, and returns the request
 func SetupRequest(authConfig *AuthConfig, contentType, method, uri string, body *bytes.Buffer) (*http.Request, error) {
 	log := authConfig.Log.WithValues("kokumetricsconfig", "SetupRequest")
@@ -118,48 +184,97 @@ func SetupRequest(authConfig *AuthConfig, contentType, method, uri string, body
 	case "basic":
 		log.Info("request using basic authentication")
 		req.SetBasicAuth(authConfig.BasicAuthUser, authConfig.BasicAuthPassword)
+	case "static":
+		log.Info("request using static token authentication", "header", authConfig.StaticTokenHeader)
+		req.Header.Set(authConfig.StaticTokenHeader, authConfig.StaticToken)
+		req.Header.Set("User-Agent", fmt.Sprintf("cost-mgmt-operator/%s cluster/%s", authConfig.OperatorCommit, authConfig.ClusterID))
+	case "service-account":
+		log.Info("request using service account authentication")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", authConfig.BearerTokenString))
+		req.Header.Set("User-Agent", fmt.Sprintf("cost-mgmt-operator/%s cluster/%s", authConfig.OperatorCommit, authConfig.ClusterID))
 	default:
 		log.Info("request using token authentication")
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", authConfig.BearerTokenString))
 		req.Header.Set("User-Agent", fmt.Sprintf("cost-mgmt-operator/%s cluster/%s", authConfig.OperatorCommit, authConfig.ClusterID))
 	}
 
+	if authConfig.IdempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, authConfig.IdempotencyKey)
+	}
+
+	// the extra headers cannot replace the headers set above
+	for name, values := range authConfig.ExtraHeaders {
+		if ReservedHeader(name, authConfig.StaticTokenHeader) {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
 	// log the request headers
 	byteReq, err := httputil.DumpRequest(req, false)
 	if err == nil { // only log if the dump is successful
-		log.Info(fmt.Sprintf("request:\n%s", scrubAuthorization(byteReq)))
+		scrubbed := append([]string{authConfig.StaticTokenHeader}, authConfig.SensitiveHeaders...)
+		log.Info(fmt.Sprintf("request:\n%s", scrubAuthorization(byteReq, scrubbed...)))
 	}
 
 	return req, nil
 }
 
-// GetClient Return client with certificate handling based on configuration
-func GetClient(authConfig *AuthConfig) HTTPClient {
-	log := authConfig.Log.WithValues("kokumetricsconfig", "GetClient")
-	transport := DefaultTransport
-	if authConfig.ValidateCert {
-		// create the client specifying the ca cert file for transport
-		caCert, err := ioutil.ReadFile(cacerts)
-		if err != nil {
-			log.Error(err, "The following error occurred: ") // TODO fix this error handling
-		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
+// GetClient Return client with certificate handling based on configuration. Certificates are validated against the
+// system CAs and the cluster-wide trusted CA bundle, which is reloaded when it changes, unless the certificate policy
+// skips the validation of the endpoint.
+func GetClient(authConfig *AuthConfig, endpoint Endpoint) HTTPClient {
+	skipVerify := authConfig != nil && !authConfig.CertPolicy.Validate(endpoint)
+	return &http.Client{Timeout: 30 * time.Second, Transport: TrustedTransport{InsecureSkipVerify: skipVerify}}
+}
 
-		transport.TLSClientConfig = &tls.Config{RootCAs: caCertPool}
+// SendError is an error sending a request: the host could not be resolved or connected to, or no response was
+// received, so the endpoint was not reached
+type SendError struct {
+	Err error
+}
+
+func (e *SendError) Error() string {
+	if IsDNSError(e.Err) {
+		return fmt.Sprintf("could not resolve the host: %v", e.Err)
 	}
-	// Default the client
-	return &http.Client{Timeout: 30 * time.Second, Transport: transport}
+	return fmt.Sprintf("could not send the request: %v", e.Err)
+}
+
+// Unwrap returns the transport or resolver error
+func (e *SendError) Unwrap() error { return e.Err }
+
+// IsSendError returns true if the error is caused by a failure to send a request
+func IsSendError(err error) bool {
+	var sendErr *SendError
+	return errors.As(err, &sendErr)
+}
+
+// sendError describes an error sending a request, telling resolver failures apart from connection failures
+func sendError(err error) error {
+	return &SendError{Err: err}
+}
+
+// ResponseError is the error returned by ProcessResponse for a response with a status other than 2xx
+type ResponseError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("status: %d | error response: %s", e.StatusCode, e.Body)
 }
 
 // ProcessResponse Log response for request and return valid
 func ProcessResponse(logger logr.Logger, resp *http.Response) ([]byte, error) {
-	log := logger.WithValues("kokumetricsconfig", "ProcessResponse")
+	log := logger.WithValues("kokumetricsconfig", "ProcessResponse", logging.HTTPStatus, resp.StatusCode)
 	log.Info("request response",
 		"method", resp.Request.Method,
-		"status", resp.StatusCode,
 		"URL", resp.Request.URL,
 		"x-rh-insights-request-id", resp.Header.Get("x-rh-insights-request-id"))
+	LogNegotiatedTLS(log, resp.Request.URL.Host, resp.TLS)
 
 	dump, err := httputil.DumpResponse(resp, true)
 	if err != nil {
@@ -174,7 +289,7 @@ func ProcessResponse(logger logr.Logger, resp *http.Response) ([]byte, error) {
 	body := bodySlice[1]
 
 	if resp.StatusCode >= 300 || resp.StatusCode < 200 {
-		return nil, fmt.Errorf("status: %d | error response: %s", resp.StatusCode, body)
+		return nil, &ResponseError{StatusCode: resp.StatusCode, Body: body}
 	}
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
@@ -184,28 +299,63 @@ func ProcessResponse(logger logr.Logger, resp *http.Response) ([]byte, error) {
 }
 
 // Upload Send data to cloud.redhat.com
-func Upload(authConfig *AuthConfig, contentType, method, uri string, body *bytes.Buffer) (string, metav1.Time, error) {
+func Upload(authConfig *AuthConfig, contentType, method, uri string, body *bytes.Buffer) (UploadResponse, error) {
 	log := authConfig.Log.WithValues("kokumetricsconfig", "Upload")
-	currentTime := metav1.Now()
+	response := UploadResponse{Time: metav1.Now()}
 	req, err := SetupRequest(authConfig, contentType, method, uri, body)
 	if err != nil {
-		return "", currentTime, fmt.Errorf("could not setup the request: %v", err)
+		return response, fmt.Errorf("could not setup the request: %v", err)
 	}
 
-	client := GetClient(authConfig)
+	client := GetClient(authConfig, IngressEndpoint)
+	sent := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", currentTime, fmt.Errorf("could not send the request: %v", err)
+		return response, sendError(err)
 	}
 	defer resp.Body.Close()
+	recordIngressClockSkew(authConfig, resp, sent, time.Now())
 
-	uploadStatus := fmt.Sprintf("%d ", resp.StatusCode) + string(http.StatusText(resp.StatusCode))
+	response.Status = fmt.Sprintf("%d ", resp.StatusCode) + string(http.StatusText(resp.StatusCode))
+	response.RequestID = resp.Header.Get("x-rh-insights-request-id")
 	uploadTime := metav1.Now()
 
 	_, err = ProcessResponse(log, resp)
 	if err != nil {
-		return uploadStatus, currentTime, err
+		return response, err
+	}
+
+	response.Time = uploadTime
+	return response, nil
+}
+
+// CheckIngress sends a HEAD request to the ingress endpoint to verify that the endpoint is reachable
+// and that the credentials are accepted, without uploading any data.
+func CheckIngress(authConfig *AuthConfig, uri string) (string, error) {
+	log := authConfig.Log.WithValues("kokumetricsconfig", "CheckIngress")
+	req, err := SetupRequest(authConfig, "", "HEAD", uri, &bytes.Buffer{})
+	if err != nil {
+		return "", fmt.Errorf("could not setup the request: %v", err)
 	}
 
-	return uploadStatus, uploadTime, nil
+	client := GetClient(authConfig, IngressEndpoint)
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", sendError(err)
+	}
+	defer resp.Body.Close()
+	recordIngressClockSkew(authConfig, resp, sent, time.Now())
+
+	status := fmt.Sprintf("%d ", resp.StatusCode) + http.StatusText(resp.StatusCode)
+	log.Info("ingress response", logging.HTTPStatus, resp.StatusCode, "x-rh-insights-request-id", resp.Header.Get("x-rh-insights-request-id"))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return status, fmt.Errorf("credentials were rejected by the ingress endpoint: %s", status)
+	case resp.StatusCode >= 500:
+		return status, fmt.Errorf("ingress endpoint returned a server error: %s", status)
+	}
+	// any other response means the endpoint is reachable and the request was authenticated.
+	// The upload endpoint does not need to support HEAD for the check to succeed.
+	return status, nil
 }
@@ -0,0 +1,132 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestProcessResponse(t *testing.T) {
+	responseTests := []struct {
+		name       string
+		status     int
+		body       string
+		wantBody   string
+		wantStatus int
+	}{
+		{name: "accepted", status: 202, body: `{"request_id": "1"}`, wantBody: `{"request_id": "1"}`},
+		{name: "rejected", status: 400, body: `{"errors": ["invalid"]}`, wantStatus: 400, wantBody: `{"errors": ["invalid"]}`},
+		{name: "server error", status: 503, body: "unavailable", wantStatus: 503, wantBody: "unavailable"},
+	}
+	for _, tt := range responseTests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "https://cloud.redhat.com/api/ingress/v1/upload", nil)
+			resp := &http.Response{
+				StatusCode:    tt.status,
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        http.Header{},
+				Body:          ioutil.NopCloser(strings.NewReader(tt.body)),
+				ContentLength: int64(len(tt.body)),
+				Request:       req,
+			}
+			body, err := ProcessResponse(testutils.TestLogger{}, resp)
+			if tt.wantStatus == 0 {
+				if err != nil || string(body) != tt.wantBody {
+					t.Errorf("%s got body %q and error %v want %q", tt.name, body, err, tt.wantBody)
+				}
+				return
+			}
+			var respErr *ResponseError
+			if !errors.As(err, &respErr) {
+				t.Fatalf("%s got error %v want a ResponseError", tt.name, err)
+			}
+			if respErr.StatusCode != tt.wantStatus || string(respErr.Body) != tt.wantBody {
+				t.Errorf("%s got %d %q want %d %q", tt.name, respErr.StatusCode, respErr.Body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSetupRequestExtraHeaders(t *testing.T) {
+	authConfig := &AuthConfig{
+		Log:               testutils.TestLogger{},
+		Authentication:    "static",
+		StaticTokenHeader: "X-Relay-Token",
+		StaticToken:       "token",
+		ExtraHeaders: http.Header{
+			"X-Org-Id":        []string{"12345"},
+			"X-Route":         []string{"a", "b"},
+			"X-Relay-Token":   []string{"override"},
+			"Content-Type":    []string{"text/plain"},
+			"Idempotency-Key": []string{"override"},
+		},
+		SensitiveHeaders: []string{"X-Org-Id"},
+		IdempotencyKey:   "key",
+	}
+	req, err := SetupRequest(authConfig, "application/json", "POST", "https://relay.example.com/upload", &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("failed to set up request: %v", err)
+	}
+	if got := req.Header.Get("X-Org-Id"); got != "12345" {
+		t.Errorf("got X-Org-Id %q want 12345", got)
+	}
+	if got := req.Header["X-Route"]; len(got) != 2 {
+		t.Errorf("got X-Route %v want [a b]", got)
+	}
+	if got := req.Header.Get("X-Relay-Token"); got != "token" {
+		t.Errorf("got X-Relay-Token %q want the static token", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("got Content-Type %q want application/json", got)
+	}
+	if got := req.Header.Get(IdempotencyKeyHeader); got != "key" {
+		t.Errorf("got Idempotency-Key %q want key", got)
+	}
+}
+
+func TestReservedHeader(t *testing.T) {
+	reservedTests := []struct {
+		name   string
+		header string
+		static string
+		want   bool
+	}{
+		{name: "authorization", header: "authorization", want: true},
+		{name: "user agent", header: "User-Agent", want: true},
+		{name: "idempotency key", header: "idempotency-key", want: true},
+		{name: "static token header", header: "x-relay-token", static: "X-Relay-Token", want: true},
+		{name: "routing header", header: "X-Org-ID", static: "X-Relay-Token"},
+	}
+	for _, tt := range reservedTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReservedHeader(tt.header, tt.static); got != tt.want {
+				t.Errorf("%s got %t want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// serviceAccountScope is the scope of the tokens requested for console service accounts
+const serviceAccountScope = "api.console"
+
+// ServiceAccountToken is a token issued by SSO for a console service account
+type ServiceAccountToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	// Expiry is the time the token expires, computed from ExpiresIn when the token is issued
+	Expiry time.Time `json:"-"`
+}
+
+// RequestServiceAccountToken requests a token from the SSO token endpoint with the client credentials grant of
+// a console service account. The token is sent as a bearer token with service account authentication.
+func RequestServiceAccountToken(authConfig *AuthConfig, tokenURL, clientID, clientSecret string) (ServiceAccountToken, error) {
+	log := authConfig.Log.WithValues("kokumetricsconfig", "RequestServiceAccountToken")
+	token := ServiceAccountToken{}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+	form.Set("scope", serviceAccountScope)
+	req, err := http.NewRequest("POST", tokenURL, bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return token, fmt.Errorf("RequestServiceAccountToken: could not create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	issued := time.Now()
+	resp, err := GetClient(authConfig, SSOEndpoint).Do(req)
+	if err != nil {
+		return token, fmt.Errorf("RequestServiceAccountToken: %v", sendError(err))
+	}
+	defer resp.Body.Close()
+	log.Info("token response", "URL", tokenURL, "status", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		return token, fmt.Errorf("RequestServiceAccountToken: SSO rejected the service account credentials: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return token, fmt.Errorf("RequestServiceAccountToken: failed to parse token response: %v", err)
+	}
+	if token.AccessToken == "" {
+		return token, fmt.Errorf("RequestServiceAccountToken: token response did not include an access token")
+	}
+	token.Expiry = issued.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return token, nil
+}
@@ -0,0 +1,75 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestRequestServiceAccountToken(t *testing.T) {
+	tokenTests := []struct {
+		name     string
+		status   int
+		response string
+		want     string
+		wantErr  bool
+	}{
+		{name: "token is issued", status: http.StatusOK, response: `{"access_token":"abc","expires_in":900}`, want: "abc"},
+		{name: "credentials are rejected", status: http.StatusUnauthorized, response: `{"error":"invalid_client"}`, wantErr: true},
+		{name: "response without a token", status: http.StatusOK, response: `{"expires_in":900}`, wantErr: true},
+		{name: "response is not json", status: http.StatusOK, response: `token`, wantErr: true},
+	}
+	for _, tt := range tokenTests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Errorf("failed to parse form: %v", err)
+				}
+				for key, want := range map[string]string{"grant_type": "client_credentials", "client_id": "id", "client_secret": "secret", "scope": serviceAccountScope} {
+					if got := r.PostForm.Get(key); got != want {
+						t.Errorf("%s got form %s=%q want %q", tt.name, key, got, want)
+					}
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+
+			authConfig := &AuthConfig{Log: testutils.TestLogger{}}
+			before := time.Now()
+			got, err := RequestServiceAccountToken(authConfig, server.URL, "id", "secret")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			if got.AccessToken != tt.want {
+				t.Errorf("%s got token %q want %q", tt.name, got.AccessToken, tt.want)
+			}
+			if !tt.wantErr && got.Expiry.Before(before.Add(900*time.Second)) {
+				t.Errorf("%s got expiry %v want 900 seconds after the request", tt.name, got.Expiry)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// TLSSettings are the TLS settings of a transport. The zero value leaves the defaults of the Go TLS client.
+type TLSSettings struct {
+	// MinVersion is the minimum TLS version, or 0 for the default
+	MinVersion uint16
+	// CipherSuites are the cipher suites offered for TLS 1.2 and earlier, or nil for the default
+	CipherSuites []uint16
+}
+
+var tlsVersions = map[string]uint16{
+	"VersionTLS10": tls.VersionTLS10,
+	"VersionTLS11": tls.VersionTLS11,
+	"VersionTLS12": tls.VersionTLS12,
+	"VersionTLS13": tls.VersionTLS13,
+}
+
+// cipherSuites are the cipher suites that can be configured, by IANA name
+var cipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_RC4_128_SHA":                tls.TLS_RSA_WITH_RC4_128_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA256":         tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":        tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":          tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":     tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":    tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+}
+
+// tls13CipherSuites are the cipher suites of TLS 1.3, which are always offered
+var tls13CipherSuites = map[string]uint16{
+	"TLS_AES_128_GCM_SHA256":       tls.TLS_AES_128_GCM_SHA256,
+	"TLS_AES_256_GCM_SHA384":       tls.TLS_AES_256_GCM_SHA384,
+	"TLS_CHACHA20_POLY1305_SHA256": tls.TLS_CHACHA20_POLY1305_SHA256,
+}
+
+// ParseTLSSettings returns the settings of the minimum version and the cipher suites of the spec. An error is
+// returned for an unknown version or cipher suite, and for a TLS 1.3 cipher suite, which cannot be configured.
+func ParseTLSSettings(minVersion string, suites []string) (TLSSettings, error) {
+	var settings TLSSettings
+	if minVersion != "" {
+		version, ok := tlsVersions[minVersion]
+		if !ok {
+			return TLSSettings{}, fmt.Errorf("ParseTLSSettings: unknown TLS version %q", minVersion)
+		}
+		settings.MinVersion = version
+	}
+	for _, name := range suites {
+		if _, ok := tls13CipherSuites[name]; ok {
+			return TLSSettings{}, fmt.Errorf("ParseTLSSettings: the TLS 1.3 cipher suite %s cannot be configured", name)
+		}
+		id, ok := cipherSuites[name]
+		if !ok {
+			return TLSSettings{}, fmt.Errorf("ParseTLSSettings: unknown cipher suite %q", name)
+		}
+		settings.CipherSuites = append(settings.CipherSuites, id)
+	}
+	if settings.MinVersion == tls.VersionTLS13 && len(settings.CipherSuites) > 0 {
+		return TLSSettings{}, fmt.Errorf("ParseTLSSettings: cipher suites cannot be configured when the minimum version is TLS 1.3")
+	}
+	return settings, nil
+}
+
+// IsZero returns true if the settings leave the defaults of the Go TLS client
+func (s TLSSettings) IsZero() bool {
+	return s.MinVersion == 0 && len(s.CipherSuites) == 0
+}
+
+// Apply sets the minimum version and the cipher suites in cfg
+func (s TLSSettings) Apply(cfg *tls.Config) {
+	if s.MinVersion != 0 {
+		cfg.MinVersion = s.MinVersion
+	}
+	if len(s.CipherSuites) > 0 {
+		cfg.CipherSuites = append([]uint16(nil), s.CipherSuites...)
+	}
+}
+
+// TLSVersionName returns the name of a TLS version
+func TLSVersionName(version uint16) string {
+	for name, v := range tlsVersions {
+		if v == version {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+// CipherSuiteName returns the IANA name of a cipher suite
+func CipherSuiteName(id uint16) string {
+	for _, names := range []map[string]uint16{cipherSuites, tls13CipherSuites} {
+		for name, v := range names {
+			if v == id {
+				return name
+			}
+		}
+	}
+	return fmt.Sprintf("0x%04x", id)
+}
+
+// LogNegotiatedTLS writes the negotiated TLS version and cipher suite of a connection to the debug log
+func LogNegotiatedTLS(log logr.Logger, host string, state *tls.ConnectionState) {
+	if state == nil {
+		return
+	}
+	log.V(1).Info("negotiated TLS", "host", host, "version", TLSVersionName(state.Version),
+		"cipherSuite", CipherSuiteName(state.CipherSuite))
+}
@@ -0,0 +1,79 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestParseTLSSettings(t *testing.T) {
+	parseTests := []struct {
+		name       string
+		minVersion string
+		suites     []string
+		want       TLSSettings
+		wantErr    bool
+	}{
+		{name: "defaults", want: TLSSettings{}},
+		{name: "minimum version", minVersion: "VersionTLS12", want: TLSSettings{MinVersion: tls.VersionTLS12}},
+		{
+			name:       "cipher suites",
+			minVersion: "VersionTLS12",
+			suites:     []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"},
+			want: TLSSettings{
+				MinVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+			},
+		},
+		{name: "unknown version", minVersion: "1.2", wantErr: true},
+		{name: "unknown cipher suite", suites: []string{"ECDHE-RSA-AES128-GCM-SHA256"}, wantErr: true},
+		{name: "TLS 1.3 cipher suite", suites: []string{"TLS_AES_128_GCM_SHA256"}, wantErr: true},
+		{name: "cipher suites with TLS 1.3", minVersion: "VersionTLS13", suites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}, wantErr: true},
+	}
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTLSSettings(tt.minVersion, tt.suites)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s got error %v want error %v", tt.name, err, tt.wantErr)
+			}
+			if got.MinVersion != tt.want.MinVersion || len(got.CipherSuites) != len(tt.want.CipherSuites) {
+				t.Fatalf("%s got %+v want %+v", tt.name, got, tt.want)
+			}
+			for i := range got.CipherSuites {
+				if got.CipherSuites[i] != tt.want.CipherSuites[i] {
+					t.Errorf("%s got cipher suites %v want %v", tt.name, got.CipherSuites, tt.want.CipherSuites)
+				}
+			}
+		})
+	}
+}
+
+func TestTLSNames(t *testing.T) {
+	if got := TLSVersionName(tls.VersionTLS13); got != "VersionTLS13" {
+		t.Errorf("got version name %s want VersionTLS13", got)
+	}
+	if got := CipherSuiteName(tls.TLS_AES_256_GCM_SHA384); got != "TLS_AES_256_GCM_SHA384" {
+		t.Errorf("got cipher suite name %s want TLS_AES_256_GCM_SHA384", got)
+	}
+	if got := CipherSuiteName(0x1234); got != "0x1234" {
+		t.Errorf("got cipher suite name %s want 0x1234", got)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package crhchttp
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// TrustedCABundle is where the cluster-wide trusted CA bundle is mounted. The bundle is injected into the
+// koku-metrics-trusted-ca-bundle ConfigMap because it has the config.openshift.io/inject-trusted-cabundle label,
+// and contains the CA of the cluster proxy when one is configured.
+var TrustedCABundle = "/etc/koku-metrics-operator/trusted-ca/ca-bundle.crt"
+
+// trustedCAPollInterval is how often the mounted bundle is checked for changes
+var trustedCAPollInterval = time.Minute
+
+// trustedCA holds the transport that validates certificates against the system and trusted CA bundles
+type trustedCA struct {
+	mu        sync.RWMutex
+	transport *http.Transport
+	// insecure is the transport with the same settings that does not validate certificates
+	insecure *http.Transport
+	checksum [sha256.Size]byte
+	dial     DialConfig
+	tls      TLSSettings
+}
+
+var trusted = &trustedCA{}
+
+// readBundles returns the concatenated contents of the CA bundles that exist
+func readBundles() ([]byte, error) {
+	var pem []byte
+	for _, path := range []string{cacerts, TrustedCABundle} {
+		contents, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("readBundles: failed to read %s: %v", path, err)
+		}
+		pem = append(pem, contents...)
+		pem = append(pem, '\n')
+	}
+	return pem, nil
+}
+
+// ReloadTrustedCA rebuilds the transport used by GetClient when the CA bundles have changed. It returns
+// true if the transport was rebuilt.
+func ReloadTrustedCA() (bool, error) {
+	changed, err := reloadTransport(nil, nil)
+	if err != nil {
+		return false, fmt.Errorf("ReloadTrustedCA: %v", err)
+	}
+	return changed, nil
+}
+
+// SetDialConfig rebuilds the transport used by GetClient when the dial configuration has changed. It returns
+// true if the transport was rebuilt.
+func SetDialConfig(dial DialConfig) (bool, error) {
+	changed, err := reloadTransport(&dial, nil)
+	if err != nil {
+		return false, fmt.Errorf("SetDialConfig: %v", err)
+	}
+	return changed, nil
+}
+
+// SetTLSSettings rebuilds the transport used by GetClient when the TLS settings have changed. It returns true if the
+// transport was rebuilt.
+func SetTLSSettings(settings TLSSettings) (bool, error) {
+	changed, err := reloadTransport(nil, &settings)
+	if err != nil {
+		return false, fmt.Errorf("SetTLSSettings: %v", err)
+	}
+	return changed, nil
+}
+
+// reloadTransport rebuilds the transport when the CA bundles, the dial configuration, or the TLS settings have changed
+func reloadTransport(dial *DialConfig, settings *TLSSettings) (bool, error) {
+	pem, err := readBundles()
+	if err != nil {
+		return false, err
+	}
+	checksum := sha256.Sum256(pem)
+
+	trusted.mu.Lock()
+	defer trusted.mu.Unlock()
+	dialChanged := dial != nil && *dial != trusted.dial
+	tlsChanged := settings != nil && !reflect.DeepEqual(*settings, trusted.tls)
+	if trusted.transport != nil && checksum == trusted.checksum && !dialChanged && !tlsChanged {
+		return false, nil
+	}
+	if dial != nil {
+		trusted.dial = *dial
+	}
+	if settings != nil {
+		trusted.tls = *settings
+	}
+
+	transport := DefaultTransport.Clone()
+	if trusted.dial != (DialConfig{}) {
+		transport.DialContext = trusted.dial.dialContext(defaultDialer)
+	}
+	tlsConfig := &tls.Config{}
+	pool := x509.NewCertPool()
+	if pool.AppendCertsFromPEM(pem) {
+		tlsConfig.RootCAs = pool
+	}
+	// without any readable bundle, the transport falls back to the system roots
+	trusted.tls.Apply(tlsConfig)
+	transport.TLSClientConfig = tlsConfig
+	insecure := transport.Clone()
+	insecure.TLSClientConfig.InsecureSkipVerify = true
+	if trusted.transport != nil {
+		trusted.transport.CloseIdleConnections()
+		trusted.insecure.CloseIdleConnections()
+	}
+	trusted.transport = transport
+	trusted.insecure = insecure
+	trusted.checksum = checksum
+	return true, nil
+}
+
+// TrustedTransport is an http.RoundTripper that always uses the most recently loaded CA bundles, so that
+// clients created before the bundle changes pick up the new CAs.
+type TrustedTransport struct {
+	// InsecureSkipVerify sends the requests without validating the certificate of the server
+	InsecureSkipVerify bool
+}
+
+// current returns the current transport
+func (t TrustedTransport) current() *http.Transport {
+	trusted.mu.RLock()
+	defer trusted.mu.RUnlock()
+	if t.InsecureSkipVerify {
+		return trusted.insecure
+	}
+	return trusted.transport
+}
+
+// RoundTrip sends the request using the current transport
+func (t TrustedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.current()
+	if transport == nil {
+		if _, err := ReloadTrustedCA(); err != nil {
+			return nil, err
+		}
+		transport = t.current()
+	}
+	return transport.RoundTrip(req)
+}
+
+// TrustedCAWatcher reloads the CA bundles when the mounted trusted CA ConfigMap changes. It implements the
+// controller-runtime Runnable interface so that it is started and stopped with the manager.
+type TrustedCAWatcher struct {
+	Log logr.Logger
+}
+
+// Start polls the CA bundles until stop is closed
+func (w *TrustedCAWatcher) Start(stop <-chan struct{}) error {
+	w.reload()
+	ticker := time.NewTicker(trustedCAPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+func (w *TrustedCAWatcher) reload() {
+	changed, err := ReloadTrustedCA()
+	if err != nil {
+		w.Log.Error(err, "failed to load the trusted CA bundle")
+		return
+	}
+	if changed {
+		w.Log.Info("loaded CA bundles", "trustedCABundle", TrustedCABundle)
+	}
+}
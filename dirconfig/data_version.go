@@ -0,0 +1,129 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package dirconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DataVersionFile is the name of the file in the parent directory that records the version of the data format
+const DataVersionFile = "data-version.json"
+
+// DataMigration upgrades the data on the volume to a version of the data format
+type DataMigration struct {
+	// Version is the data format version that the migration upgrades to. Versions start at 1 and are increasing.
+	Version int
+	// Name describes the migration in logs and in the status
+	Name string
+	// Migrate upgrades the data from the previous version. It must be safe to run again after a partial failure.
+	Migrate func(dirCfg *DirectoryConfig) (*Migration, error)
+}
+
+// DataVersion is the content of the data version file
+type DataVersion struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DataMigrationResult is the result of upgrading the data on the volume
+type DataMigrationResult struct {
+	Migration
+	// FromVersion is the version of the data format before the migrations ran
+	FromVersion int
+	// Version is the version of the data format after the migrations ran
+	Version int
+	// Applied are the names of the migrations that ran
+	Applied []string
+}
+
+// ReadDataVersion returns the version of the data format in the parent directory. Volumes written before the
+// data format was versioned have no data version file, and are at version 0.
+func (dirCfg *DirectoryConfig) ReadDataVersion() (int, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dirCfg.Parent.Path, DataVersionFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("ReadDataVersion: failed to read data version: %v", err)
+	}
+	version := DataVersion{}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return 0, fmt.Errorf("ReadDataVersion: failed to parse data version: %v", err)
+	}
+	return version.Version, nil
+}
+
+// writeDataVersion writes the data version to a temporary file and renames it so that it is never left partially written
+func (dirCfg *DirectoryConfig) writeDataVersion(version int) error {
+	data, err := json.Marshal(DataVersion{Version: version, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("writeDataVersion: failed to marshal data version: %v", err)
+	}
+	path := filepath.Join(dirCfg.Parent.Path, DataVersionFile)
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("writeDataVersion: failed to write data version: %v", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("writeDataVersion: failed to replace data version: %v", err)
+	}
+	return nil
+}
+
+// MigrateData runs the migrations newer than the data version of the volume in order, and records the version
+// after each migration, so that a failed migration is the first to run again on the next start. The data is left
+// untouched when it was written by a newer operator with a data format that this operator does not know.
+func (dirCfg *DirectoryConfig) MigrateData(migrations []DataMigration) (*DataMigrationResult, error) {
+	result := &DataMigrationResult{}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return result, fmt.Errorf("MigrateData: migration %q has version %d, want %d", migration.Name, migration.Version, i+1)
+		}
+	}
+
+	version, err := dirCfg.ReadDataVersion()
+	if err != nil {
+		return result, fmt.Errorf("MigrateData: %v", err)
+	}
+	result.FromVersion, result.Version = version, version
+	if version > len(migrations) {
+		return result, fmt.Errorf("MigrateData: data version %d is newer than %d", version, len(migrations))
+	}
+
+	for _, migration := range migrations[version:] {
+		moved, err := migration.Migrate(dirCfg)
+		if moved != nil {
+			result.Layouts = append(result.Layouts, moved.Layouts...)
+			result.Files += moved.Files
+		}
+		if err != nil {
+			return result, fmt.Errorf("MigrateData: migration %q failed: %v", migration.Name, err)
+		}
+		if err := dirCfg.writeDataVersion(migration.Version); err != nil {
+			return result, fmt.Errorf("MigrateData: %v", err)
+		}
+		result.Version = migration.Version
+		result.Applied = append(result.Applied, migration.Name)
+	}
+	return result, nil
+}
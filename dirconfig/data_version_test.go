@@ -0,0 +1,108 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package dirconfig
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testDataMigrations(ran *[]string, failing string) []DataMigration {
+	migration := func(name string, files int) func(*DirectoryConfig) (*Migration, error) {
+		return func(*DirectoryConfig) (*Migration, error) {
+			*ran = append(*ran, name)
+			if name == failing {
+				return &Migration{}, errors.New("failed")
+			}
+			return &Migration{Layouts: []string{name}, Files: files}, nil
+		}
+	}
+	return []DataMigration{
+		{Version: 1, Name: "one", Migrate: migration("one", 1)},
+		{Version: 2, Name: "two", Migrate: migration("two", 0)},
+		{Version: 3, Name: "three", Migrate: migration("three", 2)},
+	}
+}
+
+func TestMigrateData(t *testing.T) {
+	migrateTests := []struct {
+		name        string
+		version     string
+		failing     string
+		wantRan     []string
+		wantVersion int
+		wantFiles   int
+		wantErr     bool
+	}{
+		{name: "unversioned data runs every migration", wantRan: []string{"one", "two", "three"}, wantVersion: 3, wantFiles: 3},
+		{name: "only newer migrations run", version: `{"version":2}`, wantRan: []string{"three"}, wantVersion: 3, wantFiles: 2},
+		{name: "current data is not migrated", version: `{"version":3}`, wantVersion: 3},
+		{name: "newer data is not migrated", version: `{"version":4}`, wantVersion: 4, wantErr: true},
+		{name: "unreadable data version is not migrated", version: `{`, wantErr: true},
+		{name: "failed migration stops the migrations", failing: "two", wantRan: []string{"one", "two"}, wantVersion: 1, wantFiles: 1, wantErr: true},
+	}
+	for _, tt := range migrateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{}
+			if tt.version != "" {
+				files[DataVersionFile] = tt.version
+			}
+			dirCfg, parent := setupMigrationDirs(t, files)
+			defer os.RemoveAll(parent)
+
+			var ran []string
+			got, err := dirCfg.MigrateData(testDataMigrations(&ran, tt.failing))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ran, tt.wantRan) {
+				t.Errorf("%s ran %v want %v", tt.name, ran, tt.wantRan)
+			}
+			if got.Version != tt.wantVersion || got.Files != tt.wantFiles {
+				t.Errorf("%s got version %d and %d files want version %d and %d files", tt.name, got.Version, got.Files, tt.wantVersion, tt.wantFiles)
+			}
+			if version, _ := dirCfg.ReadDataVersion(); version != tt.wantVersion {
+				t.Errorf("%s got recorded version %d want %d", tt.name, version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestMigrateDataOrder(t *testing.T) {
+	dirCfg, parent := setupMigrationDirs(t, nil)
+	defer os.RemoveAll(parent)
+
+	var ran []string
+	migrations := testDataMigrations(&ran, "")
+	migrations[1], migrations[2] = migrations[2], migrations[1]
+	if _, err := dirCfg.MigrateData(migrations); err == nil {
+		t.Errorf("expected an error for migrations out of order")
+	}
+	if len(ran) != 0 {
+		t.Errorf("ran %v want no migrations", ran)
+	}
+	if _, err := ioutil.ReadFile(filepath.Join(parent, DataVersionFile)); !os.IsNotExist(err) {
+		t.Errorf("expected no data version file, got %v", err)
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/mitchellh/mapstructure"
@@ -36,6 +38,37 @@ var (
 	uploadDir    = "upload"
 )
 
+// TenantDir is the directory, within the reports and staging directories, containing a directory for each tenant
+// when reports are partitioned by tenant
+const TenantDir = "tenants"
+
+// RecollectDir is the directory, within the reports and staging directories, containing a directory for each billing
+// period of re-collected reports
+const RecollectDir = "recollect"
+
+// DailyDir is the directory, within the reports and staging directories, containing a directory for each day with
+// copies of the reports of the day that are rolled up to one row for each day when the payload cadence is both
+const DailyDir = "daily"
+
+// RetryDir is the directory, within the parent directory, containing payloads to upload again when a replay is requested
+const RetryDir = "retry"
+
+// ReplayStateDir is the directory, within the parent directory, containing the upload queue state of replays, so that
+// replays do not reset the state of the upload directory
+const ReplayStateDir = "replay-state"
+
+// QuarantineDir is the directory, within the parent directory, containing payloads that were quarantined after
+// ingress rejected them repeatedly
+const QuarantineDir = "quarantine"
+
+// PartialSuffix is the suffix of files that are still being written. Files are written with the suffix and renamed
+// when they are complete, so that only complete files are listed, packaged, or uploaded.
+const PartialSuffix = ".part"
+
+// DeliverySuffix is the suffix of the file, next to a payload in the upload directory, that records the destinations
+// the payload was delivered to
+const DeliverySuffix = ".delivery.json"
+
 type DirListFunc = func(path string) ([]os.FileInfo, error)
 type RemoveAllFunc = func(path string) error
 type StatFunc = func(path string) (os.FileInfo, error)
@@ -93,11 +126,32 @@ func (dir *Directory) GetFiles() ([]string, error) {
 	}
 	fileList := []string{}
 	for _, file := range outFiles {
+		if strings.HasSuffix(file.Name(), PartialSuffix) || strings.HasSuffix(file.Name(), DeliverySuffix) {
+			continue
+		}
 		fileList = append(fileList, file.Name())
 	}
 	return fileList, nil
 }
 
+// RemovePartialFiles removes the files that were left partially written, for example by a restart, and have not been
+// modified for longer than age. Newer partial files may still be being written and are kept.
+func (dir *Directory) RemovePartialFiles(age time.Duration) error {
+	files, err := ioutil.ReadDir(dir.Path)
+	if err != nil {
+		return fmt.Errorf("RemovePartialFiles: could not read directory: %v", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), PartialSuffix) || time.Since(file.ModTime()) < age {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir.Path, file.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("RemovePartialFiles: could not remove file: %v", err)
+		}
+	}
+	return nil
+}
+
 func (dir *Directory) GetFilesFullPath() ([]string, error) {
 	files, err := dir.GetFiles()
 	if err != nil {
@@ -163,9 +217,14 @@ func getOrCreatePath(directory string, dirFs *DirectoryFileSystem) (*Directory,
 }
 
 func (dirCfg *DirectoryConfig) GetDirectoryConfig() error {
+	return dirCfg.GetDirectoryConfigAt(parentDir)
+}
+
+// GetDirectoryConfigAt gets or creates the parent directory, and the reports, staging, and upload directories in it
+func (dirCfg *DirectoryConfig) GetDirectoryConfigAt(parent string) error {
 	var err error
 	dirMap := map[string]*Directory{}
-	dirMap["parent"], err = getOrCreatePath(parentDir, dirCfg.DirectoryFileSystem)
+	dirMap["parent"], err = getOrCreatePath(parent, dirCfg.DirectoryFileSystem)
 	if err != nil {
 		return fmt.Errorf("getDirectoryConfig: %v", err)
 	}
@@ -176,7 +235,7 @@ func (dirCfg *DirectoryConfig) GetDirectoryConfig() error {
 		"upload":  uploadDir,
 	}
 	for name, folder := range folders {
-		d := filepath.Join(parentDir, folder)
+		d := filepath.Join(parent, folder)
 		dirMap[name], err = getOrCreatePath(d, dirCfg.DirectoryFileSystem)
 		if err != nil {
 			return fmt.Errorf("getDirectoryConfig: %v", err)
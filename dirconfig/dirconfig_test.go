@@ -3,6 +3,7 @@ package dirconfig
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -98,6 +99,49 @@ func TestGetFiles(t *testing.T) {
 	}
 }
 
+func TestRemovePartialFiles(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "partial")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	old := time.Now().Add(-2 * time.Hour)
+	files := map[string]bool{"complete.tar.gz": false, "stale.tar.gz.part": true, "writing.tar.gz.part": false}
+	for name, stale := range files {
+		path := filepath.Join(tmp, name)
+		if err := ioutil.WriteFile(path, []byte("payload"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if stale {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatalf("failed to age %s: %v", name, err)
+			}
+		}
+	}
+
+	dir := &Directory{Path: tmp}
+	got, err := dir.GetFiles()
+	if err != nil {
+		t.Fatalf("GetFiles unexpected error: %v", err)
+	}
+	if want := []string{"complete.tar.gz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetFiles got %v want %v", got, want)
+	}
+	if err := dir.RemovePartialFiles(time.Hour); err != nil {
+		t.Fatalf("RemovePartialFiles unexpected error: %v", err)
+	}
+	for name, stale := range files {
+		_, err := os.Stat(filepath.Join(tmp, name))
+		if stale != os.IsNotExist(err) {
+			t.Errorf("%s got removed %t want %t", name, os.IsNotExist(err), stale)
+		}
+	}
+	missing := &Directory{Path: filepath.Join(tmp, "not_real")}
+	if err := missing.RemovePartialFiles(time.Hour); err == nil {
+		t.Errorf("RemovePartialFiles expected error for missing directory")
+	}
+}
+
 func TestGetFilesFullPath(t *testing.T) {
 	if err := os.Mkdir("empty-dir", 0644); err != nil {
 		t.Fatalf("failed to create empty-dir: %v", err)
@@ -0,0 +1,116 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package dirconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// InventoryFile is a file of the report volume
+type InventoryFile struct {
+	// Directory is the directory of the volume the file is in: reports, staging, upload, retry, or quarantine
+	Directory string `json:"directory"`
+	// Path is the path of the file relative to the directory
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// Partial is true when the file is still being written
+	Partial bool `json:"partial,omitempty"`
+}
+
+// inventoryDirs are the directories of the report volume that are listed in the inventory, by name
+var inventoryDirs = []struct{ name, path string }{
+	{"reports", queryDataDir},
+	{"staging", stagingDir},
+	{"upload", uploadDir},
+	{"retry", RetryDir},
+	{"quarantine", QuarantineDir},
+}
+
+// InventoryAt lists the files of the reports, staging, upload, retry, and quarantine directories of the report
+// volume at parent. Directories that do not exist are skipped.
+func InventoryAt(parent string) ([]InventoryFile, error) {
+	files := []InventoryFile{}
+	for _, dir := range inventoryDirs {
+		root := filepath.Join(parent, dir.path)
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, InventoryFile{
+				Directory: dir.name,
+				Path:      rel,
+				Size:      info.Size(),
+				ModTime:   info.ModTime().UTC(),
+				Partial:   strings.HasSuffix(rel, PartialSuffix),
+			})
+			return nil
+		})
+		if err != nil {
+			return files, fmt.Errorf("InventoryAt: could not list %s: %v", root, err)
+		}
+	}
+	return files, nil
+}
+
+// Inventory lists the files of the default report volume
+func Inventory() ([]InventoryFile, error) {
+	return InventoryAt(parentDir)
+}
+
+// InventoryPathAt returns the path of a file of the inventory of the report volume at parent, given as its
+// directory and its path relative to the directory, such as upload/20210102T100000-cost-mgmt.tar.gz
+func InventoryPathAt(parent, name string) (string, error) {
+	parts := strings.SplitN(filepath.ToSlash(name), "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", fmt.Errorf("InventoryPathAt: %q is not a <directory>/<file> path", name)
+	}
+	for _, dir := range inventoryDirs {
+		if dir.name != parts[0] {
+			continue
+		}
+		root := filepath.Join(parent, dir.path)
+		path := filepath.Join(root, filepath.FromSlash(parts[1]))
+		if rel, err := filepath.Rel(root, path); err != nil || strings.HasPrefix(rel, "..") {
+			return "", fmt.Errorf("InventoryPathAt: %q is outside of the %s directory", name, dir.name)
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("InventoryPathAt: unknown directory %q", parts[0])
+}
+
+// InventoryPath returns the path of a file of the inventory of the default report volume
+func InventoryPath(name string) (string, error) {
+	return InventoryPathAt(parentDir, name)
+}
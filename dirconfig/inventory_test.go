@@ -0,0 +1,98 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package dirconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInventoryAt(t *testing.T) {
+	parent, err := ioutil.TempDir("", "inventory")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(parent)
+	files := map[string]string{
+		"data/a.csv":                    "a,b",
+		"data/tenants/t1/b.csv":         "b",
+		"staging/c.csv" + PartialSuffix: "",
+		"upload/payload.tar.gz":         "payload",
+		"quarantine/bad.tar.gz":         "",
+		"history.jsonl":                 "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(parent, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	got, err := InventoryAt(parent)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	type file struct {
+		dir, path string
+		size      int64
+		partial   bool
+	}
+	var gotFiles []file
+	for _, f := range got {
+		gotFiles = append(gotFiles, file{f.Directory, f.Path, f.Size, f.Partial})
+	}
+	want := []file{
+		{"reports", "a.csv", 3, false},
+		{"reports", filepath.Join("tenants", "t1", "b.csv"), 1, false},
+		{"staging", "c.csv" + PartialSuffix, 0, true},
+		{"upload", "payload.tar.gz", 7, false},
+		{"quarantine", "bad.tar.gz", 0, false},
+	}
+	if !reflect.DeepEqual(gotFiles, want) {
+		t.Errorf("got files %v want %v", gotFiles, want)
+	}
+}
+
+func TestInventoryPathAt(t *testing.T) {
+	parent := "/volume"
+	inventoryPathTests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "upload/payload.tar.gz", want: filepath.Join(parent, uploadDir, "payload.tar.gz")},
+		{name: "reports/tenants/t1/b.csv", want: filepath.Join(parent, queryDataDir, "tenants", "t1", "b.csv")},
+		{name: "payload.tar.gz", wantErr: true},
+		{name: "archive/payload.tar.gz", wantErr: true},
+		{name: "upload/../../etc/passwd", wantErr: true},
+	}
+	for _, tt := range inventoryPathTests {
+		got, err := InventoryPathAt(parent, tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s got (%q, %v) want %q, error %t", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package dirconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FlatLayout is the legacy layout with the reports and payloads written directly in the parent directory
+const FlatLayout = "flat"
+
+// legacyPrefix is prepended to the name of a migrated file that would replace an existing file
+const legacyPrefix = "legacy-"
+
+// Migration is the result of moving the files of legacy directory layouts into the current layout
+type Migration struct {
+	// Layouts are the legacy layouts that files were found in
+	Layouts []string
+	// Files is the number of files that were moved
+	Files int
+}
+
+// MigrateLegacyLayout moves the files of legacy directory layouts into the current layout: reports are moved to the
+// reports directory so that they are packaged again, and payloads are moved to the upload directory. The reports in
+// the staging directory are left in place, since the reports of the last packaging run stay there until the next run,
+// and moving them would package and upload them again.
+func (dirCfg *DirectoryConfig) MigrateLegacyLayout() (*Migration, error) {
+	migration := &Migration{}
+
+	moved, err := migrateFiles(dirCfg.Parent.Path, map[string]string{".csv": dirCfg.Reports.Path, ".tar.gz": dirCfg.Upload.Path})
+	if err != nil {
+		return migration, fmt.Errorf("MigrateLegacyLayout: %v", err)
+	}
+	migration.add(FlatLayout, moved)
+	return migration, nil
+}
+
+func (m *Migration) add(layout string, moved int) {
+	if moved > 0 {
+		m.Layouts = append(m.Layouts, layout)
+		m.Files += moved
+	}
+}
+
+// migrateFiles moves the files of the directory that end with one of the suffixes to the directory of the suffix,
+// and returns the number of files moved. Directories and other files are left in place.
+func migrateFiles(dir string, targets map[string]string) (int, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("could not read %s: %v", dir, err)
+	}
+	moved := 0
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		for suffix, target := range targets {
+			if !strings.HasSuffix(file.Name(), suffix) {
+				continue
+			}
+			to := filepath.Join(target, file.Name())
+			if _, err := os.Stat(to); err == nil {
+				to = filepath.Join(target, legacyPrefix+file.Name())
+			}
+			if err := os.Rename(filepath.Join(dir, file.Name()), to); err != nil {
+				return moved, fmt.Errorf("could not move %s: %v", file.Name(), err)
+			}
+			moved++
+			break
+		}
+	}
+	return moved, nil
+}
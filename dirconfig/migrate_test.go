@@ -0,0 +1,157 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package dirconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func setupMigrationDirs(t *testing.T, files map[string]string) (*DirectoryConfig, string) {
+	parent, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	dirCfg := &DirectoryConfig{
+		Parent:  Directory{Path: parent},
+		Reports: Directory{Path: filepath.Join(parent, "data")},
+		Staging: Directory{Path: filepath.Join(parent, "staging")},
+		Upload:  Directory{Path: filepath.Join(parent, "upload")},
+	}
+	for _, dir := range []Directory{dirCfg.Reports, dirCfg.Staging, dirCfg.Upload, {Path: filepath.Join(parent, "bundles")}} {
+		if err := os.Mkdir(dir.Path, 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(parent, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return dirCfg, parent
+}
+
+func listTree(t *testing.T, root string) []string {
+	var got []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			got = append(got, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk dir: %v", err)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestMigrateLegacyLayout(t *testing.T) {
+	migrateTests := []struct {
+		name        string
+		files       map[string]string
+		wantLayouts []string
+		wantFiles   int
+		wantTree    []string
+	}{
+		{
+			name:     "current layout is left untouched",
+			files:    map[string]string{"data/a.csv": "", "upload/a.tar.gz": "", "bundles/b.tar.gz": "", "index.json": ""},
+			wantTree: []string{"bundles/b.tar.gz", "data/a.csv", "index.json", "upload/a.tar.gz"},
+		},
+		{
+			name:        "flat layout",
+			files:       map[string]string{"a.csv": "", "b.tar.gz": "", "index.json": ""},
+			wantLayouts: []string{FlatLayout},
+			wantFiles:   2,
+			wantTree:    []string{"data/a.csv", "index.json", "upload/b.tar.gz"},
+		},
+		{
+			name: "reports of a completed packaging run are left in staging",
+			files: map[string]string{
+				"staging/0d3cb6a5-cm-openshift-usage-lookback-0.csv":       "",
+				"staging/0d3cb6a5-cm-openshift-node-labels-lookback-0.csv": "",
+				"upload/20210301T000000-cost-mgmt.tar.gz":                  "",
+				"upload/20210301T000000-cost-mgmt.tar.gz.delivery.json":    "",
+			},
+			wantTree: []string{
+				"staging/0d3cb6a5-cm-openshift-node-labels-lookback-0.csv",
+				"staging/0d3cb6a5-cm-openshift-usage-lookback-0.csv",
+				"upload/20210301T000000-cost-mgmt.tar.gz",
+				"upload/20210301T000000-cost-mgmt.tar.gz.delivery.json",
+			},
+		},
+		{
+			name:        "colliding names are kept",
+			files:       map[string]string{"a.csv": "legacy", "data/a.csv": "current"},
+			wantLayouts: []string{FlatLayout},
+			wantFiles:   1,
+			wantTree:    []string{"data/a.csv", "data/legacy-a.csv"},
+		},
+	}
+	for _, tt := range migrateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirCfg, parent := setupMigrationDirs(t, tt.files)
+			defer os.RemoveAll(parent)
+
+			got, err := dirCfg.MigrateLegacyLayout()
+			if err != nil {
+				t.Fatalf("%s got unexpected error: %v", tt.name, err)
+			}
+			if !reflect.DeepEqual(got.Layouts, tt.wantLayouts) {
+				t.Errorf("%s got layouts %v want %v", tt.name, got.Layouts, tt.wantLayouts)
+			}
+			if got.Files != tt.wantFiles {
+				t.Errorf("%s got %d files want %d", tt.name, got.Files, tt.wantFiles)
+			}
+			if tree := listTree(t, parent); !reflect.DeepEqual(tree, tt.wantTree) {
+				t.Errorf("%s got tree %v want %v", tt.name, tree, tt.wantTree)
+			}
+			if legacy := tt.files["a.csv"]; legacy != "" {
+				content, err := ioutil.ReadFile(filepath.Join(dirCfg.Reports.Path, legacyPrefix+"a.csv"))
+				if err != nil || string(content) != legacy {
+					t.Errorf("%s got migrated content %q want %q", tt.name, content, legacy)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateLegacyLayoutError(t *testing.T) {
+	dirCfg, parent := setupMigrationDirs(t, map[string]string{"a.csv": ""})
+	defer os.RemoveAll(parent)
+	dirCfg.Reports.Path = filepath.Join(parent, "missing")
+
+	got, err := dirCfg.MigrateLegacyLayout()
+	if err == nil {
+		t.Errorf("expected an error when the reports directory is missing")
+	}
+	if got.Files != 0 {
+		t.Errorf("got %d files want 0", got.Files)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package exporter
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// formatted exports copies of the payloads whose reports are rewritten with the delimiter and quoting of the
+// destination. The payloads on the volume, which are uploaded to ingress, are not changed.
+type formatted struct {
+	Exporter
+	delimiter kokumetricscfgv1beta1.CSVDelimiter
+	quoteAll  bool
+}
+
+// withCSVFormat wraps the exporter of the destination so that it exports formatted copies of the payloads. The
+// exporter is returned unchanged when the destination uses the default format.
+func withCSVFormat(exp Exporter, dest kokumetricscfgv1beta1.DestinationSpec) Exporter {
+	delimiter := dest.CSVDelimiter
+	if delimiter == "" {
+		delimiter = kokumetricscfgv1beta1.DefaultCSVDelimiter
+	}
+	quoteAll := dest.CSVQuoting == kokumetricscfgv1beta1.AllQuoting
+	if delimiter == kokumetricscfgv1beta1.CommaDelimiter && !quoteAll {
+		return exp
+	}
+	return &formatted{Exporter: exp, delimiter: delimiter, quoteAll: quoteAll}
+}
+
+// Export exports a formatted copy of the payload. The copy is written next to the payload and removed once it is exported.
+func (f *formatted) Export(payload Payload) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(payload.Path), "."+payload.Name+"-*"+dirconfig.PartialSuffix)
+	if err != nil {
+		return fmt.Errorf("Export: failed to create formatted payload: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := formatPayload(payload.Path, tmp, f.delimiter, f.quoteAll); err != nil {
+		return fmt.Errorf("Export: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Export: failed to write formatted payload: %v", err)
+	}
+	formattedPayload := payload
+	formattedPayload.Path = tmp.Name()
+	return f.Exporter.Export(formattedPayload)
+}
+
+// formatPayload writes a copy of the payload at path to out, with the reports rewritten with the delimiter and
+// quoting. The delimiter is recorded in the manifest when it is not a comma, and the signature is removed from the
+// manifest because it does not match the rewritten reports.
+func formatPayload(path string, out io.Writer, delimiter kokumetricscfgv1beta1.CSVDelimiter, quoteAll bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("formatPayload: failed to open payload: %v", err)
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("formatPayload: failed to read payload: %v", err)
+	}
+	defer gzr.Close()
+
+	gzw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gzw)
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("formatPayload: failed to read payload: %v", err)
+		}
+		var contents bytes.Buffer
+		switch {
+		case strings.HasSuffix(header.Name, ".csv"):
+			err = formatReport(tr, &contents, delimiter, quoteAll)
+		case filepath.Base(header.Name) == "manifest.json":
+			err = formatManifest(tr, &contents, delimiter)
+		default:
+			_, err = io.Copy(&contents, tr)
+		}
+		if err != nil {
+			return fmt.Errorf("formatPayload: %s: %v", header.Name, err)
+		}
+		header.Size = int64(contents.Len())
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("formatPayload: failed to write %s: %v", header.Name, err)
+		}
+		if _, err := io.Copy(tw, &contents); err != nil {
+			return fmt.Errorf("formatPayload: failed to write %s: %v", header.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("formatPayload: failed to write payload: %v", err)
+	}
+	if err := gzw.Close(); err != nil {
+		return fmt.Errorf("formatPayload: failed to write payload: %v", err)
+	}
+	return nil
+}
+
+// formatManifest records the delimiter in the manifest, and removes the signature of the reports
+func formatManifest(r io.Reader, w io.Writer, delimiter kokumetricscfgv1beta1.CSVDelimiter) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.NewDecoder(r).Decode(&fields); err != nil {
+		return fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	delete(fields, "signature")
+	delete(fields, "csv_delimiter")
+	if delimiter != kokumetricscfgv1beta1.CommaDelimiter {
+		value, err := json.Marshal(delimiter)
+		if err != nil {
+			return fmt.Errorf("failed to marshal delimiter: %v", err)
+		}
+		fields["csv_delimiter"] = value
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// formatReport rewrites a comma separated report with the delimiter, quoting every field if quoteAll is true
+func formatReport(r io.Reader, out io.Writer, delimiter kokumetricscfgv1beta1.CSVDelimiter, quoteAll bool) error {
+	comma := ','
+	if delimiter == kokumetricscfgv1beta1.TabDelimiter {
+		comma = '\t'
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	w := bufio.NewWriter(out)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read report: %v", err)
+		}
+		if err := writeRow(w, row, comma, quoteAll); err != nil {
+			return fmt.Errorf("failed to write report: %v", err)
+		}
+	}
+	return w.Flush()
+}
+
+// writeRow writes the fields of a row separated by the delimiter. Fields are quoted as the csv package does, or
+// always if quoteAll is true.
+func writeRow(w *bufio.Writer, row []string, delimiter rune, quoteAll bool) error {
+	for i, field := range row {
+		if i > 0 {
+			if _, err := w.WriteRune(delimiter); err != nil {
+				return err
+			}
+		}
+		if quoteAll || fieldNeedsQuotes(field, delimiter) {
+			field = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+		if _, err := w.WriteString(field); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("\n")
+	return err
+}
+
+// fieldNeedsQuotes reports whether the field must be quoted to be read back
+func fieldNeedsQuotes(field string, delimiter rune) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.ContainsRune(field, delimiter) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+	return field[0] == ' ' || field[0] == '\t'
+}
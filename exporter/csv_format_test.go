@@ -0,0 +1,142 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package exporter
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func readPayloadFiles(t *testing.T, path string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open payload: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read payload: %v", err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to read payload: %v", err)
+		}
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", header.Name, err)
+		}
+		files[header.Name] = string(contents)
+	}
+	return files
+}
+
+func TestFormatReport(t *testing.T) {
+	report := "interval_start,namespace,pod_labels\n2021-01-05 18:00:00,ns-a,\"label_app:a,label_tier:web\"\n2021-01-05 18:00:00,ns-b,\n"
+	formatTests := []struct {
+		name      string
+		delimiter kokumetricscfgv1beta1.CSVDelimiter
+		quoteAll  bool
+		want      string
+	}{
+		{
+			name:      "tab delimiter",
+			delimiter: kokumetricscfgv1beta1.TabDelimiter,
+			want:      "interval_start\tnamespace\tpod_labels\n2021-01-05 18:00:00\tns-a\tlabel_app:a,label_tier:web\n2021-01-05 18:00:00\tns-b\t\n",
+		},
+		{
+			name:      "always quote",
+			delimiter: kokumetricscfgv1beta1.CommaDelimiter,
+			quoteAll:  true,
+			want:      "\"interval_start\",\"namespace\",\"pod_labels\"\n\"2021-01-05 18:00:00\",\"ns-a\",\"label_app:a,label_tier:web\"\n\"2021-01-05 18:00:00\",\"ns-b\",\"\"\n",
+		},
+	}
+	for _, tt := range formatTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := formatReport(strings.NewReader(report), &got, tt.delimiter, tt.quoteAll); err != nil {
+				t.Fatalf("%s unexpected error: %v", tt.name, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("%s got\n%q\nwant\n%q", tt.name, got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFormattedExport(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	payload := writeUsagePayload(t, dir)
+	original, err := ioutil.ReadFile(payload.Path)
+	if err != nil {
+		t.Fatalf("failed to read payload: %v", err)
+	}
+
+	// the default format exports the payload unchanged
+	exp, err := New(kokumetricscfgv1beta1.DestinationSpec{Name: "archive", Type: "filesystem", Path: filepath.Join(dir, "archive")}, nil)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	if _, ok := exp.(*formatted); ok {
+		t.Error("got a formatted exporter for the default format")
+	}
+
+	archive := filepath.Join(dir, "tab")
+	exp, err = New(kokumetricscfgv1beta1.DestinationSpec{Name: "tab", Type: "filesystem", Path: archive, CSVDelimiter: kokumetricscfgv1beta1.TabDelimiter}, nil)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	if exp.Name() != "tab" {
+		t.Errorf("got name %s want tab", exp.Name())
+	}
+	if err := exp.Export(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := readPayloadFiles(t, filepath.Join(archive, payload.Name))
+	if got := files["abc_openshift_usage_report.0.csv"]; !strings.HasPrefix(got, "interval_start\tnamespace\tpod\t") {
+		t.Errorf("got report %q want tab separated fields", got)
+	}
+	if got := files["manifest.json"]; !strings.Contains(got, `"csv_delimiter":"tab"`) || !strings.Contains(got, `"uuid":"abc"`) {
+		t.Errorf("got manifest %s want the tab delimiter recorded", got)
+	}
+
+	// the payload on the volume is not changed, and the formatted copy is removed
+	if got, err := ioutil.ReadFile(payload.Path); err != nil || !bytes.Equal(got, original) {
+		t.Errorf("got the payload changed by the export: %v", err)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "*.part")); len(left) != 0 {
+		t.Errorf("got formatted copies %v left next to the payload", left)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const payloadContentType = "application/vnd.redhat.hccm.tar+tgz"
+
+// ErrNotAccepted is returned when a destination responded without accepting the payload. The payload
+// is kept and exported again on the next cycle.
+var ErrNotAccepted = errors.New("payload was not accepted")
+
+// Payload is a packaged report file that is ready to be exported
+type Payload struct {
+	// Path is the full path to the tar.gz file
+	Path string
+	// Name is the file name of the payload
+	Name string
+	// PayloadID is the uuid from the payload manifest
+	PayloadID string
+	// IdempotencyKey identifies the uploads of the payload, and of payloads packaged again from the same reports
+	IdempotencyKey string
+}
+
+// Exporter sends payloads to a destination
+type Exporter interface {
+	// Name identifies the destination in logs and status
+	Name() string
+	// Export sends the payload to the destination
+	Export(payload Payload) error
+}
+
+// Factory creates an Exporter from a destination in the KokuMetricsConfig. The credentials
+// are the data of the destination's secret, with lowercase keys.
+type Factory func(dest kokumetricscfgv1beta1.DestinationSpec, credentials map[string]string) (Exporter, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a destination type available to the KokuMetricsConfig. Destinations that are not built in
+// are added by registering a Factory from an init function.
+func Register(destType string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("exporter: Register factory is nil")
+	}
+	if _, dup := factories[destType]; dup {
+		panic("exporter: Register called twice for type " + destType)
+	}
+	factories[destType] = factory
+}
+
+// Types returns the registered destination types
+func Types() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	var types []string
+	for t := range factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New creates the Exporter for a destination. Destinations with a CSV format other than the default are exported
+// copies of the payloads in their format.
+func New(dest kokumetricscfgv1beta1.DestinationSpec, credentials map[string]string) (Exporter, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[dest.Type]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("New: unknown destination type %q, expected one of %v", dest.Type, Types())
+	}
+	exp, err := factory(dest, credentials)
+	if err != nil {
+		return nil, err
+	}
+	return withCSVFormat(exp, dest), nil
+}
+
+// IsNotAccepted returns true if the error indicates the destination did not accept the payload
+func IsNotAccepted(err error) bool {
+	return errors.Is(err, ErrNotAccepted)
+}
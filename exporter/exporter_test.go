@@ -0,0 +1,316 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/testutils/fakes"
+)
+
+func writePayload(t *testing.T, dir string) Payload {
+	path := filepath.Join(dir, "20210101T000000-cost-mgmt.tar.gz")
+	if err := ioutil.WriteFile(path, []byte("payload-contents"), 0644); err != nil {
+		t.Fatalf("failed to write payload: %v", err)
+	}
+	return Payload{Path: path, Name: filepath.Base(path), PayloadID: "abc-123"}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "exporter")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	return dir
+}
+
+func TestNew(t *testing.T) {
+	newTests := []struct {
+		name        string
+		dest        kokumetricscfgv1beta1.DestinationSpec
+		credentials map[string]string
+		wantError   bool
+	}{
+		{name: "unknown type", dest: kokumetricscfgv1beta1.DestinationSpec{Type: "carrier-pigeon"}, wantError: true},
+		{name: "filesystem relative path", dest: kokumetricscfgv1beta1.DestinationSpec{Type: "filesystem", Path: "archive"}, wantError: true},
+		{name: "filesystem", dest: kokumetricscfgv1beta1.DestinationSpec{Type: "filesystem", Path: "/archive"}},
+		{name: "webhook invalid url", dest: kokumetricscfgv1beta1.DestinationSpec{Type: "webhook", URL: "not a url"}, wantError: true},
+		{name: "webhook", dest: kokumetricscfgv1beta1.DestinationSpec{Type: "webhook", URL: "https://example.com/hook"}},
+		{name: "s3 missing bucket", dest: kokumetricscfgv1beta1.DestinationSpec{Type: "s3"}, wantError: true},
+		{
+			name:        "s3 missing credentials",
+			dest:        kokumetricscfgv1beta1.DestinationSpec{Type: "s3", Bucket: "reports"},
+			credentials: map[string]string{accessKeyIDKey: "key"},
+			wantError:   true,
+		},
+		{
+			name:        "s3",
+			dest:        kokumetricscfgv1beta1.DestinationSpec{Type: "s3", Bucket: "reports"},
+			credentials: map[string]string{accessKeyIDKey: "key", secretAccessKeyKey: "secret"},
+		},
+	}
+	for _, tt := range newTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.dest, tt.credentials)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+		})
+	}
+}
+
+func TestFilesystemExport(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	payload := writePayload(t, dir)
+
+	archive := filepath.Join(dir, "archive", "nested")
+	exp, err := New(kokumetricscfgv1beta1.DestinationSpec{Name: "archive", Type: "filesystem", Path: archive}, nil)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	if err := exp.Export(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(archive, payload.Name))
+	if err != nil || string(got) != "payload-contents" {
+		t.Errorf("payload was not copied: %q, %v", got, err)
+	}
+	files, _ := ioutil.ReadDir(archive)
+	if len(files) != 1 {
+		t.Errorf("expected only the payload in the archive, got %d files", len(files))
+	}
+}
+
+func TestWebhookExport(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	payload := writePayload(t, dir)
+
+	webhookTests := []struct {
+		name      string
+		status    int
+		wantError bool
+	}{
+		{name: "accepted", status: http.StatusOK},
+		{name: "no content", status: http.StatusNoContent},
+		{name: "rejected", status: http.StatusForbidden, wantError: true},
+	}
+	for _, tt := range webhookTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody, gotAuth, gotName string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				gotBody, gotAuth, gotName = string(body), r.Header.Get("Authorization"), r.Header.Get("X-Payload-Name")
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			exp, err := New(kokumetricscfgv1beta1.DestinationSpec{Name: "hook", Type: "webhook", URL: ts.URL}, map[string]string{tokenKey: "secret-token"})
+			if err != nil {
+				t.Fatalf("failed to create exporter: %v", err)
+			}
+			err = exp.Export(payload)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+			if gotBody != "payload-contents" || gotAuth != "Bearer secret-token" || gotName != payload.Name {
+				t.Errorf("%s got body %q auth %q name %q", tt.name, gotBody, gotAuth, gotName)
+			}
+		})
+	}
+}
+
+func TestS3Export(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	payload := writePayload(t, dir)
+
+	var gotPath, gotAuth, gotDate, gotHash, gotHost, gotContentType string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		gotDate, gotHash = r.Header.Get("X-Amz-Date"), r.Header.Get("X-Amz-Content-Sha256")
+		gotHost, gotContentType = r.Host, r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	dest := kokumetricscfgv1beta1.DestinationSpec{Name: "bucket", Type: "s3", URL: ts.URL, Bucket: "reports", Path: "/cluster-a/", Region: "eu-west-1"}
+	exp, err := New(dest, map[string]string{accessKeyIDKey: "AKID", secretAccessKeyKey: "secret"})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	exp.(*S3).now = func() time.Time { return time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC) }
+	if err := exp.Export(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "/reports/cluster-a/" + payload.Name; gotPath != want {
+		t.Errorf("got path %s want %s", gotPath, want)
+	}
+	if gotDate != "20210102T030405Z" {
+		t.Errorf("got date %s", gotDate)
+	}
+	sum := sha256.Sum256([]byte("payload-contents"))
+	if gotHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("got content hash %s", gotHash)
+	}
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKID/20210102/eu-west-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(gotAuth, wantPrefix) || len(gotAuth) != len(wantPrefix)+64 {
+		t.Errorf("got authorization %s", gotAuth)
+	}
+
+	// the request that was received is signed the same way with the secret key
+	req, _ := http.NewRequest(http.MethodPut, "http://"+gotHost+gotPath, strings.NewReader("payload-contents"))
+	req.Header.Set("Content-Type", gotContentType)
+	signer := v4.NewSigner(credentials.NewStaticCredentials("AKID", "secret", ""))
+	signer.DisableURIPathEscaping = true
+	if _, err := signer.Sign(req, strings.NewReader("payload-contents"), "s3", "eu-west-1", time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)); err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	if want := req.Header.Get("Authorization"); gotAuth != want {
+		t.Errorf("got authorization %s want %s", gotAuth, want)
+	}
+}
+
+func TestIngressExport(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	payload := writePayload(t, dir)
+
+	ingressTests := []struct {
+		name            string
+		status          int
+		format          kokumetricscfgv1beta1.PayloadFormat
+		wantError       bool
+		wantNotAccepted bool
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "accepted passthrough", status: http.StatusAccepted, format: kokumetricscfgv1beta1.PassthroughPayload},
+		{name: "not accepted", status: http.StatusOK, wantError: true, wantNotAccepted: true},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantError: true},
+	}
+	for _, tt := range ingressTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotContentType string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotContentType = r.Header.Get("Content-Type")
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			exp := &Ingress{
+				AuthConfig: &crhchttp.AuthConfig{Log: testutils.TestLogger{}, Authentication: "basic"},
+				URL:        ts.URL,
+				Format:     tt.format,
+			}
+			err := exp.Export(payload)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+			if IsNotAccepted(err) != tt.wantNotAccepted {
+				t.Errorf("%s got not accepted %t want %t", tt.name, IsNotAccepted(err), tt.wantNotAccepted)
+			}
+			if !strings.HasPrefix(exp.LastStatus, http.StatusText(tt.status)) && !strings.Contains(exp.LastStatus, http.StatusText(tt.status)) {
+				t.Errorf("%s got status %q", tt.name, exp.LastStatus)
+			}
+			if tt.format == kokumetricscfgv1beta1.PassthroughPayload && gotContentType != payloadContentType {
+				t.Errorf("%s got content type %s", tt.name, gotContentType)
+			}
+			if tt.format == "" && !strings.HasPrefix(gotContentType, "multipart/form-data") {
+				t.Errorf("%s got content type %s", tt.name, gotContentType)
+			}
+		})
+	}
+}
+
+func TestIngressExportUploader(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	payload := writePayload(t, dir)
+
+	uploader := &fakes.Uploader{}
+	exp := &Ingress{
+		AuthConfig: &crhchttp.AuthConfig{Log: testutils.TestLogger{}, ClusterID: "cluster-id"},
+		URL:        "https://ingress.invalid/upload",
+		Format:     kokumetricscfgv1beta1.PassthroughPayload,
+		Uploader:   uploader,
+	}
+	if err := exp.Export(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uploads := uploader.Uploads()
+	if len(uploads) != 1 {
+		t.Fatalf("got %d uploads want 1", len(uploads))
+	}
+	got := uploads[0]
+	if got.ClusterID != "cluster-id" || got.Method != "POST" || got.URI != exp.URL || got.ContentType != payloadContentType || len(got.Body) == 0 {
+		t.Errorf("got upload %+v", got)
+	}
+}
+
+func TestIsRejected(t *testing.T) {
+	rejectedTests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "bad request", err: &crhchttp.ResponseError{StatusCode: http.StatusBadRequest}, want: true},
+		{name: "payload too large", err: fmt.Errorf("upload: %w", &crhchttp.ResponseError{StatusCode: http.StatusRequestEntityTooLarge}), want: true},
+		{name: "unsupported media type", err: &crhchttp.ResponseError{StatusCode: http.StatusUnsupportedMediaType}, want: true},
+		{name: "unauthorized", err: &crhchttp.ResponseError{StatusCode: http.StatusUnauthorized}},
+		{name: "rate limited", err: &crhchttp.ResponseError{StatusCode: http.StatusTooManyRequests}},
+		{name: "server error", err: &crhchttp.ResponseError{StatusCode: http.StatusInternalServerError}},
+		{name: "not accepted", err: ErrNotAccepted},
+		{name: "no error"},
+	}
+	for _, tt := range rejectedTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRejected(tt.err); got != tt.want {
+				t.Errorf("%s got %t want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func init() {
+	Register("filesystem", newFilesystem)
+}
+
+// Filesystem copies payloads into a directory, such as a local archive volume
+type Filesystem struct {
+	name string
+	dir  string
+}
+
+func newFilesystem(dest kokumetricscfgv1beta1.DestinationSpec, _ map[string]string) (Exporter, error) {
+	if !filepath.IsAbs(dest.Path) {
+		return nil, fmt.Errorf("newFilesystem: path must be an absolute directory, got %q", dest.Path)
+	}
+	return &Filesystem{name: dest.Name, dir: dest.Path}, nil
+}
+
+// Name returns the name of the destination
+func (f *Filesystem) Name() string { return f.name }
+
+// Export copies the payload into the directory. The copy is renamed into place so a partial
+// payload is never visible in the directory.
+func (f *Filesystem) Export(payload Payload) error {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return fmt.Errorf("Export: failed to create directory: %v", err)
+	}
+	src, err := os.Open(payload.Path)
+	if err != nil {
+		return fmt.Errorf("Export: failed to open payload: %v", err)
+	}
+	defer src.Close()
+
+	tmp, err := ioutil.TempFile(f.dir, ".export-*.part")
+	if err != nil {
+		return fmt.Errorf("Export: failed to create file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Export: failed to copy payload: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Export: failed to write payload: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(f.dir, payload.Name)); err != nil {
+		return fmt.Errorf("Export: failed to store payload: %v", err)
+	}
+	return nil
+}
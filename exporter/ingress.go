@@ -0,0 +1,100 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/logging"
+)
+
+// IngressName is the name of the ingress destination in logs
+const IngressName = "ingress"
+
+// Ingress uploads payloads to the cloud.redhat.com Ingress API, or to a relay that accepts the same requests
+type Ingress struct {
+	AuthConfig *crhchttp.AuthConfig
+	URL        string
+	Format     kokumetricscfgv1beta1.PayloadFormat
+	// Uploader sends the payloads. When nil, crhchttp.APIUploader is used.
+	Uploader crhchttp.Uploader
+
+	// LastStatus and LastUploadTime are the http status and time of the last upload attempt
+	LastStatus     string
+	LastUploadTime metav1.Time
+	// LastRequestID is the ID that ingress assigned to the last upload attempt
+	LastRequestID string
+}
+
+// Name returns the name of the destination
+func (i *Ingress) Name() string { return IngressName }
+
+// Export uploads the payload. ErrNotAccepted is returned if ingress responds with a status other than 202.
+func (i *Ingress) Export(payload Payload) error {
+	// grab the body and the multipart file header, or the raw payload for relays
+	getBody := crhchttp.GetMultiPartBodyAndHeaders
+	if i.Format == kokumetricscfgv1beta1.PassthroughPayload {
+		getBody = crhchttp.GetPassthroughBodyAndHeaders
+	}
+	body, contentType, err := getBody(payload.Path)
+	if err != nil {
+		return fmt.Errorf("Export: failed to set request body and headers: %v", err)
+	}
+
+	uploadConfig := *i.AuthConfig
+	uploadConfig.Log = i.AuthConfig.Log.WithValues(logging.PayloadID, payload.PayloadID)
+	uploadConfig.IdempotencyKey = payload.IdempotencyKey
+	uploader := i.Uploader
+	if uploader == nil {
+		uploader = crhchttp.APIUploader{}
+	}
+	response, err := uploader.Upload(&uploadConfig, contentType, "POST", i.URL, body)
+	i.LastStatus = response.Status
+	i.LastRequestID = response.RequestID
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(response.Status, "202") {
+		return fmt.Errorf("Export: ingress responded with %s: %w", response.Status, ErrNotAccepted)
+	}
+	i.LastUploadTime = response.Time
+	return nil
+}
+
+// IsRejected returns true if the error is a 4xx response of ingress that rejects the payload itself, so that uploading
+// the payload again gets the same response. Authentication, timeout, and rate limit responses are not rejections.
+func IsRejected(err error) bool {
+	var respErr *crhchttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode < 400 || respErr.StatusCode >= 500 {
+		return false
+	}
+	switch respErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusProxyAuthRequired, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return true
+}
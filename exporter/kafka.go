@@ -0,0 +1,292 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package exporter
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const (
+	usernameKey = "username"
+	passwordKey = "password"
+	caCertKey   = "ca.crt"
+	tlsCertKey  = "tls.crt"
+	tlsKeyKey   = "tls.key"
+
+	kafkaClientID = "koku-metrics-operator"
+	kafkaTimeout  = 30 * time.Second
+)
+
+// usageReports maps a column that is unique to a usage report to the report type written in each record
+var usageReports = map[string]string{
+	"pod_usage_cpu_core_seconds":               "pod",
+	"persistentvolumeclaim_usage_byte_seconds": "storage",
+}
+
+func init() {
+	Register("kafka", newKafka)
+}
+
+// Kafka publishes the hourly usage records of payloads to a topic. Each pod and storage row of the
+// payload is written as a JSON message keyed by namespace, so that the records of a namespace are
+// written to the same partition.
+type Kafka struct {
+	name        string
+	brokers     []string
+	topic       string
+	config      *sarama.Config
+	newProducer func(brokers []string, config *sarama.Config) (sarama.SyncProducer, error)
+	now         func() time.Time
+}
+
+// kafkaRecord is a message of a usage record
+type kafkaRecord struct {
+	key   []byte
+	value []byte
+}
+
+func newKafka(dest kokumetricscfgv1beta1.DestinationSpec, credentials map[string]string) (Exporter, error) {
+	if len(dest.Brokers) == 0 {
+		return nil, fmt.Errorf("newKafka: brokers are required")
+	}
+	if dest.Topic == "" {
+		return nil, fmt.Errorf("newKafka: topic is required")
+	}
+	if (credentials[usernameKey] == "") != (credentials[passwordKey] == "") {
+		return nil, fmt.Errorf("newKafka: secret must contain both %s and %s data", usernameKey, passwordKey)
+	}
+	tlsConfig, err := kafkaTLSConfig(credentials)
+	if err != nil {
+		return nil, fmt.Errorf("newKafka: %v", err)
+	}
+
+	config := sarama.NewConfig()
+	config.ClientID = kafkaClientID
+	config.Net.DialTimeout = kafkaTimeout
+	config.Net.ReadTimeout = kafkaTimeout
+	config.Net.WriteTimeout = kafkaTimeout
+	if tlsConfig != nil {
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+	if credentials[usernameKey] != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		config.Net.SASL.User = credentials[usernameKey]
+		config.Net.SASL.Password = credentials[passwordKey]
+	}
+	// acks from all in-sync replicas are required before a record is published
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Timeout = kafkaTimeout
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("newKafka: %v", err)
+	}
+
+	return &Kafka{
+		name:        dest.Name,
+		brokers:     dest.Brokers,
+		topic:       dest.Topic,
+		config:      config,
+		newProducer: sarama.NewSyncProducer,
+		now:         time.Now,
+	}, nil
+}
+
+// kafkaTLSConfig returns the TLS config from the secret, or nil when TLS is not configured
+func kafkaTLSConfig(credentials map[string]string) (*tls.Config, error) {
+	ca, cert, key := credentials[caCertKey], credentials[tlsCertKey], credentials[tlsKeyKey]
+	if ca == "" && cert == "" && key == "" {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ca != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			return nil, fmt.Errorf("%s does not contain a PEM encoded certificate", caCertKey)
+		}
+		config.RootCAs = pool
+	}
+	if cert != "" || key != "" {
+		pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	return config, nil
+}
+
+// Name returns the name of the destination
+func (k *Kafka) Name() string { return k.name }
+
+// Export publishes the usage records of the payload
+func (k *Kafka) Export(payload Payload) error {
+	records, err := readUsageRecords(payload)
+	if err != nil {
+		return fmt.Errorf("Export: %v", err)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	producer, err := k.newProducer(k.brokers, k.config)
+	if err != nil {
+		return fmt.Errorf("Export: failed to connect to the brokers: %v", err)
+	}
+	defer producer.Close()
+
+	timestamp := k.now()
+	messages := make([]*sarama.ProducerMessage, 0, len(records))
+	for _, rec := range records {
+		messages = append(messages, &sarama.ProducerMessage{
+			Topic:     k.topic,
+			Key:       sarama.ByteEncoder(rec.key),
+			Value:     sarama.ByteEncoder(rec.value),
+			Timestamp: timestamp,
+		})
+	}
+	if err := producer.SendMessages(messages); err != nil {
+		if errs, ok := err.(sarama.ProducerErrors); ok && len(errs) > 0 {
+			return fmt.Errorf("Export: failed to publish %d of %d records to topic %s: %v", len(errs), len(messages), k.topic, errs[0].Err)
+		}
+		return fmt.Errorf("Export: failed to publish to topic %s: %v", k.topic, err)
+	}
+	return nil
+}
+
+// readUsageRecords returns a JSON record for each row of the usage reports in a payload
+func readUsageRecords(payload Payload) ([]kafkaRecord, error) {
+	f, err := os.Open(payload.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open payload: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip: %v", err)
+	}
+	defer gz.Close()
+
+	var clusterID string
+	var rows []map[string]string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read tar file: %v", err)
+		}
+		switch {
+		case header.Name == "manifest.json":
+			var m struct {
+				ClusterID string `json:"cluster_id"`
+			}
+			if err := json.NewDecoder(tr).Decode(&m); err != nil {
+				return nil, fmt.Errorf("failed to read manifest: %v", err)
+			}
+			clusterID = m.ClusterID
+		case strings.HasSuffix(header.Name, ".csv"):
+			reportRows, err := readUsageRows(tr, payload.PayloadID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %v", header.Name, err)
+			}
+			rows = append(rows, reportRows...)
+		}
+	}
+
+	// the manifest is written after the reports, so the cluster id is added once the tar file is read
+	records := make([]kafkaRecord, 0, len(rows))
+	for _, fields := range rows {
+		fields["cluster_id"] = clusterID
+		value, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal record: %v", err)
+		}
+		records = append(records, kafkaRecord{key: []byte(fields["namespace"]), value: value})
+	}
+	return records, nil
+}
+
+// readUsageRows returns the fields of each row of a usage report CSV. Reports that do not contain usage are skipped.
+// Reports packaged with the tab delimiter are detected from the header.
+func readUsageRows(r io.Reader, payloadID string) ([]map[string]string, error) {
+	buffered := bufio.NewReader(r)
+	reader := csv.NewReader(buffered)
+	// a short report is returned with an error, but the header is still complete
+	line, _ := buffered.Peek(buffered.Size())
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if bytes.Count(line, []byte("\t")) > bytes.Count(line, []byte(",")) {
+		reader.Comma = '\t'
+	}
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	reportType := ""
+	for _, col := range header {
+		if t, ok := usageReports[col]; ok {
+			reportType = t
+		}
+	}
+	if reportType == "" {
+		return nil, nil
+	}
+
+	var rows []map[string]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		} else if err != nil {
+			return nil, err
+		}
+		fields := map[string]string{"report_type": reportType, "payload_id": payloadID}
+		for i, col := range header {
+			if i < len(row) {
+				fields[col] = row[i]
+			}
+		}
+		rows = append(rows, fields)
+	}
+}
@@ -0,0 +1,288 @@
+package exporter
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func writeUsagePayload(t *testing.T, dir string) Payload {
+	path := filepath.Join(dir, "20210101T000000-cost-mgmt.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create payload: %v", err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	files := []struct{ name, contents string }{
+		{"abc_openshift_usage_report.0.csv", "interval_start,namespace,pod,pod_usage_cpu_core_seconds\n2021-01-01 00:00:00,ns-a,pod-a,10\n2021-01-01 00:00:00,ns-b,pod-b,20\n"},
+		{"abc_openshift_usage_report.1.csv", "interval_start,namespace,persistentvolumeclaim,persistentvolumeclaim_usage_byte_seconds\n2021-01-01 00:00:00,ns-a,pvc-a,30\n"},
+		{"abc_openshift_usage_report.2.csv", "interval_start,namespace,namespace_labels\n2021-01-01 00:00:00,ns-a,label_a:b\n"},
+		{"manifest.json", `{"uuid":"abc","cluster_id":"my-cluster"}`},
+	}
+	for _, file := range files {
+		tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.contents))})
+		tw.Write([]byte(file.contents))
+	}
+	tw.Close()
+	gz.Close()
+	return Payload{Path: path, Name: filepath.Base(path), PayloadID: "abc"}
+}
+
+func TestReadUsageRecords(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	records, err := readUsageRecords(writeUsagePayload(t, dir))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records want 3", len(records))
+	}
+	wantTypes := []string{"pod", "pod", "storage"}
+	for i, rec := range records {
+		fields := map[string]string{}
+		if err := json.Unmarshal(rec.value, &fields); err != nil {
+			t.Fatalf("record is not json: %v", err)
+		}
+		if fields["cluster_id"] != "my-cluster" || fields["payload_id"] != "abc" || fields["report_type"] != wantTypes[i] {
+			t.Errorf("got record %v", fields)
+		}
+		if string(rec.key) != fields["namespace"] {
+			t.Errorf("got key %s for namespace %s", rec.key, fields["namespace"])
+		}
+	}
+}
+
+func TestReadUsageRowsDelimiter(t *testing.T) {
+	delimiterTests := []struct {
+		name   string
+		report string
+	}{
+		{name: "comma", report: "interval_start,namespace,pod_usage_cpu_core_seconds\n2021-01-01 00:00:00,ns-a,10\n"},
+		{name: "tab", report: "interval_start\tnamespace\tpod_usage_cpu_core_seconds\n2021-01-01 00:00:00\tns-a\t10\n"},
+		{name: "quoted tab", report: "\"interval_start\"\t\"namespace\"\t\"pod_usage_cpu_core_seconds\"\n\"2021-01-01 00:00:00\"\t\"ns-a\"\t\"10\"\n"},
+	}
+	for _, tt := range delimiterTests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readUsageRows(strings.NewReader(tt.report), "abc")
+			if err != nil {
+				t.Fatalf("%s unexpected error: %v", tt.name, err)
+			}
+			if len(rows) != 1 || rows[0]["namespace"] != "ns-a" || rows[0]["pod_usage_cpu_core_seconds"] != "10" {
+				t.Errorf("%s got rows %v", tt.name, rows)
+			}
+		})
+	}
+}
+
+func TestKafkaExport(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	payload := writeUsagePayload(t, dir)
+
+	kafkaTests := []struct {
+		name      string
+		topic     string
+		produce   sarama.KError
+		wantError bool
+	}{
+		{name: "published", topic: "usage"},
+		{name: "unknown topic", topic: "missing", wantError: true},
+		{name: "not authorized", topic: "usage", produce: sarama.ErrTopicAuthorizationFailed, wantError: true},
+	}
+	for _, tt := range kafkaTests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := sarama.NewMockBroker(t, 1)
+			defer broker.Close()
+			metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+			// the produce requests of kafka 0.11 and later carry record batches
+			produce := sarama.NewMockProduceResponse(t).SetVersion(3)
+			for partition := int32(0); partition < 3; partition++ {
+				metadata.SetLeader("usage", partition, broker.BrokerID())
+				if tt.produce != sarama.ErrNoError {
+					produce.SetError("usage", partition, tt.produce)
+				}
+			}
+			broker.SetHandlerByMap(map[string]sarama.MockResponse{"MetadataRequest": metadata, "ProduceRequest": produce})
+
+			dest := kokumetricscfgv1beta1.DestinationSpec{Name: "chargeback", Type: "kafka", Brokers: []string{"127.0.0.1:1", broker.Addr()}, Topic: tt.topic}
+			exp, err := newKafka(dest, nil)
+			if err != nil {
+				t.Fatalf("failed to create exporter: %v", err)
+			}
+			config := exp.(*Kafka).config
+			config.Metadata.Retry.Backoff = time.Millisecond
+			config.Producer.Retry.Backoff = time.Millisecond
+			err = exp.Export(payload)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+			if tt.wantError {
+				return
+			}
+			produced := false
+			for _, rr := range broker.History() {
+				if _, ok := rr.Request.(*sarama.ProduceRequest); ok {
+					produced = true
+				}
+			}
+			if !produced {
+				t.Errorf("%s got no produce request", tt.name)
+			}
+		})
+	}
+}
+
+func TestKafkaExportRecords(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	payload := writeUsagePayload(t, dir)
+
+	exp, err := newKafka(kokumetricscfgv1beta1.DestinationSpec{Name: "chargeback", Brokers: []string{"kafka:9092"}, Topic: "usage"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	k := exp.(*Kafka)
+	producer := mocks.NewSyncProducer(t, k.config)
+	k.newProducer = func(brokers []string, config *sarama.Config) (sarama.SyncProducer, error) { return producer, nil }
+	var namespaces []string
+	for i := 0; i < 3; i++ {
+		producer.ExpectSendMessageWithCheckerFunctionAndSucceed(func(value []byte) error {
+			fields := map[string]string{}
+			if err := json.Unmarshal(value, &fields); err != nil {
+				return err
+			}
+			if fields["cluster_id"] != "my-cluster" {
+				return errors.New("record without the cluster id")
+			}
+			namespaces = append(namespaces, fields["namespace"])
+			return nil
+		})
+	}
+	if err := k.Export(payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(namespaces, ",") != "ns-a,ns-b,ns-a" {
+		t.Errorf("got records of namespaces %v", namespaces)
+	}
+
+	producer = mocks.NewSyncProducer(t, k.config)
+	producer.ExpectSendMessageAndFail(sarama.ErrTopicAuthorizationFailed)
+	producer.ExpectSendMessageAndSucceed()
+	producer.ExpectSendMessageAndSucceed()
+	if err := k.Export(payload); err == nil || !strings.Contains(err.Error(), "topic usage") {
+		t.Errorf("got error %v want a failure to publish to the topic", err)
+	}
+}
+
+// testCA returns a PEM encoded self-signed CA certificate
+func testCA(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafka-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestNewKafkaConfig(t *testing.T) {
+	dest := kokumetricscfgv1beta1.DestinationSpec{Brokers: []string{"kafka:9092"}, Topic: "usage"}
+	exp, err := newKafka(dest, map[string]string{usernameKey: "user", passwordKey: "pass"})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	config := exp.(*Kafka).config
+	if !config.Net.SASL.Enable || config.Net.SASL.Mechanism != sarama.SASLTypePlaintext || config.Net.SASL.User != "user" || config.Net.SASL.Password != "pass" {
+		t.Errorf("got SASL config %+v want PLAIN with the secret credentials", config.Net.SASL)
+	}
+	if config.Net.TLS.Enable {
+		t.Errorf("got TLS enabled without TLS data in the secret")
+	}
+	if config.Producer.RequiredAcks != sarama.WaitForAll {
+		t.Errorf("got required acks %d want all in-sync replicas", config.Producer.RequiredAcks)
+	}
+	partitioner := config.Producer.Partitioner("usage")
+	first, _ := partitioner.Partition(&sarama.ProducerMessage{Key: sarama.StringEncoder("ns-a")}, 3)
+	second, _ := partitioner.Partition(&sarama.ProducerMessage{Key: sarama.StringEncoder("ns-a")}, 3)
+	if !partitioner.RequiresConsistency() || first != second {
+		t.Errorf("got partitions %d and %d want the records of a namespace in one partition", first, second)
+	}
+
+	exp, err = newKafka(dest, map[string]string{caCertKey: string(testCA(t))})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	if config := exp.(*Kafka).config; !config.Net.TLS.Enable || config.Net.TLS.Config == nil || config.Net.SASL.Enable {
+		t.Errorf("got TLS %t and SASL %t want TLS only", config.Net.TLS.Enable, config.Net.SASL.Enable)
+	}
+}
+
+func TestNewKafka(t *testing.T) {
+	newKafkaTests := []struct {
+		name        string
+		dest        kokumetricscfgv1beta1.DestinationSpec
+		credentials map[string]string
+		wantError   bool
+	}{
+		{name: "missing brokers", dest: kokumetricscfgv1beta1.DestinationSpec{Topic: "usage"}, wantError: true},
+		{name: "missing topic", dest: kokumetricscfgv1beta1.DestinationSpec{Brokers: []string{"kafka:9092"}}, wantError: true},
+		{
+			name:        "missing password",
+			dest:        kokumetricscfgv1beta1.DestinationSpec{Brokers: []string{"kafka:9092"}, Topic: "usage"},
+			credentials: map[string]string{usernameKey: "user"},
+			wantError:   true,
+		},
+		{
+			name:        "invalid ca",
+			dest:        kokumetricscfgv1beta1.DestinationSpec{Brokers: []string{"kafka:9092"}, Topic: "usage"},
+			credentials: map[string]string{caCertKey: "not a cert"},
+			wantError:   true,
+		},
+		{name: "valid", dest: kokumetricscfgv1beta1.DestinationSpec{Brokers: []string{"kafka:9092"}, Topic: "usage"}},
+	}
+	for _, tt := range newKafkaTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newKafka(tt.dest, tt.credentials)
+			if err != nil && !tt.wantError {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.wantError {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+		})
+	}
+}
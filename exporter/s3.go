@@ -0,0 +1,127 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+)
+
+const (
+	accessKeyIDKey     = "access_key_id"
+	secretAccessKeyKey = "secret_access_key"
+	defaultS3Region    = "us-east-1"
+)
+
+func init() {
+	Register("s3", newS3)
+}
+
+// S3 writes payloads to an S3 compatible bucket using path-style requests signed with AWS Signature Version 4
+type S3 struct {
+	name     string
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	region   string
+	signer   *v4.Signer
+	client   *http.Client
+	now      func() time.Time
+}
+
+func newS3(dest kokumetricscfgv1beta1.DestinationSpec, creds map[string]string) (Exporter, error) {
+	if dest.Bucket == "" {
+		return nil, fmt.Errorf("newS3: bucket is required")
+	}
+	for _, k := range []string{accessKeyIDKey, secretAccessKeyKey} {
+		if creds[k] == "" {
+			return nil, fmt.Errorf("newS3: secret not found with expected %s data", k)
+		}
+	}
+	region := dest.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	endpoint := dest.URL
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	u, err := url.ParseRequestURI(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("newS3: invalid url: %v", err)
+	}
+	signer := v4.NewSigner(credentials.NewStaticCredentials(creds[accessKeyIDKey], creds[secretAccessKeyKey], ""))
+	// S3 object keys are escaped once, not twice like the paths of other AWS services
+	signer.DisableURIPathEscaping = true
+	return &S3{
+		name:     dest.Name,
+		endpoint: u,
+		bucket:   dest.Bucket,
+		prefix:   strings.Trim(dest.Path, "/"),
+		region:   region,
+		signer:   signer,
+		client:   &http.Client{Timeout: 2 * time.Minute, Transport: crhchttp.TrustedTransport{}},
+		now:      time.Now,
+	}, nil
+}
+
+// Name returns the name of the destination
+func (s *S3) Name() string { return s.name }
+
+// Export puts the payload into the bucket
+func (s *S3) Export(payload Payload) error {
+	contents, err := ioutil.ReadFile(payload.Path)
+	if err != nil {
+		return fmt.Errorf("Export: failed to read payload: %v", err)
+	}
+	u := *s.endpoint
+	u.Path = "/" + path.Join(strings.Trim(u.Path, "/"), s.bucket, s.prefix, payload.Name)
+	req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(contents))
+	if err != nil {
+		return fmt.Errorf("Export: could not create request: %v", err)
+	}
+	req.Header.Set("Content-Type", payloadContentType)
+	if _, err := s.signer.Sign(req, bytes.NewReader(contents), "s3", s.region, s.now()); err != nil {
+		return fmt.Errorf("Export: could not sign the request: %v", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Export: could not send the request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Export: s3 responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
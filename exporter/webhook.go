@@ -0,0 +1,90 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+)
+
+const tokenKey = "token"
+
+func init() {
+	Register("webhook", newWebhook)
+}
+
+// Webhook sends payloads as the raw body of a POST request
+type Webhook struct {
+	name   string
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newWebhook(dest kokumetricscfgv1beta1.DestinationSpec, credentials map[string]string) (Exporter, error) {
+	if _, err := url.ParseRequestURI(dest.URL); err != nil {
+		return nil, fmt.Errorf("newWebhook: invalid url: %v", err)
+	}
+	return &Webhook{
+		name:   dest.Name,
+		url:    dest.URL,
+		token:  credentials[tokenKey],
+		client: &http.Client{Timeout: 2 * time.Minute, Transport: crhchttp.TrustedTransport{}},
+	}, nil
+}
+
+// Name returns the name of the destination
+func (w *Webhook) Name() string { return w.name }
+
+// Export posts the payload to the webhook. Any 2xx response is treated as accepted.
+func (w *Webhook) Export(payload Payload) error {
+	contents, err := ioutil.ReadFile(payload.Path)
+	if err != nil {
+		return fmt.Errorf("Export: failed to read payload: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(contents))
+	if err != nil {
+		return fmt.Errorf("Export: could not create request: %v", err)
+	}
+	req.Header.Set("Content-Type", payloadContentType)
+	req.Header.Set("X-Payload-Name", payload.Name)
+	if payload.PayloadID != "" {
+		req.Header.Set("X-Payload-Id", payload.PayloadID)
+	}
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Export: could not send the request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Export: webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ module github.com/project-koku/koku-metrics-operator
 go 1.13
 
 require (
+	github.com/Shopify/sarama v1.27.2
+	github.com/aws/aws-sdk-go v1.35.37
 	github.com/go-logr/logr v0.1.0
 	github.com/google/uuid v1.1.1
 	github.com/mitchellh/mapstructure v1.1.2
@@ -0,0 +1,286 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package hub
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/logging"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+)
+
+const (
+	// UploadPath is the path spoke clusters upload payloads to. It matches the ingress upload path
+	// so that spokes only need their api_url pointed at the hub.
+	UploadPath = "/api/ingress/v1/upload"
+
+	payloadContentType = "application/vnd.redhat.hccm.tar+tgz"
+
+	// maxPayloadBytes bounds the size of an uploaded payload. Payloads are split at 100MB by the packager.
+	maxPayloadBytes int64 = 110 * 1024 * 1024
+)
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// DefaultReceiver is the receiver served by the operator. It rejects uploads until it is configured by the reconciler.
+var DefaultReceiver = &Receiver{Log: ctrl.Log.WithName("hub").WithName("Receiver")}
+
+// Config is the configuration of a hub receiver
+type Config struct {
+	// UploadDir is the directory received payloads are written to. Payloads in this directory are
+	// forwarded by the hub's own upload cycle.
+	UploadDir string
+	Username  string
+	Password  string
+}
+
+// Stats are the counts of payloads received from spoke clusters
+type Stats struct {
+	Received           int64
+	Rejected           int64
+	LastReceivedTime   time.Time
+	LastSpokeClusterID string
+}
+
+// Receiver accepts payloads uploaded by spoke clusters
+type Receiver struct {
+	Log logr.Logger
+
+	mu      sync.RWMutex
+	enabled bool
+	config  Config
+	stats   Stats
+}
+
+type manifest struct {
+	UUID      string `json:"uuid"`
+	ClusterID string `json:"cluster_id"`
+}
+
+// Configure enables the receiver with the given configuration
+func (r *Receiver) Configure(cfg Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enabled = true
+	r.config = cfg
+}
+
+// Disable causes the receiver to reject all uploads
+func (r *Receiver) Disable() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enabled = false
+	r.config = Config{}
+}
+
+// Stats returns the counts of payloads received
+func (r *Receiver) Stats() Stats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.stats
+}
+
+func (r *Receiver) reject(w http.ResponseWriter, status int, msg string) {
+	r.mu.Lock()
+	r.stats.Rejected++
+	r.mu.Unlock()
+	r.Log.Info("rejected spoke upload", logging.HTTPStatus, status, "reason", msg)
+	http.Error(w, msg, status)
+}
+
+// ServeHTTP accepts a multipart payload upload in the same format as the ingress service
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	enabled, cfg := r.enabled, r.config
+	r.mu.RUnlock()
+
+	if !enabled {
+		r.reject(w, http.StatusServiceUnavailable, "hub aggregation is not enabled")
+		return
+	}
+	if req.Method != http.MethodPost {
+		r.reject(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	user, password, ok := req.BasicAuth()
+	if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(cfg.Username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(cfg.Password)) != 1 {
+		r.reject(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, maxPayloadBytes)
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		r.reject(w, http.StatusBadRequest, fmt.Sprintf("missing payload: %v", err))
+		return
+	}
+	defer file.Close()
+	if header.Header.Get("Content-Type") != payloadContentType {
+		r.reject(w, http.StatusUnsupportedMediaType, "unsupported payload content type")
+		return
+	}
+
+	m, err := storePayload(cfg.UploadDir, file)
+	if err != nil {
+		r.reject(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	r.mu.Lock()
+	r.stats.Received++
+	r.stats.LastReceivedTime = time.Now().UTC()
+	r.stats.LastSpokeClusterID = m.ClusterID
+	r.mu.Unlock()
+
+	r.Log.Info("received spoke payload", logging.ClusterID, m.ClusterID, logging.PayloadID, m.UUID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// storePayload writes the payload to the upload directory once its manifest has been validated
+func storePayload(dir string, payload io.Reader) (*manifest, error) {
+	tmp, err := ioutil.TempFile(dir, ".spoke-*"+dirconfig.PartialSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("storePayload: failed to create file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, payload); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("storePayload: failed to read payload: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("storePayload: failed to write payload: %v", err)
+	}
+
+	contents, err := packaging.ReadManifest(tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("storePayload: invalid payload: %v", err)
+	}
+	m := &manifest{}
+	if err := json.Unmarshal(contents, m); err != nil {
+		return nil, fmt.Errorf("storePayload: invalid manifest: %v", err)
+	}
+	if m.UUID == "" || m.ClusterID == "" {
+		return nil, fmt.Errorf("storePayload: manifest is missing the uuid or cluster_id")
+	}
+
+	name := fmt.Sprintf("%s%s-%s.tar.gz", packaging.ForwardedPayloadPrefix,
+		unsafeNameChars.ReplaceAllString(m.ClusterID, "_"), unsafeNameChars.ReplaceAllString(m.UUID, "_"))
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return nil, fmt.Errorf("storePayload: failed to store payload: %v", err)
+	}
+	return m, nil
+}
+
+// servingCertificate is the serving certificate of the hub receiver. The files are loaded again when they change, so
+// that a certificate rotated by the service CA is used without a restart.
+type servingCertificate struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// get returns the certificate, and loads it again if the certificate file changed since it was loaded
+func (c *servingCertificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	info, err := os.Stat(c.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("no serving certificate: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cert != nil && info.ModTime().Equal(c.modTime) {
+		return c.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the serving certificate: %v", err)
+	}
+	c.cert = &cert
+	c.modTime = info.ModTime()
+	return c.cert, nil
+}
+
+// Server serves a handler on its own address over TLS. It implements the controller-runtime Runnable
+// interface so that it is started and stopped with the manager.
+type Server struct {
+	Addr    string
+	Handler http.Handler
+	Log     logr.Logger
+
+	// CertFile and KeyFile are the serving certificate and key, for example a service serving certificate. Until
+	// the files exist, connections are refused during the TLS handshake: the handler is never served without TLS.
+	CertFile string
+	KeyFile  string
+}
+
+// Start serves the handler until stop is closed
+func (s *Server) Start(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(UploadPath, s.Handler)
+	cert := &servingCertificate{certFile: s.CertFile, keyFile: s.KeyFile}
+	srv := &http.Server{
+		Addr:    s.Addr,
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: cert.get,
+		},
+	}
+	if _, err := cert.get(nil); err != nil {
+		s.Log.Error(err, "spoke uploads are refused until the serving certificate is available", "certFile", s.CertFile)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.Log.Info("starting hub receiver", "addr", s.Addr)
+		if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case <-stop:
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	case err := <-errCh:
+		return err
+	}
+}
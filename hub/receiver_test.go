@@ -0,0 +1,291 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package hub
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func makePayload(t *testing.T, manifest string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	if manifest != "" {
+		if err := tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(manifest))}); err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(manifest)); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func makeRequest(t *testing.T, payload []byte, contentType, user, password string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="payload.tar.gz"`)
+	h.Set("Content-Type", contentType)
+	fw, err := mw.CreatePart(h)
+	if err != nil {
+		t.Fatalf("failed to create part: %v", err)
+	}
+	fw.Write(payload)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, UploadPath, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+	return req
+}
+
+func TestReceiver(t *testing.T) {
+	validManifest := `{"uuid": "abc-123", "cluster_id": "spoke-cluster"}`
+	receiverTests := []struct {
+		name        string
+		enabled     bool
+		method      string
+		payload     []byte
+		contentType string
+		user        string
+		wantStatus  int
+		wantFile    string
+	}{
+		{
+			name:        "receiver disabled",
+			enabled:     false,
+			payload:     makePayload(t, validManifest),
+			contentType: payloadContentType,
+			user:        "user",
+			wantStatus:  http.StatusServiceUnavailable,
+		},
+		{
+			name:        "invalid credentials",
+			enabled:     true,
+			payload:     makePayload(t, validManifest),
+			contentType: payloadContentType,
+			user:        "someone-else",
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "missing credentials",
+			enabled:     true,
+			payload:     makePayload(t, validManifest),
+			contentType: payloadContentType,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong method",
+			enabled:    true,
+			method:     http.MethodGet,
+			user:       "user",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:        "wrong content type",
+			enabled:     true,
+			payload:     makePayload(t, validManifest),
+			contentType: "application/json",
+			user:        "user",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "payload without manifest",
+			enabled:     true,
+			payload:     makePayload(t, ""),
+			contentType: payloadContentType,
+			user:        "user",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "manifest without cluster id",
+			enabled:     true,
+			payload:     makePayload(t, `{"uuid": "abc-123"}`),
+			contentType: payloadContentType,
+			user:        "user",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "valid payload",
+			enabled:     true,
+			payload:     makePayload(t, validManifest),
+			contentType: payloadContentType,
+			user:        "user",
+			wantStatus:  http.StatusAccepted,
+			wantFile:    "forwarded-spoke-cluster-abc-123.tar.gz",
+		},
+	}
+	for _, tt := range receiverTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hub-receiver")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			r := &Receiver{Log: testutils.TestLogger{}}
+			if tt.enabled {
+				r.Configure(Config{UploadDir: dir, Username: "user", Password: "password"})
+			}
+			req := makeRequest(t, tt.payload, tt.contentType, tt.user, "password")
+			if tt.method != "" {
+				req.Method = tt.method
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s got status %d want %d: %s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			files, _ := ioutil.ReadDir(dir)
+			var names []string
+			for _, f := range files {
+				names = append(names, f.Name())
+			}
+			if tt.wantFile == "" && len(names) != 0 {
+				t.Errorf("%s expected no files to be stored, got %v", tt.name, names)
+			}
+			if tt.wantFile != "" && (len(names) != 1 || names[0] != tt.wantFile) {
+				t.Errorf("%s got files %v want %s", tt.name, names, tt.wantFile)
+			}
+
+			stats := r.Stats()
+			wantReceived := int64(0)
+			if tt.wantStatus == http.StatusAccepted {
+				wantReceived = 1
+			}
+			if stats.Received != wantReceived || stats.Received+stats.Rejected != 1 {
+				t.Errorf("%s got stats %+v", tt.name, stats)
+			}
+		})
+	}
+}
+
+func TestStorePayloadSanitizesName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hub-store")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	manifest := fmt.Sprintf(`{"uuid": %q, "cluster_id": %q}`, "../../etc", "cluster/../x")
+	if _, err := storePayload(dir, bytes.NewReader(makePayload(t, manifest))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || filepath.Dir(files[0]) != dir || strings.Contains(filepath.Base(files[0]), "/") {
+		t.Errorf("payload stored outside of the upload directory: %v", files)
+	}
+}
+
+func writeServingCertificate(t *testing.T, dir, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(filepath.Join(dir, "tls.crt"), certPEM, 0600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "tls.key"), keyPEM, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+func TestServingCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hub-tls")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	cert := &servingCertificate{certFile: filepath.Join(dir, "tls.crt"), keyFile: filepath.Join(dir, "tls.key")}
+
+	// connections are refused until the certificate exists
+	if _, err := cert.get(nil); err == nil {
+		t.Error("got no error without a serving certificate")
+	}
+
+	writeServingCertificate(t, dir, "first")
+	got, err := cert.get(nil)
+	if err != nil {
+		t.Fatalf("failed to load the serving certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(got.Certificate[0])
+	if leaf.Subject.CommonName != "first" {
+		t.Errorf("got certificate %s want first", leaf.Subject.CommonName)
+	}
+
+	// a rotated certificate is loaded again
+	writeServingCertificate(t, dir, "rotated")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(cert.certFile, later, later); err != nil {
+		t.Fatalf("failed to change the certificate time: %v", err)
+	}
+	got, err = cert.get(nil)
+	if err != nil {
+		t.Fatalf("failed to load the rotated serving certificate: %v", err)
+	}
+	leaf, _ = x509.ParseCertificate(got.Certificate[0])
+	if leaf.Subject.CommonName != "rotated" {
+		t.Errorf("got certificate %s want rotated", leaf.Subject.CommonName)
+	}
+}
@@ -0,0 +1,33 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package logging
+
+// Structured log keys shared by the controllers, collector, packaging and crhchttp
+// packages so that a payload can be correlated through the whole pipeline.
+const (
+	// ClusterID is the key for the cluster identifier
+	ClusterID = "cluster_id"
+	// PayloadID is the key for the manifest uuid of a payload
+	PayloadID = "payload_id"
+	// QueryName is the key for the name of a prometheus query
+	QueryName = "query_name"
+	// HTTPStatus is the key for the status code of an http response
+	HTTPStatus = "http_status"
+)
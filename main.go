@@ -20,22 +20,37 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
 	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	configv1 "github.com/openshift/api/config/v1"
 
 	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
 	"github.com/project-koku/koku-metrics-operator/controllers"
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/hub"
+	"github.com/project-koku/koku-metrics-operator/mustgather"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/uploader"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -59,33 +74,96 @@ func init() {
 
 func main() {
 	var metricsAddr string
+	var probeAddr string
+	var hubAddr string
+	var hubCertDir string
+	var adminAddr string
 	var enableLeaderElection bool
+	var runOnce string
+	var listFiles bool
+	var diffPayloads bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-addr", ":8081", "The address the health probe endpoints bind to.")
+	flag.StringVar(&hubAddr, "hub-addr", ":8082", "The address the hub receiver for spoke cluster uploads binds to. Set to 0 to disable.")
+	flag.StringVar(&hubCertDir, "hub-cert-dir", "/etc/koku-metrics-operator/hub-tls", "The directory with the tls.crt and tls.key serving certificate of the hub receiver.")
+	flag.StringVar(&adminAddr, "admin-addr", "127.0.0.1:8083", "The loopback address the admin API binds to. Set to 0 to disable.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&runOnce, "run-once", "",
+		"Collect and upload the reports of the named KokuMetricsConfig once, and exit. "+
+			"Used by the Jobs of the cronjob execution mode.")
+	// logs are written as structured json by default. Use --zap-devel for human readable logs.
+	// logs are also retained in memory so they can be included in debug bundles.
+	flag.BoolVar(&listFiles, "list-files", false,
+		"Print the files of the report volume as JSON, and exit. Used by the kubectl-koku plugin.")
+	flag.BoolVar(&diffPayloads, "diff-payloads", false,
+		"Compare the two payloads of the report volume given as arguments, such as upload/<file>.tar.gz, "+
+			"print the rows added and removed in each report as JSON, and exit. Used by the kubectl-koku plugin.")
+	opts := zap.Options{DestWritter: io.MultiWriter(os.Stderr, mustgather.RecentLogs)}
+	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	if listFiles {
+		os.Exit(printInventory(os.Stdout))
+	}
+	if diffPayloads {
+		os.Exit(printPayloadDiff(os.Stdout, flag.Args()))
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
 	inCluster := false
 	if value, ok := os.LookupEnv("IN_CLUSTER"); ok {
 		inCluster = value == "true"
 	}
 
+	// secrets are read by name from the api server, instead of the cache, so the operator only needs get on named secrets
+	minimalRBAC := false
+	if value, ok := os.LookupEnv("MINIMAL_RBAC"); ok {
+		minimalRBAC = value == "true"
+	}
+
+	// reports are collected from recorded prometheus responses, instead of prometheus, to benchmark and load test
+	var fixtures collector.Fixtures
+	if dir, ok := os.LookupEnv(collector.FixturesEnvVar); ok && dir != "" {
+		var err error
+		fixtures, err = collector.LoadFixtures(dir)
+		if err != nil {
+			setupLog.Error(err, "unable to load prometheus fixtures")
+			os.Exit(1)
+		}
+		setupLog.Info("collecting reports from prometheus fixtures", "fixtures", dir)
+	}
+
+	postProcess, err := packaging.PostProcessHookFromEnv()
+	if err != nil {
+		setupLog.Error(err, "unable to set the post-processing hook")
+		os.Exit(1)
+	}
+	if postProcess != nil {
+		setupLog.Info("payloads are post-processed before they are uploaded", "hook", postProcess.Path)
+		uploader.DefaultQueue.PostProcess = postProcess
+	}
+
 	watchNamespace, err := getWatchNamespace()
 	if err != nil {
 		setupLog.Error(err, "unable to get WatchNamespace, "+
 			"the manager will watch and manage resources in all namespaces")
 	}
 
+	if runOnce != "" {
+		os.Exit(runJob(runOnce, watchNamespace, inCluster, fixtures))
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "91c624a5.openshift.io",
-		Namespace:          watchNamespace,
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: probeAddr,
+		Port:                   9443,
+		LeaderElection:         enableLeaderElection,
+		LeaderElectionID:       "91c624a5.openshift.io",
+		Namespace:              watchNamespace,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -98,25 +176,176 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controllers.KokuMetricsConfigReconciler{
+	reconciler := &controllers.KokuMetricsConfigReconciler{
 		Client:    mgr.GetClient(),
 		Log:       ctrl.Log.WithName("controllers").WithName("KokuMetricsConfig"),
 		Scheme:    mgr.GetScheme(),
 		Clientset: clientset,
 		InCluster: inCluster,
 		Namespace: watchNamespace,
-	}).SetupWithManager(mgr); err != nil {
+		Recorder:  mgr.GetEventRecorderFor("koku-metrics-operator"),
+		Fixtures:  fixtures,
+		// the prometheus responses of each collected window are recorded so that they can be replayed as fixtures
+		RecordFixtures: os.Getenv(collector.RecordFixturesEnvVar),
+	}
+	if minimalRBAC {
+		setupLog.Info("running with minimal RBAC, secrets are read without a cluster-wide secret watch")
+		reconciler.SecretReader = mgr.GetAPIReader()
+	}
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "KokuMetricsConfig")
 		os.Exit(1)
 	}
 
 	// +kubebuilder:scaffold:builder
 
+	if hubAddr != "0" {
+		// uploads are rejected unless hub aggregation is enabled in the KokuMetricsConfig
+		hubServer := &hub.Server{
+			Addr:     hubAddr,
+			Handler:  hub.DefaultReceiver,
+			Log:      ctrl.Log.WithName("hub"),
+			CertFile: filepath.Join(hubCertDir, "tls.crt"),
+			KeyFile:  filepath.Join(hubCertDir, "tls.key"),
+		}
+		if err := mgr.Add(hubServer); err != nil {
+			setupLog.Error(err, "unable to set up hub receiver")
+			os.Exit(1)
+		}
+	}
+
+	// serve the admin API on a loopback address
+	if adminAddr != "0" {
+		admin := &controllers.AdminServer{
+			Addr:      adminAddr,
+			Client:    mgr.GetClient(),
+			Namespace: watchNamespace,
+			Log:       ctrl.Log.WithName("admin"),
+		}
+		if err := mgr.Add(admin); err != nil {
+			setupLog.Error(err, "unable to set up admin API")
+			os.Exit(1)
+		}
+	}
+
+	// upload payloads outside of the reconcile
+	if err := mgr.Add(&uploader.Worker{Queue: uploader.DefaultQueue}); err != nil {
+		setupLog.Error(err, "unable to set up upload worker")
+		os.Exit(1)
+	}
+
+	// rebuild the http transports when the injected trusted CA bundle changes
+	if err := mgr.Add(&crhchttp.TrustedCAWatcher{Log: ctrl.Log.WithName("trusted-ca")}); err != nil {
+		setupLog.Error(err, "unable to set up trusted CA bundle watcher")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddHealthzCheck("reconcile", controllers.ReconcileCheck); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("prometheus", controllers.PrometheusCheck); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("storage", controllers.StorageCheck); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	// the reports being collected, packaged, or uploaded are finished within the termination grace period
+	setupLog.Info("waiting for the reconcile in progress before stopping")
+	controllers.WaitForReconcile()
+}
+
+// runJob collects and uploads the reports of a KokuMetricsConfig once, without a manager, and returns the exit code
+func runJob(name, namespace string, inCluster bool, fixtures collector.Fixtures) int {
+	log := setupLog.WithValues("KokuMetricsConfig", name)
+
+	cfg := ctrl.GetConfigOrDie()
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		log.Error(err, "unable to create client")
+		return 1
+	}
+	clientset, err := controllers.GetClientset()
+	if err != nil {
+		log.Error(err, "unable to get clientset")
+		return 1
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	defer broadcaster.Shutdown()
+
+	reconciler := &controllers.KokuMetricsConfigReconciler{
+		Client:    c,
+		Log:       ctrl.Log.WithName("controllers").WithName("KokuMetricsConfig"),
+		Scheme:    scheme,
+		Clientset: clientset,
+		InCluster: inCluster,
+		Namespace: namespace,
+		Recorder:  broadcaster.NewRecorder(scheme, corev1.EventSource{Component: "koku-metrics-operator"}),
+		Fixtures:  fixtures,
+		// a Job does not watch secrets, so they are always read from the api server
+		SecretReader: c,
+	}
+
+	log.Info("running a single collection")
+	if err := reconciler.RunOnce(types.NamespacedName{Namespace: namespace, Name: name}, ctrl.SetupSignalHandler()); err != nil {
+		log.Error(err, "collection failed")
+		return 1
+	}
+	log.Info("collection complete")
+	return 0
+}
+
+// printPayloadDiff writes the difference between two payloads of the report volume as JSON
+func printPayloadDiff(w io.Writer, names []string) int {
+	if len(names) != 2 {
+		fmt.Fprintln(os.Stderr, "--diff-payloads requires the old and the new payload")
+		return 2
+	}
+	var paths []string
+	for _, name := range names {
+		path, err := dirconfig.InventoryPath(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		paths = append(paths, path)
+	}
+	diff, err := packaging.DiffPayloads(paths[0], paths[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	diff.Old.File, diff.New.File = names[0], names[1]
+	if err := json.NewEncoder(w).Encode(diff); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// printInventory writes the files of the report volume as JSON
+func printInventory(w io.Writer) int {
+	files, err := dirconfig.Inventory()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := json.NewEncoder(w).Encode(files); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
 
 // getWatchNamespace returns the Namespace the operator should be watching for changes
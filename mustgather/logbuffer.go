@@ -0,0 +1,114 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package mustgather
+
+import (
+	"bytes"
+	"sync"
+)
+
+// DefaultLogLines is the number of log lines retained for the debug bundle
+const DefaultLogLines = 2000
+
+// RecentLogs holds the most recent operator log lines. main.go tees the logger output into it.
+var RecentLogs = NewLogBuffer(DefaultLogLines)
+
+// LogBuffer is an io.Writer that retains the last N lines written to it
+type LogBuffer struct {
+	mu      sync.Mutex
+	lines   [][]byte
+	next    int
+	full    bool
+	partial []byte
+}
+
+// NewLogBuffer returns a LogBuffer that retains up to size lines
+func NewLogBuffer(size int) *LogBuffer {
+	if size <= 0 {
+		size = DefaultLogLines
+	}
+	return &LogBuffer{lines: make([][]byte, size)}
+}
+
+// Write stores each complete line in p, discarding the oldest line when the buffer is full
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := append(b.partial, p...)
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		line := make([]byte, idx+1)
+		copy(line, data[:idx+1])
+		b.lines[b.next] = line
+		b.next = (b.next + 1) % len(b.lines)
+		if b.next == 0 {
+			b.full = true
+		}
+		data = data[idx+1:]
+	}
+	b.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// Bytes returns the retained lines, oldest first
+func (b *LogBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var out bytes.Buffer
+	if b.full {
+		for _, line := range b.lines[b.next:] {
+			out.Write(line)
+		}
+	}
+	for _, line := range b.lines[:b.next] {
+		out.Write(line)
+	}
+	return out.Bytes()
+}
+
+// Resize changes the number of lines retained, keeping the most recent lines
+func (b *LogBuffer) Resize(size int) {
+	if size <= 0 {
+		size = DefaultLogLines
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if size == len(b.lines) {
+		return
+	}
+	var retained [][]byte
+	if b.full {
+		retained = append(retained, b.lines[b.next:]...)
+	}
+	retained = append(retained, b.lines[:b.next]...)
+	if len(retained) > size {
+		retained = retained[len(retained)-size:]
+	}
+	b.lines = make([][]byte, size)
+	copy(b.lines, retained)
+	b.next = len(retained) % size
+	b.full = len(retained) == size
+}
@@ -0,0 +1,122 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package mustgather
+
+import (
+	"testing"
+)
+
+func TestLogBuffer(t *testing.T) {
+	logBufferTests := []struct {
+		name   string
+		size   int
+		writes []string
+		want   string
+	}{
+		{
+			name:   "fewer lines than size",
+			size:   3,
+			writes: []string{"one\n", "two\n"},
+			want:   "one\ntwo\n",
+		},
+		{
+			name:   "oldest lines are dropped",
+			size:   2,
+			writes: []string{"one\n", "two\n", "three\n"},
+			want:   "two\nthree\n",
+		},
+		{
+			name:   "partial lines are joined",
+			size:   3,
+			writes: []string{"o", "ne\ntw", "o\nthr"},
+			want:   "one\ntwo\n",
+		},
+		{
+			name:   "multiple lines in one write",
+			size:   2,
+			writes: []string{"one\ntwo\nthree\nfour\n"},
+			want:   "three\nfour\n",
+		},
+	}
+	for _, tt := range logBufferTests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewLogBuffer(tt.size)
+			for _, w := range tt.writes {
+				n, err := b.Write([]byte(w))
+				if err != nil || n != len(w) {
+					t.Fatalf("%s write returned (%d, %v)", tt.name, n, err)
+				}
+			}
+			if got := string(b.Bytes()); got != tt.want {
+				t.Errorf("%s got %q want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogBufferResize(t *testing.T) {
+	resizeTests := []struct {
+		name   string
+		size   int
+		writes []string
+		resize int
+		after  []string
+		want   string
+	}{
+		{
+			name:   "shrink keeps most recent lines",
+			size:   4,
+			writes: []string{"one\ntwo\nthree\n"},
+			resize: 2,
+			want:   "two\nthree\n",
+		},
+		{
+			name:   "shrink wrapped buffer",
+			size:   3,
+			writes: []string{"one\ntwo\nthree\nfour\n"},
+			resize: 2,
+			after:  []string{"five\n"},
+			want:   "four\nfive\n",
+		},
+		{
+			name:   "grow keeps all lines",
+			size:   2,
+			writes: []string{"one\ntwo\nthree\n"},
+			resize: 4,
+			after:  []string{"four\n"},
+			want:   "two\nthree\nfour\n",
+		},
+	}
+	for _, tt := range resizeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewLogBuffer(tt.size)
+			for _, w := range tt.writes {
+				b.Write([]byte(w))
+			}
+			b.Resize(tt.resize)
+			for _, w := range tt.after {
+				b.Write([]byte(w))
+			}
+			if got := string(b.Bytes()); got != tt.want {
+				t.Errorf("%s got %q want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
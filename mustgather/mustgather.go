@@ -0,0 +1,249 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package mustgather
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+)
+
+const (
+	// BundleDir is the directory, relative to the parent report directory, where bundles are written
+	BundleDir = "debug"
+
+	// DefaultMaxManifests is the number of most recent payload manifests included in a bundle
+	DefaultMaxManifests = 10
+
+	// maxBundles is the number of bundles retained on the volume
+	maxBundles = 3
+
+	timestampFormat = "20060102T150405"
+)
+
+// redactPattern matches credentials that may appear in logs, e.g. `"Authorization": "Bearer abc"` or `password=abc`
+var redactPattern = regexp.MustCompile(`(?i)(\b(?:authorization|password|token|secret)\b["']?\s*[:=]\s*["']?(?:bearer\s+|basic\s+)?|\bbearer\s+)[^\s"',]+`)
+
+// Gatherer assembles a sanitized support bundle from the operator state
+type Gatherer struct {
+	KMCfg        *kokumetricscfgv1beta1.KokuMetricsConfig
+	DirCfg       *dirconfig.DirectoryConfig
+	Logs         *LogBuffer
+	QueryStats   interface{}
+	MaxManifests int
+	Log          logr.Logger
+}
+
+type fileEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// sanitize removes credentials from the content
+func sanitize(content []byte) []byte {
+	return redactPattern.ReplaceAll(content, []byte("${1}REDACTED"))
+}
+
+// listDirectory walks the parent report directory and records every file in it
+func (g *Gatherer) listDirectory() ([]byte, error) {
+	entries := []fileEntry{}
+	root := g.DirCfg.Parent.Path
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, fileEntry{Path: rel, Size: info.Size(), Mode: info.Mode().String(), ModTime: info.ModTime().UTC()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listDirectory: %v", err)
+	}
+	return json.MarshalIndent(entries, "", " ")
+}
+
+// manifests returns the manifests of the most recent payloads in the upload directory
+func (g *Gatherer) manifests() map[string][]byte {
+	log := g.Log.WithValues("kokumetricsconfig", "manifests")
+	out := map[string][]byte{}
+	files, err := g.DirCfg.Upload.GetFiles()
+	if err != nil {
+		log.Error(err, "failed to list upload directory")
+		return out
+	}
+	var tarballs []string
+	for _, f := range files {
+		if strings.HasSuffix(f, ".tar.gz") {
+			tarballs = append(tarballs, f)
+		}
+	}
+	// payload names begin with their creation timestamp, so the newest sort last
+	sort.Strings(tarballs)
+	max := g.MaxManifests
+	if max <= 0 {
+		max = DefaultMaxManifests
+	}
+	if len(tarballs) > max {
+		tarballs = tarballs[len(tarballs)-max:]
+	}
+	for _, f := range tarballs {
+		manifest, err := packaging.ReadManifest(filepath.Join(g.DirCfg.Upload.Path, f))
+		if err != nil {
+			log.Error(err, "failed to read manifest", "file", f)
+			continue
+		}
+		out[strings.TrimSuffix(f, ".tar.gz")+"-manifest.json"] = manifest
+	}
+	return out
+}
+
+// contents gathers every file that is written to the bundle
+func (g *Gatherer) contents() (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	status, err := json.MarshalIndent(g.KMCfg.Status, "", " ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal status: %v", err)
+	}
+	files["status.json"] = status
+
+	spec, err := json.MarshalIndent(g.KMCfg.Spec, "", " ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spec: %v", err)
+	}
+	files["spec.json"] = spec
+
+	listing, err := g.listDirectory()
+	if err != nil {
+		return nil, err
+	}
+	files["directory-listing.json"] = listing
+
+	if g.QueryStats != nil {
+		stats, err := json.MarshalIndent(g.QueryStats, "", " ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal query stats: %v", err)
+		}
+		files["query-stats.json"] = stats
+	}
+
+	if g.Logs != nil {
+		files["operator.log"] = g.Logs.Bytes()
+	}
+
+	for name, manifest := range g.manifests() {
+		files[filepath.Join("manifests", name)] = manifest
+	}
+
+	for name, content := range files {
+		files[name] = sanitize(content)
+	}
+	return files, nil
+}
+
+// trimBundles removes the oldest bundles so that at most maxBundles remain
+func trimBundles(dir string) error {
+	d := dirconfig.Directory{Path: dir}
+	files, err := d.GetFiles()
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for len(files) > maxBundles {
+		if err := os.Remove(filepath.Join(dir, files[0])); err != nil {
+			return fmt.Errorf("failed to remove %s: %v", files[0], err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// WriteBundle writes the support bundle to the debug directory on the report volume and returns its path
+func (g *Gatherer) WriteBundle() (string, error) {
+	log := g.Log.WithValues("kokumetricsconfig", "WriteBundle")
+	files, err := g.contents()
+	if err != nil {
+		return "", fmt.Errorf("WriteBundle: %v", err)
+	}
+
+	dir := dirconfig.Directory{Path: filepath.Join(g.DirCfg.Parent.Path, BundleDir)}
+	if err := dirconfig.CheckExistsOrRecreate(log, dir); err != nil {
+		return "", fmt.Errorf("WriteBundle: could not create bundle directory: %v", err)
+	}
+
+	bundlePath := filepath.Join(dir.Path, time.Now().UTC().Format(timestampFormat)+"-debug-bundle.tar.gz")
+	bundle, err := os.Create(bundlePath)
+	if err != nil {
+		return "", fmt.Errorf("WriteBundle: error creating bundle: %v", err)
+	}
+	defer bundle.Close()
+
+	gzipWriter := gzip.NewWriter(bundle)
+	tw := tar.NewWriter(gzipWriter)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	now := time.Now()
+	for _, name := range names {
+		header := &tar.Header{Name: name, Size: int64(len(files[name])), Mode: 0644, ModTime: now}
+		if err := tw.WriteHeader(header); err != nil {
+			return "", fmt.Errorf("WriteBundle: error writing header for %s: %v", name, err)
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return "", fmt.Errorf("WriteBundle: error writing %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("WriteBundle: error closing tar: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", fmt.Errorf("WriteBundle: error closing gzip: %v", err)
+	}
+	if err := bundle.Sync(); err != nil {
+		return "", fmt.Errorf("WriteBundle: error syncing bundle: %v", err)
+	}
+
+	if err := trimBundles(dir.Path); err != nil {
+		log.Error(err, "failed to remove old bundles")
+	}
+	log.Info("debug bundle written", "bundle", bundlePath)
+	return bundlePath, nil
+}
@@ -0,0 +1,160 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package mustgather
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+)
+
+func TestSanitize(t *testing.T) {
+	sanitizeTests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "bearer header",
+			input: "Authorization: Bearer abc.def",
+			want:  "Authorization: Bearer REDACTED",
+		},
+		{
+			name:  "json password",
+			input: `{"password":"hunter2","user":"me"}`,
+			want:  `{"password":"REDACTED","user":"me"}`,
+		},
+		{
+			name:  "key value token",
+			input: "token=abc123 other=value",
+			want:  "token=REDACTED other=value",
+		},
+		{
+			name:  "field names are untouched",
+			input: `{"secret_name":"cloud-dot-redhat","msg":"found cloud.openshift.com token"}`,
+			want:  `{"secret_name":"cloud-dot-redhat","msg":"found cloud.openshift.com token"}`,
+		},
+	}
+	for _, tt := range sanitizeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(sanitize([]byte(tt.input))); got != tt.want {
+				t.Errorf("%s got %q want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func readBundle(t *testing.T, path string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open bundle: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read bundle: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	contents := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to read bundle: %v", err)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", hdr.Name, err)
+		}
+		contents[hdr.Name] = string(b)
+	}
+	return contents
+}
+
+func TestWriteBundle(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "mustgather")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dirCfg := &dirconfig.DirectoryConfig{
+		Parent: dirconfig.Directory{Path: tmpDir},
+		Upload: dirconfig.Directory{Path: filepath.Join(tmpDir, "upload")},
+	}
+	if err := dirCfg.Upload.Create(); err != nil {
+		t.Fatalf("failed to create upload dir: %v", err)
+	}
+
+	logs := NewLogBuffer(10)
+	logs.Write([]byte(`{"msg":"request","Authorization":"Bearer supersecret"}` + "\n"))
+
+	kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+	kmCfg.Status.ClusterID = "my-cluster"
+
+	g := &Gatherer{
+		KMCfg:      kmCfg,
+		DirCfg:     dirCfg,
+		Logs:       logs,
+		QueryStats: []string{"stat"},
+		Log:        testutils.TestLogger{},
+	}
+
+	var path string
+	for i := 0; i < maxBundles+1; i++ {
+		path, err = g.WriteBundle()
+		if err != nil {
+			t.Fatalf("WriteBundle returned an error: %v", err)
+		}
+		// bundle names are only unique to the second
+		os.Rename(path, strings.Replace(path, "-debug-bundle", "-"+string(rune('a'+i))+"-debug-bundle", 1))
+	}
+
+	bundles, err := ioutil.ReadDir(filepath.Join(tmpDir, BundleDir))
+	if err != nil {
+		t.Fatalf("failed to read bundle dir: %v", err)
+	}
+	if len(bundles) != maxBundles {
+		t.Errorf("expected %d bundles to be retained, got %d", maxBundles, len(bundles))
+	}
+
+	contents := readBundle(t, filepath.Join(tmpDir, BundleDir, bundles[len(bundles)-1].Name()))
+	for _, name := range []string{"status.json", "spec.json", "directory-listing.json", "query-stats.json", "operator.log"} {
+		if _, ok := contents[name]; !ok {
+			t.Errorf("bundle is missing %s", name)
+		}
+	}
+	if !strings.Contains(contents["status.json"], "my-cluster") {
+		t.Errorf("status.json does not contain the status: %s", contents["status.json"])
+	}
+	if strings.Contains(contents["operator.log"], "supersecret") {
+		t.Errorf("operator.log was not sanitized: %s", contents["operator.log"])
+	}
+}
@@ -0,0 +1,318 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// cadence returns the configured payload cadence
+func (p *FilePackager) cadence() kokumetricscfgv1beta1.PayloadCadence {
+	if p.KMCfg.Status.Packaging.PayloadCadence == "" {
+		return kokumetricscfgv1beta1.DefaultPayloadCadence
+	}
+	return p.KMCfg.Status.Packaging.PayloadCadence
+}
+
+// rollup returns true if the reports of the payload are rolled up to one row for each day
+func (p *FilePackager) rollup() bool {
+	return p.daily || p.cadence() == kokumetricscfgv1beta1.DailyCadence
+}
+
+// intervalLayout is the format of the interval columns of the reports
+const intervalLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// dailyHoursFile is the file, in the directory of each day of the daily rollup, that records the hours appended to
+// the reports of the day
+const dailyHoursFile = "rollup-hours.json"
+
+// dailyGracePeriod is how long after the end of a day its rollup is packaged even if its last hour was not packaged
+const dailyGracePeriod = 6 * time.Hour
+
+// dailyHoursRetention is how long the directory of a packaged day is kept, so that an hour that is back-filled
+// after its day was packaged is still not rolled up twice
+const dailyHoursRetention = 93 * 24 * time.Hour
+
+// dailyHours records the hours appended to the reports of a day of the daily rollup
+type dailyHours struct {
+	path string
+	// End is the end of the day, in the offset of its latest hour
+	End time.Time `json:"end"`
+	// Reports are the starts of the hours appended to each report, in UTC
+	Reports map[string][]string `json:"reports"`
+}
+
+// loadDailyHours reads the hours of the day directory. Empty hours are returned if the file does not exist.
+func loadDailyHours(dir string) (*dailyHours, error) {
+	hours := &dailyHours{path: filepath.Join(dir, dailyHoursFile), Reports: map[string][]string{}}
+	data, err := ioutil.ReadFile(hours.path)
+	if os.IsNotExist(err) {
+		return hours, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("loadDailyHours: failed to read hours: %v", err)
+	}
+	if err := json.Unmarshal(data, hours); err != nil {
+		return nil, fmt.Errorf("loadDailyHours: failed to parse hours: %v", err)
+	}
+	if hours.Reports == nil {
+		hours.Reports = map[string][]string{}
+	}
+	return hours, nil
+}
+
+// save writes the hours. The hours are written to a temporary file and renamed so that they are never left partially written.
+func (h *dailyHours) save() error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("save: failed to marshal hours: %v", err)
+	}
+	tmp := h.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("save: failed to write hours: %v", err)
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		return fmt.Errorf("save: failed to replace hours: %v", err)
+	}
+	return nil
+}
+
+// complete returns true once the last hour of the day was appended to a report, or the grace period after the end of
+// the day has passed
+func (h *dailyHours) complete(now time.Time) bool {
+	if h.End.IsZero() || now.Before(h.End) {
+		return false
+	}
+	if !now.Before(h.End.Add(dailyGracePeriod)) {
+		return true
+	}
+	last := h.End.Add(-time.Hour).UTC().Format(time.RFC3339)
+	for _, hours := range h.Reports {
+		for _, hour := range hours {
+			if hour == last {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dayRows are the rows of a report for a day, by the start of their hour in UTC
+type dayRows struct {
+	latest time.Time
+	hours  map[string][][]string
+}
+
+// copyToDaily appends the staged reports to the reports of their day in the daily directory, so that the daily
+// rollup of each day is packaged in a separate payload once the day is complete when the payload cadence is both.
+// The hours appended to each report are recorded, so that an hour that is packaged again is not rolled up twice.
+func (p *FilePackager) copyToDaily(filesToPackage []os.FileInfo) error {
+	if p.daily || p.cadence() != kokumetricscfgv1beta1.HourlyAndDailyCadence {
+		return nil
+	}
+	dailyPath := filepath.Join(p.DirCfg.Reports.Path, dirconfig.DailyDir)
+	for _, file := range filesToPackage {
+		name := strings.TrimPrefix(file.Name(), p.uid+"-")
+		if err := copyReportToDaily(filepath.Join(p.DirCfg.Staging.Path, file.Name()), name, dailyPath); err != nil {
+			return fmt.Errorf("copyToDaily: %v", err)
+		}
+	}
+	return nil
+}
+
+// copyReportToDaily appends the hours of the report at from that were not appended yet to the report named name in
+// the directory of their day. Reports without an interval start column are not rolled up.
+func copyReportToDaily(from, name, dailyPath string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return fmt.Errorf("failed to open report: %v", err)
+	}
+	defer src.Close()
+	reader := csv.NewReader(src)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read report header: %v", err)
+	}
+	startIdx := -1
+	for i, column := range header {
+		if column == "interval_start" {
+			startIdx = i
+		}
+	}
+	if startIdx < 0 {
+		return nil
+	}
+
+	days := map[string]*dayRows{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read report row: %v", err)
+		}
+		start, err := time.Parse(intervalLayout, row[startIdx])
+		if err != nil {
+			return fmt.Errorf("failed to parse interval start %q: %v", row[startIdx], err)
+		}
+		day, ok := days[start.Format("2006-01-02")]
+		if !ok {
+			day = &dayRows{hours: map[string][][]string{}}
+			days[start.Format("2006-01-02")] = day
+		}
+		if start.After(day.latest) {
+			day.latest = start
+		}
+		hour := start.UTC().Format(time.RFC3339)
+		day.hours[hour] = append(day.hours[hour], row)
+	}
+
+	for key, day := range days {
+		dir := filepath.Join(dailyPath, key)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return fmt.Errorf("could not create daily directory: %v", err)
+		}
+		hours, err := loadDailyHours(dir)
+		if err != nil {
+			return err
+		}
+		appended := map[string]bool{}
+		for _, hour := range hours.Reports[name] {
+			appended[hour] = true
+		}
+		var newHours []string
+		for hour := range day.hours {
+			if !appended[hour] {
+				newHours = append(newHours, hour)
+			}
+		}
+		if len(newHours) == 0 {
+			continue
+		}
+		sort.Strings(newHours)
+		var rows [][]string
+		for _, hour := range newHours {
+			rows = append(rows, day.hours[hour]...)
+		}
+		if err := appendRows(filepath.Join(dir, name), header, rows); err != nil {
+			return err
+		}
+		if end := time.Date(day.latest.Year(), day.latest.Month(), day.latest.Day()+1, 0, 0, 0, 0, day.latest.Location()); end.After(hours.End) {
+			hours.End = end
+		}
+		hours.Reports[name] = append(hours.Reports[name], newHours...)
+		if err := hours.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendRows appends the rows to the report at path. The header is only written when the report does not exist.
+func appendRows(path string, header []string, rows [][]string) error {
+	_, err := os.Stat(path)
+	exists := err == nil
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open daily report: %v", err)
+	}
+	defer dst.Close()
+
+	w := csv.NewWriter(dst)
+	if !exists {
+		if err := w.Write(header); err != nil {
+			return fmt.Errorf("failed to write daily report header: %v", err)
+		}
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write daily report: %v", err)
+	}
+	return dst.Close()
+}
+
+// dailyPackagers returns a packager for the rollup of each complete day that has reports to package. The directories
+// of the days that were packaged are removed after the retention.
+func (p *FilePackager) dailyPackagers(now time.Time) ([]*FilePackager, error) {
+	dailyPath := filepath.Join(p.DirCfg.Reports.Path, dirconfig.DailyDir)
+	dirs, err := ioutil.ReadDir(dailyPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("dailyPackagers: could not read daily reports directory: %v", err)
+	}
+	var packagers []*FilePackager
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		dayPath := filepath.Join(dailyPath, dir.Name())
+		hours, err := loadDailyHours(dayPath)
+		if err != nil {
+			return nil, fmt.Errorf("dailyPackagers: %v", err)
+		}
+		reports, err := filepath.Glob(filepath.Join(dayPath, "*.csv"))
+		if err != nil {
+			return nil, fmt.Errorf("dailyPackagers: %v", err)
+		}
+		if len(reports) == 0 {
+			if now.Sub(hours.End) > dailyHoursRetention {
+				if err := os.RemoveAll(dayPath); err != nil {
+					return nil, fmt.Errorf("dailyPackagers: failed to remove %s: %v", dir.Name(), err)
+				}
+			}
+			continue
+		}
+		if !hours.complete(now) {
+			continue
+		}
+		packagers = append(packagers, &FilePackager{
+			KMCfg: p.KMCfg,
+			DirCfg: &dirconfig.DirectoryConfig{
+				Parent:  p.DirCfg.Parent,
+				Reports: dirconfig.Directory{Path: dayPath},
+				Staging: dirconfig.Directory{Path: filepath.Join(p.DirCfg.Staging.Path, dirconfig.DailyDir, dir.Name())},
+				Upload:  p.DirCfg.Upload,
+			},
+			Log:              p.Log.WithValues("cadence", kokumetricscfgv1beta1.DailyCadence, "day", dir.Name()),
+			SigningKey:       p.SigningKey,
+			ClockSkew:        p.ClockSkew,
+			createdTimestamp: p.createdTimestamp,
+			maxBytes:         p.maxBytes,
+			tenant:           p.tenant,
+			nameSuffix:       p.nameSuffix + "-daily",
+			recollected:      p.recollected,
+			daily:            true,
+		})
+	}
+	return packagers, nil
+}
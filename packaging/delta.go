@@ -0,0 +1,277 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DimensionIndexFile is the name of the index of the dimension reports sent in full on the report volume
+const DimensionIndexFile = "dimension-index.json"
+
+// deltaRefresh is how long a dimension report can be referenced before it is sent in full again, so that a
+// payload that was not received is not referenced for long
+const deltaRefresh = 24 * time.Hour
+
+// periodColumns is the number of report period and interval columns at the start of each report row
+const periodColumns = 4
+
+// dimensionReports are the reports of dimension data, such as labels, that rarely change from hour to hour,
+// by the file name of the report
+var dimensionReports = map[string]string{
+	"node":      "-openshift-node-usage-",
+	"namespace": "-openshift-namespace-usage-",
+}
+
+// dimensionTable is a dimension report in the manifest of a payload in delta mode. The report is only included
+// in the payload with the payload id; later payloads reference it by its hash while it does not change.
+type dimensionTable struct {
+	Report    string `json:"report"`
+	Hash      string `json:"hash"`
+	PayloadID string `json:"payload_id"`
+}
+
+// DimensionEntry records the last payload that a dimension report was sent in full
+type DimensionEntry struct {
+	Hash      string    `json:"hash"`
+	PayloadID string    `json:"payload_id"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// PendingDimension is a dimension report sent in full in a payload that has not been uploaded yet
+type PendingDimension struct {
+	ClusterID  string    `json:"cluster_id"`
+	Report     string    `json:"report"`
+	Hash       string    `json:"hash"`
+	PackagedAt time.Time `json:"packaged_at"`
+}
+
+// DimensionIndex records the dimension reports sent in full, by cluster id and report. The dimension reports of a
+// payload are pending, by payload id, until the payload is uploaded, so that a payload that was never received is
+// not referenced.
+type DimensionIndex struct {
+	path    string
+	Tables  map[string]DimensionEntry     `json:"tables"`
+	Pending map[string][]PendingDimension `json:"pending,omitempty"`
+}
+
+// LoadDimensionIndex reads the dimension index at path. An empty index is returned if the file does not exist.
+func LoadDimensionIndex(path string) (*DimensionIndex, error) {
+	index := &DimensionIndex{path: path, Tables: map[string]DimensionEntry{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return index, fmt.Errorf("LoadDimensionIndex: failed to read index: %v", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return &DimensionIndex{path: path, Tables: map[string]DimensionEntry{}}, fmt.Errorf("LoadDimensionIndex: failed to parse index: %v", err)
+	}
+	if index.Tables == nil {
+		index.Tables = map[string]DimensionEntry{}
+	}
+	return index, nil
+}
+
+// Get returns the entry of the report of the cluster
+func (d *DimensionIndex) Get(clusterID, report string) (DimensionEntry, bool) {
+	entry, ok := d.Tables[clusterID+"/"+report]
+	return entry, ok
+}
+
+// Record sets the entry of the report of the cluster
+func (d *DimensionIndex) Record(clusterID, report string, entry DimensionEntry) {
+	d.Tables[clusterID+"/"+report] = entry
+}
+
+// Pend records the dimension reports sent in full in the payload, until the payload is uploaded. Pending reports of
+// payloads that were not uploaded within the refresh interval are dropped.
+func (d *DimensionIndex) Pend(payloadID string, pending []PendingDimension) {
+	for id, tables := range d.Pending {
+		if len(tables) == 0 || time.Since(tables[0].PackagedAt) >= deltaRefresh {
+			delete(d.Pending, id)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	if d.Pending == nil {
+		d.Pending = map[string][]PendingDimension{}
+	}
+	d.Pending[payloadID] = pending
+}
+
+// RecordUploaded records the pending dimension reports of the payload as sent at sentAt. It returns false if the
+// payload has no pending dimension reports.
+func (d *DimensionIndex) RecordUploaded(payloadID string, sentAt time.Time) bool {
+	pending, ok := d.Pending[payloadID]
+	if !ok {
+		return false
+	}
+	for _, table := range pending {
+		d.Record(table.ClusterID, table.Report, DimensionEntry{Hash: table.Hash, PayloadID: payloadID, SentAt: sentAt})
+	}
+	delete(d.Pending, payloadID)
+	return true
+}
+
+// Save writes the index. The index is written to a temporary file and renamed so that it is never left partially written.
+func (d *DimensionIndex) Save() error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("Save: failed to marshal index: %v", err)
+	}
+	tmp := d.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("Save: failed to write index: %v", err)
+	}
+	if err := os.Rename(tmp, d.path); err != nil {
+		return fmt.Errorf("Save: failed to replace index: %v", err)
+	}
+	return nil
+}
+
+// dimensionHash returns the hash of the distinct rows of a report without the report period and interval columns,
+// so that the hash only changes when the dimension data changes
+func dimensionHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("dimensionHash: failed to open report: %v", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	rows := map[string]bool{}
+	header := ""
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("dimensionHash: failed to read report: %v", err)
+		}
+		if len(record) > periodColumns {
+			record = record[periodColumns:]
+		}
+		if header == "" {
+			header = strings.Join(record, ",")
+			continue
+		}
+		rows[strings.Join(record, ",")] = true
+	}
+	sorted := make([]string, 0, len(rows))
+	for row := range rows {
+		sorted = append(sorted, row)
+	}
+	sort.Strings(sorted)
+	hash := sha256.New()
+	hash.Write([]byte(header + "\n"))
+	for _, row := range sorted {
+		hash.Write([]byte(row + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// dimensionReport returns the dimension report of the file, or an empty string if it is not a dimension report
+func dimensionReport(name string) string {
+	for report, pattern := range dimensionReports {
+		if strings.Contains(name, pattern) {
+			return report
+		}
+	}
+	return ""
+}
+
+// applyDelta replaces the dimension reports that have not changed since they were last sent in full by a
+// reference in the manifest, when delta reports are enabled. Re-collected reports are always sent in full.
+// It returns the files that remain to be packaged.
+func (p *FilePackager) applyDelta(files []os.FileInfo) ([]os.FileInfo, error) {
+	p.dimensions = nil
+	if !p.KMCfg.Status.Packaging.DeltaReports || p.recollected {
+		return files, nil
+	}
+	log := p.Log.WithValues("kokumetricsconfig", "applyDelta")
+	index, err := LoadDimensionIndex(filepath.Join(p.DirCfg.Parent.Path, DimensionIndexFile))
+	if err != nil {
+		log.Error(err, "failed to load dimension index, sending dimension reports in full")
+	}
+
+	var remaining []os.FileInfo
+	for _, file := range files {
+		report := dimensionReport(file.Name())
+		if report == "" {
+			remaining = append(remaining, file)
+			continue
+		}
+		absPath := filepath.Join(p.DirCfg.Staging.Path, file.Name())
+		hash, err := dimensionHash(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("applyDelta: %v", err)
+		}
+		entry, ok := index.Get(p.clusterID(), report)
+		if ok && entry.Hash == hash && time.Since(entry.SentAt) < deltaRefresh {
+			log.Info("dimension report has not changed, referencing the payload it was sent in", "report", report, "payloadID", entry.PayloadID)
+			if err := os.Remove(absPath); err != nil {
+				return nil, fmt.Errorf("applyDelta: failed to remove unchanged report: %v", err)
+			}
+			p.dimensions = append(p.dimensions, dimensionTable{Report: report, Hash: hash, PayloadID: entry.PayloadID})
+			continue
+		}
+		p.dimensions = append(p.dimensions, dimensionTable{Report: report, Hash: hash, PayloadID: p.uid})
+		remaining = append(remaining, file)
+	}
+	sort.Slice(p.dimensions, func(i, j int) bool { return p.dimensions[i].Report < p.dimensions[j].Report })
+	return remaining, nil
+}
+
+// pendDimensions records the dimension reports that were sent in full in the payload, once it is packaged. They are
+// only referenced by later payloads once the payload is uploaded and RecordUploaded is called.
+func (p *FilePackager) pendDimensions(uid string, dimensions []dimensionTable) error {
+	var sent []PendingDimension
+	for _, table := range dimensions {
+		if table.PayloadID == uid {
+			sent = append(sent, PendingDimension{ClusterID: p.clusterID(), Report: table.Report, Hash: table.Hash, PackagedAt: time.Now()})
+		}
+	}
+	if len(sent) == 0 {
+		return nil
+	}
+	index, err := LoadDimensionIndex(filepath.Join(p.DirCfg.Parent.Path, DimensionIndexFile))
+	if err != nil {
+		p.Log.Info("replacing unreadable dimension index", "error", err)
+	}
+	index.Pend(uid, sent)
+	if err := index.Save(); err != nil {
+		return fmt.Errorf("pendDimensions: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const testNamespaceHeader = "report_period_start,report_period_end,interval_start,interval_end,namespace,namespace_labels\n"
+
+func namespaceReport(interval, labels string) string {
+	return testNamespaceHeader +
+		"2021-01-01,2021-02-01," + interval + "," + interval + ",default," + labels + "\n" +
+		"2021-01-01,2021-02-01," + interval + "," + interval + ",kube-system,\n"
+}
+
+func TestDimensionHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dimensions")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write report: %v", err)
+		}
+		return path
+	}
+	first, err := dimensionHash(write("first.csv", namespaceReport("10:00", "label_app:a")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the same namespaces at another hour, and for two hours
+	for _, contents := range []string{
+		namespaceReport("11:00", "label_app:a"),
+		namespaceReport("11:00", "label_app:a") + namespaceReport("12:00", "label_app:a")[len(testNamespaceHeader):],
+	} {
+		got, err := dimensionHash(write("same.csv", contents))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != first {
+			t.Errorf("expected unchanged dimensions to have the same hash")
+		}
+	}
+	changed, err := dimensionHash(write("changed.csv", namespaceReport("10:00", "label_app:b")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed == first {
+		t.Errorf("expected changed dimensions to have a different hash")
+	}
+	if _, err := dimensionHash(filepath.Join(dir, "nonexistent.csv")); err == nil {
+		t.Errorf("expected an error for a missing report")
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	tests := []struct {
+		name        string
+		delta       bool
+		recollected bool
+		sentAt      time.Duration
+		labels      string
+		wantFiles   int
+		wantRef     bool
+		notUploaded bool
+	}{
+		{name: "delta reports disabled", delta: false, labels: "label_app:a", wantFiles: 3},
+		{name: "unchanged dimensions are referenced", delta: true, labels: "label_app:a", wantFiles: 2, wantRef: true},
+		{name: "changed dimensions are sent", delta: true, labels: "label_app:b", wantFiles: 3},
+		{name: "dimensions of a payload that was not uploaded are sent", delta: true, notUploaded: true, labels: "label_app:a", wantFiles: 3},
+		{name: "dimensions are refreshed", delta: true, sentAt: deltaRefresh, labels: "label_app:a", wantFiles: 3},
+		{name: "re-collected reports are sent in full", delta: true, recollected: true, labels: "label_app:a", wantFiles: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "delta")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			kmCfg := &kokumetricscfgv1beta1.KokuMetricsConfig{}
+			kmCfg.Status.ClusterID = "cluster"
+			kmCfg.Status.Packaging.DeltaReports = tt.delta
+			p := &FilePackager{KMCfg: kmCfg, DirCfg: genDirCfg(t, dir), Log: testLogger, uid: "second", recollected: tt.recollected}
+
+			// the namespace report was sent in full in an earlier payload
+			hash, err := dimensionHash(writeStaged(t, p, "first-cm-openshift-namespace-usage-202101.csv", namespaceReport("10:00", "label_app:a")))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := p.pendDimensions("first", []dimensionTable{{Report: "namespace", Hash: hash, PayloadID: "first"}}); err != nil {
+				t.Fatalf("failed to record dimensions: %v", err)
+			}
+			if !tt.notUploaded {
+				index, _ := LoadDimensionIndex(filepath.Join(dir, DimensionIndexFile))
+				if !index.RecordUploaded("first", time.Now()) {
+					t.Fatalf("expected pending dimensions of the first payload")
+				}
+				if err := index.Save(); err != nil {
+					t.Fatalf("failed to save index: %v", err)
+				}
+			}
+			if tt.sentAt > 0 {
+				index, _ := LoadDimensionIndex(filepath.Join(dir, DimensionIndexFile))
+				entry, _ := index.Get("cluster", "namespace")
+				entry.SentAt = entry.SentAt.Add(-tt.sentAt)
+				index.Record("cluster", "namespace", entry)
+				if err := index.Save(); err != nil {
+					t.Fatalf("failed to save index: %v", err)
+				}
+			}
+			if err := p.clearStaging(); err != nil {
+				t.Fatalf("failed to clear staging: %v", err)
+			}
+
+			var files []os.FileInfo
+			for name, contents := range map[string]string{
+				"second-cm-openshift-namespace-usage-202101.csv": namespaceReport("11:00", tt.labels),
+				"second-cm-openshift-node-usage-202101.csv":      "report_period_start,report_period_end,interval_start,interval_end,node\n2021-01-01,2021-02-01,11:00,11:00,node-1\n",
+				"second-cm-openshift-pod-usage-202101.csv":       "report_period_start,report_period_end,interval_start,interval_end,pod\n2021-01-01,2021-02-01,11:00,11:00,pod-1\n",
+			} {
+				info, err := os.Stat(writeStaged(t, p, name, contents))
+				if err != nil {
+					t.Fatalf("failed to stat report: %v", err)
+				}
+				files = append(files, info)
+			}
+
+			got, err := p.applyDelta(files)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != tt.wantFiles {
+				t.Errorf("got %d files to package, want %d", len(got), tt.wantFiles)
+			}
+			var ref *dimensionTable
+			for i, table := range p.dimensions {
+				if table.PayloadID != p.uid {
+					ref = &p.dimensions[i]
+				}
+			}
+			if (ref != nil) != tt.wantRef {
+				t.Fatalf("got reference %v, want a reference %t", ref, tt.wantRef)
+			}
+			if ref != nil {
+				if ref.Report != "namespace" || ref.PayloadID != "first" || ref.Hash != hash {
+					t.Errorf("unexpected reference %+v", ref)
+				}
+				if _, err := os.Stat(filepath.Join(p.DirCfg.Staging.Path, "second-cm-openshift-namespace-usage-202101.csv")); !os.IsNotExist(err) {
+					t.Errorf("expected the unchanged report to be removed from staging")
+				}
+			}
+			if tt.delta && !tt.recollected && len(p.dimensions) != 2 {
+				t.Errorf("expected the node and namespace reports in the manifest dimensions, got %+v", p.dimensions)
+			}
+
+			p.getManifest(map[int]string{}, p.DirCfg.Staging.Path)
+			if m := p.manifest.manifest.(manifest); len(m.Dimensions) != len(p.dimensions) {
+				t.Errorf("expected the dimensions in the manifest, got %+v", m.Dimensions)
+			}
+		})
+	}
+}
+
+func writeStaged(t *testing.T, p *FilePackager, name, contents string) string {
+	path := filepath.Join(p.DirCfg.Staging.Path, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	return path
+}
@@ -22,6 +22,7 @@ package packaging
 import (
 	"archive/tar"
 	"compress/gzip"
+	"crypto/ed25519"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
@@ -38,26 +39,52 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
 	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
 	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/logging"
 	"github.com/project-koku/koku-metrics-operator/strset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // FilePackager struct for defining the packaging vars
 type FilePackager struct {
-	KMCfg            *kokumetricscfgv1beta1.KokuMetricsConfig
-	DirCfg           *dirconfig.DirectoryConfig
-	Log              logr.Logger
+	KMCfg  *kokumetricscfgv1beta1.KokuMetricsConfig
+	DirCfg *dirconfig.DirectoryConfig
+	Log    logr.Logger
+	// SigningKey, when set, signs the reports of each payload
+	SigningKey ed25519.PrivateKey
+	// ClockSkew is how far the local clock is ahead of the API clock. It is subtracted from the manifest date; the
+	// start and end come from the Prometheus timestamps of the reports and are not corrected.
+	ClockSkew time.Duration
+	// Trimmed are the payloads that TrimPackages removed before they were uploaded
+	Trimmed []PayloadSummary
+
 	manifest         manifestInfo
 	uid              string
 	createdTimestamp string
 	maxBytes         int64
 	start            time.Time
 	end              time.Time
+	tenant           string
+	nameSuffix       string
+	// recollected is true when packaging re-collected reports
+	recollected bool
+	// daily is true when packaging the copies of the reports that are rolled up for each day
+	daily bool
+	// dimensions are the dimension reports of the payload in delta mode
+	dimensions []dimensionTable
 }
 
 const timestampFormat = "20060102T150405"
 
+// stalePartialAge is the age after which a partially written file in the upload directory is removed. Newer
+// partial files may be payloads that are still being received from spoke clusters.
+const stalePartialAge = time.Hour
+
+// ForwardedPayloadPrefix is the file name prefix of payloads received from spoke clusters. These payloads
+// are uploaded with the operator's own payloads but are not counted as local reports, and are trimmed separately.
+const ForwardedPayloadPrefix = "forwarded-"
+
 // Define the global variables
 const megaByte int64 = 1024 * 1024
 
@@ -69,6 +96,10 @@ const megaByte int64 = 1024 * 1024
 // VARIANCE := 0.03
 const variance float64 = 0.03
 
+// defaultCopyBufferKiB is the size of the buffer reports are copied into the tar.gz files with when no resource
+// hints are set
+const defaultCopyBufferKiB = 32
+
 // if we're creating more than 1k files, something is probably wrong.
 var maxSplits int64 = 1000
 
@@ -80,13 +111,77 @@ type Manifest interface{}
 
 // manifest template
 type manifest struct {
-	UUID      string    `json:"uuid"`
-	ClusterID string    `json:"cluster_id"`
-	Version   string    `json:"version"`
-	Date      time.Time `json:"date"`
-	Files     []string  `json:"files"`
-	Start     time.Time `json:"start"`
-	End       time.Time `json:"end"`
+	UUID            string         `json:"uuid"`
+	ClusterID       string         `json:"cluster_id"`
+	Version         string         `json:"version"`
+	Date            time.Time      `json:"date"`
+	Files           []string       `json:"files"`
+	Start           time.Time      `json:"start"`
+	End             time.Time      `json:"end"`
+	CostModel       *costModelHint `json:"cost_model,omitempty"`
+	Tenant          string         `json:"tenant,omitempty"`
+	BillingTimezone string         `json:"billing_timezone,omitempty"`
+	DataResidency   string         `json:"data_residency,omitempty"`
+	SchemaVersion   string         `json:"report_schema_version"`
+	// CSVDelimiter is the field delimiter of the reports when it is not a comma, in the copies exported to a destination
+	CSVDelimiter string `json:"csv_delimiter,omitempty"`
+	// Dimensions are the dimension reports of a payload in delta mode, and the payloads that they are included in
+	Dimensions []dimensionTable `json:"dimensions,omitempty"`
+	// Signature is the signature of the reports when payloads are signed
+	Signature *payloadSignature `json:"signature,omitempty"`
+}
+
+// costModelHint is the cost model in the manifest. It mirrors the cost model API of cost management so
+// that the cost model can be created and associated with the cluster's source without changes.
+type costModelHint struct {
+	Name         string           `json:"name,omitempty"`
+	Currency     string           `json:"currency,omitempty"`
+	Distribution string           `json:"distribution,omitempty"`
+	Markup       *costModelMarkup `json:"markup,omitempty"`
+	Rates        []costModelRate  `json:"rates,omitempty"`
+}
+
+type costModelMarkup struct {
+	Value string `json:"value"`
+	Unit  string `json:"unit"`
+}
+
+type costModelRate struct {
+	Metric      costModelMetric     `json:"metric"`
+	CostType    string              `json:"cost_type,omitempty"`
+	TieredRates []costModelTierRate `json:"tiered_rates"`
+}
+
+type costModelMetric struct {
+	Name string `json:"name"`
+}
+
+type costModelTierRate struct {
+	Value string `json:"value"`
+	Unit  string `json:"unit,omitempty"`
+}
+
+// newCostModelHint converts the cost model in the CR to the manifest format
+func newCostModelHint(spec *kokumetricscfgv1beta1.CostModelSpec) *costModelHint {
+	if spec == nil {
+		return nil
+	}
+	hint := &costModelHint{
+		Name:         spec.Name,
+		Currency:     spec.Currency,
+		Distribution: string(spec.Distribution),
+	}
+	if spec.Markup != "" {
+		hint.Markup = &costModelMarkup{Value: spec.Markup, Unit: "percent"}
+	}
+	for _, rate := range spec.Rates {
+		hint.Rates = append(hint.Rates, costModelRate{
+			Metric:      costModelMetric{Name: rate.Metric},
+			CostType:    string(rate.CostType),
+			TieredRates: []costModelTierRate{{Value: rate.Value, Unit: spec.Currency}},
+		})
+	}
+	return hint
 }
 
 type manifestInfo struct {
@@ -119,6 +214,19 @@ func (p *FilePackager) buildLocalCSVFileList(fileList []os.FileInfo, stagingDire
 	return csvList
 }
 
+// billingLocation returns the location of the manifest dates, along with the billing time zone when it is not UTC
+func (p *FilePackager) billingLocation() (*time.Location, string) {
+	timezone := p.KMCfg.Status.Reporting.BillingTimezone
+	if timezone == "" || timezone == "UTC" {
+		return time.UTC, ""
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC, ""
+	}
+	return loc, timezone
+}
+
 func (p *FilePackager) getManifest(archiveFiles map[int]string, filePath string) {
 	// setup the manifest
 	manifestDate := metav1.Now()
@@ -127,15 +235,22 @@ func (p *FilePackager) getManifest(archiveFiles map[int]string, filePath string)
 		uploadName := p.uid + "_openshift_usage_report." + strconv.Itoa(idx) + ".csv"
 		manifestFiles = append(manifestFiles, uploadName)
 	}
+	loc, timezone := p.billingLocation()
 	p.manifest = manifestInfo{
 		manifest: manifest{
-			UUID:      p.uid,
-			ClusterID: p.KMCfg.Status.ClusterID,
-			Version:   p.KMCfg.Status.OperatorCommit,
-			Date:      manifestDate.UTC(),
-			Files:     manifestFiles,
-			Start:     p.start.UTC(),
-			End:       p.end.UTC(),
+			UUID:            p.uid,
+			ClusterID:       p.clusterID(),
+			Version:         p.KMCfg.Status.OperatorCommit,
+			Date:            manifestDate.Add(-p.ClockSkew).In(loc),
+			Files:           manifestFiles,
+			Start:           p.start.In(loc),
+			End:             p.end.In(loc),
+			CostModel:       newCostModelHint(p.KMCfg.Status.CostModel),
+			Tenant:          p.tenant,
+			BillingTimezone: timezone,
+			DataResidency:   p.KMCfg.Status.Reporting.DataResidency,
+			SchemaVersion:   collector.ReportSchemaVersion,
+			Dimensions:      p.dimensions,
 		},
 		filename: filepath.Join(filePath, "manifest.json"),
 	}
@@ -166,14 +281,31 @@ func (p *FilePackager) addFileToTarWriter(uploadName, filePath string, tarWriter
 		return err
 	}
 
-	if _, err := io.Copy(tarWriter, file); err != nil {
+	if _, err := io.CopyBuffer(tarWriter, file, make([]byte, p.copyBufferSize())); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// writeTarball packages the files into tar balls
+// copyBufferSize returns the size, in bytes, of the buffer reports are copied into the tar.gz files with
+func (p *FilePackager) copyBufferSize() int {
+	kib := p.KMCfg.Status.Resources.CopyBufferKiB
+	if kib <= 0 {
+		kib = defaultCopyBufferKiB
+	}
+	return int(kib) * 1024
+}
+
+// compressionWorkers returns the number of tar.gz files of a payload that are compressed at a time
+func (p *FilePackager) compressionWorkers() int {
+	if p.KMCfg.Status.Resources.CompressionWorkers <= 0 {
+		return 1
+	}
+	return int(p.KMCfg.Status.Resources.CompressionWorkers)
+}
+
+// writeTarball packages the files into tar balls. The tar ball is complete, and synced to disk, when it returns.
 func (p *FilePackager) writeTarball(tarFileName, manifestFileName string, archiveFiles map[int]string) error {
 
 	// create the tarfile
@@ -184,9 +316,7 @@ func (p *FilePackager) writeTarball(tarFileName, manifestFileName string, archiv
 	defer tarFile.Close()
 
 	gzipWriter := gzip.NewWriter(tarFile)
-	defer gzipWriter.Close()
 	tw := tar.NewWriter(gzipWriter)
-	defer tw.Close()
 
 	// add the files to the tarFile
 	for idx, filePath := range archiveFiles {
@@ -201,7 +331,89 @@ func (p *FilePackager) writeTarball(tarFileName, manifestFileName string, archiv
 		return fmt.Errorf("writeTarball: failed to create tar file: %v", err)
 	}
 
-	return tarFile.Sync()
+	// the tar and gzip footers are written on close, and must be on disk before the tar ball is moved into place
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("writeTarball: failed to close tar file: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("writeTarball: failed to close gzip file: %v", err)
+	}
+	if err := tarFile.Sync(); err != nil {
+		return fmt.Errorf("writeTarball: failed to sync tar file: %v", err)
+	}
+	return tarFile.Close()
+}
+
+// ReadManifest returns the raw manifest.json contained in a packaged tar.gz file
+func ReadManifest(tarFilePath string) ([]byte, error) {
+	tarFile, err := os.Open(tarFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("ReadManifest: error opening tar file: %v", err)
+	}
+	defer tarFile.Close()
+
+	gzipReader, err := gzip.NewReader(tarFile)
+	if err != nil {
+		return nil, fmt.Errorf("ReadManifest: error reading gzip: %v", err)
+	}
+	defer gzipReader.Close()
+
+	tr := tar.NewReader(gzipReader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("ReadManifest: manifest.json not found in %s", tarFilePath)
+		} else if err != nil {
+			return nil, fmt.Errorf("ReadManifest: error reading tar file: %v", err)
+		}
+		if header.Name != "manifest.json" {
+			continue
+		}
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("ReadManifest: error reading manifest: %v", err)
+		}
+		return contents, nil
+	}
+}
+
+// ReadPayloadID returns the manifest uuid of a packaged tar.gz file
+func ReadPayloadID(tarFilePath string) (string, error) {
+	contents, err := ReadManifest(tarFilePath)
+	if err != nil {
+		return "", fmt.Errorf("ReadPayloadID: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(contents, &m); err != nil {
+		return "", fmt.Errorf("ReadPayloadID: failed to unmarshal manifest: %v", err)
+	}
+	return m.UUID, nil
+}
+
+// PayloadSummary describes a payload from its manifest
+type PayloadSummary struct {
+	PayloadID string
+	Start     time.Time
+	End       time.Time
+	Files     []string
+	SizeBytes int64
+}
+
+// ReadPayloadSummary reads the manifest of the payload tar.gz file at tarFilePath
+func ReadPayloadSummary(tarFilePath string) (PayloadSummary, error) {
+	info, err := os.Stat(tarFilePath)
+	if err != nil {
+		return PayloadSummary{}, fmt.Errorf("ReadPayloadSummary: %v", err)
+	}
+	contents, err := ReadManifest(tarFilePath)
+	if err != nil {
+		return PayloadSummary{}, fmt.Errorf("ReadPayloadSummary: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(contents, &m); err != nil {
+		return PayloadSummary{}, fmt.Errorf("ReadPayloadSummary: failed to unmarshal manifest: %v", err)
+	}
+	return PayloadSummary{PayloadID: m.UUID, Start: m.Start, End: m.End, Files: m.Files, SizeBytes: info.Size()}, nil
 }
 
 // writePart writes a portion of a split file into a new file
@@ -284,7 +496,11 @@ func (p *FilePackager) getStartEnd(filePath string) error {
 	if err != nil {
 		return fmt.Errorf("getStartEnd: error reading file: %v", err)
 	}
-	lastLine := allLines[len(allLines)-1]
+	// a report rolled up for each day may have a single row
+	lastLine := firstLine
+	if len(allLines) > 0 {
+		lastLine = allLines[len(allLines)-1]
+	}
 	endInterval := lastLine[endIndex]
 	p.end, _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", endInterval)
 	return nil
@@ -360,7 +576,13 @@ func (p *FilePackager) moveFiles() ([]os.FileInfo, error) {
 	if err != nil {
 		return nil, fmt.Errorf("moveFiles: could not read reports directory: %v", err)
 	}
-	if len(fileList) <= 0 {
+	hasReports := false
+	for _, file := range fileList {
+		if strings.HasSuffix(file.Name(), ".csv") {
+			hasReports = true
+		}
+	}
+	if !hasReports {
 		return nil, ErrNoReports
 	}
 
@@ -368,7 +590,7 @@ func (p *FilePackager) moveFiles() ([]os.FileInfo, error) {
 	if p.KMCfg.Status.Packaging.PackagingError == "" {
 		// Only clear the staging directory if previous packaging was successful
 		log.Info("clearing out staging directory")
-		if err := p.DirCfg.Staging.RemoveContents(); err != nil {
+		if err := p.clearStaging(); err != nil {
 			return nil, fmt.Errorf("moveFiles: could not clear staging: %v", err)
 		}
 	}
@@ -400,8 +622,15 @@ func (p *FilePackager) TrimPackages() error {
 		return fmt.Errorf("failed to read upload dir: %v", err)
 	}
 
+	if err := p.trimForwarded(packages); err != nil {
+		return err
+	}
+
 	datetimesSet := strset.NewSet()
 	for _, f := range packages {
+		if strings.HasPrefix(f, ForwardedPayloadPrefix) {
+			continue
+		}
 		if strings.HasSuffix(f, "tar.gz") {
 			datetimesSet.Add(strings.Split(f, "-")[0])
 		}
@@ -432,6 +661,11 @@ func (p *FilePackager) TrimPackages() error {
 				continue
 			}
 			log.Info(fmt.Sprintf("removing report: %s", file))
+			if summary, err := ReadPayloadSummary(filepath.Join(p.DirCfg.Upload.Path, file)); err != nil {
+				log.Error(err, "failed to read the manifest of the removed report", "file", file)
+			} else {
+				p.Trimmed = append(p.Trimmed, summary)
+			}
 			if err := os.Remove(filepath.Join(p.DirCfg.Upload.Path, file)); err != nil {
 				return fmt.Errorf("failed to remove %s: %v", file, err)
 			}
@@ -442,25 +676,220 @@ func (p *FilePackager) TrimPackages() error {
 	return nil
 }
 
-// PackageReports is responsible for packing report files for upload
+// trimForwarded removes the oldest payloads received from spoke clusters when there are more than max reports of
+// them, so that spokes cannot fill the storage of the hub while uploads fail
+func (p *FilePackager) trimForwarded(packages []string) error {
+	log := p.Log.WithValues("kokumetricsconfig", "trimForwarded")
+
+	type forwarded struct {
+		name    string
+		modTime time.Time
+	}
+	var payloads []forwarded
+	for _, f := range packages {
+		if !strings.HasPrefix(f, ForwardedPayloadPrefix) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(p.DirCfg.Upload.Path, f))
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %v", f, err)
+		}
+		payloads = append(payloads, forwarded{name: f, modTime: info.ModTime()})
+	}
+	excess := len(payloads) - int(p.KMCfg.Spec.Packaging.MaxReports)
+	if excess <= 0 {
+		return nil
+	}
+
+	log.Info("max report count reached: removing oldest forwarded payloads")
+	sort.SliceStable(payloads, func(i, j int) bool { return payloads[i].modTime.Before(payloads[j].modTime) })
+	for _, f := range payloads[:excess] {
+		log.Info(fmt.Sprintf("removing forwarded payload: %s", f.name))
+		path := filepath.Join(p.DirCfg.Upload.Path, f.name)
+		if summary, err := ReadPayloadSummary(path); err != nil {
+			log.Error(err, "failed to read the manifest of the removed payload", "file", f.name)
+		} else {
+			p.Trimmed = append(p.Trimmed, summary)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %v", f.name, err)
+		}
+	}
+	return nil
+}
+
+// clusterID returns the cluster id written to the manifest. The reports of each tenant are identified as a
+// separate cluster so that each tenant can have its own source.
+func (p *FilePackager) clusterID() string {
+	if p.tenant == "" {
+		return p.KMCfg.Status.ClusterID
+	}
+	return p.KMCfg.Status.ClusterID + "-" + p.tenant
+}
+
+// nestedPackagers returns a packager for each report directory nested in the reports directory: the reports of
+// each tenant, the reports of each billing period that was re-collected, and the reports rolled up for each complete day
+func (p *FilePackager) nestedPackagers() ([]*FilePackager, error) {
+	var packagers []*FilePackager
+	if !p.daily {
+		daily, err := p.dailyPackagers(time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("nestedPackagers: %v", err)
+		}
+		packagers = append(packagers, daily...)
+	}
+	for _, nested := range []string{dirconfig.TenantDir, dirconfig.RecollectDir} {
+		reportsPath := filepath.Join(p.DirCfg.Reports.Path, nested)
+		dirs, err := ioutil.ReadDir(reportsPath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("nestedPackagers: could not read %s reports directory: %v", nested, err)
+		}
+		for _, dir := range dirs {
+			if !dir.IsDir() {
+				continue
+			}
+			np := &FilePackager{
+				KMCfg: p.KMCfg,
+				DirCfg: &dirconfig.DirectoryConfig{
+					Parent:  p.DirCfg.Parent,
+					Reports: dirconfig.Directory{Path: filepath.Join(reportsPath, dir.Name())},
+					Staging: dirconfig.Directory{Path: filepath.Join(p.DirCfg.Staging.Path, nested, dir.Name())},
+					Upload:  p.DirCfg.Upload,
+				},
+				SigningKey:       p.SigningKey,
+				ClockSkew:        p.ClockSkew,
+				createdTimestamp: p.createdTimestamp,
+				maxBytes:         p.maxBytes,
+				tenant:           p.tenant,
+				recollected:      p.recollected,
+			}
+			if nested == dirconfig.TenantDir {
+				np.tenant = dir.Name()
+				np.Log = p.Log.WithValues("tenant", dir.Name())
+				np.nameSuffix = p.nameSuffix + "-tenant-" + dir.Name()
+			} else {
+				np.recollected = true
+				np.Log = p.Log.WithValues("recollected", dir.Name())
+				np.nameSuffix = p.nameSuffix + "-recollect-" + dir.Name()
+			}
+			packagers = append(packagers, np)
+		}
+	}
+	return packagers, nil
+}
+
+// packageAll packages the reports directory and the report directories nested in it. It returns true if any
+// reports were packaged.
+func (p *FilePackager) packageAll() (bool, error) {
+	err := p.packageReports()
+	if err != nil && err != ErrNoReports {
+		return false, err
+	}
+	packaged := err == nil
+
+	nested, err := p.nestedPackagers()
+	if err != nil {
+		return packaged, err
+	}
+	for _, np := range nested {
+		ok, err := np.packageAll()
+		if err != nil {
+			return packaged, fmt.Errorf("%s: %v", np.DirCfg.Reports.Path, err)
+		}
+		packaged = packaged || ok
+	}
+	return packaged, nil
+}
+
+// PackageReports is responsible for packing report files for upload. The reports of each tenant, and of each
+// re-collected billing period, are packaged into separate payloads.
 func (p *FilePackager) PackageReports() error {
-	log := p.Log.WithValues("kokumetricsconfig", "PackageReports")
 	p.maxBytes = *p.KMCfg.Status.Packaging.MaxSize * megaByte
-	p.uid = uuid.New().String()
 	p.createdTimestamp = time.Now().Format(timestampFormat)
+	log := p.Log.WithValues("kokumetricsconfig", "PackageReports")
+
+	// tar.gz files that were left partially written, for example by a restart, are never uploaded
+	if p.DirCfg.Upload.Exists() {
+		if err := p.DirCfg.Upload.RemovePartialFiles(stalePartialAge); err != nil {
+			log.Error(err, "failed to remove partially written payloads")
+		}
+	}
+
+	packaged, err := p.packageAll()
+	if err != nil {
+		return err
+	}
+	if !packaged {
+		return nil
+	}
+
+	log.Info("file packaging was successful")
+	p.KMCfg.Status.Packaging.LastSuccessfulPackagingTime = metav1.Now()
+	return nil
+}
+
+// packageReports packages the report files of a single reports directory. ErrNoReports is returned when
+// there are no reports to package.
+func (p *FilePackager) packageReports() error {
+	p.uid = uuid.New().String()
+	log := p.Log.WithValues("kokumetricsconfig", "PackageReports", logging.PayloadID, p.uid)
 
 	// create reports/staging/upload directories if they do not exist
 	if err := dirconfig.CheckExistsOrRecreate(log, p.DirCfg.Reports, p.DirCfg.Staging, p.DirCfg.Upload); err != nil {
 		return fmt.Errorf("PackageReports: could not check directory: %v", err)
 	}
 
+	// finish a packaging run that was interrupted, for example by a restart, before staging new reports
+	resumed, err := p.resumePackaging()
+	if err != nil {
+		return fmt.Errorf("PackageReports: %v", err)
+	}
+
 	// move CSV reports from data directory to staging directory
 	filesToPackage, err := p.moveFiles()
 	if err == ErrNoReports {
-		return nil
+		if resumed {
+			return nil
+		}
+		return err
 	} else if err != nil {
 		return fmt.Errorf("PackageReports: %v", err)
 	}
+	if err := p.copyToDaily(filesToPackage); err != nil {
+		return fmt.Errorf("PackageReports: %v", err)
+	}
+	// reports written by a previous version of the operator are upgraded to the current schema version, and the
+	// rows are sorted so that the same reports are always packaged into the same files
+	for i, file := range filesToPackage {
+		absPath := filepath.Join(p.DirCfg.Staging.Path, file.Name())
+		version, err := collector.UpgradeReportFile(absPath)
+		if err != nil {
+			return fmt.Errorf("PackageReports: %v", err)
+		}
+		if version != "" && version != collector.ReportSchemaVersion {
+			log.Info("upgraded report to the current schema version", "file", file.Name(), "fromVersion", version, "toVersion", collector.ReportSchemaVersion)
+		}
+		if p.rollup() {
+			if err := collector.RollupReportFile(absPath); err != nil {
+				return fmt.Errorf("PackageReports: %v", err)
+			}
+		}
+		if err := collector.SortReportFile(absPath); err != nil {
+			return fmt.Errorf("PackageReports: %v", err)
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return fmt.Errorf("PackageReports: failed to stat report: %v", err)
+		}
+		filesToPackage[i] = info
+	}
+	// in delta mode, dimension reports that have not changed are referenced instead of packaged
+	filesToPackage, err = p.applyDelta(filesToPackage)
+	if err != nil {
+		return fmt.Errorf("PackageReports: %v", err)
+	}
 	// get the start and end dates from the report
 	log.Info("getting the start and end intervals for the manifest")
 	for _, file := range filesToPackage {
@@ -478,37 +907,24 @@ func (p *FilePackager) PackageReports() error {
 		return fmt.Errorf("PackageReports: %v", err)
 	}
 	fileList := p.buildLocalCSVFileList(filesToPackage, p.DirCfg.Staging.Path)
-	p.getManifest(fileList, p.DirCfg.Staging.Path)
-	log.Info("rendering manifest", "manifest", p.manifest.filename)
-	if err := p.manifest.renderManifest(); err != nil {
+
+	// record the chunks and their checksums so that the tar.gz files can be assembled after a restart
+	state, err := p.newPackagingState(fileList, split)
+	if err != nil {
 		return fmt.Errorf("PackageReports: %v", err)
 	}
-
-	filenameBase := p.createdTimestamp + "-cost-mgmt"
-
-	if split {
-		for idx, fileName := range fileList {
-			if !strings.HasSuffix(fileName, ".csv") {
-				continue
-			}
-			fileList = map[int]string{idx: fileName}
-			tarFileName := filenameBase + "-" + strconv.Itoa(idx) + ".tar.gz"
-			tarFilePath := filepath.Join(p.DirCfg.Upload.Path, tarFileName)
-			log.Info("generating tar.gz", "tarFile", tarFilePath)
-			if err := p.writeTarball(tarFilePath, p.manifest.filename, fileList); err != nil {
-				return fmt.Errorf("PackageReports: %v", err)
-			}
-		}
-	} else {
-		tarFileName := filenameBase + ".tar.gz"
-		tarFilePath := filepath.Join(p.DirCfg.Upload.Path, tarFileName)
-		log.Info("generating tar.gz", "tarFile", tarFilePath)
-		if err := p.writeTarball(tarFilePath, p.manifest.filename, fileList); err != nil {
-			return fmt.Errorf("PackageReports: %v", err)
-		}
+	if err := state.save(); err != nil {
+		return fmt.Errorf("PackageReports: %v", err)
+	}
+	if err := p.assemble(state); err != nil {
+		return fmt.Errorf("PackageReports: %v", err)
+	}
+	if err := state.remove(); err != nil {
+		return fmt.Errorf("PackageReports: %v", err)
+	}
+	if err := p.pendDimensions(p.uid, p.dimensions); err != nil {
+		log.Error(err, "failed to record dimension reports, they will be sent in full")
 	}
 
-	log.Info("file packaging was successful")
-	p.KMCfg.Status.Packaging.LastSuccessfulPackagingTime = metav1.Now()
 	return nil
 }
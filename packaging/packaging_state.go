@@ -0,0 +1,343 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/logging"
+)
+
+// PackagingStateFile is the name of the state of an unfinished packaging run in a staging directory
+const PackagingStateFile = "packaging-state.json"
+
+// stagedChunk is a report chunk in the staging directory, along with the checksum taken when it was staged
+type stagedChunk struct {
+	Index    int    `json:"index"`
+	File     string `json:"file"`
+	Checksum string `json:"checksum"`
+}
+
+// stagedTarball is a tar.gz file in the upload directory and the chunks it is assembled from
+type stagedTarball struct {
+	Name   string        `json:"name"`
+	Chunks []stagedChunk `json:"chunks"`
+	Done   bool          `json:"done"`
+}
+
+// packagingState records a packaging run so that the assembly of its tar.gz files can be resumed, for example
+// after the operator restarts in the middle of packaging the reports of a very large cluster
+type packagingState struct {
+	path             string
+	UID              string          `json:"uid"`
+	CreatedTimestamp string          `json:"created_timestamp"`
+	Start            time.Time       `json:"start"`
+	End              time.Time       `json:"end"`
+	Tarballs         []stagedTarball `json:"tarballs"`
+	// Dimensions are the dimension reports of the payload in delta mode
+	Dimensions []dimensionTable `json:"dimensions,omitempty"`
+}
+
+// loadPackagingState reads the packaging state at path. A nil state is returned if the file does not exist.
+func loadPackagingState(path string) (*packagingState, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("loadPackagingState: failed to read state: %v", err)
+	}
+	state := &packagingState{path: path}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("loadPackagingState: failed to parse state: %v", err)
+	}
+	return state, nil
+}
+
+// save writes the state. The state is written to a temporary file and renamed so that it is never left partially written.
+func (s *packagingState) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("save: failed to marshal state: %v", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("save: failed to write state: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("save: failed to replace state: %v", err)
+	}
+	return nil
+}
+
+// remove deletes the state once every tar.gz file is assembled
+func (s *packagingState) remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove: failed to remove state: %v", err)
+	}
+	return nil
+}
+
+// archiveFiles returns the paths of the chunks of every tar.gz file, keyed by the index used in the upload name
+func (s *packagingState) archiveFiles(stagingDirectory string) map[int]string {
+	files := make(map[int]string)
+	for _, tarball := range s.Tarballs {
+		for idx, path := range tarball.archiveFiles(stagingDirectory) {
+			files[idx] = path
+		}
+	}
+	return files
+}
+
+// archiveFiles returns the paths of the chunks of the tar.gz file, keyed by the index used in the upload name
+func (t *stagedTarball) archiveFiles(stagingDirectory string) map[int]string {
+	files := make(map[int]string)
+	for _, chunk := range t.Chunks {
+		files[chunk.Index] = filepath.Join(stagingDirectory, chunk.File)
+	}
+	return files
+}
+
+// fileChecksum returns the sha256 checksum of the file at path
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// newPackagingState records the chunks in the staging directory and the tar.gz files they are assembled into. When
+// the reports were split, each chunk is packaged into its own tar.gz file.
+func (p *FilePackager) newPackagingState(fileList map[int]string, split bool) (*packagingState, error) {
+	state := &packagingState{
+		path:             filepath.Join(p.DirCfg.Staging.Path, PackagingStateFile),
+		UID:              p.uid,
+		CreatedTimestamp: p.createdTimestamp,
+		Start:            p.start,
+		End:              p.end,
+		Dimensions:       p.dimensions,
+	}
+	var indexes []int
+	for idx := range fileList {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	filenameBase := p.createdTimestamp + "-cost-mgmt" + p.nameSuffix
+	var chunks []stagedChunk
+	for _, idx := range indexes {
+		checksum, err := fileChecksum(fileList[idx])
+		if err != nil {
+			return nil, fmt.Errorf("newPackagingState: failed to checksum %s: %v", fileList[idx], err)
+		}
+		chunk := stagedChunk{Index: idx, File: filepath.Base(fileList[idx]), Checksum: checksum}
+		if split {
+			state.Tarballs = append(state.Tarballs, stagedTarball{
+				Name:   filenameBase + "-" + strconv.Itoa(idx) + ".tar.gz",
+				Chunks: []stagedChunk{chunk},
+			})
+			continue
+		}
+		chunks = append(chunks, chunk)
+	}
+	if !split {
+		state.Tarballs = []stagedTarball{{Name: filenameBase + ".tar.gz", Chunks: chunks}}
+	}
+	return state, nil
+}
+
+// verifyChunks drops the chunks of the unfinished tar.gz files that are missing or no longer match their checksum,
+// so that a corrupt chunk is not uploaded
+func (p *FilePackager) verifyChunks(state *packagingState) {
+	log := p.Log.WithValues("kokumetricsconfig", "verifyChunks")
+	for i := range state.Tarballs {
+		tarball := &state.Tarballs[i]
+		if tarball.Done {
+			continue
+		}
+		var verified []stagedChunk
+		for _, chunk := range tarball.Chunks {
+			checksum, err := fileChecksum(filepath.Join(p.DirCfg.Staging.Path, chunk.File))
+			if err != nil {
+				log.Info("dropping staged report that could not be read", "file", chunk.File, "error", err)
+				continue
+			}
+			if checksum != chunk.Checksum {
+				log.Info("dropping staged report that does not match its checksum", "file", chunk.File)
+				continue
+			}
+			verified = append(verified, chunk)
+		}
+		tarball.Chunks = verified
+	}
+}
+
+// assemble renders the manifest and writes the tar.gz files that are not done yet. The state is saved after each
+// tar.gz file so that a restart only repeats the file that was being written.
+func (p *FilePackager) assemble(state *packagingState) error {
+	log := p.Log.WithValues("kokumetricsconfig", "assemble")
+	p.getManifest(state.archiveFiles(p.DirCfg.Staging.Path), p.DirCfg.Staging.Path)
+	if err := p.signManifest(state.archiveFiles(p.DirCfg.Staging.Path)); err != nil {
+		return fmt.Errorf("assemble: %v", err)
+	}
+	log.Info("rendering manifest", "manifest", p.manifest.filename)
+	if err := p.manifest.renderManifest(); err != nil {
+		return fmt.Errorf("assemble: %v", err)
+	}
+
+	// the tar.gz files of a batch are compressed at the same time, and are then moved into place in order, so that the
+	// state records the same progress as when they are compressed one at a time
+	workers := p.compressionWorkers()
+	for start := 0; start < len(state.Tarballs); start += workers {
+		end := start + workers
+		if end > len(state.Tarballs) {
+			end = len(state.Tarballs)
+		}
+		batch := state.Tarballs[start:end]
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for i := range batch {
+			tarball := &batch[i]
+			if tarball.Done || len(tarball.Chunks) == 0 {
+				continue
+			}
+			tarFilePath := filepath.Join(p.DirCfg.Upload.Path, tarball.Name)
+			log.Info("generating tar.gz", "tarFile", tarFilePath)
+			// the tar.gz is written under a partial name, and only renamed into place once it is complete, so that an
+			// upload never reads a tar.gz that is still being written
+			wg.Add(1)
+			go func(i int, partialPath string, archiveFiles map[int]string) {
+				defer wg.Done()
+				errs[i] = p.writeTarball(partialPath, p.manifest.filename, archiveFiles)
+			}(i, tarFilePath+dirconfig.PartialSuffix, tarball.archiveFiles(p.DirCfg.Staging.Path))
+		}
+		wg.Wait()
+
+		for i := range batch {
+			tarball := &batch[i]
+			if tarball.Done {
+				log.Info("tar.gz already generated", "tarFile", tarball.Name)
+				continue
+			}
+			if len(tarball.Chunks) > 0 {
+				tarFilePath := filepath.Join(p.DirCfg.Upload.Path, tarball.Name)
+				partialPath := tarFilePath + dirconfig.PartialSuffix
+				if err := errs[i]; err != nil {
+					p.removePartials(batch[i:])
+					return fmt.Errorf("assemble: %v", err)
+				}
+				if err := os.Rename(partialPath, tarFilePath); err != nil {
+					p.removePartials(batch[i+1:])
+					return fmt.Errorf("assemble: failed to move tar.gz into place: %v", err)
+				}
+				p.recordPayloadSize(tarFilePath, tarball.archiveFiles(p.DirCfg.Staging.Path))
+			}
+			tarball.Done = true
+			if err := state.save(); err != nil {
+				p.removePartials(batch[i+1:])
+				return fmt.Errorf("assemble: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// removePartials removes the partially written tar.gz files of the tarballs that were not moved into place
+func (p *FilePackager) removePartials(tarballs []stagedTarball) {
+	for _, tarball := range tarballs {
+		if tarball.Done || len(tarball.Chunks) == 0 {
+			continue
+		}
+		os.Remove(filepath.Join(p.DirCfg.Upload.Path, tarball.Name) + dirconfig.PartialSuffix)
+	}
+}
+
+// resumePackaging finishes the packaging run recorded in the staging directory, if any. The tar.gz files keep the
+// payload id and name of the interrupted run. It returns true if a packaging run was resumed.
+func (p *FilePackager) resumePackaging() (bool, error) {
+	log := p.Log.WithValues("kokumetricsconfig", "resumePackaging")
+	path := filepath.Join(p.DirCfg.Staging.Path, PackagingStateFile)
+	state, err := loadPackagingState(path)
+	if err != nil {
+		// the staged reports cannot be matched to their tar.gz files without the state
+		log.Info("discarding unreadable packaging state", "error", err)
+		if err := os.Remove(path); err != nil {
+			return false, fmt.Errorf("resumePackaging: failed to remove state: %v", err)
+		}
+		return false, nil
+	}
+	if state == nil {
+		return false, nil
+	}
+
+	rp := *p
+	rp.uid = state.UID
+	rp.createdTimestamp = state.CreatedTimestamp
+	rp.start = state.Start
+	rp.end = state.End
+	rp.dimensions = state.Dimensions
+	log.Info("resuming interrupted packaging", logging.PayloadID, state.UID)
+	rp.verifyChunks(state)
+	if err := rp.assemble(state); err != nil {
+		return false, fmt.Errorf("resumePackaging: %v", err)
+	}
+	if err := state.remove(); err != nil {
+		return false, fmt.Errorf("resumePackaging: %v", err)
+	}
+	if err := rp.pendDimensions(state.UID, state.Dimensions); err != nil {
+		log.Error(err, "failed to record dimension reports, they will be sent in full")
+	}
+	return true, nil
+}
+
+// clearStaging removes the files of the previous packaging run from the staging directory. The staging
+// directories of the tenants and re-collected billing periods are kept so that their packaging can be resumed.
+func (p *FilePackager) clearStaging() error {
+	fileList, err := ioutil.ReadDir(p.DirCfg.Staging.Path)
+	if err != nil {
+		return fmt.Errorf("clearStaging: could not read directory: %v", err)
+	}
+	for _, file := range fileList {
+		if file.IsDir() {
+			continue
+		}
+		if err := os.Remove(filepath.Join(p.DirCfg.Staging.Path, file.Name())); err != nil {
+			return fmt.Errorf("clearStaging: could not remove file: %v", err)
+		}
+	}
+	return nil
+}
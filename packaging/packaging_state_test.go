@@ -0,0 +1,279 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+func newStatePackager(t *testing.T, chunks map[int]string) (*FilePackager, map[int]string) {
+	dir, err := ioutil.TempDir("", "packaging-state")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	p := &FilePackager{
+		KMCfg:            &kokumetricscfgv1beta1.KokuMetricsConfig{},
+		DirCfg:           genDirCfg(t, dir),
+		Log:              testLogger,
+		uid:              uuid.New().String(),
+		createdTimestamp: "20210101T000000",
+		start:            time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		end:              time.Date(2021, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	fileList := make(map[int]string)
+	for idx, contents := range chunks {
+		path := filepath.Join(p.DirCfg.Staging.Path, p.uid+"-chunk-"+string(rune('a'+idx))+".csv")
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write chunk: %v", err)
+		}
+		fileList[idx] = path
+	}
+	return p, fileList
+}
+
+func TestNewPackagingState(t *testing.T) {
+	chunks := map[int]string{0: "a,b\n1,2\n", 1: "a,b\n3,4\n"}
+	tests := []struct {
+		name  string
+		split bool
+		want  []string
+	}{
+		{
+			name:  "reports not split",
+			split: false,
+			want:  []string{"20210101T000000-cost-mgmt.tar.gz"},
+		},
+		{
+			name:  "reports split",
+			split: true,
+			want:  []string{"20210101T000000-cost-mgmt-0.tar.gz", "20210101T000000-cost-mgmt-1.tar.gz"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fileList := newStatePackager(t, chunks)
+			defer os.RemoveAll(p.DirCfg.Parent.Path)
+			state, err := p.newPackagingState(fileList, tt.split)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(state.Tarballs) != len(tt.want) {
+				t.Fatalf("got %d tarballs, want %d", len(state.Tarballs), len(tt.want))
+			}
+			for i, tarball := range state.Tarballs {
+				if tarball.Name != tt.want[i] {
+					t.Errorf("tarball %d name = %s, want %s", i, tarball.Name, tt.want[i])
+				}
+			}
+			files := state.archiveFiles(p.DirCfg.Staging.Path)
+			for idx, path := range fileList {
+				if files[idx] != path {
+					t.Errorf("chunk %d = %s, want %s", idx, files[idx], path)
+				}
+			}
+
+			if err := state.save(); err != nil {
+				t.Fatalf("failed to save state: %v", err)
+			}
+			loaded, err := loadPackagingState(filepath.Join(p.DirCfg.Staging.Path, PackagingStateFile))
+			if err != nil {
+				t.Fatalf("failed to load state: %v", err)
+			}
+			if loaded.UID != p.uid || !loaded.Start.Equal(p.start) || len(loaded.Tarballs) != len(tt.want) {
+				t.Errorf("loaded state does not match the saved state: %+v", loaded)
+			}
+		})
+	}
+}
+
+func TestAssembleCompressionWorkers(t *testing.T) {
+	chunks := map[int]string{0: "a,b\n1,2\n", 1: "a,b\n3,4\n", 2: "a,b\n5,6\n"}
+	for _, workers := range []int64{0, 1, 2, 8} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			p, fileList := newStatePackager(t, chunks)
+			defer os.RemoveAll(p.DirCfg.Parent.Path)
+			p.KMCfg.Status.Resources.CompressionWorkers = workers
+			p.KMCfg.Status.Resources.CopyBufferKiB = 4
+			state, err := p.newPackagingState(fileList, true)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := p.assemble(state); err != nil {
+				t.Fatalf("%d workers got unexpected error: %v", workers, err)
+			}
+			for _, tarball := range state.Tarballs {
+				if !tarball.Done {
+					t.Errorf("%d workers got tarball %s not done", workers, tarball.Name)
+				}
+				tarFilePath := filepath.Join(p.DirCfg.Upload.Path, tarball.Name)
+				if _, err := ReadManifest(tarFilePath); err != nil {
+					t.Errorf("%d workers got unreadable tarball %s: %v", workers, tarball.Name, err)
+				}
+				if _, err := os.Stat(tarFilePath + dirconfig.PartialSuffix); !os.IsNotExist(err) {
+					t.Errorf("%d workers got partial file left for %s", workers, tarball.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPackagingStateMissing(t *testing.T) {
+	state, err := loadPackagingState(filepath.Join(os.TempDir(), "nonexistent", PackagingStateFile))
+	if err != nil || state != nil {
+		t.Errorf("expected no state and no error, got %v and %v", state, err)
+	}
+}
+
+func TestResumePackaging(t *testing.T) {
+	tests := []struct {
+		name      string
+		corrupt   bool
+		wantFiles []string
+	}{
+		{
+			name:      "unfinished tarball is written",
+			wantFiles: []string{"20210101T000000-cost-mgmt-1.tar.gz"},
+		},
+		{
+			name:      "corrupt chunk is dropped",
+			corrupt:   true,
+			wantFiles: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fileList := newStatePackager(t, map[int]string{0: "a,b\n1,2\n", 1: "a,b\n3,4\n"})
+			defer os.RemoveAll(p.DirCfg.Parent.Path)
+			state, err := p.newPackagingState(fileList, true)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// the first tarball was written and uploaded before the restart
+			state.Tarballs[0].Done = true
+			if err := state.save(); err != nil {
+				t.Fatalf("failed to save state: %v", err)
+			}
+			if tt.corrupt {
+				if err := ioutil.WriteFile(fileList[1], []byte("a,b\n"), 0644); err != nil {
+					t.Fatalf("failed to corrupt chunk: %v", err)
+				}
+			} else {
+				// the second tarball was being written when the operator restarted
+				partial := filepath.Join(p.DirCfg.Upload.Path, state.Tarballs[1].Name+dirconfig.PartialSuffix)
+				if err := ioutil.WriteFile(partial, []byte("truncated"), 0644); err != nil {
+					t.Fatalf("failed to write partial tarball: %v", err)
+				}
+			}
+
+			resumer := &FilePackager{KMCfg: p.KMCfg, DirCfg: p.DirCfg, Log: testLogger, uid: "new", createdTimestamp: "20210102T000000"}
+			resumed, err := resumer.resumePackaging()
+			if err != nil || !resumed {
+				t.Fatalf("expected resumed packaging, got %v and %v", resumed, err)
+			}
+			if resumer.uid != "new" || resumer.createdTimestamp != "20210102T000000" {
+				t.Errorf("resuming changed the packager: uid %s, timestamp %s", resumer.uid, resumer.createdTimestamp)
+			}
+
+			files, err := p.DirCfg.Upload.GetFiles()
+			if err != nil {
+				t.Fatalf("failed to get upload files: %v", err)
+			}
+			if len(files) != len(tt.wantFiles) {
+				t.Fatalf("got upload files %v, want %v", files, tt.wantFiles)
+			}
+			for i, file := range files {
+				if file != tt.wantFiles[i] {
+					t.Errorf("got upload file %s, want %s", file, tt.wantFiles[i])
+				}
+				id, err := ReadPayloadID(filepath.Join(p.DirCfg.Upload.Path, file))
+				if err != nil {
+					t.Fatalf("failed to read payload id: %v", err)
+				}
+				if id != p.uid {
+					t.Errorf("resumed payload id = %s, want %s", id, p.uid)
+				}
+			}
+			if _, err := os.Stat(state.path); !os.IsNotExist(err) {
+				t.Errorf("expected packaging state to be removed")
+			}
+			if !tt.corrupt {
+				all, err := ioutil.ReadDir(p.DirCfg.Upload.Path)
+				if err != nil {
+					t.Fatalf("failed to read upload directory: %v", err)
+				}
+				for _, f := range all {
+					if strings.HasSuffix(f.Name(), dirconfig.PartialSuffix) {
+						t.Errorf("partial tarball %s was left in the upload directory", f.Name())
+					}
+				}
+			}
+
+			resumed, err = resumer.resumePackaging()
+			if err != nil || resumed {
+				t.Errorf("expected nothing to resume, got %v and %v", resumed, err)
+			}
+		})
+	}
+}
+
+func TestResumePackagingUnreadableState(t *testing.T) {
+	p, _ := newStatePackager(t, nil)
+	defer os.RemoveAll(p.DirCfg.Parent.Path)
+	path := filepath.Join(p.DirCfg.Staging.Path, PackagingStateFile)
+	if err := ioutil.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+	resumed, err := p.resumePackaging()
+	if err != nil || resumed {
+		t.Errorf("expected nothing to resume, got %v and %v", resumed, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected unreadable packaging state to be removed")
+	}
+}
+
+func TestClearStagingKeepsNestedDirectories(t *testing.T) {
+	p, _ := newStatePackager(t, map[int]string{0: "a,b\n"})
+	defer os.RemoveAll(p.DirCfg.Parent.Path)
+	nested := filepath.Join(p.DirCfg.Staging.Path, "tenants", "team-a")
+	if err := os.MkdirAll(nested, os.ModePerm); err != nil {
+		t.Fatalf("failed to create nested staging: %v", err)
+	}
+	if err := p.clearStaging(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, err := ioutil.ReadDir(p.DirCfg.Staging.Path)
+	if err != nil {
+		t.Fatalf("failed to read staging: %v", err)
+	}
+	if len(files) != 1 || files[0].Name() != "tenants" {
+		t.Errorf("expected only the nested staging directory, got %d entries", len(files))
+	}
+}
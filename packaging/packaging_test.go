@@ -30,6 +30,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -37,6 +38,7 @@ import (
 
 	"github.com/google/uuid"
 	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
 	"github.com/project-koku/koku-metrics-operator/dirconfig"
 	"github.com/project-koku/koku-metrics-operator/testutils"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -496,6 +498,253 @@ func TestPackagingReports(t *testing.T) {
 	}
 }
 
+func TestPackagingTenantReports(t *testing.T) {
+	dirCfg := genDirCfg(t, filepath.Join(testingDir, "tenants"))
+	tenantReports := filepath.Join(dirCfg.Reports.Path, dirconfig.TenantDir, "team-a")
+	if err := os.MkdirAll(tenantReports, os.ModePerm); err != nil {
+		t.Fatalf("failed to create tenant reports directory: %v", err)
+	}
+	for _, dir := range []string{dirCfg.Reports.Path, tenantReports} {
+		for _, file := range []string{"ocp_node_label.csv", "ocp_pod_label.csv"} {
+			if _, err := Copy(0777, filepath.Join("test_files/", file), filepath.Join(dir, file)); err != nil {
+				t.Fatalf("failed to copy %s: %v", file, err)
+			}
+		}
+	}
+	maxSize := int64(100)
+	testPackager.DirCfg = dirCfg
+	testPackager.KMCfg.Spec.Packaging.MaxReports = 10
+	testPackager.KMCfg.Status.Packaging.MaxSize = &maxSize
+	if err := testPackager.PackageReports(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	outFiles, err := dirCfg.Upload.GetFiles()
+	if err != nil {
+		t.Fatalf("failed to get upload files: %v", err)
+	}
+	var cluster, tenant int
+	for _, file := range outFiles {
+		if strings.Contains(file, "-tenant-team-a") {
+			tenant++
+		} else {
+			cluster++
+		}
+	}
+	if cluster != 1 || tenant != 1 {
+		t.Errorf("expected 1 cluster and 1 tenant payload, got %d and %d: %v", cluster, tenant, outFiles)
+	}
+	tenantStaging := filepath.Join(dirCfg.Staging.Path, dirconfig.TenantDir, "team-a")
+	manifestFile := filepath.Join(tenantStaging, "manifest.json")
+	data, err := ioutil.ReadFile(manifestFile)
+	if err != nil {
+		t.Fatalf("failed to read tenant manifest: %v", err)
+	}
+	var got manifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("failed to unmarshal tenant manifest: %v", err)
+	}
+	wantClusterID := testPackager.KMCfg.Status.ClusterID + "-team-a"
+	if got.ClusterID != wantClusterID || got.Tenant != "team-a" {
+		t.Errorf("tenant manifest cluster_id = %q, tenant = %q, want %q and %q", got.ClusterID, got.Tenant, wantClusterID, "team-a")
+	}
+}
+
+func TestPackagingRecollectedReports(t *testing.T) {
+	dirCfg := genDirCfg(t, filepath.Join(testingDir, "recollect"))
+	recollectReports := filepath.Join(dirCfg.Reports.Path, dirconfig.RecollectDir, "202012")
+	tenantReports := filepath.Join(recollectReports, dirconfig.TenantDir, "team-a")
+	if err := os.MkdirAll(tenantReports, os.ModePerm); err != nil {
+		t.Fatalf("failed to create recollected reports directory: %v", err)
+	}
+	for _, dir := range []string{recollectReports, tenantReports} {
+		for _, file := range []string{"ocp_node_label.csv", "ocp_pod_label.csv"} {
+			if _, err := Copy(0777, filepath.Join("test_files/", file), filepath.Join(dir, file)); err != nil {
+				t.Fatalf("failed to copy %s: %v", file, err)
+			}
+		}
+	}
+	maxSize := int64(100)
+	testPackager.DirCfg = dirCfg
+	testPackager.KMCfg.Spec.Packaging.MaxReports = 10
+	testPackager.KMCfg.Status.Packaging.MaxSize = &maxSize
+	testPackager.KMCfg.Status.Packaging.LastSuccessfulPackagingTime = metav1.Time{}
+	if err := testPackager.PackageReports(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if testPackager.KMCfg.Status.Packaging.LastSuccessfulPackagingTime.IsZero() {
+		t.Errorf("expected the packaging time to be set")
+	}
+
+	outFiles, err := dirCfg.Upload.GetFiles()
+	if err != nil {
+		t.Fatalf("failed to get upload files: %v", err)
+	}
+	var recollected, tenant int
+	for _, file := range outFiles {
+		switch {
+		case strings.HasSuffix(file, "-cost-mgmt-recollect-202012.tar.gz"):
+			recollected++
+		case strings.HasSuffix(file, "-cost-mgmt-recollect-202012-tenant-team-a.tar.gz"):
+			tenant++
+		default:
+			t.Errorf("unexpected payload %s", file)
+		}
+	}
+	if recollected != 1 || tenant != 1 {
+		t.Errorf("expected 1 recollected and 1 recollected tenant payload, got %d and %d: %v", recollected, tenant, outFiles)
+	}
+}
+
+func TestPackagingDailyReports(t *testing.T) {
+	report := "report_period_start,report_period_end,interval_start,interval_end,node,namespace,pod,pod_usage_cpu_core_seconds,node_capacity_cpu_cores,pod_labels\n" +
+		"2021-01-01 00:00:00 +0000 UTC,2021-02-01 00:00:00 +0000 UTC,2021-01-05 18:00:00 +0000 UTC,2021-01-05 18:59:59 +0000 UTC,node-a,ns,pod-a,10.000000,16.000000,label_app:a\n" +
+		"2021-01-01 00:00:00 +0000 UTC,2021-02-01 00:00:00 +0000 UTC,2021-01-05 19:00:00 +0000 UTC,2021-01-05 19:59:59 +0000 UTC,node-a,ns,pod-a,5.000000,16.000000,label_app:a\n"
+	cadenceTests := []struct {
+		name       string
+		cadence    kokumetricscfgv1beta1.PayloadCadence
+		wantSuffix []string
+		wantRows   int
+	}{
+		{
+			name:       "hourly",
+			cadence:    kokumetricscfgv1beta1.HourlyCadence,
+			wantSuffix: []string{"-cost-mgmt.tar.gz"},
+			wantRows:   2,
+		},
+		{
+			name:       "daily",
+			cadence:    kokumetricscfgv1beta1.DailyCadence,
+			wantSuffix: []string{"-cost-mgmt.tar.gz"},
+			wantRows:   1,
+		},
+		{
+			name:       "both",
+			cadence:    kokumetricscfgv1beta1.HourlyAndDailyCadence,
+			wantSuffix: []string{"-cost-mgmt-daily.tar.gz", "-cost-mgmt.tar.gz"},
+			wantRows:   2,
+		},
+	}
+	for _, tt := range cadenceTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirCfg := genDirCfg(t, filepath.Join(testingDir, "cadence-"+tt.name))
+			if err := ioutil.WriteFile(filepath.Join(dirCfg.Reports.Path, "ocp_pod_label.csv"), []byte(report), 0644); err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+			maxSize := int64(100)
+			testPackager.DirCfg = dirCfg
+			testPackager.KMCfg.Spec.Packaging.MaxReports = 10
+			testPackager.KMCfg.Status.Packaging.MaxSize = &maxSize
+			testPackager.KMCfg.Status.Packaging.PayloadCadence = tt.cadence
+			defer func() { testPackager.KMCfg.Status.Packaging.PayloadCadence = "" }()
+			if err := testPackager.PackageReports(); err != nil {
+				t.Fatalf("%s unexpected error: %v", tt.name, err)
+			}
+
+			outFiles, err := dirCfg.Upload.GetFiles()
+			if err != nil {
+				t.Fatalf("failed to get upload files: %v", err)
+			}
+			if len(outFiles) != len(tt.wantSuffix) {
+				t.Fatalf("%s got payloads %v want %d payloads", tt.name, outFiles, len(tt.wantSuffix))
+			}
+			sort.Strings(outFiles)
+			for i, suffix := range tt.wantSuffix {
+				if !strings.HasSuffix(outFiles[i], suffix) {
+					t.Errorf("%s got payload %s want suffix %s", tt.name, outFiles[i], suffix)
+				}
+			}
+
+			staged, err := filepath.Glob(filepath.Join(dirCfg.Staging.Path, "*ocp_pod_label*.csv"))
+			if err != nil || len(staged) != 1 {
+				t.Fatalf("%s failed to find staged report: %v %v", tt.name, staged, err)
+			}
+			data, err := ioutil.ReadFile(staged[0])
+			if err != nil {
+				t.Fatalf("failed to read staged report: %v", err)
+			}
+			if rows := strings.Count(string(data), "\n") - 1; rows != tt.wantRows {
+				t.Errorf("%s got %d rows want %d", tt.name, rows, tt.wantRows)
+			}
+			if tt.cadence == kokumetricscfgv1beta1.HourlyAndDailyCadence {
+				daily, err := filepath.Glob(filepath.Join(dirCfg.Staging.Path, dirconfig.DailyDir, "2021-01-05", "*ocp_pod_label*.csv"))
+				if err != nil || len(daily) != 1 {
+					t.Fatalf("%s failed to find daily report: %v %v", tt.name, daily, err)
+				}
+				data, err := ioutil.ReadFile(daily[0])
+				if err != nil {
+					t.Fatalf("failed to read daily report: %v", err)
+				}
+				if !strings.Contains(string(data), "2021-01-05 18:00:00 +0000 UTC,2021-01-05 19:59:59 +0000 UTC,node-a,ns,pod-a,15.000000,16.000000") {
+					t.Errorf("%s got daily report %s want a single rolled up row", tt.name, data)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyReportToDaily(t *testing.T) {
+	header := "report_period_start,report_period_end,interval_start,interval_end,pod,pod_usage_cpu_core_seconds\n"
+	row := func(hour int) string {
+		return fmt.Sprintf("2021-01-01 00:00:00 +0000 UTC,2021-02-01 00:00:00 +0000 UTC,2021-01-05 %02d:00:00 +0000 UTC,2021-01-05 %02d:59:59 +0000 UTC,pod-a,10.000000\n", hour, hour)
+	}
+	dirCfg := genDirCfg(t, filepath.Join(testingDir, "copy-to-daily"))
+	staged := filepath.Join(dirCfg.Staging.Path, "ocp_pod_label.csv")
+	dailyPath := filepath.Join(dirCfg.Reports.Path, dirconfig.DailyDir)
+	dayPath := filepath.Join(dailyPath, "2021-01-05")
+	testPackager.DirCfg = dirCfg
+
+	// an hour that is packaged again is only rolled up once
+	for _, report := range []string{header + row(18), header + row(18) + row(19)} {
+		if err := ioutil.WriteFile(staged, []byte(report), 0644); err != nil {
+			t.Fatalf("failed to write report: %v", err)
+		}
+		if err := copyReportToDaily(staged, "ocp_pod_label.csv", dailyPath); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dayPath, "ocp_pod_label.csv"))
+	if err != nil {
+		t.Fatalf("failed to read daily report: %v", err)
+	}
+	if want := header + row(18) + row(19); string(data) != want {
+		t.Errorf("got daily report %s want %s", data, want)
+	}
+
+	// the day is only packaged once its last hour was rolled up, or after the grace period
+	end := time.Date(2021, 1, 6, 0, 0, 0, 0, time.UTC)
+	for _, now := range []time.Time{end.Add(-time.Hour), end.Add(time.Hour), end.Add(dailyGracePeriod)} {
+		packagers, err := testPackager.dailyPackagers(now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := !now.Before(end.Add(dailyGracePeriod)); (len(packagers) == 1) != want {
+			t.Errorf("got %d packagers at %v want the day packaged %t", len(packagers), now, want)
+		}
+	}
+	if err := ioutil.WriteFile(staged, []byte(header+row(23)), 0644); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	if err := copyReportToDaily(staged, "ocp_pod_label.csv", dailyPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	packagers, err := testPackager.dailyPackagers(end.Add(time.Hour))
+	if err != nil || len(packagers) != 1 || packagers[0].DirCfg.Reports.Path != dayPath {
+		t.Errorf("got packagers %v and error %v want the complete day packaged", packagers, err)
+	}
+
+	// the directory of a packaged day is removed after the retention
+	if err := os.Remove(filepath.Join(dayPath, "ocp_pod_label.csv")); err != nil {
+		t.Fatalf("failed to remove daily report: %v", err)
+	}
+	if _, err := testPackager.dailyPackagers(end.Add(dailyHoursRetention + time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(dayPath); !os.IsNotExist(err) {
+		t.Errorf("got day directory %s after the retention want it removed", dayPath)
+	}
+}
+
 func TestGetAndRenderManifest(t *testing.T) {
 	// set up the tests to check the manifest contents
 	getAndRenderManifestTests := []struct {
@@ -612,6 +861,9 @@ func TestGetAndRenderManifest(t *testing.T) {
 			if foundManifest.Version != expectedManifest.Version {
 				t.Errorf(errorMsg, expectedManifest.Version, foundManifest.Version)
 			}
+			if foundManifest.SchemaVersion != collector.ReportSchemaVersion {
+				t.Errorf(errorMsg, collector.ReportSchemaVersion, foundManifest.SchemaVersion)
+			}
 			if foundManifest.Start != expectedManifest.Start {
 				t.Errorf(errorMsg, expectedManifest.Start, foundManifest.Start)
 			}
@@ -636,6 +888,108 @@ func TestGetAndRenderManifest(t *testing.T) {
 	}
 }
 
+func TestGetManifestBillingTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data is not available: %v", err)
+	}
+	start := time.Date(2021, 1, 5, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		timezone     string
+		wantLoc      *time.Location
+		wantTimezone string
+	}{
+		{name: "default", timezone: "", wantLoc: time.UTC, wantTimezone: ""},
+		{name: "utc", timezone: "UTC", wantLoc: time.UTC, wantTimezone: ""},
+		{name: "billing time zone", timezone: "Asia/Tokyo", wantLoc: tokyo, wantTimezone: "Asia/Tokyo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &FilePackager{KMCfg: &kokumetricscfgv1beta1.KokuMetricsConfig{}, start: start, end: start.Add(time.Hour)}
+			p.KMCfg.Status.Reporting.BillingTimezone = tt.timezone
+			p.getManifest(map[int]string{}, "")
+			got := p.manifest.manifest.(manifest)
+			if got.BillingTimezone != tt.wantTimezone {
+				t.Errorf("billing_timezone = %q, want %q", got.BillingTimezone, tt.wantTimezone)
+			}
+			if got.Start.Location().String() != tt.wantLoc.String() || !got.Start.Equal(start) {
+				t.Errorf("start = %v, want %v in %v", got.Start, start, tt.wantLoc)
+			}
+		})
+	}
+}
+
+func TestGetManifestDataResidency(t *testing.T) {
+	start := time.Date(2021, 1, 5, 18, 0, 0, 0, time.UTC)
+	for _, region := range []string{"", "eu"} {
+		p := &FilePackager{KMCfg: &kokumetricscfgv1beta1.KokuMetricsConfig{}, start: start, end: start.Add(time.Hour)}
+		p.KMCfg.Status.Reporting.DataResidency = region
+		p.getManifest(map[int]string{}, "")
+		if got := p.manifest.manifest.(manifest).DataResidency; got != region {
+			t.Errorf("data_residency = %q, want %q", got, region)
+		}
+	}
+}
+
+func TestGetManifestClockSkew(t *testing.T) {
+	start := time.Date(2021, 1, 5, 18, 0, 0, 0, time.UTC)
+	skew := 10 * time.Minute
+	p := &FilePackager{KMCfg: &kokumetricscfgv1beta1.KokuMetricsConfig{}, start: start, end: start.Add(time.Hour), ClockSkew: skew}
+	before := time.Now()
+	p.getManifest(map[int]string{}, "")
+	got := p.manifest.manifest.(manifest)
+	if want := start; !got.Start.Equal(want) {
+		t.Errorf("start = %v, want %v", got.Start, want)
+	}
+	if want := start.Add(time.Hour); !got.End.Equal(want) {
+		t.Errorf("end = %v, want %v", got.End, want)
+	}
+	if !got.Date.Before(before.Add(-skew + time.Second)) {
+		t.Errorf("date = %v, want about %v", got.Date, before.Add(-skew))
+	}
+}
+
+func TestNewCostModelHint(t *testing.T) {
+	newCostModelHintTests := []struct {
+		name string
+		spec *kokumetricscfgv1beta1.CostModelSpec
+		want string
+	}{
+		{name: "no cost model", spec: nil, want: "null"},
+		{
+			name: "distribution only",
+			spec: &kokumetricscfgv1beta1.CostModelSpec{Distribution: kokumetricscfgv1beta1.MemoryDistribution},
+			want: `{"distribution":"memory"}`,
+		},
+		{
+			name: "full cost model",
+			spec: &kokumetricscfgv1beta1.CostModelSpec{
+				Name:         "fleet",
+				Currency:     "EUR",
+				Distribution: kokumetricscfgv1beta1.CPUDistribution,
+				Markup:       "10.5",
+				Rates: []kokumetricscfgv1beta1.CostModelRate{
+					{Metric: "cpu_core_usage_per_hour", Value: "0.07", CostType: kokumetricscfgv1beta1.SupplementaryCost},
+				},
+			},
+			want: `{"name":"fleet","currency":"EUR","distribution":"cpu","markup":{"value":"10.5","unit":"percent"},` +
+				`"rates":[{"metric":{"name":"cpu_core_usage_per_hour"},"cost_type":"Supplementary","tiered_rates":[{"value":"0.07","unit":"EUR"}]}]}`,
+		},
+	}
+	for _, tt := range newCostModelHintTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(newCostModelHint(tt.spec))
+			if err != nil {
+				t.Fatalf("%s failed to marshal: %v", tt.name, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("%s got %s want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRenderManifest(t *testing.T) {
 	tempFile := getTempFile(t, 0644, ".")
 	tempFileNoPerm := getTempFile(t, 0000, ".")
@@ -803,6 +1157,59 @@ func TestWriteTarball(t *testing.T) {
 	}
 }
 
+func TestReadPayloadID(t *testing.T) {
+	testPackager.uid = uuid.New().String()
+	testPackager.DirCfg = genDirCfg(t, testDirs.empty.directory)
+	stagingDir := testPackager.DirCfg.Reports.Path
+	testPackager.getManifest(map[int]string{}, stagingDir)
+	if err := testPackager.manifest.renderManifest(); err != nil {
+		t.Fatal("failed to render manifest")
+	}
+	tarFileName := filepath.Join(testPackager.DirCfg.Upload.Path, "payload.tar.gz")
+	if err := testPackager.writeTarball(tarFileName, testPackager.manifest.filename, map[int]string{}); err != nil {
+		t.Fatalf("failed to write tarball: %v", err)
+	}
+	readPayloadIDTests := []struct {
+		name        string
+		tarFileName string
+		want        string
+		expectedErr bool
+	}{
+		{
+			name:        "manifest in tarball",
+			tarFileName: tarFileName,
+			want:        testPackager.uid,
+			expectedErr: false,
+		},
+		{
+			name:        "file is not a tarball",
+			tarFileName: "test_files/nonCSV.txt",
+			want:        "",
+			expectedErr: true,
+		},
+		{
+			name:        "file does not exist",
+			tarFileName: filepath.Join(testPackager.DirCfg.Upload.Path, "nonexistent.tar.gz"),
+			want:        "",
+			expectedErr: true,
+		},
+	}
+	for _, tt := range readPayloadIDTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadPayloadID(tt.tarFileName)
+			if err != nil && !tt.expectedErr {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if err == nil && tt.expectedErr {
+				t.Errorf("%s expected error but got nil", tt.name)
+			}
+			if got != tt.want {
+				t.Errorf("%s got %s want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSplitFiles(t *testing.T) {
 	tmpDir := getTempDir(t, 0777, "./test_files", "tmp-*")
 	defer os.RemoveAll(tmpDir)
@@ -975,6 +1382,16 @@ func TestTrimPackages(t *testing.T) {
 			numReportsExpected: 1,
 			want:               nil,
 		},
+		{
+			name:               "forwarded payloads are trimmed separately",
+			tmpFilePattern:     "forwarded-%d-*.tar.gz",
+			numFiles:           3,
+			maxReports:         1,
+			duplicateReports:   false,
+			numFilesExpected:   1,
+			numReportsExpected: 0,
+			want:               nil,
+		},
 	}
 
 	for _, tt := range trimPackagesTests {
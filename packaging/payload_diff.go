@@ -0,0 +1,290 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+	"github.com/project-koku/koku-metrics-operator/collector"
+)
+
+// maxDiffSamples is the number of dimensions added and removed that are listed for each report
+const maxDiffSamples = 10
+
+// PayloadDiffSide describes one of the payloads that are compared
+type PayloadDiffSide struct {
+	File      string    `json:"file"`
+	PayloadID string    `json:"payload_id"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+}
+
+// ColumnTotal is the sum of a numeric column of a report in the old and the new payload
+type ColumnTotal struct {
+	Column string  `json:"column"`
+	Old    float64 `json:"old"`
+	New    float64 `json:"new"`
+}
+
+// ReportDiff is the difference between the rows of a report in two payloads. Rows are compared by their
+// dimensions: the values of the columns that are neither numeric nor of the reporting period, such as the node,
+// namespace, and pod of the pod report. A row is added when the new payload has more rows with its dimensions than
+// the old payload, and removed when it has fewer.
+type ReportDiff struct {
+	Report  string `json:"report"`
+	OldRows int    `json:"old_rows"`
+	NewRows int    `json:"new_rows"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	// AddedDimensions and RemovedDimensions are samples of the dimensions that are only in the new, or only in the
+	// old, payload
+	AddedDimensions   []string      `json:"added_dimensions,omitempty"`
+	RemovedDimensions []string      `json:"removed_dimensions,omitempty"`
+	Totals            []ColumnTotal `json:"totals,omitempty"`
+}
+
+// PayloadDiff is the difference between the reports of two payloads
+type PayloadDiff struct {
+	Old     PayloadDiffSide `json:"old"`
+	New     PayloadDiffSide `json:"new"`
+	Reports []ReportDiff    `json:"reports"`
+}
+
+// reportRows is a function that is called with the name, columns, and each row of the reports of a payload
+type reportRows func(report string, columns, row []string)
+
+// walkPayloadReports reads the manifest of the payload tar.gz file at tarFilePath, and calls fn with each row of
+// each report in the payload. The rows of the files of a report that was split are all given to fn.
+func walkPayloadReports(tarFilePath string, fn reportRows) (PayloadDiffSide, error) {
+	contents, err := ReadManifest(tarFilePath)
+	if err != nil {
+		return PayloadDiffSide{}, fmt.Errorf("walkPayloadReports: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(contents, &m); err != nil {
+		return PayloadDiffSide{}, fmt.Errorf("walkPayloadReports: failed to unmarshal manifest: %v", err)
+	}
+	side := PayloadDiffSide{File: tarFilePath, PayloadID: m.UUID, Start: m.Start, End: m.End}
+	delimiter := ','
+	if m.CSVDelimiter == string(kokumetricscfgv1beta1.TabDelimiter) {
+		delimiter = '\t'
+	}
+
+	tarFile, err := os.Open(tarFilePath)
+	if err != nil {
+		return side, fmt.Errorf("walkPayloadReports: error opening tar file: %v", err)
+	}
+	defer tarFile.Close()
+	gzipReader, err := gzip.NewReader(tarFile)
+	if err != nil {
+		return side, fmt.Errorf("walkPayloadReports: error reading gzip: %v", err)
+	}
+	defer gzipReader.Close()
+
+	tr := tar.NewReader(gzipReader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return side, nil
+		} else if err != nil {
+			return side, fmt.Errorf("walkPayloadReports: error reading tar file: %v", err)
+		}
+		if !strings.HasSuffix(header.Name, ".csv") {
+			continue
+		}
+		reader := csv.NewReader(tr)
+		reader.Comma = delimiter
+		reader.FieldsPerRecord = -1
+		columns, err := reader.Read()
+		if err == io.EOF {
+			continue
+		} else if err != nil {
+			return side, fmt.Errorf("walkPayloadReports: failed to read the columns of %s: %v", header.Name, err)
+		}
+		report, ok := collector.ReportName(columns)
+		if !ok {
+			report = header.Name
+		}
+		for {
+			row, err := reader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return side, fmt.Errorf("walkPayloadReports: failed to read %s: %v", header.Name, err)
+			}
+			fn(report, columns, row)
+		}
+	}
+}
+
+// numericColumns tracks the columns of each report whose values are all numbers
+type numericColumns map[string]map[string]bool
+
+// add records whether the values of the row are numbers. A column is numeric until a value that is not empty
+// cannot be parsed as a number.
+func (n numericColumns) add(report string, columns, row []string) {
+	if n[report] == nil {
+		n[report] = map[string]bool{}
+	}
+	for i, column := range columns {
+		if i >= len(row) || row[i] == "" || collector.IsPeriodColumn(column) {
+			continue
+		}
+		_, err := strconv.ParseFloat(row[i], 64)
+		numeric, seen := n[report][column]
+		n[report][column] = err == nil && (numeric || !seen)
+	}
+}
+
+// reportAggregate is the number of rows of each dimension, and the sum of each numeric column, of a report
+type reportAggregate struct {
+	rows       int
+	dimensions map[string]int
+	totals     map[string]float64
+}
+
+// aggregateRows returns a reportRows that adds each row to the aggregate of its report
+func aggregateRows(aggregates map[string]*reportAggregate, numeric numericColumns) reportRows {
+	return func(report string, columns, row []string) {
+		agg := aggregates[report]
+		if agg == nil {
+			agg = &reportAggregate{dimensions: map[string]int{}, totals: map[string]float64{}}
+			aggregates[report] = agg
+		}
+		agg.rows++
+		var dimensions []string
+		for i, column := range columns {
+			value := ""
+			if i < len(row) {
+				value = row[i]
+			}
+			switch {
+			case collector.IsPeriodColumn(column):
+			case numeric[report][column]:
+				number, _ := strconv.ParseFloat(value, 64)
+				agg.totals[column] += number
+			case value != "" && !isMetadataColumn(column):
+				dimensions = append(dimensions, column+"="+value)
+			}
+		}
+		agg.dimensions[strings.Join(dimensions, ", ")]++
+	}
+}
+
+// isMetadataColumn returns true for the label and annotation columns, which are not used to tell rows apart
+func isMetadataColumn(column string) bool {
+	return strings.HasSuffix(column, "_labels") || strings.HasSuffix(column, "_annotations")
+}
+
+// diffReport compares the aggregates of a report in the old and the new payload
+func diffReport(report string, oldAgg, newAgg *reportAggregate, numeric map[string]bool) ReportDiff {
+	empty := &reportAggregate{dimensions: map[string]int{}, totals: map[string]float64{}}
+	if oldAgg == nil {
+		oldAgg = empty
+	}
+	if newAgg == nil {
+		newAgg = empty
+	}
+	diff := ReportDiff{Report: report, OldRows: oldAgg.rows, NewRows: newAgg.rows}
+	for dimensions, count := range newAgg.dimensions {
+		if count > oldAgg.dimensions[dimensions] {
+			diff.Added += count - oldAgg.dimensions[dimensions]
+		}
+		if oldAgg.dimensions[dimensions] == 0 {
+			diff.AddedDimensions = append(diff.AddedDimensions, dimensions)
+		}
+	}
+	for dimensions, count := range oldAgg.dimensions {
+		if count > newAgg.dimensions[dimensions] {
+			diff.Removed += count - newAgg.dimensions[dimensions]
+		}
+		if newAgg.dimensions[dimensions] == 0 {
+			diff.RemovedDimensions = append(diff.RemovedDimensions, dimensions)
+		}
+	}
+	diff.AddedDimensions = sampleDimensions(diff.AddedDimensions)
+	diff.RemovedDimensions = sampleDimensions(diff.RemovedDimensions)
+
+	var columns []string
+	for column, isNumeric := range numeric {
+		if isNumeric {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		diff.Totals = append(diff.Totals, ColumnTotal{Column: column, Old: oldAgg.totals[column], New: newAgg.totals[column]})
+	}
+	return diff
+}
+
+// sampleDimensions returns the first dimensions in order
+func sampleDimensions(dimensions []string) []string {
+	sort.Strings(dimensions)
+	if len(dimensions) > maxDiffSamples {
+		return dimensions[:maxDiffSamples]
+	}
+	return dimensions
+}
+
+// DiffPayloads compares the reports of the payload tar.gz files at oldPath and newPath, such as the payloads of two
+// days, and returns the rows added and removed, and the change of the totals of the numeric columns, of each report.
+// Each payload is read twice, to find the numeric columns before the rows are compared, so that the payloads are not
+// kept in memory.
+func DiffPayloads(oldPath, newPath string) (PayloadDiff, error) {
+	numeric := numericColumns{}
+	for _, path := range []string{oldPath, newPath} {
+		if _, err := walkPayloadReports(path, numeric.add); err != nil {
+			return PayloadDiff{}, fmt.Errorf("DiffPayloads: %v", err)
+		}
+	}
+
+	oldAggregates := map[string]*reportAggregate{}
+	newAggregates := map[string]*reportAggregate{}
+	diff := PayloadDiff{}
+	var err error
+	if diff.Old, err = walkPayloadReports(oldPath, aggregateRows(oldAggregates, numeric)); err != nil {
+		return PayloadDiff{}, fmt.Errorf("DiffPayloads: %v", err)
+	}
+	if diff.New, err = walkPayloadReports(newPath, aggregateRows(newAggregates, numeric)); err != nil {
+		return PayloadDiff{}, fmt.Errorf("DiffPayloads: %v", err)
+	}
+
+	reports := make([]string, 0, len(numeric))
+	for report := range numeric {
+		reports = append(reports, report)
+	}
+	sort.Strings(reports)
+	for _, report := range reports {
+		diff.Reports = append(diff.Reports, diffReport(report, oldAggregates[report], newAggregates[report], numeric[report]))
+	}
+	return diff, nil
+}
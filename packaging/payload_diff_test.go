@@ -0,0 +1,105 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+const customReportHeader = "report_period_start,report_period_end,interval_start,interval_end,namespace,pod,metric_name,metric_value\n"
+
+func customReportRows(interval string, rows ...string) string {
+	var b strings.Builder
+	for _, row := range rows {
+		b.WriteString("2021-01-01 00:00:00 +0000 UTC,2021-02-01 00:00:00 +0000 UTC," + interval + "," + interval + "," + row + "\n")
+	}
+	return b.String()
+}
+
+func TestDiffPayloads(t *testing.T) {
+	dir := getTempDir(t, 0777, "./test_files", "tmp-*")
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)
+	oldPath := filepath.Join(dir, "old.tar.gz")
+	writeTestPayload(t, oldPath, &manifest{UUID: "uid-1", Start: start, End: start.Add(24 * time.Hour)}, map[string]string{
+		"uid-1_openshift_usage_report.0.csv": customReportHeader + customReportRows("2021-01-05 00:00:00 +0000 UTC",
+			"ns1,pod-a,requests,10",
+			"ns1,pod-b,requests,20",
+			"ns2,pod-c,requests,30"),
+		// the rows of a report that was split into two files are compared together
+		"uid-1_openshift_usage_report.1.csv": customReportHeader + customReportRows("2021-01-05 01:00:00 +0000 UTC",
+			"ns1,pod-a,requests,10",
+			"ns2,pod-c,requests,30"),
+		"uid-1_openshift_usage_report.2.csv": "name,size\nfile-a,10\n",
+	})
+
+	newPath := filepath.Join(dir, "new.tar.gz")
+	tab := string(kokumetricscfgv1beta1.TabDelimiter)
+	newStart := start.Add(24 * time.Hour)
+	writeTestPayload(t, newPath, &manifest{UUID: "uid-2", Start: newStart, End: newStart.Add(24 * time.Hour), CSVDelimiter: tab}, map[string]string{
+		"uid-2_openshift_usage_report.0.csv": strings.Replace(customReportHeader+customReportRows("2021-01-06 00:00:00 +0000 UTC",
+			"ns1,pod-a,requests,5",
+			"ns3,pod-d,requests,1"), ",", "\t", -1),
+	})
+
+	got, err := DiffPayloads(oldPath, newPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantOld := PayloadDiffSide{File: oldPath, PayloadID: "uid-1", Start: start, End: start.Add(24 * time.Hour)}
+	if !reflect.DeepEqual(got.Old, wantOld) || got.New.PayloadID != "uid-2" {
+		t.Errorf("got payloads %+v and %+v want %+v and uid-2", got.Old, got.New, wantOld)
+	}
+
+	want := []ReportDiff{
+		{
+			Report:            "custom",
+			OldRows:           5,
+			NewRows:           2,
+			Added:             1,
+			Removed:           4,
+			AddedDimensions:   []string{"namespace=ns3, pod=pod-d, metric_name=requests"},
+			RemovedDimensions: []string{"namespace=ns1, pod=pod-b, metric_name=requests", "namespace=ns2, pod=pod-c, metric_name=requests"},
+			Totals:            []ColumnTotal{{Column: "metric_value", Old: 100, New: 6}},
+		},
+		{
+			Report:            "uid-1_openshift_usage_report.2.csv",
+			OldRows:           1,
+			Removed:           1,
+			RemovedDimensions: []string{"name=file-a"},
+			Totals:            []ColumnTotal{{Column: "size", Old: 10}},
+		},
+	}
+	if !reflect.DeepEqual(got.Reports, want) {
+		t.Errorf("got reports\n%+v\nwant\n%+v", got.Reports, want)
+	}
+
+	if _, err := DiffPayloads(oldPath, filepath.Join(dir, "missing.tar.gz")); err == nil {
+		t.Errorf("expected an error when a payload does not exist")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+// payloadHistorySize is the number of payloads whose sizes are kept in the status
+const payloadHistorySize = 10
+
+// reportTypeRegex matches the report type in the name of a report file
+var reportTypeRegex = regexp.MustCompile(`openshift-([a-z0-9-]+?)-usage-`)
+
+var (
+	payloadReportBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_payload_report_bytes",
+			Help: "Uncompressed size in bytes of each report in the last packaged payload.",
+		},
+		[]string{"report"},
+	)
+
+	payloadCompressedBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "koku_metrics_payload_compressed_bytes",
+			Help: "Compressed size in bytes of the last packaged payload.",
+		},
+	)
+
+	packagedBytesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "koku_metrics_packaged_bytes_total",
+			Help: "Total size in bytes of the packaged payloads, before (raw) and after (compressed) compression.",
+		},
+		[]string{"size"},
+	)
+)
+
+func init() {
+	// register the packaging metrics with the controller-runtime registry so they are served on the metrics endpoint
+	metrics.Registry.MustRegister(payloadReportBytes, payloadCompressedBytes, packagedBytesTotal)
+}
+
+// reportType returns the type of the report file, such as pod or node
+func reportType(name string) string {
+	if match := reportTypeRegex.FindStringSubmatch(filepath.Base(name)); match != nil {
+		return match[1]
+	}
+	return "other"
+}
+
+// recordPayloadSize adds the raw and compressed sizes of a packaged tar.gz file to the payload history in the
+// status and to the packaging metrics. Only the most recent payloads are kept in the history.
+func (p *FilePackager) recordPayloadSize(tarFilePath string, archiveFiles map[int]string) {
+	tarInfo, err := os.Stat(tarFilePath)
+	if err != nil {
+		p.Log.Info("failed to get payload size", "tarFile", tarFilePath, "error", err)
+		return
+	}
+	size := kokumetricscfgv1beta1.PayloadSize{
+		File:            filepath.Base(tarFilePath),
+		PackagedTime:    metav1.Now(),
+		CompressedBytes: tarInfo.Size(),
+		ReportBytes:     map[string]int64{},
+	}
+	for _, path := range archiveFiles {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		size.RawBytes += info.Size()
+		size.ReportBytes[reportType(path)] += info.Size()
+	}
+
+	payloadReportBytes.Reset()
+	for report, bytes := range size.ReportBytes {
+		payloadReportBytes.WithLabelValues(report).Set(float64(bytes))
+	}
+	payloadCompressedBytes.Set(float64(size.CompressedBytes))
+	packagedBytesTotal.WithLabelValues("raw").Add(float64(size.RawBytes))
+	packagedBytesTotal.WithLabelValues("compressed").Add(float64(size.CompressedBytes))
+
+	history := append(p.KMCfg.Status.Packaging.PayloadHistory, size)
+	if len(history) > payloadHistorySize {
+		history = history[len(history)-payloadHistorySize:]
+	}
+	p.KMCfg.Status.Packaging.PayloadHistory = history
+}
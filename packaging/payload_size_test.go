@@ -0,0 +1,96 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	kokumetricscfgv1beta1 "github.com/project-koku/koku-metrics-operator/api/v1beta1"
+)
+
+func TestReportType(t *testing.T) {
+	tests := map[string]string{
+		"/staging/uid-cm-openshift-pod-usage-202101.csv":                  "pod",
+		"/staging/uid-cm-openshift-namespace-usage-202101.csv":            "namespace",
+		"/staging/uid-cm-openshift-storage-usage-202101_0.csv":            "storage",
+		"/staging/uid-cm-openshift-node-capacity-change-usage-202101.csv": "node-capacity-change",
+		"/staging/manifest.json":                                          "other",
+	}
+	for path, want := range tests {
+		if got := reportType(path); got != want {
+			t.Errorf("reportType(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestRecordPayloadSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "payload-size")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[int]string{}
+	for i, name := range []string{"uid-cm-openshift-pod-usage-202101.csv", "uid-cm-openshift-node-usage-202101.csv"} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, make([]byte, 100*(i+1)), 0644); err != nil {
+			t.Fatalf("failed to write report: %v", err)
+		}
+		files[i] = path
+	}
+	p := &FilePackager{KMCfg: &kokumetricscfgv1beta1.KokuMetricsConfig{}, Log: testLogger}
+	for i := 0; i < payloadHistorySize+2; i++ {
+		tarFile := filepath.Join(dir, fmt.Sprintf("payload-%d.tar.gz", i))
+		if err := ioutil.WriteFile(tarFile, make([]byte, 30), 0644); err != nil {
+			t.Fatalf("failed to write payload: %v", err)
+		}
+		p.recordPayloadSize(tarFile, files)
+	}
+
+	history := p.KMCfg.Status.Packaging.PayloadHistory
+	if len(history) != payloadHistorySize {
+		t.Fatalf("got %d payloads in the history, want %d", len(history), payloadHistorySize)
+	}
+	if history[0].File != "payload-2.tar.gz" || history[len(history)-1].File != fmt.Sprintf("payload-%d.tar.gz", payloadHistorySize+1) {
+		t.Errorf("expected the most recent payloads, oldest first, got %s to %s", history[0].File, history[len(history)-1].File)
+	}
+	last := history[len(history)-1]
+	if last.RawBytes != 300 || last.CompressedBytes != 30 || last.ReportBytes["pod"] != 100 || last.ReportBytes["node"] != 200 {
+		t.Errorf("unexpected payload size %+v", last)
+	}
+	if got := testutil.ToFloat64(payloadReportBytes.WithLabelValues("node")); got != 200 {
+		t.Errorf("got node report bytes metric %v, want 200", got)
+	}
+	if got := testutil.ToFloat64(payloadCompressedBytes); got != 30 {
+		t.Errorf("got compressed bytes metric %v, want 30", got)
+	}
+
+	// a payload that cannot be read is not recorded
+	p.recordPayloadSize(filepath.Join(dir, "nonexistent.tar.gz"), files)
+	if len(p.KMCfg.Status.Packaging.PayloadHistory) != payloadHistorySize || p.KMCfg.Status.Packaging.PayloadHistory[payloadHistorySize-1].File != last.File {
+		t.Errorf("expected a missing payload not to be recorded")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PostProcessHookEnvVar is the environment variable of the operator with the path of the executable that is run for
+	// each payload before it is uploaded
+	PostProcessHookEnvVar = "POST_PROCESS_HOOK"
+	// PostProcessTimeoutEnvVar is the environment variable of the operator with the number of seconds the post-processing
+	// hook may run for each payload
+	PostProcessTimeoutEnvVar = "POST_PROCESS_TIMEOUT"
+	// DefaultPostProcessTimeout is the time the post-processing hook may run for each payload when no timeout is set
+	DefaultPostProcessTimeout = 300 * time.Second
+)
+
+// maxPostProcessOutput is the number of bytes of the post-processing output kept in errors
+const maxPostProcessOutput = 1024
+
+// PostProcessHook is an executable that is run for each payload before it is uploaded, for example to sign, copy, or
+// scan the payload. It runs in the operator container with the service account of the operator, so it is set on the
+// operator deployment and not in the KokuMetricsConfig.
+type PostProcessHook struct {
+	// Path is the path of the executable
+	Path string
+	// Timeout is the time the hook may run for each payload
+	Timeout time.Duration
+}
+
+// PostProcessHookFromEnv returns the post-processing hook set in the environment of the operator, or nil if no hook is set
+func PostProcessHookFromEnv() (*PostProcessHook, error) {
+	path := os.Getenv(PostProcessHookEnvVar)
+	if path == "" {
+		return nil, nil
+	}
+	hook := &PostProcessHook{Path: path, Timeout: DefaultPostProcessTimeout}
+	if timeout := os.Getenv(PostProcessTimeoutEnvVar); timeout != "" {
+		seconds, err := strconv.Atoi(timeout)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("PostProcessHookFromEnv: %s must be a positive number of seconds, got %q", PostProcessTimeoutEnvVar, timeout)
+		}
+		hook.Timeout = time.Duration(seconds) * time.Second
+	}
+	return hook, nil
+}
+
+// tailBuffer keeps the last max bytes written to it, so that a hook that writes a lot of output does not use up the
+// memory of the operator
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.max:]...)
+	}
+	return len(p), nil
+}
+
+// Run runs the hook with the path of the payload as its argument. An error with the end of the output of the hook is
+// returned if the hook fails, does not finish within its timeout, or removes the payload.
+func (h *PostProcessHook) Run(tarFilePath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.Path, tarFilePath)
+	output := &tailBuffer{max: maxPostProcessOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", h.Timeout)
+	}
+	if err == nil {
+		if _, err = os.Stat(tarFilePath); err != nil {
+			err = fmt.Errorf("payload is missing after post-processing: %v", err)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	if out := strings.TrimSpace(string(output.buf)); out != "" {
+		err = fmt.Errorf("%v: %s", err, out)
+	}
+	return fmt.Errorf("Run: %s: %v", h.Path, err)
+}
@@ -0,0 +1,126 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeHook writes an executable shell script that runs script
+func writeHook(t *testing.T, dir, script string) string {
+	path := filepath.Join(dir, "hook.sh")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write hook: %v", err)
+	}
+	return path
+}
+
+func TestPostProcessHookRun(t *testing.T) {
+	postProcessTests := []struct {
+		name     string
+		script   string
+		timeout  time.Duration
+		wantErr  string
+		wantKept bool
+		wantCopy bool
+	}{
+		{name: "hook succeeds", script: `cp "$1" "$COPY_DIR"`, wantKept: true, wantCopy: true},
+		{name: "hook fails", script: `echo infected; exit 3`, wantErr: "exit status 3: infected", wantKept: true},
+		{name: "hook times out", script: `exec sleep 5`, timeout: time.Second, wantErr: "timed out after 1s", wantKept: true},
+		{name: "hook removes the payload", script: `rm "$1"`, wantErr: "payload is missing after post-processing"},
+		{name: "output is capped", script: `head -c 100000 /dev/zero | tr '\0' x; exit 1`, wantErr: strings.Repeat("x", maxPostProcessOutput), wantKept: true},
+	}
+	for _, tt := range postProcessTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "post-process")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			copyDir := filepath.Join(dir, "copies")
+			if err := os.MkdirAll(copyDir, os.ModePerm); err != nil {
+				t.Fatalf("failed to create copy dir: %v", err)
+			}
+			os.Setenv("COPY_DIR", copyDir)
+			defer os.Unsetenv("COPY_DIR")
+			tarFile := filepath.Join(dir, "payload.tar.gz")
+			if err := ioutil.WriteFile(tarFile, []byte("payload"), 0644); err != nil {
+				t.Fatalf("failed to write payload: %v", err)
+			}
+
+			hook := &PostProcessHook{Path: writeHook(t, dir, tt.script), Timeout: DefaultPostProcessTimeout}
+			if tt.timeout > 0 {
+				hook.Timeout = tt.timeout
+			}
+			err = hook.Run(tarFile)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("%s got error %.200v want %.200s", tt.name, err, tt.wantErr)
+			}
+			if err != nil && len(err.Error()) > maxPostProcessOutput+len(hook.Path)+100 {
+				t.Errorf("%s got an error of %d bytes want the output capped", tt.name, len(err.Error()))
+			}
+			if _, err := os.Stat(tarFile); (err == nil) != tt.wantKept {
+				t.Errorf("%s got payload kept %t want %t", tt.name, err == nil, tt.wantKept)
+			}
+			copies, _ := ioutil.ReadDir(copyDir)
+			if (len(copies) == 1) != tt.wantCopy {
+				t.Errorf("%s got %d copies, want copy %t", tt.name, len(copies), tt.wantCopy)
+			}
+		})
+	}
+}
+
+func TestPostProcessHookFromEnv(t *testing.T) {
+	envTests := []struct {
+		name    string
+		hook    string
+		timeout string
+		want    *PostProcessHook
+		wantErr bool
+	}{
+		{name: "no hook"},
+		{name: "default timeout", hook: "/hooks/scan", want: &PostProcessHook{Path: "/hooks/scan", Timeout: DefaultPostProcessTimeout}},
+		{name: "timeout", hook: "/hooks/scan", timeout: "30", want: &PostProcessHook{Path: "/hooks/scan", Timeout: 30 * time.Second}},
+		{name: "invalid timeout", hook: "/hooks/scan", timeout: "-1", wantErr: true},
+	}
+	defer os.Unsetenv(PostProcessHookEnvVar)
+	defer os.Unsetenv(PostProcessTimeoutEnvVar)
+	for _, tt := range envTests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(PostProcessHookEnvVar, tt.hook)
+			os.Setenv(PostProcessTimeoutEnvVar, tt.timeout)
+			got, err := PostProcessHookFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("%s got %+v want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// SignatureAlgorithm is the algorithm of the payload signatures in the manifest
+const SignatureAlgorithm = "ed25519"
+
+// payloadSignature is the signature of the reports of a payload. The signed message is built by signatureMessage
+// from the payload uuid, the cluster id, and the checksum of each report.
+type payloadSignature struct {
+	Algorithm string `json:"algorithm"`
+	// PublicKeyFingerprint identifies the key that verifies the signature
+	PublicKeyFingerprint string `json:"public_key_fingerprint"`
+	// Checksums are the hex encoded SHA-256 checksums of the reports, by file name
+	Checksums map[string]string `json:"checksums"`
+	// Value is the base64 encoded signature
+	Value string `json:"value"`
+}
+
+// ParseSigningKey parses a PEM encoded PKCS #8 Ed25519 private key, such as one generated by
+// `openssl genpkey -algorithm ed25519`
+func ParseSigningKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("ParseSigningKey: key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("ParseSigningKey: failed to parse key: %v", err)
+	}
+	signingKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("ParseSigningKey: key is a %T, not an Ed25519 private key", key)
+	}
+	return signingKey, nil
+}
+
+// KeyFingerprint returns the hex encoded SHA-256 checksum of the PKIX encoding of the public key, the same as
+// `openssl pkey -pubout -outform DER | sha256sum`
+func KeyFingerprint(key ed25519.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return "", fmt.Errorf("KeyFingerprint: failed to marshal public key: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// signatureMessage returns the message that is signed: the payload uuid and the cluster id, followed by the name and
+// checksum of each report in name order, each on its own line
+func signatureMessage(uuid, clusterID string, checksums map[string]string) []byte {
+	names := make([]string, 0, len(checksums))
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "%s\n%s\n", uuid, clusterID)
+	for _, name := range names {
+		fmt.Fprintf(&msg, "%s %s\n", name, checksums[name])
+	}
+	return msg.Bytes()
+}
+
+// signManifest adds the signature of the archive files to the manifest when a signing key is set
+func (p *FilePackager) signManifest(archiveFiles map[int]string) error {
+	if p.SigningKey == nil {
+		return nil
+	}
+	m, ok := p.manifest.manifest.(manifest)
+	if !ok {
+		return fmt.Errorf("signManifest: unexpected manifest type %T", p.manifest.manifest)
+	}
+	fingerprint, err := KeyFingerprint(p.SigningKey)
+	if err != nil {
+		return fmt.Errorf("signManifest: %v", err)
+	}
+	checksums := make(map[string]string, len(archiveFiles))
+	for idx, path := range archiveFiles {
+		checksum, err := fileChecksum(path)
+		if err != nil {
+			return fmt.Errorf("signManifest: failed to checksum %s: %v", path, err)
+		}
+		checksums[p.uid+"_openshift_usage_report."+strconv.Itoa(idx)+".csv"] = checksum
+	}
+	m.Signature = &payloadSignature{
+		Algorithm:            SignatureAlgorithm,
+		PublicKeyFingerprint: fingerprint,
+		Checksums:            checksums,
+		Value:                base64.StdEncoding.EncodeToString(ed25519.Sign(p.SigningKey, signatureMessage(m.UUID, m.ClusterID, checksums))),
+	}
+	p.manifest.manifest = m
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func pemKey(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestParseSigningKey(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	parseTests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{name: "ed25519 key", data: pemKey(t, edKey)},
+		{name: "ecdsa key", data: pemKey(t, ecKey), wantErr: true},
+		{name: "not PEM", data: []byte("not a key"), wantErr: true},
+	}
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSigningKey(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s got error %v want error %t", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, edKey) {
+				t.Errorf("%s got a different key", tt.name)
+			}
+		})
+	}
+}
+
+func TestSignManifest(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	p, fileList := newStatePackager(t, map[int]string{0: "a,b\n1,2\n", 1: "a,b\n3,4\n"})
+	defer os.RemoveAll(p.DirCfg.Parent.Path)
+	p.SigningKey = key
+	state, err := p.newPackagingState(fileList, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.assemble(state); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	raw, err := ReadManifest(filepath.Join(p.DirCfg.Upload.Path, state.Tarballs[0].Name))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		t.Fatalf("failed to unmarshal manifest: %v", err)
+	}
+	if m.Signature == nil {
+		t.Fatal("manifest is not signed")
+	}
+	fingerprint, _ := KeyFingerprint(key)
+	if m.Signature.Algorithm != SignatureAlgorithm || m.Signature.PublicKeyFingerprint != fingerprint {
+		t.Errorf("got signature %+v", m.Signature)
+	}
+	if len(m.Signature.Checksums) != len(m.Files) {
+		t.Fatalf("got checksums %v for files %v", m.Signature.Checksums, m.Files)
+	}
+	for idx, path := range fileList {
+		want, _ := fileChecksum(path)
+		name := p.uid + "_openshift_usage_report." + string(rune('0'+idx)) + ".csv"
+		if got := m.Signature.Checksums[name]; got != want {
+			t.Errorf("got checksum %s for %s want %s", got, name, want)
+		}
+	}
+	sig, err := base64.StdEncoding.DecodeString(m.Signature.Value)
+	if err != nil {
+		t.Fatalf("failed to decode signature: %v", err)
+	}
+	if !ed25519.Verify(pub, signatureMessage(m.UUID, m.ClusterID, m.Signature.Checksums), sig) {
+		t.Error("signature does not verify")
+	}
+	m.Signature.Checksums[m.Files[0]] = "tampered"
+	if ed25519.Verify(pub, signatureMessage(m.UUID, m.ClusterID, m.Signature.Checksums), sig) {
+		t.Error("signature verifies tampered checksums")
+	}
+}
@@ -0,0 +1,172 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UploadIndexFile is the name of the index of uploaded payloads on the report volume
+const UploadIndexFile = "upload-index.json"
+
+// uploadRetention is how long uploaded payloads are kept in the index
+const uploadRetention = 93 * 24 * time.Hour
+
+// UploadEntry records a payload that was accepted by every destination
+type UploadEntry struct {
+	PayloadID  string    `json:"payload_id"`
+	File       string    `json:"file"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	// Endpoint is the ingress URL that received the payload
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// UploadIndex records the identities of uploaded payloads so that a payload that is packaged again from the same
+// reports, for example after the operator restarts, is not uploaded twice
+type UploadIndex struct {
+	path     string
+	Payloads map[string]UploadEntry `json:"payloads"`
+}
+
+// LoadUploadIndex reads the upload index at path. An empty index is returned if the file does not exist.
+func LoadUploadIndex(path string) (*UploadIndex, error) {
+	index := &UploadIndex{path: path, Payloads: map[string]UploadEntry{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return index, fmt.Errorf("LoadUploadIndex: failed to read index: %v", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return &UploadIndex{path: path, Payloads: map[string]UploadEntry{}}, fmt.Errorf("LoadUploadIndex: failed to parse index: %v", err)
+	}
+	if index.Payloads == nil {
+		index.Payloads = map[string]UploadEntry{}
+	}
+	return index, nil
+}
+
+// Get returns the entry of the payload with the identity
+func (u *UploadIndex) Get(identity string) (UploadEntry, bool) {
+	entry, ok := u.Payloads[identity]
+	return entry, ok
+}
+
+// Record adds the payload to the index. Payloads uploaded more than the retention before the entry are removed.
+func (u *UploadIndex) Record(identity string, entry UploadEntry) {
+	u.Payloads[identity] = entry
+	cutoff := entry.UploadedAt.Add(-uploadRetention)
+	for key, e := range u.Payloads {
+		if e.UploadedAt.Before(cutoff) {
+			delete(u.Payloads, key)
+		}
+	}
+}
+
+// Save writes the index. The index is written to a temporary file and renamed so that it is never left partially written.
+func (u *UploadIndex) Save() error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("Save: failed to marshal index: %v", err)
+	}
+	tmp := u.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("Save: failed to write index: %v", err)
+	}
+	if err := os.Rename(tmp, u.path); err != nil {
+		return fmt.Errorf("Save: failed to replace index: %v", err)
+	}
+	return nil
+}
+
+// IdempotencyKey returns the key that identifies the uploads of a payload identity. A payload that is packaged again
+// from the same reports has the same key, so that ingress can deduplicate its uploads and the attempts can be linked.
+func IdempotencyKey(identity string) string {
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ReadPayloadIdentity returns the identity of a packaged tar.gz file. The identity is made of the cluster id and
+// the report window from the manifest, and a hash of the contents of the reports. The manifest uuid, the packaging
+// date, and the file names are not part of the identity, so the same reports packaged twice have the same identity.
+func ReadPayloadIdentity(tarFilePath string) (string, error) {
+	tarFile, err := os.Open(tarFilePath)
+	if err != nil {
+		return "", fmt.Errorf("ReadPayloadIdentity: error opening tar file: %v", err)
+	}
+	defer tarFile.Close()
+
+	gzipReader, err := gzip.NewReader(tarFile)
+	if err != nil {
+		return "", fmt.Errorf("ReadPayloadIdentity: error reading gzip: %v", err)
+	}
+	defer gzipReader.Close()
+
+	var m *manifest
+	var hashes []string
+	tr := tar.NewReader(gzipReader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("ReadPayloadIdentity: error reading tar file: %v", err)
+		}
+		if header.Name == "manifest.json" {
+			contents, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return "", fmt.Errorf("ReadPayloadIdentity: error reading manifest: %v", err)
+			}
+			m = &manifest{}
+			if err := json.Unmarshal(contents, m); err != nil {
+				return "", fmt.Errorf("ReadPayloadIdentity: failed to unmarshal manifest: %v", err)
+			}
+			continue
+		}
+		hash := sha256.New()
+		if _, err := io.Copy(hash, tr); err != nil {
+			return "", fmt.Errorf("ReadPayloadIdentity: error reading %s: %v", header.Name, err)
+		}
+		hashes = append(hashes, hex.EncodeToString(hash.Sum(nil)))
+	}
+	if m == nil {
+		return "", fmt.Errorf("ReadPayloadIdentity: manifest.json not found in %s", tarFilePath)
+	}
+
+	sort.Strings(hashes)
+	content := sha256.Sum256([]byte(strings.Join(hashes, "\n")))
+	return fmt.Sprintf("%s/%s/%s/%s",
+		m.ClusterID,
+		m.Start.UTC().Format(time.RFC3339),
+		m.End.UTC().Format(time.RFC3339),
+		hex.EncodeToString(content[:]),
+	), nil
+}
@@ -0,0 +1,154 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package packaging
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestPayload(t *testing.T, path string, m *manifest, files map[string]string) {
+	tarFile, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create tar file: %v", err)
+	}
+	defer tarFile.Close()
+	gw := gzip.NewWriter(tarFile)
+	defer gw.Close()
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+
+	if m != nil {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("failed to marshal manifest: %v", err)
+		}
+		files["manifest.json"] = string(data)
+	}
+	for name, contents := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(contents)), Mode: 0644}); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+}
+
+func TestReadPayloadIdentity(t *testing.T) {
+	dir := getTempDir(t, 0777, "./test_files", "tmp-*")
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC)
+	newManifest := func(uid, clusterID string, start time.Time) *manifest {
+		return &manifest{UUID: uid, ClusterID: clusterID, Date: time.Now(), Start: start, End: start.Add(time.Hour)}
+	}
+	payloads := map[string]struct {
+		manifest *manifest
+		files    map[string]string
+	}{
+		"original":          {newManifest("uid-1", "cluster", start), map[string]string{"uid-1_0.csv": "a,b", "uid-1_1.csv": "c,d"}},
+		"repackaged":        {newManifest("uid-2", "cluster", start), map[string]string{"uid-2_0.csv": "c,d", "uid-2_1.csv": "a,b"}},
+		"different content": {newManifest("uid-3", "cluster", start), map[string]string{"uid-3_0.csv": "a,b", "uid-3_1.csv": "c,e"}},
+		"different window":  {newManifest("uid-4", "cluster", start.Add(time.Hour)), map[string]string{"uid-4_0.csv": "a,b", "uid-4_1.csv": "c,d"}},
+		"different cluster": {newManifest("uid-5", "other", start), map[string]string{"uid-5_0.csv": "a,b", "uid-5_1.csv": "c,d"}},
+		"no manifest":       {nil, map[string]string{"0.csv": "a,b"}},
+	}
+	identities := map[string]string{}
+	for name, payload := range payloads {
+		path := filepath.Join(dir, name+".tar.gz")
+		writeTestPayload(t, path, payload.manifest, payload.files)
+		identity, err := ReadPayloadIdentity(path)
+		if name == "no manifest" {
+			if err == nil {
+				t.Errorf("%s expected error but got nil", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s got unexpected error: %v", name, err)
+		}
+		identities[name] = identity
+	}
+
+	if identities["original"] != identities["repackaged"] {
+		t.Errorf("repackaged payload has a different identity: %s != %s", identities["original"], identities["repackaged"])
+	}
+	for _, name := range []string{"different content", "different window", "different cluster"} {
+		if identities[name] == identities["original"] {
+			t.Errorf("%s payload has the same identity as the original", name)
+		}
+	}
+
+	if _, err := ReadPayloadIdentity(filepath.Join(dir, "nonexistent.tar.gz")); err == nil {
+		t.Errorf("expected error for missing file but got nil")
+	}
+
+	key := IdempotencyKey(identities["original"])
+	if len(key) != 32 || key != IdempotencyKey(identities["repackaged"]) {
+		t.Errorf("got idempotency key %q for the original and %q for the repackaged payload", key, IdempotencyKey(identities["repackaged"]))
+	}
+	if key == IdempotencyKey(identities["different content"]) {
+		t.Errorf("different content payload has the same idempotency key as the original")
+	}
+}
+
+func TestUploadIndex(t *testing.T) {
+	dir := getTempDir(t, 0777, "./test_files", "tmp-*")
+	defer os.RemoveAll(dir)
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := ioutil.WriteFile(corrupt, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("failed to write corrupt index: %v", err)
+	}
+	if index, err := LoadUploadIndex(corrupt); err == nil || len(index.Payloads) != 0 {
+		t.Errorf("corrupt index: got error %v and %d payloads, want error and empty index", err, len(index.Payloads))
+	}
+
+	path := filepath.Join(dir, UploadIndexFile)
+	index, err := LoadUploadIndex(path)
+	if err != nil {
+		t.Fatalf("failed to load missing index: %v", err)
+	}
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	index.Record("old", UploadEntry{PayloadID: "uid-old", UploadedAt: now.Add(-uploadRetention - time.Hour)})
+	index.Record("new", UploadEntry{PayloadID: "uid-new", File: "payload.tar.gz", UploadedAt: now})
+	if _, ok := index.Get("old"); ok {
+		t.Errorf("payload older than the retention was not removed")
+	}
+	if err := index.Save(); err != nil {
+		t.Fatalf("failed to save index: %v", err)
+	}
+
+	loaded, err := LoadUploadIndex(path)
+	if err != nil {
+		t.Fatalf("failed to load saved index: %v", err)
+	}
+	entry, ok := loaded.Get("new")
+	if !ok || entry.PayloadID != "uid-new" || entry.File != "payload.tar.gz" {
+		t.Errorf("got %#v, want payload uid-new", entry)
+	}
+}
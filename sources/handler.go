@@ -60,6 +60,15 @@ const (
 
 	// ApplicationsEndpoint The endpoint for associating a source with an application.
 	ApplicationsEndpoint string = "applications"
+
+	// AccountsManagementAPIPath The path of the OpenShift Cluster Manager accounts management API.
+	AccountsManagementAPIPath string = "/api/accounts_mgmt/v1/"
+
+	// SubscriptionsEndpoint The endpoint for retrieving cluster subscriptions.
+	SubscriptionsEndpoint string = "subscriptions"
+
+	// SearchQueryParam The keyword for searching subscriptions via query parameter.
+	SearchQueryParam string = "search"
 )
 
 // GenericMeta A data structure for the meta data in a paginated response
@@ -93,6 +102,18 @@ type SourceResponse struct {
 	Data []SourceItem
 }
 
+// SubscriptionItem A data structure for the cluster subscription item
+type SubscriptionItem struct {
+	DisplayName       string `json:"display_name"`
+	ExternalClusterID string `json:"external_cluster_id"`
+}
+
+// SubscriptionResponse A data structure for the paginated subscription response
+type SubscriptionResponse struct {
+	Total int
+	Items []SubscriptionItem
+}
+
 // ApplicationTypeDataItem A data structure for the application type item
 type ApplicationTypeDataItem struct {
 	ID   string
@@ -269,6 +290,38 @@ func CheckSourceExists(sSpec *SourceSpec, client crhchttp.HTTPClient, sourceType
 	return &data.Data[0], nil
 }
 
+// GetClusterDisplayName Request the display name of the cluster from OpenShift Cluster Manager
+func GetClusterDisplayName(sSpec *SourceSpec, client crhchttp.HTTPClient) (string, error) {
+	log := sSpec.Log.WithValues("kokumetricsconfig", "GetClusterDisplayName")
+	request := &sourceGetReq{
+		client:   client,
+		root:     sSpec.APIURL + AccountsManagementAPIPath,
+		endpoint: SubscriptionsEndpoint,
+		queries:  map[string]string{SearchQueryParam: fmt.Sprintf("external_cluster_id='%s'", sSpec.Auth.ClusterID)},
+		errKey:   "cluster display name lookup",
+	}
+
+	// Get the cluster subscription
+	// https://cloud.redhat.com/api/accounts_mgmt/v1/subscriptions?search=external_cluster_id%3D%27eb93b259-1369-4f90-88ce-e68c6ba879a9%27
+	bodyBytes, err := request.getRequest(sSpec)
+	if err != nil {
+		return "", err
+	}
+
+	var data SubscriptionResponse
+	err = json.Unmarshal(bodyBytes, &data)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse the cluster subscription response: %v.", err)
+	}
+
+	if data.Total != 1 || len(data.Items) != 1 {
+		log.Info("cluster subscription does not exist")
+		return "", nil
+	}
+
+	return data.Items[0].DisplayName, nil
+}
+
 // GetApplicationTypeID Request the application type ID for Cost Management
 func GetApplicationTypeID(sSpec *SourceSpec, client crhchttp.HTTPClient) (string, error) {
 	log := sSpec.Log.WithValues("kokumetricsconfig", "GetApplicationTypeID")
@@ -443,3 +496,36 @@ func SourceGetOrCreate(sSpec *SourceSpec, client crhchttp.HTTPClient) (bool, met
 
 	return true, metav1.Now(), nil
 }
+
+// Client is the part of the sources API that the operator uses
+type Client interface {
+	GetSources(sSpec *SourceSpec) ([]byte, error)
+	SourceGetOrCreate(sSpec *SourceSpec) (bool, metav1.Time, error)
+	GetClusterDisplayName(sSpec *SourceSpec) (string, error)
+	GetReportStatus(sSpec *SourceSpec, path string, limit int) ([]ReportStatusItem, error)
+}
+
+// APIClient is the Client that sends requests to the sources API with the client returned by crhchttp.GetClient
+type APIClient struct{}
+
+var _ Client = APIClient{}
+
+// GetSources returns the sources that match the source spec
+func (APIClient) GetSources(sSpec *SourceSpec) ([]byte, error) {
+	return GetSources(sSpec, crhchttp.GetClient(sSpec.Auth, crhchttp.SourcesEndpoint))
+}
+
+// SourceGetOrCreate checks if the source exists, and creates it if specified
+func (APIClient) SourceGetOrCreate(sSpec *SourceSpec) (bool, metav1.Time, error) {
+	return SourceGetOrCreate(sSpec, crhchttp.GetClient(sSpec.Auth, crhchttp.SourcesEndpoint))
+}
+
+// GetClusterDisplayName returns the display name of the cluster in OpenShift Cluster Manager
+func (APIClient) GetClusterDisplayName(sSpec *SourceSpec) (string, error) {
+	return GetClusterDisplayName(sSpec, crhchttp.GetClient(sSpec.Auth, crhchttp.SourcesEndpoint))
+}
+
+// GetReportStatus returns the processing status of the most recent payloads of the cluster
+func (APIClient) GetReportStatus(sSpec *SourceSpec, path string, limit int) ([]ReportStatusItem, error) {
+	return GetReportStatus(sSpec, crhchttp.GetClient(sSpec.Auth, crhchttp.SourcesEndpoint), path, limit)
+}
@@ -402,6 +402,77 @@ func TestCheckSourceExists(t *testing.T) {
 	}
 }
 
+func TestGetClusterDisplayName(t *testing.T) {
+	expectedURL := "https://ci.cloud.redhat.com/api/accounts_mgmt/v1/subscriptions?search=external_cluster_id%3D%27post-cluster-id%27"
+	getClusterDisplayNameTests := []struct {
+		name        string
+		response    *http.Response
+		responseErr error
+		expected    string
+		expectedErr error
+	}{
+		{
+			name: "successful response with data",
+			response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("{\"total\":1,\"items\":[{\"display_name\":\"my-cluster\",\"external_cluster_id\":\"post-cluster-id\"}]}")), // type is io.ReadCloser,
+				Request:    &http.Request{Method: "GET", URL: &url.URL{}},
+			},
+			responseErr: nil,
+			expected:    "my-cluster",
+			expectedErr: nil,
+		},
+		{
+			name:        "request failure",
+			response:    &http.Response{},
+			responseErr: errSources,
+			expected:    "",
+			expectedErr: errSources,
+		},
+		{
+			name: "parse error", // response body is bad json
+			response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("{\"total\":1,\"items\":[{\"display_name:\"my-cluster\"}]}")), // type is io.ReadCloser,
+				Request:    &http.Request{Method: "GET", URL: &url.URL{}},
+			},
+			responseErr: nil,
+			expected:    "",
+			expectedErr: errSources,
+		},
+		{
+			name: "no subscription for the cluster",
+			response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("{\"total\":0,\"items\":[]}")), // type is io.ReadCloser,
+				Request:    &http.Request{Method: "GET", URL: &url.URL{}},
+			},
+			responseErr: nil,
+			expected:    "",
+			expectedErr: nil,
+		},
+	}
+	for _, tt := range getClusterDisplayNameTests {
+		t.Run(tt.name, func(t *testing.T) {
+			clt := &MockClient{res: tt.response, err: tt.responseErr}
+			got, err := GetClusterDisplayName(sSpec, clt)
+			if tt.expectedErr != nil && err == nil {
+				t.Errorf("%s expected error, got: %v", tt.name, err)
+			}
+			if tt.expectedErr == nil && err != nil {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if got != tt.expected {
+				t.Errorf("%s got %s want %s", tt.name, got, tt.expected)
+			}
+			// check that the request query is correctly constructed
+			if got := clt.req.URL.String(); got != expectedURL {
+				t.Errorf("%s\n\tgot:\n\t\t%+v\n\twant:\n\t\t%s", tt.name, got, expectedURL)
+			}
+		})
+	}
+}
+
 func TestGetApplicationTypeID(t *testing.T) {
 	expectedURL := "https://ci.cloud.redhat.com/api/sources/v1.0/application_types?filter[name]=/insights/platform/cost-management"
 	getApplicationTypeIDTests := []struct {
@@ -0,0 +1,87 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+)
+
+const (
+	// ClusterIDFilterQueryParam The keyword for filtering reports by cluster ID via query parameter.
+	ClusterIDFilterQueryParam string = "filter[cluster_id]"
+
+	// LimitQueryParam The keyword for limiting the number of items of a paginated response via query parameter.
+	LimitQueryParam string = "limit"
+)
+
+// The processing states of an uploaded payload in the report status API. Payloads in any other state, such as
+// pending or processing, have not been processed yet.
+const (
+	ReportStateProcessed string = "processed"
+	ReportStateFailed    string = "failed"
+)
+
+// ReportStatusItem A data structure for the processing status of an uploaded payload
+type ReportStatusItem struct {
+	// AssemblyID is the UUID of the payload manifest
+	AssemblyID        string `json:"assembly_id"`
+	State             string `json:"state"`
+	CompletedDatetime string `json:"completed_datetime"`
+}
+
+// ReportStatusResponse A data structure for the paginated report status response
+type ReportStatusResponse struct {
+	Meta GenericMeta
+	Data []ReportStatusItem
+}
+
+// GetReportStatus Request the processing status of the most recent payloads of the cluster from the cost management
+// report status API at path
+func GetReportStatus(sSpec *SourceSpec, client crhchttp.HTTPClient, path string, limit int) ([]ReportStatusItem, error) {
+	request := &sourceGetReq{
+		client: client,
+		root:   sSpec.APIURL + path,
+		queries: map[string]string{
+			ClusterIDFilterQueryParam: sSpec.Auth.ClusterID,
+			LimitQueryParam:           strconv.Itoa(limit),
+		},
+		errKey: "payload processing status lookup",
+	}
+
+	// https://cloud.redhat.com/api/cost-management/v1/ingress/reports/?filter[cluster_id]=eb93b259-1369-4f90-88ce-e68c6ba879a9&limit=50
+	bodyBytes, err := request.getRequest(sSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	var data ReportStatusResponse
+	if err := json.Unmarshal(bodyBytes, &data); err != nil {
+		return nil, fmt.Errorf("Failed to parse the report status response: %v.", err)
+	}
+	for i := range data.Data {
+		data.Data[i].State = strings.ToLower(data.Data[i].State)
+	}
+	return data.Data, nil
+}
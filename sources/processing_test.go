@@ -0,0 +1,89 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package sources
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetReportStatus(t *testing.T) {
+	expectedURL := "https://ci.cloud.redhat.com/api/cost-management/v1/ingress/reports/?filter%5Bcluster_id%5D=post-cluster-id&limit=50"
+	getReportStatusTests := []struct {
+		name        string
+		response    *http.Response
+		responseErr error
+		expected    []ReportStatusItem
+		expectedErr error
+	}{
+		{
+			name: "successful response with data",
+			response: &http.Response{
+				StatusCode: 200,
+				Body: ioutil.NopCloser(strings.NewReader(`{"meta":{"count":2},"data":[` +
+					`{"assembly_id":"payload-1","state":"Processed","completed_datetime":"2021-01-02T10:00:00Z"},` +
+					`{"assembly_id":"payload-2","state":"pending"}]}`)),
+				Request: &http.Request{Method: "GET", URL: &url.URL{}},
+			},
+			expected: []ReportStatusItem{
+				{AssemblyID: "payload-1", State: ReportStateProcessed, CompletedDatetime: "2021-01-02T10:00:00Z"},
+				{AssemblyID: "payload-2", State: "pending"},
+			},
+		},
+		{
+			name:        "request failure",
+			response:    &http.Response{},
+			responseErr: errSources,
+			expectedErr: errSources,
+		},
+		{
+			name: "parse error",
+			response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"meta":{"count":1},"data":[{"assembly_id:"payload-1"}]}`)),
+				Request:    &http.Request{Method: "GET", URL: &url.URL{}},
+			},
+			expectedErr: errSources,
+		},
+	}
+	for _, tt := range getReportStatusTests {
+		t.Run(tt.name, func(t *testing.T) {
+			clt := &MockClient{res: tt.response, err: tt.responseErr}
+			got, err := GetReportStatus(sSpec, clt, "/api/cost-management/v1/ingress/reports/", 50)
+			if tt.expectedErr != nil && err == nil {
+				t.Errorf("%s expected error, got: %v", tt.name, err)
+			}
+			if tt.expectedErr == nil && err != nil {
+				t.Errorf("%s got unexpected error: %v", tt.name, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("%s got %+v want %+v", tt.name, got, tt.expected)
+			}
+			// check that the request query is correctly constructed
+			if got := clt.req.URL.String(); got != expectedURL {
+				t.Errorf("%s\n\tgot:\n\t\t%+v\n\twant:\n\t\t%s", tt.name, got, expectedURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package fakes provides fake implementations of the services that the operator talks to, so that tests and
+// downstream builds can run the reconciler without a live cluster or cloud.redhat.com.
+package fakes
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"github.com/project-koku/koku-metrics-operator/collector"
+)
+
+var _ collector.PrometheusConnection = &PrometheusConnection{}
+
+// PrometheusConnection is a collector.PrometheusConnection that returns canned results
+type PrometheusConnection struct {
+	// Results are the values returned for each query. Range queries without a result return an empty matrix,
+	// and instant queries an empty vector.
+	Results map[string]model.Value
+	// Err, when set, is returned by every query
+	Err error
+
+	mu      sync.Mutex
+	queries []string
+}
+
+func (p *PrometheusConnection) result(query string, empty model.Value) (model.Value, promv1.Warnings, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queries = append(p.queries, query)
+	if p.Err != nil {
+		return nil, nil, p.Err
+	}
+	if value, ok := p.Results[query]; ok {
+		return value, nil, nil
+	}
+	return empty, nil, nil
+}
+
+// QueryRange returns the result of the query
+func (p *PrometheusConnection) QueryRange(ctx context.Context, query string, r promv1.Range) (model.Value, promv1.Warnings, error) {
+	return p.result(query, model.Matrix{})
+}
+
+// Query returns the result of the query
+func (p *PrometheusConnection) Query(ctx context.Context, query string, ts time.Time) (model.Value, promv1.Warnings, error) {
+	return p.result(query, model.Vector{})
+}
+
+// Queries returns the queries that were made, in order
+func (p *PrometheusConnection) Queries() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.queries...)
+}
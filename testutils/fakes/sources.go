@@ -0,0 +1,93 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package fakes
+
+import (
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/project-koku/koku-metrics-operator/sources"
+)
+
+var _ sources.Client = &SourcesClient{}
+
+// SourcesClient is a sources.Client that returns canned responses
+type SourcesClient struct {
+	// Sources is the response body returned by GetSources
+	Sources []byte
+	// SourceDefined is returned by SourceGetOrCreate
+	SourceDefined bool
+	// DisplayName is returned by GetClusterDisplayName
+	DisplayName string
+	// ReportStatus is returned by GetReportStatus
+	ReportStatus []sources.ReportStatusItem
+	// Err, when set, is returned by every request
+	Err error
+
+	mu       sync.Mutex
+	requests []sources.SourceSpec
+}
+
+func (s *SourcesClient) record(sSpec *sources.SourceSpec) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, *sSpec)
+}
+
+// GetSources returns Sources
+func (s *SourcesClient) GetSources(sSpec *sources.SourceSpec) ([]byte, error) {
+	s.record(sSpec)
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return s.Sources, nil
+}
+
+// SourceGetOrCreate returns SourceDefined
+func (s *SourcesClient) SourceGetOrCreate(sSpec *sources.SourceSpec) (bool, metav1.Time, error) {
+	s.record(sSpec)
+	return s.SourceDefined && s.Err == nil, metav1.Now(), s.Err
+}
+
+// GetClusterDisplayName returns DisplayName
+func (s *SourcesClient) GetClusterDisplayName(sSpec *sources.SourceSpec) (string, error) {
+	s.record(sSpec)
+	if s.Err != nil {
+		return "", s.Err
+	}
+	return s.DisplayName, nil
+}
+
+// GetReportStatus returns ReportStatus
+func (s *SourcesClient) GetReportStatus(sSpec *sources.SourceSpec, path string, limit int) ([]sources.ReportStatusItem, error) {
+	s.record(sSpec)
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return s.ReportStatus, nil
+}
+
+// Requests returns the source specs of the requests that were made, in order
+func (s *SourcesClient) Requests() []sources.SourceSpec {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sources.SourceSpec{}, s.requests...)
+}
@@ -0,0 +1,92 @@
+/*
+
+
+Copyright 2020 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package fakes
+
+import (
+	"bytes"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+)
+
+var _ crhchttp.Uploader = &Uploader{}
+
+// Upload is a request received by Uploader
+type Upload struct {
+	ClusterID   string
+	ContentType string
+	Method      string
+	URI         string
+	Body        []byte
+}
+
+// Uploader is a crhchttp.Uploader that records uploads instead of sending them
+type Uploader struct {
+	// Status is the http status returned for uploads and ingress checks. When empty, "202 Accepted" is returned.
+	Status string
+	// Err, when set, is returned by every request
+	Err error
+	// RequestID is the request ID returned for uploads
+	RequestID string
+
+	mu      sync.Mutex
+	uploads []Upload
+}
+
+func (u *Uploader) status() string {
+	if u.Status == "" {
+		return "202 Accepted"
+	}
+	return u.Status
+}
+
+// Upload records the upload
+func (u *Uploader) Upload(authConfig *crhchttp.AuthConfig, contentType, method, uri string, body *bytes.Buffer) (crhchttp.UploadResponse, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.uploads = append(u.uploads, Upload{
+		ClusterID:   authConfig.ClusterID,
+		ContentType: contentType,
+		Method:      method,
+		URI:         uri,
+		Body:        append([]byte{}, body.Bytes()...),
+	})
+	if u.Err != nil {
+		return crhchttp.UploadResponse{Time: metav1.Now()}, u.Err
+	}
+	return crhchttp.UploadResponse{Status: u.status(), Time: metav1.Now(), RequestID: u.RequestID}, nil
+}
+
+// CheckIngress returns Status
+func (u *Uploader) CheckIngress(authConfig *crhchttp.AuthConfig, uri string) (string, error) {
+	if u.Err != nil {
+		return "", u.Err
+	}
+	return u.status(), nil
+}
+
+// Uploads returns the uploads that were made, in order
+func (u *Uploader) Uploads() []Upload {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]Upload{}, u.uploads...)
+}
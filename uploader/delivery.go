@@ -0,0 +1,83 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package uploader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+)
+
+// delivery records the destinations that accepted a payload, so that a destination that failed is tried again without
+// exporting the payload again to the destinations that accepted it, and whether the payload was post-processed
+type delivery struct {
+	// Delivered are the names of the destinations that accepted the payload
+	Delivered []string `json:"delivered"`
+	// PostProcessed is set once the post-processing hook succeeded for the payload, so that it is not run again
+	PostProcessed bool `json:"post_processed,omitempty"`
+}
+
+// deliveryPath returns the path of the delivery state of the payload at payloadPath
+func deliveryPath(payloadPath string) string {
+	return payloadPath + dirconfig.DeliverySuffix
+}
+
+func loadDelivery(path string) (*delivery, error) {
+	d := &delivery{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return d, nil
+	} else if err != nil {
+		return d, fmt.Errorf("loadDelivery: failed to read delivery state: %v", err)
+	}
+	if err := json.Unmarshal(data, d); err != nil {
+		return &delivery{}, fmt.Errorf("loadDelivery: failed to parse delivery state: %v", err)
+	}
+	return d, nil
+}
+
+// delivered returns true if the destination accepted the payload
+func (d *delivery) delivered(name string) bool {
+	for _, delivered := range d.Delivered {
+		if delivered == name {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the delivery state to a temporary file and renames it so that it is never left partially written
+func (d *delivery) save(path string) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("save: failed to marshal delivery state: %v", err)
+	}
+	tmp := path + dirconfig.PartialSuffix
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("save: failed to write delivery state: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("save: failed to replace delivery state: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,619 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package uploader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/logging"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+)
+
+// StateFile is the name of the upload queue state on the report volume
+const StateFile = "upload-queue.json"
+
+// backfillAge is the amount of time after which a payload waiting in the upload directory is uploaded as backfill
+const backfillAge = 24 * time.Hour
+
+// recollectMarker is in the names of payloads packaged from re-collected reports
+const recollectMarker = "-recollect-"
+
+// Priority is the order payloads are uploaded in
+type Priority int
+
+const (
+	// Critical payloads contain the most recent reports. They are uploaded first, newest first.
+	Critical Priority = iota
+
+	// Backfill payloads are re-collected payloads and payloads that have waited for more than a day. They are
+	// uploaded after the critical payloads, oldest first, up to the backfill limit of the batch.
+	Backfill
+)
+
+// DefaultQueue is the queue uploaded by the Worker. Batches are submitted by the reconciler on the upload cycle.
+var DefaultQueue = &Queue{Log: ctrl.Log.WithName("uploader").WithName("Queue")}
+
+// Batch is a request to upload the payloads in a directory
+type Batch struct {
+	// UploadDir is the directory of the payloads
+	UploadDir string
+	// StateDir is the directory the queue state and the upload index are kept in
+	StateDir string
+	// Exporters are the destinations every payload is exported to
+	Exporters []exporter.Exporter
+	// NotBefore is the time the upload starts
+	NotBefore time.Time
+	// Interval is the minimum amount of time between two payload uploads
+	Interval time.Duration
+	// MaxBackfill is the maximum number of backfill payloads uploaded in the batch
+	MaxBackfill int
+	// QuarantineDir is the directory payloads are moved to once ingress has rejected them MaxRejections times
+	QuarantineDir string
+	// MaxRejections is the number of 4xx rejections after which a payload is quarantined. 0 disables the quarantine.
+	MaxRejections int
+	// IgnoreIndex uploads the payloads even if the upload index records them as uploaded, for example when they are replayed
+	IgnoreIndex bool
+	Log         logr.Logger
+}
+
+// Result is the outcome of exporting a payload to a destination
+type Result struct {
+	Exporter exporter.Exporter
+	Err      error
+	// File is the name of the payload file
+	File string
+	// Payload is the description of the payload from its manifest
+	Payload packaging.PayloadSummary
+	// IdempotencyKey is the key that the payload was uploaded with
+	IdempotencyKey string
+	// Status, RequestID, and UploadTime are the http status, the request ID, and the time of the ingress upload. They
+	// are taken from the exporter as soon as the upload returns, since the exporter is shared by the payloads of a batch.
+	Status     string
+	RequestID  string
+	UploadTime metav1.Time
+}
+
+// NewResult returns the result of exporting a payload with exp
+func NewResult(exp exporter.Exporter, err error) Result {
+	result := Result{Exporter: exp, Err: err}
+	if ingress, ok := exp.(*exporter.Ingress); ok {
+		result.Status = ingress.LastStatus
+		result.RequestID = ingress.LastRequestID
+		result.UploadTime = ingress.LastUploadTime
+	}
+	return result
+}
+
+// Stats are the state of the queue
+type Stats struct {
+	Critical       int
+	Backfill       int
+	InProgress     bool
+	LastBatchStart time.Time
+	LastBatchEnd   time.Time
+	// PostProcessError is the error of the last payload that the post-processing hook failed for. It is cleared when the
+	// hook succeeds.
+	PostProcessError string
+}
+
+// Queue holds the batch waiting to be uploaded and the results of the uploads. Uploads run in the Worker so
+// that the reconciler is not blocked while payloads are sent.
+type Queue struct {
+	Log logr.Logger
+	// PostProcess, when set, is run for each payload before it is uploaded. Payloads that it fails for are kept and
+	// post-processed again on the next batch.
+	PostProcess *packaging.PostProcessHook
+
+	mu          sync.Mutex
+	batch       *Batch
+	wake        chan struct{}
+	results     []Result
+	quarantined []string
+	stats       Stats
+}
+
+// state is the upload queue state kept on the report volume
+type state struct {
+	// InProgress is set while a batch is uploaded, so that an interrupted batch is resumed after a restart
+	InProgress bool `json:"in_progress"`
+	// Attempts are the number of failed uploads of each payload
+	Attempts map[string]int `json:"attempts,omitempty"`
+	// Rejections are the number of uploads of each payload that ingress rejected with a 4xx status
+	Rejections map[string]int `json:"rejections,omitempty"`
+}
+
+type payload struct {
+	name     string
+	path     string
+	priority Priority
+	modTime  time.Time
+}
+
+func (q *Queue) wakeChan() chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.wake == nil {
+		q.wake = make(chan struct{}, 1)
+	}
+	return q.wake
+}
+
+// Submit queues the batch. A batch that has not started is replaced.
+func (q *Queue) Submit(b Batch) {
+	wake := q.wakeChan()
+	q.mu.Lock()
+	q.batch = &b
+	q.mu.Unlock()
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Cancel removes the batch that has not started
+func (q *Queue) Cancel() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.batch = nil
+}
+
+// Busy returns true if a batch is waiting or being uploaded
+func (q *Queue) Busy() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.batch != nil || q.stats.InProgress
+}
+
+// Results returns the results of the uploads since the last call
+func (q *Queue) Results() []Result {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	results := q.results
+	q.results = nil
+	return results
+}
+
+// Quarantined returns the names of the payloads quarantined since the last call
+func (q *Queue) Quarantined() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	quarantined := q.quarantined
+	q.quarantined = nil
+	return quarantined
+}
+
+// Stats returns the state of the queue
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stats
+}
+
+// Interrupted returns true if the last batch did not finish, for example because the operator restarted
+func Interrupted(stateDir string) bool {
+	s, _ := loadState(filepath.Join(stateDir, StateFile))
+	return s.InProgress
+}
+
+func loadState(path string) (*state, error) {
+	s := &state{Attempts: map[string]int{}, Rejections: map[string]int{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return s, fmt.Errorf("loadState: failed to read state: %v", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return &state{Attempts: map[string]int{}, Rejections: map[string]int{}}, fmt.Errorf("loadState: failed to parse state: %v", err)
+	}
+	if s.Attempts == nil {
+		s.Attempts = map[string]int{}
+	}
+	if s.Rejections == nil {
+		s.Rejections = map[string]int{}
+	}
+	return s, nil
+}
+
+// save writes the state to a temporary file and renames it so that it is never left partially written
+func (s *state) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("save: failed to marshal state: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("save: failed to write state: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("save: failed to replace state: %v", err)
+	}
+	return nil
+}
+
+// pending returns the payloads in dir in upload order, and the number of critical and backfill payloads
+func pending(dir string, maxBackfill int, now time.Time) ([]payload, int, int, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("pending: failed to read upload directory: %v", err)
+	}
+	var critical, backfill []payload
+	for _, file := range files {
+		if file.IsDir() || !strings.Contains(file.Name(), "tar.gz") ||
+			strings.HasSuffix(file.Name(), dirconfig.PartialSuffix) || strings.HasSuffix(file.Name(), dirconfig.DeliverySuffix) {
+			continue
+		}
+		p := payload{name: file.Name(), path: filepath.Join(dir, file.Name()), modTime: file.ModTime()}
+		if strings.Contains(p.name, recollectMarker) || now.Sub(p.modTime) > backfillAge {
+			p.priority = Backfill
+			backfill = append(backfill, p)
+			continue
+		}
+		critical = append(critical, p)
+	}
+	sort.SliceStable(critical, func(i, j int) bool { return critical[i].modTime.After(critical[j].modTime) })
+	sort.SliceStable(backfill, func(i, j int) bool { return backfill[i].modTime.Before(backfill[j].modTime) })
+
+	ordered := critical
+	if maxBackfill > 0 && len(backfill) > maxBackfill {
+		ordered = append(ordered, backfill[:maxBackfill]...)
+	} else {
+		ordered = append(ordered, backfill...)
+	}
+	return ordered, len(critical), len(backfill), nil
+}
+
+func (q *Queue) take() *Batch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b := q.batch
+	q.batch = nil
+	if b != nil {
+		q.stats.InProgress = true
+	}
+	return b
+}
+
+func (q *Queue) finish() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stats.InProgress = false
+}
+
+func (q *Queue) record(result Result) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.results = append(q.results, result)
+}
+
+// postProcessed records the outcome of the post-processing hook
+func (q *Queue) postProcessed(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stats.PostProcessError = ""
+	if err != nil {
+		q.stats.PostProcessError = err.Error()
+	}
+}
+
+func (q *Queue) quarantine(name string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.quarantined = append(q.quarantined, name)
+}
+
+// quarantinePayload moves the payload to the quarantine directory, where it is not uploaded again
+func quarantinePayload(p payload, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("quarantinePayload: failed to create quarantine directory: %v", err)
+	}
+	if err := os.Rename(p.path, filepath.Join(dir, p.name)); err != nil {
+		return fmt.Errorf("quarantinePayload: failed to move payload: %v", err)
+	}
+	os.Remove(deliveryPath(p.path))
+	return nil
+}
+
+func (q *Queue) uploaded(priority Priority) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if priority == Critical && q.stats.Critical > 0 {
+		q.stats.Critical--
+	} else if priority == Backfill && q.stats.Backfill > 0 {
+		q.stats.Backfill--
+	}
+}
+
+// wait returns false if stop is closed before d has passed
+func wait(stop <-chan struct{}, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// run uploads the queued batch. The batch is abandoned if stop is closed, and the queue state is left in progress
+// so that the batch is resumed after a restart.
+func (q *Queue) run(stop <-chan struct{}) {
+	b := q.take()
+	if b == nil {
+		return
+	}
+	defer q.finish()
+	log := b.Log
+	if log == nil {
+		log = q.Log
+	}
+
+	if !wait(stop, time.Until(b.NotBefore)) {
+		return
+	}
+
+	statePath := filepath.Join(b.StateDir, StateFile)
+	s, err := loadState(statePath)
+	if err != nil {
+		log.Error(err, "failed to load upload queue state, starting a new state")
+	}
+	index, err := packaging.LoadUploadIndex(filepath.Join(b.StateDir, packaging.UploadIndexFile))
+	if err != nil {
+		log.Error(err, "failed to load upload index, starting a new index")
+	}
+
+	payloads, critical, backfill, err := pending(b.UploadDir, b.MaxBackfill, time.Now())
+	if err != nil {
+		log.Error(err, "failed to read upload directory")
+		return
+	}
+	q.mu.Lock()
+	q.stats.Critical, q.stats.Backfill = critical, backfill
+	q.stats.LastBatchStart = time.Now()
+	q.mu.Unlock()
+	log.Info("uploading payloads", "critical", critical, "backfill", backfill, "batch", len(payloads))
+
+	s.InProgress = true
+	if err := s.save(statePath); err != nil {
+		log.Error(err, "failed to save upload queue state")
+	}
+	for i, p := range payloads {
+		if i > 0 && !wait(stop, b.Interval) {
+			return
+		}
+		done, err := q.upload(b, p, index, log)
+		if done {
+			delete(s.Attempts, p.name)
+			delete(s.Rejections, p.name)
+			q.uploaded(p.priority)
+		} else {
+			s.Attempts[p.name]++
+		}
+		rejected := exporter.IsRejected(err)
+		if rejected {
+			s.Rejections[p.name]++
+			if b.MaxRejections > 0 && b.QuarantineDir != "" && s.Rejections[p.name] >= b.MaxRejections {
+				if qErr := quarantinePayload(p, b.QuarantineDir); qErr != nil {
+					log.Error(qErr, "failed to quarantine payload", "file", p.name)
+				} else {
+					log.Info("payload quarantined after repeated rejections", "file", p.name, "rejections", s.Rejections[p.name])
+					delete(s.Attempts, p.name)
+					delete(s.Rejections, p.name)
+					q.quarantine(p.name)
+					q.uploaded(p.priority)
+				}
+			}
+		}
+		if saveErr := s.save(statePath); saveErr != nil {
+			log.Error(saveErr, "failed to save upload queue state")
+		}
+		if err != nil && !rejected {
+			// ingress failed, so the rest of the batch is tried on the next upload cycle. A rejection is specific to
+			// the payload, so the batch continues.
+			break
+		}
+	}
+
+	// forget the attempts and rejections of payloads that are no longer queued
+	for _, counts := range []map[string]int{s.Attempts, s.Rejections} {
+		for name := range counts {
+			if _, err := os.Stat(filepath.Join(b.UploadDir, name)); os.IsNotExist(err) {
+				delete(counts, name)
+			}
+		}
+	}
+	removeStaleDeliveries(b.UploadDir, log)
+	s.InProgress = false
+	if err := s.save(statePath); err != nil {
+		log.Error(err, "failed to save upload queue state")
+	}
+	q.mu.Lock()
+	q.stats.LastBatchEnd = time.Now()
+	q.mu.Unlock()
+}
+
+// upload exports the payload to the destinations that have not accepted it yet, and removes it once every destination
+// has accepted it. done is true if the payload was removed. An error is returned if ingress failed. The other
+// destinations do not hold up the batch: a destination that fails is recorded in its result and tried again on the next
+// cycle, and the payload is not uploaded to ingress again.
+func (q *Queue) upload(b *Batch, p payload, index *packaging.UploadIndex, log logr.Logger) (bool, error) {
+	statePath := deliveryPath(p.path)
+	d, err := loadDelivery(statePath)
+	if err != nil {
+		// without the state, the payload is exported again to every destination
+		log.Error(err, "failed to load delivery state", "file", p.name)
+	}
+	// the payload is post-processed before it is read, since the hook may change its contents
+	if q.PostProcess != nil && !d.PostProcessed {
+		log.Info("running post-processing hook", "file", p.name, "hook", q.PostProcess.Path)
+		err := q.PostProcess.Run(p.path)
+		q.postProcessed(err)
+		if err != nil {
+			log.Error(err, "post-processing failed, the payload is not uploaded", "file", p.name)
+			return false, nil
+		}
+		d.PostProcessed = true
+		if err := d.save(statePath); err != nil {
+			log.Error(err, "failed to save delivery state", "file", p.name)
+		}
+	}
+
+	summary, err := packaging.ReadPayloadSummary(p.path)
+	if err != nil {
+		log.Error(err, "failed to read payload id")
+	}
+	payloadID := summary.PayloadID
+	fileLog := log.WithValues(logging.PayloadID, payloadID)
+	identity, err := packaging.ReadPayloadIdentity(p.path)
+	if err != nil {
+		fileLog.Error(err, "failed to read payload identity, the payload will be uploaded without deduplication")
+	} else if previous, ok := index.Get(identity); ok && !b.IgnoreIndex {
+		fileLog.Info("skipping payload that was already uploaded",
+			"file", p.name,
+			"identity", identity,
+			"uploadedPayloadID", previous.PayloadID,
+			"uploadedAt", previous.UploadedAt)
+		if err := os.Remove(p.path); err != nil {
+			fileLog.Error(err, "error removing duplicate tar file")
+			return false, nil
+		}
+		os.Remove(deliveryPath(p.path))
+		return true, nil
+	}
+
+	item := exporter.Payload{Path: p.path, Name: p.name, PayloadID: payloadID}
+	if identity != "" {
+		item.IdempotencyKey = packaging.IdempotencyKey(identity)
+	}
+	var endpoint string
+	pending := false
+	for _, exp := range b.Exporters {
+		if d.delivered(exp.Name()) {
+			continue
+		}
+		fileLog.Info(fmt.Sprintf("uploading file: %s", p.name), "destination", exp.Name())
+		err := exp.Export(item)
+		result := NewResult(exp, err)
+		result.File, result.Payload, result.IdempotencyKey = p.name, summary, item.IdempotencyKey
+		q.record(result)
+		_, isIngress := exp.(*exporter.Ingress)
+		if isIngress && exporter.IsNotAccepted(err) {
+			// keep the file and try again on the next cycle
+			return false, nil
+		}
+		if isIngress && err != nil {
+			fileLog.Error(err, "upload failed", "destination", exp.Name())
+			return false, err
+		}
+		if err != nil {
+			fileLog.Error(err, "export failed, the destination is tried again on the next cycle", "destination", exp.Name())
+			pending = true
+			continue
+		}
+		if isIngress {
+			endpoint = exp.(*exporter.Ingress).URL
+			fileLog.Info("payload received", "endpoint", endpoint)
+		}
+		d.Delivered = append(d.Delivered, exp.Name())
+		if err := d.save(statePath); err != nil {
+			fileLog.Error(err, "failed to save delivery state")
+		}
+	}
+	if pending {
+		return false, nil
+	}
+
+	if identity != "" {
+		index.Record(identity, packaging.UploadEntry{PayloadID: payloadID, File: p.name, UploadedAt: time.Now(), Endpoint: endpoint})
+		if err := index.Save(); err != nil {
+			fileLog.Error(err, "failed to save upload index")
+		}
+	}
+	// remove the tar.gz after a successful upload
+	fileLog.Info("removing tar file since upload was successful")
+	if err := os.Remove(p.path); err != nil {
+		fileLog.Error(err, "error removing tar file")
+		return false, nil
+	}
+	os.Remove(statePath)
+	return true, nil
+}
+
+// removeStaleDeliveries removes the delivery state of payloads that are no longer in the upload directory, for
+// example because they were removed to free up storage
+func removeStaleDeliveries(dir string, log logr.Logger) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), dirconfig.DeliverySuffix) {
+			continue
+		}
+		payloadPath := strings.TrimSuffix(filepath.Join(dir, file.Name()), dirconfig.DeliverySuffix)
+		if _, err := os.Stat(payloadPath); os.IsNotExist(err) {
+			if err := os.Remove(filepath.Join(dir, file.Name())); err != nil {
+				log.Error(err, "failed to remove delivery state", "file", file.Name())
+			}
+		}
+	}
+}
+
+// Drain uploads the queued batch in the calling goroutine. It is used where no Worker runs, for example in a Job that
+// exits once its payloads are uploaded.
+func (q *Queue) Drain(stop <-chan struct{}) {
+	q.run(stop)
+}
+
+// Worker uploads the batches submitted to the queue
+type Worker struct {
+	Queue *Queue
+}
+
+// Start uploads batches until stop is closed
+func (w *Worker) Start(stop <-chan struct{}) error {
+	wake := w.Queue.wakeChan()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-wake:
+			w.Queue.run(stop)
+		}
+	}
+}
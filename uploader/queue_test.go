@@ -0,0 +1,484 @@
+/*
+
+
+Copyright 2021 Red Hat, Inc.
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package uploader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-koku/koku-metrics-operator/crhchttp"
+	"github.com/project-koku/koku-metrics-operator/dirconfig"
+	"github.com/project-koku/koku-metrics-operator/exporter"
+	"github.com/project-koku/koku-metrics-operator/packaging"
+	"github.com/project-koku/koku-metrics-operator/testutils"
+	"github.com/project-koku/koku-metrics-operator/testutils/fakes"
+)
+
+var testLogger = testutils.TestLogger{}
+
+type fakeExporter struct {
+	err      error
+	exported []string
+	onExport func()
+}
+
+func (f *fakeExporter) Name() string { return "fake" }
+
+func (f *fakeExporter) Export(payload exporter.Payload) error {
+	f.exported = append(f.exported, payload.Name)
+	if f.onExport != nil {
+		f.onExport()
+	}
+	return f.err
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "uploader")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	return dir
+}
+
+// writePayload writes a tar.gz payload with a manifest and a single report
+func writePayload(t *testing.T, dir, name, uid, report string, modTime time.Time) {
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create payload: %v", err)
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	manifest, _ := json.Marshal(map[string]string{"uuid": uid, "cluster_id": "cluster"})
+	for name, contents := range map[string][]byte{"manifest.json": manifest, uid + "_0.csv": []byte(report)} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(contents)), Mode: 0644}); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if _, err := tw.Write(contents); err != nil {
+			t.Fatalf("failed to write payload: %v", err)
+		}
+	}
+	tw.Close()
+	gw.Close()
+	f.Close()
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set payload time: %v", err)
+	}
+}
+
+func TestPending(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	files := map[string]time.Duration{
+		"new.tar.gz":                          time.Hour,
+		"newer.tar.gz":                        10 * time.Minute,
+		"old.tar.gz":                          48 * time.Hour,
+		"older.tar.gz":                        72 * time.Hour,
+		"1-cost-mgmt-recollect-202101.tar.gz": 0,
+	}
+	for name, age := range files {
+		writePayload(t, dir, name, name, name, now.Add(-age))
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "not-a-payload.csv"), []byte("a,b"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	pendingTests := []struct {
+		name        string
+		maxBackfill int
+		want        []string
+	}{
+		{
+			name:        "critical newest first, then backfill oldest first",
+			maxBackfill: 0,
+			want:        []string{"newer.tar.gz", "new.tar.gz", "older.tar.gz", "old.tar.gz", "1-cost-mgmt-recollect-202101.tar.gz"},
+		},
+		{
+			name:        "backfill is limited",
+			maxBackfill: 2,
+			want:        []string{"newer.tar.gz", "new.tar.gz", "older.tar.gz", "old.tar.gz"},
+		},
+	}
+	for _, tt := range pendingTests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads, critical, backfill, err := pending(dir, tt.maxBackfill, now)
+			if err != nil {
+				t.Fatalf("%s got unexpected error: %v", tt.name, err)
+			}
+			var got []string
+			for _, p := range payloads {
+				got = append(got, p.name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s got %v want %v", tt.name, got, tt.want)
+			}
+			if critical != 2 || backfill != 3 {
+				t.Errorf("%s got %d critical and %d backfill, want 2 and 3", tt.name, critical, backfill)
+			}
+		})
+	}
+}
+
+func TestQueueRun(t *testing.T) {
+	now := time.Now()
+	runTests := []struct {
+		name           string
+		err            error
+		wantExported   int
+		wantRemaining  int
+		wantAttempts   int
+		wantInProgress bool
+		stopOnExport   bool
+		ignoreIndex    bool
+	}{
+		{
+			name:         "payloads are uploaded and duplicates are skipped",
+			wantExported: 2,
+		},
+		{
+			name:         "duplicates are uploaded when the index is ignored",
+			wantExported: 3,
+			ignoreIndex:  true,
+		},
+		{
+			name:          "payloads that are not accepted are kept",
+			err:           exporter.ErrNotAccepted,
+			wantExported:  3,
+			wantRemaining: 3,
+			wantAttempts:  3,
+		},
+		{
+			name:           "interrupted batch is left in progress",
+			wantExported:   1,
+			wantRemaining:  2,
+			wantInProgress: true,
+			stopOnExport:   true,
+		},
+	}
+	for _, tt := range runTests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploadDir := tempDir(t)
+			defer os.RemoveAll(uploadDir)
+			stateDir := tempDir(t)
+			defer os.RemoveAll(stateDir)
+
+			writePayload(t, uploadDir, "a.tar.gz", "uid-a", "a,b", now.Add(-time.Minute))
+			// b is a re-packaged copy of a
+			writePayload(t, uploadDir, "b.tar.gz", "uid-b", "a,b", now.Add(-2*time.Minute))
+			writePayload(t, uploadDir, "c.tar.gz", "uid-c", "c,d", now.Add(-3*time.Minute))
+
+			stop := make(chan struct{})
+			exp := &fakeExporter{err: tt.err}
+			if tt.stopOnExport {
+				exp.onExport = func() { close(stop) }
+			}
+			q := &Queue{Log: testLogger}
+			q.Submit(Batch{
+				UploadDir:   uploadDir,
+				StateDir:    stateDir,
+				Exporters:   []exporter.Exporter{exp},
+				Interval:    time.Millisecond,
+				IgnoreIndex: tt.ignoreIndex,
+				Log:         testLogger,
+			})
+			q.run(stop)
+
+			if len(exp.exported) != tt.wantExported {
+				t.Errorf("%s exported %v, want %d payloads", tt.name, exp.exported, tt.wantExported)
+			}
+			if results := q.Results(); len(results) != tt.wantExported {
+				t.Errorf("%s got %d results, want %d", tt.name, len(results), tt.wantExported)
+			}
+			files, _ := ioutil.ReadDir(uploadDir)
+			if len(files) != tt.wantRemaining {
+				t.Errorf("%s got %d payloads remaining, want %d", tt.name, len(files), tt.wantRemaining)
+			}
+			s, err := loadState(filepath.Join(stateDir, StateFile))
+			if err != nil {
+				t.Fatalf("%s failed to load state: %v", tt.name, err)
+			}
+			attempts := 0
+			for _, n := range s.Attempts {
+				attempts += n
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("%s got %d attempts, want %d", tt.name, attempts, tt.wantAttempts)
+			}
+			if s.InProgress != tt.wantInProgress || Interrupted(stateDir) != tt.wantInProgress {
+				t.Errorf("%s got in progress %t, want %t", tt.name, s.InProgress, tt.wantInProgress)
+			}
+			if q.Busy() {
+				t.Errorf("%s queue is busy after the batch", tt.name)
+			}
+		})
+	}
+}
+
+func TestQueueRecordsEndpoint(t *testing.T) {
+	uploadDir := tempDir(t)
+	defer os.RemoveAll(uploadDir)
+	stateDir := tempDir(t)
+	defer os.RemoveAll(stateDir)
+	writePayload(t, uploadDir, "a.tar.gz", "uid-a", "a,b", time.Now())
+
+	endpoint := "https://relay.example.com/api/ingress/v1/upload"
+	ingress := &exporter.Ingress{
+		AuthConfig: &crhchttp.AuthConfig{Log: testLogger},
+		URL:        endpoint,
+		Uploader:   &fakes.Uploader{},
+	}
+	q := &Queue{Log: testLogger}
+	q.Submit(Batch{
+		UploadDir: uploadDir,
+		StateDir:  stateDir,
+		Exporters: []exporter.Exporter{ingress},
+		Log:       testLogger,
+	})
+	q.run(make(chan struct{}))
+
+	index, err := packaging.LoadUploadIndex(filepath.Join(stateDir, packaging.UploadIndexFile))
+	if err != nil {
+		t.Fatalf("failed to load upload index: %v", err)
+	}
+	if len(index.Payloads) != 1 {
+		t.Fatalf("got %d payloads in the upload index want 1", len(index.Payloads))
+	}
+	for _, entry := range index.Payloads {
+		if entry.Endpoint != endpoint {
+			t.Errorf("got endpoint %q want %q", entry.Endpoint, endpoint)
+		}
+	}
+}
+
+// countingUploader returns a new request ID for every upload
+type countingUploader struct {
+	fakes.Uploader
+	count int
+}
+
+func (u *countingUploader) Upload(authConfig *crhchttp.AuthConfig, contentType, method, uri string, body *bytes.Buffer) (crhchttp.UploadResponse, error) {
+	u.count++
+	u.RequestID = fmt.Sprintf("req-%d", u.count)
+	return u.Uploader.Upload(authConfig, contentType, method, uri, body)
+}
+
+func TestQueueResultsPerPayload(t *testing.T) {
+	uploadDir := tempDir(t)
+	defer os.RemoveAll(uploadDir)
+	stateDir := tempDir(t)
+	defer os.RemoveAll(stateDir)
+	now := time.Now()
+	writePayload(t, uploadDir, "a.tar.gz", "uid-a", "a,b", now)
+	writePayload(t, uploadDir, "b.tar.gz", "uid-b", "c,d", now.Add(-time.Minute))
+
+	ingress := &exporter.Ingress{
+		AuthConfig: &crhchttp.AuthConfig{Log: testLogger},
+		URL:        "https://ingress",
+		Uploader:   &countingUploader{},
+	}
+	q := &Queue{Log: testLogger}
+	q.Submit(Batch{
+		UploadDir: uploadDir,
+		StateDir:  stateDir,
+		Exporters: []exporter.Exporter{ingress},
+		Log:       testLogger,
+	})
+	q.run(make(chan struct{}))
+
+	// every result keeps the response of its own upload, not the last response of the batch
+	got := map[string]string{}
+	for _, result := range q.Results() {
+		if result.Status != "202 Accepted" || result.UploadTime.IsZero() {
+			t.Errorf("got status %q and upload time %v for %s", result.Status, result.UploadTime, result.File)
+		}
+		got[result.File] = result.RequestID
+	}
+	if want := map[string]string{"a.tar.gz": "req-1", "b.tar.gz": "req-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got request ids %v want %v", got, want)
+	}
+}
+
+func TestQueueQuarantine(t *testing.T) {
+	uploadDir := tempDir(t)
+	defer os.RemoveAll(uploadDir)
+	stateDir := tempDir(t)
+	defer os.RemoveAll(stateDir)
+	quarantineDir := filepath.Join(stateDir, "quarantine")
+	now := time.Now()
+	writePayload(t, uploadDir, "a.tar.gz", "uid-a", "a,b", now.Add(-time.Minute))
+	writePayload(t, uploadDir, "b.tar.gz", "uid-b", "c,d", now.Add(-2*time.Minute))
+
+	uploads := &fakes.Uploader{Err: fmt.Errorf("upload failed: %w", &crhchttp.ResponseError{StatusCode: 400})}
+	exp := &exporter.Ingress{AuthConfig: &crhchttp.AuthConfig{Log: testLogger}, URL: "https://ingress", Uploader: uploads}
+	q := &Queue{Log: testLogger}
+	batch := Batch{
+		UploadDir:     uploadDir,
+		StateDir:      stateDir,
+		Exporters:     []exporter.Exporter{exp},
+		QuarantineDir: quarantineDir,
+		MaxRejections: 2,
+		Log:           testLogger,
+	}
+
+	// a rejected payload does not stop the batch, and is kept until it is rejected MaxRejections times
+	q.Submit(batch)
+	q.run(make(chan struct{}))
+	if len(uploads.Uploads()) != 2 {
+		t.Errorf("uploaded %d payloads want both payloads", len(uploads.Uploads()))
+	}
+	s, err := loadState(filepath.Join(stateDir, StateFile))
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if want := map[string]int{"a.tar.gz": 1, "b.tar.gz": 1}; !reflect.DeepEqual(s.Rejections, want) {
+		t.Errorf("got rejections %v want %v", s.Rejections, want)
+	}
+	if got := q.Quarantined(); len(got) != 0 {
+		t.Errorf("got quarantined %v after the first rejection", got)
+	}
+
+	q.Submit(batch)
+	q.run(make(chan struct{}))
+	if got := q.Quarantined(); !reflect.DeepEqual(got, []string{"a.tar.gz", "b.tar.gz"}) {
+		t.Errorf("got quarantined %v want [a.tar.gz b.tar.gz]", got)
+	}
+	if files, _ := ioutil.ReadDir(uploadDir); len(files) != 0 {
+		t.Errorf("got %d payloads remaining in the upload directory want 0", len(files))
+	}
+	if files, _ := ioutil.ReadDir(quarantineDir); len(files) != 2 {
+		t.Errorf("got %d payloads in the quarantine directory want 2", len(files))
+	}
+	if s, _ = loadState(filepath.Join(stateDir, StateFile)); len(s.Rejections) != 0 || len(s.Attempts) != 0 {
+		t.Errorf("got rejections %v and attempts %v for quarantined payloads", s.Rejections, s.Attempts)
+	}
+
+	// server errors stop the batch and are never quarantined
+	writePayload(t, uploadDir, "c.tar.gz", "uid-c", "e,f", now)
+	writePayload(t, uploadDir, "d.tar.gz", "uid-d", "g,h", now.Add(-time.Minute))
+	uploads = &fakes.Uploader{Err: &crhchttp.ResponseError{StatusCode: 503}}
+	exp.Uploader = uploads
+	for i := 0; i < 3; i++ {
+		q.Submit(batch)
+		q.run(make(chan struct{}))
+	}
+	if len(uploads.Uploads()) != 3 || len(q.Quarantined()) != 0 {
+		t.Errorf("uploaded %d payloads want only the first payload of each batch and nothing quarantined", len(uploads.Uploads()))
+	}
+}
+
+func TestQueueRetriesFailedDestinations(t *testing.T) {
+	uploadDir := tempDir(t)
+	defer os.RemoveAll(uploadDir)
+	stateDir := tempDir(t)
+	defer os.RemoveAll(stateDir)
+	now := time.Now()
+	writePayload(t, uploadDir, "a.tar.gz", "uid-a", "a,b", now.Add(-time.Minute))
+	writePayload(t, uploadDir, "b.tar.gz", "uid-b", "c,d", now.Add(-2*time.Minute))
+
+	uploads := &fakes.Uploader{}
+	ingress := &exporter.Ingress{AuthConfig: &crhchttp.AuthConfig{Log: testLogger}, URL: "https://ingress", Uploader: uploads}
+	secondary := &fakeExporter{err: errors.New("bucket unavailable")}
+	q := &Queue{Log: testLogger}
+	batch := Batch{
+		UploadDir: uploadDir,
+		StateDir:  stateDir,
+		Exporters: []exporter.Exporter{ingress, secondary},
+		Log:       testLogger,
+	}
+
+	// a failed destination does not stop the batch, and the payloads are kept for it
+	q.Submit(batch)
+	q.run(make(chan struct{}))
+	if len(uploads.Uploads()) != 2 || len(secondary.exported) != 2 {
+		t.Errorf("uploaded %d payloads to ingress and exported %v want both payloads to both destinations", len(uploads.Uploads()), secondary.exported)
+	}
+	for _, name := range []string{"a.tar.gz", "b.tar.gz"} {
+		d, err := loadDelivery(deliveryPath(filepath.Join(uploadDir, name)))
+		if err != nil || !reflect.DeepEqual(d.Delivered, []string{exporter.IngressName}) {
+			t.Errorf("got delivery state %+v (%v) for %s want ingress", d, err, name)
+		}
+	}
+	if files, _ := (&dirconfig.Directory{Path: uploadDir}).GetFiles(); len(files) != 2 {
+		t.Errorf("got payloads %v want both payloads kept", files)
+	}
+
+	// the next cycle only exports to the destination that failed
+	secondary.err = nil
+	secondary.exported = nil
+	q.Submit(batch)
+	q.run(make(chan struct{}))
+	if len(uploads.Uploads()) != 2 || len(secondary.exported) != 2 {
+		t.Errorf("uploaded %d payloads to ingress and exported %v want no new ingress upload", len(uploads.Uploads()), secondary.exported)
+	}
+	if files, _ := ioutil.ReadDir(uploadDir); len(files) != 0 {
+		t.Errorf("got %d files remaining in the upload directory want 0", len(files))
+	}
+}
+
+func TestQueuePostProcess(t *testing.T) {
+	uploadDir := tempDir(t)
+	defer os.RemoveAll(uploadDir)
+	stateDir := tempDir(t)
+	defer os.RemoveAll(stateDir)
+	writePayload(t, uploadDir, "a.tar.gz", "uid-a", "a,b", time.Now())
+
+	// the hook counts its runs, and fails until the ready file exists
+	hookPath := filepath.Join(stateDir, "hook.sh")
+	script := fmt.Sprintf("#!/bin/sh\necho run >> %s/runs\n[ -f %s/ready ] || { echo not ready; exit 1; }\n", stateDir, stateDir)
+	if err := ioutil.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write hook: %v", err)
+	}
+	secondary := &fakeExporter{err: errors.New("bucket unavailable")}
+	q := &Queue{Log: testLogger, PostProcess: &packaging.PostProcessHook{Path: hookPath, Timeout: 10 * time.Second}}
+	batch := Batch{UploadDir: uploadDir, StateDir: stateDir, Exporters: []exporter.Exporter{secondary}, Log: testLogger}
+
+	// a payload that the hook fails for is kept and not uploaded
+	q.Submit(batch)
+	q.run(make(chan struct{}))
+	if len(secondary.exported) != 0 || !strings.Contains(q.Stats().PostProcessError, "not ready") {
+		t.Errorf("exported %v with post-processing error %q want no export and the hook error", secondary.exported, q.Stats().PostProcessError)
+	}
+
+	// the hook runs once for a payload that is retried for a destination
+	if err := ioutil.WriteFile(filepath.Join(stateDir, "ready"), nil, 0644); err != nil {
+		t.Fatalf("failed to write ready file: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		q.Submit(batch)
+		q.run(make(chan struct{}))
+	}
+	runs, _ := ioutil.ReadFile(filepath.Join(stateDir, "runs"))
+	if got := strings.Count(string(runs), "run"); got != 2 || len(secondary.exported) != 2 || q.Stats().PostProcessError != "" {
+		t.Errorf("got %d hook runs and exported %v want 2 runs and 2 exports", got, secondary.exported)
+	}
+}